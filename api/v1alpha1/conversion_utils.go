@@ -69,3 +69,97 @@ func Convert_v1beta1_HelmChart_To_v1alpha1_HelmChart(src *configv1beta1.HelmChar
 
 	return nil
 }
+
+func Convert_v1beta1_PolicyRef_To_v1alpha1_PolicyRef(src *configv1beta1.PolicyRef, dst *PolicyRef,
+	s conversion.Scope) error {
+
+	return autoConvert_v1beta1_PolicyRef_To_v1alpha1_PolicyRef(src, dst, nil)
+}
+
+func Convert_v1beta1_ValidateHealth_To_v1alpha1_ValidateHealth(src *configv1beta1.ValidateHealth, dst *ValidateHealth,
+	s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ValidateHealth_To_v1alpha1_ValidateHealth(src, dst, nil)
+}
+
+func Convert_v1beta1_ReleaseReport_To_v1alpha1_ReleaseReport(src *configv1beta1.ReleaseReport, dst *ReleaseReport,
+	s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ReleaseReport_To_v1alpha1_ReleaseReport(src, dst, nil)
+}
+
+func Convert_v1beta1_ClusterSummarySpec_To_v1alpha1_ClusterSummarySpec(src *configv1beta1.ClusterSummarySpec,
+	dst *ClusterSummarySpec, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ClusterSummarySpec_To_v1alpha1_ClusterSummarySpec(src, dst, nil)
+}
+
+func Convert_v1beta1_ClusterSummaryStatus_To_v1alpha1_ClusterSummaryStatus(src *configv1beta1.ClusterSummaryStatus,
+	dst *ClusterSummaryStatus, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ClusterSummaryStatus_To_v1alpha1_ClusterSummaryStatus(src, dst, nil)
+}
+
+func Convert_v1beta1_FeatureDeploymentInfo_To_v1alpha1_FeatureDeploymentInfo(src *configv1beta1.FeatureDeploymentInfo,
+	dst *FeatureDeploymentInfo, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_FeatureDeploymentInfo_To_v1alpha1_FeatureDeploymentInfo(src, dst, nil)
+}
+
+func Convert_v1beta1_FeatureSummary_To_v1alpha1_FeatureSummary(src *configv1beta1.FeatureSummary,
+	dst *FeatureSummary, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_FeatureSummary_To_v1alpha1_FeatureSummary(src, dst, nil)
+}
+
+func Convert_v1beta1_HelmChartSummary_To_v1alpha1_HelmChartSummary(src *configv1beta1.HelmChartSummary,
+	dst *HelmChartSummary, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_HelmChartSummary_To_v1alpha1_HelmChartSummary(src, dst, nil)
+}
+
+func Convert_v1beta1_Chart_To_v1alpha1_Chart(src *configv1beta1.Chart, dst *Chart, s conversion.Scope) error {
+	return autoConvert_v1beta1_Chart_To_v1alpha1_Chart(src, dst, nil)
+}
+
+func Convert_v1beta1_ClusterConfiguration_To_v1alpha1_ClusterConfiguration(src *configv1beta1.ClusterConfiguration,
+	dst *ClusterConfiguration, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ClusterConfiguration_To_v1alpha1_ClusterConfiguration(src, dst, nil)
+}
+
+func Convert_v1beta1_ClusterConfigurationStatus_To_v1alpha1_ClusterConfigurationStatus(src *configv1beta1.ClusterConfigurationStatus,
+	dst *ClusterConfigurationStatus, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ClusterConfigurationStatus_To_v1alpha1_ClusterConfigurationStatus(src, dst, nil)
+}
+
+func Convert_v1beta1_ClusterProfileResource_To_v1alpha1_ClusterProfileResource(src *configv1beta1.ClusterProfileResource,
+	dst *ClusterProfileResource, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ClusterProfileResource_To_v1alpha1_ClusterProfileResource(src, dst, nil)
+}
+
+func Convert_v1beta1_ProfileResource_To_v1alpha1_ProfileResource(src *configv1beta1.ProfileResource,
+	dst *ProfileResource, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ProfileResource_To_v1alpha1_ProfileResource(src, dst, nil)
+}
+
+func Convert_v1beta1_ClusterReportSpec_To_v1alpha1_ClusterReportSpec(src *configv1beta1.ClusterReportSpec,
+	dst *ClusterReportSpec, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ClusterReportSpec_To_v1alpha1_ClusterReportSpec(src, dst, nil)
+}
+
+func Convert_v1beta1_ClusterReportStatus_To_v1alpha1_ClusterReportStatus(src *configv1beta1.ClusterReportStatus,
+	dst *ClusterReportStatus, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_ClusterReportStatus_To_v1alpha1_ClusterReportStatus(src, dst, nil)
+}
+
+func Convert_v1beta1_Status_To_v1alpha1_Status(src *configv1beta1.Status,
+	dst *Status, s conversion.Scope) error {
+
+	return autoConvert_v1beta1_Status_To_v1alpha1_Status(src, dst, nil)
+}
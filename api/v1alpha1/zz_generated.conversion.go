@@ -46,21 +46,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.Chart)(nil), (*Chart)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_Chart_To_v1alpha1_Chart(a.(*v1beta1.Chart), b.(*Chart), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*ClusterConfiguration)(nil), (*v1beta1.ClusterConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ClusterConfiguration_To_v1beta1_ClusterConfiguration(a.(*ClusterConfiguration), b.(*v1beta1.ClusterConfiguration), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ClusterConfiguration)(nil), (*ClusterConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ClusterConfiguration_To_v1alpha1_ClusterConfiguration(a.(*v1beta1.ClusterConfiguration), b.(*ClusterConfiguration), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*ClusterConfigurationList)(nil), (*v1beta1.ClusterConfigurationList)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ClusterConfigurationList_To_v1beta1_ClusterConfigurationList(a.(*ClusterConfigurationList), b.(*v1beta1.ClusterConfigurationList), scope)
 	}); err != nil {
@@ -76,11 +66,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ClusterConfigurationStatus)(nil), (*ClusterConfigurationStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ClusterConfigurationStatus_To_v1alpha1_ClusterConfigurationStatus(a.(*v1beta1.ClusterConfigurationStatus), b.(*ClusterConfigurationStatus), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*ClusterProfile)(nil), (*v1beta1.ClusterProfile)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ClusterProfile_To_v1beta1_ClusterProfile(a.(*ClusterProfile), b.(*v1beta1.ClusterProfile), scope)
 	}); err != nil {
@@ -106,11 +91,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ClusterProfileResource)(nil), (*ClusterProfileResource)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ClusterProfileResource_To_v1alpha1_ClusterProfileResource(a.(*v1beta1.ClusterProfileResource), b.(*ClusterProfileResource), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*ClusterReport)(nil), (*v1beta1.ClusterReport)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ClusterReport_To_v1beta1_ClusterReport(a.(*ClusterReport), b.(*v1beta1.ClusterReport), scope)
 	}); err != nil {
@@ -136,21 +116,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ClusterReportSpec)(nil), (*ClusterReportSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ClusterReportSpec_To_v1alpha1_ClusterReportSpec(a.(*v1beta1.ClusterReportSpec), b.(*ClusterReportSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*ClusterReportStatus)(nil), (*v1beta1.ClusterReportStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ClusterReportStatus_To_v1beta1_ClusterReportStatus(a.(*ClusterReportStatus), b.(*v1beta1.ClusterReportStatus), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ClusterReportStatus)(nil), (*ClusterReportStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ClusterReportStatus_To_v1alpha1_ClusterReportStatus(a.(*v1beta1.ClusterReportStatus), b.(*ClusterReportStatus), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*ClusterSummary)(nil), (*v1beta1.ClusterSummary)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ClusterSummary_To_v1beta1_ClusterSummary(a.(*ClusterSummary), b.(*v1beta1.ClusterSummary), scope)
 	}); err != nil {
@@ -176,21 +146,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ClusterSummarySpec)(nil), (*ClusterSummarySpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ClusterSummarySpec_To_v1alpha1_ClusterSummarySpec(a.(*v1beta1.ClusterSummarySpec), b.(*ClusterSummarySpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*ClusterSummaryStatus)(nil), (*v1beta1.ClusterSummaryStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ClusterSummaryStatus_To_v1beta1_ClusterSummaryStatus(a.(*ClusterSummaryStatus), b.(*v1beta1.ClusterSummaryStatus), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ClusterSummaryStatus)(nil), (*ClusterSummaryStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ClusterSummaryStatus_To_v1alpha1_ClusterSummaryStatus(a.(*v1beta1.ClusterSummaryStatus), b.(*ClusterSummaryStatus), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*Clusters)(nil), (*v1beta1.Clusters)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_Clusters_To_v1beta1_Clusters(a.(*Clusters), b.(*v1beta1.Clusters), scope)
 	}); err != nil {
@@ -226,21 +186,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.FeatureDeploymentInfo)(nil), (*FeatureDeploymentInfo)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_FeatureDeploymentInfo_To_v1alpha1_FeatureDeploymentInfo(a.(*v1beta1.FeatureDeploymentInfo), b.(*FeatureDeploymentInfo), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*FeatureSummary)(nil), (*v1beta1.FeatureSummary)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_FeatureSummary_To_v1beta1_FeatureSummary(a.(*FeatureSummary), b.(*v1beta1.FeatureSummary), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.FeatureSummary)(nil), (*FeatureSummary)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_FeatureSummary_To_v1alpha1_FeatureSummary(a.(*v1beta1.FeatureSummary), b.(*FeatureSummary), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*HelmChart)(nil), (*v1beta1.HelmChart)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_HelmChart_To_v1beta1_HelmChart(a.(*HelmChart), b.(*v1beta1.HelmChart), scope)
 	}); err != nil {
@@ -251,11 +201,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.HelmChartSummary)(nil), (*HelmChartSummary)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_HelmChartSummary_To_v1alpha1_HelmChartSummary(a.(*v1beta1.HelmChartSummary), b.(*HelmChartSummary), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*HelmInstallOptions)(nil), (*v1beta1.HelmInstallOptions)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_HelmInstallOptions_To_v1beta1_HelmInstallOptions(a.(*HelmInstallOptions), b.(*v1beta1.HelmInstallOptions), scope)
 	}); err != nil {
@@ -311,11 +256,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.PolicyRef)(nil), (*PolicyRef)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_PolicyRef_To_v1alpha1_PolicyRef(a.(*v1beta1.PolicyRef), b.(*PolicyRef), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*Profile)(nil), (*v1beta1.Profile)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_Profile_To_v1beta1_Profile(a.(*Profile), b.(*v1beta1.Profile), scope)
 	}); err != nil {
@@ -341,21 +281,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ProfileResource)(nil), (*ProfileResource)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ProfileResource_To_v1alpha1_ProfileResource(a.(*v1beta1.ProfileResource), b.(*ProfileResource), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*ReleaseReport)(nil), (*v1beta1.ReleaseReport)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ReleaseReport_To_v1beta1_ReleaseReport(a.(*ReleaseReport), b.(*v1beta1.ReleaseReport), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ReleaseReport)(nil), (*ReleaseReport)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ReleaseReport_To_v1alpha1_ReleaseReport(a.(*v1beta1.ReleaseReport), b.(*ReleaseReport), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*Resource)(nil), (*v1beta1.Resource)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_Resource_To_v1beta1_Resource(a.(*Resource), b.(*v1beta1.Resource), scope)
 	}); err != nil {
@@ -381,11 +311,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.Status)(nil), (*Status)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_Status_To_v1alpha1_Status(a.(*v1beta1.Status), b.(*Status), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*TemplateResourceRef)(nil), (*v1beta1.TemplateResourceRef)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_TemplateResourceRef_To_v1beta1_TemplateResourceRef(a.(*TemplateResourceRef), b.(*v1beta1.TemplateResourceRef), scope)
 	}); err != nil {
@@ -401,11 +326,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.ValidateHealth)(nil), (*ValidateHealth)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_ValidateHealth_To_v1alpha1_ValidateHealth(a.(*v1beta1.ValidateHealth), b.(*ValidateHealth), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*ValueFrom)(nil), (*v1beta1.ValueFrom)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ValueFrom_To_v1beta1_ValueFrom(a.(*ValueFrom), b.(*v1beta1.ValueFrom), scope)
 	}); err != nil {
@@ -421,16 +341,96 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta1.Chart)(nil), (*Chart)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_Chart_To_v1alpha1_Chart(a.(*v1beta1.Chart), b.(*Chart), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ClusterConfiguration)(nil), (*ClusterConfiguration)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ClusterConfiguration_To_v1alpha1_ClusterConfiguration(a.(*v1beta1.ClusterConfiguration), b.(*ClusterConfiguration), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ClusterConfigurationStatus)(nil), (*ClusterConfigurationStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ClusterConfigurationStatus_To_v1alpha1_ClusterConfigurationStatus(a.(*v1beta1.ClusterConfigurationStatus), b.(*ClusterConfigurationStatus), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ClusterProfileResource)(nil), (*ClusterProfileResource)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ClusterProfileResource_To_v1alpha1_ClusterProfileResource(a.(*v1beta1.ClusterProfileResource), b.(*ClusterProfileResource), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ClusterReportSpec)(nil), (*ClusterReportSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ClusterReportSpec_To_v1alpha1_ClusterReportSpec(a.(*v1beta1.ClusterReportSpec), b.(*ClusterReportSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ClusterReportStatus)(nil), (*ClusterReportStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ClusterReportStatus_To_v1alpha1_ClusterReportStatus(a.(*v1beta1.ClusterReportStatus), b.(*ClusterReportStatus), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ClusterSummarySpec)(nil), (*ClusterSummarySpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ClusterSummarySpec_To_v1alpha1_ClusterSummarySpec(a.(*v1beta1.ClusterSummarySpec), b.(*ClusterSummarySpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ClusterSummaryStatus)(nil), (*ClusterSummaryStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ClusterSummaryStatus_To_v1alpha1_ClusterSummaryStatus(a.(*v1beta1.ClusterSummaryStatus), b.(*ClusterSummaryStatus), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.FeatureDeploymentInfo)(nil), (*FeatureDeploymentInfo)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_FeatureDeploymentInfo_To_v1alpha1_FeatureDeploymentInfo(a.(*v1beta1.FeatureDeploymentInfo), b.(*FeatureDeploymentInfo), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.FeatureSummary)(nil), (*FeatureSummary)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_FeatureSummary_To_v1alpha1_FeatureSummary(a.(*v1beta1.FeatureSummary), b.(*FeatureSummary), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1beta1.HelmChart)(nil), (*HelmChart)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta1_HelmChart_To_v1alpha1_HelmChart(a.(*v1beta1.HelmChart), b.(*HelmChart), scope)
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta1.HelmChartSummary)(nil), (*HelmChartSummary)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_HelmChartSummary_To_v1alpha1_HelmChartSummary(a.(*v1beta1.HelmChartSummary), b.(*HelmChartSummary), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.PolicyRef)(nil), (*PolicyRef)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_PolicyRef_To_v1alpha1_PolicyRef(a.(*v1beta1.PolicyRef), b.(*PolicyRef), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ProfileResource)(nil), (*ProfileResource)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ProfileResource_To_v1alpha1_ProfileResource(a.(*v1beta1.ProfileResource), b.(*ProfileResource), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ReleaseReport)(nil), (*ReleaseReport)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ReleaseReport_To_v1alpha1_ReleaseReport(a.(*v1beta1.ReleaseReport), b.(*ReleaseReport), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1beta1.Spec)(nil), (*Spec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta1_Spec_To_v1alpha1_Spec(a.(*v1beta1.Spec), b.(*Spec), scope)
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta1.Status)(nil), (*Status)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_Status_To_v1alpha1_Status(a.(*v1beta1.Status), b.(*Status), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.ValidateHealth)(nil), (*ValidateHealth)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ValidateHealth_To_v1alpha1_ValidateHealth(a.(*v1beta1.ValidateHealth), b.(*ValidateHealth), scope)
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -456,16 +456,14 @@ func autoConvert_v1beta1_Chart_To_v1alpha1_Chart(in *v1beta1.Chart, out *Chart,
 	out.Namespace = in.Namespace
 	out.ChartVersion = in.ChartVersion
 	out.AppVersion = in.AppVersion
+	// WARNING: in.Revision requires manual conversion: does not exist in peer-type
 	out.Icon = in.Icon
 	out.LastAppliedTime = (*v1.Time)(unsafe.Pointer(in.LastAppliedTime))
+	// WARNING: in.Verification requires manual conversion: does not exist in peer-type
+	// WARNING: in.Values requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_Chart_To_v1alpha1_Chart is an autogenerated conversion function.
-func Convert_v1beta1_Chart_To_v1alpha1_Chart(in *v1beta1.Chart, out *Chart, s conversion.Scope) error {
-	return autoConvert_v1beta1_Chart_To_v1alpha1_Chart(in, out, s)
-}
-
 func autoConvert_v1alpha1_ClusterConfiguration_To_v1beta1_ClusterConfiguration(in *ClusterConfiguration, out *v1beta1.ClusterConfiguration, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha1_ClusterConfigurationStatus_To_v1beta1_ClusterConfigurationStatus(&in.Status, &out.Status, s); err != nil {
@@ -481,20 +479,26 @@ func Convert_v1alpha1_ClusterConfiguration_To_v1beta1_ClusterConfiguration(in *C
 
 func autoConvert_v1beta1_ClusterConfiguration_To_v1alpha1_ClusterConfiguration(in *v1beta1.ClusterConfiguration, out *ClusterConfiguration, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
+	// WARNING: in.Spec requires manual conversion: does not exist in peer-type
 	if err := Convert_v1beta1_ClusterConfigurationStatus_To_v1alpha1_ClusterConfigurationStatus(&in.Status, &out.Status, s); err != nil {
 		return err
 	}
 	return nil
 }
 
-// Convert_v1beta1_ClusterConfiguration_To_v1alpha1_ClusterConfiguration is an autogenerated conversion function.
-func Convert_v1beta1_ClusterConfiguration_To_v1alpha1_ClusterConfiguration(in *v1beta1.ClusterConfiguration, out *ClusterConfiguration, s conversion.Scope) error {
-	return autoConvert_v1beta1_ClusterConfiguration_To_v1alpha1_ClusterConfiguration(in, out, s)
-}
-
 func autoConvert_v1alpha1_ClusterConfigurationList_To_v1beta1_ClusterConfigurationList(in *ClusterConfigurationList, out *v1beta1.ClusterConfigurationList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]v1beta1.ClusterConfiguration)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]v1beta1.ClusterConfiguration, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ClusterConfiguration_To_v1beta1_ClusterConfiguration(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -505,7 +509,17 @@ func Convert_v1alpha1_ClusterConfigurationList_To_v1beta1_ClusterConfigurationLi
 
 func autoConvert_v1beta1_ClusterConfigurationList_To_v1alpha1_ClusterConfigurationList(in *v1beta1.ClusterConfigurationList, out *ClusterConfigurationList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]ClusterConfiguration)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterConfiguration, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_ClusterConfiguration_To_v1alpha1_ClusterConfiguration(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -515,8 +529,28 @@ func Convert_v1beta1_ClusterConfigurationList_To_v1alpha1_ClusterConfigurationLi
 }
 
 func autoConvert_v1alpha1_ClusterConfigurationStatus_To_v1beta1_ClusterConfigurationStatus(in *ClusterConfigurationStatus, out *v1beta1.ClusterConfigurationStatus, s conversion.Scope) error {
-	out.ClusterProfileResources = *(*[]v1beta1.ClusterProfileResource)(unsafe.Pointer(&in.ClusterProfileResources))
-	out.ProfileResources = *(*[]v1beta1.ProfileResource)(unsafe.Pointer(&in.ProfileResources))
+	if in.ClusterProfileResources != nil {
+		in, out := &in.ClusterProfileResources, &out.ClusterProfileResources
+		*out = make([]v1beta1.ClusterProfileResource, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ClusterProfileResource_To_v1beta1_ClusterProfileResource(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ClusterProfileResources = nil
+	}
+	if in.ProfileResources != nil {
+		in, out := &in.ProfileResources, &out.ProfileResources
+		*out = make([]v1beta1.ProfileResource, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ProfileResource_To_v1beta1_ProfileResource(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ProfileResources = nil
+	}
 	return nil
 }
 
@@ -526,16 +560,32 @@ func Convert_v1alpha1_ClusterConfigurationStatus_To_v1beta1_ClusterConfiguration
 }
 
 func autoConvert_v1beta1_ClusterConfigurationStatus_To_v1alpha1_ClusterConfigurationStatus(in *v1beta1.ClusterConfigurationStatus, out *ClusterConfigurationStatus, s conversion.Scope) error {
-	out.ClusterProfileResources = *(*[]ClusterProfileResource)(unsafe.Pointer(&in.ClusterProfileResources))
-	out.ProfileResources = *(*[]ProfileResource)(unsafe.Pointer(&in.ProfileResources))
+	if in.ClusterProfileResources != nil {
+		in, out := &in.ClusterProfileResources, &out.ClusterProfileResources
+		*out = make([]ClusterProfileResource, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_ClusterProfileResource_To_v1alpha1_ClusterProfileResource(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ClusterProfileResources = nil
+	}
+	if in.ProfileResources != nil {
+		in, out := &in.ProfileResources, &out.ProfileResources
+		*out = make([]ProfileResource, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_ProfileResource_To_v1alpha1_ProfileResource(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ProfileResources = nil
+	}
+	// WARNING: in.Conditions requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_ClusterConfigurationStatus_To_v1alpha1_ClusterConfigurationStatus is an autogenerated conversion function.
-func Convert_v1beta1_ClusterConfigurationStatus_To_v1alpha1_ClusterConfigurationStatus(in *v1beta1.ClusterConfigurationStatus, out *ClusterConfigurationStatus, s conversion.Scope) error {
-	return autoConvert_v1beta1_ClusterConfigurationStatus_To_v1alpha1_ClusterConfigurationStatus(in, out, s)
-}
-
 func autoConvert_v1alpha1_ClusterProfile_To_v1beta1_ClusterProfile(in *ClusterProfile, out *v1beta1.ClusterProfile, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha1_Spec_To_v1beta1_Spec(&in.Spec, &out.Spec, s); err != nil {
@@ -612,7 +662,17 @@ func Convert_v1beta1_ClusterProfileList_To_v1alpha1_ClusterProfileList(in *v1bet
 
 func autoConvert_v1alpha1_ClusterProfileResource_To_v1beta1_ClusterProfileResource(in *ClusterProfileResource, out *v1beta1.ClusterProfileResource, s conversion.Scope) error {
 	out.ClusterProfileName = in.ClusterProfileName
-	out.Features = *(*[]v1beta1.Feature)(unsafe.Pointer(&in.Features))
+	if in.Features != nil {
+		in, out := &in.Features, &out.Features
+		*out = make([]v1beta1.Feature, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_Feature_To_v1beta1_Feature(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Features = nil
+	}
 	return nil
 }
 
@@ -623,15 +683,21 @@ func Convert_v1alpha1_ClusterProfileResource_To_v1beta1_ClusterProfileResource(i
 
 func autoConvert_v1beta1_ClusterProfileResource_To_v1alpha1_ClusterProfileResource(in *v1beta1.ClusterProfileResource, out *ClusterProfileResource, s conversion.Scope) error {
 	out.ClusterProfileName = in.ClusterProfileName
-	out.Features = *(*[]Feature)(unsafe.Pointer(&in.Features))
+	if in.Features != nil {
+		in, out := &in.Features, &out.Features
+		*out = make([]Feature, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_Feature_To_v1alpha1_Feature(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Features = nil
+	}
+	// WARNING: in.SourceRevision requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_ClusterProfileResource_To_v1alpha1_ClusterProfileResource is an autogenerated conversion function.
-func Convert_v1beta1_ClusterProfileResource_To_v1alpha1_ClusterProfileResource(in *v1beta1.ClusterProfileResource, out *ClusterProfileResource, s conversion.Scope) error {
-	return autoConvert_v1beta1_ClusterProfileResource_To_v1alpha1_ClusterProfileResource(in, out, s)
-}
-
 func autoConvert_v1alpha1_ClusterReport_To_v1beta1_ClusterReport(in *ClusterReport, out *v1beta1.ClusterReport, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha1_ClusterReportSpec_To_v1beta1_ClusterReportSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -666,7 +732,17 @@ func Convert_v1beta1_ClusterReport_To_v1alpha1_ClusterReport(in *v1beta1.Cluster
 
 func autoConvert_v1alpha1_ClusterReportList_To_v1beta1_ClusterReportList(in *ClusterReportList, out *v1beta1.ClusterReportList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]v1beta1.ClusterReport)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]v1beta1.ClusterReport, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ClusterReport_To_v1beta1_ClusterReport(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -677,7 +753,17 @@ func Convert_v1alpha1_ClusterReportList_To_v1beta1_ClusterReportList(in *Cluster
 
 func autoConvert_v1beta1_ClusterReportList_To_v1alpha1_ClusterReportList(in *v1beta1.ClusterReportList, out *ClusterReportList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]ClusterReport)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterReport, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_ClusterReport_To_v1alpha1_ClusterReport(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -700,16 +786,22 @@ func Convert_v1alpha1_ClusterReportSpec_To_v1beta1_ClusterReportSpec(in *Cluster
 func autoConvert_v1beta1_ClusterReportSpec_To_v1alpha1_ClusterReportSpec(in *v1beta1.ClusterReportSpec, out *ClusterReportSpec, s conversion.Scope) error {
 	out.ClusterNamespace = in.ClusterNamespace
 	out.ClusterName = in.ClusterName
+	// WARNING: in.ClusterType requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_ClusterReportSpec_To_v1alpha1_ClusterReportSpec is an autogenerated conversion function.
-func Convert_v1beta1_ClusterReportSpec_To_v1alpha1_ClusterReportSpec(in *v1beta1.ClusterReportSpec, out *ClusterReportSpec, s conversion.Scope) error {
-	return autoConvert_v1beta1_ClusterReportSpec_To_v1alpha1_ClusterReportSpec(in, out, s)
-}
-
 func autoConvert_v1alpha1_ClusterReportStatus_To_v1beta1_ClusterReportStatus(in *ClusterReportStatus, out *v1beta1.ClusterReportStatus, s conversion.Scope) error {
-	out.ReleaseReports = *(*[]v1beta1.ReleaseReport)(unsafe.Pointer(&in.ReleaseReports))
+	if in.ReleaseReports != nil {
+		in, out := &in.ReleaseReports, &out.ReleaseReports
+		*out = make([]v1beta1.ReleaseReport, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ReleaseReport_To_v1beta1_ReleaseReport(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ReleaseReports = nil
+	}
 	out.ResourceReports = *(*[]v1beta1.ResourceReport)(unsafe.Pointer(&in.ResourceReports))
 	out.KustomizeResourceReports = *(*[]v1beta1.ResourceReport)(unsafe.Pointer(&in.KustomizeResourceReports))
 	return nil
@@ -721,17 +813,23 @@ func Convert_v1alpha1_ClusterReportStatus_To_v1beta1_ClusterReportStatus(in *Clu
 }
 
 func autoConvert_v1beta1_ClusterReportStatus_To_v1alpha1_ClusterReportStatus(in *v1beta1.ClusterReportStatus, out *ClusterReportStatus, s conversion.Scope) error {
-	out.ReleaseReports = *(*[]ReleaseReport)(unsafe.Pointer(&in.ReleaseReports))
+	if in.ReleaseReports != nil {
+		in, out := &in.ReleaseReports, &out.ReleaseReports
+		*out = make([]ReleaseReport, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_ReleaseReport_To_v1alpha1_ReleaseReport(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ReleaseReports = nil
+	}
 	out.ResourceReports = *(*[]ResourceReport)(unsafe.Pointer(&in.ResourceReports))
 	out.KustomizeResourceReports = *(*[]ResourceReport)(unsafe.Pointer(&in.KustomizeResourceReports))
+	// WARNING: in.Conformance requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_ClusterReportStatus_To_v1alpha1_ClusterReportStatus is an autogenerated conversion function.
-func Convert_v1beta1_ClusterReportStatus_To_v1alpha1_ClusterReportStatus(in *v1beta1.ClusterReportStatus, out *ClusterReportStatus, s conversion.Scope) error {
-	return autoConvert_v1beta1_ClusterReportStatus_To_v1alpha1_ClusterReportStatus(in, out, s)
-}
-
 func autoConvert_v1alpha1_ClusterSummary_To_v1beta1_ClusterSummary(in *ClusterSummary, out *v1beta1.ClusterSummary, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha1_ClusterSummarySpec_To_v1beta1_ClusterSummarySpec(&in.Spec, &out.Spec, s); err != nil {
@@ -828,19 +926,45 @@ func autoConvert_v1beta1_ClusterSummarySpec_To_v1alpha1_ClusterSummarySpec(in *v
 	if err := Convert_v1beta1_Spec_To_v1alpha1_Spec(&in.ClusterProfileSpec, &out.ClusterProfileSpec, s); err != nil {
 		return err
 	}
+	// WARNING: in.ProfileRevision requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_ClusterSummarySpec_To_v1alpha1_ClusterSummarySpec is an autogenerated conversion function.
-func Convert_v1beta1_ClusterSummarySpec_To_v1alpha1_ClusterSummarySpec(in *v1beta1.ClusterSummarySpec, out *ClusterSummarySpec, s conversion.Scope) error {
-	return autoConvert_v1beta1_ClusterSummarySpec_To_v1alpha1_ClusterSummarySpec(in, out, s)
-}
-
 func autoConvert_v1alpha1_ClusterSummaryStatus_To_v1beta1_ClusterSummaryStatus(in *ClusterSummaryStatus, out *v1beta1.ClusterSummaryStatus, s conversion.Scope) error {
 	out.Dependencies = (*string)(unsafe.Pointer(in.Dependencies))
-	out.FeatureSummaries = *(*[]v1beta1.FeatureSummary)(unsafe.Pointer(&in.FeatureSummaries))
-	out.DeployedGVKs = *(*[]v1beta1.FeatureDeploymentInfo)(unsafe.Pointer(&in.DeployedGVKs))
-	out.HelmReleaseSummaries = *(*[]v1beta1.HelmChartSummary)(unsafe.Pointer(&in.HelmReleaseSummaries))
+	if in.FeatureSummaries != nil {
+		in, out := &in.FeatureSummaries, &out.FeatureSummaries
+		*out = make([]v1beta1.FeatureSummary, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_FeatureSummary_To_v1beta1_FeatureSummary(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.FeatureSummaries = nil
+	}
+	if in.DeployedGVKs != nil {
+		in, out := &in.DeployedGVKs, &out.DeployedGVKs
+		*out = make([]v1beta1.FeatureDeploymentInfo, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_FeatureDeploymentInfo_To_v1beta1_FeatureDeploymentInfo(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.DeployedGVKs = nil
+	}
+	if in.HelmReleaseSummaries != nil {
+		in, out := &in.HelmReleaseSummaries, &out.HelmReleaseSummaries
+		*out = make([]v1beta1.HelmChartSummary, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_HelmChartSummary_To_v1beta1_HelmChartSummary(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.HelmReleaseSummaries = nil
+	}
 	return nil
 }
 
@@ -851,17 +975,49 @@ func Convert_v1alpha1_ClusterSummaryStatus_To_v1beta1_ClusterSummaryStatus(in *C
 
 func autoConvert_v1beta1_ClusterSummaryStatus_To_v1alpha1_ClusterSummaryStatus(in *v1beta1.ClusterSummaryStatus, out *ClusterSummaryStatus, s conversion.Scope) error {
 	out.Dependencies = (*string)(unsafe.Pointer(in.Dependencies))
-	out.FeatureSummaries = *(*[]FeatureSummary)(unsafe.Pointer(&in.FeatureSummaries))
-	out.DeployedGVKs = *(*[]FeatureDeploymentInfo)(unsafe.Pointer(&in.DeployedGVKs))
-	out.HelmReleaseSummaries = *(*[]HelmChartSummary)(unsafe.Pointer(&in.HelmReleaseSummaries))
+	if in.FeatureSummaries != nil {
+		in, out := &in.FeatureSummaries, &out.FeatureSummaries
+		*out = make([]FeatureSummary, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_FeatureSummary_To_v1alpha1_FeatureSummary(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.FeatureSummaries = nil
+	}
+	if in.DeployedGVKs != nil {
+		in, out := &in.DeployedGVKs, &out.DeployedGVKs
+		*out = make([]FeatureDeploymentInfo, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_FeatureDeploymentInfo_To_v1alpha1_FeatureDeploymentInfo(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.DeployedGVKs = nil
+	}
+	if in.HelmReleaseSummaries != nil {
+		in, out := &in.HelmReleaseSummaries, &out.HelmReleaseSummaries
+		*out = make([]HelmChartSummary, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_HelmChartSummary_To_v1alpha1_HelmChartSummary(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.HelmReleaseSummaries = nil
+	}
+	// WARNING: in.Conditions requires manual conversion: does not exist in peer-type
+	// WARNING: in.Connection requires manual conversion: does not exist in peer-type
+	// WARNING: in.Drift requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastEnforcedTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.ObservedRevision requires manual conversion: does not exist in peer-type
+	// WARNING: in.QueuePosition requires manual conversion: does not exist in peer-type
+	// WARNING: in.SourceRevision requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_ClusterSummaryStatus_To_v1alpha1_ClusterSummaryStatus is an autogenerated conversion function.
-func Convert_v1beta1_ClusterSummaryStatus_To_v1alpha1_ClusterSummaryStatus(in *v1beta1.ClusterSummaryStatus, out *ClusterSummaryStatus, s conversion.Scope) error {
-	return autoConvert_v1beta1_ClusterSummaryStatus_To_v1alpha1_ClusterSummaryStatus(in, out, s)
-}
-
 func autoConvert_v1alpha1_Clusters_To_v1beta1_Clusters(in *Clusters, out *v1beta1.Clusters, s conversion.Scope) error {
 	out.Hash = *(*[]byte)(unsafe.Pointer(&in.Hash))
 	out.Clusters = *(*[]corev1.ObjectReference)(unsafe.Pointer(&in.Clusters))
@@ -905,7 +1061,17 @@ func Convert_v1beta1_DryRunReconciliationError_To_v1alpha1_DryRunReconciliationE
 func autoConvert_v1alpha1_Feature_To_v1beta1_Feature(in *Feature, out *v1beta1.Feature, s conversion.Scope) error {
 	out.FeatureID = v1beta1.FeatureID(in.FeatureID)
 	out.Resources = *(*[]v1beta1.Resource)(unsafe.Pointer(&in.Resources))
-	out.Charts = *(*[]v1beta1.Chart)(unsafe.Pointer(&in.Charts))
+	if in.Charts != nil {
+		in, out := &in.Charts, &out.Charts
+		*out = make([]v1beta1.Chart, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_Chart_To_v1beta1_Chart(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Charts = nil
+	}
 	return nil
 }
 
@@ -917,7 +1083,17 @@ func Convert_v1alpha1_Feature_To_v1beta1_Feature(in *Feature, out *v1beta1.Featu
 func autoConvert_v1beta1_Feature_To_v1alpha1_Feature(in *v1beta1.Feature, out *Feature, s conversion.Scope) error {
 	out.FeatureID = FeatureID(in.FeatureID)
 	out.Resources = *(*[]Resource)(unsafe.Pointer(&in.Resources))
-	out.Charts = *(*[]Chart)(unsafe.Pointer(&in.Charts))
+	if in.Charts != nil {
+		in, out := &in.Charts, &out.Charts
+		*out = make([]Chart, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_Chart_To_v1alpha1_Chart(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Charts = nil
+	}
 	return nil
 }
 
@@ -940,14 +1116,11 @@ func Convert_v1alpha1_FeatureDeploymentInfo_To_v1beta1_FeatureDeploymentInfo(in
 func autoConvert_v1beta1_FeatureDeploymentInfo_To_v1alpha1_FeatureDeploymentInfo(in *v1beta1.FeatureDeploymentInfo, out *FeatureDeploymentInfo, s conversion.Scope) error {
 	out.FeatureID = FeatureID(in.FeatureID)
 	out.DeployedGroupVersionKind = *(*[]string)(unsafe.Pointer(&in.DeployedGroupVersionKind))
+	// WARNING: in.DeployedResources requires manual conversion: does not exist in peer-type
+	// WARNING: in.DeployedManagementClusterResources requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_FeatureDeploymentInfo_To_v1alpha1_FeatureDeploymentInfo is an autogenerated conversion function.
-func Convert_v1beta1_FeatureDeploymentInfo_To_v1alpha1_FeatureDeploymentInfo(in *v1beta1.FeatureDeploymentInfo, out *FeatureDeploymentInfo, s conversion.Scope) error {
-	return autoConvert_v1beta1_FeatureDeploymentInfo_To_v1alpha1_FeatureDeploymentInfo(in, out, s)
-}
-
 func autoConvert_v1alpha1_FeatureSummary_To_v1beta1_FeatureSummary(in *FeatureSummary, out *v1beta1.FeatureSummary, s conversion.Scope) error {
 	out.FeatureID = v1beta1.FeatureID(in.FeatureID)
 	out.Hash = *(*[]byte)(unsafe.Pointer(&in.Hash))
@@ -972,14 +1145,12 @@ func autoConvert_v1beta1_FeatureSummary_To_v1alpha1_FeatureSummary(in *v1beta1.F
 	out.FailureMessage = (*string)(unsafe.Pointer(in.FailureMessage))
 	out.DeployedGroupVersionKind = *(*[]string)(unsafe.Pointer(&in.DeployedGroupVersionKind))
 	out.LastAppliedTime = (*v1.Time)(unsafe.Pointer(in.LastAppliedTime))
+	// WARNING: in.LastTransitionTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.ConsecutiveFailures requires manual conversion: does not exist in peer-type
+	// WARNING: in.NextRetryTime requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_FeatureSummary_To_v1alpha1_FeatureSummary is an autogenerated conversion function.
-func Convert_v1beta1_FeatureSummary_To_v1alpha1_FeatureSummary(in *v1beta1.FeatureSummary, out *FeatureSummary, s conversion.Scope) error {
-	return autoConvert_v1beta1_FeatureSummary_To_v1alpha1_FeatureSummary(in, out, s)
-}
-
 func autoConvert_v1alpha1_HelmChart_To_v1beta1_HelmChart(in *HelmChart, out *v1beta1.HelmChart, s conversion.Scope) error {
 	out.RepositoryURL = in.RepositoryURL
 	out.RepositoryName = in.RepositoryName
@@ -1008,9 +1179,13 @@ func autoConvert_v1beta1_HelmChart_To_v1alpha1_HelmChart(in *v1beta1.HelmChart,
 	out.ReleaseNamespace = in.ReleaseNamespace
 	out.Values = in.Values
 	out.ValuesFrom = *(*[]ValueFrom)(unsafe.Pointer(&in.ValuesFrom))
+	// WARNING: in.ValuesOverrides requires manual conversion: does not exist in peer-type
+	// WARNING: in.CreateNamespace requires manual conversion: does not exist in peer-type
 	out.HelmChartAction = HelmChartAction(in.HelmChartAction)
 	out.Options = (*HelmOptions)(unsafe.Pointer(in.Options))
 	// WARNING: in.RegistryCredentialsConfig requires manual conversion: does not exist in peer-type
+	// WARNING: in.Verify requires manual conversion: does not exist in peer-type
+	// WARNING: in.Tests requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -1034,14 +1209,10 @@ func autoConvert_v1beta1_HelmChartSummary_To_v1alpha1_HelmChartSummary(in *v1bet
 	out.Status = HelmChartStatus(in.Status)
 	out.ValuesHash = *(*[]byte)(unsafe.Pointer(&in.ValuesHash))
 	out.ConflictMessage = in.ConflictMessage
+	// WARNING: in.TestResult requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_HelmChartSummary_To_v1alpha1_HelmChartSummary is an autogenerated conversion function.
-func Convert_v1beta1_HelmChartSummary_To_v1alpha1_HelmChartSummary(in *v1beta1.HelmChartSummary, out *HelmChartSummary, s conversion.Scope) error {
-	return autoConvert_v1beta1_HelmChartSummary_To_v1alpha1_HelmChartSummary(in, out, s)
-}
-
 func autoConvert_v1alpha1_HelmInstallOptions_To_v1beta1_HelmInstallOptions(in *HelmInstallOptions, out *v1beta1.HelmInstallOptions, s conversion.Scope) error {
 	out.CreateNamespace = in.CreateNamespace
 	out.Replace = in.Replace
@@ -1232,14 +1403,10 @@ func autoConvert_v1beta1_PolicyRef_To_v1alpha1_PolicyRef(in *v1beta1.PolicyRef,
 	out.Kind = in.Kind
 	out.Path = in.Path
 	out.DeploymentType = DeploymentType(in.DeploymentType)
+	// WARNING: in.ConflictPolicy requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_PolicyRef_To_v1alpha1_PolicyRef is an autogenerated conversion function.
-func Convert_v1beta1_PolicyRef_To_v1alpha1_PolicyRef(in *v1beta1.PolicyRef, out *PolicyRef, s conversion.Scope) error {
-	return autoConvert_v1beta1_PolicyRef_To_v1alpha1_PolicyRef(in, out, s)
-}
-
 func autoConvert_v1alpha1_Profile_To_v1beta1_Profile(in *Profile, out *v1beta1.Profile, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha1_Spec_To_v1beta1_Spec(&in.Spec, &out.Spec, s); err != nil {
@@ -1316,7 +1483,17 @@ func Convert_v1beta1_ProfileList_To_v1alpha1_ProfileList(in *v1beta1.ProfileList
 
 func autoConvert_v1alpha1_ProfileResource_To_v1beta1_ProfileResource(in *ProfileResource, out *v1beta1.ProfileResource, s conversion.Scope) error {
 	out.ProfileName = in.ProfileName
-	out.Features = *(*[]v1beta1.Feature)(unsafe.Pointer(&in.Features))
+	if in.Features != nil {
+		in, out := &in.Features, &out.Features
+		*out = make([]v1beta1.Feature, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_Feature_To_v1beta1_Feature(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Features = nil
+	}
 	return nil
 }
 
@@ -1327,15 +1504,21 @@ func Convert_v1alpha1_ProfileResource_To_v1beta1_ProfileResource(in *ProfileReso
 
 func autoConvert_v1beta1_ProfileResource_To_v1alpha1_ProfileResource(in *v1beta1.ProfileResource, out *ProfileResource, s conversion.Scope) error {
 	out.ProfileName = in.ProfileName
-	out.Features = *(*[]Feature)(unsafe.Pointer(&in.Features))
+	if in.Features != nil {
+		in, out := &in.Features, &out.Features
+		*out = make([]Feature, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_Feature_To_v1alpha1_Feature(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Features = nil
+	}
+	// WARNING: in.SourceRevision requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_ProfileResource_To_v1alpha1_ProfileResource is an autogenerated conversion function.
-func Convert_v1beta1_ProfileResource_To_v1alpha1_ProfileResource(in *v1beta1.ProfileResource, out *ProfileResource, s conversion.Scope) error {
-	return autoConvert_v1beta1_ProfileResource_To_v1alpha1_ProfileResource(in, out, s)
-}
-
 func autoConvert_v1alpha1_ReleaseReport_To_v1beta1_ReleaseReport(in *ReleaseReport, out *v1beta1.ReleaseReport, s conversion.Scope) error {
 	out.ReleaseName = in.ReleaseName
 	out.ReleaseNamespace = in.ReleaseNamespace
@@ -1356,14 +1539,11 @@ func autoConvert_v1beta1_ReleaseReport_To_v1alpha1_ReleaseReport(in *v1beta1.Rel
 	out.ChartVersion = in.ChartVersion
 	out.Action = in.Action
 	out.Message = in.Message
+	// WARNING: in.ManifestDiff requires manual conversion: does not exist in peer-type
+	// WARNING: in.ValuesDiff requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_ReleaseReport_To_v1alpha1_ReleaseReport is an autogenerated conversion function.
-func Convert_v1beta1_ReleaseReport_To_v1alpha1_ReleaseReport(in *v1beta1.ReleaseReport, out *ReleaseReport, s conversion.Scope) error {
-	return autoConvert_v1beta1_ReleaseReport_To_v1alpha1_ReleaseReport(in, out, s)
-}
-
 func autoConvert_v1alpha1_Resource_To_v1beta1_Resource(in *Resource, out *v1beta1.Resource, s conversion.Scope) error {
 	out.Name = in.Name
 	out.Namespace = in.Namespace
@@ -1438,7 +1618,17 @@ func autoConvert_v1alpha1_Spec_To_v1beta1_Spec(in *Spec, out *v1beta1.Spec, s co
 	out.Reloader = in.Reloader
 	out.TemplateResourceRefs = *(*[]v1beta1.TemplateResourceRef)(unsafe.Pointer(&in.TemplateResourceRefs))
 	out.DependsOn = *(*[]string)(unsafe.Pointer(&in.DependsOn))
-	out.PolicyRefs = *(*[]v1beta1.PolicyRef)(unsafe.Pointer(&in.PolicyRefs))
+	if in.PolicyRefs != nil {
+		in, out := &in.PolicyRefs, &out.PolicyRefs
+		*out = make([]v1beta1.PolicyRef, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_PolicyRef_To_v1beta1_PolicyRef(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.PolicyRefs = nil
+	}
 	if in.HelmCharts != nil {
 		in, out := &in.HelmCharts, &out.HelmCharts
 		*out = make([]v1beta1.HelmChart, len(*in))
@@ -1451,7 +1641,17 @@ func autoConvert_v1alpha1_Spec_To_v1beta1_Spec(in *Spec, out *v1beta1.Spec, s co
 		out.HelmCharts = nil
 	}
 	out.KustomizationRefs = *(*[]v1beta1.KustomizationRef)(unsafe.Pointer(&in.KustomizationRefs))
-	out.ValidateHealths = *(*[]v1beta1.ValidateHealth)(unsafe.Pointer(&in.ValidateHealths))
+	if in.ValidateHealths != nil {
+		in, out := &in.ValidateHealths, &out.ValidateHealths
+		*out = make([]v1beta1.ValidateHealth, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ValidateHealth_To_v1beta1_ValidateHealth(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ValidateHealths = nil
+	}
 	out.ExtraLabels = *(*map[string]string)(unsafe.Pointer(&in.ExtraLabels))
 	out.ExtraAnnotations = *(*map[string]string)(unsafe.Pointer(&in.ExtraAnnotations))
 	return nil
@@ -1459,6 +1659,7 @@ func autoConvert_v1alpha1_Spec_To_v1beta1_Spec(in *Spec, out *v1beta1.Spec, s co
 
 func autoConvert_v1beta1_Spec_To_v1alpha1_Spec(in *v1beta1.Spec, out *Spec, s conversion.Scope) error {
 	// WARNING: in.ClusterSelector requires manual conversion: inconvertible types (github.com/projectsveltos/libsveltos/api/v1beta1.Selector vs github.com/projectsveltos/libsveltos/api/v1alpha1.Selector)
+	// WARNING: in.ClusterCELSelector requires manual conversion: does not exist in peer-type
 	out.ClusterRefs = *(*[]corev1.ObjectReference)(unsafe.Pointer(&in.ClusterRefs))
 	out.SetRefs = *(*[]string)(unsafe.Pointer(&in.SetRefs))
 	out.SyncMode = SyncMode(in.SyncMode)
@@ -1469,7 +1670,18 @@ func autoConvert_v1beta1_Spec_To_v1alpha1_Spec(in *v1beta1.Spec, out *Spec, s co
 	out.Reloader = in.Reloader
 	out.TemplateResourceRefs = *(*[]TemplateResourceRef)(unsafe.Pointer(&in.TemplateResourceRefs))
 	out.DependsOn = *(*[]string)(unsafe.Pointer(&in.DependsOn))
-	out.PolicyRefs = *(*[]PolicyRef)(unsafe.Pointer(&in.PolicyRefs))
+	if in.PolicyRefs != nil {
+		in, out := &in.PolicyRefs, &out.PolicyRefs
+		*out = make([]PolicyRef, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_PolicyRef_To_v1alpha1_PolicyRef(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.PolicyRefs = nil
+	}
+	// WARNING: in.SourceRef requires manual conversion: does not exist in peer-type
 	if in.HelmCharts != nil {
 		in, out := &in.HelmCharts, &out.HelmCharts
 		*out = make([]HelmChart, len(*in))
@@ -1482,11 +1694,43 @@ func autoConvert_v1beta1_Spec_To_v1alpha1_Spec(in *v1beta1.Spec, out *Spec, s co
 		out.HelmCharts = nil
 	}
 	out.KustomizationRefs = *(*[]KustomizationRef)(unsafe.Pointer(&in.KustomizationRefs))
-	out.ValidateHealths = *(*[]ValidateHealth)(unsafe.Pointer(&in.ValidateHealths))
+	if in.ValidateHealths != nil {
+		in, out := &in.ValidateHealths, &out.ValidateHealths
+		*out = make([]ValidateHealth, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_ValidateHealth_To_v1alpha1_ValidateHealth(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ValidateHealths = nil
+	}
+	// WARNING: in.PreDeploymentJobs requires manual conversion: does not exist in peer-type
+	// WARNING: in.PostDeploymentJobs requires manual conversion: does not exist in peer-type
 	// WARNING: in.Patches requires manual conversion: does not exist in peer-type
+	// WARNING: in.RegistryMirrors requires manual conversion: does not exist in peer-type
 	// WARNING: in.DriftExclusions requires manual conversion: does not exist in peer-type
+	// WARNING: in.DriftExcludedKinds requires manual conversion: does not exist in peer-type
+	// WARNING: in.DriftDetectionPlacement requires manual conversion: does not exist in peer-type
+	// WARNING: in.DriftDetectionResources requires manual conversion: does not exist in peer-type
 	out.ExtraLabels = *(*map[string]string)(unsafe.Pointer(&in.ExtraLabels))
 	out.ExtraAnnotations = *(*map[string]string)(unsafe.Pointer(&in.ExtraAnnotations))
+	// WARNING: in.Paused requires manual conversion: does not exist in peer-type
+	// WARNING: in.BaseProfile requires manual conversion: does not exist in peer-type
+	// WARNING: in.ClusterReadinessChecks requires manual conversion: does not exist in peer-type
+	// WARNING: in.MaxConcurrentClusterDeployments requires manual conversion: does not exist in peer-type
+	// WARNING: in.ClusterMetadataPropagations requires manual conversion: does not exist in peer-type
+	// WARNING: in.PublishChangeSummary requires manual conversion: does not exist in peer-type
+	// WARNING: in.RolloutRings requires manual conversion: does not exist in peer-type
+	// WARNING: in.RolloutFailureThreshold requires manual conversion: does not exist in peer-type
+	// WARNING: in.RollbackOnRolloutAbort requires manual conversion: does not exist in peer-type
+	// WARNING: in.EnforceInterval requires manual conversion: does not exist in peer-type
+	// WARNING: in.MaxConcurrentEnforcements requires manual conversion: does not exist in peer-type
+	// WARNING: in.DeploymentOrder requires manual conversion: does not exist in peer-type
+	// WARNING: in.TenantRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.Notifications requires manual conversion: does not exist in peer-type
+	// WARNING: in.MinControllerVersion requires manual conversion: does not exist in peer-type
+	// WARNING: in.TargetNamespaceMetadata requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -1514,14 +1758,17 @@ func autoConvert_v1beta1_Status_To_v1alpha1_Status(in *v1beta1.Status, out *Stat
 	if err := Convert_v1beta1_Clusters_To_v1alpha1_Clusters(&in.UpdatedClusters, &out.UpdatedClusters, s); err != nil {
 		return err
 	}
+	// WARNING: in.ClusterSummaries requires manual conversion: does not exist in peer-type
+	// WARNING: in.Rollout requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastRolledOutSpec requires manual conversion: does not exist in peer-type
+	// WARNING: in.Revision requires manual conversion: does not exist in peer-type
+	// WARNING: in.RevisionHistory requires manual conversion: does not exist in peer-type
+	// WARNING: in.Conditions requires manual conversion: does not exist in peer-type
+	// WARNING: in.ResolvedChartVersions requires manual conversion: does not exist in peer-type
+	// WARNING: in.ObservedGeneration requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_Status_To_v1alpha1_Status is an autogenerated conversion function.
-func Convert_v1beta1_Status_To_v1alpha1_Status(in *v1beta1.Status, out *Status, s conversion.Scope) error {
-	return autoConvert_v1beta1_Status_To_v1alpha1_Status(in, out, s)
-}
-
 func autoConvert_v1alpha1_TemplateResourceRef_To_v1beta1_TemplateResourceRef(in *TemplateResourceRef, out *v1beta1.TemplateResourceRef, s conversion.Scope) error {
 	out.Resource = in.Resource
 	out.Identifier = in.Identifier
@@ -1569,15 +1816,12 @@ func autoConvert_v1beta1_ValidateHealth_To_v1alpha1_ValidateHealth(in *v1beta1.V
 	out.Kind = in.Kind
 	out.LabelFilters = *(*[]apiv1alpha1.LabelFilter)(unsafe.Pointer(&in.LabelFilters))
 	out.Namespace = in.Namespace
+	// WARNING: in.ResourceName requires manual conversion: does not exist in peer-type
 	out.Script = in.Script
+	// WARNING: in.CELExpression requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_ValidateHealth_To_v1alpha1_ValidateHealth is an autogenerated conversion function.
-func Convert_v1beta1_ValidateHealth_To_v1alpha1_ValidateHealth(in *v1beta1.ValidateHealth, out *ValidateHealth, s conversion.Scope) error {
-	return autoConvert_v1beta1_ValidateHealth_To_v1alpha1_ValidateHealth(in, out, s)
-}
-
 func autoConvert_v1alpha1_ValueFrom_To_v1beta1_ValueFrom(in *ValueFrom, out *v1beta1.ValueFrom, s conversion.Scope) error {
 	out.Namespace = in.Namespace
 	out.Name = in.Name
@@ -21,6 +21,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
 
 const (
@@ -84,11 +86,57 @@ type Chart struct {
 	// +optional
 	AppVersion string `json:"appVersion,omitempty"`
 
+	// Revision is the revision of the helm release deployed in the Cluster.
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+
 	// The URL to an icon file.
 	Icon string `json:"icon,omitempty"`
 
 	// LastAppliedTime identifies when this resource was last applied to the cluster.
 	LastAppliedTime *metav1.Time `json:"lastAppliedTime"`
+
+	// Verification contains the result of the chart signature verification.
+	// Only set when HelmChart.Verify is set.
+	// +optional
+	Verification *ChartVerification `json:"verification,omitempty"`
+
+	// Values is a sanitized snapshot of the values the release is deployed with.
+	// +optional
+	Values *ChartValues `json:"values,omitempty"`
+}
+
+// ChartValues is a sanitized snapshot of the values a helm release is deployed with, so
+// operators can confirm which values a cluster actually received
+type ChartValues struct {
+	// Hash is the sha256 hash of all the values (including the redacted ones)
+	Hash string `json:"hash"`
+
+	// Values contains the values, flattened using dot notation (for instance image.tag).
+	// Values whose key suggests they are sensitive (password, token, secret, ...) are redacted.
+	// +optional
+	Values map[string]string `json:"values,omitempty"`
+
+	// Truncated is set when only part of the values is reported, to keep ClusterConfiguration small.
+	// Hash always covers all values.
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ChartVerification contains the result of a successful helm chart signature verification
+type ChartVerification struct {
+	// Provider is the verification method used
+	Provider HelmChartVerificationProvider `json:"provider"`
+
+	// SignedBy identifies the key the chart is signed with.
+	// For Provenance, the identity of the PGP key. For Cosign, the sha256 fingerprint of the public key.
+	// +optional
+	SignedBy string `json:"signedBy,omitempty"`
+
+	// Digest is the digest of the verified chart.
+	// For Provenance, the chart archive digest. For Cosign, the OCI manifest digest.
+	// +optional
+	Digest string `json:"digest,omitempty"`
 }
 
 type Feature struct {
@@ -114,6 +162,11 @@ type ProfileResource struct {
 	// of a given feature
 	// +optional
 	Features []Feature `json:"Features,omitempty"`
+
+	// SourceRevision is the revision of the SourceRef artifact resources
+	// were last successfully deployed from.
+	// +optional
+	SourceRevision string `json:"sourceRevision,omitempty"`
 }
 
 // ClusterProfileResource keeps info on all of the resources deployed in this Cluster
@@ -126,6 +179,22 @@ type ClusterProfileResource struct {
 	// of a given feature
 	// +optional
 	Features []Feature `json:"Features,omitempty"`
+
+	// SourceRevision is the revision of the SourceRef artifact resources
+	// were last successfully deployed from.
+	// +optional
+	SourceRevision string `json:"sourceRevision,omitempty"`
+}
+
+// ClusterConfigurationSpec defines the cluster a ClusterConfiguration is for
+type ClusterConfigurationSpec struct {
+	// ClusterName is the name of the workload Cluster this ClusterConfiguration is for.
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// ClusterType is the type of Cluster this ClusterConfiguration is for.
+	// +optional
+	ClusterType libsveltosv1beta1.ClusterType `json:"clusterType,omitempty"`
 }
 
 // ClusterConfigurationStatus defines the observed state of ClusterConfiguration
@@ -139,8 +208,25 @@ type ClusterConfigurationStatus struct {
 	// to Profiles
 	// +optional
 	ProfileResources []ProfileResource `json:"profileResources,omitempty"`
+
+	// Conditions reports cluster level conditions, like whether base add-ons
+	// (deployed by ClusterProfiles/Profiles with BaseProfile set) are ready
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+const (
+	// BaseAddOnsReadyCondition reports whether all ClusterProfiles/Profiles with BaseProfile set,
+	// matching the cluster, are provisioned. Till then, add-ons of other ClusterProfiles/Profiles
+	// are not deployed.
+	BaseAddOnsReadyCondition = "BaseAddOnsReady"
+
+	// BaseAddOnsFailedReason indicates deploying base add-ons failed
+	BaseAddOnsFailedReason = "BaseAddOnsFailed"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:path=clusterconfigurations,scope=Namespaced
 // +kubebuilder:subresource:status
@@ -151,6 +237,7 @@ type ClusterConfiguration struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
+	Spec   ClusterConfigurationSpec   `json:"spec,omitempty"`
 	Status ClusterConfigurationStatus `json:"status,omitempty"`
 }
 
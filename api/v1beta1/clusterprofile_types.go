@@ -26,6 +26,24 @@ const (
 	ClusterProfileFinalizer = "clusterprofilefinalizer.projectsveltos.io"
 
 	ClusterProfileKind = "ClusterProfile"
+
+	// CollectDebugBundleAnnotation can be set on a ClusterProfile/Profile to request a debug bundle.
+	// Sveltos collects the ClusterProfile/Profile, its ClusterSummaries and ClusterReports, deployment
+	// hashes and errors, and recent controller logs into a ConfigMap and then removes the annotation.
+	CollectDebugBundleAnnotation = "projectsveltos.io/collect-debug-bundle"
+
+	// RollbackToRevisionAnnotation can be set on a ClusterProfile/Profile to roll all matching
+	// clusters back to a revision in Status.RevisionHistory. Sveltos restores the Spec of that
+	// revision and then removes the annotation. For instance:
+	// kubectl annotate clusterprofile <name> projectsveltos.io/rollback-to-revision=3
+	RollbackToRevisionAnnotation = "projectsveltos.io/rollback-to-revision"
+
+	// PlanAnnotation can be set on a ClusterProfile/Profile to get, in ClusterReports, what Sveltos
+	// would change in each matching cluster without switching SyncMode to DryRun (which pauses
+	// reconciliation). Sveltos passes the request to the ClusterSummaries, removes the annotation,
+	// and each ClusterSummary removes it once its ClusterReport is updated. For instance:
+	// kubectl annotate clusterprofile <name> projectsveltos.io/plan=now
+	PlanAnnotation = "projectsveltos.io/plan"
 )
 
 // +kubebuilder:object:root=true
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
 
 // HelmAction represents the type of action on a give resource or helm release
@@ -65,6 +67,19 @@ type ReleaseReport struct {
 	// explain the action.
 	// +optional
 	Message string `json:"message,omitempty"`
+
+	// ManifestDiff contains the diff between the manifest of the helm release
+	// currently deployed in the CAPI Cluster and the manifest which would be deployed.
+	// Manifest is rendered with helm template and validated with a server-side dry-run.
+	// +optional
+	ManifestDiff string `json:"manifestDiff,omitempty"`
+
+	// ValuesDiff contains the diff between the values used by the helm release
+	// currently deployed in the CAPI Cluster and the values which would be used.
+	// Values are flattened, one path per line. Sensitive values and values coming
+	// from Secrets are redacted.
+	// +optional
+	ValuesDiff string `json:"valuesDiff,omitempty"`
 }
 
 type ResourceReport struct {
@@ -81,6 +96,21 @@ type ResourceReport struct {
 	Message string `json:"message,omitempty"`
 }
 
+// Conformance summarizes how much a cluster conforms to the desired state
+// defined by a ClusterProfile/Profile
+type Conformance struct {
+	// Matching is the number of resources and helm releases already matching
+	// the desired state
+	Matching int32 `json:"matching"`
+
+	// Total is the number of resources and helm releases evaluated
+	Total int32 `json:"total"`
+
+	// Score is the percentage of resources and helm releases matching the
+	// desired state. 100 when nothing was evaluated.
+	Score int32 `json:"score"`
+}
+
 // ClusterReportSpec defines the desired state of ClusterReport
 type ClusterReportSpec struct {
 	// ClusterNamespace is the namespace of the CAPI Cluster this
@@ -90,6 +120,10 @@ type ClusterReportSpec struct {
 	// ClusterName is the name of the CAPI Cluster this ClusterReport
 	// is for.
 	ClusterName string `json:"clusterName"`
+
+	// ClusterType is the type of Cluster this ClusterReport is for.
+	// +optional
+	ClusterType libsveltosv1beta1.ClusterType `json:"clusterType,omitempty"`
 }
 
 // ClusterReportStatus defines the observed state of ClusterReport
@@ -107,6 +141,11 @@ type ClusterReportStatus struct {
 	// deployed because of KustomizationRefs
 	// +optional
 	KustomizeResourceReports []ResourceReport `json:"kustomizeResourceReports,omitempty"`
+
+	// Conformance is set only when SyncMode is AssessOnly.
+	// It summarizes how much the cluster conforms to the desired state.
+	// +optional
+	Conformance *Conformance `json:"conformance,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -36,9 +36,118 @@ const (
 	ClusterSummaryFinalizer = "clustersummaryfinalizer.projectsveltos.io"
 
 	ClusterSummaryKind = "ClusterSummary"
+
+	// PausedAnnotation can be set on a ClusterSummary to freeze deployments to the corresponding
+	// cluster only. Sveltos preserves it when the ClusterSummary is updated because of changes in
+	// the owner ClusterProfile/Profile.
+	PausedAnnotation = "projectsveltos.io/paused"
+
+	// PinnedChartVersionsAnnotation can be set on a ClusterSummary to temporarily deploy, in the
+	// corresponding cluster only, helm chart versions different from the ones in the owner
+	// ClusterProfile/Profile (for instance because of an incompatibility). Value is a comma separated
+	// list of <release namespace>/<release name>=<chart version>. Sveltos preserves it when the
+	// ClusterSummary is updated because of changes in the owner ClusterProfile/Profile.
+	PinnedChartVersionsAnnotation = "projectsveltos.io/pinned-chart-versions"
+
+	// ProxyURLAnnotation can be set on a Cluster (SveltosCluster or ClusterAPI Cluster) only reachable
+	// via a bastion. Its value is the URL of the HTTP (CONNECT) or SOCKS5 proxy used to reach the cluster
+	// API server, for instance http://bastion.example.com:3128 or socks5://bastion.example.com:1080.
+	// It takes precedence over any proxy-url set in the cluster kubeconfig.
+	ProxyURLAnnotation = "projectsveltos.io/proxy-url"
+
+	// RegistryMirrorsAnnotation can be set on a Cluster (SveltosCluster or ClusterAPI Cluster) to rewrite
+	// the registry of container images deployed in the cluster. Value is a comma separated list of
+	// <registry>=<mirror>, for instance docker.io=mirror.example.com/dockerhub,ghcr.io=mirror.example.com/ghcr.
+	// Those take precedence over the RegistryMirrors set in the ClusterProfile/Profile.
+	RegistryMirrorsAnnotation = "projectsveltos.io/registry-mirrors"
+)
+
+const (
+	// ClusterSummaryPausedCondition reports whether ClusterSummary is currently paused
+	ClusterSummaryPausedCondition = "Paused"
+
+	// PausedByClusterReason indicates the managed cluster is paused
+	PausedByClusterReason = "ClusterPaused"
+
+	// PausedByProfileReason indicates the owner ClusterProfile/Profile has Spec.Paused set
+	PausedByProfileReason = "ProfilePaused"
+
+	// PausedByAnnotationReason indicates the ClusterSummary has the pause annotation
+	PausedByAnnotationReason = "PausedAnnotation"
+
+	// PausedByMaintenanceWindowReason indicates the managed cluster matches at least one
+	// MaintenanceWindow and none of those is currently open
+	PausedByMaintenanceWindowReason = "OutsideMaintenanceWindow"
+
+	// PausedByControllerVersionReason indicates the controller is older than the
+	// ClusterProfile/Profile Spec.MinControllerVersion
+	PausedByControllerVersionReason = "ControllerVersionTooOld"
+
+	// NotPausedReason indicates the ClusterSummary is not paused
+	NotPausedReason = "NotPaused"
+)
+
+const (
+	// ConnectionDownCondition reports whether the managed cluster cannot be reached. It allows
+	// to distinguish an add-on failing to be deployed from the cluster being unreachable.
+	ConnectionDownCondition = "ConnectionDown"
+
+	// KubeconfigInvalidReason indicates the kubeconfig to access the managed cluster is
+	// missing or cannot be parsed
+	KubeconfigInvalidReason = "KubeconfigInvalid"
+
+	// APIServerUnreachableReason indicates the managed cluster API server did not answer
+	APIServerUnreachableReason = "APIServerUnreachable"
+
+	// ConnectedReason indicates the managed cluster API server was successfully reached
+	ConnectedReason = "Connected"
+)
+
+const (
+	// HelmProvisionedCondition reports whether all helm charts are provisioned
+	HelmProvisionedCondition = "HelmProvisioned"
+
+	// ResourcesProvisionedCondition reports whether all resources referenced in PolicyRefs are provisioned
+	ResourcesProvisionedCondition = "ResourcesProvisioned"
+
+	// KustomizeProvisionedCondition reports whether all resources referenced in KustomizationRefs are provisioned
+	KustomizeProvisionedCondition = "KustomizeProvisioned"
+
+	// ClusterMetadataProvisionedCondition reports whether all ClusterMetadataPropagations are provisioned
+	ClusterMetadataProvisionedCondition = "ClusterMetadataProvisioned"
+
+	// DriftDetectionDeployedCondition reports whether drift-detection-manager is deployed for the
+	// managed cluster. Set only when SyncMode is ContinuousWithDriftDetection.
+	DriftDetectionDeployedCondition = "DriftDetectionDeployed"
+
+	// ProvisionedReason indicates the feature is provisioned
+	ProvisionedReason = "Provisioned"
+
+	// ProvisioningReason indicates the feature is being provisioned
+	ProvisioningReason = "Provisioning"
+
+	// ProvisioningFailedReason indicates provisioning the feature failed. It will be retried.
+	ProvisioningFailedReason = "ProvisioningFailed"
+
+	// ProvisioningFailedNonRetriableReason indicates provisioning the feature failed and won't be retried
+	ProvisioningFailedNonRetriableReason = "ProvisioningFailedNonRetriable"
+
+	// RemovingReason indicates the feature is being removed
+	RemovingReason = "Removing"
+
+	// RemovedReason indicates the feature has been removed
+	RemovedReason = "Removed"
 )
 
-// +kubebuilder:validation:Enum:=Resources;Helm;Kustomize
+// FeatureConditionTypes maps each feature to the ClusterSummary condition type reporting its status
+var FeatureConditionTypes = map[FeatureID]string{
+	FeatureHelm:            HelmProvisionedCondition,
+	FeatureResources:       ResourcesProvisionedCondition,
+	FeatureKustomize:       KustomizeProvisionedCondition,
+	FeatureClusterMetadata: ClusterMetadataProvisionedCondition,
+}
+
+// +kubebuilder:validation:Enum:=Resources;Helm;Kustomize;ClusterMetadata
 type FeatureID string
 
 const (
@@ -50,6 +159,10 @@ const (
 
 	// FeatureKustomize is the identifier for Kustomize feature
 	FeatureKustomize = FeatureID("Kustomize")
+
+	// FeatureClusterMetadata is the identifier for the feature propagating Cluster
+	// labels/annotations to resources in the managed cluster
+	FeatureClusterMetadata = FeatureID("ClusterMetadata")
 )
 
 // +kubebuilder:validation:Enum:=Provisioning;Provisioned;Failed;FailedNonRetriable;Removing;Removed
@@ -113,6 +226,20 @@ type FeatureSummary struct {
 	// LastAppliedTime is the time feature was last reconciled
 	// +optional
 	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	// LastTransitionTime is the last time Status changed
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// ConsecutiveFailures is the number of consecutive failed attempts to deploy
+	// this feature. It is reset when deployment succeeds or configuration changes.
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// NextRetryTime is the earliest time Sveltos will retry deploying this feature
+	// after a failure.
+	// +optional
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
 }
 
 type FeatureDeploymentInfo struct {
@@ -124,6 +251,18 @@ type FeatureDeploymentInfo struct {
 	// Each element has format kind.version.group
 	// +optional
 	DeployedGroupVersionKind []string `json:"deployedGroupVersionKind,omitempty"`
+
+	// DeployedResources is the inventory of resources currently deployed in the
+	// managed cluster because of this feature. Resources not deployed anymore are pruned.
+	// Each element has format kind.version.group:namespace:name
+	// +optional
+	DeployedResources []string `json:"deployedResources,omitempty"`
+
+	// DeployedManagementClusterResources is the inventory of resources currently deployed
+	// in the management cluster because of this feature.
+	// Each element has format kind.version.group:namespace:name
+	// +optional
+	DeployedManagementClusterResources []string `json:"deployedManagementClusterResources,omitempty"`
 }
 
 // HelChartStatus specifies whether ClusterSummary is successfully managing
@@ -161,6 +300,32 @@ type HelmChartSummary struct {
 	// chart or there is a conflict
 	// +optional
 	ConflictMessage string `json:"conflictMessage,omitempty"`
+
+	// TestResult reports the result of the last run of the chart tests.
+	// Only set when HelmChart.Tests is set.
+	// +optional
+	TestResult *HelmTestResult `json:"testResult,omitempty"`
+}
+
+// HelmTestResult is the result of running the chart tests (helm test) against a release revision
+type HelmTestResult struct {
+	// Revision is the release revision tests ran against
+	Revision int64 `json:"revision"`
+
+	// Succeeded is true if all tests succeeded
+	Succeeded bool `json:"succeeded"`
+
+	// FailedTests lists the names of the tests which failed
+	// +listType=atomic
+	// +optional
+	FailedTests []string `json:"failedTests,omitempty"`
+
+	// FailureMessage reports why tests failed
+	// +optional
+	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// LastRunTime is the time tests were last run
+	LastRunTime metav1.Time `json:"lastRunTime"`
 }
 
 // ClusterSummarySpec defines the desired state of ClusterSummary
@@ -178,6 +343,89 @@ type ClusterSummarySpec struct {
 	// ClusterProfileSpec represent the configuration that will be applied to
 	// the workload cluster.
 	ClusterProfileSpec Spec `json:"clusterProfileSpec,omitempty"`
+
+	// ProfileRevision is the revision of the ClusterProfile/Profile Spec ClusterProfileSpec
+	// was rendered from.
+	// +optional
+	ProfileRevision int64 `json:"profileRevision,omitempty"`
+}
+
+// ClusterConnectionStatus reports the health of the connection to a managed cluster
+type ClusterConnectionStatus struct {
+	// KubeconfigValid indicates whether the kubeconfig to access the managed cluster
+	// was found and could be parsed
+	KubeconfigValid bool `json:"kubeconfigValid"`
+
+	// APIServerReachable indicates whether the managed cluster API server answered
+	// the last connection check
+	APIServerReachable bool `json:"apiServerReachable"`
+
+	// LastSuccessfulConnectionTime is the last time the managed cluster API server
+	// was successfully reached. It is refreshed at most every few minutes.
+	// +optional
+	LastSuccessfulConnectionTime *metav1.Time `json:"lastSuccessfulConnectionTime,omitempty"`
+}
+
+// DriftAction is the action Sveltos took when a configuration drift was detected
+type DriftAction string
+
+const (
+	// DriftActionRedeploy indicates the feature was redeployed, reverting the drift
+	DriftActionRedeploy = DriftAction("Redeploy")
+)
+
+// DriftedResource identifies a resource in the managed cluster which drifted
+type DriftedResource struct {
+	// Group of the resource
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Version of the resource
+	Version string `json:"version"`
+
+	// Kind of the resource
+	Kind string `json:"kind"`
+
+	// Namespace of the resource. Empty for resources scoped at cluster level.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the resource
+	Name string `json:"name"`
+}
+
+// DriftEvent is a configuration drift reported by drift-detection-manager
+type DriftEvent struct {
+	// FeatureID is the feature whose resources drifted
+	FeatureID FeatureID `json:"featureID"`
+
+	// Resources lists the resources which drifted. Empty when it could not be
+	// determined which resources changed.
+	// +listType=atomic
+	// +optional
+	Resources []DriftedResource `json:"resources,omitempty"`
+
+	// DetectionTime is the time the drift was processed
+	DetectionTime metav1.Time `json:"detectionTime"`
+
+	// Action is what Sveltos did because of the drift
+	Action DriftAction `json:"action"`
+}
+
+// DriftStatus summarizes the configuration drifts detected in the managed cluster
+type DriftStatus struct {
+	// DriftCount is the number of configuration drifts detected
+	DriftCount int64 `json:"driftCount"`
+
+	// LastDriftTime is the last time a configuration drift was detected
+	// +optional
+	LastDriftTime *metav1.Time `json:"lastDriftTime,omitempty"`
+
+	// RecentDrifts lists the most recent configuration drifts, oldest first.
+	// Only the last few drifts are kept.
+	// +listType=atomic
+	// +optional
+	RecentDrifts []DriftEvent `json:"recentDrifts,omitempty"`
 }
 
 // ClusterSummaryStatus defines the observed state of ClusterSummary
@@ -205,6 +453,44 @@ type ClusterSummaryStatus struct {
 	// +listType=atomic
 	// +optional
 	HelmReleaseSummaries []HelmChartSummary `json:"helmReleaseSummaries,omitempty"`
+
+	// Conditions reports ClusterSummary conditions, like whether deployments are paused
+	// and, for each feature, whether it is provisioned (HelmProvisioned, ResourcesProvisioned,
+	// KustomizeProvisioned, ClusterMetadataProvisioned and DriftDetectionDeployed)
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Connection reports the health of the connection to the managed cluster.
+	// +optional
+	Connection *ClusterConnectionStatus `json:"connection,omitempty"`
+
+	// Drift summarizes the configuration drifts detected by drift-detection-manager
+	// when SyncMode is ContinuousWithDriftDetection.
+	// +optional
+	Drift *DriftStatus `json:"drift,omitempty"`
+
+	// LastEnforcedTime is the last time all features were re-applied to the cluster because
+	// of ClusterProfile/Profile EnforceInterval.
+	// +optional
+	LastEnforcedTime *metav1.Time `json:"lastEnforcedTime,omitempty"`
+
+	// ObservedRevision is the ClusterProfile/Profile revision all features were last
+	// successfully provisioned for.
+	// +optional
+	ObservedRevision int64 `json:"observedRevision,omitempty"`
+
+	// QueuePosition, when set, indicates ClusterSummary is waiting for a deployment slot
+	// (ClusterProfile/Profile MaxConcurrentClusterDeployments) and reports its approximate
+	// position among the ClusterSummaries waiting.
+	// +optional
+	QueuePosition *int32 `json:"queuePosition,omitempty"`
+
+	// SourceRevision is the revision of the ClusterProfile/Profile SourceRef artifact
+	// resources were last successfully deployed from.
+	// +optional
+	SourceRevision string `json:"sourceRevision,omitempty"`
 }
 
 //nolint: lll // marker
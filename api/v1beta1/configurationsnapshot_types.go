@@ -0,0 +1,140 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ConfigurationSnapshotKind = "ConfigurationSnapshot"
+
+	// RestoreSnapshotAnnotation, when set on a ConfigurationSnapshot, rolls the management
+	// cluster configuration back to the snapshot. Annotation is removed once the restore
+	// succeeds.
+	RestoreSnapshotAnnotation = "projectsveltos.io/restore-snapshot"
+)
+
+// SnapshotChange describes how an object differs between two snapshots
+// +kubebuilder:validation:Enum:=Added;Removed;Modified
+type SnapshotChange string
+
+const (
+	// SnapshotChangeAdded indicates the object is only present in the newer snapshot
+	SnapshotChangeAdded = SnapshotChange("Added")
+
+	// SnapshotChangeRemoved indicates the object is only present in the older snapshot
+	SnapshotChangeRemoved = SnapshotChange("Removed")
+
+	// SnapshotChangeModified indicates the object is present in both snapshots with
+	// different content
+	SnapshotChangeModified = SnapshotChange("Modified")
+)
+
+// ConfigurationSnapshotSpec defines the desired state of ConfigurationSnapshot
+type ConfigurationSnapshotSpec struct {
+	// CompareTo is the name of another ConfigurationSnapshot. When set, the differences
+	// between that snapshot (considered the older one) and this one are reported in Status.
+	// +optional
+	CompareTo string `json:"compareTo,omitempty"`
+}
+
+// SnapshotDifference is an object whose configuration differs between two snapshots
+type SnapshotDifference struct {
+	// Kind of the object (ClusterProfile, Profile, ConfigMap, Secret or HelmRelease)
+	Kind string `json:"kind"`
+
+	// Namespace of the object. Empty for cluster-wide objects.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the object
+	Name string `json:"name"`
+
+	// Cluster is set for HelmRelease only and identifies the managed cluster
+	// (in the form clusterType:namespace/name) where the release is deployed.
+	// +optional
+	Cluster string `json:"cluster,omitempty"`
+
+	// Change describes how the object differs
+	Change SnapshotChange `json:"change"`
+}
+
+// ConfigurationSnapshotStatus defines the observed state of ConfigurationSnapshot
+type ConfigurationSnapshotStatus struct {
+	// CaptureTime is when the configuration was captured. Content of a snapshot
+	// never changes once captured.
+	// +optional
+	CaptureTime *metav1.Time `json:"captureTime,omitempty"`
+
+	// SecretName is the name of the Secret, in the projectsveltos namespace, containing
+	// the captured configuration.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// ObjectCount is the number of ClusterProfiles, Profiles, ConfigMaps and Secrets
+	// captured
+	// +optional
+	ObjectCount int32 `json:"objectCount,omitempty"`
+
+	// ComparedTo is the ConfigurationSnapshot Differences were computed against
+	// +optional
+	ComparedTo string `json:"comparedTo,omitempty"`
+
+	// Differences lists the objects which differ between ComparedTo and this snapshot
+	// +listType=atomic
+	// +optional
+	Differences []SnapshotDifference `json:"differences,omitempty"`
+
+	// LastRestoreTime is the last time the management cluster configuration was rolled
+	// back to this snapshot
+	// +optional
+	LastRestoreTime *metav1.Time `json:"lastRestoreTime,omitempty"`
+
+	// FailureMessage provides more information about last failure, if any
+	// +optional
+	FailureMessage *string `json:"failureMessage,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=configurationsnapshots,scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+
+// ConfigurationSnapshot is the Schema for the configurationsnapshots API.
+// A ConfigurationSnapshot captures, when created, all ClusterProfiles, Profiles, the
+// ConfigMaps/Secrets they reference and which ClusterSummary manages each helm release.
+type ConfigurationSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ConfigurationSnapshotSpec   `json:"spec,omitempty"`
+	Status ConfigurationSnapshotStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ConfigurationSnapshotList contains a list of ConfigurationSnapshot
+type ConfigurationSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ConfigurationSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ConfigurationSnapshot{}, &ConfigurationSnapshotList{})
+}
@@ -19,12 +19,14 @@ package index
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
 
 const (
@@ -35,8 +37,21 @@ const (
 	// ClusterNameField is used by the ClusterSummary Controller to index ClusterSummary
 	// by CAPI Cluster name, and add a watch on CAPI Cluster.
 	ClusterNameField = ".spec.clusterName"
+
+	// ClusterTypeField is used to index ClusterSummary by cluster type.
+	ClusterTypeField = ".spec.clusterType"
+
+	// ClusterField is used to index ClusterSummary, ClusterReport and ClusterConfiguration
+	// by cluster type, namespace and name. Identically named clusters of different types
+	// have different values.
+	ClusterField = ".spec.cluster"
 )
 
+// ClusterIndexValue returns the value indexed by ClusterField for a cluster
+func ClusterIndexValue(clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType) string {
+	return fmt.Sprintf("%s:%s/%s", strings.ToLower(string(clusterType)), clusterNamespace, clusterName)
+}
+
 // ByClusterNamespace adds the CAPI Cluster namespace index to the
 // managers cache.
 func ByClusterNamespace(ctx context.Context, mgr ctrl.Manager) error {
@@ -63,6 +78,44 @@ func ByClusterName(ctx context.Context, mgr ctrl.Manager) error {
 	return nil
 }
 
+// ByClusterType adds the cluster type index to the managers cache.
+func ByClusterType(ctx context.Context, mgr ctrl.Manager) error {
+	if err := mgr.GetCache().IndexField(ctx, &configv1beta1.ClusterSummary{},
+		ClusterTypeField,
+		clusterSummaryByClusterType,
+	); err != nil {
+		return errors.Wrap(err, "error setting index field")
+	}
+
+	return nil
+}
+
+// ByCluster adds the cluster (type, namespace and name) index to the managers cache.
+func ByCluster(ctx context.Context, mgr ctrl.Manager) error {
+	if err := mgr.GetCache().IndexField(ctx, &configv1beta1.ClusterSummary{},
+		ClusterField,
+		clusterSummaryByCluster,
+	); err != nil {
+		return errors.Wrap(err, "error setting index field")
+	}
+
+	if err := mgr.GetCache().IndexField(ctx, &configv1beta1.ClusterReport{},
+		ClusterField,
+		clusterReportByCluster,
+	); err != nil {
+		return errors.Wrap(err, "error setting index field")
+	}
+
+	if err := mgr.GetCache().IndexField(ctx, &configv1beta1.ClusterConfiguration{},
+		ClusterField,
+		clusterConfigurationByCluster,
+	); err != nil {
+		return errors.Wrap(err, "error setting index field")
+	}
+
+	return nil
+}
+
 func clusterSummaryByClusterNamespace(o client.Object) []string {
 	clusterSummary, ok := o.(*configv1beta1.ClusterSummary)
 	if !ok {
@@ -80,3 +133,52 @@ func clusterSummaryByClusterName(o client.Object) []string {
 
 	return []string{clusterSummary.Spec.ClusterName}
 }
+
+func clusterSummaryByClusterType(o client.Object) []string {
+	clusterSummary, ok := o.(*configv1beta1.ClusterSummary)
+	if !ok {
+		panic(fmt.Sprintf("Expected a ClusterSummary but got a %T", o))
+	}
+
+	return []string{string(clusterSummary.Spec.ClusterType)}
+}
+
+func clusterSummaryByCluster(o client.Object) []string {
+	clusterSummary, ok := o.(*configv1beta1.ClusterSummary)
+	if !ok {
+		panic(fmt.Sprintf("Expected a ClusterSummary but got a %T", o))
+	}
+
+	return []string{ClusterIndexValue(clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+		clusterSummary.Spec.ClusterType)}
+}
+
+func clusterReportByCluster(o client.Object) []string {
+	clusterReport, ok := o.(*configv1beta1.ClusterReport)
+	if !ok {
+		panic(fmt.Sprintf("Expected a ClusterReport but got a %T", o))
+	}
+
+	// ClusterReports created before ClusterType was introduced are not indexed
+	if clusterReport.Spec.ClusterType == "" {
+		return nil
+	}
+
+	return []string{ClusterIndexValue(clusterReport.Spec.ClusterNamespace, clusterReport.Spec.ClusterName,
+		clusterReport.Spec.ClusterType)}
+}
+
+func clusterConfigurationByCluster(o client.Object) []string {
+	clusterConfiguration, ok := o.(*configv1beta1.ClusterConfiguration)
+	if !ok {
+		panic(fmt.Sprintf("Expected a ClusterConfiguration but got a %T", o))
+	}
+
+	// ClusterConfigurations created before Spec was introduced are not indexed
+	if clusterConfiguration.Spec.ClusterName == "" || clusterConfiguration.Spec.ClusterType == "" {
+		return nil
+	}
+
+	return []string{ClusterIndexValue(clusterConfiguration.Namespace, clusterConfiguration.Spec.ClusterName,
+		clusterConfiguration.Spec.ClusterType)}
+}
@@ -32,5 +32,13 @@ func AddDefaultIndexes(ctx context.Context, mgr ctrl.Manager) error {
 		return err
 	}
 
+	if err := ByClusterType(ctx, mgr); err != nil {
+		return err
+	}
+
+	if err := ByCluster(ctx, mgr); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,69 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	MaintenanceWindowKind = "MaintenanceWindow"
+)
+
+// MaintenanceWindowSpec defines when add-ons can be deployed to matching clusters
+type MaintenanceWindowSpec struct {
+	// ClusterSelector identifies clusters this MaintenanceWindow applies to.
+	// Clusters matching at least one MaintenanceWindow are updated only while
+	// one of those is open. Changes happening outside are applied once a
+	// window opens.
+	ClusterSelector libsveltosv1beta1.Selector `json:"clusterSelector"`
+
+	// Schedule, in Cron format (minute hour day-of-month month day-of-week),
+	// indicates when the window opens. Times are in UTC.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open every time it opens.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=maintenancewindows,scope=Cluster
+// +kubebuilder:storageversion
+
+// MaintenanceWindow is the Schema for the maintenancewindows API
+type MaintenanceWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MaintenanceWindowSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// MaintenanceWindowList contains a list of MaintenanceWindow
+type MaintenanceWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenanceWindow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenanceWindow{}, &MaintenanceWindowList{})
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MessageCode is a stable, machine-readable identifier of a condition or event message.
+// Human readable text might change between releases, codes never do. Messages carrying a
+// code have format "[SVExxxx] text". Codes are grouped by area:
+// - SVE1xxx: feature deployment
+// - SVE2xxx: ClusterSummary
+// - SVE3xxx: rollout
+type MessageCode string
+
+const (
+	// MessageCodeProvisioningFailed indicates a feature failed to be deployed
+	MessageCodeProvisioningFailed = MessageCode("SVE1000")
+
+	// MessageCodeChartPullFailed indicates a helm chart could not be pulled or loaded
+	MessageCodeChartPullFailed = MessageCode("SVE1001")
+
+	// MessageCodeTemplateFailed indicates a template could not be instantiated
+	MessageCodeTemplateFailed = MessageCode("SVE1002")
+
+	// MessageCodeResourceConflict indicates a resource is already managed by another
+	// ClusterProfile/Profile
+	MessageCodeResourceConflict = MessageCode("SVE1003")
+
+	// MessageCodeHealthCheckFailed indicates a ValidateHealths check failed
+	MessageCodeHealthCheckFailed = MessageCode("SVE1004")
+
+	// MessageCodeProvisioningFailedNonRetriable indicates a feature failed to be deployed
+	// and deployment won't be retried
+	MessageCodeProvisioningFailedNonRetriable = MessageCode("SVE1005")
+
+	// MessageCodeChartRepositoryRateLimited indicates a helm chart repository kept rate
+	// limiting (HTTP 429) requests
+	MessageCodeChartRepositoryRateLimited = MessageCode("SVE1006")
+
+	// MessageCodeChartRepositoryUnavailable indicates a helm chart repository kept failing
+	// requests with a transient error (HTTP 408 or 5xx)
+	MessageCodeChartRepositoryUnavailable = MessageCode("SVE1007")
+
+	// MessageCodeChartRepositoryRejected indicates a helm chart repository failed a request
+	// with a permanent error (HTTP 4xx, for instance chart not found or unauthorized)
+	MessageCodeChartRepositoryRejected = MessageCode("SVE1008")
+
+	// MessageCodeChartVerificationFailed indicates a helm chart signature could not be verified
+	MessageCodeChartVerificationFailed = MessageCode("SVE1009")
+
+	// MessageCodeHelmTestFailed indicates the tests (helm test) of a helm chart failed
+	MessageCodeHelmTestFailed = MessageCode("SVE1010")
+
+	// MessageCodeClusterPaused indicates the managed cluster is paused
+	MessageCodeClusterPaused = MessageCode("SVE2001")
+
+	// MessageCodeProfilePaused indicates the owner ClusterProfile/Profile is paused
+	MessageCodeProfilePaused = MessageCode("SVE2002")
+
+	// MessageCodePausedByAnnotation indicates the ClusterSummary has the pause annotation
+	MessageCodePausedByAnnotation = MessageCode("SVE2003")
+
+	// MessageCodeOutsideMaintenanceWindow indicates the managed cluster is outside its
+	// maintenance windows
+	MessageCodeOutsideMaintenanceWindow = MessageCode("SVE2004")
+
+	// MessageCodeKubeconfigInvalid indicates the kubeconfig to access the managed cluster
+	// is missing or invalid
+	MessageCodeKubeconfigInvalid = MessageCode("SVE2005")
+
+	// MessageCodeAPIServerUnreachable indicates the managed cluster API server cannot be reached
+	MessageCodeAPIServerUnreachable = MessageCode("SVE2006")
+
+	// MessageCodeFailureThresholdExceeded indicates a rollout was aborted because more
+	// clusters than RolloutFailureThreshold failed
+	MessageCodeFailureThresholdExceeded = MessageCode("SVE3001")
+
+	// MessageCodeRolloutRingAborted indicates a rollout was aborted because more clusters
+	// than MaxFailures failed in a RolloutRing
+	MessageCodeRolloutRingAborted = MessageCode("SVE3002")
+
+	// MessageCodeRolledBack indicates ClusterProfile/Profile was rolled back to a revision
+	MessageCodeRolledBack = MessageCode("SVE3003")
+
+	// MessageCodeRollbackFailed indicates ClusterProfile/Profile could not be rolled back
+	MessageCodeRollbackFailed = MessageCode("SVE3004")
+)
+
+var messageCodeRegexp = regexp.MustCompile(`^\[(SVE[0-9]{4})\] `)
+
+// FormatMessage returns message prefixed with code
+func FormatMessage(code MessageCode, message string) string {
+	return fmt.Sprintf("[%s] %s", code, message)
+}
+
+// GetMessageCode returns the code of a message formatted with FormatMessage.
+// An empty code is returned if message carries no code.
+func GetMessageCode(message string) MessageCode {
+	match := messageCodeRegexp.FindStringSubmatch(message)
+	if match == nil {
+		return ""
+	}
+	return MessageCode(match[1])
+}
@@ -36,8 +36,20 @@ const (
 	// - ClusterConfiguration instances created by a ClusterProfile instance for a given cluster;
 	// - ClusterReport instances created by a ClusterProfile instance for a given cluster;
 	ClusterTypeLabel = "projectsveltos.io/cluster-type"
+
+	// ManagementClusterName is the name of the pseudo-cluster representing the management cluster
+	// itself. A SveltosCluster with this name listed in ClusterRefs matches the management cluster
+	// even though no such SveltosCluster exists. Add-ons are then deployed using the in-cluster
+	// client, without registering the management cluster.
+	ManagementClusterName = "sveltos-management-cluster"
 )
 
+// IsManagementCluster returns true if cluster is the pseudo-cluster representing
+// the management cluster itself
+func IsManagementCluster(clusterName string, clusterType libsveltosv1beta1.ClusterType) bool {
+	return clusterName == ManagementClusterName && clusterType == libsveltosv1beta1.ClusterTypeSveltos
+}
+
 type DryRunReconciliationError struct{}
 
 func (m *DryRunReconciliationError) Error() string {
@@ -77,15 +89,46 @@ type ValidateHealth struct {
 	// +optional
 	Namespace string `json:"namespace,omitempty"`
 
+	// ResourceName, when set, restricts this check to the resource with such name.
+	// +optional
+	ResourceName string `json:"resourceName,omitempty"`
+
 	// Script is a text containing a lua script.
 	// Must return struct with field "health"
 	// representing whether object is a match (true or false)
 	// +optional
 	Script string `json:"script,omitempty"`
+
+	// CELExpression is a CEL expression evaluated against each fetched resource, available
+	// as obj. It must return true if resource is healthy.
+	// For instance: obj.status.availableReplicas >= 1
+	// When both Script and CELExpression are set, resource must pass both.
+	// +optional
+	CELExpression string `json:"celExpression,omitempty"`
+}
+
+// DeploymentJob references a Job to run in the managed cluster before or after
+// a feature is deployed.
+type DeploymentJob struct {
+	// Name identifies this job. The Job created in the managed cluster is named after it.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+
+	// FeatureID is an indentifier of the feature (Helm/Kustomize/Resources)
+	// this job is run for.
+	FeatureID FeatureID `json:"featureID"`
+
+	// JobTemplateRef references the ConfigMap/Secret containing the Job (batch/v1) to run.
+	// The Job can be expressed as a template, in which case it is instantiated using
+	// resources within the management cluster (Cluster and TemplateResourceRefs).
+	// If the Job does not set a namespace, default namespace is used.
+	// Set the Job spec.activeDeadlineSeconds to bound how long it can run.
+	JobTemplateRef ValueFrom `json:"jobTemplateRef"`
 }
 
 // SyncMode specifies how features are synced in a workload cluster.
-// +kubebuilder:validation:Enum:=OneTime;Continuous;ContinuousWithDriftDetection;DryRun
+// +kubebuilder:validation:Enum:=OneTime;Continuous;ContinuousWithDriftDetection;DryRun;AssessOnly
 type SyncMode string
 
 const (
@@ -102,8 +145,20 @@ const (
 	// SyncModeDryRun indicates feature sync should continuously happen
 	// no feature will be updated in the CAPI Cluster though.
 	SyncModeDryRun = SyncMode("DryRun")
+
+	// SyncModeAssessOnly is like SyncModeDryRun, no feature will be updated in the Cluster.
+	// Desired state is compared with the live objects in the Cluster (instead of with what
+	// Sveltos last deployed) and a conformance score is reported in the ClusterReport.
+	// Useful to evaluate clusters whose add-ons were not deployed by Sveltos.
+	SyncModeAssessOnly = SyncMode("AssessOnly")
 )
 
+// IsDryRunSyncMode returns true if, in the given sync mode, Sveltos does not make any change
+// to the managed clusters
+func IsDryRunSyncMode(mode SyncMode) bool {
+	return mode == SyncModeDryRun || mode == SyncModeAssessOnly
+}
+
 // DeploymentType indicates whether resources need to be deployed
 // into the management cluster (local) or the managed cluster (remote)
 // +kubebuilder:validation:Enum:=Local;Remote
@@ -119,6 +174,27 @@ const (
 	DeploymentTypeRemote = DeploymentType("Remote")
 )
 
+// ConflictPolicy specifies what to do when a resource to deploy already exists
+// and is not owned by the ClusterProfile/Profile deploying it
+// +kubebuilder:validation:Enum:=Adopt;Fail;Force
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyAdopt takes ownership of resources not deployed by Sveltos.
+	// Resources deployed by a different ClusterProfile/Profile are taken over only
+	// if this one has higher priority (lower tier).
+	ConflictPolicyAdopt = ConflictPolicy("Adopt")
+
+	// ConflictPolicyFail never takes ownership of existing resources. Deployment fails
+	// with a conflict if a resource exists and is not owned by this ClusterProfile/Profile.
+	// Fields owned by other controllers (server-side apply field managers) are not taken over either.
+	ConflictPolicyFail = ConflictPolicy("Fail")
+
+	// ConflictPolicyForce always takes ownership of existing resources, regardless of
+	// which ClusterProfile/Profile currently owns them and its tier.
+	ConflictPolicyForce = ConflictPolicy("Force")
+)
+
 type ValueFrom struct {
 	// Namespace of the referenced resource.
 	// For ClusterProfile namespace can be left empty. In such a case, namespace will
@@ -169,8 +245,76 @@ type RegistryCredentialsConfig struct {
 	PlainHTTP bool `json:"plainHTTP,omitempty"`
 }
 
+// HelmChartVerificationProvider specifies how helm chart signature is verified
+// +kubebuilder:validation:Enum:=Provenance;Cosign
+type HelmChartVerificationProvider string
+
+// Define the HelmChartVerificationProvider constants.
+const (
+	// HelmChartVerificationProviderProvenance verifies the chart against its provenance
+	// file (.prov), signed with a PGP key
+	HelmChartVerificationProviderProvenance = HelmChartVerificationProvider("Provenance")
+
+	// HelmChartVerificationProviderCosign verifies the cosign signature of an OCI chart
+	HelmChartVerificationProviderCosign = HelmChartVerificationProvider("Cosign")
+)
+
+type HelmChartVerify struct {
+	// Provider is the verification method.
+	// - Provenance: chart provenance file (.prov) must be signed by a key in the keyring.
+	// Supported for both HTTP and OCI repositories.
+	// - Cosign: chart must have a cosign signature, made by one of the public keys.
+	// Supported for OCI repositories only.
+	// +kubebuilder:default:=Provenance
+	// +optional
+	Provider HelmChartVerificationProvider `json:"provider,omitempty"`
+
+	// SecretRef references a secret containing the keys used to verify the chart.
+	// - Provenance: all data keys together form the PGP public keyring (binary format,
+	// as produced by gpg --export);
+	// - Cosign: each data key contains one or more PEM encoded public keys.
+	// For ClusterProfile namespace can be left empty. In such a case, namespace will
+	// be implicit set to cluster's namespace.
+	SecretRef corev1.SecretReference `json:"secretRef"`
+}
+
+// HelmChartTests configures running the chart tests (helm test) as a verification step
+type HelmChartTests struct {
+	// Timeout is the time to wait for all tests to complete (default 5m0s)
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// IgnoreFailures, when set, only reports failed tests. By default failed tests
+	// cause the deployment to fail (and tests to be run again on next attempt).
+	// +kubebuilder:default:=false
+	// +optional
+	IgnoreFailures bool `json:"ignoreFailures,omitempty"`
+}
+
+// NamespaceMetadata is the metadata set on namespaces created in the managed clusters
+type NamespaceMetadata struct {
+	// Labels to set on the namespaces
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations to set on the namespaces
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// HelmValuesOverride defines values used only for the matching clusters
+type HelmValuesOverride struct {
+	// ClusterSelector selects the clusters these values are used for
+	ClusterSelector libsveltosv1beta1.Selector `json:"clusterSelector"`
+
+	// Values to use for the matching clusters. These values can be static or leverage
+	// Go templates (instantiated as HelmChart.Values).
+	// +kubebuilder:validation:MinLength=1
+	Values string `json:"values"`
+}
+
 // HelmChartAction specifies action on an helm chart
-// +kubebuilder:validation:Enum:=Install;Uninstall
+// +kubebuilder:validation:Enum:=Install;Uninstall;Manage
 type HelmChartAction string
 
 const (
@@ -179,6 +323,11 @@ const (
 
 	// HelmChartActionUninstall will cause Helm chart to be removed
 	HelmChartActionUninstall = HelmChartAction("Uninstall")
+
+	// HelmChartActionManage will cause Helm chart to be installed. If a release with same
+	// name/namespace was already installed, not by Sveltos, such release is adopted and from
+	// then on managed by Sveltos.
+	HelmChartActionManage = HelmChartAction("Manage")
 )
 
 type HelmOptions struct {
@@ -206,7 +355,8 @@ type HelmOptions struct {
 	// +optional
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
 
-	// prevent hooks from running during install/upgrade/uninstall
+	// prevent hooks from running during install/upgrade/uninstall. When set, chart tests are
+	// not run either.
 	// Default to false
 	// +kubebuilder:default:=false
 	// +optional
@@ -225,7 +375,7 @@ type HelmOptions struct {
 	// +optional
 	Atomic bool `json:"atomic,omitempty"`
 
-	// update dependencies if they are missing before installing the chart
+	// update dependencies if they are missing before installing/upgrading the chart
 	// Default to false
 	// +kubebuilder:default:=false
 	// +optional
@@ -343,7 +493,9 @@ type HelmChart struct {
 	// +kubebuilder:validation:MinLength=1
 	ChartName string `json:"chartName"`
 
-	// ChartVersion is the chart version
+	// ChartVersion is the chart version. It can also be a semver constraint (for instance
+	// ">=2.5.0 <3.0.0") or latest. In such case the repository is periodically checked and the
+	// highest published version matching the constraint is deployed.
 	// +kubebuilder:validation:MinLength=1
 	ChartVersion string `json:"chartVersion"`
 
@@ -367,9 +519,24 @@ type HelmChart struct {
 	// These values can be static or leverage Go templates for dynamic customization.
 	// When expressed as templates, the values are filled in using information from
 	// resources within the management cluster before deployment (Cluster and TemplateResourceRefs)
+	// Values are merged in order, starting from Values, then each referenced ConfigMap/Secret
+	// (keys within the same ConfigMap/Secret are processed in alphabetical order). Last one wins.
 	// +optional
 	ValuesFrom []ValueFrom `json:"valuesFrom,omitempty"`
 
+	// ValuesOverrides allows to express small per-cluster differences (for instance a different
+	// ingress class for production and staging clusters) without duplicating the profile.
+	// Values of each override whose ClusterSelector matches the cluster labels are merged, in order,
+	// after Values and ValuesFrom. Last one wins.
+	// +optional
+	ValuesOverrides []HelmValuesOverride `json:"valuesOverrides,omitempty"`
+
+	// CreateNamespace, when set, overrides Options.InstallOptions.CreateNamespace. When the release
+	// namespace must be created, Sveltos creates it, before installing the chart, with the labels and
+	// annotations defined in TargetNamespaceMetadata.
+	// +optional
+	CreateNamespace *bool `json:"createNamespace,omitempty"`
+
 	// HelmChartAction is the action that will be taken on the helm chart
 	// +kubebuilder:default:=Install
 	// +optional
@@ -383,6 +550,18 @@ type HelmChart struct {
 	// including information to connect to private registries.
 	// +optional
 	RegistryCredentialsConfig *RegistryCredentialsConfig `json:"registryCredentialsConfig,omitempty"`
+
+	// Verify, when set, requires the chart to be signed. Chart is installed/upgraded only
+	// if its signature is successfully verified. Verification result is reported in the
+	// ClusterConfiguration.
+	// +optional
+	Verify *HelmChartVerify `json:"verify,omitempty"`
+
+	// Tests, when set, runs the chart tests (helm test) every time a new release revision is
+	// deployed. Test results are reported in the ClusterSummary HelmReleaseSummaries.
+	// All hooks, tests included, can be skipped with Options.DisableHooks.
+	// +optional
+	Tests *HelmChartTests `json:"tests,omitempty"`
 }
 
 type KustomizationRef struct {
@@ -477,6 +656,124 @@ const (
 	LeavePolicies    StopMatchingBehavior = "LeavePolicies"
 )
 
+// ClusterReadinessCheckType identifies a criteria a cluster must satisfy before add-ons are deployed
+// +kubebuilder:validation:Enum:=ControlPlaneInitialized;ControlPlaneMachinesRunning;ClusterReady;LabelPresent
+type ClusterReadinessCheckType string
+
+const (
+	// ClusterReadinessControlPlaneInitialized requires the ControlPlaneInitialized condition
+	// to be true on the ClusterAPI Cluster.
+	ClusterReadinessControlPlaneInitialized = ClusterReadinessCheckType("ControlPlaneInitialized")
+
+	// ClusterReadinessControlPlaneMachinesRunning requires all control plane Machines
+	// of the ClusterAPI Cluster to be in the Running phase.
+	ClusterReadinessControlPlaneMachinesRunning = ClusterReadinessCheckType("ControlPlaneMachinesRunning")
+
+	// ClusterReadinessClusterReady requires the Ready condition to be true on the ClusterAPI Cluster
+	// (Status.Ready on SveltosCluster).
+	ClusterReadinessClusterReady = ClusterReadinessCheckType("ClusterReady")
+
+	// ClusterReadinessLabelPresent requires a label to be present on the cluster.
+	ClusterReadinessLabelPresent = ClusterReadinessCheckType("LabelPresent")
+)
+
+type ClusterReadinessCheck struct {
+	// Type is the readiness criteria
+	Type ClusterReadinessCheckType `json:"type"`
+
+	// LabelKey is the key of the label which must be present on the cluster.
+	// Only used when Type is LabelPresent.
+	// +optional
+	LabelKey string `json:"labelKey,omitempty"`
+
+	// LabelValue, if set, is the value the label must have.
+	// Only used when Type is LabelPresent.
+	// +optional
+	LabelValue string `json:"labelValue,omitempty"`
+}
+
+// RolloutRing is a group of matching clusters updated together. Rings are updated in order:
+// a ring is updated only once all previous rings are.
+type RolloutRing struct {
+	// Name of the ring (for instance canary, early or broad)
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// ClusterSelector selects, among the matching clusters, the ones belonging to this ring.
+	// A cluster belongs to the first ring it matches. Clusters matching no ring belong to the last ring.
+	// +optional
+	ClusterSelector libsveltosv1beta1.Selector `json:"clusterSelector,omitempty"`
+
+	// SoakTime is how long to wait, once all clusters in this ring are updated, before updating
+	// clusters in the next ring.
+	// +optional
+	SoakTime *metav1.Duration `json:"soakTime,omitempty"`
+
+	// MaxFailures is the number (ex: 1) or percentage (ex: 10%) of clusters in this ring which
+	// can fail to be updated. When exceeded, rollout is aborted: clusters in following rings
+	// are not updated till ClusterProfile/Profile Spec changes.
+	// When not set, rollout is never aborted (a ring is anyway not completed till all its
+	// clusters are updated).
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Pattern="^((100|[0-9]{1,2})%|[0-9]+)$"
+	// +optional
+	MaxFailures *intstr.IntOrString `json:"maxFailures,omitempty"`
+}
+
+// RolloutNotificationType is the channel a RolloutNotification is sent to
+// +kubebuilder:validation:Enum:=Slack;Teams;Webhook;SMTP
+type RolloutNotificationType string
+
+const (
+	// RolloutNotificationTypeSlack posts a message to a Slack incoming webhook
+	RolloutNotificationTypeSlack = RolloutNotificationType("Slack")
+
+	// RolloutNotificationTypeTeams posts a message to a Microsoft Teams incoming webhook
+	RolloutNotificationTypeTeams = RolloutNotificationType("Teams")
+
+	// RolloutNotificationTypeWebhook posts a JSON event to an URL
+	RolloutNotificationTypeWebhook = RolloutNotificationType("Webhook")
+
+	// RolloutNotificationTypeSMTP sends an email
+	RolloutNotificationTypeSMTP = RolloutNotificationType("SMTP")
+)
+
+// RolloutNotification is sent when a ClusterProfile/Profile finishes rolling out to all
+// matching clusters and when deploying to a cluster keeps failing.
+type RolloutNotification struct {
+	// Name of the notification. Must be unique within the ClusterProfile/Profile.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Type of the notification
+	Type RolloutNotificationType `json:"type"`
+
+	// SecretRef references the Secret containing the notification details:
+	// - Slack, Teams and Webhook: the URL the message is posted to (key "url");
+	// - SMTP: the server in the form host:port (key "host"), the sender (key "from"), the
+	// comma separated recipients (key "to") and, optionally, credentials (keys "username"
+	// and "password").
+	// For Profiles, the Secret must be in the Profile namespace (Namespace is ignored).
+	SecretRef corev1.SecretReference `json:"secretRef"`
+
+	// FailureThreshold is the number of consecutive failed attempts to deploy a feature in a
+	// cluster after which a failure notification is sent. Default: 3
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// TenantRef identifies the ServiceAccount, in the managed cluster, a tenant is mapped to.
+type TenantRef struct {
+	// ServiceAccountNamespace is the namespace of the ServiceAccount in the managed cluster
+	// +kubebuilder:validation:MinLength=1
+	ServiceAccountNamespace string `json:"serviceAccountNamespace"`
+
+	// ServiceAccountName is the name of the ServiceAccount in the managed cluster
+	// +kubebuilder:validation:MinLength=1
+	ServiceAccountName string `json:"serviceAccountName"`
+}
+
 type TemplateResourceRef struct {
 	// Resource references a Kubernetes instance in the management
 	// cluster to fetch and use during template instantiation.
@@ -526,6 +823,46 @@ type PolicyRef struct {
 	// +kubebuilder:default:=Remote
 	// +optional
 	DeploymentType DeploymentType `json:"deploymentType,omitempty"`
+
+	// ConflictPolicy controls what happens when a resource contained in the referenced
+	// resource already exists and is not owned by this ClusterProfile/Profile:
+	// - Adopt: resources not deployed by Sveltos are taken over. Resources deployed by
+	// a different ClusterProfile/Profile are taken over only if Tier allows it;
+	// - Fail: deployment fails with a conflict;
+	// - Force: resources are always taken over, regardless of their current owner.
+	// +kubebuilder:default:=Adopt
+	// +optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+}
+
+// SourceRef references a Flux Source containing the YAMLs of the kubernetes resources
+// to deploy, as PolicyRefs do.
+type SourceRef struct {
+	// Namespace of the referenced Flux Source.
+	// For ClusterProfile namespace can be left empty. In such a case, namespace will
+	// be implicit set to cluster's namespace.
+	// For Profile namespace must be left empty. Profile namespace will be used.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the referenced Flux Source.
+	// Name can be expressed as a template and instantiate using
+	// - cluster namespace: .Cluster.metadata.namespace
+	// - cluster name: .Cluster.metadata.name
+	// - cluster type: .Cluster.kind
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Kind of the Flux Source.
+	// +kubebuilder:default:=GitRepository
+	// +kubebuilder:validation:Enum=GitRepository;OCIRepository;Bucket
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Path to the directory containing the YAML files.
+	// Defaults to 'None', which translates to the root path of the Source.
+	// +optional
+	Path string `json:"path,omitempty"`
 }
 
 type DriftExclusion struct {
@@ -538,6 +875,118 @@ type DriftExclusion struct {
 	Target *libsveltosv1beta1.PatchSelector `json:"target,omitempty"`
 }
 
+// DriftExcludedKind identifies a kind of resources excluded from configuration drift tracking.
+type DriftExcludedKind struct {
+	// Group of the resources. Empty for core resources.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Version of the resources. When not set, all versions are excluded.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Kind of the resources.
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+}
+
+// RegistryMirror redirects container images from a registry to a mirror.
+type RegistryMirror struct {
+	// Registry is the registry images are pulled from, for instance docker.io or ghcr.io.
+	// Images with no registry are considered to be pulled from docker.io.
+	// +kubebuilder:validation:MinLength=1
+	Registry string `json:"registry"`
+
+	// Mirror replaces Registry in the image references. It can contain a path, for
+	// instance registry.internal.example.com/dockerhub.
+	// +kubebuilder:validation:MinLength=1
+	Mirror string `json:"mirror"`
+}
+
+// MetadataPropagationTarget identifies resources in the managed cluster whose labels/annotations
+// are managed by a ClusterMetadataPropagation.
+type MetadataPropagationTarget struct {
+	// Group of the resources. Empty for core resources.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Version of the resources.
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// Kind of the resources.
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Namespace of the resources. Leave empty for cluster wide resources.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the resource. When not set, all resources of this kind (in Namespace if set)
+	// are considered. For instance, all Nodes.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// ClusterMetadataPropagation defines labels/annotations to set on resources in the managed
+// cluster. Values can be copied from the Cluster (SveltosCluster or ClusterAPI Cluster) or
+// expressed as templates instantiated using Cluster information.
+type ClusterMetadataPropagation struct {
+	// Target identifies the resources in the managed cluster to update
+	Target MetadataPropagationTarget `json:"target"`
+
+	// LabelKeys is the list of Cluster label keys to copy, with their values,
+	// to the target resources. Keys not present on the Cluster are ignored.
+	// +listType=atomic
+	// +optional
+	LabelKeys []string `json:"labelKeys,omitempty"`
+
+	// AnnotationKeys is the list of Cluster annotation keys to copy, with their values,
+	// to the target resources. Keys not present on the Cluster are ignored.
+	// +listType=atomic
+	// +optional
+	AnnotationKeys []string `json:"annotationKeys,omitempty"`
+
+	// Labels to set on the target resources. Values can be templates, instantiated using
+	// Cluster information (for instance {{ index .Cluster.metadata.labels "region" }}).
+	// Take precedence over labels copied because of LabelKeys.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations to set on the target resources. Values can be templates, instantiated using
+	// Cluster information. Take precedence over annotations copied because of AnnotationKeys.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// DriftDetectionPlacement indicates where drift-detection-manager runs
+// +kubebuilder:validation:Enum:=ManagedCluster;ManagementCluster
+type DriftDetectionPlacement string
+
+const (
+	// DriftDetectionPlacementManagedCluster runs drift-detection-manager in the managed cluster
+	DriftDetectionPlacementManagedCluster = DriftDetectionPlacement("ManagedCluster")
+
+	// DriftDetectionPlacementManagementCluster runs drift-detection-manager in the management
+	// cluster. There is one drift-detection-manager instance per managed cluster. Nothing other
+	// than the Sveltos CRDs and the ResourceSummary is deployed in the managed cluster.
+	DriftDetectionPlacementManagementCluster = DriftDetectionPlacement("ManagementCluster")
+)
+
+// DriftDetectionResources contains the compute resources of drift-detection-manager,
+// for each placement.
+type DriftDetectionResources struct {
+	// ManagedCluster contains the compute resources of drift-detection-manager when
+	// running in the managed cluster
+	// +optional
+	ManagedCluster *corev1.ResourceRequirements `json:"managedCluster,omitempty"`
+
+	// ManagementCluster contains the compute resources of drift-detection-manager when
+	// running in the management cluster
+	// +optional
+	ManagementCluster *corev1.ResourceRequirements `json:"managementCluster,omitempty"`
+}
+
 type Clusters struct {
 	// Hash represents of a unique value for ClusterProfile Spec at
 	// a fixed point in time
@@ -555,6 +1004,14 @@ type Spec struct {
 	// +optional
 	ClusterSelector libsveltosv1beta1.Selector `json:"clusterSelector,omitempty"`
 
+	// ClusterCELSelector is a CEL expression evaluated against each cluster, available as
+	// variable cluster (for instance cluster.spec.topology.version.startsWith("v1.27")).
+	// Expression must return a boolean. When set, only clusters for which it returns true match.
+	// If ClusterSelector is also set, clusters must match both; otherwise the expression is
+	// evaluated against all clusters. Clusters in ClusterRefs and SetRefs are not filtered.
+	// +optional
+	ClusterCELSelector string `json:"clusterCELSelector,omitempty"`
+
 	// ClusterRefs identifies clusters to associate to.
 	// +optional
 	ClusterRefs []corev1.ObjectReference `json:"clusterRefs,omitempty"`
@@ -652,6 +1109,13 @@ type Spec struct {
 	// +optional
 	PolicyRefs []PolicyRef `json:"policyRefs,omitempty"`
 
+	// SourceRef references a Flux Source (by default a GitRepository) and a path within it containing
+	// the kubernetes resources to deploy in the matching managed clusters, as if the Source was listed
+	// in PolicyRefs. Resources are re-deployed every time the Source has a new artifact revision.
+	// The revision deployed is reported in ClusterSummary and ClusterConfiguration Status.
+	// +optional
+	SourceRef *SourceRef `json:"sourceRef,omitempty"`
+
 	// Helm charts is a list of helm charts that need to be deployed
 	HelmCharts []HelmChart `json:"helmCharts,omitempty"`
 
@@ -664,17 +1128,63 @@ type Spec struct {
 	// is healthy
 	ValidateHealths []ValidateHealth `json:"validateHealths,omitempty"`
 
+	// PreDeploymentJobs are Jobs run in the managed cluster before a feature is deployed
+	// (for instance a database migration). The feature is deployed only once all its
+	// PreDeploymentJobs have successfully completed.
+	// Jobs are run again every time the ClusterSummary Spec changes.
+	// +listType=atomic
+	// +optional
+	PreDeploymentJobs []DeploymentJob `json:"preDeploymentJobs,omitempty"`
+
+	// PostDeploymentJobs are Jobs run in the managed cluster after a feature is deployed
+	// (for instance smoke tests). The feature is reported as Provisioned only once all its
+	// PostDeploymentJobs have successfully completed.
+	// Jobs are run again every time the ClusterSummary Spec changes.
+	// +listType=atomic
+	// +optional
+	PostDeploymentJobs []DeploymentJob `json:"postDeploymentJobs,omitempty"`
+
 	// Define additional Kustomize inline Patches applied for all resources on this profile
 	// Within the Patch Spec you can use templating
 	// +optional
 	Patches []libsveltosv1beta1.Patch `json:"patches,omitempty"`
 
+	// RegistryMirrors rewrites the registry of container images in the resources and Helm
+	// charts deployed in managed clusters, before those are applied. This allows the same
+	// profile to be used in air-gapped clusters, which can only pull images from internal mirrors.
+	// Mirrors can also be set, per cluster, with the projectsveltos.io/registry-mirrors annotation
+	// on the Cluster. Those take precedence.
+	// +listType=atomic
+	// +optional
+	RegistryMirrors []RegistryMirror `json:"registryMirrors,omitempty"`
+
 	// DriftExclusions is a list of configuration drift exclusions to be applied when syncMode is
 	// set to ContinuousWithDriftDetection. Each exclusion specifies JSON6902 paths to ignore
 	// when evaluating drift, optionally targeting specific resources and features.
 	// +optional
 	DriftExclusions []DriftExclusion `json:"driftExclusions,omitempty"`
 
+	// DriftExcludedKinds is a list of resource kinds which are not tracked for configuration drift
+	// when syncMode is set to ContinuousWithDriftDetection. Resources of those kinds are still
+	// deployed but are not part of the inventory Sveltos keeps in the managed cluster.
+	// Useful for noisy or ephemeral resources like Events, Jobs or Leases.
+	// Kinds excluded globally (drift-excluded-kinds controller flag) are always excluded.
+	// +listType=atomic
+	// +optional
+	DriftExcludedKinds []DriftExcludedKind `json:"driftExcludedKinds,omitempty"`
+
+	// DriftDetectionPlacement indicates whether drift-detection-manager, used when syncMode is
+	// set to ContinuousWithDriftDetection, runs in the managed cluster or in the management cluster.
+	// When not set, the addon-controller default (agent-in-mgmt-cluster flag) is used.
+	// All ClusterProfiles/Profiles matching a cluster are expected to use the same placement.
+	// +optional
+	DriftDetectionPlacement DriftDetectionPlacement `json:"driftDetectionPlacement,omitempty"`
+
+	// DriftDetectionResources overrides, for each placement, the compute resources
+	// (requests/limits) of drift-detection-manager
+	// +optional
+	DriftDetectionResources *DriftDetectionResources `json:"driftDetectionResources,omitempty"`
+
 	// ExtraLabels: These labels will be added by Sveltos to all Kubernetes resources deployed in
 	// a managed cluster based on this ClusterProfile/Profile instance.
 	// **Important:** If a resource deployed by Sveltos already has a label with a key present in
@@ -690,4 +1200,130 @@ type Spec struct {
 	// (Deprecated use Patches instead)
 	// +optional
 	ExtraAnnotations map[string]string `json:"extraAnnotations,omitempty"`
+
+	// Paused, when set to true, freezes deployments: Sveltos will not deploy, update or withdraw
+	// any add-on/application in any matching cluster until Paused is set back to false.
+	// To pause a single cluster, set the projectsveltos.io/paused annotation on the
+	// corresponding ClusterSummary instead.
+	// +kubebuilder:default:=false
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// BaseProfile marks add-ons deployed by this ClusterProfile/Profile as base add-ons (for instance
+	// CNI) of the matching clusters. In a cluster, add-ons of other ClusterProfiles/Profiles are deployed
+	// only once all base ClusterProfiles/Profiles matching the cluster are provisioned. So add-ons do not
+	// repeatedly fail before the cluster is functional.
+	// Readiness is reported by the BaseAddOnsReady condition of the cluster ClusterConfiguration.
+	// +optional
+	BaseProfile bool `json:"baseProfile,omitempty"`
+
+	// ClusterReadinessChecks are additional criteria a matching cluster must satisfy before
+	// add-ons/applications are deployed. By default a cluster is ready to be configured as soon as
+	// its control plane is reachable. Checks listed here must all be satisfied in addition.
+	// Checks specific to ClusterAPI clusters are ignored for SveltosClusters.
+	// +listType=atomic
+	// +optional
+	ClusterReadinessChecks []ClusterReadinessCheck `json:"clusterReadinessChecks,omitempty"`
+
+	// MaxConcurrentClusterDeployments is the maximum number of matching clusters add-ons/applications
+	// are deployed to at the same time for this profile. It is independent of the number of clusters
+	// the controller can reconcile concurrently.
+	// A cluster holds one slot from the moment deployment starts till all features are successfully
+	// deployed. So a cluster where deployment keeps failing does not release its slot, preventing
+	// a faulty rollout from spreading to more clusters.
+	// When not set or set to zero, no limit is enforced.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentClusterDeployments int32 `json:"maxConcurrentClusterDeployments,omitempty"`
+
+	// ClusterMetadataPropagations lists labels/annotations to keep in sync between the Cluster
+	// and resources in the managed cluster (for instance kube-system namespace or Nodes).
+	// Only the listed labels/annotations are managed: any other label/annotation on target
+	// resources is left untouched. When a propagation is removed, labels/annotations set
+	// because of it are removed from the target resources.
+	// +listType=atomic
+	// +optional
+	ClusterMetadataPropagations []ClusterMetadataPropagation `json:"clusterMetadataPropagations,omitempty"`
+
+	// PublishChangeSummary, when set to true, makes Sveltos write into each managed cluster a
+	// ConfigMap (namespace projectsveltos) summarizing the latest changes (resources applied, helm charts
+	// installed/upgraded, profile generation). So users without access to the management cluster
+	// can see why workloads changed.
+	// +kubebuilder:default:=false
+	// +optional
+	PublishChangeSummary bool `json:"publishChangeSummary,omitempty"`
+
+	// RolloutRings partitions matching clusters in rings (for instance canary, early and broad)
+	// updated one after the other when ClusterProfile/Profile Spec changes. MaxUpdate still
+	// applies within each ring.
+	// +listType=atomic
+	// +optional
+	RolloutRings []RolloutRing `json:"rolloutRings,omitempty"`
+
+	// RolloutFailureThreshold is the number (ex: 2) or percentage (ex: 10%) of matching clusters
+	// which can fail to be updated when ClusterProfile/Profile Spec changes. When exceeded, rollout
+	// is aborted: no more clusters are updated till Spec changes again.
+	// When not set, rollout is never aborted because of failures.
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Pattern="^((100|[0-9]{1,2})%|[0-9]+)$"
+	// +optional
+	RolloutFailureThreshold *intstr.IntOrString `json:"rolloutFailureThreshold,omitempty"`
+
+	// RollbackOnRolloutAbort, when set, rolls clusters already updated back to the last Spec
+	// rolled out to all matching clusters, once a rollout is aborted.
+	// +kubebuilder:default:=false
+	// +optional
+	RollbackOnRolloutAbort bool `json:"rollbackOnRolloutAbort,omitempty"`
+
+	// EnforceInterval, when set, makes Sveltos re-apply all add-ons/applications to each matching
+	// cluster periodically, even if nothing has changed, to correct drifts in clusters where
+	// drift detection is not used. Each cluster is re-applied at a different time (up to 10%
+	// of EnforceInterval later), so clusters are not all re-applied together.
+	// Only used when SyncMode is Continuous or ContinuousWithDriftDetection.
+	// +optional
+	EnforceInterval *metav1.Duration `json:"enforceInterval,omitempty"`
+
+	// MaxConcurrentEnforcements is the maximum number of matching clusters periodically
+	// re-applied (see EnforceInterval) at the same time for this profile.
+	// When not set or set to zero, no limit is enforced.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentEnforcements int32 `json:"maxConcurrentEnforcements,omitempty"`
+
+	// DeploymentOrder, when set, lists features which are deployed sequentially, in the listed
+	// order, to each matching cluster: a feature is deployed only once all features preceding
+	// it are provisioned. For instance, with [Helm, Resources] PolicyRefs are deployed only once
+	// all HelmCharts (which might install the needed CRDs) are provisioned.
+	// Features not listed are deployed independently.
+	// +kubebuilder:validation:MaxItems=4
+	// +listType=set
+	// +optional
+	DeploymentOrder []FeatureID `json:"deploymentOrder,omitempty"`
+
+	// TenantRef, when set, makes Sveltos deploy add-ons/applications in the managed clusters (and
+	// remove them) impersonating this ServiceAccount. So managed cluster RBAC limits what the
+	// profile can deploy. Sveltos must be allowed to impersonate ServiceAccounts in the managed cluster.
+	// +optional
+	TenantRef *TenantRef `json:"tenantRef,omitempty"`
+
+	// Notifications are sent when the ClusterProfile/Profile finishes rolling out to all
+	// matching clusters and when deploying to a cluster fails repeatedly.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Notifications []RolloutNotification `json:"notifications,omitempty"`
+
+	// MinControllerVersion is the minimum addon-controller version (for instance v0.39.0) required
+	// by this ClusterProfile/Profile. Older controllers do not deploy it and report the
+	// ControllerVersionSupported condition as false, instead of silently ignoring fields they
+	// do not know about.
+	// +kubebuilder:validation:Pattern="^v?[0-9]+\\.[0-9]+\\.[0-9]+([-+].*)?$"
+	// +optional
+	MinControllerVersion string `json:"minControllerVersion,omitempty"`
+
+	// TargetNamespaceMetadata defines labels and annotations (for instance pod-security labels) set
+	// on the namespaces Sveltos creates in the managed clusters: helm release namespaces and namespaces
+	// of deployed resources. Namespaces already existing are not modified.
+	// +optional
+	TargetNamespaceMetadata *NamespaceMetadata `json:"targetNamespaceMetadata,omitempty"`
 }
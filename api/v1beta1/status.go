@@ -18,6 +18,7 @@ package v1beta1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Status defines the observed state of ClusterProfile/Profile
@@ -37,4 +38,272 @@ type Status struct {
 	// Spec
 	// +optional
 	UpdatedClusters Clusters `json:"updatedClusters,omitempty"`
+
+	// ClusterSummaries aggregates the deployment state of all the clusters currently
+	// matching ClusterProfile/Profile
+	// +optional
+	ClusterSummaries *ClusterSummariesStatus `json:"clusterSummaries,omitempty"`
+
+	// Rollout reports the progress of the rollout across RolloutRings
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// LastRolledOutSpec is the last ClusterProfile/Profile Spec rolled out to all
+	// matching clusters. Clusters are rolled back to it when a rollout is aborted
+	// and RollbackOnRolloutAbort is set.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	LastRolledOutSpec *Spec `json:"lastRolledOutSpec,omitempty"`
+
+	// Revision is the revision of current ClusterProfile/Profile Spec. A new revision
+	// is recorded every time Spec changes.
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+
+	// RevisionHistory contains the most recent ClusterProfile/Profile Specs, oldest first.
+	// All matching clusters can be rolled back to any of those using RollbackToRevisionAnnotation.
+	// +listType=atomic
+	// +optional
+	RevisionHistory []ProfileRevision `json:"revisionHistory,omitempty"`
+
+	// Conditions reports ClusterProfile/Profile conditions, like whether rollout was aborted
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ResolvedChartVersions contains, for each helm chart whose ChartVersion is a version
+	// constraint, the highest published version matching the constraint. This is the version
+	// deployed in the matching clusters.
+	// +listType=atomic
+	// +optional
+	ResolvedChartVersions []ResolvedChartVersion `json:"resolvedChartVersions,omitempty"`
+
+	// ObservedGeneration is the most recent ClusterProfile/Profile generation
+	// processed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+const (
+	// RolloutAbortedCondition reports whether the rollout of the current Spec was aborted
+	RolloutAbortedCondition = "RolloutAborted"
+
+	// FailureThresholdExceededReason indicates more clusters than RolloutFailureThreshold failed
+	FailureThresholdExceededReason = "FailureThresholdExceeded"
+
+	// RolloutRingAbortedReason indicates more clusters than MaxFailures failed in a RolloutRing
+	RolloutRingAbortedReason = "RolloutRingAborted"
+
+	// NotAbortedReason indicates rollout was not aborted
+	NotAbortedReason = "NotAborted"
+)
+
+const (
+	// ReadyCondition reports whether all matching clusters are provisioned with the current
+	// ClusterProfile/Profile Spec
+	ReadyCondition = "Ready"
+
+	// AllClustersProvisionedReason indicates all matching clusters are provisioned
+	AllClustersProvisionedReason = "AllClustersProvisioned"
+
+	// ClustersProvisioningReason indicates some matching clusters are still being provisioned
+	ClustersProvisioningReason = "ClustersProvisioning"
+
+	// ClustersFailedReason indicates provisioning failed in some matching clusters
+	ClustersFailedReason = "ClustersFailed"
+)
+
+const (
+	// ControllerVersionSupportedCondition reports whether this controller version satisfies
+	// Spec.MinControllerVersion
+	ControllerVersionSupportedCondition = "ControllerVersionSupported"
+
+	// ControllerVersionTooOldReason indicates the controller is older than Spec.MinControllerVersion
+	ControllerVersionTooOldReason = "ControllerVersionTooOld"
+
+	// ControllerVersionSatisfiedReason indicates the controller satisfies Spec.MinControllerVersion
+	ControllerVersionSatisfiedReason = "ControllerVersionSatisfied"
+)
+
+// +kubebuilder:validation:Enum:=Provisioned;Provisioning;Failed;Queued
+type ClusterDeploymentState string
+
+const (
+	// ClusterDeploymentStateProvisioned indicates all features are provisioned in the cluster
+	ClusterDeploymentStateProvisioned = ClusterDeploymentState("Provisioned")
+
+	// ClusterDeploymentStateProvisioning indicates features are being provisioned in the cluster
+	ClusterDeploymentStateProvisioning = ClusterDeploymentState("Provisioning")
+
+	// ClusterDeploymentStateFailed indicates provisioning at least one feature failed
+	ClusterDeploymentStateFailed = ClusterDeploymentState("Failed")
+
+	// ClusterDeploymentStateQueued indicates the cluster is waiting to be updated because
+	// of MaxUpdate, RolloutRings or MaxConcurrentClusterDeployments
+	ClusterDeploymentStateQueued = ClusterDeploymentState("Queued")
+)
+
+// ClusterDeploymentStatus is the condensed deployment state of a cluster
+type ClusterDeploymentStatus struct {
+	// Cluster references the matching cluster
+	Cluster corev1.ObjectReference `json:"cluster"`
+
+	// State is the deployment state of the cluster
+	State ClusterDeploymentState `json:"state"`
+
+	// FailureMessage reports the failure of the first failed feature, if any
+	// +optional
+	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// QueuePosition is the approximate position of the cluster among the clusters waiting
+	// to be updated. Set only when State is Queued.
+	// +optional
+	QueuePosition *int32 `json:"queuePosition,omitempty"`
+
+	// ObservedRevision is the ClusterProfile/Profile revision all features were last
+	// successfully provisioned for in the cluster
+	// +optional
+	ObservedRevision int64 `json:"observedRevision,omitempty"`
+
+	// PinnedCharts lists the helm charts pinned, in this cluster only, to a version different
+	// from the one in the ClusterProfile/Profile Spec
+	// +listType=atomic
+	// +optional
+	PinnedCharts []PinnedChart `json:"pinnedCharts,omitempty"`
+}
+
+// PinnedChart is a helm chart pinned, in a cluster, to a specific version
+type PinnedChart struct {
+	// ReleaseNamespace is the chart release namespace
+	ReleaseNamespace string `json:"releaseNamespace"`
+
+	// ReleaseName is the chart release name
+	ReleaseName string `json:"releaseName"`
+
+	// ChartVersion is the version the chart is pinned to
+	ChartVersion string `json:"chartVersion"`
+}
+
+// ResolvedChartVersion is the version a helm chart version constraint resolved to
+type ResolvedChartVersion struct {
+	// ReleaseNamespace is the chart release namespace
+	ReleaseNamespace string `json:"releaseNamespace"`
+
+	// ReleaseName is the chart release name
+	ReleaseName string `json:"releaseName"`
+
+	// VersionConstraint is the ChartVersion constraint in the ClusterProfile/Profile Spec
+	VersionConstraint string `json:"versionConstraint"`
+
+	// ChartVersion is the highest published chart version matching VersionConstraint.
+	// Empty if constraint was never resolved.
+	// +optional
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// FailureMessage reports why last attempt to resolve the constraint failed. In such
+	// case ChartVersion is the version previously resolved.
+	// +optional
+	FailureMessage *string `json:"failureMessage,omitempty"`
+}
+
+// ProfileRevision is a ClusterProfile/Profile Spec recorded in the revision history
+type ProfileRevision struct {
+	// Revision is the revision number
+	Revision int64 `json:"revision"`
+
+	// Hash represents a unique value for Spec
+	Hash []byte `json:"hash"`
+
+	// CreationTime is the time this revision was recorded
+	CreationTime metav1.Time `json:"creationTime"`
+
+	// Spec is the ClusterProfile/Profile Spec of this revision
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Spec Spec `json:"spec"`
+}
+
+// ClusterSummariesStatus aggregates ClusterSummaries status
+type ClusterSummariesStatus struct {
+	// Provisioned is the number of clusters where all features are provisioned
+	Provisioned int32 `json:"provisioned"`
+
+	// Provisioning is the number of clusters where features are being provisioned
+	Provisioning int32 `json:"provisioning"`
+
+	// Failed is the number of clusters where provisioning failed
+	Failed int32 `json:"failed"`
+
+	// Queued is the number of clusters waiting to be updated
+	// +optional
+	Queued int32 `json:"queued,omitempty"`
+
+	// Pinned is the number of clusters with at least one helm chart pinned to a version
+	// different from the one in the ClusterProfile/Profile Spec
+	// +optional
+	Pinned int32 `json:"pinned,omitempty"`
+
+	// Clusters contains the deployment state of each matching cluster
+	// +listType=atomic
+	// +optional
+	Clusters []ClusterDeploymentStatus `json:"clusters,omitempty"`
+}
+
+// +kubebuilder:validation:Enum:=Pending;Progressing;Soaking;Completed;Aborted
+type RolloutRingPhase string
+
+const (
+	// RolloutRingPhasePending indicates clusters in the ring are waiting for previous rings
+	RolloutRingPhasePending = RolloutRingPhase("Pending")
+
+	// RolloutRingPhaseProgressing indicates clusters in the ring are being updated
+	RolloutRingPhaseProgressing = RolloutRingPhase("Progressing")
+
+	// RolloutRingPhaseSoaking indicates all clusters in the ring are updated and SoakTime
+	// has not elapsed yet
+	RolloutRingPhaseSoaking = RolloutRingPhase("Soaking")
+
+	// RolloutRingPhaseCompleted indicates all clusters in the ring are updated
+	RolloutRingPhaseCompleted = RolloutRingPhase("Completed")
+
+	// RolloutRingPhaseAborted indicates more clusters than MaxFailures failed in the ring
+	RolloutRingPhaseAborted = RolloutRingPhase("Aborted")
+)
+
+// RolloutRingStatus reports the rollout progress in a ring
+type RolloutRingStatus struct {
+	// Name of the ring
+	Name string `json:"name"`
+
+	// Phase of the ring
+	Phase RolloutRingPhase `json:"phase"`
+
+	// Clusters is the number of matching clusters in the ring
+	Clusters int32 `json:"clusters"`
+
+	// Updated is the number of clusters in the ring already updated
+	Updated int32 `json:"updated"`
+
+	// Failed is the number of clusters in the ring which failed to be updated
+	Failed int32 `json:"failed"`
+
+	// CompletionTime is the time all clusters in the ring were updated
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// RolloutStatus reports the progress of the rollout across rings
+type RolloutStatus struct {
+	// Hash represents a unique value for ClusterProfile/Profile Spec this rollout is for
+	// +optional
+	Hash []byte `json:"hash,omitempty"`
+
+	// Rings contains the progress of each ring, in the order rings are updated
+	// +listType=atomic
+	// +optional
+	Rings []RolloutRingStatus `json:"rings,omitempty"`
 }
@@ -21,8 +21,8 @@ limitations under the License.
 package v1beta1
 
 import (
-	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -36,6 +36,16 @@ func (in *Chart) DeepCopyInto(out *Chart) {
 		in, out := &in.LastAppliedTime, &out.LastAppliedTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(ChartVerification)
+		**out = **in
+	}
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(ChartValues)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Chart.
@@ -48,11 +58,49 @@ func (in *Chart) DeepCopy() *Chart {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartValues) DeepCopyInto(out *ChartValues) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartValues.
+func (in *ChartValues) DeepCopy() *ChartValues {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartValues)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartVerification) DeepCopyInto(out *ChartVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartVerification.
+func (in *ChartVerification) DeepCopy() *ChartVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterConfiguration) DeepCopyInto(out *ClusterConfiguration) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -106,6 +154,21 @@ func (in *ClusterConfigurationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterConfigurationSpec) DeepCopyInto(out *ClusterConfigurationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterConfigurationSpec.
+func (in *ClusterConfigurationSpec) DeepCopy() *ClusterConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterConfigurationStatus) DeepCopyInto(out *ClusterConfigurationStatus) {
 	*out = *in
@@ -123,6 +186,13 @@ func (in *ClusterConfigurationStatus) DeepCopyInto(out *ClusterConfigurationStat
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterConfigurationStatus.
@@ -135,6 +205,96 @@ func (in *ClusterConfigurationStatus) DeepCopy() *ClusterConfigurationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterConnectionStatus) DeepCopyInto(out *ClusterConnectionStatus) {
+	*out = *in
+	if in.LastSuccessfulConnectionTime != nil {
+		in, out := &in.LastSuccessfulConnectionTime, &out.LastSuccessfulConnectionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterConnectionStatus.
+func (in *ClusterConnectionStatus) DeepCopy() *ClusterConnectionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterConnectionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDeploymentStatus) DeepCopyInto(out *ClusterDeploymentStatus) {
+	*out = *in
+	out.Cluster = in.Cluster
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+	if in.QueuePosition != nil {
+		in, out := &in.QueuePosition, &out.QueuePosition
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PinnedCharts != nil {
+		in, out := &in.PinnedCharts, &out.PinnedCharts
+		*out = make([]PinnedChart, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDeploymentStatus.
+func (in *ClusterDeploymentStatus) DeepCopy() *ClusterDeploymentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDeploymentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterMetadataPropagation) DeepCopyInto(out *ClusterMetadataPropagation) {
+	*out = *in
+	out.Target = in.Target
+	if in.LabelKeys != nil {
+		in, out := &in.LabelKeys, &out.LabelKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AnnotationKeys != nil {
+		in, out := &in.AnnotationKeys, &out.AnnotationKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterMetadataPropagation.
+func (in *ClusterMetadataPropagation) DeepCopy() *ClusterMetadataPropagation {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterMetadataPropagation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterProfile) DeepCopyInto(out *ClusterProfile) {
 	*out = *in
@@ -216,6 +376,21 @@ func (in *ClusterProfileResource) DeepCopy() *ClusterProfileResource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterReadinessCheck) DeepCopyInto(out *ClusterReadinessCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterReadinessCheck.
+func (in *ClusterReadinessCheck) DeepCopy() *ClusterReadinessCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterReadinessCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterReport) DeepCopyInto(out *ClusterReport) {
 	*out = *in
@@ -312,6 +487,11 @@ func (in *ClusterReportStatus) DeepCopyInto(out *ClusterReportStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Conformance != nil {
+		in, out := &in.Conformance, &out.Conformance
+		*out = new(Conformance)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterReportStatus.
@@ -324,6 +504,28 @@ func (in *ClusterReportStatus) DeepCopy() *ClusterReportStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSummariesStatus) DeepCopyInto(out *ClusterSummariesStatus) {
+	*out = *in
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]ClusterDeploymentStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSummariesStatus.
+func (in *ClusterSummariesStatus) DeepCopy() *ClusterSummariesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSummariesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterSummary) DeepCopyInto(out *ClusterSummary) {
 	*out = *in
@@ -428,6 +630,32 @@ func (in *ClusterSummaryStatus) DeepCopyInto(out *ClusterSummaryStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Connection != nil {
+		in, out := &in.Connection, &out.Connection
+		*out = new(ClusterConnectionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = new(DriftStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastEnforcedTime != nil {
+		in, out := &in.LastEnforcedTime, &out.LastEnforcedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.QueuePosition != nil {
+		in, out := &in.QueuePosition, &out.QueuePosition
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSummaryStatus.
@@ -450,7 +678,7 @@ func (in *Clusters) DeepCopyInto(out *Clusters) {
 	}
 	if in.Clusters != nil {
 		in, out := &in.Clusters, &out.Clusters
-		*out = make([]v1.ObjectReference, len(*in))
+		*out = make([]corev1.ObjectReference, len(*in))
 		copy(*out, *in)
 	}
 }
@@ -466,142 +694,412 @@ func (in *Clusters) DeepCopy() *Clusters {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DriftExclusion) DeepCopyInto(out *DriftExclusion) {
+func (in *ConfigurationSnapshot) DeepCopyInto(out *ConfigurationSnapshot) {
 	*out = *in
-	if in.Paths != nil {
-		in, out := &in.Paths, &out.Paths
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Target != nil {
-		in, out := &in.Target, &out.Target
-		*out = new(apiv1beta1.PatchSelector)
-		**out = **in
-	}
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftExclusion.
-func (in *DriftExclusion) DeepCopy() *DriftExclusion {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationSnapshot.
+func (in *ConfigurationSnapshot) DeepCopy() *ConfigurationSnapshot {
 	if in == nil {
 		return nil
 	}
-	out := new(DriftExclusion)
+	out := new(ConfigurationSnapshot)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DryRunReconciliationError) DeepCopyInto(out *DryRunReconciliationError) {
-	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunReconciliationError.
-func (in *DryRunReconciliationError) DeepCopy() *DryRunReconciliationError {
-	if in == nil {
-		return nil
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigurationSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	out := new(DryRunReconciliationError)
-	in.DeepCopyInto(out)
-	return out
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Feature) DeepCopyInto(out *Feature) {
+func (in *ConfigurationSnapshotList) DeepCopyInto(out *ConfigurationSnapshotList) {
 	*out = *in
-	if in.Resources != nil {
-		in, out := &in.Resources, &out.Resources
-		*out = make([]Resource, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.Charts != nil {
-		in, out := &in.Charts, &out.Charts
-		*out = make([]Chart, len(*in))
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ConfigurationSnapshot, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Feature.
-func (in *Feature) DeepCopy() *Feature {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationSnapshotList.
+func (in *ConfigurationSnapshotList) DeepCopy() *ConfigurationSnapshotList {
 	if in == nil {
 		return nil
 	}
-	out := new(Feature)
+	out := new(ConfigurationSnapshotList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigurationSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *FeatureDeploymentInfo) DeepCopyInto(out *FeatureDeploymentInfo) {
+func (in *ConfigurationSnapshotSpec) DeepCopyInto(out *ConfigurationSnapshotSpec) {
 	*out = *in
-	if in.DeployedGroupVersionKind != nil {
-		in, out := &in.DeployedGroupVersionKind, &out.DeployedGroupVersionKind
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureDeploymentInfo.
-func (in *FeatureDeploymentInfo) DeepCopy() *FeatureDeploymentInfo {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationSnapshotSpec.
+func (in *ConfigurationSnapshotSpec) DeepCopy() *ConfigurationSnapshotSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(FeatureDeploymentInfo)
+	out := new(ConfigurationSnapshotSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *FeatureSummary) DeepCopyInto(out *FeatureSummary) {
+func (in *ConfigurationSnapshotStatus) DeepCopyInto(out *ConfigurationSnapshotStatus) {
 	*out = *in
-	if in.Hash != nil {
-		in, out := &in.Hash, &out.Hash
-		*out = make([]byte, len(*in))
+	if in.CaptureTime != nil {
+		in, out := &in.CaptureTime, &out.CaptureTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Differences != nil {
+		in, out := &in.Differences, &out.Differences
+		*out = make([]SnapshotDifference, len(*in))
 		copy(*out, *in)
 	}
-	if in.FailureReason != nil {
-		in, out := &in.FailureReason, &out.FailureReason
-		*out = new(string)
-		**out = **in
+	if in.LastRestoreTime != nil {
+		in, out := &in.LastRestoreTime, &out.LastRestoreTime
+		*out = (*in).DeepCopy()
 	}
 	if in.FailureMessage != nil {
 		in, out := &in.FailureMessage, &out.FailureMessage
 		*out = new(string)
 		**out = **in
 	}
-	if in.DeployedGroupVersionKind != nil {
-		in, out := &in.DeployedGroupVersionKind, &out.DeployedGroupVersionKind
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.LastAppliedTime != nil {
-		in, out := &in.LastAppliedTime, &out.LastAppliedTime
-		*out = (*in).DeepCopy()
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureSummary.
-func (in *FeatureSummary) DeepCopy() *FeatureSummary {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationSnapshotStatus.
+func (in *ConfigurationSnapshotStatus) DeepCopy() *ConfigurationSnapshotStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(FeatureSummary)
+	out := new(ConfigurationSnapshotStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmChart) DeepCopyInto(out *HelmChart) {
+func (in *Conformance) DeepCopyInto(out *Conformance) {
 	*out = *in
-	if in.ValuesFrom != nil {
-		in, out := &in.ValuesFrom, &out.ValuesFrom
-		*out = make([]ValueFrom, len(*in))
-		copy(*out, *in)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Conformance.
+func (in *Conformance) DeepCopy() *Conformance {
+	if in == nil {
+		return nil
 	}
-	if in.Options != nil {
+	out := new(Conformance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentJob) DeepCopyInto(out *DeploymentJob) {
+	*out = *in
+	out.JobTemplateRef = in.JobTemplateRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentJob.
+func (in *DeploymentJob) DeepCopy() *DeploymentJob {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftDetectionResources) DeepCopyInto(out *DriftDetectionResources) {
+	*out = *in
+	if in.ManagedCluster != nil {
+		in, out := &in.ManagedCluster, &out.ManagedCluster
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManagementCluster != nil {
+		in, out := &in.ManagementCluster, &out.ManagementCluster
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftDetectionResources.
+func (in *DriftDetectionResources) DeepCopy() *DriftDetectionResources {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftDetectionResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftEvent) DeepCopyInto(out *DriftEvent) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]DriftedResource, len(*in))
+		copy(*out, *in)
+	}
+	in.DetectionTime.DeepCopyInto(&out.DetectionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftEvent.
+func (in *DriftEvent) DeepCopy() *DriftEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftExcludedKind) DeepCopyInto(out *DriftExcludedKind) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftExcludedKind.
+func (in *DriftExcludedKind) DeepCopy() *DriftExcludedKind {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftExcludedKind)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftExclusion) DeepCopyInto(out *DriftExclusion) {
+	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(apiv1beta1.PatchSelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftExclusion.
+func (in *DriftExclusion) DeepCopy() *DriftExclusion {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftExclusion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftStatus) DeepCopyInto(out *DriftStatus) {
+	*out = *in
+	if in.LastDriftTime != nil {
+		in, out := &in.LastDriftTime, &out.LastDriftTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RecentDrifts != nil {
+		in, out := &in.RecentDrifts, &out.RecentDrifts
+		*out = make([]DriftEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftStatus.
+func (in *DriftStatus) DeepCopy() *DriftStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftedResource) DeepCopyInto(out *DriftedResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftedResource.
+func (in *DriftedResource) DeepCopy() *DriftedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunReconciliationError) DeepCopyInto(out *DryRunReconciliationError) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunReconciliationError.
+func (in *DryRunReconciliationError) DeepCopy() *DryRunReconciliationError {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunReconciliationError)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Feature) DeepCopyInto(out *Feature) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]Resource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Charts != nil {
+		in, out := &in.Charts, &out.Charts
+		*out = make([]Chart, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Feature.
+func (in *Feature) DeepCopy() *Feature {
+	if in == nil {
+		return nil
+	}
+	out := new(Feature)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeatureDeploymentInfo) DeepCopyInto(out *FeatureDeploymentInfo) {
+	*out = *in
+	if in.DeployedGroupVersionKind != nil {
+		in, out := &in.DeployedGroupVersionKind, &out.DeployedGroupVersionKind
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeployedResources != nil {
+		in, out := &in.DeployedResources, &out.DeployedResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeployedManagementClusterResources != nil {
+		in, out := &in.DeployedManagementClusterResources, &out.DeployedManagementClusterResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureDeploymentInfo.
+func (in *FeatureDeploymentInfo) DeepCopy() *FeatureDeploymentInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(FeatureDeploymentInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeatureSummary) DeepCopyInto(out *FeatureSummary) {
+	*out = *in
+	if in.Hash != nil {
+		in, out := &in.Hash, &out.Hash
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailureReason != nil {
+		in, out := &in.FailureReason, &out.FailureReason
+		*out = new(string)
+		**out = **in
+	}
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+	if in.DeployedGroupVersionKind != nil {
+		in, out := &in.DeployedGroupVersionKind, &out.DeployedGroupVersionKind
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeatureSummary.
+func (in *FeatureSummary) DeepCopy() *FeatureSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(FeatureSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChart) DeepCopyInto(out *HelmChart) {
+	*out = *in
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValueFrom, len(*in))
+		copy(*out, *in)
+	}
+	if in.ValuesOverrides != nil {
+		in, out := &in.ValuesOverrides, &out.ValuesOverrides
+		*out = make([]HelmValuesOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CreateNamespace != nil {
+		in, out := &in.CreateNamespace, &out.CreateNamespace
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Options != nil {
 		in, out := &in.Options, &out.Options
 		*out = new(HelmOptions)
 		(*in).DeepCopyInto(*out)
@@ -611,6 +1109,16 @@ func (in *HelmChart) DeepCopyInto(out *HelmChart) {
 		*out = new(RegistryCredentialsConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = new(HelmChartVerify)
+		**out = **in
+	}
+	if in.Tests != nil {
+		in, out := &in.Tests, &out.Tests
+		*out = new(HelmChartTests)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChart.
@@ -631,6 +1139,11 @@ func (in *HelmChartSummary) DeepCopyInto(out *HelmChartSummary) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.TestResult != nil {
+		in, out := &in.TestResult, &out.TestResult
+		*out = new(HelmTestResult)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartSummary.
@@ -643,6 +1156,42 @@ func (in *HelmChartSummary) DeepCopy() *HelmChartSummary {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartTests) DeepCopyInto(out *HelmChartTests) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartTests.
+func (in *HelmChartTests) DeepCopy() *HelmChartTests {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartTests)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartVerify) DeepCopyInto(out *HelmChartVerify) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartVerify.
+func (in *HelmChartVerify) DeepCopy() *HelmChartVerify {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartVerify)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HelmInstallOptions) DeepCopyInto(out *HelmInstallOptions) {
 	*out = *in
@@ -659,88 +1208,264 @@ func (in *HelmInstallOptions) DeepCopy() *HelmInstallOptions {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmOptions) DeepCopyInto(out *HelmOptions) {
+func (in *HelmOptions) DeepCopyInto(out *HelmOptions) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.InstallOptions = in.InstallOptions
+	out.UpgradeOptions = in.UpgradeOptions
+	out.UninstallOptions = in.UninstallOptions
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmOptions.
+func (in *HelmOptions) DeepCopy() *HelmOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmTestResult) DeepCopyInto(out *HelmTestResult) {
+	*out = *in
+	if in.FailedTests != nil {
+		in, out := &in.FailedTests, &out.FailedTests
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+	in.LastRunTime.DeepCopyInto(&out.LastRunTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmTestResult.
+func (in *HelmTestResult) DeepCopy() *HelmTestResult {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmTestResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmUninstallOptions) DeepCopyInto(out *HelmUninstallOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmUninstallOptions.
+func (in *HelmUninstallOptions) DeepCopy() *HelmUninstallOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmUninstallOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmUpgradeOptions) DeepCopyInto(out *HelmUpgradeOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmUpgradeOptions.
+func (in *HelmUpgradeOptions) DeepCopy() *HelmUpgradeOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmUpgradeOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmValuesOverride) DeepCopyInto(out *HelmValuesOverride) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmValuesOverride.
+func (in *HelmValuesOverride) DeepCopy() *HelmValuesOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmValuesOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KustomizationRef) DeepCopyInto(out *KustomizationRef) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValueFrom, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KustomizationRef.
+func (in *KustomizationRef) DeepCopy() *KustomizationRef {
+	if in == nil {
+		return nil
+	}
+	out := new(KustomizationRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindow) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowList) DeepCopyInto(out *MaintenanceWindowList) {
 	*out = *in
-	if in.Timeout != nil {
-		in, out := &in.Timeout, &out.Timeout
-		*out = new(metav1.Duration)
-		**out = **in
-	}
-	if in.Labels != nil {
-		in, out := &in.Labels, &out.Labels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	out.InstallOptions = in.InstallOptions
-	out.UpgradeOptions = in.UpgradeOptions
-	out.UninstallOptions = in.UninstallOptions
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmOptions.
-func (in *HelmOptions) DeepCopy() *HelmOptions {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowList.
+func (in *MaintenanceWindowList) DeepCopy() *MaintenanceWindowList {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmOptions)
+	out := new(MaintenanceWindowList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindowList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmUninstallOptions) DeepCopyInto(out *HelmUninstallOptions) {
+func (in *MaintenanceWindowSpec) DeepCopyInto(out *MaintenanceWindowSpec) {
 	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+	out.Duration = in.Duration
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmUninstallOptions.
-func (in *HelmUninstallOptions) DeepCopy() *HelmUninstallOptions {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowSpec.
+func (in *MaintenanceWindowSpec) DeepCopy() *MaintenanceWindowSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmUninstallOptions)
+	out := new(MaintenanceWindowSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmUpgradeOptions) DeepCopyInto(out *HelmUpgradeOptions) {
+func (in *MetadataPropagationTarget) DeepCopyInto(out *MetadataPropagationTarget) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmUpgradeOptions.
-func (in *HelmUpgradeOptions) DeepCopy() *HelmUpgradeOptions {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataPropagationTarget.
+func (in *MetadataPropagationTarget) DeepCopy() *MetadataPropagationTarget {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmUpgradeOptions)
+	out := new(MetadataPropagationTarget)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KustomizationRef) DeepCopyInto(out *KustomizationRef) {
+func (in *NamespaceMetadata) DeepCopyInto(out *NamespaceMetadata) {
 	*out = *in
-	if in.Values != nil {
-		in, out := &in.Values, &out.Values
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
 		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
 	}
-	if in.ValuesFrom != nil {
-		in, out := &in.ValuesFrom, &out.ValuesFrom
-		*out = make([]ValueFrom, len(*in))
-		copy(*out, *in)
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KustomizationRef.
-func (in *KustomizationRef) DeepCopy() *KustomizationRef {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceMetadata.
+func (in *NamespaceMetadata) DeepCopy() *NamespaceMetadata {
 	if in == nil {
 		return nil
 	}
-	out := new(KustomizationRef)
+	out := new(NamespaceMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PinnedChart) DeepCopyInto(out *PinnedChart) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PinnedChart.
+func (in *PinnedChart) DeepCopy() *PinnedChart {
+	if in == nil {
+		return nil
+	}
+	out := new(PinnedChart)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -841,17 +1566,39 @@ func (in *ProfileResource) DeepCopy() *ProfileResource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileRevision) DeepCopyInto(out *ProfileRevision) {
+	*out = *in
+	if in.Hash != nil {
+		in, out := &in.Hash, &out.Hash
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	in.CreationTime.DeepCopyInto(&out.CreationTime)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileRevision.
+func (in *ProfileRevision) DeepCopy() *ProfileRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RegistryCredentialsConfig) DeepCopyInto(out *RegistryCredentialsConfig) {
 	*out = *in
 	if in.CredentialsSecretRef != nil {
 		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
-		*out = new(v1.SecretReference)
+		*out = new(corev1.SecretReference)
 		**out = **in
 	}
 	if in.CASecretRef != nil {
 		in, out := &in.CASecretRef, &out.CASecretRef
-		*out = new(v1.SecretReference)
+		*out = new(corev1.SecretReference)
 		**out = **in
 	}
 }
@@ -866,6 +1613,21 @@ func (in *RegistryCredentialsConfig) DeepCopy() *RegistryCredentialsConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryMirror) DeepCopyInto(out *RegistryMirror) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryMirror.
+func (in *RegistryMirror) DeepCopy() *RegistryMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReleaseReport) DeepCopyInto(out *ReleaseReport) {
 	*out = *in
@@ -881,6 +1643,26 @@ func (in *ReleaseReport) DeepCopy() *ReleaseReport {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedChartVersion) DeepCopyInto(out *ResolvedChartVersion) {
+	*out = *in
+	if in.FailureMessage != nil {
+		in, out := &in.FailureMessage, &out.FailureMessage
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedChartVersion.
+func (in *ResolvedChartVersion) DeepCopy() *ResolvedChartVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedChartVersion)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Resource) DeepCopyInto(out *Resource) {
 	*out = *in
@@ -917,13 +1699,131 @@ func (in *ResourceReport) DeepCopy() *ResourceReport {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutNotification) DeepCopyInto(out *RolloutNotification) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutNotification.
+func (in *RolloutNotification) DeepCopy() *RolloutNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutRing) DeepCopyInto(out *RolloutRing) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+	if in.SoakTime != nil {
+		in, out := &in.SoakTime, &out.SoakTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxFailures != nil {
+		in, out := &in.MaxFailures, &out.MaxFailures
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutRing.
+func (in *RolloutRing) DeepCopy() *RolloutRing {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutRing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutRingStatus) DeepCopyInto(out *RolloutRingStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutRingStatus.
+func (in *RolloutRingStatus) DeepCopy() *RolloutRingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutRingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	if in.Hash != nil {
+		in, out := &in.Hash, &out.Hash
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rings != nil {
+		in, out := &in.Rings, &out.Rings
+		*out = make([]RolloutRingStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotDifference) DeepCopyInto(out *SnapshotDifference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotDifference.
+func (in *SnapshotDifference) DeepCopy() *SnapshotDifference {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotDifference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SourceRef) DeepCopyInto(out *SourceRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourceRef.
+func (in *SourceRef) DeepCopy() *SourceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SourceRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Spec) DeepCopyInto(out *Spec) {
 	*out = *in
 	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
 	if in.ClusterRefs != nil {
 		in, out := &in.ClusterRefs, &out.ClusterRefs
-		*out = make([]v1.ObjectReference, len(*in))
+		*out = make([]corev1.ObjectReference, len(*in))
 		copy(*out, *in)
 	}
 	if in.SetRefs != nil {
@@ -951,6 +1851,11 @@ func (in *Spec) DeepCopyInto(out *Spec) {
 		*out = make([]PolicyRef, len(*in))
 		copy(*out, *in)
 	}
+	if in.SourceRef != nil {
+		in, out := &in.SourceRef, &out.SourceRef
+		*out = new(SourceRef)
+		**out = **in
+	}
 	if in.HelmCharts != nil {
 		in, out := &in.HelmCharts, &out.HelmCharts
 		*out = make([]HelmChart, len(*in))
@@ -972,6 +1877,16 @@ func (in *Spec) DeepCopyInto(out *Spec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PreDeploymentJobs != nil {
+		in, out := &in.PreDeploymentJobs, &out.PreDeploymentJobs
+		*out = make([]DeploymentJob, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostDeploymentJobs != nil {
+		in, out := &in.PostDeploymentJobs, &out.PostDeploymentJobs
+		*out = make([]DeploymentJob, len(*in))
+		copy(*out, *in)
+	}
 	if in.Patches != nil {
 		in, out := &in.Patches, &out.Patches
 		*out = make([]apiv1beta1.Patch, len(*in))
@@ -979,6 +1894,11 @@ func (in *Spec) DeepCopyInto(out *Spec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RegistryMirrors != nil {
+		in, out := &in.RegistryMirrors, &out.RegistryMirrors
+		*out = make([]RegistryMirror, len(*in))
+		copy(*out, *in)
+	}
 	if in.DriftExclusions != nil {
 		in, out := &in.DriftExclusions, &out.DriftExclusions
 		*out = make([]DriftExclusion, len(*in))
@@ -986,6 +1906,16 @@ func (in *Spec) DeepCopyInto(out *Spec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DriftExcludedKinds != nil {
+		in, out := &in.DriftExcludedKinds, &out.DriftExcludedKinds
+		*out = make([]DriftExcludedKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.DriftDetectionResources != nil {
+		in, out := &in.DriftDetectionResources, &out.DriftDetectionResources
+		*out = new(DriftDetectionResources)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ExtraLabels != nil {
 		in, out := &in.ExtraLabels, &out.ExtraLabels
 		*out = make(map[string]string, len(*in))
@@ -1000,6 +1930,55 @@ func (in *Spec) DeepCopyInto(out *Spec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ClusterReadinessChecks != nil {
+		in, out := &in.ClusterReadinessChecks, &out.ClusterReadinessChecks
+		*out = make([]ClusterReadinessCheck, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterMetadataPropagations != nil {
+		in, out := &in.ClusterMetadataPropagations, &out.ClusterMetadataPropagations
+		*out = make([]ClusterMetadataPropagation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RolloutRings != nil {
+		in, out := &in.RolloutRings, &out.RolloutRings
+		*out = make([]RolloutRing, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RolloutFailureThreshold != nil {
+		in, out := &in.RolloutFailureThreshold, &out.RolloutFailureThreshold
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.EnforceInterval != nil {
+		in, out := &in.EnforceInterval, &out.EnforceInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DeploymentOrder != nil {
+		in, out := &in.DeploymentOrder, &out.DeploymentOrder
+		*out = make([]FeatureID, len(*in))
+		copy(*out, *in)
+	}
+	if in.TenantRef != nil {
+		in, out := &in.TenantRef, &out.TenantRef
+		*out = new(TenantRef)
+		**out = **in
+	}
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = make([]RolloutNotification, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetNamespaceMetadata != nil {
+		in, out := &in.TargetNamespaceMetadata, &out.TargetNamespaceMetadata
+		*out = new(NamespaceMetadata)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Spec.
@@ -1017,11 +1996,47 @@ func (in *Status) DeepCopyInto(out *Status) {
 	*out = *in
 	if in.MatchingClusterRefs != nil {
 		in, out := &in.MatchingClusterRefs, &out.MatchingClusterRefs
-		*out = make([]v1.ObjectReference, len(*in))
+		*out = make([]corev1.ObjectReference, len(*in))
 		copy(*out, *in)
 	}
 	in.UpdatingClusters.DeepCopyInto(&out.UpdatingClusters)
 	in.UpdatedClusters.DeepCopyInto(&out.UpdatedClusters)
+	if in.ClusterSummaries != nil {
+		in, out := &in.ClusterSummaries, &out.ClusterSummaries
+		*out = new(ClusterSummariesStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastRolledOutSpec != nil {
+		in, out := &in.LastRolledOutSpec, &out.LastRolledOutSpec
+		*out = new(Spec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RevisionHistory != nil {
+		in, out := &in.RevisionHistory, &out.RevisionHistory
+		*out = make([]ProfileRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResolvedChartVersions != nil {
+		in, out := &in.ResolvedChartVersions, &out.ResolvedChartVersions
+		*out = make([]ResolvedChartVersion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Status.
@@ -1034,6 +2049,21 @@ func (in *Status) DeepCopy() *Status {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenantRef) DeepCopyInto(out *TenantRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantRef.
+func (in *TenantRef) DeepCopy() *TenantRef {
+	if in == nil {
+		return nil
+	}
+	out := new(TenantRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TemplateResourceRef) DeepCopyInto(out *TemplateResourceRef) {
 	*out = *in
@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
@@ -42,7 +43,9 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -56,33 +59,62 @@ import (
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	"github.com/projectsveltos/addon-controller/api/v1beta1/index"
 	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/controllers/chartmanager"
+	"github.com/projectsveltos/addon-controller/pkg/clusterdeployer"
+	"github.com/projectsveltos/addon-controller/pkg/crsmigration"
+	"github.com/projectsveltos/addon-controller/pkg/lint"
+	"github.com/projectsveltos/addon-controller/pkg/logbuffer"
+	"github.com/projectsveltos/addon-controller/pkg/verifier"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 	"github.com/projectsveltos/libsveltos/lib/crd"
-	"github.com/projectsveltos/libsveltos/lib/deployer"
 	logsettings "github.com/projectsveltos/libsveltos/lib/logsettings"
 	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
 	//+kubebuilder:scaffold:imports
 )
 
 var (
-	setupLog                = ctrl.Log.WithName("setup")
-	diagnosticsAddress      string
-	insecureDiagnostics     bool
-	shardKey                string
-	workers                 int
-	concurrentReconciles    int
-	agentInMgmtCluster      bool
-	reportMode              controllers.ReportMode
-	tmpReportMode           int
-	restConfigQPS           float32
-	restConfigBurst         int
-	webhookPort             int
-	syncPeriod              time.Duration
-	conflictRetryTime       time.Duration
-	version                 string
-	healthAddr              string
-	profilerAddress         string
-	driftDetectionConfigMap string
+	setupLog                    = ctrl.Log.WithName("setup")
+	diagnosticsAddress          string
+	insecureDiagnostics         bool
+	shardKey                    string
+	workers                     int
+	perClusterWorkers           int
+	perClusterQueueSize         int
+	healthVerifierOptions       verifier.Options
+	concurrentReconciles        int
+	agentInMgmtCluster          bool
+	reportMode                  controllers.ReportMode
+	tmpReportMode               int
+	restConfigQPS               float32
+	restConfigBurst             int
+	webhookPort                 int
+	syncPeriod                  time.Duration
+	conflictRetryTime           time.Duration
+	deploymentSlotTimeout       time.Duration
+	clusterSummaryRequeue       controllers.RequeuePolicy
+	profileRequeue              controllers.RequeuePolicy
+	setRequeue                  controllers.RequeuePolicy
+	retryPolicy                 controllers.RetryPolicy
+	version                     string
+	healthAddr                  string
+	profilerAddress             string
+	driftDetectionConfigMap     string
+	driftDetectionImage         string
+	driftExcludedKinds          []string
+	disallowHelmReleaseAdoption bool
+	helmMaxConcurrentReleases   int
+	clusterConfigSnapshots      int
+	referencedResourceSelector  string
+	shutdownGracePeriod         time.Duration
+	debugLogBufferSize          int
+	outboundTLSOptions          controllers.OutboundTLSOptions
+	chartCacheOptions           controllers.ChartCacheOptions
+	chartCacheMaxSizeMiB        int64
+	chartRepositoryRetryOptions controllers.ChartRepositoryRetryOptions
+	chartIndexRefreshInterval   time.Duration
+	listPageSize                int64
+	auditLogOptions             controllers.AuditLogOptions
+	remoteClientCacheOptions    controllers.RemoteClientCacheOptions
 )
 
 const (
@@ -92,6 +124,9 @@ const (
 	defaulReportMode     = int(controllers.CollectFromManagementCluster)
 	mebibytes_bytes      = 1 << 20
 	gibibytes_per_bytes  = 1 << 30
+	// gracefulShutdownMargin (in seconds) is added to the shutdown grace period to let aborted
+	// operations return and persist their checkpoints before manager stops
+	gracefulShutdownMargin = 10
 )
 
 // Add RBAC for the authorized diagnostics endpoint.
@@ -99,6 +134,13 @@ const (
 // +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate-crs" {
+		os.Exit(runMigrateCRS(os.Args[2:]))
+	}
+
 	scheme, err := controllers.InitScheme()
 	if err != nil {
 		os.Exit(1)
@@ -113,7 +155,14 @@ func main() {
 
 	reportMode = controllers.ReportMode(tmpReportMode)
 
-	ctrl.SetLogger(klog.Background())
+	logger := klog.Background()
+	if debugLogBufferSize > 0 {
+		// Keep recent logs in memory so they can be included in debug bundles
+		buffer := logbuffer.New(debugLogBufferSize)
+		controllers.SetDebugLogBuffer(buffer)
+		logger = logr.New(buffer.WrapSink(logger.GetSink()))
+	}
+	ctrl.SetLogger(logger)
 	ctrlOptions := ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                getDiagnosticsOptions(),
@@ -126,6 +175,8 @@ func main() {
 			SyncPeriod: &syncPeriod,
 		},
 		PprofBindAddress: profilerAddress,
+		// Leave in-flight remote operations time to drain before manager gives up on runnables
+		GracefulShutdownTimeout: ptr.To(shutdownGracePeriod + gracefulShutdownMargin*time.Second),
 	}
 
 	restConfig := ctrl.GetConfigOrDie()
@@ -142,19 +193,79 @@ func main() {
 	ctx := ctrl.SetupSignalHandler()
 	controllers.SetManagementClusterAccess(mgr.GetClient(), mgr.GetConfig())
 	controllers.SetDriftdetectionConfigMap(driftDetectionConfigMap)
+	controllers.SetDriftDetectionImage(driftDetectionImage)
+	if err := controllers.SetDriftExcludedKinds(driftExcludedKinds); err != nil {
+		setupLog.Error(err, "invalid drift-excluded-kinds")
+		os.Exit(1)
+	}
+	controllers.SetShutdownGracePeriod(shutdownGracePeriod)
+	controllers.SetListPageSize(listPageSize)
+	controllers.SetDisallowHelmReleaseAdoption(disallowHelmReleaseAdoption)
+	controllers.SetHelmMaxConcurrentReleases(helmMaxConcurrentReleases)
+	controllers.SetClusterConfigurationSnapshots(clusterConfigSnapshots)
+	if err := controllers.SetReferencedResourceSelector(referencedResourceSelector); err != nil {
+		setupLog.Error(err, "invalid referenced-resource-selector")
+		os.Exit(1)
+	}
+	if err := controllers.SetOutboundTLSOptions(&outboundTLSOptions); err != nil {
+		setupLog.Error(err, "invalid outbound TLS configuration")
+		os.Exit(1)
+	}
+	chartCacheOptions.MaxSize = chartCacheMaxSizeMiB * mebibytes_bytes
+	if err := controllers.SetChartCacheOptions(&chartCacheOptions); err != nil {
+		setupLog.Error(err, "invalid chart cache configuration")
+		os.Exit(1)
+	}
+	if err := controllers.SetChartRepositoryRetryOptions(&chartRepositoryRetryOptions); err != nil {
+		setupLog.Error(err, "invalid chart repository retry configuration")
+		os.Exit(1)
+	}
+	controllers.SetChartIndexRefreshInterval(chartIndexRefreshInterval)
+	if err := controllers.SetRemoteClientCacheOptions(&remoteClientCacheOptions); err != nil {
+		setupLog.Error(err, "invalid remote client cache configuration")
+		os.Exit(1)
+	}
+	if err := controllers.SetAuditLogOptions(&auditLogOptions); err != nil {
+		setupLog.Error(err, "invalid audit log configuration")
+		os.Exit(1)
+	}
+	chartmanager.EnablePersistence(shardKey)
 
 	logsettings.RegisterForLogSettings(ctx,
 		libsveltosv1beta1.ComponentAddonManager, ctrl.Log.WithName("log-setter"),
 		ctrl.GetConfigOrDie())
 
+	// Manager cache is not started yet. Use APIReader.
+	if err := controllers.CheckRequiredCRDs(ctx, mgr.GetAPIReader()); err != nil {
+		setupLog.Error(err, "required CRDs are missing or incompatible")
+		os.Exit(1)
+	}
+
 	debug.SetMemoryLimit(gibibytes_per_bytes)
 	go printMemUsage(ctrl.Log.WithName("memory-usage"))
 
 	startControllersAndWatchers(ctx, mgr)
 
 	setupChecks(mgr)
+	setupDescribeHandler(mgr)
 	controllers.SetVersion(version)
 
+	if err := mgr.Add(controllers.NewRemoteOperationsDrainer(mgr, shardKey, ctrl.Log.WithName("drainer"))); err != nil {
+		setupLog.Error(err, "unable to add remote operations drainer")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(controllers.NewOperationCheckpointsLoader(mgr, shardKey,
+		ctrl.Log.WithName("checkpoints-loader"))); err != nil {
+		setupLog.Error(err, "unable to add operation checkpoints loader")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(controllers.NewChartCacheCollector(ctrl.Log.WithName("chart-cache-collector"))); err != nil {
+		setupLog.Error(err, "unable to add chart cache collector")
+		os.Exit(1)
+	}
+
 	setupIndexes(ctx, mgr)
 
 	setupLog.Info("starting manager")
@@ -185,6 +296,32 @@ func initFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&workers, "worker-number", defaultWorkers,
 		"Number of worker. Workers are used to deploy features in CAPI clusters")
 
+	const defaultPerClusterWorkers = 1
+	fs.IntVar(&perClusterWorkers, "per-cluster-worker-number", defaultPerClusterWorkers,
+		fmt.Sprintf("Maximum number of workers deploying features in the same cluster at the same time. "+
+			"Bounds how many workers a slow or unreachable cluster can hold. Default: %d", defaultPerClusterWorkers))
+
+	const defaultPerClusterQueueSize = 1000
+	fs.IntVar(&perClusterQueueSize, "per-cluster-queue-size", defaultPerClusterQueueSize,
+		fmt.Sprintf("Maximum number of pending deployment requests per cluster. When full, new requests are "+
+			"retried later. Zero means no limit. Default: %d", defaultPerClusterQueueSize))
+
+	const defaultHealthVerificationWorkers = 10
+	fs.IntVar(&healthVerifierOptions.PoolSize, "health-verification-worker-number", defaultHealthVerificationWorkers,
+		fmt.Sprintf("Number of workers verifying health (ValidateHealths) of deployed features. Those workers are "+
+			"separate from the ones deploying features. Zero means health is verified by the workers deploying "+
+			"features. Default: %d", defaultHealthVerificationWorkers))
+
+	const defaultHealthVerificationQPS = 20
+	fs.Float64Var(&healthVerifierOptions.QPS, "health-verification-qps", defaultHealthVerificationQPS,
+		fmt.Sprintf("Maximum number of health verifications started per second. Default: %d",
+			defaultHealthVerificationQPS))
+
+	const defaultHealthVerificationBurst = 40
+	fs.IntVar(&healthVerifierOptions.Burst, "health-verification-burst", defaultHealthVerificationBurst,
+		fmt.Sprintf("Maximum number of health verifications started at once. Default: %d",
+			defaultHealthVerificationBurst))
+
 	fs.IntVar(&concurrentReconciles, "concurrent-reconciles", defaultReconcilers,
 		"concurrent reconciles is the maximum number of concurrent Reconciles which can be run. Defaults to 10")
 
@@ -197,7 +334,32 @@ func initFlags(fs *pflag.FlagSet) {
 		"Bind address to expose the pprof profiler (e.g. localhost:6060)")
 
 	fs.StringVar(&driftDetectionConfigMap, "drift-detection-config", "",
-		"The name of the ConfigMap in the projectsveltos namespace containing the drift-detection-manager configuration")
+		"The name of the ConfigMap in the projectsveltos namespace containing the drift-detection-manager configuration. "+
+			"Each entry is a patch applied to the drift-detection-manager Deployment "+
+			"(e.g. to set resources, tolerations or nodeSelector)")
+
+	fs.StringVar(&driftDetectionImage, "drift-detection-image", "",
+		"The image used for drift-detection-manager (e.g. registry.example.com/projectsveltos/drift-detection-manager:v0.38.0). "+
+			"If not set, the image embedded in addon-controller is used")
+
+	fs.StringSliceVar(&driftExcludedKinds, "drift-excluded-kinds", nil,
+		"Comma separated list of kinds, in the form apiVersion/Kind (e.g. v1/Event,batch/v1/Job), never tracked for configuration drift")
+
+	fs.BoolVar(&disallowHelmReleaseAdoption, "disallow-helm-release-adoption", false,
+		"When set, helm releases not installed by Sveltos are adopted only if helmChartAction is set to Manage")
+
+	fs.IntVar(&helmMaxConcurrentReleases, "helm-max-concurrent-releases", 1,
+		"Maximum number of helm charts of a ClusterSummary deployed concurrently. Operations on the same release "+
+			"are always serialized. Leave to 1 if charts must be deployed in the order they are listed")
+
+	const defaultClusterConfigSnapshots = 20
+	fs.IntVar(&clusterConfigSnapshots, "cluster-configuration-snapshots", defaultClusterConfigSnapshots,
+		fmt.Sprintf("Number of snapshots of the add-ons deployed in each cluster (ClusterConfiguration) retained "+
+			"to compare them over time. Zero disables snapshots. Default: %d", defaultClusterConfigSnapshots))
+
+	fs.StringVar(&referencedResourceSelector, "referenced-resource-selector", "",
+		"When set, content of referenced ConfigMaps/Secrets is deployed only if their labels match this selector "+
+			"(e.g. projectsveltos.io/policy=true)")
 
 	const defautlRestConfigQPS = 20
 	fs.Float32Var(&restConfigQPS, "kube-api-qps", defautlRestConfigQPS,
@@ -222,6 +384,157 @@ func initFlags(fs *pflag.FlagSet) {
 	fs.DurationVar(&conflictRetryTime, "conflict-retry-time", defaultConflictRetryTime*time.Second,
 		fmt.Sprintf("The minimum interval at which watched ClusterProfile with conflicts are retried. Defaul: %d seconds",
 			defaultConflictRetryTime))
+
+	addRequeuePolicyFlags(fs, "clustersummary", "ClusterSummary", &clusterSummaryRequeue, true)
+	addRequeuePolicyFlags(fs, "profile", "ClusterProfile/Profile", &profileRequeue, true)
+	addRequeuePolicyFlags(fs, "set", "ClusterSet/Set", &setRequeue, false)
+
+	addRetryPolicyFlags(fs, &retryPolicy)
+
+	fs.DurationVar(&deploymentSlotTimeout, "deployment-slot-timeout", 0,
+		"How long a cluster can hold one of the MaxConcurrentClusterDeployments slots of a profile while other "+
+			"clusters are waiting. Zero means default (30m)")
+
+	const defaultShutdownGracePeriod = 60
+	fs.DurationVar(&shutdownGracePeriod, "shutdown-grace-period", defaultShutdownGracePeriod*time.Second,
+		fmt.Sprintf("On shutdown, the maximum time in-flight helm, resources and kustomize operations are given to complete before being aborted. Default: %d seconds",
+			defaultShutdownGracePeriod))
+
+	const defaultListPageSize = 500
+	fs.Int64Var(&listPageSize, "list-page-size", defaultListPageSize,
+		fmt.Sprintf("Maximum number of objects fetched per request when listing all ClusterSummaries, ClusterReports "+
+			"and ClusterConfigurations. Zero disables pagination. Default: %d", defaultListPageSize))
+
+	addOutboundTLSFlags(fs, &outboundTLSOptions)
+
+	addChartCacheFlags(fs, &chartCacheOptions)
+
+	addChartRepositoryRetryFlags(fs, &chartRepositoryRetryOptions)
+
+	addRemoteClientCacheFlags(fs, &remoteClientCacheOptions)
+
+	addAuditLogFlags(fs, &auditLogOptions)
+
+	const defaultChartIndexRefreshInterval = 10
+	fs.DurationVar(&chartIndexRefreshInterval, "chart-index-refresh-interval", defaultChartIndexRefreshInterval*time.Minute,
+		fmt.Sprintf("How often helm repository indexes are checked for new chart versions matching the ChartVersion "+
+			"constraints. Default: %d minutes", defaultChartIndexRefreshInterval))
+
+	const defaultDebugLogBufferSize = 5000
+	fs.IntVar(&debugLogBufferSize, "debug-log-buffer-size", defaultDebugLogBufferSize,
+		fmt.Sprintf("Number of recent log lines kept in memory and included in debug bundles requested with the %s "+
+			"annotation. Zero disables it. Default: %d", configv1beta1.CollectDebugBundleAnnotation, defaultDebugLogBufferSize))
+}
+
+// addRetryPolicyFlags adds the flags to configure how deploying features in managed clusters
+// is retried after a failure. Zero values mean controller defaults are used.
+func addRetryPolicyFlags(fs *pflag.FlagSet, policy *controllers.RetryPolicy) {
+	fs.DurationVar(&policy.BaseBackoff, "retry-base-backoff", 0,
+		"How long to wait before retrying deploying a feature in a managed cluster after the first failure. "+
+			"Each consecutive failure doubles it. Zero means default (10s)")
+
+	fs.DurationVar(&policy.MaxBackoff, "retry-max-backoff", 0,
+		"Maximum time to wait before retrying deploying a feature in a managed cluster. Zero means default (5m)")
+
+	const defaultJitter = 0.1
+	fs.Float64Var(&policy.Jitter, "retry-jitter", defaultJitter,
+		fmt.Sprintf("Fraction (between 0 and 1) of random time added to each retry backoff. Default: %.1f", defaultJitter))
+
+	fs.Int32Var(&policy.MaxAttempts, "retry-max-attempts", 0,
+		"Number of consecutive failures deploying a feature in a managed cluster after which no attempt is made "+
+			"for retry-circuit-break-duration. Zero disables circuit breaking. Default: 0")
+
+	fs.DurationVar(&policy.CircuitBreakDuration, "retry-circuit-break-duration", 0,
+		"How long no attempt is made once retry-max-attempts consecutive failures are reached. "+
+			"Zero means retry-max-backoff")
+}
+
+// addChartCacheFlags adds the flags to configure the helm repository cache garbage collection
+func addChartCacheFlags(fs *pflag.FlagSet, options *controllers.ChartCacheOptions) {
+	fs.StringVar(&options.Dir, "chart-cache-dir", "",
+		"Directory where helm repository index files and charts are cached. If omitted, helm default is used")
+
+	fs.Int64Var(&chartCacheMaxSizeMiB, "chart-cache-max-size", 0,
+		"Size, in MiB, above which least recently used files are evicted from the chart cache. "+
+			"Zero means no limit. Default: 0")
+
+	fs.DurationVar(&options.TTL, "chart-cache-ttl", 0,
+		"Files in the chart cache not used for longer than this are evicted (e.g. 24h). Zero means no TTL. Default: 0")
+
+	fs.DurationVar(&options.GCInterval, "chart-cache-gc-interval", 0,
+		"How often the chart cache and stale temporary files are garbage collected. Zero means default (10m)")
+
+	fs.BoolVar(&options.RenderCache, "chart-render-cache", false,
+		"Reuse, across clusters, chart templates rendered for a release with same chart version and values. "+
+			"Charts whose templates depend on the cluster (lookup, Capabilities) or on each render (random, dates) "+
+			"are always rendered. Default: false")
+}
+
+// addRemoteClientCacheFlags adds the flags to configure the cache of clients (rest config, RESTMapper,
+// dynamic client) used to access managed clusters
+func addRemoteClientCacheFlags(fs *pflag.FlagSet, options *controllers.RemoteClientCacheOptions) {
+	fs.IntVar(&options.Size, "remote-client-cache-size", 0,
+		"Maximum number of cached clients to access managed clusters. Least recently used are evicted first. "+
+			"Zero means default (256). A negative value disables the cache")
+
+	fs.DurationVar(&options.TTL, "remote-client-cache-ttl", 0,
+		"How long a cached client to access a managed cluster is used before being rebuilt. Zero means default (10m)")
+}
+
+// addAuditLogFlags adds the flags to configure where the audit trail of changes applied to
+// managed clusters is written
+func addAuditLogFlags(fs *pflag.FlagSet, options *controllers.AuditLogOptions) {
+	fs.StringVar(&options.File, "audit-log-file", "",
+		"File every change applied to managed clusters is appended to, one JSON record per line. "+
+			"If omitted, no audit file is written")
+
+	fs.StringVar(&options.WebhookURL, "audit-webhook-url", "",
+		"URL every change applied to managed clusters is POSTed to as a JSON record. "+
+			"If omitted, no audit webhook is invoked")
+}
+
+// addChartRepositoryRetryFlags adds the flags to configure how requests to helm chart repositories
+// rate limited (429) or failed (5xx) by the repository are retried
+func addChartRepositoryRetryFlags(fs *pflag.FlagSet, options *controllers.ChartRepositoryRetryOptions) {
+	const defaultMaxRetries = 3
+	fs.IntVar(&options.MaxRetries, "chart-repository-max-retries", defaultMaxRetries,
+		fmt.Sprintf("Number of times a request to a helm chart repository is retried when rate limited (429) or "+
+			"failed (408, 5xx). Zero disables retries. Default: %d", defaultMaxRetries))
+
+	fs.DurationVar(&options.BaseBackoff, "chart-repository-retry-base-backoff", 0,
+		"How long to wait before retrying a request to a helm chart repository not sending Retry-After. "+
+			"Each retry doubles it. Zero means default (1s)")
+
+	fs.DurationVar(&options.MaxWait, "chart-repository-retry-max-wait", 0,
+		"Maximum time to wait before retrying a request to a helm chart repository. Requests the repository "+
+			"asks (Retry-After) to retry later than this are not retried. Zero means default (30s)")
+}
+
+// addOutboundTLSFlags adds the flags to configure TLS for connections to chart repositories
+// and OCI registries
+func addOutboundTLSFlags(fs *pflag.FlagSet, options *controllers.OutboundTLSOptions) {
+	fs.BoolVar(&options.Strict, "strict-outbound-tls", false,
+		"If set, connections to helm chart repositories and OCI registries enforce the outbound-tls-* settings. "+
+			"Default: false")
+
+	fs.StringVar(&options.MinVersion, "outbound-tls-min-version", "VersionTLS12",
+		"Minimum TLS version for outbound connections when strict-outbound-tls is set. "+
+			"Possible values: VersionTLS12, VersionTLS13")
+
+	fs.StringSliceVar(&options.CipherSuites, "outbound-tls-cipher-suites", nil,
+		"Comma-separated list of cipher suites allowed for outbound TLS 1.2 connections when strict-outbound-tls "+
+			"is set. If omitted, FIPS approved ECDHE AES-GCM cipher suites are used")
+
+	fs.StringVar(&options.ClientCertFile, "outbound-tls-client-cert", "",
+		"Client certificate presented to chart repositories and OCI registries (mTLS) when strict-outbound-tls is set")
+
+	fs.StringVar(&options.ClientKeyFile, "outbound-tls-client-key", "",
+		"Private key of the outbound-tls-client-cert")
+
+	fs.StringSliceVar(&options.PinnedPublicKeys, "outbound-tls-pinned-public-keys", nil,
+		"Comma-separated list of hex encoded SHA256 fingerprints of the SubjectPublicKeyInfo of accepted "+
+			"server certificates when strict-outbound-tls is set. If set, the server certificate chain must "+
+			"contain at least one pinned public key")
 }
 
 func setupIndexes(ctx context.Context, mgr ctrl.Manager) {
@@ -242,6 +555,20 @@ func setupChecks(mgr ctrl.Manager) {
 	}
 }
 
+// setupDescribeHandler serves ClusterProfile/Profile descriptions on the diagnostics endpoint.
+// Descriptions expose cluster and resource names, so they are only served when the diagnostics
+// endpoint is protected by authentication/authorization.
+func setupDescribeHandler(mgr ctrl.Manager) {
+	if insecureDiagnostics {
+		return
+	}
+	if err := mgr.AddMetricsServerExtraHandler(controllers.DescribePath,
+		controllers.NewDescribeHandler(mgr.GetClient())); err != nil {
+		setupLog.Error(err, "unable to set up describe handler")
+		os.Exit(1)
+	}
+}
+
 // capiCRDHandler restarts process if a CAPI CRD is updated
 func capiCRDHandler(gvk *schema.GroupVersionKind) {
 	if gvk.Group == clusterv1.GroupVersion.Group {
@@ -251,19 +578,20 @@ func capiCRDHandler(gvk *schema.GroupVersionKind) {
 	}
 }
 
-// isCAPIInstalled returns true if CAPI is installed, false otherwise
+// isCAPIInstalled returns true if CAPI is installed, false otherwise.
+// If CAPI is installed but its Cluster CRD is not compatible, CAPI support is disabled.
 func isCAPIInstalled(ctx context.Context, c client.Client) (bool, error) {
-	clusterCRD := &apiextensionsv1.CustomResourceDefinition{}
-
-	err := c.Get(ctx, types.NamespacedName{Name: "clusters.cluster.x-k8s.io"}, clusterCRD)
+	installed, err := controllers.CheckCAPIClusterCRD(ctx, c)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
+		var incompatibleErr *controllers.IncompatibleCRDError
+		if errors.As(err, &incompatibleErr) {
+			setupLog.Error(err, "CAPI Cluster CRD is not compatible. CAPI clusters won't be managed")
 			return false, nil
 		}
 		return false, err
 	}
 
-	return true, nil
+	return installed, nil
 }
 
 // fluxCRDHandler restarts process if a Flux CRD is updated
@@ -371,6 +699,23 @@ func startWatchers(ctx context.Context, mgr manager.Manager,
 	go fluxWatchers(ctx, mgr, watchersForFlux, setupLog)
 }
 
+// addRequeuePolicyFlags adds the flags to configure the requeue policy of a reconciler.
+// Zero values mean controller defaults are used.
+func addRequeuePolicyFlags(fs *pflag.FlagSet, prefix, kind string, policy *controllers.RequeuePolicy,
+	withDelete bool) {
+
+	fs.DurationVar(&policy.NormalRequeueAfter, prefix+"-requeue-after", 0,
+		fmt.Sprintf("How long to wait before reconciling again a %s whose reconciliation could not complete. "+
+			"Zero means default (10s)", kind))
+	if withDelete {
+		fs.DurationVar(&policy.DeleteRequeueAfter, prefix+"-delete-requeue-after", 0,
+			fmt.Sprintf("How long to wait before reconciling again a %s whose deletion could not complete. "+
+				"Zero means default (10s)", kind))
+	}
+	fs.DurationVar(&policy.MaxFailureBackoff, prefix+"-max-failure-backoff", 0,
+		fmt.Sprintf("Maximum backoff before retrying a %s failing reconciliation. Zero means default (1000s)", kind))
+}
+
 func getProfileReconciler(mgr manager.Manager) *controllers.ProfileReconciler {
 	return &controllers.ProfileReconciler{
 		Client:               mgr.GetClient(),
@@ -381,6 +726,8 @@ func getProfileReconciler(mgr manager.Manager) *controllers.ProfileReconciler {
 		ClusterLabels:        make(map[corev1.ObjectReference]map[string]string),
 		Mux:                  sync.Mutex{},
 		ConcurrentReconciles: concurrentReconciles,
+		RequeuePolicy:        profileRequeue,
+		EventRecorder:        mgr.GetEventRecorderFor("addon-controller"),
 		Logger:               ctrl.Log.WithName("profilereconciler"),
 	}
 }
@@ -395,29 +742,46 @@ func getClusterProfileReconciler(mgr manager.Manager) *controllers.ClusterProfil
 		ClusterLabels:        make(map[corev1.ObjectReference]map[string]string),
 		Mux:                  sync.Mutex{},
 		ConcurrentReconciles: concurrentReconciles,
+		RequeuePolicy:        profileRequeue,
+		EventRecorder:        mgr.GetEventRecorderFor("addon-controller"),
 		Logger:               ctrl.Log.WithName("clusterprofilereconciler"),
 	}
 }
 
 func getClusterSummaryReconciler(ctx context.Context, mgr manager.Manager) *controllers.ClusterSummaryReconciler {
-	d := deployer.GetClient(ctx, ctrl.Log.WithName("deployer"), mgr.GetClient(), workers)
+	d := clusterdeployer.GetClient(ctx, ctrl.Log.WithName("deployer"), mgr.GetClient(),
+		clusterdeployer.Options{
+			PoolSize:            workers,
+			PerClusterWorkers:   perClusterWorkers,
+			PerClusterQueueSize: perClusterQueueSize,
+		})
 	controllers.RegisterFeatures(d, setupLog)
+	controllers.RegisterDeployPoolMetrics(d.Stats, setupLog)
+
+	if healthVerifierOptions.PoolSize > 0 {
+		controllers.StartHealthVerifier(ctx, ctrl.Log.WithName("healthverifier"), healthVerifierOptions)
+	}
 
 	return &controllers.ClusterSummaryReconciler{
-		Config:               mgr.GetConfig(),
-		Client:               mgr.GetClient(),
-		Scheme:               mgr.GetScheme(),
-		ShardKey:             shardKey,
-		Version:              version,
-		ReportMode:           reportMode,
-		AgentInMgmtCluster:   agentInMgmtCluster,
-		Deployer:             d,
-		ClusterMap:           make(map[corev1.ObjectReference]*libsveltosset.Set),
-		ReferenceMap:         make(map[corev1.ObjectReference]*libsveltosset.Set),
-		PolicyMux:            sync.Mutex{},
-		ConcurrentReconciles: concurrentReconciles,
-		ConflictRetryTime:    conflictRetryTime,
-		Logger:               ctrl.Log.WithName("clustersummaryreconciler"),
+		Config:                mgr.GetConfig(),
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		ShardKey:              shardKey,
+		Version:               version,
+		ReportMode:            reportMode,
+		AgentInMgmtCluster:    agentInMgmtCluster,
+		Deployer:              d,
+		ClusterMap:            make(map[corev1.ObjectReference]*libsveltosset.Set),
+		ReferenceMap:          make(map[corev1.ObjectReference]*libsveltosset.Set),
+		DeploymentSlots:       make(map[corev1.ObjectReference]*libsveltosset.Set),
+		DeploymentQueue:       make(map[corev1.ObjectReference][]corev1.ObjectReference),
+		PolicyMux:             sync.Mutex{},
+		ConcurrentReconciles:  concurrentReconciles,
+		ConflictRetryTime:     conflictRetryTime,
+		DeploymentSlotTimeout: deploymentSlotTimeout,
+		RequeuePolicy:         clusterSummaryRequeue,
+		RetryPolicy:           retryPolicy,
+		Logger:                ctrl.Log.WithName("clustersummaryreconciler"),
 	}
 }
 
@@ -431,6 +795,7 @@ func getSetReconciler(mgr manager.Manager) *controllers.SetReconciler {
 		SetMap:               make(map[corev1.ObjectReference]*libsveltosset.Set),
 		Sets:                 make(map[corev1.ObjectReference]libsveltosv1beta1.Selector),
 		ClusterLabels:        make(map[corev1.ObjectReference]map[string]string),
+		RequeuePolicy:        setRequeue,
 		Logger:               ctrl.Log.WithName("setreconciler"),
 	}
 }
@@ -445,10 +810,19 @@ func getClusterSetReconciler(mgr manager.Manager) *controllers.ClusterSetReconci
 		ClusterSetMap:        make(map[corev1.ObjectReference]*libsveltosset.Set),
 		ClusterSets:          make(map[corev1.ObjectReference]libsveltosv1beta1.Selector),
 		ClusterLabels:        make(map[corev1.ObjectReference]map[string]string),
+		RequeuePolicy:        setRequeue,
 		Logger:               ctrl.Log.WithName("clustersetreconciler"),
 	}
 }
 
+func getConfigurationSnapshotReconciler(mgr manager.Manager) *controllers.ConfigurationSnapshotReconciler {
+	return &controllers.ConfigurationSnapshotReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Logger: ctrl.Log.WithName("configurationsnapshotreconciler"),
+	}
+}
+
 // getDiagnosticsOptions returns metrics options which can be used to configure a Manager.
 func getDiagnosticsOptions() metricsserver.Options {
 	// If "--insecure-diagnostics" is set, serve metrics via http
@@ -535,6 +909,22 @@ func startControllersAndWatchers(ctx context.Context, mgr manager.Manager) {
 			os.Exit(1)
 		}
 		watchersForCAPI = append(watchersForCAPI, setReconciler)
+
+		configurationSnapshotReconciler := getConfigurationSnapshotReconciler(mgr)
+		err = configurationSnapshotReconciler.SetupWithManager(mgr)
+		if err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", configv1beta1.ConfigurationSnapshotKind)
+			os.Exit(1)
+		}
+
+		// Remove ClusterConfigurations/ClusterReports/ClusterSummaries for clusters not existing anymore
+		staleClusterResourcesCollector := controllers.NewStaleClusterResourcesCollector(mgr,
+			ctrl.Log.WithName("stale-cluster-resources-collector"))
+		if err = mgr.Add(staleClusterResourcesCollector); err != nil {
+			setupLog.Error(err, "unable to add stale cluster resources collector")
+			os.Exit(1)
+		}
+		watchersForCAPI = append(watchersForCAPI, staleClusterResourcesCollector)
 	}
 
 	clusterSummaryReconciler := getClusterSummaryReconciler(ctx, mgr)
@@ -568,3 +958,100 @@ func printMemUsage(logger logr.Logger) {
 func bToMb(b uint64) uint64 {
 	return b / mebibytes_bytes
 }
+
+// runLint validates ClusterProfiles/Profiles offline and returns the exit code:
+// 0 if no issue is found, 1 if issues are found and 2 on error.
+// Usage: manager lint --file profile.yaml [--file other.yaml]
+func runLint(args []string) int {
+	fs := pflag.NewFlagSet("lint", pflag.ContinueOnError)
+	files := fs.StringSlice("file", nil,
+		"File containing ClusterProfiles/Profiles to validate. Can be repeated.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(*files) == 0 {
+		fmt.Fprintln(os.Stderr, "at least one --file is required")
+		return 2
+	}
+
+	found := false
+	for _, file := range *files {
+		issues, err := lint.LintFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			return 2
+		}
+		for i := range issues {
+			fmt.Printf("%s: %s\n", file, issues[i].String())
+			found = true
+		}
+	}
+
+	if found {
+		return 1
+	}
+	return 0
+}
+
+// runMigrateCRS converts ClusterResourceSets to Profiles and returns the exit code:
+// 0 on success, 1 if warnings were reported and 2 on error.
+// Resources ClusterResourceSets already applied are marked as owned by the Profiles, so the
+// switchover neither re-creates nor removes them. ClusterResourceSets are left untouched.
+// Usage: manager migrate-crs [--namespace ns] [--dry-run]
+func runMigrateCRS(args []string) int {
+	fs := pflag.NewFlagSet("migrate-crs", pflag.ContinueOnError)
+	namespace := fs.String("namespace", "",
+		"Only migrate ClusterResourceSets in this namespace. All namespaces if not set.")
+	dryRun := fs.Bool("dry-run", false,
+		"Only report the Profiles which would be created. Nothing is created or modified.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	scheme, err := controllers.InitScheme()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := addonsv1.AddToScheme(scheme); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	controllers.SetManagementClusterAccess(c, restConfig)
+
+	results, err := controllers.MigrateClusterResourceSets(context.Background(), c,
+		crsmigration.MigrateOptions{Namespace: *namespace, DryRun: *dryRun}, klog.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	warnings := false
+	for i := range results {
+		profile := results[i].Profile
+		fmt.Printf("Profile %s/%s\n", profile.Namespace, profile.Name)
+		for _, clusterName := range results[i].AdoptedClusters {
+			fmt.Printf("  adopted resources in cluster %s\n", clusterName)
+		}
+		for _, warning := range results[i].Warnings {
+			fmt.Printf("  warning: %s\n", warning)
+			warnings = true
+		}
+	}
+
+	if warnings {
+		return 1
+	}
+	return 0
+}
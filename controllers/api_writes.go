@@ -0,0 +1,146 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync/atomic"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// In steady state (nothing changed) a reconciliation must not write to the API server. To catch
+// regressions, reconcilers count the API writes (create, update, patch, delete) issued while
+// reconciling and report them with the reconcile_api_writes histogram.
+// Only writes issued with the reconciliation context are counted.
+
+type apiWritesKey struct{}
+
+// withAPIWritesCounter returns a context counting API writes issued with it
+func withAPIWritesCounter(ctx context.Context) (context.Context, *atomic.Int64) {
+	counter := &atomic.Int64{}
+	return context.WithValue(ctx, apiWritesKey{}, counter), counter
+}
+
+// countAPIWrite increments the API writes counter of ctx, if any
+func countAPIWrite(ctx context.Context) {
+	if counter, ok := ctx.Value(apiWritesKey{}).(*atomic.Int64); ok {
+		counter.Add(1)
+	}
+}
+
+// apiWritesCountingClient is a client counting API writes
+type apiWritesCountingClient struct {
+	client.Client
+}
+
+// newAPIWritesCountingClient returns a client counting, in the request context, the API writes issued with it
+func newAPIWritesCountingClient(c client.Client) client.Client {
+	if _, ok := c.(*apiWritesCountingClient); ok {
+		return c
+	}
+	return &apiWritesCountingClient{Client: c}
+}
+
+func (c *apiWritesCountingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	countAPIWrite(ctx)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *apiWritesCountingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	countAPIWrite(ctx)
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *apiWritesCountingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch,
+	opts ...client.PatchOption) error {
+
+	countAPIWrite(ctx)
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *apiWritesCountingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	countAPIWrite(ctx)
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *apiWritesCountingClient) DeleteAllOf(ctx context.Context, obj client.Object,
+	opts ...client.DeleteAllOfOption) error {
+
+	countAPIWrite(ctx)
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
+
+func (c *apiWritesCountingClient) Status() client.SubResourceWriter {
+	return &apiWritesCountingSubResourceWriter{SubResourceWriter: c.Client.Status()}
+}
+
+func (c *apiWritesCountingClient) SubResource(subResource string) client.SubResourceClient {
+	return &apiWritesCountingSubResourceClient{SubResourceClient: c.Client.SubResource(subResource)}
+}
+
+// apiWritesCountingSubResourceWriter is a SubResourceWriter counting API writes
+type apiWritesCountingSubResourceWriter struct {
+	client.SubResourceWriter
+}
+
+func (w *apiWritesCountingSubResourceWriter) Create(ctx context.Context, obj, subResource client.Object,
+	opts ...client.SubResourceCreateOption) error {
+
+	countAPIWrite(ctx)
+	return w.SubResourceWriter.Create(ctx, obj, subResource, opts...)
+}
+
+func (w *apiWritesCountingSubResourceWriter) Update(ctx context.Context, obj client.Object,
+	opts ...client.SubResourceUpdateOption) error {
+
+	countAPIWrite(ctx)
+	return w.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+func (w *apiWritesCountingSubResourceWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch,
+	opts ...client.SubResourcePatchOption) error {
+
+	countAPIWrite(ctx)
+	return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// apiWritesCountingSubResourceClient is a SubResourceClient counting API writes
+type apiWritesCountingSubResourceClient struct {
+	client.SubResourceClient
+}
+
+func (s *apiWritesCountingSubResourceClient) Create(ctx context.Context, obj, subResource client.Object,
+	opts ...client.SubResourceCreateOption) error {
+
+	countAPIWrite(ctx)
+	return s.SubResourceClient.Create(ctx, obj, subResource, opts...)
+}
+
+func (s *apiWritesCountingSubResourceClient) Update(ctx context.Context, obj client.Object,
+	opts ...client.SubResourceUpdateOption) error {
+
+	countAPIWrite(ctx)
+	return s.SubResourceClient.Update(ctx, obj, opts...)
+}
+
+func (s *apiWritesCountingSubResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch,
+	opts ...client.SubResourcePatchOption) error {
+
+	countAPIWrite(ctx)
+	return s.SubResourceClient.Patch(ctx, obj, patch, opts...)
+}
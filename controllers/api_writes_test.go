@@ -0,0 +1,110 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("API writes", func() {
+	var clusterProfile *configv1beta1.ClusterProfile
+
+	BeforeEach(func() {
+		clusterProfile = &configv1beta1.ClusterProfile{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       configv1beta1.ClusterProfileKind,
+				APIVersion: configv1beta1.GroupVersion.String(),
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: randomString(),
+			},
+		}
+	})
+
+	It("counting client counts writes issued with the reconciliation context only", func() {
+		c := controllers.NewAPIWritesCountingClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+
+		ctx, writes := controllers.WithAPIWritesCounter(context.TODO())
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()},
+		}
+		Expect(c.Create(ctx, configMap)).To(Succeed())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(configMap), configMap)).To(Succeed())
+		configMap.Data = map[string]string{randomString(): randomString()}
+		Expect(c.Update(ctx, configMap)).To(Succeed())
+		Expect(writes.Load()).To(Equal(int64(2)))
+
+		// Writes issued with a different context are not counted
+		Expect(c.Delete(context.TODO(), configMap)).To(Succeed())
+		Expect(writes.Load()).To(Equal(int64(2)))
+	})
+
+	It("steady state produces zero writes for ClusterConfigurations and ClusterSummary SyncMode", func() {
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterProfileSpec: configv1beta1.Spec{
+					SyncMode: configv1beta1.SyncModeContinuous,
+				},
+				ClusterType: libsveltosv1beta1.ClusterTypeSveltos,
+			},
+		}
+
+		initObjects := []client.Object{
+			clusterProfile,
+			clusterSummary,
+		}
+
+		c := controllers.NewAPIWritesCountingClient(fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&configv1beta1.ClusterConfiguration{}).WithObjects(initObjects...).Build())
+
+		clusterRef := corev1.ObjectReference{Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+
+		ctx, writes := controllers.WithAPIWritesCounter(context.TODO())
+		Expect(controllers.CreateClusterConfiguration(ctx, c, clusterProfile, &clusterRef)).To(Succeed())
+		Expect(controllers.UpdateClusterConfigurationWithProfile(ctx, c, clusterProfile, &clusterRef)).To(Succeed())
+		Expect(writes.Load()).ToNot(BeZero())
+
+		ctx, writes = controllers.WithAPIWritesCounter(context.TODO())
+		Expect(controllers.CreateClusterConfiguration(ctx, c, clusterProfile, &clusterRef)).To(Succeed())
+		Expect(controllers.UpdateClusterConfigurationWithProfile(ctx, c, clusterProfile, &clusterRef)).To(Succeed())
+		Expect(controllers.UpdateClusterSummarySyncMode(ctx, c, clusterSummary,
+			configv1beta1.SyncModeContinuous)).To(Succeed())
+		Expect(writes.Load()).To(BeZero())
+
+		Expect(controllers.UpdateClusterSummarySyncMode(ctx, c, clusterSummary,
+			configv1beta1.SyncModeDryRun)).To(Succeed())
+		Expect(writes.Load()).To(Equal(int64(1)))
+	})
+})
@@ -0,0 +1,170 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/objectdiff"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+)
+
+// In AssessOnly mode nothing is deployed. Each resource is compared with the live object in
+// the managed cluster. An object conforms when every field set in the desired resource has
+// the same value in the live object (fields set only on the live object, like status or
+// defaulted fields, are ignored). Values are compared once canonicalized (see pkg/objectdiff).
+// Helm releases conform when neither the manifest nor the values would change.
+
+const (
+	matchingDesiredStateMessage = "Object matches desired state"
+)
+
+func isAssessOnlyMode(clusterSummary *configv1beta1.ClusterSummary) bool {
+	return clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeAssessOnly
+}
+
+// generateAssessmentReport compares policy with the corresponding live object in the managed cluster
+func generateAssessmentReport(ctx context.Context, dr dynamic.ResourceInterface, policy *unstructured.Unstructured,
+	resource *configv1beta1.Resource) (*configv1beta1.ResourceReport, error) {
+
+	report := &configv1beta1.ResourceReport{Resource: *resource}
+
+	live, err := dr.Get(ctx, policy.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			report.Action = string(configv1beta1.CreateResourceAction)
+			report.Message = "Object not found"
+			return report, nil
+		}
+		return nil, err
+	}
+
+	if path := findNonConformingField(policy, live); path != "" {
+		report.Action = string(configv1beta1.UpdateResourceAction)
+		report.Message = fmt.Sprintf("Object differs from desired state at %s", path)
+		return report, nil
+	}
+
+	report.Action = string(configv1beta1.NoResourceAction)
+	report.Message = matchingDesiredStateMessage
+	return report, nil
+}
+
+// refineDryRunUpdateReport compares policy, reported as updated because its hash differs from the one
+// of the deployed policy, with the live object. If they are equivalent once canonicalized (for instance
+// referenced policy changed from 1 to 1000m cpu) nothing would actually change and report is turned
+// into a no action one. Sveltos bookkeeping annotations, which change with the hash, are not compared.
+func refineDryRunUpdateReport(ctx context.Context, dr dynamic.ResourceInterface, policy *unstructured.Unstructured,
+	report *configv1beta1.ResourceReport) error {
+
+	live, err := dr.Get(ctx, policy.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	desired := policy.DeepCopy()
+	annotations := desired.GetAnnotations()
+	delete(annotations, deployer.PolicyHash)
+	desired.SetAnnotations(annotations)
+	removeAuditAnnotations(desired)
+
+	if path := findNonConformingField(desired, live); path != "" {
+		report.Message = fmt.Sprintf("Object differs from desired state at %s", path)
+		return nil
+	}
+
+	report.Action = string(configv1beta1.NoResourceAction)
+	report.Message = matchingDesiredStateMessage
+	return nil
+}
+
+// findNonConformingField returns the path of the first field set in desired whose value is
+// different in live. Returns an empty string if live conforms to desired.
+// Objects are canonicalized first, so equivalent representations (1 vs 1000m cpu) conform.
+func findNonConformingField(desired, live *unstructured.Unstructured) string {
+	differences := objectdiff.Compare(desired, live, objectdiff.Options{Subset: true})
+	if len(differences) == 0 {
+		return ""
+	}
+	return differences[0].Path
+}
+
+// assessReleaseReport marks an helm release which would be upgraded as conforming if neither
+// manifest nor values would change.
+func assessReleaseReport(clusterSummary *configv1beta1.ClusterSummary, report *configv1beta1.ReleaseReport) {
+	if !isAssessOnlyMode(clusterSummary) {
+		return
+	}
+
+	if report.Action == string(configv1beta1.UpgradeHelmAction) &&
+		report.ManifestDiff == "" && report.ValuesDiff == "" {
+
+		report.Action = string(configv1beta1.NoHelmAction)
+		report.Message = "Release matches desired state"
+	}
+}
+
+// getConformance returns the conformance summary for the reports in a ClusterReport
+func getConformance(status *configv1beta1.ClusterReportStatus) *configv1beta1.Conformance {
+	conformance := &configv1beta1.Conformance{}
+
+	for i := range status.ReleaseReports {
+		conformance.Total++
+		if status.ReleaseReports[i].Action == string(configv1beta1.NoHelmAction) {
+			conformance.Matching++
+		}
+	}
+
+	resourceReports := make([]configv1beta1.ResourceReport, 0,
+		len(status.ResourceReports)+len(status.KustomizeResourceReports))
+	resourceReports = append(resourceReports, status.ResourceReports...)
+	resourceReports = append(resourceReports, status.KustomizeResourceReports...)
+	for i := range resourceReports {
+		conformance.Total++
+		if resourceReports[i].Action == string(configv1beta1.NoResourceAction) {
+			conformance.Matching++
+		}
+	}
+
+	const fullScore = 100
+	conformance.Score = fullScore
+	if conformance.Total != 0 {
+		conformance.Score = conformance.Matching * fullScore / conformance.Total
+	}
+
+	return conformance
+}
+
+// updateConformance sets ClusterReport conformance. Conformance is reported only in AssessOnly mode.
+func updateConformance(clusterSummary *configv1beta1.ClusterSummary, clusterReport *configv1beta1.ClusterReport) {
+	if !isAssessOnlyMode(clusterSummary) {
+		clusterReport.Status.Conformance = nil
+		return
+	}
+
+	clusterReport.Status.Conformance = getConformance(&clusterReport.Status)
+}
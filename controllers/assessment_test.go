@@ -0,0 +1,157 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/libsveltos/lib/utils"
+)
+
+const (
+	desiredDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: default
+  labels:
+    app: nginx
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.25`
+
+	liveDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: default
+  resourceVersion: "100"
+  labels:
+    app: nginx
+    env: production
+spec:
+  replicas: %d
+  progressDeadlineSeconds: 600
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.25
+        imagePullPolicy: IfNotPresent
+status:
+  readyReplicas: 2`
+)
+
+var _ = Describe("AssessOnly", func() {
+	It("findNonConformingField ignores fields set only in the live object", func() {
+		desired, err := utils.GetUnstructured([]byte(desiredDeployment))
+		Expect(err).To(BeNil())
+
+		live, err := utils.GetUnstructured([]byte(fmt.Sprintf(liveDeployment, 2)))
+		Expect(err).To(BeNil())
+		Expect(controllers.FindNonConformingField(desired, live)).To(BeEmpty())
+
+		live, err = utils.GetUnstructured([]byte(fmt.Sprintf(liveDeployment, 3)))
+		Expect(err).To(BeNil())
+		Expect(controllers.FindNonConformingField(desired, live)).To(Equal("spec.replicas"))
+
+		live.SetLabels(map[string]string{"app": "web"})
+		Expect(controllers.FindNonConformingField(desired, live)).To(Equal("metadata.labels.app"))
+
+		desired = &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1", "kind": "ConfigMap",
+			"data": map[string]interface{}{"key": "value"},
+		}}
+		live = &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1", "kind": "ConfigMap",
+		}}
+		Expect(controllers.FindNonConformingField(desired, live)).To(Equal("data"))
+	})
+
+	It("findNonConformingField compares canonicalized values", func() {
+		desired := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1", "kind": "ResourceQuota",
+			"spec": map[string]interface{}{"hard": map[string]interface{}{"cpu": "1", "memory": "1Gi"}},
+		}}
+		live := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1", "kind": "ResourceQuota",
+			"spec": map[string]interface{}{"hard": map[string]interface{}{"cpu": "1000m", "memory": "1024Mi"}},
+		}}
+		Expect(controllers.FindNonConformingField(desired, live)).To(BeEmpty())
+
+		Expect(unstructured.SetNestedField(live.Object, "2", "spec", "hard", "cpu")).To(Succeed())
+		Expect(controllers.FindNonConformingField(desired, live)).To(Equal("spec.hard.cpu"))
+	})
+
+	It("getConformance computes matching resources and score", func() {
+		status := &configv1beta1.ClusterReportStatus{}
+		conformance := controllers.GetConformance(status)
+		Expect(conformance.Total).To(Equal(int32(0)))
+		Expect(conformance.Score).To(Equal(int32(100)))
+
+		status.ResourceReports = []configv1beta1.ResourceReport{
+			{Action: string(configv1beta1.NoResourceAction)},
+			{Action: string(configv1beta1.UpdateResourceAction)},
+			{Action: string(configv1beta1.CreateResourceAction)},
+		}
+		status.KustomizeResourceReports = []configv1beta1.ResourceReport{
+			{Action: string(configv1beta1.NoResourceAction)},
+		}
+		status.ReleaseReports = []configv1beta1.ReleaseReport{
+			{Action: string(configv1beta1.NoHelmAction)},
+			{Action: string(configv1beta1.UpgradeHelmAction)},
+		}
+
+		conformance = controllers.GetConformance(status)
+		Expect(conformance.Total).To(Equal(int32(6)))
+		Expect(conformance.Matching).To(Equal(int32(3)))
+		Expect(conformance.Score).To(Equal(int32(50)))
+	})
+
+	It("assessReleaseReport marks releases with no diff as matching only in AssessOnly mode", func() {
+		clusterSummary := &configv1beta1.ClusterSummary{
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterProfileSpec: configv1beta1.Spec{
+					SyncMode: configv1beta1.SyncModeDryRun,
+				},
+			},
+		}
+
+		report := &configv1beta1.ReleaseReport{Action: string(configv1beta1.UpgradeHelmAction)}
+		controllers.AssessReleaseReport(clusterSummary, report)
+		Expect(report.Action).To(Equal(string(configv1beta1.UpgradeHelmAction)))
+
+		clusterSummary.Spec.ClusterProfileSpec.SyncMode = configv1beta1.SyncModeAssessOnly
+		controllers.AssessReleaseReport(clusterSummary, report)
+		Expect(report.Action).To(Equal(string(configv1beta1.NoHelmAction)))
+
+		report = &configv1beta1.ReleaseReport{Action: string(configv1beta1.UpgradeHelmAction), ValuesDiff: "diff"}
+		controllers.AssessReleaseReport(clusterSummary, report)
+		Expect(report.Action).To(Equal(string(configv1beta1.UpgradeHelmAction)))
+	})
+})
@@ -0,0 +1,291 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Every change addon-controller applies to a managed cluster (resource apply/delete, helm
+// install/upgrade/uninstall) produces an AuditRecord. Records are written to all registered
+// AuditSinks. Built-in sinks append records to a file (one JSON object per line) or POST them
+// to a webhook. Users embedding this controller can register their own sinks (for instance Kafka).
+// Nothing is recorded in DryRun mode, as nothing is changed.
+
+// AuditOperation is the change applied to a managed cluster
+type AuditOperation string
+
+const (
+	AuditOperationApply         = AuditOperation("Apply")
+	AuditOperationDelete        = AuditOperation("Delete")
+	AuditOperationHelmInstall   = AuditOperation("HelmInstall")
+	AuditOperationHelmUpgrade   = AuditOperation("HelmUpgrade")
+	AuditOperationHelmUninstall = AuditOperation("HelmUninstall")
+)
+
+const (
+	auditWebhookTimeout = 10 * time.Second
+)
+
+// AuditRecord describes a change applied to a managed cluster
+type AuditRecord struct {
+	Time      time.Time      `json:"time"`
+	Operation AuditOperation `json:"operation"`
+
+	ClusterNamespace string                        `json:"clusterNamespace"`
+	ClusterName      string                        `json:"clusterName"`
+	ClusterType      libsveltosv1beta1.ClusterType `json:"clusterType"`
+
+	// Profile is the ClusterProfile/Profile the change was applied for (Kind/name or Kind/namespace/name)
+	Profile string `json:"profile"`
+	// AppliedBy is the controller (and its version) which applied the change
+	AppliedBy string                  `json:"appliedBy"`
+	Feature   configv1beta1.FeatureID `json:"feature,omitempty"`
+
+	// APIVersion, Kind, Namespace and Name identify the resource. Set for Apply and Delete.
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	// PreviousHash and Hash are the hash of the resource before and after Apply. PreviousHash
+	// is empty when the resource is created.
+	PreviousHash string `json:"previousHash,omitempty"`
+	Hash         string `json:"hash,omitempty"`
+
+	// ReleaseNamespace, ReleaseName, ChartName and ChartVersion identify the helm release.
+	// Set for helm operations.
+	ReleaseNamespace string `json:"releaseNamespace,omitempty"`
+	ReleaseName      string `json:"releaseName,omitempty"`
+	ChartName        string `json:"chartName,omitempty"`
+	ChartVersion     string `json:"chartVersion,omitempty"`
+
+	// Error is set if the change failed
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink stores AuditRecords. Records must be stored in an append-only fashion.
+// Write is invoked synchronously while deploying, so it must be fast. Errors are logged.
+type AuditSink interface {
+	Write(ctx context.Context, record *AuditRecord) error
+}
+
+// AuditLogOptions configures the built-in AuditSinks
+type AuditLogOptions struct {
+	// File, if set, is the file AuditRecords are appended to
+	File string
+
+	// WebhookURL, if set, is the URL AuditRecords are POSTed to
+	WebhookURL string
+}
+
+var (
+	auditSinksMux sync.RWMutex
+	auditSinks    []AuditSink
+)
+
+// RegisterAuditSink registers an AuditSink. It must be called before the manager is started.
+func RegisterAuditSink(sink AuditSink) {
+	auditSinksMux.Lock()
+	defer auditSinksMux.Unlock()
+	auditSinks = append(auditSinks, sink)
+}
+
+// SetAuditLogOptions registers the built-in AuditSinks enabled in options
+func SetAuditLogOptions(options *AuditLogOptions) error {
+	if options == nil {
+		return nil
+	}
+
+	if options.File != "" {
+		sink, err := NewFileAuditSink(options.File)
+		if err != nil {
+			return err
+		}
+		RegisterAuditSink(sink)
+	}
+
+	if options.WebhookURL != "" {
+		RegisterAuditSink(NewWebhookAuditSink(options.WebhookURL))
+	}
+
+	return nil
+}
+
+// fileAuditSink appends AuditRecords, one JSON object per line, to a file
+type fileAuditSink struct {
+	mux  sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditSink returns an AuditSink appending records to path
+func NewFileAuditSink(path string) (AuditSink, error) {
+	const permissions = 0o600
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, permissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file %s: %w", path, err)
+	}
+	return &fileAuditSink{file: f}, nil
+}
+
+func (s *fileAuditSink) Write(_ context.Context, record *AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, err = s.file.Write(data)
+	return err
+}
+
+// webhookAuditSink POSTs AuditRecords, as JSON, to an URL
+type webhookAuditSink struct {
+	url string
+}
+
+// NewWebhookAuditSink returns an AuditSink POSTing records to url
+func NewWebhookAuditSink(url string) AuditSink {
+	return &webhookAuditSink{url: url}
+}
+
+func (s *webhookAuditSink) Write(ctx context.Context, record *AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, auditWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var tlsConfig *tls.Config
+	if isStrictOutboundTLS() {
+		tlsConfig = outboundTLSConfig.Clone()
+	}
+	httpClient := &http.Client{Transport: getOutboundTransport(tlsConfig)}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("audit webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// recordAudit completes record with time, cluster, profile and controller information and
+// writes it to all registered AuditSinks
+func recordAudit(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary, record *AuditRecord,
+	changeErr error, logger logr.Logger) {
+
+	auditSinksMux.RLock()
+	sinks := auditSinks
+	auditSinksMux.RUnlock()
+
+	if len(sinks) == 0 {
+		return
+	}
+
+	record.Time = time.Now().UTC()
+	record.ClusterNamespace = clusterSummary.Spec.ClusterNamespace
+	record.ClusterName = clusterSummary.Spec.ClusterName
+	record.ClusterType = clusterSummary.Spec.ClusterType
+	record.Profile = getAuditProfile(clusterSummary)
+	record.AppliedBy = getRemoteUserAgent()
+	if changeErr != nil {
+		record.Error = changeErr.Error()
+	}
+
+	// A change was already applied to the managed cluster. Do not stop recording it if
+	// the reconciliation context is canceled.
+	ctx = context.WithoutCancel(ctx)
+	for i := range sinks {
+		if err := sinks[i].Write(ctx, record); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to write audit record: %v", err))
+		}
+	}
+}
+
+// getAuditProfile returns the ClusterProfile/Profile owning clusterSummary in the same format
+// used by the appliedProfileAnnotation
+func getAuditProfile(clusterSummary *configv1beta1.ClusterSummary) string {
+	ref, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
+	if err != nil {
+		return ""
+	}
+	if ref.Kind == configv1beta1.ProfileKind {
+		return fmt.Sprintf("%s/%s/%s", ref.Kind, clusterSummary.Namespace, ref.Name)
+	}
+	return fmt.Sprintf("%s/%s", ref.Kind, ref.Name)
+}
+
+// getResourceAuditRecord returns the AuditRecord for operation on a resource
+func getResourceAuditRecord(operation AuditOperation, featureID configv1beta1.FeatureID,
+	obj client.Object) *AuditRecord {
+
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return &AuditRecord{
+		Operation:  operation,
+		Feature:    featureID,
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// getHelmAuditRecord returns the AuditRecord for operation on a helm release
+func getHelmAuditRecord(operation AuditOperation, releaseNamespace, releaseName string,
+	helmChart *configv1beta1.HelmChart) *AuditRecord {
+
+	record := &AuditRecord{
+		Operation:        operation,
+		Feature:          configv1beta1.FeatureHelm,
+		ReleaseNamespace: releaseNamespace,
+		ReleaseName:      releaseName,
+	}
+	if helmChart != nil {
+		record.ChartName = helmChart.ChartName
+		record.ChartVersion = helmChart.ChartVersion
+	}
+	return record
+}
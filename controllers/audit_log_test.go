@@ -0,0 +1,143 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Audit log", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+	var profileName string
+
+	BeforeEach(func() {
+		profileName = randomString()
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       profileName,
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: randomString(),
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		controllers.ResetAuditSinks()
+	})
+
+	It("file sink appends one JSON record per change", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "audit.log")
+		Expect(controllers.SetAuditLogOptions(&controllers.AuditLogOptions{File: path})).To(Succeed())
+
+		configMap := &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()},
+		}
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		record := controllers.GetResourceAuditRecord(controllers.AuditOperationApply,
+			configv1beta1.FeatureResources, configMap)
+		record.Hash = randomString()
+		controllers.RecordAudit(context.TODO(), clusterSummary, record, nil, logger)
+
+		controllers.RecordAudit(context.TODO(), clusterSummary,
+			controllers.GetHelmAuditRecord(controllers.AuditOperationHelmUninstall, randomString(), randomString(), nil),
+			errors.New("uninstall failed"), logger)
+
+		f, err := os.Open(path)
+		Expect(err).To(BeNil())
+		defer f.Close()
+
+		records := make([]controllers.AuditRecord, 0)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var current controllers.AuditRecord
+			Expect(json.Unmarshal(scanner.Bytes(), &current)).To(Succeed())
+			records = append(records, current)
+		}
+		Expect(records).To(HaveLen(2))
+
+		Expect(records[0].Operation).To(Equal(controllers.AuditOperationApply))
+		Expect(records[0].Kind).To(Equal("ConfigMap"))
+		Expect(records[0].Namespace).To(Equal(configMap.Namespace))
+		Expect(records[0].Name).To(Equal(configMap.Name))
+		Expect(records[0].Hash).To(Equal(record.Hash))
+		Expect(records[0].ClusterName).To(Equal(clusterSummary.Spec.ClusterName))
+		Expect(records[0].Profile).To(Equal(configv1beta1.ClusterProfileKind + "/" + profileName))
+		Expect(records[0].AppliedBy).ToNot(BeEmpty())
+		Expect(records[0].Error).To(BeEmpty())
+
+		Expect(records[1].Operation).To(Equal(controllers.AuditOperationHelmUninstall))
+		Expect(records[1].Feature).To(Equal(configv1beta1.FeatureHelm))
+		Expect(records[1].Error).To(Equal("uninstall failed"))
+	})
+
+	It("webhook sink posts records", func() {
+		received := make(chan controllers.AuditRecord, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			var record controllers.AuditRecord
+			Expect(json.NewDecoder(r.Body).Decode(&record)).To(Succeed())
+			received <- record
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		controllers.RegisterAuditSink(controllers.NewWebhookAuditSink(server.URL))
+
+		helmChart := &configv1beta1.HelmChart{ChartName: randomString(), ChartVersion: "1.2.3"}
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		controllers.RecordAudit(context.TODO(), clusterSummary,
+			controllers.GetHelmAuditRecord(controllers.AuditOperationHelmInstall, randomString(), randomString(), helmChart),
+			nil, logger)
+
+		var record controllers.AuditRecord
+		Eventually(received).Should(Receive(&record))
+		Expect(record.Operation).To(Equal(controllers.AuditOperationHelmInstall))
+		Expect(record.ChartName).To(Equal(helmChart.ChartName))
+		Expect(record.ChartVersion).To(Equal("1.2.3"))
+	})
+})
@@ -0,0 +1,176 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// ClusterProfiles/Profiles with BaseProfile set deploy base add-ons (for instance CNI). In a cluster,
+// add-ons of other ClusterProfiles/Profiles are deployed only once all base ClusterSummaries for the
+// cluster are provisioned. Whether base add-ons are ready is published in the cluster ClusterConfiguration
+// as BaseAddOnsReady condition.
+
+// getBaseAddOnsReadyCondition evaluates the BaseAddOnsReady condition for the cluster matched by
+// clusterSummary. Nil is returned if no base ClusterProfile/Profile matches the cluster.
+func getBaseAddOnsReadyCondition(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary) (*metav1.Condition, error) {
+
+	clusterSummaries := &configv1beta1.ClusterSummaryList{}
+	err := c.List(ctx, clusterSummaries, client.InNamespace(clusterSummary.Spec.ClusterNamespace),
+		client.MatchingLabels{
+			configv1beta1.ClusterNameLabel: clusterSummary.Spec.ClusterName,
+			configv1beta1.ClusterTypeLabel: string(clusterSummary.Spec.ClusterType),
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var condition *metav1.Condition
+	for i := range clusterSummaries.Items {
+		cs := &clusterSummaries.Items[i]
+		if !cs.Spec.ClusterProfileSpec.BaseProfile || !cs.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if isCluterSummaryProvisioned(cs) {
+			if condition == nil {
+				condition = &metav1.Condition{
+					Type:    configv1beta1.BaseAddOnsReadyCondition,
+					Status:  metav1.ConditionTrue,
+					Reason:  configv1beta1.ProvisionedReason,
+					Message: "all base add-ons are provisioned",
+				}
+			}
+			continue
+		}
+
+		if failureMessage := getBaseAddOnsFailure(cs); failureMessage != "" {
+			// A failure is reported over base add-ons still being provisioned
+			return &metav1.Condition{
+				Type:    configv1beta1.BaseAddOnsReadyCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  configv1beta1.BaseAddOnsFailedReason,
+				Message: fmt.Sprintf("base add-ons of ClusterSummary %s failed: %s", cs.Name, failureMessage),
+			}, nil
+		}
+
+		if condition == nil || condition.Status == metav1.ConditionTrue {
+			condition = &metav1.Condition{
+				Type:    configv1beta1.BaseAddOnsReadyCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  configv1beta1.ProvisioningReason,
+				Message: fmt.Sprintf("base add-ons of ClusterSummary %s are being provisioned", cs.Name),
+			}
+		}
+	}
+
+	return condition, nil
+}
+
+// getBaseAddOnsFailure returns the failure message of the first failed feature of a base ClusterSummary.
+// Empty string is returned if no feature failed.
+func getBaseAddOnsFailure(clusterSummary *configv1beta1.ClusterSummary) string {
+	for i := range clusterSummary.Status.FeatureSummaries {
+		fs := &clusterSummary.Status.FeatureSummaries[i]
+		if fs.Status != configv1beta1.FeatureStatusFailed && fs.Status != configv1beta1.FeatureStatusFailedNonRetriable {
+			continue
+		}
+		if fs.FailureMessage != nil {
+			return fmt.Sprintf("%s: %s", fs.FeatureID, *fs.FailureMessage)
+		}
+		return fmt.Sprintf("%s: %s", fs.FeatureID, fs.Status)
+	}
+	return ""
+}
+
+// updateBaseAddOnsReadyCondition publishes condition in the ClusterConfiguration of the cluster matched
+// by clusterSummary. Nil condition removes the BaseAddOnsReady condition.
+func updateBaseAddOnsReadyCondition(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, condition *metav1.Condition) error {
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{}
+		err := c.Get(ctx,
+			types.NamespacedName{
+				Namespace: clusterSummary.Spec.ClusterNamespace,
+				Name:      getClusterConfigurationName(clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType),
+			},
+			clusterConfiguration)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		var changed bool
+		if condition == nil {
+			changed = apimeta.RemoveStatusCondition(&clusterConfiguration.Status.Conditions,
+				configv1beta1.BaseAddOnsReadyCondition)
+		} else {
+			changed = apimeta.SetStatusCondition(&clusterConfiguration.Status.Conditions, *condition)
+		}
+		if !changed {
+			return nil
+		}
+
+		return c.Status().Update(ctx, clusterConfiguration)
+	})
+}
+
+// areBaseAddOnsReady returns true if add-ons of clusterSummary can be deployed, i.e. if clusterSummary
+// is for a base ClusterProfile/Profile or all base ClusterProfiles/Profiles matching the cluster are
+// provisioned. If not, the reason is returned in the message.
+// The BaseAddOnsReady condition of the cluster is refreshed as a side effect.
+func areBaseAddOnsReady(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	logger logr.Logger) (bool, string, error) {
+
+	condition, err := getBaseAddOnsReadyCondition(ctx, c, clusterSummary)
+	if err != nil {
+		return false, "", err
+	}
+
+	if !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+		if err := updateBaseAddOnsReadyCondition(ctx, c, clusterSummary, condition); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to update %s condition: %v",
+				configv1beta1.BaseAddOnsReadyCondition, err))
+		}
+	}
+
+	if clusterSummary.Spec.ClusterProfileSpec.BaseProfile ||
+		condition == nil || condition.Status == metav1.ConditionTrue {
+
+		return true, "", nil
+	}
+
+	msg := fmt.Sprintf("base add-ons are not ready: %s", condition.Message)
+	logger.V(logs.LogDebug).Info(msg)
+	return false, msg, nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Base profiles", func() {
+	var clusterNamespace string
+	var clusterName string
+
+	getClusterSummary := func(baseProfile bool, status configv1beta1.FeatureStatus) *configv1beta1.ClusterSummary {
+		return &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      randomString(),
+				Labels: map[string]string{
+					configv1beta1.ClusterNameLabel: clusterName,
+					configv1beta1.ClusterTypeLabel: string(libsveltosv1beta1.ClusterTypeSveltos),
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: clusterNamespace,
+				ClusterName:      clusterName,
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+				ClusterProfileSpec: configv1beta1.Spec{
+					BaseProfile: baseProfile,
+					HelmCharts: []configv1beta1.HelmChart{
+						{
+							RepositoryURL:    "https://helm.cilium.io/",
+							RepositoryName:   "cilium",
+							ChartName:        "cilium/cilium",
+							ChartVersion:     "1.16.1",
+							ReleaseName:      "cilium",
+							ReleaseNamespace: "kube-system",
+						},
+					},
+				},
+			},
+			Status: configv1beta1.ClusterSummaryStatus{
+				FeatureSummaries: []configv1beta1.FeatureSummary{
+					{FeatureID: configv1beta1.FeatureHelm, Status: status},
+				},
+			},
+		}
+	}
+
+	getClusterConfiguration := func() *configv1beta1.ClusterConfiguration {
+		return &configv1beta1.ClusterConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      controllers.GetClusterConfigurationName(clusterName, libsveltosv1beta1.ClusterTypeSveltos),
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		clusterNamespace = randomString()
+		clusterName = randomString()
+	})
+
+	It("getBaseAddOnsReadyCondition reports whether base add-ons are provisioned", func() {
+		dependent := getClusterSummary(false, configv1beta1.FeatureStatusFailed)
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(dependent).Build()
+		condition, err := controllers.GetBaseAddOnsReadyCondition(context.TODO(), c, dependent)
+		Expect(err).To(BeNil())
+		Expect(condition).To(BeNil())
+
+		provisioned := getClusterSummary(true, configv1beta1.FeatureStatusProvisioned)
+		provisioning := getClusterSummary(true, configv1beta1.FeatureStatusProvisioning)
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(dependent, provisioned, provisioning).Build()
+		condition, err = controllers.GetBaseAddOnsReadyCondition(context.TODO(), c, dependent)
+		Expect(err).To(BeNil())
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(configv1beta1.ProvisioningReason))
+
+		failed := getClusterSummary(true, configv1beta1.FeatureStatusFailed)
+		failureMessage := "cilium-agent not ready"
+		failed.Status.FeatureSummaries[0].FailureMessage = &failureMessage
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(dependent, provisioned, provisioning, failed).Build()
+		condition, err = controllers.GetBaseAddOnsReadyCondition(context.TODO(), c, dependent)
+		Expect(err).To(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(configv1beta1.BaseAddOnsFailedReason))
+		Expect(condition.Message).To(ContainSubstring(failureMessage))
+
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(dependent, provisioned).Build()
+		condition, err = controllers.GetBaseAddOnsReadyCondition(context.TODO(), c, dependent)
+		Expect(err).To(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("areBaseAddOnsReady gates dependent profiles only and publishes condition", func() {
+		dependent := getClusterSummary(false, configv1beta1.FeatureStatusFailed)
+		base := getClusterSummary(true, configv1beta1.FeatureStatusProvisioning)
+		clusterConfiguration := getClusterConfiguration()
+
+		initObjects := []client.Object{dependent, base, clusterConfiguration}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).
+			WithObjects(initObjects...).Build()
+
+		ready, msg, err := controllers.AreBaseAddOnsReady(context.TODO(), c, dependent,
+			textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+		Expect(ready).To(BeFalse())
+		Expect(msg).To(ContainSubstring(base.Name))
+
+		// Base profiles are never gated
+		ready, _, err = controllers.AreBaseAddOnsReady(context.TODO(), c, base,
+			textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+		Expect(ready).To(BeTrue())
+
+		currentClusterConfiguration := &configv1beta1.ClusterConfiguration{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: clusterConfiguration.Namespace,
+			Name: clusterConfiguration.Name}, currentClusterConfiguration)).To(Succeed())
+		condition := apimeta.FindStatusCondition(currentClusterConfiguration.Status.Conditions,
+			configv1beta1.BaseAddOnsReadyCondition)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(configv1beta1.ProvisioningReason))
+	})
+})
@@ -0,0 +1,54 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// evaluateCELExpression evaluates expression with obj available as variable name.
+// Expression must return a boolean.
+func evaluateCELExpression(expression, name string, obj map[string]interface{}) (bool, error) {
+	env, err := cel.NewEnv(cel.Variable(name, cel.DynType))
+	if err != nil {
+		return false, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return false, fmt.Errorf("invalid CEL expression %q: %w", expression, issues.Err())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return false, fmt.Errorf("invalid CEL expression %q: %w", expression, err)
+	}
+
+	out, _, err := program.Eval(map[string]interface{}{name: obj})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate CEL expression %q: %w", expression, err)
+	}
+
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("CEL expression %q must return a boolean", expression)
+	}
+
+	return result, nil
+}
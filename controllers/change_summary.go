@@ -0,0 +1,186 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// When Spec.PublishChangeSummary is set, after each deployment which changed something in the
+// managed cluster, a ConfigMap is written in the projectsveltos namespace of the managed cluster.
+// There is one ConfigMap per ClusterProfile/Profile. Each feature (Resources, Helm, Kustomize)
+// has its own key listing the latest changes.
+
+const (
+	changeSummaryNamePrefix = "sveltos-changes-"
+
+	changeSummaryProfileKey     = "profile"
+	changeSummaryGenerationKey  = "profileGeneration"
+	changeSummaryUpdateTimeKey  = "lastUpdateTime"
+	changeSummaryProfileKindKey = "profileKind"
+)
+
+// getChangeSummaryName returns the name of the ConfigMap containing the change summary
+// for a given ClusterProfile/Profile. profileNamespace is empty for ClusterProfiles.
+func getChangeSummaryName(profileKind, profileNamespace, profileName string) string {
+	name := changeSummaryNamePrefix + strings.ToLower(profileKind) + "-"
+	if profileNamespace != "" {
+		name += profileNamespace + "-"
+	}
+	name += profileName
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = fmt.Sprintf("%s%x", changeSummaryNamePrefix,
+			sha256.Sum256([]byte(profileKind+profileNamespace+profileName)))
+	}
+	return name
+}
+
+// getResourceChanges returns, sorted, a description of each resource created, updated or deleted
+func getResourceChanges(reports []configv1beta1.ResourceReport) []string {
+	changes := make([]string, 0)
+	for i := range reports {
+		switch reports[i].Action {
+		case string(configv1beta1.CreateResourceAction), string(configv1beta1.UpdateResourceAction),
+			string(configv1beta1.DeleteResourceAction):
+
+			resource := &reports[i].Resource
+			kind := resource.Kind
+			if resource.Group != "" {
+				kind = fmt.Sprintf("%s.%s", resource.Kind, resource.Group)
+			}
+			name := resource.Name
+			if resource.Namespace != "" {
+				name = fmt.Sprintf("%s/%s", resource.Namespace, resource.Name)
+			}
+			changes = append(changes, fmt.Sprintf("%s %s %s", reports[i].Action, kind, name))
+		}
+	}
+
+	sort.Strings(changes)
+	return changes
+}
+
+// getReleaseChanges returns, sorted, a description of each helm release installed, upgraded or deleted
+func getReleaseChanges(reports []configv1beta1.ReleaseReport) []string {
+	changes := make([]string, 0)
+	for i := range reports {
+		switch reports[i].Action {
+		case string(configv1beta1.InstallHelmAction), string(configv1beta1.UpgradeHelmAction),
+			string(configv1beta1.UninstallHelmAction):
+
+			changes = append(changes, fmt.Sprintf("%s helm release %s/%s (chart version %s)",
+				reports[i].Action, reports[i].ReleaseNamespace, reports[i].ReleaseName, reports[i].ChartVersion))
+		}
+	}
+
+	sort.Strings(changes)
+	return changes
+}
+
+// publishChangeSummary writes changes in the change summary ConfigMap in the managed cluster.
+// No-op if PublishChangeSummary is not set, in DryRun mode or if nothing changed.
+// Failing to publish the change summary is not considered a deployment failure.
+func publishChangeSummary(ctx context.Context, c, remoteClient client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID,
+	changes []string, logger logr.Logger) {
+
+	if !clusterSummary.Spec.ClusterProfileSpec.PublishChangeSummary ||
+		configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+
+		return
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	if err := updateChangeSummary(ctx, c, remoteClient, clusterSummary, featureID, changes); err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to publish change summary: %v", err))
+	}
+}
+
+func updateChangeSummary(ctx context.Context, c, remoteClient client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID,
+	changes []string) error {
+
+	profile, _, err := configv1beta1.GetProfileOwnerAndTier(ctx, c, clusterSummary)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		// Profile does not exist anymore
+		return nil
+	}
+
+	profileKind := configv1beta1.ClusterProfileKind
+	profileName := profile.GetName()
+	if profile.GetNamespace() != "" {
+		profileKind = configv1beta1.ProfileKind
+		profileName = fmt.Sprintf("%s/%s", profile.GetNamespace(), profile.GetName())
+	}
+
+	err = createNamespace(ctx, remoteClient, clusterSummary, projectsveltos)
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{}
+	name := getChangeSummaryName(profileKind, profile.GetNamespace(), profile.GetName())
+	err = remoteClient.Get(ctx, types.NamespacedName{Namespace: projectsveltos, Name: name}, configMap)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: projectsveltos,
+				Name:      name,
+			},
+		}
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[changeSummaryProfileKindKey] = profileKind
+	configMap.Data[changeSummaryProfileKey] = profileName
+	configMap.Data[changeSummaryGenerationKey] = strconv.FormatInt(profile.GetGeneration(), 10)
+	configMap.Data[changeSummaryUpdateTimeKey] = time.Now().UTC().Format(time.RFC3339)
+	configMap.Data[string(featureID)] = strings.Join(changes, "\n")
+
+	if configMap.ResourceVersion == "" {
+		return remoteClient.Create(ctx, configMap)
+	}
+	return remoteClient.Update(ctx, configMap)
+}
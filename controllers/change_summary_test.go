@@ -0,0 +1,145 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Change summary", func() {
+	var clusterProfile *configv1beta1.ClusterProfile
+	var clusterSummary *configv1beta1.ClusterSummary
+
+	BeforeEach(func() {
+		clusterProfile = &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       randomString(),
+				Generation: 3,
+			},
+		}
+
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       clusterProfile.Name,
+						UID:        "1",
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterName: randomString(),
+				ClusterType: libsveltosv1beta1.ClusterTypeSveltos,
+				ClusterProfileSpec: configv1beta1.Spec{
+					SyncMode:             configv1beta1.SyncModeContinuous,
+					PublishChangeSummary: true,
+				},
+			},
+		}
+		clusterSummary.Spec.ClusterNamespace = clusterSummary.Namespace
+	})
+
+	It("getChangeSummaryName returns a valid ConfigMap name", func() {
+		name := controllers.GetChangeSummaryName(configv1beta1.ProfileKind, "foo", "bar")
+		Expect(name).To(Equal("sveltos-changes-profile-foo-bar"))
+
+		name = controllers.GetChangeSummaryName(configv1beta1.ClusterProfileKind, "", strings.Repeat("a", 250))
+		Expect(len(name)).To(BeNumerically("<=", validation.DNS1123SubdomainMaxLength))
+	})
+
+	It("getResourceChanges and getReleaseChanges only report changes", func() {
+		resourceReports := []configv1beta1.ResourceReport{
+			{
+				Resource: configv1beta1.Resource{Group: "apps", Kind: "Deployment", Namespace: "default", Name: "nginx"},
+				Action:   string(configv1beta1.UpdateResourceAction),
+			},
+			{
+				Resource: configv1beta1.Resource{Kind: "Namespace", Name: "nginx"},
+				Action:   string(configv1beta1.CreateResourceAction),
+			},
+			{
+				Resource: configv1beta1.Resource{Kind: "ConfigMap", Namespace: "default", Name: "config"},
+				Action:   string(configv1beta1.NoResourceAction),
+			},
+		}
+		Expect(controllers.GetResourceChanges(resourceReports)).To(Equal([]string{
+			"Create Namespace nginx",
+			"Update Deployment.apps default/nginx",
+		}))
+
+		releaseReports := []configv1beta1.ReleaseReport{
+			{ReleaseNamespace: "kube-system", ReleaseName: "cilium", ChartVersion: "1.15.0",
+				Action: string(configv1beta1.UpgradeHelmAction)},
+			{ReleaseNamespace: "kyverno", ReleaseName: "kyverno", ChartVersion: "3.2.0",
+				Action: string(configv1beta1.NoHelmAction)},
+		}
+		Expect(controllers.GetReleaseChanges(releaseReports)).To(Equal([]string{
+			"Upgrade helm release kube-system/cilium (chart version 1.15.0)",
+		}))
+	})
+
+	It("publishChangeSummary writes changes in the managed cluster", func() {
+		initObjects := []client.Object{clusterProfile, clusterSummary}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+		remoteClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		controllers.PublishChangeSummary(context.TODO(), c, remoteClient, clusterSummary, configv1beta1.FeatureHelm,
+			[]string{"Install helm release kyverno/kyverno (chart version 3.2.0)"}, logger)
+		controllers.PublishChangeSummary(context.TODO(), c, remoteClient, clusterSummary, configv1beta1.FeatureResources,
+			[]string{"Create Namespace nginx"}, logger)
+
+		configMap := &corev1.ConfigMap{}
+		Expect(remoteClient.Get(context.TODO(),
+			types.NamespacedName{
+				Namespace: "projectsveltos",
+				Name:      controllers.GetChangeSummaryName(configv1beta1.ClusterProfileKind, "", clusterProfile.Name),
+			}, configMap)).To(Succeed())
+		Expect(configMap.Data["profile"]).To(Equal(clusterProfile.Name))
+		Expect(configMap.Data["profileGeneration"]).To(Equal("3"))
+		Expect(configMap.Data[string(configv1beta1.FeatureHelm)]).To(ContainSubstring("kyverno"))
+		Expect(configMap.Data[string(configv1beta1.FeatureResources)]).To(Equal("Create Namespace nginx"))
+
+		// Nothing is published when PublishChangeSummary is not set
+		clusterSummary.Spec.ClusterProfileSpec.PublishChangeSummary = false
+		controllers.PublishChangeSummary(context.TODO(), c, remoteClient, clusterSummary, configv1beta1.FeatureResources,
+			[]string{"Delete Namespace nginx"}, logger)
+		Expect(remoteClient.Get(context.TODO(),
+			types.NamespacedName{Namespace: configMap.Namespace, Name: configMap.Name}, configMap)).To(Succeed())
+		Expect(configMap.Data[string(configv1beta1.FeatureResources)]).To(Equal("Create Namespace nginx"))
+	})
+})
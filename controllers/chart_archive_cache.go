@@ -0,0 +1,185 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// Helm downloads the chart archive on every install/upgrade. Deploying the same chart to many
+// clusters would download it once per cluster. Archives of charts with an exact version are
+// instead downloaded once and then reused from the chart cache, till evicted by the
+// ChartCacheCollector (see chart_cache.go) or removed because not used anymore.
+// Archives are keyed by repository, chart, version and credentials used to pull them. So
+// a chart pulled with some credentials is never served to a request with different ones.
+// Concurrent requests for the same archive wait for the first one to download it.
+// Archives are referenced while being used by a deployment, so they are not removed from the chart
+// cache (see removeChartCacheFile) between being located and being loaded.
+// Templates rendered from a chart archive can be reused as well (see chart_render_cache.go).
+
+var (
+	chartArchivesMux sync.Mutex
+	// chartArchives contains, per key (getChartArchiveKey), the path of the downloaded chart archive
+	chartArchives = map[string]string{}
+	// chartArchivesInUse contains, per chart archive path, the number of deployments using it
+	chartArchivesInUse = map[string]int{}
+
+	// chartArchiveLocks contains, per key, the lock held while the chart archive is being downloaded
+	chartArchiveLocks = newKeyedMutex()
+)
+
+// getChartArchiveKey returns the key identifying a chart archive. Returns an empty string
+// if the chart archive must not be reused (local charts or version not exact).
+func getChartArchiveKey(chartPathOptions *action.ChartPathOptions, name string, settings *cli.EnvSettings,
+) (string, error) {
+
+	if _, err := semver.StrictNewVersion(strings.TrimPrefix(chartPathOptions.Version, "v")); err != nil {
+		return "", nil //nolint: nilerr // version is not exact. Chart is not cached
+	}
+
+	var registryConfig []byte
+	if settings.RegistryConfig != "" {
+		var err error
+		registryConfig, err = os.ReadFile(settings.RegistryConfig)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	fields := []string{chartPathOptions.RepoURL, name, chartPathOptions.Version,
+		chartPathOptions.Username, chartPathOptions.Password, chartPathOptions.CaFile, chartPathOptions.CertFile,
+		chartPathOptions.KeyFile, fmt.Sprint(chartPathOptions.InsecureSkipTLSverify),
+		fmt.Sprint(chartPathOptions.PlainHTTP), string(registryConfig)}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(fields, "\x00")))), nil
+}
+
+// getCachedChartArchive returns the path of the chart archive downloaded for key, if still
+// in the chart cache. The archive is marked as used, so it is evicted last, and referenced till
+// returned function is invoked.
+func getCachedChartArchive(key string, now time.Time) (string, func(), bool) {
+	chartArchivesMux.Lock()
+	defer chartArchivesMux.Unlock()
+
+	path, ok := chartArchives[key]
+	if !ok {
+		return "", nil, false
+	}
+
+	if err := os.Chtimes(path, now, now); err != nil {
+		// Evicted
+		delete(chartArchives, key)
+		return "", nil, false
+	}
+	return path, referenceChartArchive(path), true
+}
+
+// acquireChartArchive references the chart archive at path till returned function is invoked
+func acquireChartArchive(path string) func() {
+	chartArchivesMux.Lock()
+	defer chartArchivesMux.Unlock()
+	return referenceChartArchive(path)
+}
+
+// referenceChartArchive must be invoked with chartArchivesMux held
+func referenceChartArchive(path string) func() {
+	chartArchivesInUse[path]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			chartArchivesMux.Lock()
+			defer chartArchivesMux.Unlock()
+			chartArchivesInUse[path]--
+			if chartArchivesInUse[path] <= 0 {
+				delete(chartArchivesInUse, path)
+			}
+		})
+	}
+}
+
+// removeChartCacheFile removes the file at path from the chart cache, unless it is a chart archive
+// currently used by a deployment. Returns false if file was not removed because in use.
+func removeChartCacheFile(path string) (bool, error) {
+	chartArchivesMux.Lock()
+	defer chartArchivesMux.Unlock()
+
+	if chartArchivesInUse[path] > 0 {
+		return false, nil
+	}
+
+	for key := range chartArchives {
+		if chartArchives[key] == path {
+			delete(chartArchives, key)
+		}
+	}
+
+	return true, os.Remove(path)
+}
+
+func storeChartArchive(key, path string) {
+	if !isInChartCache(path) {
+		return
+	}
+
+	chartArchivesMux.Lock()
+	defer chartArchivesMux.Unlock()
+	chartArchives[key] = path
+}
+
+// locateCachedChart returns the local path of the chart archive. The archive is downloaded,
+// using download, only if not already in the chart cache.
+// Archive is not removed from the chart cache till the returned function is invoked. Callers
+// must invoke it once done loading the archive.
+func locateCachedChart(chartPathOptions *action.ChartPathOptions, name string, settings *cli.EnvSettings,
+	download func() (string, error)) (string, func(), error) {
+
+	key, err := getChartArchiveKey(chartPathOptions, name, settings)
+	if err != nil || key == "" {
+		path, err := download()
+		if err != nil {
+			return "", nil, err
+		}
+		return path, acquireChartArchive(path), nil
+	}
+
+	unlock := chartArchiveLocks.lock(key)
+	defer unlock()
+
+	if path, release, ok := getCachedChartArchive(key, time.Now()); ok {
+		chartArchiveCacheHitsCounter.Inc()
+		return path, release, nil
+	}
+
+	path, err := download()
+	if err != nil {
+		return "", nil, err
+	}
+	release := acquireChartArchive(path)
+	storeChartArchive(key, path)
+	return path, release, nil
+}
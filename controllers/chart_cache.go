@@ -0,0 +1,322 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/cli"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Helm downloads repository index files and chart archives in the repository cache and never
+// removes them. The kustomize and helm handlers also create temporary files and directories which
+// are left behind if the controller is killed while using them. On long-running pods all of those
+// keep accumulating.
+// The ChartCacheCollector periodically removes cached files not used for longer than the configured
+// TTL and, when the cache exceeds the configured size, the least recently used ones. An evicted
+// file is simply downloaded again when needed (see chart_archive_cache.go for archives reuse).
+// Chart archives are also removed as soon as no ClusterSummary, which deployed them, uses
+// the helm feature anymore. Chart archives currently being deployed are never removed.
+// Chart templates rendered for a release are cached in the same directory (see chart_render_cache.go).
+
+const (
+	defaultChartCacheGCInterval = 10 * time.Minute
+	// staleTempEntryAge is the age after which temporary files and directories created by the
+	// controller are considered left behind
+	staleTempEntryAge = time.Hour
+)
+
+var (
+	chartCacheOptions ChartCacheOptions
+
+	chartCacheMux sync.Mutex
+	// chartCacheUsers contains, per cached chart archive, the ClusterSummaries which deployed it
+	chartCacheUsers = map[string]map[string]bool{}
+
+	// tempEntryPatterns are the patterns of the temporary files and directories created by the controller
+	tempEntryPatterns = []string{"kustomization-*", "kubeconfig*", "ca-*.crt", "config-*.json"}
+)
+
+// ChartCacheOptions configures the helm repository cache
+type ChartCacheOptions struct {
+	// Dir is the directory used as helm repository cache. Empty means helm default
+	Dir string
+
+	// MaxSize is the size, in bytes, above which least recently used files are evicted.
+	// Zero means no limit.
+	MaxSize int64
+
+	// TTL is the time after which a file not used is evicted. Zero means no TTL.
+	TTL time.Duration
+
+	// GCInterval is how often cache is garbage collected. Zero means default (10m)
+	GCInterval time.Duration
+
+	// RenderCache enables reusing chart templates rendered for a release (see chart_render_cache.go)
+	RenderCache bool
+}
+
+// SetChartCacheOptions sets the helm repository cache options. Nil resets to defaults.
+func SetChartCacheOptions(options *ChartCacheOptions) error {
+	if options == nil {
+		chartCacheOptions = ChartCacheOptions{}
+		return nil
+	}
+	if options.MaxSize < 0 {
+		return fmt.Errorf("invalid chart cache max size %d", options.MaxSize)
+	}
+	if options.TTL < 0 {
+		return fmt.Errorf("invalid chart cache TTL %s", options.TTL)
+	}
+	if options.Dir != "" {
+		dir, err := filepath.Abs(options.Dir)
+		if err != nil {
+			return fmt.Errorf("invalid chart cache directory %q: %w", options.Dir, err)
+		}
+		options.Dir = dir
+	}
+
+	chartCacheOptions = *options
+	return nil
+}
+
+// getChartCacheDir returns the directory used as helm repository cache
+func getChartCacheDir() string {
+	if chartCacheOptions.Dir != "" {
+		return chartCacheOptions.Dir
+	}
+	return cli.New().RepositoryCache
+}
+
+func getChartCacheGCInterval() time.Duration {
+	if chartCacheOptions.GCInterval <= 0 {
+		return defaultChartCacheGCInterval
+	}
+	return chartCacheOptions.GCInterval
+}
+
+func getChartCacheUserKey(clusterSummary *configv1beta1.ClusterSummary) string {
+	return fmt.Sprintf("%s/%s", clusterSummary.Namespace, clusterSummary.Name)
+}
+
+// recordChartCacheUsage records that clusterSummary deployed the chart archive at chartPath
+func recordChartCacheUsage(clusterSummary *configv1beta1.ClusterSummary, chartPath string) {
+	if !isInChartCache(chartPath) {
+		return
+	}
+
+	chartCacheMux.Lock()
+	defer chartCacheMux.Unlock()
+
+	if _, ok := chartCacheUsers[chartPath]; !ok {
+		chartCacheUsers[chartPath] = map[string]bool{}
+	}
+	chartCacheUsers[chartPath][getChartCacheUserKey(clusterSummary)] = true
+}
+
+// releaseChartCacheUsage is invoked when clusterSummary does not use the helm feature anymore.
+// Chart archives not used by any other ClusterSummary are removed from the cache.
+func releaseChartCacheUsage(clusterSummary *configv1beta1.ClusterSummary, logger logr.Logger) {
+	key := getChartCacheUserKey(clusterSummary)
+
+	chartCacheMux.Lock()
+	defer chartCacheMux.Unlock()
+
+	for chartPath, users := range chartCacheUsers {
+		if !users[key] {
+			continue
+		}
+		delete(users, key)
+		if len(users) != 0 {
+			continue
+		}
+		delete(chartCacheUsers, chartPath)
+		removed, err := removeChartCacheFile(chartPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to remove cached chart %s: %v", chartPath, err))
+			continue
+		}
+		if !removed {
+			// Being deployed by another ClusterSummary. Left to the ChartCacheCollector.
+			continue
+		}
+		chartCacheEvictionsCounter.Inc()
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("removed cached chart %s", chartPath))
+	}
+}
+
+func isInChartCache(path string) bool {
+	rel, err := filepath.Rel(getChartCacheDir(), path)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+type cachedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// collectChartCache evicts files not used for longer than TTL and then, if cache size is
+// still above MaxSize, least recently used files till it is not anymore.
+// Files are considered used when (re)downloaded.
+func collectChartCache(now time.Time, logger logr.Logger) {
+	dir := getChartCacheDir()
+
+	var files []cachedFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// File removed in the meantime
+			return nil //nolint: nilerr // ignore files removed while walking
+		}
+		files = append(files, cachedFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to walk chart cache %s: %v", dir, err))
+		return
+	}
+
+	// Least recently used first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	var size int64
+	for i := range files {
+		size += files[i].size
+	}
+
+	remaining := 0
+	for i := range files {
+		expired := chartCacheOptions.TTL > 0 && now.Sub(files[i].modTime) > chartCacheOptions.TTL
+		oversize := chartCacheOptions.MaxSize > 0 && size > chartCacheOptions.MaxSize
+		if !expired && !oversize {
+			remaining++
+			continue
+		}
+
+		removed, err := removeChartCacheFile(files[i].path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to remove cached file %s: %v", files[i].path, err))
+			remaining++
+			continue
+		}
+		if !removed {
+			// Chart archive currently being deployed
+			remaining++
+			continue
+		}
+		size -= files[i].size
+		chartCacheEvictionsCounter.Inc()
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("evicted cached file %s", files[i].path))
+	}
+
+	chartCacheSizeGauge.Set(float64(size))
+	chartCacheFilesGauge.Set(float64(remaining))
+}
+
+// removeStaleTempEntries removes temporary files and directories created by the controller
+// and not modified for longer than staleTempEntryAge
+func removeStaleTempEntries(tempDir string, now time.Time, logger logr.Logger) {
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to read temporary directory %s: %v", tempDir, err))
+		return
+	}
+
+	for i := range entries {
+		if !isControllerTempEntry(entries[i].Name()) {
+			continue
+		}
+		info, err := entries[i].Info()
+		if err != nil || now.Sub(info.ModTime()) <= staleTempEntryAge {
+			continue
+		}
+		path := filepath.Join(tempDir, entries[i].Name())
+		if err := os.RemoveAll(path); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to remove stale temporary entry %s: %v", path, err))
+			continue
+		}
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("removed stale temporary entry %s", path))
+	}
+}
+
+func isControllerTempEntry(name string) bool {
+	for i := range tempEntryPatterns {
+		if ok, _ := filepath.Match(tempEntryPatterns[i], name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ChartCacheCollector is a manager Runnable which periodically garbage collects the helm
+// repository cache and temporary files left behind
+type ChartCacheCollector struct {
+	logger logr.Logger
+}
+
+// NewChartCacheCollector returns a ChartCacheCollector. Cache is garbage collected based on
+// the options set with SetChartCacheOptions.
+func NewChartCacheCollector(logger logr.Logger) *ChartCacheCollector {
+	return &ChartCacheCollector{logger: logger}
+}
+
+// NeedLeaderElection returns false so that collector runs on every replica, each having its own cache
+func (c *ChartCacheCollector) NeedLeaderElection() bool {
+	return false
+}
+
+func (c *ChartCacheCollector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(getChartCacheGCInterval())
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		collectChartCache(now, c.logger)
+		removeStaleTempEntries(os.TempDir(), now, c.logger)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
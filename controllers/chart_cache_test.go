@@ -0,0 +1,336 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Chart cache", func() {
+	var cacheDir string
+
+	BeforeEach(func() {
+		cacheDir = GinkgoT().TempDir()
+		Expect(controllers.SetChartCacheOptions(&controllers.ChartCacheOptions{Dir: cacheDir})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(controllers.SetChartCacheOptions(nil)).To(Succeed())
+	})
+
+	writeFile := func(dir, name string, size int, modTime time.Time) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, make([]byte, size), 0o600)).To(Succeed())
+		Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+		return path
+	}
+
+	It("SetChartCacheOptions validates options", func() {
+		Expect(controllers.SetChartCacheOptions(&controllers.ChartCacheOptions{MaxSize: -1})).ToNot(Succeed())
+		Expect(controllers.SetChartCacheOptions(&controllers.ChartCacheOptions{TTL: -time.Minute})).ToNot(Succeed())
+		Expect(controllers.GetChartCacheDir()).To(Equal(cacheDir))
+	})
+
+	It("collectChartCache evicts expired and least recently used files", func() {
+		now := time.Now()
+		expired := writeFile(cacheDir, "expired-0.1.0.tgz", 10, now.Add(-2*time.Hour))
+		oldest := writeFile(cacheDir, "oldest-0.1.0.tgz", 10, now.Add(-30*time.Minute))
+		recent := writeFile(cacheDir, "recent-0.1.0.tgz", 10, now.Add(-time.Minute))
+
+		Expect(controllers.SetChartCacheOptions(&controllers.ChartCacheOptions{
+			Dir: cacheDir, TTL: time.Hour, MaxSize: 15,
+		})).To(Succeed())
+
+		controllers.CollectChartCache(now, logr.Discard())
+
+		Expect(expired).ToNot(BeAnExistingFile())
+		Expect(oldest).ToNot(BeAnExistingFile())
+		Expect(recent).To(BeAnExistingFile())
+	})
+
+	It("releaseChartCacheUsage removes charts not used anymore", func() {
+		shared := writeFile(cacheDir, "shared-0.1.0.tgz", 10, time.Now())
+		owned := writeFile(cacheDir, "owned-0.1.0.tgz", 10, time.Now())
+
+		first := &configv1beta1.ClusterSummary{ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()}}
+		second := &configv1beta1.ClusterSummary{ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()}}
+
+		controllers.RecordChartCacheUsage(first, shared)
+		controllers.RecordChartCacheUsage(first, owned)
+		controllers.RecordChartCacheUsage(second, shared)
+
+		controllers.ReleaseChartCacheUsage(first, logr.Discard())
+		Expect(owned).ToNot(BeAnExistingFile())
+		Expect(shared).To(BeAnExistingFile())
+
+		controllers.ReleaseChartCacheUsage(second, logr.Discard())
+		Expect(shared).ToNot(BeAnExistingFile())
+	})
+
+	It("locateCachedChart downloads chart archives with exact version only once", func() {
+		downloads := 0
+		name := randomString()
+		download := func() (string, error) {
+			downloads++
+			return writeFile(cacheDir, name+"-1.2.3.tgz", 10, time.Now().Add(-time.Hour)), nil
+		}
+
+		options := &action.ChartPathOptions{RepoURL: "https://" + randomString(), Version: "1.2.3"}
+		settings := &cli.EnvSettings{}
+		path, release, err := controllers.LocateCachedChart(options, name, settings, download)
+		Expect(err).To(BeNil())
+		Expect(downloads).To(Equal(1))
+		release()
+
+		cached, release, err := controllers.LocateCachedChart(options, name, settings, download)
+		Expect(err).To(BeNil())
+		Expect(cached).To(Equal(path))
+		Expect(downloads).To(Equal(1))
+		release()
+		// Reused archive is marked as used
+		info, err := os.Stat(path)
+		Expect(err).To(BeNil())
+		Expect(time.Since(info.ModTime())).To(BeNumerically("<", time.Minute))
+
+		// Different credentials, different archive
+		withCredentials := *options
+		withCredentials.Username = randomString()
+		_, release, err = controllers.LocateCachedChart(&withCredentials, name, settings, download)
+		Expect(err).To(BeNil())
+		Expect(downloads).To(Equal(2))
+		release()
+
+		// Evicted archive is downloaded again
+		Expect(os.Remove(path)).To(Succeed())
+		_, release, err = controllers.LocateCachedChart(options, name, settings, download)
+		Expect(err).To(BeNil())
+		Expect(downloads).To(Equal(3))
+		release()
+
+		// Version not exact, chart is always downloaded
+		options.Version = ">=1.0.0"
+		for i := 0; i < 2; i++ {
+			_, release, err = controllers.LocateCachedChart(options, name, settings, download)
+			Expect(err).To(BeNil())
+			release()
+		}
+		Expect(downloads).To(Equal(5))
+
+		// No download lock is left behind
+		Expect(controllers.GetChartArchiveLocksLen()).To(BeZero())
+	})
+
+	It("chart archives being deployed are not evicted", func() {
+		name := randomString()
+		download := func() (string, error) {
+			return writeFile(cacheDir, name+"-1.2.3.tgz", 10, time.Now().Add(-2*time.Hour)), nil
+		}
+
+		options := &action.ChartPathOptions{RepoURL: "https://" + randomString(), Version: "1.2.3"}
+		path, release, err := controllers.LocateCachedChart(options, name, &cli.EnvSettings{}, download)
+		Expect(err).To(BeNil())
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()},
+		}
+		controllers.RecordChartCacheUsage(clusterSummary, path)
+
+		Expect(controllers.SetChartCacheOptions(&controllers.ChartCacheOptions{Dir: cacheDir,
+			TTL: time.Hour})).To(Succeed())
+		controllers.CollectChartCache(time.Now(), logr.Discard())
+		controllers.ReleaseChartCacheUsage(clusterSummary, logr.Discard())
+		Expect(path).To(BeAnExistingFile())
+
+		release()
+		// Releasing more than once has no effect
+		release()
+		controllers.CollectChartCache(time.Now(), logr.Discard())
+		Expect(path).ToNot(BeAnExistingFile())
+	})
+
+	It("rendered chart templates are reused for same chart archive, release and values", func() {
+		Expect(controllers.SetChartCacheOptions(&controllers.ChartCacheOptions{
+			Dir: cacheDir, RenderCache: true,
+		})).To(Succeed())
+
+		ch := &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: randomString(), Version: "1.2.3"},
+			Values:   map[string]interface{}{"replicas": 1},
+			Templates: []*chart.File{
+				{Name: "templates/cm.yaml", Data: []byte(renderCacheConfigMap)},
+				{Name: "templates/hook.yaml", Data: []byte(renderCacheHook)},
+				{Name: "templates/NOTES.txt", Data: []byte("Installed {{ .Release.Name }}")},
+			},
+			Files: []*chart.File{{Name: "crds/crd.yaml", Data: []byte("kind: CustomResourceDefinition")}},
+		}
+		values := map[string]interface{}{"replicas": 3}
+		options := &action.ChartPathOptions{RepoURL: "https://" + randomString(), Version: "1.2.3"}
+		settings := &cli.EnvSettings{}
+
+		key := controllers.GetChartRenderKey(options, ch.Name(), settings, "default", "release", ch, values)
+		Expect(key).ToNot(BeEmpty())
+		_, ok := controllers.GetCachedRenderedChart(key, ch, time.Now())
+		Expect(ok).To(BeFalse())
+
+		rel := renderRelease(ch, "default", "release", values)
+		Expect(controllers.StoreRenderedChart(key, rel)).To(Succeed())
+
+		cached, ok := controllers.GetCachedRenderedChart(key, ch, time.Now())
+		Expect(ok).To(BeTrue())
+		Expect(cached.Dependencies()).To(BeEmpty())
+		Expect(cached.CRDObjects()).To(HaveLen(1))
+		Expect(cached.CRDObjects()[0].File.Data).To(Equal(ch.CRDObjects()[0].File.Data))
+
+		reused := renderRelease(cached, "default", "release", values)
+		Expect(reused.Manifest).To(Equal(rel.Manifest))
+		Expect(reused.Hooks).To(HaveLen(1))
+		Expect(reused.Hooks[0].Manifest).To(Equal(rel.Hooks[0].Manifest))
+		Expect(reused.Hooks[0].Events).To(Equal(rel.Hooks[0].Events))
+		Expect(reused.Info.Notes).To(Equal(rel.Info.Notes))
+
+		// Different values or release, different key
+		Expect(controllers.GetChartRenderKey(options, ch.Name(), settings, "default", "release", ch,
+			map[string]interface{}{"replicas": 2})).ToNot(Equal(key))
+		Expect(controllers.GetChartRenderKey(options, ch.Name(), settings, "other", "release", ch,
+			values)).ToNot(Equal(key))
+
+		// Version not exact, templates are always rendered
+		notExact := *options
+		notExact.Version = ">=1.0.0"
+		Expect(controllers.GetChartRenderKey(&notExact, ch.Name(), settings, "default", "release", ch,
+			values)).To(BeEmpty())
+	})
+
+	It("templates depending on the cluster or on each render are not reused", func() {
+		Expect(controllers.SetChartCacheOptions(&controllers.ChartCacheOptions{
+			Dir: cacheDir, RenderCache: true,
+		})).To(Succeed())
+
+		options := &action.ChartPathOptions{RepoURL: "https://" + randomString(), Version: "1.2.3"}
+		settings := &cli.EnvSettings{}
+		newChart := func(template string) *chart.Chart {
+			return &chart.Chart{
+				Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: randomString(), Version: "1.2.3"},
+				Templates: []*chart.File{{Name: "templates/cm.yaml", Data: []byte(template)}},
+			}
+		}
+
+		for _, template := range []string{
+			`{{ (lookup "v1" "Namespace" "" "default").metadata.uid }}`,
+			`{{ .Capabilities.KubeVersion.Version }}`,
+			`{{ randAlphaNum 10 }}`,
+			`{{ now | date "2006" }}`,
+			`{{ genCA "ca" 365 }}`,
+			`{{- if .Release.IsUpgrade }}upgrade{{ end }}`,
+		} {
+			Expect(controllers.GetChartRenderKey(options, "chart", settings, "default", "release",
+				newChart(template), nil)).To(BeEmpty(), template)
+		}
+
+		// Values can be rendered with tpl
+		Expect(controllers.GetChartRenderKey(options, "chart", settings, "default", "release",
+			newChart(`{{ tpl .Values.data . }}`), map[string]interface{}{"data": "{{ uuidv4 }}"})).To(BeEmpty())
+
+		// Subchart depending on the cluster
+		parent := newChart(`{{ .Values.data }}`)
+		parent.AddDependency(newChart(`{{ .Capabilities.APIVersions }}`))
+		Expect(controllers.GetChartRenderKey(options, "chart", settings, "default", "release", parent,
+			nil)).To(BeEmpty())
+
+		// Functions outside template actions are plain text
+		Expect(controllers.GetChartRenderKey(options, "chart", settings, "default", "release",
+			newChart(`# lookup and now are plain text {{ .Values.data }}`), nil)).ToNot(BeEmpty())
+
+		// Render cache disabled
+		Expect(controllers.SetChartCacheOptions(&controllers.ChartCacheOptions{Dir: cacheDir})).To(Succeed())
+		Expect(controllers.GetChartRenderKey(options, "chart", settings, "default", "release",
+			newChart(`{{ .Values.data }}`), nil)).To(BeEmpty())
+	})
+
+	It("removeStaleTempEntries removes only stale controller temporary entries", func() {
+		tempDir := GinkgoT().TempDir()
+		now := time.Now()
+
+		stale := filepath.Join(tempDir, "kustomization-"+randomString())
+		Expect(os.Mkdir(stale, 0o700)).To(Succeed())
+		Expect(os.Chtimes(stale, now.Add(-2*time.Hour), now.Add(-2*time.Hour))).To(Succeed())
+		inUse := writeFile(tempDir, "kubeconfig"+randomString(), 10, now)
+		other := writeFile(tempDir, randomString(), 10, now.Add(-2*time.Hour))
+
+		controllers.RemoveStaleTempEntries(tempDir, now, logr.Discard())
+
+		Expect(stale).ToNot(BeADirectory())
+		Expect(inUse).To(BeAnExistingFile())
+		Expect(other).To(BeAnExistingFile())
+	})
+})
+
+const (
+	renderCacheConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}
+  namespace: {{ .Release.Namespace }}
+data:
+  replicas: "{{ .Values.replicas }}"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  ports:
+  - port: 80`
+
+	renderCacheHook = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-hook
+  annotations:
+    helm.sh/hook: pre-install,pre-upgrade
+data:
+  chart: {{ .Chart.Name }}`
+)
+
+// renderRelease renders chart as helm install would, without any cluster
+func renderRelease(ch *chart.Chart, releaseNamespace, releaseName string, values map[string]interface{},
+) *release.Release {
+
+	installClient := action.NewInstall(&action.Configuration{Log: func(string, ...interface{}) {}})
+	installClient.ClientOnly = true
+	installClient.DryRun = true
+	installClient.Namespace = releaseNamespace
+	installClient.ReleaseName = releaseName
+	rel, err := installClient.Run(ch, values)
+	Expect(err).To(BeNil())
+	return rel
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Deploying the same chart, with the same values, to many clusters renders the same templates over
+// and over. When the render cache is enabled (ChartCacheOptions.RenderCache), the manifests helm
+// rendered for a release are stored in the chart cache, keyed by chart archive (getChartArchiveKey),
+// release namespace/name and values. Following installs/upgrades with the same key hand helm a chart
+// whose only template outputs those manifests, so chart templates are not rendered again.
+// Rendered manifests are files in the chart cache, so evicted like chart archives.
+// Only charts whose output does not depend on the cluster are cached. Charts are not cached if any
+// template (or value, since values can be rendered with tpl) uses cluster capabilities, lookup,
+// functions returning a different result on each call (random, dates, generated certificates) or the
+// release revision. Charts deployed with a post-renderer (patches, registry mirrors), updating
+// dependencies or reusing previous release values are not cached either.
+
+const (
+	renderedChartDir = "rendered"
+	// renderedFilesDir is the directory, in the chart built from rendered manifests, containing those
+	renderedFilesDir      = "rendered"
+	renderedNotesFileName = "rendered-notes.txt"
+	manifestSourcePrefix  = "---\n# Source: "
+)
+
+var (
+	// templateActionRegex matches template actions ({{ ... }})
+	templateActionRegex = regexp.MustCompile(`(?s){{.*?}}`)
+
+	// nonCacheableTemplateRegex matches, within a template action, what makes rendered output
+	// depend on the cluster or differ on each render
+	nonCacheableTemplateRegex = regexp.MustCompile(`\.Capabilities|\.Release\.(Revision|IsInstall|IsUpgrade)|` +
+		`\b(lookup|rand[A-Za-z]*|uuidv4|now|date[A-Za-z]*|unixEpoch|gen[A-Z][A-Za-z]*|htpasswd|getHostByName|` +
+		`shuffle|bcrypt|derivePassword)\b`)
+)
+
+// renderedChart is the content stored in the render cache
+type renderedChart struct {
+	// Templates contains, per template path (relative to the chart), the manifests it rendered
+	Templates map[string]string `json:"templates"`
+	Notes     string            `json:"notes,omitempty"`
+}
+
+// getChartRenderKey returns the key identifying the manifests rendered for a release. Returns an empty
+// string if rendered manifests must not be reused.
+func getChartRenderKey(chartPathOptions *action.ChartPathOptions, name string, settings *cli.EnvSettings,
+	releaseNamespace, releaseName string, ch *chart.Chart, values map[string]interface{}) string {
+
+	if !chartCacheOptions.RenderCache {
+		return ""
+	}
+
+	archiveKey, err := getChartArchiveKey(chartPathOptions, name, settings)
+	if err != nil || archiveKey == "" {
+		return ""
+	}
+
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+
+	if !isChartRenderCacheable(ch, string(valuesJSON)) {
+		return ""
+	}
+
+	fields := []string{archiveKey, releaseNamespace, releaseName, string(valuesJSON)}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(fields, "\x00"))))
+}
+
+// isChartRenderCacheable returns true if rendering chart, and its dependencies, with values always
+// produces the same output whatever the cluster
+func isChartRenderCacheable(ch *chart.Chart, values string) bool {
+	if !isTemplateCacheable(values) {
+		return false
+	}
+
+	for i := range ch.Templates {
+		if !isTemplateCacheable(string(ch.Templates[i].Data)) {
+			return false
+		}
+	}
+
+	for _, dependency := range ch.Dependencies() {
+		if !isChartRenderCacheable(dependency, "") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isTemplateCacheable(text string) bool {
+	for _, action := range templateActionRegex.FindAllString(text, -1) {
+		if nonCacheableTemplateRegex.MatchString(action) {
+			return false
+		}
+	}
+	return true
+}
+
+func getRenderedChartPath(key string) string {
+	return filepath.Join(getChartCacheDir(), renderedChartDir, key+".json")
+}
+
+// getCachedRenderedChart returns, if manifests for key are in the render cache, a chart outputting
+// those when rendered. ch is the chart the manifests were rendered from.
+func getCachedRenderedChart(key string, ch *chart.Chart, now time.Time) (*chart.Chart, bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	renderedPath := getRenderedChartPath(key)
+	data, err := os.ReadFile(renderedPath)
+	if err != nil {
+		return nil, false
+	}
+
+	rendered := &renderedChart{}
+	if err := json.Unmarshal(data, rendered); err != nil {
+		return nil, false
+	}
+
+	// Mark as used, so it is evicted last
+	_ = os.Chtimes(renderedPath, now, now)
+	chartRenderCacheHitsCounter.Inc()
+
+	return buildRenderedChart(ch, rendered), true
+}
+
+// storeRenderedChart stores in the render cache the manifests, hooks included, helm rendered for rel
+func storeRenderedChart(key string, rel *release.Release) error {
+	if key == "" || rel == nil {
+		return nil
+	}
+
+	rendered := &renderedChart{Templates: map[string]string{}}
+	addManifest := func(source, manifest string) {
+		// Source is prefixed with the chart name
+		_, templatePath, _ := strings.Cut(source, "/")
+		if current, ok := rendered.Templates[templatePath]; ok {
+			manifest = current + "\n---\n" + manifest
+		}
+		rendered.Templates[templatePath] = manifest
+	}
+
+	// Manifest is the list of the rendered manifests, each one in the form "---\n# Source: <path>\n<manifest>\n"
+	for _, entry := range strings.Split(rel.Manifest, manifestSourcePrefix) {
+		source, manifest, found := strings.Cut(entry, "\n")
+		if !found {
+			continue
+		}
+		addManifest(source, strings.TrimSuffix(manifest, "\n"))
+	}
+	for i := range rel.Hooks {
+		addManifest(rel.Hooks[i].Path, rel.Hooks[i].Manifest)
+	}
+	if rel.Info != nil {
+		rendered.Notes = rel.Info.Notes
+	}
+
+	data, err := json.Marshal(rendered)
+	if err != nil {
+		return err
+	}
+
+	renderedPath := getRenderedChartPath(key)
+	if err := os.MkdirAll(filepath.Dir(renderedPath), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so a partially written file is never read
+	tmp, err := os.CreateTemp(filepath.Dir(renderedPath), key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), renderedPath)
+}
+
+// buildRenderedChart returns a chart with same metadata, values and CRDs as ch, whose templates
+// output rendered manifests verbatim
+func buildRenderedChart(ch *chart.Chart, rendered *renderedChart) *chart.Chart {
+	metadata := *ch.Metadata
+	// Dependencies are already rendered in the manifests
+	metadata.Dependencies = nil
+
+	var files, templates []*chart.File
+	addTemplate := func(templatePath, fileName, content string) {
+		files = append(files, &chart.File{Name: fileName, Data: []byte(content)})
+		templates = append(templates, &chart.File{
+			Name: templatePath,
+			Data: []byte(fmt.Sprintf("{{ .Files.Get %q }}", fileName)),
+		})
+	}
+
+	for templatePath, manifest := range rendered.Templates {
+		addTemplate(templatePath, path.Join(renderedFilesDir, templatePath), manifest)
+	}
+	if rendered.Notes != "" {
+		addTemplate(path.Join("templates", "NOTES.txt"), renderedNotesFileName, rendered.Notes)
+	}
+
+	// CRDs are not templates. Those, dependencies' ones included, are installed as they are.
+	for i, crd := range ch.CRDObjects() {
+		files = append(files, &chart.File{
+			Name: path.Join("crds", fmt.Sprintf("%d-%s", i, path.Base(crd.Name))),
+			Data: crd.File.Data,
+		})
+	}
+
+	return &chart.Chart{
+		Metadata:  &metadata,
+		Values:    ch.Values,
+		Schema:    ch.Schema,
+		Templates: templates,
+		Files:     files,
+	}
+}
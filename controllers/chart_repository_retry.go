@@ -0,0 +1,279 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// Chart repositories (for instance GitHub pages) rate limit clients. Requests to download
+// repository indexes and charts are retried when the repository answers:
+// - 429 (Too Many Requests) or 408 (Request Timeout);
+// - 5xx (server errors).
+// Retry-After, when sent, is honored. Otherwise an exponential backoff is used.
+// Any other 4xx is a permanent failure and is not retried.
+// When a request fails, the classification ends up in FeatureSummary.FailureReason.
+
+const (
+	defaultChartRepositoryMaxRetries  = 3
+	defaultChartRepositoryBaseBackoff = time.Second
+	defaultChartRepositoryMaxWait     = 30 * time.Second
+)
+
+// ChartRepositoryRetryOptions configures how requests to helm chart repositories are retried
+type ChartRepositoryRetryOptions struct {
+	// MaxRetries is the number of times a rate limited or failed (5xx) request is retried.
+	// Zero disables retries.
+	MaxRetries int
+
+	// BaseBackoff is how long to wait before the first retry when the repository does not
+	// send Retry-After. Each retry doubles it. Zero means default (1s)
+	BaseBackoff time.Duration
+
+	// MaxWait is the maximum time to wait before a retry. If the repository asks, with
+	// Retry-After, to wait longer, the request is not retried. Zero means default (30s)
+	MaxWait time.Duration
+}
+
+var (
+	chartRepositoryRetryOptions = ChartRepositoryRetryOptions{MaxRetries: defaultChartRepositoryMaxRetries}
+)
+
+// SetChartRepositoryRetryOptions sets how requests to chart repositories are retried. Nil resets to defaults.
+func SetChartRepositoryRetryOptions(options *ChartRepositoryRetryOptions) error {
+	if options == nil {
+		chartRepositoryRetryOptions = ChartRepositoryRetryOptions{MaxRetries: defaultChartRepositoryMaxRetries}
+		return nil
+	}
+	if options.MaxRetries < 0 {
+		return fmt.Errorf("invalid chart repository max retries %d", options.MaxRetries)
+	}
+	if options.BaseBackoff < 0 {
+		return fmt.Errorf("invalid chart repository base backoff %s", options.BaseBackoff)
+	}
+	if options.MaxWait < 0 {
+		return fmt.Errorf("invalid chart repository max wait %s", options.MaxWait)
+	}
+
+	chartRepositoryRetryOptions = *options
+	return nil
+}
+
+// chartRepositoryError is returned when a chart repository answers with an error status
+type chartRepositoryError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// Attempts is the number of requests sent
+	Attempts int
+	// RetryAfter is the wait requested by the repository, if any
+	RetryAfter time.Duration
+}
+
+func (e *chartRepositoryError) Error() string {
+	// URL is not included: http.Client already reports it
+	msg := fmt.Sprintf("chart repository answered %s (%s, %d attempt(s))",
+		e.Status, e.classification(), e.Attempts)
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(", repository asked to retry after %s", e.RetryAfter)
+	}
+	return msg
+}
+
+// IsPermanent returns true if the request should not be retried (any 4xx but 408 and 429)
+func (e *chartRepositoryError) IsPermanent() bool {
+	return !isTransientChartRepositoryStatus(e.StatusCode)
+}
+
+func (e *chartRepositoryError) classification() string {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return "rate limited"
+	case e.IsPermanent():
+		return "permanent failure"
+	default:
+		return "repository unavailable"
+	}
+}
+
+// MessageCode returns the MessageCode classifying the failure
+func (e *chartRepositoryError) MessageCode() configv1beta1.MessageCode {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return configv1beta1.MessageCodeChartRepositoryRateLimited
+	case e.IsPermanent():
+		return configv1beta1.MessageCodeChartRepositoryRejected
+	default:
+		return configv1beta1.MessageCodeChartRepositoryUnavailable
+	}
+}
+
+// getChartPullMessageCode returns the MessageCode classifying a failure pulling a helm chart
+// (or its repository index)
+func getChartPullMessageCode(err error) configv1beta1.MessageCode {
+	var repoErr *chartRepositoryError
+	if errors.As(err, &repoErr) {
+		return repoErr.MessageCode()
+	}
+	return configv1beta1.MessageCodeChartPullFailed
+}
+
+func isTransientChartRepositoryStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode >= http.StatusInternalServerError
+}
+
+// chartRepositoryRoundTripper retries requests rate limited or failed by chart repositories.
+// Responses with an error status are turned into a chartRepositoryError.
+type chartRepositoryRoundTripper struct {
+	base    http.RoundTripper
+	options ChartRepositoryRetryOptions
+}
+
+// newChartRepositoryTransport returns an http.Transport sending http(s) requests through a
+// chartRepositoryRoundTripper wrapping base. An http.Transport (and not a generic http.RoundTripper)
+// is what helm getters accept.
+func newChartRepositoryTransport(base *http.Transport) *http.Transport {
+	rt := &chartRepositoryRoundTripper{
+		base:    base,
+		options: chartRepositoryRetryOptions,
+	}
+
+	transport := &http.Transport{}
+	transport.RegisterProtocol("http", rt)
+	transport.RegisterProtocol("https", rt)
+	return transport
+}
+
+func (rt *chartRepositoryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	for attempt := 1; ; attempt++ {
+		resp, err := rt.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+
+		repoErr := &chartRepositoryError{
+			URL:        req.URL.Redacted(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Attempts:   attempt,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		drainAndClose(resp)
+
+		if !retryable || repoErr.IsPermanent() || attempt > rt.options.MaxRetries {
+			return nil, repoErr
+		}
+
+		wait := rt.getBackoff(attempt)
+		if repoErr.RetryAfter > 0 {
+			if repoErr.RetryAfter > rt.getMaxWait() {
+				// No point retrying earlier than requested
+				return nil, repoErr
+			}
+			wait = repoErr.RetryAfter
+		}
+
+		if err := sleepWithContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getBackoff returns how long to wait before retry number attempt when repository
+// does not send Retry-After
+func (rt *chartRepositoryRoundTripper) getBackoff(attempt int) time.Duration {
+	backoff := rt.options.BaseBackoff
+	if backoff == 0 {
+		backoff = defaultChartRepositoryBaseBackoff
+	}
+
+	maxWait := rt.getMaxWait()
+	for i := 1; i < attempt && backoff < maxWait; i++ {
+		backoff *= 2
+	}
+	if backoff > maxWait {
+		backoff = maxWait
+	}
+	return backoff
+}
+
+func (rt *chartRepositoryRoundTripper) getMaxWait() time.Duration {
+	if rt.options.MaxWait == 0 {
+		return defaultChartRepositoryMaxWait
+	}
+	return rt.options.MaxWait
+}
+
+// parseRetryAfter parses the Retry-After header value, either a number of seconds or an HTTP
+// date. Zero is returned if value is empty or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+func drainAndClose(resp *http.Response) {
+	// Draining the body allows the connection to be reused
+	const maxDrain = 4096
+	_, _ = io.CopyN(io.Discard, resp.Body, maxDrain)
+	resp.Body.Close()
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
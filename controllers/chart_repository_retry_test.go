@@ -0,0 +1,137 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Chart repository retry", func() {
+	var requests atomic.Int32
+
+	BeforeEach(func() {
+		requests.Store(0)
+		Expect(controllers.SetChartRepositoryRetryOptions(&controllers.ChartRepositoryRetryOptions{
+			MaxRetries:  2,
+			BaseBackoff: time.Millisecond,
+			MaxWait:     time.Second,
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(controllers.SetChartRepositoryRetryOptions(nil)).To(Succeed())
+	})
+
+	// startServer starts a server answering with statuses, in order. Once statuses are exhausted, 200.
+	startServer := func(retryAfter string, statuses ...int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			i := int(requests.Add(1)) - 1
+			if i < len(statuses) {
+				if retryAfter != "" {
+					w.Header().Set("Retry-After", retryAfter)
+				}
+				w.WriteHeader(statuses[i])
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+
+	get := func(url string) error {
+		httpClient := &http.Client{Transport: controllers.NewChartRepositoryTransport(controllers.GetOutboundTransport(nil))}
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	It("retries rate limited requests honoring Retry-After", func() {
+		server := startServer("0", http.StatusTooManyRequests, http.StatusServiceUnavailable)
+		defer server.Close()
+
+		Expect(get(server.URL)).To(Succeed())
+		Expect(requests.Load()).To(Equal(int32(3)))
+	})
+
+	It("classifies failures once retries are exhausted", func() {
+		server := startServer("", http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests)
+		defer server.Close()
+
+		err := get(server.URL)
+		Expect(err).ToNot(BeNil())
+		Expect(requests.Load()).To(Equal(int32(3)))
+		Expect(err.Error()).To(ContainSubstring("rate limited"))
+		Expect(controllers.GetChartPullMessageCode(err)).To(Equal(configv1beta1.MessageCodeChartRepositoryRateLimited))
+
+		requests.Store(0)
+		server = startServer("", http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		defer server.Close()
+
+		err = get(server.URL)
+		Expect(err).ToNot(BeNil())
+		Expect(controllers.GetChartPullMessageCode(err)).To(Equal(configv1beta1.MessageCodeChartRepositoryUnavailable))
+	})
+
+	It("does not retry permanent failures", func() {
+		server := startServer("", http.StatusNotFound)
+		defer server.Close()
+
+		err := get(server.URL)
+		Expect(err).ToNot(BeNil())
+		Expect(requests.Load()).To(Equal(int32(1)))
+		Expect(err.Error()).To(ContainSubstring("permanent failure"))
+		Expect(controllers.GetChartPullMessageCode(err)).To(Equal(configv1beta1.MessageCodeChartRepositoryRejected))
+
+		Expect(controllers.GetChartPullMessageCode(fmt.Errorf("%s", randomString()))).To(
+			Equal(configv1beta1.MessageCodeChartPullFailed))
+	})
+
+	It("does not retry when repository asks to wait longer than max wait", func() {
+		server := startServer("120", http.StatusTooManyRequests)
+		defer server.Close()
+
+		err := get(server.URL)
+		Expect(err).ToNot(BeNil())
+		Expect(requests.Load()).To(Equal(int32(1)))
+		Expect(err.Error()).To(ContainSubstring("retry after 2m0s"))
+	})
+
+	It("parseRetryAfter parses seconds and HTTP dates", func() {
+		now := time.Now()
+		Expect(controllers.ParseRetryAfter("", now)).To(Equal(time.Duration(0)))
+		Expect(controllers.ParseRetryAfter("5", now)).To(Equal(5 * time.Second))
+		Expect(controllers.ParseRetryAfter("-1", now)).To(Equal(time.Duration(0)))
+		Expect(controllers.ParseRetryAfter(randomString(), now)).To(Equal(time.Duration(0)))
+
+		date := now.Add(time.Minute).UTC().Format(http.TimeFormat)
+		Expect(controllers.ParseRetryAfter(date, now)).To(BeNumerically("~", time.Minute, time.Second))
+
+		date = now.Add(-time.Minute).UTC().Format(http.TimeFormat)
+		Expect(controllers.ParseRetryAfter(date, now)).To(Equal(time.Duration(0)))
+	})
+})
@@ -19,6 +19,7 @@ package chartmanager
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -66,6 +67,10 @@ type instance struct {
 
 	// list of tracked clusters
 	clusters *libsveltosset.Set
+
+	// persistMux serializes PersistRegistrations. persisted contains, per bucket, last persisted registrations.
+	persistMux sync.Mutex
+	persisted  map[int][]byte
 }
 
 type HelmReleaseInfo struct {
@@ -171,11 +176,9 @@ func (m *instance) UnregisterClusterSummaryForChart(clusterSummary *configv1beta
 
 	for i := range m.perClusterChartMap[clusterKey][releaseKey] {
 		if m.perClusterChartMap[clusterKey][releaseKey][i] == clusterSummaryKey {
-			// Order is not important. So move the element at index i with last one in order to avoid moving all elements.
-			length := len(m.perClusterChartMap[clusterKey][releaseKey])
-			m.perClusterChartMap[clusterKey][releaseKey][i] =
-				m.perClusterChartMap[clusterKey][releaseKey][length-1]
-			m.perClusterChartMap[clusterKey][releaseKey] = m.perClusterChartMap[clusterKey][releaseKey][:length-1]
+			// Preserve order. Next ClusterSummary in line becomes the manager.
+			m.perClusterChartMap[clusterKey][releaseKey] = append(m.perClusterChartMap[clusterKey][releaseKey][:i],
+				m.perClusterChartMap[clusterKey][releaseKey][i+1:]...)
 			break
 		}
 	}
@@ -187,7 +190,7 @@ func (m *instance) UnregisterClusterSummaryForChart(clusterSummary *configv1beta
 // registered is considered stale and removed.
 func (m *instance) RemoveStaleRegistrations(clusterSummary *configv1beta1.ClusterSummary) {
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return
 	}
 
@@ -236,11 +239,9 @@ func (m *instance) removeClusterSummaryFromChartRegistration(clusterKey, release
 	// and remove it.
 	for i := range m.perClusterChartMap[clusterKey][releaseKey] {
 		if m.perClusterChartMap[clusterKey][releaseKey][i] == clusterSummaryKey {
-			// Order is not important. So move the element at index i with last one in order to avoid moving all elements.
-			length := len(m.perClusterChartMap[clusterKey][releaseKey])
-			m.perClusterChartMap[clusterKey][releaseKey][i] =
-				m.perClusterChartMap[clusterKey][releaseKey][length-1]
-			m.perClusterChartMap[clusterKey][releaseKey] = m.perClusterChartMap[clusterKey][releaseKey][:length-1]
+			// Preserve order. Next ClusterSummary in line becomes the manager.
+			m.perClusterChartMap[clusterKey][releaseKey] = append(m.perClusterChartMap[clusterKey][releaseKey][:i],
+				m.perClusterChartMap[clusterKey][releaseKey][i+1:]...)
 			break
 		}
 	}
@@ -486,7 +487,8 @@ func isClusterSummaryAlreadyRegistered(clusterSummaries []string, clusterSummary
 
 // rebuildRegistrations rebuilds internal structures to identify ClusterSummaries managing
 // helm charts and ClusterSummaries currently just registered but not matching.
-// Relies completely on ClusterSummary.Status
+// Managers are taken from ClusterSummary.Status. If registrations were persisted, ClusterSummaries
+// not managing an helm release are queued in the persisted order.
 func (m *instance) rebuildRegistrations(ctx context.Context, c client.Client) error {
 	// Lock here
 	m.chartMux.Lock()
@@ -498,11 +500,26 @@ func (m *instance) rebuildRegistrations(ctx context.Context, c client.Client) er
 		return err
 	}
 
+	persisted, err := loadRegistrations(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	// Do not depend on list order
+	sort.Slice(clusterSummaryList.Items, func(i, j int) bool {
+		if clusterSummaryList.Items[i].Namespace != clusterSummaryList.Items[j].Namespace {
+			return clusterSummaryList.Items[i].Namespace < clusterSummaryList.Items[j].Namespace
+		}
+		return clusterSummaryList.Items[i].Name < clusterSummaryList.Items[j].Name
+	})
+
 	for i := range clusterSummaryList.Items {
 		cs := &clusterSummaryList.Items[i]
 		m.addManagers(cs)
 	}
 
+	m.addPersistedRegistrations(persisted, clusterSummaryList.Items)
+
 	for i := range clusterSummaryList.Items {
 		cs := &clusterSummaryList.Items[i]
 		m.addNonManagers(cs)
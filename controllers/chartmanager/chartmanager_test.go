@@ -18,11 +18,13 @@ package chartmanager_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -386,6 +388,103 @@ var _ = Describe("Chart manager", func() {
 		Expect(manager.CanManageChart(tmpClusterSummary,
 			&tmpClusterSummary.Spec.ClusterProfileSpec.HelmCharts[1])).To(BeTrue())
 	})
+
+	It("PersistRegistrations keeps queue order across restarts", func() {
+		chartmanager.EnablePersistence("")
+		defer chartmanager.DisablePersistence()
+
+		chart := &clusterSummary.Spec.ClusterProfileSpec.HelmCharts[0]
+		getSummaries := func(status configv1beta1.HelmChartStatus) []configv1beta1.HelmChartSummary {
+			return []configv1beta1.HelmChartSummary{
+				{ReleaseName: chart.ReleaseName, ReleaseNamespace: chart.ReleaseNamespace, Status: status},
+			}
+		}
+
+		clusterSummary.Status.HelmReleaseSummaries = getSummaries(configv1beta1.HelmChartStatusManaging)
+		Expect(c.Status().Update(context.TODO(), clusterSummary)).To(Succeed())
+
+		// Names are chosen so that sorting would queue first the ClusterSummary registered last
+		lastRegistered := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{Name: "a" + randomString(), Namespace: clusterSummary.Namespace},
+			Spec:       clusterSummary.Spec,
+			Status:     configv1beta1.ClusterSummaryStatus{HelmReleaseSummaries: getSummaries(configv1beta1.HelmChartStatusConflict)},
+		}
+		firstRegistered := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{Name: "z" + randomString(), Namespace: clusterSummary.Namespace},
+			Spec:       clusterSummary.Spec,
+			Status:     configv1beta1.ClusterSummaryStatus{HelmReleaseSummaries: getSummaries(configv1beta1.HelmChartStatusConflict)},
+		}
+		Expect(c.Create(context.TODO(), lastRegistered)).To(Succeed())
+		Expect(c.Create(context.TODO(), firstRegistered)).To(Succeed())
+		defer removeSubscriptions(c, lastRegistered)
+		defer removeSubscriptions(c, firstRegistered)
+
+		manager, err := chartmanager.GetChartManagerInstance(context.TODO(), c)
+		Expect(err).To(BeNil())
+		manager.RegisterClusterSummaryForCharts(clusterSummary)
+		manager.RegisterClusterSummaryForCharts(firstRegistered)
+		manager.RegisterClusterSummaryForCharts(lastRegistered)
+		Expect(manager.PersistRegistrations(context.TODO(), c)).To(Succeed())
+
+		configMaps := &corev1.ConfigMapList{}
+		Expect(c.List(context.TODO(), configMaps)).To(Succeed())
+		Expect(configMaps.Items).ToNot(BeEmpty())
+
+		// Simulate a restart
+		chartmanager.ResetInstance()
+		manager, err = chartmanager.GetChartManagerInstance(context.TODO(), c)
+		Expect(err).To(BeNil())
+		Expect(manager.CanManageChart(clusterSummary, chart)).To(BeTrue())
+
+		// Next in line is the ClusterSummary registered first
+		manager.RemoveAllRegistrations(clusterSummary)
+		Expect(manager.CanManageChart(firstRegistered, chart)).To(BeTrue())
+		Expect(manager.CanManageChart(lastRegistered, chart)).To(BeFalse())
+	})
+
+	It("PersistRegistrations shards registrations and reports registrations not fitting in a ConfigMap", func() {
+		chartmanager.EnablePersistence("")
+		defer chartmanager.DisablePersistence()
+
+		manager, err := chartmanager.GetChartManagerInstance(context.TODO(), c)
+		Expect(err).To(BeNil())
+
+		const clusters = 20
+		clusterSummaries := make([]*configv1beta1.ClusterSummary, clusters)
+		for i := range clusterSummaries {
+			clusterSummaries[i] = clusterSummary.DeepCopy()
+			clusterSummaries[i].Name = randomString()
+			clusterSummaries[i].ResourceVersion = ""
+			clusterSummaries[i].Spec.ClusterName = upstreamClusterNamePrefix + randomString()
+			Expect(c.Create(context.TODO(), clusterSummaries[i])).To(Succeed())
+			manager.RegisterClusterSummaryForCharts(clusterSummaries[i])
+		}
+		Expect(manager.PersistRegistrations(context.TODO(), c)).To(Succeed())
+
+		configMaps := &corev1.ConfigMapList{}
+		Expect(c.List(context.TODO(), configMaps)).To(Succeed())
+		Expect(len(configMaps.Items)).To(BeNumerically(">", 1))
+
+		// Simulate a restart. Registrations of all clusters are read back.
+		chartmanager.ResetInstance()
+		manager, err = chartmanager.GetChartManagerInstance(context.TODO(), c)
+		Expect(err).To(BeNil())
+		for i := range clusterSummaries {
+			Expect(manager.CanManageChart(clusterSummaries[i],
+				&clusterSummaries[i].Spec.ClusterProfileSpec.HelmCharts[0])).To(BeTrue())
+		}
+
+		chartmanager.SetRegistrationsMaxSize(1)
+		defer chartmanager.SetRegistrationsMaxSize(900 * 1024)
+
+		other := clusterSummary.DeepCopy()
+		other.Name = randomString()
+		other.Spec.ClusterName = upstreamClusterNamePrefix + randomString()
+		manager.RegisterClusterSummaryForCharts(other)
+		err = manager.PersistRegistrations(context.TODO(), c)
+		Expect(err).ToNot(BeNil())
+		Expect(errors.Is(err, chartmanager.ErrRegistrationsTooLarge)).To(BeTrue())
+	})
 })
 
 func removeSubscriptions(c client.Client, clusterSummary *configv1beta1.ClusterSummary) {
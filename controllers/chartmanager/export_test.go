@@ -20,3 +20,15 @@ var (
 	IsClusterSummaryAlreadyRegistered = isClusterSummaryAlreadyRegistered
 	RebuildRegistrations              = (*instance).rebuildRegistrations
 )
+
+// ResetInstance forces next GetChartManagerInstance call to rebuild registrations
+func ResetInstance() {
+	lock.Lock()
+	defer lock.Unlock()
+	managerInstance = nil
+}
+
+// SetRegistrationsMaxSize sets the maximum size of each ConfigMap registrations are persisted to
+func SetRegistrationsMaxSize(size int) {
+	registrationsMaxSize = size
+}
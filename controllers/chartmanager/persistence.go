@@ -0,0 +1,318 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// ClusterSummary Status only reports which ClusterSummary is managing an helm release. The order
+// in which other ClusterSummaries are queued for the same release is only kept in memory. When
+// persistence is enabled, registrations are stored in ConfigMaps. On restart, registrations are
+// rebuilt from ClusterSummary Status first (current managers), then from the ConfigMaps (queue
+// order). This way the next manager of an helm release does not depend on list order.
+// A single ConfigMap would not fit the registrations of large fleets (ConfigMaps are limited to 1MiB).
+// So registrations are sharded per cluster into registrationsBuckets ConfigMaps (see
+// getRegistrationsBucket). Only buckets whose registrations changed are written.
+
+const (
+	registrationsNamespace     = "projectsveltos"
+	registrationsConfigMapName = "addon-controller-chart-registrations"
+	registrationsKey           = "registrations"
+
+	// registrationsLabel is set on all ConfigMaps registrations are persisted to.
+	// Value is the persistenceConfigMapName.
+	registrationsLabel = "projectsveltos.io/chart-registrations"
+
+	// registrationsBuckets is the number of ConfigMaps registrations are sharded into
+	registrationsBuckets = 32
+
+	// emptyRegistrations is the content of a bucket with no registrations
+	emptyRegistrations = "{}"
+)
+
+var (
+	// persistenceConfigMapName is the prefix of the ConfigMaps registrations are persisted to.
+	// Empty when persistence is disabled.
+	persistenceConfigMapName string
+
+	// registrationsMaxSize keeps each ConfigMap below the 1MiB etcd object limit
+	registrationsMaxSize = 900 * 1024
+
+	// ErrRegistrationsTooLarge is returned when the registrations of a bucket do not fit in a ConfigMap.
+	// Those registrations are then not persisted.
+	ErrRegistrationsTooLarge = errors.New("helm chart registrations do not fit in a ConfigMap")
+)
+
+// EnablePersistence enables persisting helm chart registrations. Registrations of each shard
+// are stored in different ConfigMaps. Must be called before GetChartManagerInstance.
+func EnablePersistence(shardKey string) {
+	persistenceConfigMapName = getRegistrationsConfigMapName(shardKey)
+}
+
+// DisablePersistence disables persisting helm chart registrations
+func DisablePersistence() {
+	persistenceConfigMapName = ""
+}
+
+func getRegistrationsConfigMapName(shardKey string) string {
+	if shardKey == "" {
+		return registrationsConfigMapName
+	}
+
+	// ShardKey might contain characters not valid in a ConfigMap name
+	const hashLength = 8
+	hash := sha256.Sum256([]byte(shardKey))
+	return fmt.Sprintf("%s-%x", registrationsConfigMapName, hash[:hashLength])
+}
+
+// getRegistrationsBucket returns the bucket registrations for the cluster are persisted in
+func getRegistrationsBucket(clusterKey string) int {
+	h := fnv.New32a()
+	h.Write([]byte(clusterKey))
+	return int(h.Sum32() % registrationsBuckets)
+}
+
+// getRegistrationsBucketName returns the name of the ConfigMap registrations of bucket are persisted to
+func getRegistrationsBucketName(bucket int) string {
+	return fmt.Sprintf("%s-%d", persistenceConfigMapName, bucket)
+}
+
+// PersistRegistrations stores current helm chart registrations, if those changed since last time
+// they were persisted. No-op if persistence is not enabled.
+// Buckets whose registrations do not fit in a ConfigMap are not persisted and ErrRegistrationsTooLarge
+// is returned. Other buckets are persisted anyway.
+func (m *instance) PersistRegistrations(ctx context.Context, c client.Client) error {
+	if persistenceConfigMapName == "" {
+		return nil
+	}
+
+	// Serialize writers so an older snapshot never overwrites a newer one
+	m.persistMux.Lock()
+	defer m.persistMux.Unlock()
+
+	data, err := m.getRegistrationsPerBucket()
+	if err != nil {
+		return err
+	}
+
+	if m.persisted == nil {
+		m.persisted = make(map[int][]byte)
+	}
+
+	var errs []error
+	for bucket := range data {
+		if bytes.Equal(data[bucket], m.persisted[bucket]) {
+			continue
+		}
+
+		if err := storeRegistrations(ctx, c, bucket, data[bucket]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		m.persisted[bucket] = data[bucket]
+	}
+
+	return errors.Join(errs...)
+}
+
+// getRegistrationsPerBucket returns, per bucket, the registrations in JSON
+func (m *instance) getRegistrationsPerBucket() ([][]byte, error) {
+	m.chartMux.Lock()
+	defer m.chartMux.Unlock()
+
+	buckets := make([]map[string]map[string][]string, registrationsBuckets)
+	for i := range buckets {
+		buckets[i] = make(map[string]map[string][]string)
+	}
+	for clusterKey, releases := range m.perClusterChartMap {
+		buckets[getRegistrationsBucket(clusterKey)][clusterKey] = releases
+	}
+
+	data := make([][]byte, registrationsBuckets)
+	for i := range buckets {
+		var err error
+		data[i], err = json.Marshal(buckets[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func storeRegistrations(ctx context.Context, c client.Client, bucket int, data []byte) error {
+	name := getRegistrationsBucketName(bucket)
+	if len(data) > registrationsMaxSize {
+		return fmt.Errorf("%w: ConfigMap %s/%s would contain %d bytes (max %d)", ErrRegistrationsTooLarge,
+			registrationsNamespace, name, len(data), registrationsMaxSize)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: registrationsNamespace, Name: name}, configMap)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if string(data) == emptyRegistrations {
+			return nil
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: registrationsNamespace,
+				Name:      name,
+				Labels:    map[string]string{registrationsLabel: persistenceConfigMapName},
+			},
+			Data: map[string]string{registrationsKey: string(data)},
+		}
+		return getStoreRegistrationsError(c.Create(ctx, configMap), name)
+	}
+
+	if configMap.Data[registrationsKey] == string(data) {
+		return nil
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[registrationsKey] = string(data)
+	return getStoreRegistrationsError(c.Update(ctx, configMap), name)
+}
+
+// getStoreRegistrationsError returns ErrRegistrationsTooLarge if err reports ConfigMap is too large
+func getStoreRegistrationsError(err error, name string) error {
+	if apierrors.IsRequestEntityTooLargeError(err) {
+		return fmt.Errorf("%w: ConfigMap %s/%s: %v", ErrRegistrationsTooLarge, registrationsNamespace, name, err)
+	}
+	return err
+}
+
+// loadRegistrations returns the persisted helm chart registrations. Nil if none is persisted or
+// persistence is not enabled.
+func loadRegistrations(ctx context.Context, c client.Client) (map[string]map[string][]string, error) {
+	if persistenceConfigMapName == "" {
+		return nil, nil
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	err := c.List(ctx, configMaps, client.InNamespace(registrationsNamespace),
+		client.MatchingLabels{registrationsLabel: persistenceConfigMapName})
+	if err != nil {
+		return nil, err
+	}
+
+	var registrations map[string]map[string][]string
+	for i := range configMaps.Items {
+		data, ok := configMaps.Items[i].Data[registrationsKey]
+		if !ok {
+			continue
+		}
+
+		bucket := make(map[string]map[string][]string)
+		if err := json.Unmarshal([]byte(data), &bucket); err != nil {
+			return nil, fmt.Errorf("failed to parse persisted helm chart registrations in ConfigMap %s: %w",
+				configMaps.Items[i].Name, err)
+		}
+
+		if registrations == nil {
+			registrations = make(map[string]map[string][]string)
+		}
+		for clusterKey, releases := range bucket {
+			registrations[clusterKey] = releases
+		}
+	}
+	return registrations, nil
+}
+
+// addPersistedRegistrations registers, in the persisted order, ClusterSummaries which still exist
+// and still reference the helm release.
+func (m *instance) addPersistedRegistrations(registrations map[string]map[string][]string,
+	clusterSummaries []configv1beta1.ClusterSummary) {
+
+	valid := make(map[string]bool)
+	for i := range clusterSummaries {
+		for _, key := range m.getRegistrationKeys(&clusterSummaries[i]) {
+			valid[key] = true
+		}
+	}
+
+	// Maps are walked in random order. Sort keys so result is deterministic.
+	clusterKeys := make([]string, 0, len(registrations))
+	for clusterKey := range registrations {
+		clusterKeys = append(clusterKeys, clusterKey)
+	}
+	sort.Strings(clusterKeys)
+
+	for _, clusterKey := range clusterKeys {
+		releaseKeys := make([]string, 0, len(registrations[clusterKey]))
+		for releaseKey := range registrations[clusterKey] {
+			releaseKeys = append(releaseKeys, releaseKey)
+		}
+		sort.Strings(releaseKeys)
+
+		for _, releaseKey := range releaseKeys {
+			for _, clusterSummaryKey := range registrations[clusterKey][releaseKey] {
+				if !valid[getRegistrationKey(clusterKey, releaseKey, clusterSummaryKey)] {
+					continue
+				}
+				m.addClusterEntry(clusterKey)
+				m.addReleaseEntry(clusterKey, releaseKey)
+				m.addClusterSummaryEntry(clusterKey, releaseKey, clusterSummaryKey)
+			}
+		}
+	}
+}
+
+// getRegistrationKeys returns a key for each helm release clusterSummary can be registered for
+func (m *instance) getRegistrationKeys(clusterSummary *configv1beta1.ClusterSummary) []string {
+	clusterKey := m.getClusterKey(clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+		clusterSummary.Spec.ClusterType)
+	clusterSummaryKey := m.getClusterSummaryKey(clusterSummary.Name)
+
+	keys := make([]string, 0)
+	for i := range clusterSummary.Spec.ClusterProfileSpec.HelmCharts {
+		chart := &clusterSummary.Spec.ClusterProfileSpec.HelmCharts[i]
+		releaseKey := m.GetReleaseKey(chart.ReleaseNamespace, chart.ReleaseName)
+		keys = append(keys, getRegistrationKey(clusterKey, releaseKey, clusterSummaryKey))
+	}
+	for i := range clusterSummary.Status.HelmReleaseSummaries {
+		summary := &clusterSummary.Status.HelmReleaseSummaries[i]
+		releaseKey := m.GetReleaseKey(summary.ReleaseNamespace, summary.ReleaseName)
+		keys = append(keys, getRegistrationKey(clusterKey, releaseKey, clusterSummaryKey))
+	}
+
+	return keys
+}
+
+func getRegistrationKey(clusterKey, releaseKey, clusterSummaryKey string) string {
+	return clusterKey + keySeparator + releaseKey + keySeparator + clusterSummaryKey
+}
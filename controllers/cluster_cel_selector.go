@@ -0,0 +1,109 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// ClusterCELSelector lets a ClusterProfile/Profile match clusters on any field (spec, status) and
+// not only on labels. The expression is evaluated against each cluster matching ClusterSelector
+// (or against all clusters if ClusterSelector is empty). Clusters the expression cannot be evaluated
+// against (for instance because a referenced field is not set) do not match.
+
+const (
+	// clusterCELVariable is the name clusters are available as in ClusterCELSelector
+	clusterCELVariable = "cluster"
+)
+
+// getProfileMatchingClusters returns all clusters matching ClusterProfile/Profile ClusterSelector and
+// ClusterCELSelector plus the clusters listed in ClusterRefs.
+func getProfileMatchingClusters(ctx context.Context, c client.Client, namespace string,
+	profileScope *scope.ProfileScope, logger logr.Logger) ([]corev1.ObjectReference, error) {
+
+	spec := profileScope.GetSpec()
+	if spec.ClusterCELSelector == "" {
+		return getMatchingClusters(ctx, c, namespace, profileScope.GetSelector(), spec.ClusterRefs, logger)
+	}
+
+	var candidates []corev1.ObjectReference
+	var err error
+	selector := profileScope.GetSelector()
+	if len(selector.MatchLabels)+len(selector.MatchExpressions) == 0 {
+		candidates, err = clusterproxy.GetListOfClusters(ctx, c, namespace, logger)
+	} else {
+		candidates, err = clusterproxy.GetMatchingClusters(ctx, c, selector, namespace, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	matchingCluster, err := filterClustersByCELSelector(ctx, c, candidates, spec.ClusterCELSelector, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	matchingCluster = append(matchingCluster, spec.ClusterRefs...)
+
+	return matchingCluster, nil
+}
+
+// filterClustersByCELSelector returns the clusters for which expression evaluates to true
+func filterClustersByCELSelector(ctx context.Context, c client.Client, clusters []corev1.ObjectReference,
+	expression string, logger logr.Logger) ([]corev1.ObjectReference, error) {
+
+	matching := make([]corev1.ObjectReference, 0, len(clusters))
+	for i := range clusters {
+		cluster := &clusters[i]
+
+		clusterObj, err := getCluster(ctx, c, cluster.Namespace, cluster.Name, clusterproxy.GetClusterType(cluster))
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(clusterObj)
+		if err != nil {
+			return nil, err
+		}
+
+		match, err := evaluateCELExpression(expression, clusterCELVariable, content)
+		if err != nil {
+			logger.V(logs.LogDebug).Info(fmt.Sprintf("cluster %s %s/%s does not match ClusterCELSelector: %v",
+				cluster.Kind, cluster.Namespace, cluster.Name, err))
+			continue
+		}
+		if match {
+			matching = append(matching, *cluster)
+		}
+	}
+
+	return matching, nil
+}
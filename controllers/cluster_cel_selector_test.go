@@ -0,0 +1,103 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Cluster CEL selector", func() {
+	var namespace string
+
+	BeforeEach(func() {
+		namespace = randomString()
+	})
+
+	It("filterClustersByCELSelector returns SveltosClusters matching the expression", func() {
+		matching := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Status:     libsveltosv1beta1.SveltosClusterStatus{Version: "v1.27.3"},
+		}
+		nonMatching := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Status:     libsveltosv1beta1.SveltosClusterStatus{Version: "v1.29.1"},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects([]client.Object{matching, nonMatching}...).Build()
+
+		clusters := []corev1.ObjectReference{
+			{Namespace: namespace, Name: matching.Name, Kind: libsveltosv1beta1.SveltosClusterKind,
+				APIVersion: libsveltosv1beta1.GroupVersion.String()},
+			{Namespace: namespace, Name: nonMatching.Name, Kind: libsveltosv1beta1.SveltosClusterKind,
+				APIVersion: libsveltosv1beta1.GroupVersion.String()},
+			// A cluster not existing anymore never matches
+			{Namespace: namespace, Name: randomString(), Kind: libsveltosv1beta1.SveltosClusterKind,
+				APIVersion: libsveltosv1beta1.GroupVersion.String()},
+		}
+
+		result, err := controllers.FilterClustersByCELSelector(context.TODO(), c, clusters,
+			`cluster.status.version.startsWith("v1.27")`, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(result).To(ConsistOf(clusters[0]))
+	})
+
+	It("filterClustersByCELSelector evaluates CAPI Cluster spec and skips clusters missing fields", func() {
+		matching := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Spec: clusterv1.ClusterSpec{
+				Topology: &clusterv1.Topology{Class: randomString(), Version: "v1.27.1"},
+				InfrastructureRef: &corev1.ObjectReference{
+					Kind: "DockerCluster", Namespace: namespace, Name: randomString(),
+				},
+			},
+		}
+		// Cluster without topology. Expression can not be evaluated so cluster does not match
+		noTopology := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects([]client.Object{matching, noTopology}...).Build()
+
+		clusters := []corev1.ObjectReference{
+			{Namespace: namespace, Name: matching.Name, Kind: clusterKind,
+				APIVersion: clusterv1.GroupVersion.String()},
+			{Namespace: namespace, Name: noTopology.Name, Kind: clusterKind,
+				APIVersion: clusterv1.GroupVersion.String()},
+		}
+
+		result, err := controllers.FilterClustersByCELSelector(context.TODO(), c, clusters,
+			`cluster.spec.topology.version.startsWith("v1.27") && cluster.spec.infrastructureRef.kind == "DockerCluster"`,
+			logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(result).To(ConsistOf(clusters[0]))
+	})
+})
@@ -0,0 +1,215 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/addonclient"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Every time the add-ons deployed in a cluster change (ClusterConfiguration Status is updated), a
+// snapshot of the ClusterConfiguration Status is recorded in a ConfigMap owned by the
+// ClusterConfiguration (see pkg/addonclient snapshots.go for the format). Only the most recent
+// --cluster-configuration-snapshots snapshots are retained, as long as they fit in the ConfigMap.
+// addonclient.DiffClusterConfigurationSince then answers "what changed in this cluster since t".
+
+const (
+	defaultClusterConfigurationSnapshots = 20
+
+	// clusterConfigurationSnapshotsMaxSize keeps the ConfigMap below the 1MiB etcd object limit
+	clusterConfigurationSnapshotsMaxSize = 900 * 1024
+)
+
+var (
+	clusterConfigurationSnapshots = defaultClusterConfigurationSnapshots
+)
+
+// SetClusterConfigurationSnapshots sets the maximum number of ClusterConfiguration snapshots
+// retained per cluster. Zero disables snapshots. Negative values are treated as zero.
+func SetClusterConfigurationSnapshots(n int) {
+	if n < 0 {
+		n = 0
+	}
+	clusterConfigurationSnapshots = n
+}
+
+// recordClusterConfigurationSnapshotOrLog records a snapshot of clusterConfiguration. Failing to
+// record it does not fail the deployment which updated the ClusterConfiguration, so error is only logged.
+func recordClusterConfigurationSnapshotOrLog(ctx context.Context, c client.Client,
+	clusterConfiguration *configv1beta1.ClusterConfiguration) {
+
+	if err := recordClusterConfigurationSnapshot(ctx, c, clusterConfiguration, time.Now()); err != nil {
+		ctrl.LoggerFrom(ctx).V(logs.LogInfo).Info(fmt.Sprintf("failed to record ClusterConfiguration %s/%s snapshot: %v",
+			clusterConfiguration.Namespace, clusterConfiguration.Name, err))
+	}
+}
+
+// recordClusterConfigurationSnapshot records a snapshot of clusterConfiguration Status taken at now,
+// unless identical to the latest one, and removes snapshots exceeding retention.
+func recordClusterConfigurationSnapshot(ctx context.Context, c client.Client,
+	clusterConfiguration *configv1beta1.ClusterConfiguration, now time.Time) error {
+
+	if clusterConfigurationSnapshots == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(clusterConfiguration.Status)
+	if err != nil {
+		return err
+	}
+
+	name := addonclient.GetClusterConfigurationSnapshotsName(clusterConfiguration.Name)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap := &corev1.ConfigMap{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: clusterConfiguration.Namespace, Name: name}, configMap)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: clusterConfiguration.Namespace,
+					Name:      name,
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: configv1beta1.GroupVersion.String(),
+							Kind:       configv1beta1.ClusterConfigurationKind,
+							Name:       clusterConfiguration.Name,
+							UID:        clusterConfiguration.UID,
+						},
+					},
+				},
+				Data: map[string]string{addonclient.GetClusterConfigurationSnapshotKey(now): string(data)},
+			}
+			return c.Create(ctx, configMap)
+		}
+
+		if !addClusterConfigurationSnapshot(configMap, now, string(data)) {
+			return nil
+		}
+		return c.Update(ctx, configMap)
+	})
+}
+
+// addClusterConfigurationSnapshot adds to configMap the snapshot taken at now, unless identical to
+// the latest one, and removes the oldest ones exceeding retention. Returns true if configMap was modified.
+func addClusterConfigurationSnapshot(configMap *corev1.ConfigMap, now time.Time, snapshot string) bool {
+	keys := getClusterConfigurationSnapshotKeys(configMap)
+	if len(keys) > 0 && !isClusterConfigurationSnapshotChanged(configMap.Data[keys[len(keys)-1]], snapshot) {
+		return false
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	key := addonclient.GetClusterConfigurationSnapshotKey(now)
+	configMap.Data[key] = snapshot
+	keys = getClusterConfigurationSnapshotKeys(configMap)
+
+	size := 0
+	for k, v := range configMap.Data {
+		size += len(k) + len(v)
+	}
+
+	// Oldest first. Latest snapshot is always kept.
+	for i := 0; i < len(keys)-1; i++ {
+		if len(configMap.Data) <= clusterConfigurationSnapshots && size <= clusterConfigurationSnapshotsMaxSize {
+			break
+		}
+		size -= len(keys[i]) + len(configMap.Data[keys[i]])
+		delete(configMap.Data, keys[i])
+	}
+
+	return true
+}
+
+// isClusterConfigurationSnapshotChanged returns true if snapshot differs from latest one. Timestamps
+// are ignored: those are updated every time add-ons are reapplied, even if nothing changed.
+func isClusterConfigurationSnapshotChanged(latest, snapshot string) bool {
+	if latest == snapshot {
+		return false
+	}
+
+	latestStatus := &configv1beta1.ClusterConfigurationStatus{}
+	if err := json.Unmarshal([]byte(latest), latestStatus); err != nil {
+		return true
+	}
+	status := &configv1beta1.ClusterConfigurationStatus{}
+	if err := json.Unmarshal([]byte(snapshot), status); err != nil {
+		return true
+	}
+
+	removeClusterConfigurationTimestamps(latestStatus)
+	removeClusterConfigurationTimestamps(status)
+	return !reflect.DeepEqual(latestStatus, status)
+}
+
+// removeClusterConfigurationTimestamps clears all timestamps in status
+func removeClusterConfigurationTimestamps(status *configv1beta1.ClusterConfigurationStatus) {
+	removeFeaturesTimestamps := func(features []configv1beta1.Feature) {
+		for i := range features {
+			for j := range features[i].Resources {
+				features[i].Resources[j].LastAppliedTime = nil
+			}
+			for j := range features[i].Charts {
+				features[i].Charts[j].LastAppliedTime = nil
+			}
+		}
+	}
+
+	for i := range status.ClusterProfileResources {
+		removeFeaturesTimestamps(status.ClusterProfileResources[i].Features)
+	}
+	for i := range status.ProfileResources {
+		removeFeaturesTimestamps(status.ProfileResources[i].Features)
+	}
+	for i := range status.Conditions {
+		status.Conditions[i].LastTransitionTime = metav1.Time{}
+	}
+}
+
+// getClusterConfigurationSnapshotKeys returns the keys of the snapshots in configMap, oldest first
+func getClusterConfigurationSnapshotKeys(configMap *corev1.ConfigMap) []string {
+	keys := make([]string, 0, len(configMap.Data))
+	for k := range configMap.Data {
+		keys = append(keys, k)
+	}
+	// Keys are times in nanoseconds. Shorter means older.
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/addonclient"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("ClusterConfiguration snapshots", func() {
+	AfterEach(func() {
+		controllers.SetClusterConfigurationSnapshots(20)
+	})
+
+	It("recordClusterConfigurationSnapshot records changes and retains only most recent snapshots", func() {
+		clusterName := randomString()
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      addonclient.GetClusterConfigurationName(clusterName, libsveltosv1beta1.ClusterTypeCapi),
+				UID:       types.UID(randomString()),
+			},
+		}
+		setChartVersion := func(version string) {
+			clusterConfiguration.Status.ClusterProfileResources = []configv1beta1.ClusterProfileResource{
+				{
+					ClusterProfileName: randomString(),
+					Features: []configv1beta1.Feature{
+						{
+							FeatureID: configv1beta1.FeatureHelm,
+							Charts:    []configv1beta1.Chart{{ReleaseName: "nginx", Namespace: "nginx", ChartVersion: version}},
+						},
+					},
+				},
+			}
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		controllers.SetClusterConfigurationSnapshots(2)
+
+		listSnapshots := func() []addonclient.ClusterConfigurationSnapshot {
+			snapshots, err := addonclient.ListClusterConfigurationSnapshots(context.TODO(), c,
+				clusterConfiguration.Namespace, clusterName, libsveltosv1beta1.ClusterTypeCapi)
+			Expect(err).To(BeNil())
+			return snapshots
+		}
+
+		now := time.Now()
+		setChartVersion("1.0.0")
+		Expect(controllers.RecordClusterConfigurationSnapshot(context.TODO(), c, clusterConfiguration, now)).To(Succeed())
+		snapshots := listSnapshots()
+		Expect(snapshots).To(HaveLen(1))
+
+		// Snapshots are owned by the ClusterConfiguration
+		configMap := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: clusterConfiguration.Namespace,
+			Name: addonclient.GetClusterConfigurationSnapshotsName(clusterConfiguration.Name)}, configMap)).To(Succeed())
+		Expect(configMap.OwnerReferences).To(HaveLen(1))
+		Expect(configMap.OwnerReferences[0].UID).To(Equal(clusterConfiguration.UID))
+
+		// Nothing changed, no new snapshot
+		Expect(controllers.RecordClusterConfigurationSnapshot(context.TODO(), c, clusterConfiguration,
+			now.Add(time.Minute))).To(Succeed())
+		Expect(listSnapshots()).To(HaveLen(1))
+
+		setChartVersion("1.1.0")
+		Expect(controllers.RecordClusterConfigurationSnapshot(context.TODO(), c, clusterConfiguration,
+			now.Add(2*time.Minute))).To(Succeed())
+		setChartVersion("1.2.0")
+		Expect(controllers.RecordClusterConfigurationSnapshot(context.TODO(), c, clusterConfiguration,
+			now.Add(3*time.Minute))).To(Succeed())
+
+		// Oldest snapshot is removed
+		snapshots = listSnapshots()
+		Expect(snapshots).To(HaveLen(2))
+		diff := addonclient.DiffClusterConfigurations(snapshots[0].ClusterConfiguration, snapshots[1].ClusterConfiguration)
+		Expect(diff.ChartsAdded).To(HaveLen(1))
+		Expect(diff.ChartsAdded[0].ToVersion).To(Equal("1.2.0"))
+		Expect(diff.ChartsRemoved).To(HaveLen(1))
+		Expect(diff.ChartsRemoved[0].FromVersion).To(Equal("1.1.0"))
+
+		// Snapshots disabled
+		controllers.SetClusterConfigurationSnapshots(0)
+		setChartVersion("1.3.0")
+		Expect(controllers.RecordClusterConfigurationSnapshot(context.TODO(), c, clusterConfiguration,
+			now.Add(4*time.Minute))).To(Succeed())
+		Expect(listSnapshots()).To(HaveLen(2))
+	})
+
+	It("recordClusterConfigurationSnapshot ignores timestamps", func() {
+		clusterName := randomString()
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      addonclient.GetClusterConfigurationName(clusterName, libsveltosv1beta1.ClusterTypeCapi),
+				UID:       types.UID(randomString()),
+			},
+		}
+		now := time.Now()
+		setLastAppliedTime := func(t time.Time) {
+			lastAppliedTime := metav1.NewTime(t)
+			clusterConfiguration.Status.ClusterProfileResources = []configv1beta1.ClusterProfileResource{
+				{
+					ClusterProfileName: "cp",
+					Features: []configv1beta1.Feature{
+						{
+							FeatureID: configv1beta1.FeatureHelm,
+							Charts: []configv1beta1.Chart{
+								{ReleaseName: "nginx", Namespace: "nginx", ChartVersion: "1.0.0", LastAppliedTime: &lastAppliedTime},
+							},
+						},
+						{
+							FeatureID: configv1beta1.FeatureResources,
+							Resources: []configv1beta1.Resource{
+								{Name: "nginx", Namespace: "nginx", Kind: "Service", Version: "v1", LastAppliedTime: &lastAppliedTime},
+							},
+						},
+					},
+				},
+			}
+			clusterConfiguration.Status.Conditions = []metav1.Condition{
+				{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Ready", LastTransitionTime: lastAppliedTime},
+			}
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		setLastAppliedTime(now)
+		Expect(controllers.RecordClusterConfigurationSnapshot(context.TODO(), c, clusterConfiguration, now)).To(Succeed())
+
+		// Same add-ons deployed again. Only timestamps changed, no new snapshot.
+		setLastAppliedTime(now.Add(time.Minute))
+		Expect(controllers.RecordClusterConfigurationSnapshot(context.TODO(), c, clusterConfiguration,
+			now.Add(time.Minute))).To(Succeed())
+
+		snapshots, err := addonclient.ListClusterConfigurationSnapshots(context.TODO(), c,
+			clusterConfiguration.Namespace, clusterName, libsveltosv1beta1.ClusterTypeCapi)
+		Expect(err).To(BeNil())
+		Expect(snapshots).To(HaveLen(1))
+	})
+})
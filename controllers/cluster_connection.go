@@ -0,0 +1,170 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Connection health of a managed cluster is reported in ClusterSummary Status.Connection and
+// in the ConnectionDown condition, so an add-on failing to be deployed can be told apart from
+// the cluster being unreachable. Checking the connection never prevents deployments from being
+// attempted.
+// Many ClusterSummaries can be deployed to the same cluster, so the result of a connection
+// check is cached for connectionProbeTTL.
+
+const (
+	// connectionProbeTTL is how long the result of a connection check is reused
+	connectionProbeTTL = time.Minute
+
+	// connectionProbeTimeout is how long the managed cluster API server is given to answer
+	connectionProbeTimeout = 5 * time.Second
+
+	// lastSuccessfulConnectionRefresh is how often Status.Connection.LastSuccessfulConnectionTime
+	// is refreshed. Refreshing it at every reconciliation would cause a status update each time.
+	lastSuccessfulConnectionRefresh = 5 * time.Minute
+)
+
+type connectionProbe struct {
+	kubeconfigValid    bool
+	apiServerReachable bool
+	message            string
+	time               time.Time
+}
+
+var (
+	connectionProbeMux sync.Mutex
+	// connectionProbes contains, per managed cluster and admin, the last connection check
+	connectionProbes = map[string]*connectionProbe{}
+)
+
+// updateConnectionStatus checks the connection to the managed cluster clusterSummary is for
+// and reports the result in the ClusterSummary status
+func updateConnectionStatus(ctx context.Context, c client.Client, clusterSummaryScope *scope.ClusterSummaryScope,
+	logger logr.Logger) {
+
+	clusterSummary := clusterSummaryScope.ClusterSummary
+	probe := getConnectionProbe(ctx, c, clusterSummary, logger)
+
+	connection := &configv1beta1.ClusterConnectionStatus{
+		KubeconfigValid:    probe.kubeconfigValid,
+		APIServerReachable: probe.apiServerReachable,
+	}
+	if clusterSummary.Status.Connection != nil {
+		connection.LastSuccessfulConnectionTime = clusterSummary.Status.Connection.LastSuccessfulConnectionTime
+	}
+
+	reason := configv1beta1.ConnectedReason
+	switch {
+	case !probe.kubeconfigValid:
+		reason = configv1beta1.KubeconfigInvalidReason
+	case !probe.apiServerReachable:
+		reason = configv1beta1.APIServerUnreachableReason
+	default:
+		if connection.LastSuccessfulConnectionTime == nil ||
+			probe.time.Sub(connection.LastSuccessfulConnectionTime.Time) >= lastSuccessfulConnectionRefresh {
+
+			connection.LastSuccessfulConnectionTime = &metav1.Time{Time: probe.time}
+		}
+	}
+
+	if reason != configv1beta1.ConnectedReason {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("managed cluster connection is down (%s): %s",
+			reason, probe.message))
+	}
+
+	clusterSummaryScope.SetConnectionStatus(connection, reason, probe.message)
+}
+
+// getConnectionProbe returns the result of the last connection check to the managed cluster
+// clusterSummary is for, checking the connection again if the result is older than connectionProbeTTL
+func getConnectionProbe(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	logger logr.Logger) *connectionProbe {
+
+	adminNamespace, adminName := getClusterSummaryAdmin(clusterSummary)
+	key := getConnectionProbeClusterKey(clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+		clusterSummary.Spec.ClusterType) + fmt.Sprintf("%s/%s", adminNamespace, adminName)
+
+	connectionProbeMux.Lock()
+	probe, ok := connectionProbes[key]
+	connectionProbeMux.Unlock()
+	if ok && time.Since(probe.time) < connectionProbeTTL {
+		return probe
+	}
+
+	probe = probeConnection(ctx, c, clusterSummary, logger)
+
+	connectionProbeMux.Lock()
+	connectionProbes[key] = probe
+	connectionProbeMux.Unlock()
+
+	return probe
+}
+
+// getConnectionProbeClusterKey returns the prefix of the connectionProbes keys for a managed cluster
+func getConnectionProbeClusterKey(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) string {
+
+	return fmt.Sprintf("%s:%s/%s:", clusterType, clusterNamespace, clusterName)
+}
+
+// probeConnection verifies the kubeconfig to access the managed cluster is valid and the
+// managed cluster API server answers. Tenant is not impersonated: connection health does not
+// depend on it.
+func probeConnection(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	logger logr.Logger) *connectionProbe {
+
+	probe := &connectionProbe{time: time.Now()}
+
+	adminNamespace, adminName := getClusterSummaryAdmin(clusterSummary)
+	remoteRestConfig, err := getKubernetesRestConfig(ctx, c, clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	if err != nil {
+		probe.message = err.Error()
+		return probe
+	}
+	probe.kubeconfigValid = true
+
+	remoteRestConfig = rest.CopyConfig(remoteRestConfig)
+	remoteRestConfig.Timeout = connectionProbeTimeout
+	dc, err := discovery.NewDiscoveryClientForConfig(remoteRestConfig)
+	if err != nil {
+		probe.message = err.Error()
+		return probe
+	}
+	if _, err := dc.ServerVersion(); err != nil {
+		probe.message = err.Error()
+		return probe
+	}
+	probe.apiServerReachable = true
+
+	return probe
+}
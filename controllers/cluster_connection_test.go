@@ -0,0 +1,151 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Cluster connection", func() {
+	var cluster *libsveltosv1beta1.SveltosCluster
+	var clusterSummary *configv1beta1.ClusterSummary
+	var clusterSummaryScope *scope.ClusterSummaryScope
+	var c client.Client
+
+	BeforeEach(func() {
+		controllers.ResetConnectionProbes()
+
+		cluster = &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()},
+		}
+
+		clusterProfile := &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+		}
+
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{Namespace: cluster.Namespace, Name: randomString()},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: cluster.Namespace,
+				ClusterName:      cluster.Name,
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+			},
+		}
+
+		initObjects := []client.Object{cluster, clusterProfile, clusterSummary}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+
+		var err error
+		clusterSummaryScope, err = scope.NewClusterSummaryScope(&scope.ClusterSummaryScopeParams{
+			Client:         c,
+			Logger:         textlogger.NewLogger(textlogger.NewConfig()),
+			ClusterSummary: clusterSummary,
+			Profile:        clusterProfile,
+			ControllerName: "clustersummary",
+		})
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		controllers.SetRemoteRestConfigGetter(nil)
+		controllers.ResetConnectionProbes()
+	})
+
+	It("updateConnectionStatus reports KubeconfigInvalid when kubeconfig Secret does not exist", func() {
+		controllers.UpdateConnectionStatus(context.TODO(), c, clusterSummaryScope,
+			textlogger.NewLogger(textlogger.NewConfig()))
+
+		Expect(clusterSummary.Status.Connection).ToNot(BeNil())
+		Expect(clusterSummary.Status.Connection.KubeconfigValid).To(BeFalse())
+		Expect(clusterSummary.Status.Connection.APIServerReachable).To(BeFalse())
+		Expect(clusterSummary.Status.Connection.LastSuccessfulConnectionTime).To(BeNil())
+
+		condition := meta.FindStatusCondition(clusterSummary.Status.Conditions, configv1beta1.ConnectionDownCondition)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(configv1beta1.KubeconfigInvalidReason))
+	})
+
+	It("updateConnectionStatus reports whether managed cluster API server is reachable", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"major":"1","minor":"31","gitVersion":"v1.31.0"}`))
+		}))
+		defer server.Close()
+
+		serverURL := server.URL
+		controllers.SetRemoteRestConfigGetter(func(ctx context.Context, clusterNamespace, clusterName string,
+			clusterType libsveltosv1beta1.ClusterType) (*rest.Config, error) {
+
+			return &rest.Config{Host: serverURL}, nil
+		})
+
+		controllers.UpdateConnectionStatus(context.TODO(), c, clusterSummaryScope,
+			textlogger.NewLogger(textlogger.NewConfig()))
+
+		Expect(clusterSummary.Status.Connection).ToNot(BeNil())
+		Expect(clusterSummary.Status.Connection.KubeconfigValid).To(BeTrue())
+		Expect(clusterSummary.Status.Connection.APIServerReachable).To(BeTrue())
+		Expect(clusterSummary.Status.Connection.LastSuccessfulConnectionTime).ToNot(BeNil())
+		lastSuccessfulConnectionTime := *clusterSummary.Status.Connection.LastSuccessfulConnectionTime
+
+		condition := meta.FindStatusCondition(clusterSummary.Status.Conditions, configv1beta1.ConnectionDownCondition)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(configv1beta1.ConnectedReason))
+
+		// LastSuccessfulConnectionTime is not refreshed at every check
+		controllers.ResetConnectionProbes()
+		controllers.UpdateConnectionStatus(context.TODO(), c, clusterSummaryScope,
+			textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(clusterSummary.Status.Connection.LastSuccessfulConnectionTime.Time).To(
+			Equal(lastSuccessfulConnectionTime.Time))
+
+		// API server stops answering
+		server.Close()
+		controllers.ResetConnectionProbes()
+		controllers.UpdateConnectionStatus(context.TODO(), c, clusterSummaryScope,
+			textlogger.NewLogger(textlogger.NewConfig()))
+
+		Expect(clusterSummary.Status.Connection.KubeconfigValid).To(BeTrue())
+		Expect(clusterSummary.Status.Connection.APIServerReachable).To(BeFalse())
+		Expect(clusterSummary.Status.Connection.LastSuccessfulConnectionTime.Time).To(
+			Equal(lastSuccessfulConnectionTime.Time))
+
+		condition = meta.FindStatusCondition(clusterSummary.Status.Conditions, configv1beta1.ConnectionDownCondition)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(configv1beta1.APIServerUnreachableReason))
+	})
+})
@@ -0,0 +1,124 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// Clusters only reachable via a bastion can be accessed through an HTTP (CONNECT) or SOCKS5 proxy.
+// Proxy can be configured either:
+// - setting proxy-url in the cluster kubeconfig (honored by client-go);
+// - setting configv1beta1.ProxyURLAnnotation on the Cluster. This takes precedence.
+
+// getClusterProxyURL returns the proxy URL set on the cluster via ProxyURLAnnotation.
+// Nil is returned if no proxy is configured on the cluster.
+func getClusterProxyURL(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (*url.URL, error) {
+
+	if configv1beta1.IsManagementCluster(clusterName, clusterType) {
+		return nil, nil
+	}
+
+	cluster, err := getCluster(ctx, c, clusterNamespace, clusterName, clusterType)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	value, ok := cluster.GetAnnotations()[configv1beta1.ProxyURLAnnotation]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := parseProxyURL(value)
+	if err != nil {
+		return nil, &NonRetriableError{
+			Message: fmt.Sprintf("cluster %s/%s annotation %s: %v", clusterNamespace, clusterName,
+				configv1beta1.ProxyURLAnnotation, err),
+		}
+	}
+	return proxyURL, nil
+}
+
+// parseProxyURL validates value is the URL of an HTTP(S) or SOCKS5 proxy
+func parseProxyURL(value string) (*url.URL, error) {
+	proxyURL, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", value, err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy URL scheme %q. Supported schemes: http, https, socks5",
+			proxyURL.Scheme)
+	}
+
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", value)
+	}
+
+	return proxyURL, nil
+}
+
+// setClusterProxy configures restConfig to reach the cluster via the proxy set on the cluster, if any
+func setClusterProxy(ctx context.Context, c client.Client, restConfig *rest.Config,
+	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType) error {
+
+	proxyURL, err := getClusterProxyURL(ctx, c, clusterNamespace, clusterName, clusterType)
+	if err != nil || proxyURL == nil {
+		return err
+	}
+
+	restConfig.Proxy = http.ProxyURL(proxyURL)
+	return nil
+}
+
+// setKubeconfigClusterProxy sets, in all clusters of the kubeconfig, the proxy set on the cluster, if any
+func setKubeconfigClusterProxy(ctx context.Context, c client.Client, kubeconfigContent []byte,
+	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType) ([]byte, error) {
+
+	proxyURL, err := getClusterProxyURL(ctx, c, clusterNamespace, clusterName, clusterType)
+	if err != nil || proxyURL == nil {
+		return kubeconfigContent, err
+	}
+
+	kubeconfig, err := clientcmd.Load(kubeconfigContent)
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range kubeconfig.Clusters {
+		kubeconfig.Clusters[name].ProxyURL = proxyURL.String()
+	}
+
+	return clientcmd.Write(*kubeconfig)
+}
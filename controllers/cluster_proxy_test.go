@@ -0,0 +1,103 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Cluster proxy", func() {
+	It("parseProxyURL accepts HTTP and SOCKS5 proxies only", func() {
+		for _, value := range []string{"http://bastion:3128", "https://bastion", "socks5://bastion:1080"} {
+			proxyURL, err := controllers.ParseProxyURL(value)
+			Expect(err).To(BeNil())
+			Expect(proxyURL.String()).To(Equal(value))
+		}
+
+		for _, value := range []string{"ftp://bastion", "bastion:3128", "http://"} {
+			_, err := controllers.ParseProxyURL(value)
+			Expect(err).ToNot(BeNil())
+		}
+	})
+
+	It("getClusterProxyURL returns proxy set on the cluster", func() {
+		cluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster).Build()
+		proxyURL, err := controllers.GetClusterProxyURL(context.TODO(), c, cluster.Namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(proxyURL).To(BeNil())
+
+		cluster.Annotations = map[string]string{configv1beta1.ProxyURLAnnotation: "socks5://bastion:1080"}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster).Build()
+		proxyURL, err = controllers.GetClusterProxyURL(context.TODO(), c, cluster.Namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(proxyURL.String()).To(Equal("socks5://bastion:1080"))
+
+		cluster.Annotations = map[string]string{configv1beta1.ProxyURLAnnotation: "ftp://bastion"}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster).Build()
+		_, err = controllers.GetClusterProxyURL(context.TODO(), c, cluster.Namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).ToNot(BeNil())
+		var nonRetriableError *controllers.NonRetriableError
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+	})
+
+	It("setKubeconfigClusterProxy sets proxy-url in kubeconfig", func() {
+		cluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   randomString(),
+				Name:        randomString(),
+				Annotations: map[string]string{configv1beta1.ProxyURLAnnotation: "http://bastion:3128"},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster).Build()
+
+		kubeconfig := clientcmdapi.NewConfig()
+		kubeconfig.Clusters[cluster.Name] = &clientcmdapi.Cluster{Server: "https://10.0.0.1:6443"}
+		kubeconfigContent, err := clientcmd.Write(*kubeconfig)
+		Expect(err).To(BeNil())
+
+		kubeconfigContent, err = controllers.SetKubeconfigClusterProxy(context.TODO(), c, kubeconfigContent,
+			cluster.Namespace, cluster.Name, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+
+		kubeconfig, err = clientcmd.Load(kubeconfigContent)
+		Expect(err).To(BeNil())
+		Expect(kubeconfig.Clusters[cluster.Name].ProxyURL).To(Equal("http://bastion:3128"))
+	})
+})
@@ -0,0 +1,126 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// areClusterReadinessChecksSatisfied returns true if the cluster matched by ClusterSummary satisfies
+// all ClusterReadinessChecks listed in the ClusterProfile/Profile spec.
+// If checks are not satisfied, the first unsatisfied check is returned in the message.
+func areClusterReadinessChecksSatisfied(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, logger logr.Logger) (bool, string, error) {
+
+	checks := clusterSummary.Spec.ClusterProfileSpec.ClusterReadinessChecks
+	if len(checks) == 0 {
+		return true, "", nil
+	}
+
+	cluster, err := getCluster(ctx, c, clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType)
+	if err != nil {
+		return false, "", err
+	}
+
+	for i := range checks {
+		satisfied, err := isClusterReadinessCheckSatisfied(ctx, c, cluster, &checks[i])
+		if err != nil {
+			return false, "", err
+		}
+		if !satisfied {
+			msg := fmt.Sprintf("cluster readiness check %s not satisfied", checks[i].Type)
+			logger.V(logs.LogDebug).Info(msg)
+			return false, msg, nil
+		}
+	}
+
+	return true, "", nil
+}
+
+func isClusterReadinessCheckSatisfied(ctx context.Context, c client.Client, cluster client.Object,
+	check *configv1beta1.ClusterReadinessCheck) (bool, error) {
+
+	if check.Type == configv1beta1.ClusterReadinessLabelPresent {
+		if check.LabelKey == "" {
+			return false, nil
+		}
+		v, ok := cluster.GetLabels()[check.LabelKey]
+		if !ok {
+			return false, nil
+		}
+		return check.LabelValue == "" || v == check.LabelValue, nil
+	}
+
+	if sveltosCluster, ok := cluster.(*libsveltosv1beta1.SveltosCluster); ok {
+		// ControlPlaneInitialized and ControlPlaneMachinesRunning are ClusterAPI specific
+		if check.Type == configv1beta1.ClusterReadinessClusterReady {
+			return sveltosCluster.Status.Ready, nil
+		}
+		return true, nil
+	}
+
+	capiCluster := cluster.(*clusterv1.Cluster)
+	switch check.Type {
+	case configv1beta1.ClusterReadinessControlPlaneInitialized:
+		return conditions.IsTrue(capiCluster, clusterv1.ControlPlaneInitializedCondition), nil
+	case configv1beta1.ClusterReadinessClusterReady:
+		return conditions.IsTrue(capiCluster, clusterv1.ReadyCondition), nil
+	case configv1beta1.ClusterReadinessControlPlaneMachinesRunning:
+		return areControlPlaneMachinesRunning(ctx, c, capiCluster)
+	}
+
+	return false, fmt.Errorf("unknown cluster readiness check %s", check.Type)
+}
+
+// areControlPlaneMachinesRunning returns true if the cluster has at least one control plane
+// Machine and all control plane Machines are in the Running phase.
+func areControlPlaneMachinesRunning(ctx context.Context, c client.Client, cluster *clusterv1.Cluster,
+) (bool, error) {
+
+	machineList := &clusterv1.MachineList{}
+	listOptions := []client.ListOption{
+		client.InNamespace(cluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: cluster.Name},
+		client.HasLabels{clusterv1.MachineControlPlaneLabel},
+	}
+	if err := c.List(ctx, machineList, listOptions...); err != nil {
+		return false, err
+	}
+
+	if len(machineList.Items) == 0 {
+		return false, nil
+	}
+
+	for i := range machineList.Items {
+		if machineList.Items[i].Status.GetTypedPhase() != clusterv1.MachinePhaseRunning {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Cluster readiness checks", func() {
+	var cluster *clusterv1.Cluster
+	var clusterSummary *configv1beta1.ClusterSummary
+
+	BeforeEach(func() {
+		cluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+			},
+		}
+
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: cluster.Namespace,
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: cluster.Namespace,
+				ClusterName:      cluster.Name,
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+			},
+		}
+	})
+
+	It("areClusterReadinessChecksSatisfied verifies label and Ready condition", func() {
+		clusterSummary.Spec.ClusterProfileSpec.ClusterReadinessChecks = []configv1beta1.ClusterReadinessCheck{
+			{Type: configv1beta1.ClusterReadinessLabelPresent, LabelKey: "env", LabelValue: "prod"},
+			{Type: configv1beta1.ClusterReadinessClusterReady},
+		}
+
+		initObjects := []client.Object{cluster}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).
+			WithObjects(initObjects...).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		satisfied, _, err := controllers.AreClusterReadinessChecksSatisfied(context.TODO(), c, clusterSummary, logger)
+		Expect(err).To(BeNil())
+		Expect(satisfied).To(BeFalse())
+
+		cluster.Labels = map[string]string{"env": "prod"}
+		Expect(c.Update(context.TODO(), cluster)).To(Succeed())
+
+		var msg string
+		satisfied, msg, err = controllers.AreClusterReadinessChecksSatisfied(context.TODO(), c, clusterSummary, logger)
+		Expect(err).To(BeNil())
+		Expect(satisfied).To(BeFalse())
+		Expect(msg).To(ContainSubstring(string(configv1beta1.ClusterReadinessClusterReady)))
+
+		cluster.Status.Conditions = clusterv1.Conditions{
+			{Type: clusterv1.ReadyCondition, Status: corev1.ConditionTrue},
+		}
+		Expect(c.Status().Update(context.TODO(), cluster)).To(Succeed())
+
+		satisfied, _, err = controllers.AreClusterReadinessChecksSatisfied(context.TODO(), c, clusterSummary, logger)
+		Expect(err).To(BeNil())
+		Expect(satisfied).To(BeTrue())
+	})
+
+	It("areClusterReadinessChecksSatisfied requires all control plane machines to be running", func() {
+		clusterSummary.Spec.ClusterProfileSpec.ClusterReadinessChecks = []configv1beta1.ClusterReadinessCheck{
+			{Type: configv1beta1.ClusterReadinessControlPlaneMachinesRunning},
+		}
+
+		machine := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: cluster.Namespace,
+				Labels: map[string]string{
+					clusterv1.ClusterNameLabel:         cluster.Name,
+					clusterv1.MachineControlPlaneLabel: "ok",
+				},
+			},
+			Status: clusterv1.MachineStatus{
+				Phase: string(clusterv1.MachinePhaseProvisioning),
+			},
+		}
+
+		initObjects := []client.Object{cluster, machine}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(machine).
+			WithObjects(initObjects...).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		satisfied, _, err := controllers.AreClusterReadinessChecksSatisfied(context.TODO(), c, clusterSummary, logger)
+		Expect(err).To(BeNil())
+		Expect(satisfied).To(BeFalse())
+
+		machine.Status.Phase = string(clusterv1.MachinePhaseRunning)
+		Expect(c.Status().Update(context.TODO(), machine)).To(Succeed())
+
+		satisfied, _, err = controllers.AreClusterReadinessChecksSatisfied(context.TODO(), c, clusterSummary, logger)
+		Expect(err).To(BeNil())
+		Expect(satisfied).To(BeTrue())
+	})
+})
@@ -27,6 +27,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -50,6 +51,8 @@ type ClusterProfileReconciler struct {
 	Scheme               *runtime.Scheme
 	ConcurrentReconciles int
 	Logger               logr.Logger
+	RequeuePolicy        RequeuePolicy
+	EventRecorder        record.EventRecorder
 
 	// use a Mutex to update Map as MaxConcurrentReconciles is higher than one
 	Mux sync.Mutex
@@ -77,9 +80,11 @@ type ClusterProfileReconciler struct {
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterprofiles,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterprofiles/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterprofiles/finalizers,verbs=update;patch
-//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clustersummaries,verbs=get;list;update;create;delete
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clustersummaries,verbs=get;list;watch;update;create;delete
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterreports,verbs=get;list;update;create;watch;delete
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterconfigurations,verbs=get;list;update;create;watch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;watch;list
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters/status,verbs=get;watch;list
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=get;watch;list
@@ -90,6 +95,10 @@ type ClusterProfileReconciler struct {
 func (r *ClusterProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	logger := ctrl.LoggerFrom(ctx)
 	logger.V(logs.LogInfo).Info("Reconciling")
+
+	ctx, apiWrites := withAPIWritesCounter(ctx)
+	defer observeReconcileAPIWrites("clusterprofile", apiWrites)
+
 	// Fecth the ClusterProfile instance
 	clusterProfile := &configv1beta1.ClusterProfile{}
 	if err := r.Get(ctx, req.NamespacedName, clusterProfile); err != nil {
@@ -138,7 +147,7 @@ func (r *ClusterProfileReconciler) reconcileDelete(
 
 	if err := reconcileDeleteCommon(ctx, r.Client, profileScope,
 		configv1beta1.ClusterProfileFinalizer, logger); err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}
 	}
 
 	r.cleanMaps(profileScope)
@@ -156,21 +165,20 @@ func (r *ClusterProfileReconciler) reconcileNormal(
 
 	if !controllerutil.ContainsFinalizer(profileScope.Profile, configv1beta1.ClusterProfileFinalizer) {
 		if err := addFinalizer(ctx, profileScope, configv1beta1.ClusterProfileFinalizer); err != nil {
-			return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+			return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 		}
 	}
 
-	// Get all clusters matching clusterSelector and ClusterRefs
-	matchingCluster, err := getMatchingClusters(ctx, r.Client, "", profileScope.GetSelector(),
-		profileScope.GetSpec().ClusterRefs, logger)
+	// Get all clusters matching clusterSelector, ClusterCELSelector and ClusterRefs
+	matchingCluster, err := getProfileMatchingClusters(ctx, r.Client, "", profileScope, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 	}
 
 	// Get all clusters from referenced ClusterSets
 	clusterSetClusters, err := r.getClustersFromClusterSets(ctx, profileScope.GetSpec().SetRefs, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 	}
 	matchingCluster = append(matchingCluster, clusterSetClusters...)
 
@@ -178,8 +186,14 @@ func (r *ClusterProfileReconciler) reconcileNormal(
 
 	r.updateMaps(profileScope)
 
-	if err := reconcileNormalCommon(ctx, r.Client, profileScope, logger); err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+	if err := reconcileNormalCommon(ctx, r.Client, r.EventRecorder, profileScope, logger); err != nil {
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
+	}
+
+	if hasChartVersionConstraints(profileScope.GetSpec()) {
+		// Periodically look for newly published chart versions matching the constraints
+		logger.V(logs.LogInfo).Info("Reconcile success")
+		return reconcile.Result{RequeueAfter: getChartIndexRefreshInterval()}
 	}
 
 	logger.V(logs.LogInfo).Info("Reconcile success")
@@ -188,10 +202,13 @@ func (r *ClusterProfileReconciler) reconcileNormal(
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Client = newAPIWritesCountingClient(r.Client)
+
 	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&configv1beta1.ClusterProfile{}).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: r.ConcurrentReconciles,
+			RateLimiter:             r.RequeuePolicy.rateLimiter(),
 		}).
 		Watches(&libsveltosv1beta1.ClusterSet{},
 			handler.EnqueueRequestsFromMapFunc(r.requeueClusterProfileForClusterSet),
@@ -205,6 +222,12 @@ func (r *ClusterProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				SveltosClusterPredicates(mgr.GetLogger().WithValues("predicate", "sveltosclusterpredicate")),
 			),
 		).
+		Watches(&configv1beta1.ClusterSummary{},
+			handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(), &configv1beta1.ClusterProfile{}),
+			builder.WithPredicates(
+				ClusterSummaryPredicates(mgr.GetLogger().WithValues("predicate", "clustersummarypredicate")),
+			),
+		).
 		Build(r)
 	if err != nil {
 		return errors.Wrap(err, "error creating controller")
@@ -25,6 +25,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 )
@@ -91,6 +92,15 @@ func (p ClusterPredicate) Update(obj event.TypedUpdateEvent[*clusterv1.Cluster])
 		return true
 	}
 
+	// a topology or infrastructure change migth change which clusters match a ClusterCELSelector
+	if !reflect.DeepEqual(oldCluster.Spec.Topology, newCluster.Spec.Topology) ||
+		!reflect.DeepEqual(oldCluster.Spec.InfrastructureRef, newCluster.Spec.InfrastructureRef) {
+
+		log.V(logs.LogVerbose).Info(
+			"Cluster topology changed. Will attempt to reconcile associated (Cluster)Profiles/(Cluster)Set.")
+		return true
+	}
+
 	// return true if Cluster.Status.ControlPlaneReady has changed
 	if oldCluster.Status.ControlPlaneReady != newCluster.Status.ControlPlaneReady {
 		log.V(logs.LogVerbose).Info(
@@ -180,6 +190,14 @@ func SveltosClusterPredicates(logger logr.Logger) predicate.Funcs {
 				return true
 			}
 
+			// a version change migth change which clusters match a ClusterCELSelector
+			if oldCluster.Status.Version != newCluster.Status.Version {
+				log.V(logs.LogVerbose).Info(
+					"Cluster version changed. Will attempt to reconcile associated (Cluster)Profiles/(Cluster)Set.",
+				)
+				return true
+			}
+
 			// otherwise, return false
 			log.V(logs.LogVerbose).Info(
 				`Cluster did not match expected conditions.  \
@@ -365,3 +383,47 @@ func SetPredicates(logger logr.Logger) predicate.Funcs {
 		},
 	}
 }
+
+// ClusterSummaryPredicates predicates for ClusterSummary. ClusterProfile/Profile owning the ClusterSummary
+// needs to be reconciled only when ClusterSummary deployment state changes
+func ClusterSummaryPredicates(logger logr.Logger) predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			newClusterSummary := e.ObjectNew.(*configv1beta1.ClusterSummary)
+			oldClusterSummary, ok := e.ObjectOld.(*configv1beta1.ClusterSummary)
+
+			log := logger.WithValues("predicate", "updateEvent",
+				"namespace", newClusterSummary.Namespace,
+				"clustersummary", newClusterSummary.Name,
+			)
+
+			if !ok || oldClusterSummary == nil {
+				log.V(logs.LogVerbose).Info("Old ClusterSummary is nil. Reconcile ClusterProfiles/Profiles")
+				return true
+			}
+
+			// if deployment state has changed, reconcile
+			if !reflect.DeepEqual(getClusterDeploymentStatus(oldClusterSummary),
+				getClusterDeploymentStatus(newClusterSummary)) {
+
+				log.V(logs.LogVerbose).Info(
+					"ClusterSummary deployment state has changed. Will attempt to reconcile associated ClusterProfiles/Profiles.")
+				return true
+			}
+
+			// otherwise, return false
+			log.V(logs.LogVerbose).Info(
+				"ClusterSummary did not match expected conditions.  Will not attempt to reconcile associated ClusterProfiles/Profiles.")
+			return false
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// In DryRun mode, each feature (Helm, Resources, Kustomize) reports on its own section of the
+// ClusterReport Status. Features are deployed concurrently by different workers, so updates to
+// the same ClusterReport used to race and, with many charts and policies, exhaust conflict retries.
+// Writes to a ClusterReport are serialized by a per-ClusterReport lock. Each writer only replaces
+// its own section and then recomputes the sections derived from all of them (Conformance).
+// Conflicts can still happen with other writers (ClusterProfile/Profile controller creating and
+// resetting reports) and are retried.
+
+var (
+	clusterReportLocksMux sync.Mutex
+	clusterReportLocks    = map[types.NamespacedName]*sync.Mutex{}
+)
+
+// getClusterReportLock returns the lock serializing updates to a ClusterReport
+func getClusterReportLock(key types.NamespacedName) *sync.Mutex {
+	clusterReportLocksMux.Lock()
+	defer clusterReportLocksMux.Unlock()
+
+	l, ok := clusterReportLocks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		clusterReportLocks[key] = l
+	}
+	return l
+}
+
+// updateClusterReportSection updates the ClusterReport for the ClusterSummary's cluster and
+// profile. setSection must only modify the section owned by the caller.
+func updateClusterReportSection(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, setSection func(status *configv1beta1.ClusterReportStatus)) error {
+
+	profileOwnerRef, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
+	if err != nil {
+		return err
+	}
+
+	key := types.NamespacedName{
+		Namespace: clusterSummary.Spec.ClusterNamespace,
+		Name: getClusterReportName(profileOwnerRef.Kind, profileOwnerRef.Name,
+			clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType),
+	}
+
+	l := getClusterReportLock(key)
+	l.Lock()
+	defer l.Unlock()
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		clusterReport := &configv1beta1.ClusterReport{}
+		if err := c.Get(ctx, key, clusterReport); err != nil {
+			return err
+		}
+
+		setSection(&clusterReport.Status)
+		updateConformance(clusterSummary, clusterReport)
+		return c.Status().Update(ctx, clusterReport)
+	})
+}
+
+// removeClusterReportLock forgets the lock for a ClusterReport once the ClusterReport is deleted
+func removeClusterReportLock(key types.NamespacedName) {
+	clusterReportLocksMux.Lock()
+	defer clusterReportLocksMux.Unlock()
+
+	delete(clusterReportLocks, key)
+}
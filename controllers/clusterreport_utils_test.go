@@ -0,0 +1,117 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("ClusterReport updates", func() {
+	It("concurrent updates from different features do not overwrite each other", func() {
+		profileName := randomString()
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       profileName,
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: randomString(),
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+				ClusterProfileSpec: configv1beta1.Spec{
+					SyncMode: configv1beta1.SyncModeDryRun,
+				},
+			},
+		}
+
+		clusterReport := &configv1beta1.ClusterReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: controllers.GetClusterReportName(configv1beta1.ClusterProfileKind, profileName,
+					clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType),
+				Namespace: clusterSummary.Spec.ClusterNamespace,
+			},
+		}
+
+		initObjects := []client.Object{clusterSummary, clusterReport}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).
+			WithObjects(initObjects...).Build()
+
+		const writers = 20
+		releaseReports := []configv1beta1.ReleaseReport{
+			{ReleaseName: randomString(), ReleaseNamespace: randomString(), Action: string(configv1beta1.InstallHelmAction)},
+		}
+		resourceReports := []configv1beta1.ResourceReport{
+			{Action: string(configv1beta1.CreateResourceAction), Resource: configv1beta1.Resource{Name: randomString()}},
+		}
+		kustomizeReports := []configv1beta1.ResourceReport{
+			{Action: string(configv1beta1.UpdateResourceAction), Resource: configv1beta1.Resource{Name: randomString()}},
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 3*writers)
+		for i := 0; i < writers; i++ {
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				errs <- controllers.UpdateClusterReportWithHelmReports(context.TODO(), c, clusterSummary, releaseReports)
+			}()
+			go func() {
+				defer wg.Done()
+				errs <- controllers.UpdateClusterReportWithResourceReports(context.TODO(), c, clusterSummary,
+					resourceReports, configv1beta1.FeatureResources)
+			}()
+			go func() {
+				defer wg.Done()
+				errs <- controllers.UpdateClusterReportWithResourceReports(context.TODO(), c, clusterSummary,
+					kustomizeReports, configv1beta1.FeatureKustomize)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			Expect(err).To(BeNil())
+		}
+
+		currentClusterReport := &configv1beta1.ClusterReport{}
+		Expect(c.Get(context.TODO(),
+			types.NamespacedName{Namespace: clusterReport.Namespace, Name: clusterReport.Name},
+			currentClusterReport)).To(Succeed())
+		Expect(currentClusterReport.Status.ReleaseReports).To(Equal(releaseReports))
+		Expect(currentClusterReport.Status.ResourceReports).To(Equal(resourceReports))
+		Expect(currentClusterReport.Status.KustomizeResourceReports).To(Equal(kustomizeReports))
+	})
+})
@@ -46,6 +46,7 @@ type ClusterSetReconciler struct {
 	Scheme               *runtime.Scheme
 	ConcurrentReconciles int
 	Logger               logr.Logger
+	RequeuePolicy        RequeuePolicy
 
 	// use a Mutex to update Map as MaxConcurrentReconciles is higher than one
 	Mux sync.Mutex
@@ -138,14 +139,14 @@ func (r *ClusterSetReconciler) reconcileNormal(
 	matchingCluster, err := getMatchingClusters(ctx, r.Client, "", setScope.GetSelector(),
 		setScope.GetSpec().ClusterRefs, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 	}
 
 	setScope.SetMatchingClusterRefs(matchingCluster)
 
 	err = selectClusters(ctx, r.Client, setScope, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 	}
 
 	r.updateMaps(setScope)
@@ -160,6 +161,7 @@ func (r *ClusterSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&libsveltosv1beta1.ClusterSet{}).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: r.ConcurrentReconciles,
+			RateLimiter:             r.RequeuePolicy.rateLimiter(),
 		}).
 		Watches(&libsveltosv1beta1.SveltosCluster{},
 			handler.EnqueueRequestsFromMapFunc(r.requeueClusterSetForSveltosCluster),
@@ -41,6 +41,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
@@ -48,7 +49,6 @@ import (
 	"github.com/projectsveltos/addon-controller/controllers/chartmanager"
 	"github.com/projectsveltos/addon-controller/pkg/scope"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
-	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	"github.com/projectsveltos/libsveltos/lib/deployer"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
@@ -57,11 +57,11 @@ import (
 )
 
 const (
-	// deleteRequeueAfter is how long to wait before checking again to see if the cluster still has
-	// children during deletion.
+	// deleteRequeueAfter is, by default, how long to wait before checking again to see if the cluster
+	// still has children during deletion.
 	deleteRequeueAfter = 10 * time.Second
 
-	// normalRequeueAfter is how long to wait before checking again to see if the cluster can be moved
+	// normalRequeueAfter is, by default, how long to wait before checking again to see if the cluster can be moved
 	// to ready after or workload features (for instance ingress or reporter) have failed
 	normalRequeueAfter = 10 * time.Second
 )
@@ -95,8 +95,21 @@ type ClusterSummaryReconciler struct {
 	ReferenceMap         map[corev1.ObjectReference]*libsveltosset.Set // key: Referenced object; value: set of all ClusterSummaries referencing the resource
 	ClusterMap           map[corev1.ObjectReference]*libsveltosset.Set // key: Sveltos/Cluster; value: set of all ClusterSummaries for that Cluster
 
+	DeploymentSlotsMux      sync.Mutex                                          // protects Deployment* maps and EnforcementSlots
+	DeploymentSlots         map[corev1.ObjectReference]*libsveltosset.Set       // key: ClusterProfile/Profile; value: set of ClusterSummaries currently deploying
+	DeploymentQueue         map[corev1.ObjectReference][]corev1.ObjectReference // key: ClusterProfile/Profile; value: ClusterSummaries waiting for a deployment slot, in arrival order
+	DeploymentSlotsAcquired map[corev1.ObjectReference]time.Time                // key: ClusterSummary holding a deployment slot; value: when it acquired it
+	DeploymentSlotsYielded  map[corev1.ObjectReference]bool                     // key: ClusterSummary which gave up its deployment slot after failing
+	EnforcementSlots        map[corev1.ObjectReference]*libsveltosset.Set       // key: ClusterProfile/Profile; value: set of ClusterSummaries currently re-applying
+
 	ConflictRetryTime time.Duration
-	ctrl              controller.Controller
+	RequeuePolicy     RequeuePolicy
+	// RetryPolicy configures backoff and circuit breaking when deploying features in managed clusters fails
+	RetryPolicy RetryPolicy
+	// DeploymentSlotTimeout is how long a ClusterSummary can hold a deployment slot
+	// (MaxConcurrentClusterDeployments) while others are waiting. Zero means default (30m).
+	DeploymentSlotTimeout time.Duration
+	ctrl                  controller.Controller
 }
 
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clustersummaries,verbs=get;list;watch;create;update;patch;delete
@@ -106,8 +119,9 @@ type ClusterSummaryReconciler struct {
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterconfigurations/status,verbs=get;list;update
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterreports,verbs=get;list;watch
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterreports/status,verbs=get;list;update
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=maintenancewindows,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
-//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
 //+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=kubeadmcontrolplanes,verbs=get;watch;list
 //+kubebuilder:rbac:groups="infrastructure.cluster.x-k8s.io",resources="*",verbs=get;watch;list
 //+kubebuilder:rbac:groups="source.toolkit.fluxcd.io",resources=gitrepositories,verbs=get;watch;list
@@ -121,6 +135,9 @@ func (r *ClusterSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	logger := ctrl.LoggerFrom(ctx)
 	logger.V(logs.LogInfo).Info("Reconciling")
 
+	ctx, apiWrites := withAPIWritesCounter(ctx)
+	defer observeReconcileAPIWrites("clustersummary", apiWrites)
+
 	// Fecth the clusterSummary instance
 	clusterSummary := &configv1beta1.ClusterSummary{}
 	if err := r.Get(ctx, req.NamespacedName, clusterSummary); err != nil {
@@ -170,7 +187,7 @@ func (r *ClusterSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	var isMatch bool
 	isMatch, err = r.isClusterAShardMatch(ctx, clusterSummary, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
 	} else if !isMatch {
 		// This addon-controller pod is not a shard match, yet we need to refresh internal state by:
 		// - removing any helm chart registration made by this ClusterSummary
@@ -181,7 +198,7 @@ func (r *ClusterSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 
 	err = r.updateClusterShardPair(ctx, clusterSummary, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
 	}
 
 	// Always close the scope when exiting this function so we can persist any ClusterSummary
@@ -190,6 +207,7 @@ func (r *ClusterSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		if err = clusterSummaryScope.Close(ctx); err != nil {
 			reterr = err
 		}
+		r.persistChartMap(ctx, logger)
 	}()
 
 	// Handle deleted clusterSummary
@@ -199,7 +217,7 @@ func (r *ClusterSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 
 	isReady, err := r.isReady(ctx, clusterSummary, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
 	}
 	if !isReady {
 		logger.V(logs.LogInfo).Info("cluster is not ready.")
@@ -212,6 +230,32 @@ func (r *ClusterSummaryReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return reconcile.Result{}, nil
 	}
 
+	readinessChecksSatisfied, msg, err := areClusterReadinessChecksSatisfied(ctx, r.Client, clusterSummary, logger)
+	if err != nil {
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
+	}
+	if !readinessChecksSatisfied {
+		logger.V(logs.LogInfo).Info(msg)
+		r.setFailureMessage(clusterSummaryScope, msg)
+		r.resetFeatureStatus(clusterSummaryScope, configv1beta1.FeatureStatusFailed)
+		_ = r.updateMaps(clusterSummaryScope, logger)
+		// Not all readiness criteria (labels, machines) are watched. Periodically check again.
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
+	}
+
+	baseAddOnsReady, msg, err := areBaseAddOnsReady(ctx, r.Client, clusterSummary, logger)
+	if err != nil {
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
+	}
+	if !baseAddOnsReady {
+		logger.V(logs.LogInfo).Info(msg)
+		r.setFailureMessage(clusterSummaryScope, msg)
+		r.resetFeatureStatus(clusterSummaryScope, configv1beta1.FeatureStatusFailed)
+		_ = r.updateMaps(clusterSummaryScope, logger)
+		// Base ClusterSummaries are not watched. Periodically check again.
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
+	}
+
 	// Handle non-deleted clusterSummary
 	return r.reconcileNormal(ctx, clusterSummaryScope, logger)
 }
@@ -226,13 +270,13 @@ func (r *ClusterSummaryReconciler) reconcileDelete(
 
 	isReady, err := r.isReady(ctx, clusterSummaryScope.ClusterSummary, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
 	}
 
 	// If Sveltos/Cluster is not found, there is nothing to clean up.
 	isPresent, isDeleted, err := r.isClusterPresent(ctx, clusterSummaryScope)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
 	}
 	if isPresent && isReady { // if cluster is not ready, do not try to clean up. It would fail.
 		// Cleanup
@@ -248,7 +292,7 @@ func (r *ClusterSummaryReconciler) reconcileDelete(
 		err = r.removeResourceSummary(ctx, clusterSummaryScope, logger)
 		if err != nil {
 			logger.V(logs.LogInfo).Error(err, "failed to remove ResourceSummary.")
-			return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}, nil
+			return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
 		}
 
 		err = r.undeploy(ctx, clusterSummaryScope, logger)
@@ -256,13 +300,21 @@ func (r *ClusterSummaryReconciler) reconcileDelete(
 			// In DryRun mode it is expected to always get an error back
 			if !clusterSummaryScope.IsDryRunSync() {
 				logger.V(logs.LogInfo).Error(err, "failed to undeploy")
-				return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}, nil
+				return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
+			}
+		}
+
+		if clusterSummaryScope.IsContinuousWithDriftDetection() {
+			err = removeDriftDetectionManagerIfNotRequired(ctx, r.Client, clusterSummaryScope.ClusterSummary, logger)
+			if err != nil {
+				logger.V(logs.LogInfo).Error(err, "failed to remove drift-detection-manager")
+				return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
 			}
 		}
 
 		if !r.canRemoveFinalizer(ctx, clusterSummaryScope, logger) {
 			logger.V(logs.LogInfo).Error(err, "cannot remove finalizer yet")
-			return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}, nil
+			return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
 		}
 	}
 
@@ -278,7 +330,7 @@ func (r *ClusterSummaryReconciler) reconcileDelete(
 			cs.Spec.ClusterNamespace, cs.Spec.ClusterName, cs.Spec.ClusterType, logger); err != nil {
 			logger.V(logs.LogInfo).Info(
 				fmt.Sprintf("failed to remove drift-detection-manager resources from management cluster: %v", err))
-			return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}, nil
+			return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
 		}
 	}
 
@@ -296,6 +348,10 @@ func (r *ClusterSummaryReconciler) reconcileDelete(
 	}
 
 	r.cleanMaps(clusterSummaryScope)
+	trackDriftDetectionRequirement(clusterSummaryScope.ClusterSummary, false)
+	removeTimeToProvisionData(clusterSummaryScope.ClusterSummary)
+	r.releaseDeploymentSlot(clusterSummaryScope.ClusterSummary)
+	r.releaseEnforcementSlot(clusterSummaryScope.ClusterSummary)
 
 	manager := getManager()
 	manager.stopStaleWatchForTemplateResourceRef(clusterSummaryScope.ClusterSummary, true)
@@ -320,55 +376,125 @@ func (r *ClusterSummaryReconciler) reconcileNormal(
 		}
 	}
 
+	pausedReason, err := r.getPausedReason(ctx, clusterSummaryScope.ClusterSummary)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var nextWindow time.Time
+	clusterSummary := clusterSummaryScope.ClusterSummary
+	if pausedReason == "" && !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+		var inWindow bool
+		inWindow, nextWindow, err = isInMaintenanceWindow(ctx, r.Client, clusterSummary.Spec.ClusterNamespace,
+			clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType, time.Now())
+		if err != nil {
+			logger.V(logs.LogInfo).Error(err, "failed to evaluate maintenance windows")
+			return reconcile.Result{}, err
+		}
+		if !inWindow {
+			pausedReason = configv1beta1.PausedByMaintenanceWindowReason
+		}
+	}
+	clusterSummaryScope.SetPaused(pausedReason)
+
+	// Plan is honored even if ClusterSummary is paused or does not need a reconciliation
+	r.planIfRequested(ctx, clusterSummaryScope, logger)
+
 	if !r.shouldReconcile(clusterSummaryScope, logger) {
 		logger.V(logs.LogInfo).Info("ClusterSummary does not need a reconciliation")
 		return reconcile.Result{}, nil
 	}
 
-	err := r.updateMaps(clusterSummaryScope, logger)
+	err = r.updateMaps(clusterSummaryScope, logger)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	paused, err := r.isPaused(ctx, clusterSummaryScope.ClusterSummary)
-	if err != nil {
-		return reconcile.Result{}, err
-	}
-	if paused {
-		logger.V(logs.LogInfo).Info("cluster is paused. Do nothing.")
+	if pausedReason != "" {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("ClusterSummary is paused (%s). Do nothing.", pausedReason))
+		r.releaseDeploymentSlot(clusterSummaryScope.ClusterSummary)
+		r.releaseEnforcementSlot(clusterSummaryScope.ClusterSummary)
+		if !nextWindow.IsZero() {
+			// Pending changes are deployed once maintenance window opens
+			return reconcile.Result{Requeue: true, RequeueAfter: time.Until(nextWindow)}, nil
+		}
 		return reconcile.Result{}, nil
 	}
 
+	// Deployments are attempted even if the cluster is unreachable. Failures are then
+	// reported per feature while ConnectionDown reports why.
+	updateConnectionStatus(ctx, r.Client, clusterSummaryScope, logger)
+
 	err = r.startWatcherForTemplateResourceRefs(ctx, clusterSummaryScope.ClusterSummary)
 	if err != nil {
 		logger.V(logs.LogInfo).Error(err, "failed to start watcher on resources referenced in TemplateResourceRefs.")
-		return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
 	}
 
 	allDeployed, msg, err := r.areDependenciesDeployed(ctx, clusterSummaryScope, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
 	}
 	clusterSummaryScope.SetDependenciesMessage(&msg)
 	if !allDeployed {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
 	}
 
 	err = r.updateChartMap(ctx, clusterSummaryScope, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
 	}
 
 	if !clusterSummaryScope.IsContinuousWithDriftDetection() {
 		err = r.removeResourceSummary(ctx, clusterSummaryScope, logger)
 		if err != nil {
 			logger.V(logs.LogInfo).Error(err, "failed to remove ResourceSummary.")
-			return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}, nil
+			return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
 		}
+
+		// SyncMode moved away from ContinuousWithDriftDetection
+		if trackDriftDetectionRequirement(clusterSummary, false) {
+			err = removeDriftDetectionManagerIfNotRequired(ctx, r.Client, clusterSummary, logger)
+			if err != nil {
+				logger.V(logs.LogInfo).Error(err, "failed to remove drift-detection-manager")
+				// keep tracking it so removal is retried
+				trackDriftDetectionRequirement(clusterSummary, true)
+				return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}, nil
+			}
+		}
+	} else {
+		trackDriftDetectionRequirement(clusterSummary, true)
+	}
+
+	acquired, err := r.acquireDeploymentSlot(clusterSummaryScope.ClusterSummary)
+	if err != nil {
+		logger.V(logs.LogInfo).Error(err, "failed to acquire deployment slot")
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
+	}
+	if !acquired {
+		logger.V(logs.LogInfo).Info("maximum number of concurrent cluster deployments reached for profile. Wait.")
+		clusterSummaryScope.ClusterSummary.Status.QueuePosition =
+			r.getDeploymentQueuePosition(clusterSummaryScope.ClusterSummary)
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
+	}
+	clusterSummaryScope.ClusterSummary.Status.QueuePosition = nil
+
+	enforceWaiting, err := r.enforceIfDue(clusterSummaryScope, time.Now(), logger)
+	if err != nil {
+		logger.V(logs.LogInfo).Error(err, "failed to acquire enforcement slot")
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
 	}
 
 	err = r.deploy(ctx, clusterSummaryScope, logger)
 	if err != nil {
+		var retryErr *retryAfterError
+		if errors.As(err, &retryErr) {
+			logger.V(logs.LogInfo).Error(err, "failed to deploy")
+			if r.yieldDeploymentSlotIfFailing(clusterSummaryScope.ClusterSummary) {
+				logger.V(logs.LogInfo).Info("deployment keeps failing. Deployment slot yielded to other clusters.")
+			}
+			return reconcile.Result{Requeue: true, RequeueAfter: retryErr.after}, nil
+		}
 		var conflictErr *deployer.ConflictError
 		ok := errors.As(err, &conflictErr)
 		if ok {
@@ -376,19 +502,37 @@ func (r *ClusterSummaryReconciler) reconcileNormal(
 			return reconcile.Result{Requeue: true, RequeueAfter: r.ConflictRetryTime}, nil
 		}
 		logger.V(logs.LogInfo).Error(err, "failed to deploy")
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}, nil
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
+	}
+
+	r.releaseDeploymentSlot(clusterSummaryScope.ClusterSummary)
+	r.releaseEnforcementSlot(clusterSummaryScope.ClusterSummary)
+
+	if isCluterSummaryProvisioned(clusterSummaryScope.ClusterSummary) {
+		clusterSummaryScope.ClusterSummary.Status.ObservedRevision =
+			clusterSummaryScope.ClusterSummary.Spec.ProfileRevision
 	}
 
 	logger.V(logs.LogInfo).Info("Reconciling ClusterSummary success")
+	if enforceWaiting {
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}, nil
+	}
+	if nextEnforce := getNextEnforceTime(clusterSummaryScope); !nextEnforce.IsZero() {
+		// Features are re-applied once EnforceInterval elapses
+		return reconcile.Result{Requeue: true, RequeueAfter: time.Until(nextEnforce)}, nil
+	}
 	return reconcile.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterSummaryReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	r.Client = newAPIWritesCountingClient(r.Client)
+
 	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&configv1beta1.ClusterSummary{}).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: r.ConcurrentReconciles,
+			RateLimiter:             r.RequeuePolicy.rateLimiter(),
 		}).
 		Watches(&libsveltosv1beta1.SveltosCluster{},
 			handler.EnqueueRequestsFromMapFunc(r.requeueClusterSummaryForSveltosCluster),
@@ -403,11 +547,15 @@ func (r *ClusterSummaryReconciler) SetupWithManager(ctx context.Context, mgr ctr
 			),
 		).
 		Watches(&corev1.Secret{},
-			handler.EnqueueRequestsFromMapFunc(r.requeueClusterSummaryForReference),
+			handler.EnqueueRequestsFromMapFunc(r.requeueClusterSummaryForSecret),
 			builder.WithPredicates(
 				SecretPredicates(mgr.GetLogger().WithValues("predicate", "secretpredicate")),
 			),
 		).
+		Watches(&configv1beta1.MaintenanceWindow{},
+			handler.EnqueueRequestsFromMapFunc(r.requeueClusterSummaryForMaintenanceWindow),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
 		Build(r)
 	if err != nil {
 		return errors.Wrap(err, "error creating controller")
@@ -500,25 +648,49 @@ func (r *ClusterSummaryReconciler) deploy(ctx context.Context, clusterSummarySco
 	clusterSummary := clusterSummaryScope.ClusterSummary
 	logger = logger.WithValues("clusternamespace", clusterSummary.Spec.ClusterNamespace, "clustername", clusterSummary.Spec.ClusterName)
 
-	resourceErr := r.deployResources(ctx, clusterSummaryScope, logger)
-
-	helmErr := r.deployHelm(ctx, clusterSummaryScope, logger)
-
-	kustomizeError := r.deployKustomizeRefs(ctx, clusterSummaryScope, logger)
+	deployFeatures, sequential := r.getFeatureDeployers(clusterSummary)
+
+	// Features are deployed in order. Features backing off after a failure return a retryAfterError.
+	// Any other error is returned first, as it requires a quicker requeue, otherwise the
+	// retryAfterError with the shortest wait.
+	// The first sequential features (Spec.DeploymentOrder) are deployed only once all preceding
+	// ones are provisioned.
+	var deployErr error
+	var retryErr *retryAfterError
+	var blockingFeature *configv1beta1.FeatureID
+	for i := range deployFeatures {
+		if i < sequential && blockingFeature != nil {
+			logger.V(logs.LogDebug).Info(fmt.Sprintf("feature %s waiting for feature %s to be provisioned",
+				deployFeatures[i].featureID, *blockingFeature))
+			continue
+		}
 
-	if resourceErr != nil {
-		return resourceErr
+		err := deployFeatures[i].deploy(ctx, clusterSummaryScope, logger)
+		if i < sequential && blockingFeature == nil && !r.isFeatureReady(clusterSummary, deployFeatures[i].featureID, err) {
+			blockingFeature = &deployFeatures[i].featureID
+		}
+		if err == nil {
+			continue
+		}
+		var currentRetryErr *retryAfterError
+		if errors.As(err, &currentRetryErr) {
+			if retryErr == nil || currentRetryErr.after < retryErr.after {
+				retryErr = currentRetryErr
+			}
+		} else if deployErr == nil {
+			deployErr = err
+		}
 	}
 
-	if helmErr != nil {
-		return helmErr
+	if deployErr == nil && retryErr != nil {
+		return retryErr
 	}
-
-	if kustomizeError != nil {
-		return kustomizeError
+	if deployErr == nil && blockingFeature != nil {
+		// Blocking feature failed with a non retriable error
+		return fmt.Errorf("feature %s is not provisioned. Features following it in DeploymentOrder are not deployed",
+			*blockingFeature)
 	}
-
-	return nil
+	return deployErr
 }
 
 func (r *ClusterSummaryReconciler) deployKustomizeRefs(ctx context.Context, clusterSummaryScope *scope.ClusterSummaryScope, logger logr.Logger) error {
@@ -536,7 +708,7 @@ func (r *ClusterSummaryReconciler) deployKustomizeRefs(ctx context.Context, clus
 }
 
 func (r *ClusterSummaryReconciler) deployResources(ctx context.Context, clusterSummaryScope *scope.ClusterSummaryScope, logger logr.Logger) error {
-	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.PolicyRefs == nil {
+	if getResourceRefs(clusterSummaryScope.ClusterSummary) == nil {
 		logger.V(logs.LogDebug).Info("no policy configuration")
 		if !r.isFeatureStatusPresent(clusterSummaryScope.ClusterSummary, configv1beta1.FeatureResources) {
 			logger.V(logs.LogDebug).Info("no policy status. Do not reconcile this")
@@ -563,13 +735,29 @@ func (r *ClusterSummaryReconciler) deployHelm(ctx context.Context, clusterSummar
 	return r.deployFeature(ctx, clusterSummaryScope, f, logger)
 }
 
+func (r *ClusterSummaryReconciler) deployClusterMetadata(ctx context.Context, clusterSummaryScope *scope.ClusterSummaryScope,
+	logger logr.Logger) error {
+
+	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.ClusterMetadataPropagations == nil {
+		logger.V(logs.LogDebug).Info("no cluster metadata configuration")
+		if !r.isFeatureStatusPresent(clusterSummaryScope.ClusterSummary, configv1beta1.FeatureClusterMetadata) {
+			logger.V(logs.LogDebug).Info("no cluster metadata status. Do not reconcile this")
+			return nil
+		}
+	}
+
+	f := getHandlersForFeature(configv1beta1.FeatureClusterMetadata)
+
+	return r.deployFeature(ctx, clusterSummaryScope, f, logger)
+}
+
 func (r *ClusterSummaryReconciler) isClusterPresent(ctx context.Context,
 	clusterSummaryScope *scope.ClusterSummaryScope) (present, deleted bool, err error) {
 
 	cs := clusterSummaryScope.ClusterSummary
 
 	var cluster client.Object
-	cluster, err = clusterproxy.GetCluster(ctx, r.Client, cs.Spec.ClusterNamespace, cs.Spec.ClusterName, cs.Spec.ClusterType)
+	cluster, err = getCluster(ctx, r.Client, cs.Spec.ClusterNamespace, cs.Spec.ClusterName, cs.Spec.ClusterType)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			return false, false, nil
@@ -588,6 +776,8 @@ func (r *ClusterSummaryReconciler) undeploy(ctx context.Context, clusterSummaryS
 
 	helmErr := r.undeployHelm(ctx, clusterSummaryScope, logger)
 
+	clusterMetadataErr := r.undeployClusterMetadata(ctx, clusterSummaryScope, logger)
+
 	if resourceErr != nil {
 		return resourceErr
 	}
@@ -600,6 +790,10 @@ func (r *ClusterSummaryReconciler) undeploy(ctx context.Context, clusterSummaryS
 		return helmErr
 	}
 
+	if clusterMetadataErr != nil {
+		return clusterMetadataErr
+	}
+
 	return nil
 }
 
@@ -618,6 +812,13 @@ func (r *ClusterSummaryReconciler) undeployHelm(ctx context.Context, clusterSumm
 	return r.undeployFeature(ctx, clusterSummaryScope, f, logger)
 }
 
+func (r *ClusterSummaryReconciler) undeployClusterMetadata(ctx context.Context, clusterSummaryScope *scope.ClusterSummaryScope,
+	logger logr.Logger) error {
+
+	f := getHandlersForFeature(configv1beta1.FeatureClusterMetadata)
+	return r.undeployFeature(ctx, clusterSummaryScope, f, logger)
+}
+
 func (r *ClusterSummaryReconciler) updateChartMap(ctx context.Context, clusterSummaryScope *scope.ClusterSummaryScope,
 	logger logr.Logger) error {
 
@@ -629,7 +830,7 @@ func (r *ClusterSummaryReconciler) updateChartMap(ctx context.Context, clusterSu
 	// 1) this ClusterSummary would be elected as manager
 	// 2) ClusterSummary is in DryRun mode so it actually won't deploy anything
 	// 3) If another ClusterProfile in not DryRun mode tried to manage same helm chart, it would not be allowed.
-	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
@@ -667,6 +868,24 @@ func (r *ClusterSummaryReconciler) deleteChartMap(ctx context.Context, clusterSu
 	return nil
 }
 
+// persistChartMap persists helm chart registrations so that helm release ownership survives
+// controller restarts. Failures are only logged, persisting is retried at next reconciliation.
+func (r *ClusterSummaryReconciler) persistChartMap(ctx context.Context, logger logr.Logger) {
+	chartManager, err := chartmanager.GetChartManagerInstance(ctx, r.Client)
+	if err != nil {
+		return
+	}
+
+	if err := chartManager.PersistRegistrations(ctx, r.Client); err != nil {
+		if errors.Is(err, chartmanager.ErrRegistrationsTooLarge) {
+			// Retrying won't help. Queue order of those helm releases will be lost on restart.
+			logger.Error(err, "helm chart registrations are too large to be persisted")
+			return
+		}
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to persist helm chart registrations: %v", err))
+	}
+}
+
 func (r *ClusterSummaryReconciler) cleanMaps(clusterSummaryScope *scope.ClusterSummaryScope) {
 	r.PolicyMux.Lock()
 	defer r.PolicyMux.Unlock()
@@ -681,6 +900,9 @@ func (r *ClusterSummaryReconciler) cleanMaps(clusterSummaryScope *scope.ClusterS
 	for i := range r.ReferenceMap {
 		clusterSummarySet := r.ReferenceMap[i]
 		clusterSummarySet.Erase(clusterSummaryInfo)
+		if clusterSummarySet.Len() == 0 {
+			delete(r.ReferenceMap, i)
+		}
 	}
 }
 
@@ -778,12 +1000,12 @@ func (r *ClusterSummaryReconciler) shouldReconcile(clusterSummaryScope *scope.Cl
 		return true
 	}
 
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		logger.V(logs.LogDebug).Info("Mode set to dryRun. Reconciliation is needed.")
 		return true
 	}
 
-	if len(clusterSummary.Spec.ClusterProfileSpec.PolicyRefs) != 0 {
+	if len(getResourceRefs(clusterSummary)) != 0 {
 		if !r.isFeatureDeployed(clusterSummaryScope.ClusterSummary, configv1beta1.FeatureResources) {
 			logger.V(logs.LogDebug).Info("Mode set to one time. Resources not deployed yet. Reconciliation is needed.")
 			return true
@@ -804,6 +1026,13 @@ func (r *ClusterSummaryReconciler) shouldReconcile(clusterSummaryScope *scope.Cl
 		}
 	}
 
+	if len(clusterSummary.Spec.ClusterProfileSpec.ClusterMetadataPropagations) != 0 {
+		if !r.isFeatureDeployed(clusterSummaryScope.ClusterSummary, configv1beta1.FeatureClusterMetadata) {
+			logger.V(logs.LogDebug).Info("Mode set to one time. Cluster metadata not propagated yet. Reconciliation is needed.")
+			return true
+		}
+	}
+
 	return false
 }
 
@@ -827,6 +1056,13 @@ func (r *ClusterSummaryReconciler) getCurrentReferences(clusterSummaryScope *sco
 	}
 	currentReferences.Append(helmRefs)
 
+	clusterMetadataRefs, err := r.getReferencesFromPolicyRefs(clusterSummaryScope,
+		getClusterMetadataRefs(clusterSummaryScope.ClusterSummary))
+	if err != nil {
+		return nil, err
+	}
+	currentReferences.Append(clusterMetadataRefs)
+
 	return currentReferences, nil
 }
 
@@ -834,21 +1070,28 @@ func (r *ClusterSummaryReconciler) getCurrentReferences(clusterSummaryScope *sco
 func (r *ClusterSummaryReconciler) getPolicyRefReferences(clusterSummaryScope *scope.ClusterSummaryScope,
 ) (*libsveltosset.Set, error) {
 
+	return r.getReferencesFromPolicyRefs(clusterSummaryScope, getResourceRefs(clusterSummaryScope.ClusterSummary))
+}
+
+// getReferencesFromPolicyRefs returns the resources refs point to. Names expressed as templates are instantiated.
+func (r *ClusterSummaryReconciler) getReferencesFromPolicyRefs(clusterSummaryScope *scope.ClusterSummaryScope,
+	refs []configv1beta1.PolicyRef) (*libsveltosset.Set, error) {
+
 	currentReferences := &libsveltosset.Set{}
-	for i := range clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.PolicyRefs {
-		referencedNamespace := clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.PolicyRefs[i].Namespace
+	for i := range refs {
+		referencedNamespace := refs[i].Namespace
 		namespace := libsveltostemplate.GetReferenceResourceNamespace(clusterSummaryScope.Namespace(), referencedNamespace)
 
 		cs := clusterSummaryScope.ClusterSummary
 		referencedName, err := libsveltostemplate.GetReferenceResourceName(cs.Spec.ClusterNamespace, cs.Spec.ClusterName,
-			string(cs.Spec.ClusterType), clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.PolicyRefs[i].Name)
+			string(cs.Spec.ClusterType), refs[i].Name)
 		if err != nil {
 			return nil, err
 		}
 
 		currentReferences.Insert(&corev1.ObjectReference{
-			APIVersion: corev1.SchemeGroupVersion.String(), // the only resources that can be referenced are Secret and ConfigMap
-			Kind:       clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.PolicyRefs[i].Kind,
+			APIVersion: getReferenceAPIVersion(refs[i].Kind),
+			Kind:       refs[i].Kind,
 			Namespace:  namespace,
 			Name:       referencedName,
 		})
@@ -856,6 +1099,20 @@ func (r *ClusterSummaryReconciler) getPolicyRefReferences(clusterSummaryScope *s
 	return currentReferences, nil
 }
 
+// getReferenceAPIVersion returns the apiVersion of a referenced object given its kind. Referenced
+// objects are either ConfigMaps/Secrets or Flux Sources. The apiVersion must match the one used
+// when reacting to changes of referenced objects, otherwise ClusterSummaries are not requeued.
+func getReferenceAPIVersion(kind string) string {
+	switch kind {
+	case sourcev1.GitRepositoryKind:
+		return sourcev1.GroupVersion.String()
+	case sourcev1b2.OCIRepositoryKind, sourcev1b2.BucketKind:
+		return sourcev1b2.GroupVersion.String()
+	default:
+		return corev1.SchemeGroupVersion.String()
+	}
+}
+
 // getKustomizationRefReferences get all references considering the KustomizationRef section
 func (r *ClusterSummaryReconciler) getKustomizationRefReferences(clusterSummaryScope *scope.ClusterSummaryScope,
 ) (*libsveltosset.Set, error) {
@@ -876,19 +1133,8 @@ func (r *ClusterSummaryReconciler) getKustomizationRefReferences(clusterSummaryS
 			return nil, err
 		}
 
-		var apiVersion string
-		switch kr.Kind {
-		case sourcev1.GitRepositoryKind:
-			apiVersion = sourcev1.GroupVersion.String()
-		case sourcev1b2.OCIRepositoryKind:
-			apiVersion = sourcev1b2.GroupVersion.String()
-		case sourcev1b2.BucketKind:
-			apiVersion = sourcev1b2.GroupVersion.String()
-		default:
-			apiVersion = corev1.SchemeGroupVersion.String()
-		}
 		currentReferences.Insert(&corev1.ObjectReference{
-			APIVersion: apiVersion,
+			APIVersion: getReferenceAPIVersion(kr.Kind),
 			Kind:       kr.Kind,
 			Namespace:  namespace,
 			Name:       referencedName,
@@ -934,7 +1180,8 @@ func getKustomizationValueFrom(clusterSummaryScope *scope.ClusterSummaryScope, k
 	return currentValuesFromReferences, nil
 }
 
-// getHelmChartsReferences get all references considering the HelmChart section
+// getHelmChartsReferences get all references considering the HelmChart section (ValuesFrom
+// and registry credentials)
 func (r *ClusterSummaryReconciler) getHelmChartsReferences(clusterSummaryScope *scope.ClusterSummaryScope,
 ) (*libsveltosset.Set, error) {
 
@@ -946,6 +1193,15 @@ func (r *ClusterSummaryReconciler) getHelmChartsReferences(clusterSummaryScope *
 			return nil, err
 		}
 		currentReferences.Append(valuesFromReferences)
+
+		for _, secret := range getRegistryCredentialsSecrets(clusterSummaryScope.Namespace(), hc) {
+			currentReferences.Insert(&corev1.ObjectReference{
+				APIVersion: corev1.SchemeGroupVersion.String(),
+				Kind:       string(libsveltosv1beta1.SecretReferencedResourceKind),
+				Namespace:  secret.Namespace,
+				Name:       secret.Name,
+			})
+		}
 	}
 	return currentReferences, nil
 }
@@ -1005,7 +1261,7 @@ func (r *ClusterSummaryReconciler) isReady(ctx context.Context,
 		clusterRef.APIVersion = clusterv1.GroupVersion.String()
 	}
 
-	isClusterReady, err := clusterproxy.IsClusterReadyToBeConfigured(ctx, r.Client, clusterRef, logger)
+	isClusterReady, err := isClusterReadyToBeConfigured(ctx, r.Client, clusterRef, logger)
 
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -1017,25 +1273,54 @@ func (r *ClusterSummaryReconciler) isReady(ctx context.Context,
 	return isClusterReady, nil
 }
 
-// isPaused returns true if Sveltos/Cluster is paused or ClusterSummary has paused annotation.
+// isPaused returns true if Sveltos/Cluster is paused, ClusterSummary has paused annotation
+// or owner ClusterProfile/Profile is paused.
 func (r *ClusterSummaryReconciler) isPaused(ctx context.Context,
 	clusterSummary *configv1beta1.ClusterSummary) (bool, error) {
 
-	isClusterPaused, err := clusterproxy.IsClusterPaused(ctx, r.Client, clusterSummary.Spec.ClusterNamespace,
-		clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType)
+	reason, err := r.getPausedReason(ctx, clusterSummary)
+	if err != nil {
+		return false, err
+	}
+
+	return reason != "", nil
+}
+
+// getPausedReason returns why ClusterSummary is paused. Empty string is returned if ClusterSummary
+// is not paused.
+func (r *ClusterSummaryReconciler) getPausedReason(ctx context.Context,
+	clusterSummary *configv1beta1.ClusterSummary) (string, error) {
 
+	isClusterPaused, err := isClusterPaused(ctx, r.Client, clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			return false, nil
+			return "", nil
 		}
-		return false, err
+		return "", err
 	}
 
 	if isClusterPaused {
-		return true, nil
+		return configv1beta1.PausedByClusterReason, nil
+	}
+
+	if clusterSummary.Spec.ClusterProfileSpec.Paused {
+		return configv1beta1.PausedByProfileReason, nil
+	}
+
+	if supported, _ := isControllerVersionSupported(clusterSummary.Spec.ClusterProfileSpec.MinControllerVersion); !supported {
+		return configv1beta1.PausedByControllerVersionReason, nil
 	}
 
-	return annotations.HasPaused(clusterSummary), nil
+	if annotations.HasPaused(clusterSummary) {
+		return configv1beta1.PausedByAnnotationReason, nil
+	}
+
+	if _, ok := clusterSummary.Annotations[configv1beta1.PausedAnnotation]; ok {
+		return configv1beta1.PausedByAnnotationReason, nil
+	}
+
+	return "", nil
 }
 
 // canRemoveFinalizer returns true if finalizer can be removed.
@@ -1051,7 +1336,7 @@ func (r *ClusterSummaryReconciler) canRemoveFinalizer(ctx context.Context,
 		return false
 	}
 
-	_, err := clusterproxy.GetCluster(ctx, r.Client, clusterSummary.Spec.ClusterNamespace,
+	_, err := getCluster(ctx, r.Client, clusterSummary.Spec.ClusterNamespace,
 		clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -1103,7 +1388,7 @@ func (r *ClusterSummaryReconciler) removeResourceSummary(ctx context.Context,
 	// ResourceSummary is a Sveltos resource deployed in managed clusters.
 	// Such resources are always created, removed using cluster-admin roles.
 	cs := clusterSummaryScope.ClusterSummary
-	remoteClient, err := clusterproxy.GetKubernetesClient(ctx, r.Client, cs.Spec.ClusterNamespace,
+	remoteClient, err := getKubernetesClient(ctx, r.Client, cs.Spec.ClusterNamespace,
 		cs.Spec.ClusterName, "", "", cs.Spec.ClusterType, logger)
 	if err != nil {
 		return err
@@ -1146,7 +1431,7 @@ func (r *ClusterSummaryReconciler) updateClusterShardPair(ctx context.Context,
 func (r *ClusterSummaryReconciler) isClusterAShardMatch(ctx context.Context,
 	clusterSummary *configv1beta1.ClusterSummary, logger logr.Logger) (bool, error) {
 
-	cluster, err := clusterproxy.GetCluster(ctx, r.Client, clusterSummary.Spec.ClusterNamespace,
+	cluster, err := getCluster(ctx, r.Client, clusterSummary.Spec.ClusterNamespace,
 		clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType)
 	if err != nil {
 		// If Cluster does not exist anymore, make it match any shard
@@ -1258,24 +1543,30 @@ func (r *ClusterSummaryReconciler) setFailureMessage(clusterSummaryScope *scope.
 	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.HelmCharts != nil {
 		clusterSummaryScope.SetFailureMessage(configv1beta1.FeatureHelm, &failureMessage)
 	}
-	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.PolicyRefs != nil {
+	if getResourceRefs(clusterSummaryScope.ClusterSummary) != nil {
 		clusterSummaryScope.SetFailureMessage(configv1beta1.FeatureResources, &failureMessage)
 	}
 	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.KustomizationRefs != nil {
 		clusterSummaryScope.SetFailureMessage(configv1beta1.FeatureKustomize, &failureMessage)
 	}
+	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.ClusterMetadataPropagations != nil {
+		clusterSummaryScope.SetFailureMessage(configv1beta1.FeatureClusterMetadata, &failureMessage)
+	}
 }
 
 func (r *ClusterSummaryReconciler) resetFeatureStatus(clusterSummaryScope *scope.ClusterSummaryScope, status configv1beta1.FeatureStatus) {
 	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.HelmCharts != nil {
 		clusterSummaryScope.SetFeatureStatus(configv1beta1.FeatureHelm, status, nil)
 	}
-	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.PolicyRefs != nil {
+	if getResourceRefs(clusterSummaryScope.ClusterSummary) != nil {
 		clusterSummaryScope.SetFeatureStatus(configv1beta1.FeatureResources, status, nil)
 	}
 	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.KustomizationRefs != nil {
 		clusterSummaryScope.SetFeatureStatus(configv1beta1.FeatureKustomize, status, nil)
 	}
+	if clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.ClusterMetadataPropagations != nil {
+		clusterSummaryScope.SetFeatureStatus(configv1beta1.FeatureClusterMetadata, status, nil)
+	}
 }
 
 func (r *ClusterSummaryReconciler) GetController() controller.Controller {
@@ -1310,4 +1601,7 @@ func (r *ClusterSummaryReconciler) cleanupQueuedCleanOperations(clusterSummary *
 
 	r.Deployer.CleanupEntries(clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName, clusterSummary.Name,
 		string(configv1beta1.FeatureResources), clusterSummary.Spec.ClusterType, true)
+
+	r.Deployer.CleanupEntries(clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName, clusterSummary.Name,
+		string(configv1beta1.FeatureClusterMetadata), clusterSummary.Spec.ClusterType, true)
 }
@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -187,6 +188,36 @@ var _ = Describe("ClustersummaryController", func() {
 		Expect(controllers.IsPaused(reconciler, context.TODO(), clusterSummary)).To(BeFalse())
 	})
 
+	It("isPaused returns true if ClusterProfile Spec.Paused is set or ClusterSummary has pause annotation", func() {
+		initObjects := []client.Object{
+			clusterProfile,
+			clusterSummary,
+			cluster,
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).WithObjects(initObjects...).Build()
+
+		reconciler := &controllers.ClusterSummaryReconciler{
+			Client:       c,
+			Scheme:       scheme,
+			Deployer:     nil,
+			ClusterMap:   make(map[corev1.ObjectReference]*libsveltosset.Set),
+			ReferenceMap: make(map[corev1.ObjectReference]*libsveltosset.Set),
+			PolicyMux:    sync.Mutex{},
+		}
+
+		Expect(controllers.IsPaused(reconciler, context.TODO(), clusterSummary)).To(BeFalse())
+
+		clusterSummary.Spec.ClusterProfileSpec.Paused = true
+		Expect(controllers.IsPaused(reconciler, context.TODO(), clusterSummary)).To(BeTrue())
+
+		clusterSummary.Spec.ClusterProfileSpec.Paused = false
+		clusterSummary.Annotations = map[string]string{
+			configv1beta1.PausedAnnotation: "maintenance",
+		}
+		Expect(controllers.IsPaused(reconciler, context.TODO(), clusterSummary)).To(BeTrue())
+	})
+
 	It("shouldReconcile returns true when mode is Continuous", func() {
 		clusterSummary.Spec.ClusterProfileSpec.SyncMode = configv1beta1.SyncModeContinuous
 
@@ -892,6 +923,52 @@ var _ = Describe("ClustersummaryController", func() {
 		Expect(items[0].Namespace).To(Equal(clusterSummary.Namespace))
 	})
 
+	It("getCurrentReferences collects Secrets referenced in HelmChart RegistryCredentialsConfig", func() {
+		credentials := randomString()
+		ca := randomString()
+		clusterSummary.Spec.ClusterProfileSpec.HelmCharts = []configv1beta1.HelmChart{
+			{
+				RegistryCredentialsConfig: &configv1beta1.RegistryCredentialsConfig{
+					CredentialsSecretRef: &corev1.SecretReference{Name: credentials},
+					CASecretRef:          &corev1.SecretReference{Namespace: randomString(), Name: ca},
+				},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		clusterSummaryScope := getClusterSummaryScope(c,
+			textlogger.NewLogger(textlogger.NewConfig()), clusterProfile, clusterSummary)
+		reconciler := getClusterSummaryReconciler(nil, nil)
+		set, err := controllers.GetCurrentReferences(reconciler, clusterSummaryScope)
+		Expect(err).To(BeNil())
+		Expect(set.Len()).To(Equal(2))
+		Expect(set.Has(&corev1.ObjectReference{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       string(libsveltosv1beta1.SecretReferencedResourceKind),
+			Namespace:  clusterSummary.Namespace,
+			Name:       credentials,
+		})).To(BeTrue())
+	})
+
+	It("getCurrentReferences uses Flux Source apiVersion for PolicyRefs referencing Flux Sources", func() {
+		clusterSummary.Spec.ClusterProfileSpec.PolicyRefs = []configv1beta1.PolicyRef{
+			{Namespace: randomString(), Name: randomString(), Kind: sourcev1.GitRepositoryKind},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		clusterSummaryScope := getClusterSummaryScope(c,
+			textlogger.NewLogger(textlogger.NewConfig()), clusterProfile, clusterSummary)
+		reconciler := getClusterSummaryReconciler(nil, nil)
+		set, err := controllers.GetCurrentReferences(reconciler, clusterSummaryScope)
+		Expect(err).To(BeNil())
+		Expect(set.Len()).To(Equal(1))
+		items := set.Items()
+		Expect(items[0].APIVersion).To(Equal(sourcev1.GroupVersion.String()))
+		Expect(items[0].Kind).To(Equal(sourcev1.GitRepositoryKind))
+	})
+
 	It("reconcileDelete successfully returns when cluster is not found", func() {
 		clusterSummary.Spec.ClusterProfileSpec.HelmCharts = []configv1beta1.HelmChart{
 			{RepositoryURL: randomString(), ChartName: randomString(), ChartVersion: randomString(), ReleaseName: randomString()},
@@ -18,12 +18,14 @@ package controllers
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"reflect"
 	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -31,7 +33,6 @@ import (
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	"github.com/projectsveltos/addon-controller/pkg/scope"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
-	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	"github.com/projectsveltos/libsveltos/lib/deployer"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 )
@@ -53,6 +54,35 @@ func startDriftDetectionInMgmtCluster(o deployer.Options) bool {
 	return runInMgtmCluster
 }
 
+// isDriftDetectionInMgmtCluster returns true if drift-detection-manager for the cluster matching
+// clusterSummary must run in the management cluster. Spec.DriftDetectionPlacement, when set, takes
+// precedence over agentInMgmtCluster (the addon-controller default).
+func isDriftDetectionInMgmtCluster(clusterSummary *configv1beta1.ClusterSummary, agentInMgmtCluster bool) bool {
+	switch clusterSummary.Spec.ClusterProfileSpec.DriftDetectionPlacement {
+	case configv1beta1.DriftDetectionPlacementManagementCluster:
+		return true
+	case configv1beta1.DriftDetectionPlacementManagedCluster:
+		return false
+	default:
+		return agentInMgmtCluster
+	}
+}
+
+// getDriftDetectionResources returns the compute resources of drift-detection-manager requested by
+// clusterSummary for the placement drift-detection-manager runs in. Nil if none is requested.
+func getDriftDetectionResources(clusterSummary *configv1beta1.ClusterSummary, inMgmtCluster bool,
+) *corev1.ResourceRequirements {
+
+	resources := clusterSummary.Spec.ClusterProfileSpec.DriftDetectionResources
+	if resources == nil {
+		return nil
+	}
+	if inMgmtCluster {
+		return resources.ManagementCluster
+	}
+	return resources.ManagedCluster
+}
+
 type getCurrentHash func(ctx context.Context, c client.Client, clusterSummaryScope *scope.ClusterSummaryScope,
 	logger logr.Logger) ([]byte, error)
 
@@ -109,22 +139,52 @@ func (r *ClusterSummaryReconciler) deployFeature(ctx context.Context, clusterSum
 		return nil
 	}
 
+	// Deployment was aborted when previous controller instance shut down. Result recorded for it, if any,
+	// is the abort. Redeploy right away.
+	interrupted := takeInterruptedFeature(clusterSummary, f.id)
+	if interrupted {
+		logger.V(logs.LogInfo).Info("deployment was interrupted by controller shutdown. Redeploying")
+	}
+
+	consecutiveFailures := int32(0)
+	if fs := getFeatureSummaryForFeatureID(clusterSummary, f.id); fs != nil {
+		if !isConfigSame || interrupted {
+			// Configuration changed or deployment was interrupted. Do not wait for backoff to expire.
+			clusterSummaryScope.SetRetryStatus(f.id, 0, nil)
+		} else {
+			consecutiveFailures = fs.ConsecutiveFailures
+			if err := r.getRetryAfterError(fs); err != nil {
+				logger.V(logs.LogDebug).Info(fmt.Sprintf("previous attempt failed. Waiting before retrying: %v", err))
+				return err
+			}
+		}
+	}
+
 	var status *configv1beta1.FeatureStatus
 	var resultError error
 
 	// Feature is not deployed yet
-	if isConfigSame {
+	if isConfigSame && !interrupted {
 		logger.V(logs.LogDebug).Info("hash has not changed")
 		result := r.Deployer.GetResult(ctx, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
 			clusterSummary.Name, string(f.id), clusterSummary.Spec.ClusterType, false)
 		status = r.convertResultStatus(result)
 		resultError = result.Err
+		if status == nil {
+			// Result might have been lost on restart
+			status, resultError = getDeploymentIntentResult(ctx, r.Client, clusterSummary, f.id, false,
+				currentHash, logger)
+		} else if *status != configv1beta1.FeatureStatusProvisioning {
+			removeDeploymentIntent(ctx, r.Client, clusterSummary, f.id, false, logger)
+		}
+		status, resultError = verifyFeatureHealth(clusterSummary, f.id, currentHash, status, resultError, logger)
 	}
 
 	if status != nil {
 		logger.V(logs.LogDebug).Info("result is available. updating status.")
 		r.updateFeatureStatus(clusterSummaryScope, f.id, status, currentHash, resultError, logger)
 		if *status == configv1beta1.FeatureStatusProvisioned {
+			clusterSummaryScope.SetRetryStatus(f.id, 0, nil)
 			return nil
 		}
 		if resultError != nil {
@@ -135,6 +195,18 @@ func (r *ClusterSummaryReconciler) deployFeature(ctx context.Context, clusterSum
 				r.updateFeatureStatus(clusterSummaryScope, f.id, &nonRetriableStatus, currentHash, resultError, logger)
 				return nil
 			}
+
+			// Back off instead of immediately retrying
+			consecutiveFailures++
+			after := r.RetryPolicy.backoff(consecutiveFailures)
+			nextRetryTime := metav1.NewTime(time.Now().Add(after))
+			clusterSummaryScope.SetRetryStatus(f.id, consecutiveFailures, &nextRetryTime)
+			notifyDeploymentFailure(ctx, r.Client, clusterSummary, f.id, consecutiveFailures, resultError, logger)
+			if r.RetryPolicy.isCircuitOpen(consecutiveFailures) {
+				logger.V(logs.LogInfo).Info(fmt.Sprintf("%d consecutive failures. Not retrying before %s",
+					consecutiveFailures, nextRetryTime.UTC().Format(time.RFC3339)))
+			}
+			return &retryAfterError{after: after, err: resultError}
 		}
 		if *status == configv1beta1.FeatureStatusProvisioning {
 			return fmt.Errorf("feature is still being provisioned")
@@ -148,12 +220,15 @@ func (r *ClusterSummaryReconciler) deployFeature(ctx context.Context, clusterSum
 
 	// Getting here means either feature failed to be deployed or configuration has changed.
 	// Feature must be (re)deployed.
-	options := deployer.Options{HandlerOptions: map[string]string{}}
-	if r.AgentInMgmtCluster {
+	options := deployer.Options{HandlerOptions: map[string]string{
+		deploymentIntentHashOption: hex.EncodeToString(currentHash),
+	}}
+	if isDriftDetectionInMgmtCluster(clusterSummary, r.AgentInMgmtCluster) {
 		options.HandlerOptions[driftDetectionInMgtmCluster] = "management"
 	}
 
 	logger.V(logs.LogDebug).Info("queueing request to deploy")
+	recordDeploymentIntent(ctx, r.Client, clusterSummary, f.id, false, currentHash, logger)
 	if err := r.Deployer.Deploy(ctx, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
 		clusterSummary.Name, string(f.id), clusterSummary.Spec.ClusterType, false,
 		genericDeploy, programDuration, options); err != nil {
@@ -164,6 +239,25 @@ func (r *ClusterSummaryReconciler) deployFeature(ctx context.Context, clusterSum
 	return fmt.Errorf("request is queued")
 }
 
+// getRetryAfterError returns an error if feature failed to deploy and next attempt must
+// not be made yet
+func (r *ClusterSummaryReconciler) getRetryAfterError(fs *configv1beta1.FeatureSummary) error {
+	if fs.Status != configv1beta1.FeatureStatusFailed || fs.NextRetryTime == nil {
+		return nil
+	}
+
+	after := time.Until(fs.NextRetryTime.Time)
+	if after <= 0 {
+		return nil
+	}
+
+	err := errors.New("deployment failed")
+	if fs.FailureMessage != nil {
+		err = errors.New(*fs.FailureMessage)
+	}
+	return &retryAfterError{after: after, err: err}
+}
+
 func genericDeploy(ctx context.Context, c client.Client,
 	clusterNamespace, clusterName, applicant, featureID string,
 	clusterType libsveltosv1beta1.ClusterType,
@@ -178,13 +272,13 @@ func genericDeploy(ctx context.Context, c client.Client,
 	// Invoking per feature specific code
 	featureHandler := getHandlersForFeature(configv1beta1.FeatureID(featureID))
 	err := featureHandler.deploy(ctx, c, clusterNamespace, clusterName, applicant, featureID, clusterType, o, logger)
-	if err != nil {
-		return err
-	}
 
 	// After any per feature specific code
 
-	return nil
+	completeDeploymentIntent(ctx, c, clusterNamespace, applicant, configv1beta1.FeatureID(featureID), false,
+		o.HandlerOptions[deploymentIntentHashOption], err, logger)
+
+	return err
 }
 
 func (r *ClusterSummaryReconciler) undeployFeature(ctx context.Context, clusterSummaryScope *scope.ClusterSummaryScope,
@@ -220,6 +314,12 @@ func (r *ClusterSummaryReconciler) undeployFeature(ctx context.Context, clusterS
 	result := r.Deployer.GetResult(ctx, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
 		clusterSummaryScope.Name(), string(f.id), clusterSummary.Spec.ClusterType, true)
 	status := r.convertResultStatus(result)
+	if status == nil {
+		// Result might have been lost on restart
+		status, result.Err = getDeploymentIntentResult(ctx, r.Client, clusterSummary, f.id, true, nil, logger)
+	} else if *status != configv1beta1.FeatureStatusProvisioning {
+		removeDeploymentIntent(ctx, r.Client, clusterSummary, f.id, true, logger)
+	}
 
 	if status != nil {
 		if *status == configv1beta1.FeatureStatusProvisioning {
@@ -250,6 +350,7 @@ func (r *ClusterSummaryReconciler) undeployFeature(ctx context.Context, clusterS
 	}
 
 	logger.V(logs.LogDebug).Info("queueing request to un-deploy")
+	recordDeploymentIntent(ctx, r.Client, clusterSummary, f.id, true, nil, logger)
 	if err := r.Deployer.Deploy(ctx, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
 		clusterSummary.Name, string(f.id), clusterSummary.Spec.ClusterType, true, genericUndeploy, programDuration, deployer.Options{}); err != nil {
 		r.updateFeatureStatus(clusterSummaryScope, f.id, status, nil, err, logger)
@@ -270,7 +371,7 @@ func genericUndeploy(ctx context.Context, c client.Client,
 	// Before any per feature specific code
 
 	var err error
-	_, err = clusterproxy.GetCluster(ctx, c, clusterNamespace, clusterName, clusterType)
+	_, err = getCluster(ctx, c, clusterNamespace, clusterName, clusterType)
 
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -282,13 +383,14 @@ func genericUndeploy(ctx context.Context, c client.Client,
 
 	// Invoking per feature specific code
 	featureHandler := getHandlersForFeature(configv1beta1.FeatureID(featureID))
-	if err := featureHandler.undeploy(ctx, c, clusterNamespace, clusterName, applicant, featureID, clusterType, o, logger); err != nil {
-		return err
-	}
+	err = featureHandler.undeploy(ctx, c, clusterNamespace, clusterName, applicant, featureID, clusterType, o, logger)
 
 	// After any per feature specific code
 
-	return nil
+	completeDeploymentIntent(ctx, c, clusterNamespace, applicant, configv1beta1.FeatureID(featureID), true,
+		"", err, logger)
+
+	return err
 }
 
 // isFeatureStatusPresent returns true if feature status is set.
@@ -366,12 +468,19 @@ func (r *ClusterSummaryReconciler) updateFeatureStatus(clusterSummaryScope *scop
 	logger.V(logs.LogDebug).Info("updating clustersummary status")
 	now := metav1.NewTime(time.Now())
 
+	var previous *configv1beta1.FeatureSummary
+	if fs := getFeatureSummaryForFeatureID(clusterSummaryScope.ClusterSummary, featureID); fs != nil {
+		previous = fs.DeepCopy()
+	}
+
 	switch *status {
 	case configv1beta1.FeatureStatusProvisioned:
 		clusterSummaryScope.SetFeatureStatus(featureID, configv1beta1.FeatureStatusProvisioned, hash)
+		clusterSummaryScope.SetFailureReason(featureID, nil)
 		clusterSummaryScope.SetFailureMessage(featureID, nil)
 	case configv1beta1.FeatureStatusRemoved:
 		clusterSummaryScope.SetFeatureStatus(featureID, configv1beta1.FeatureStatusRemoved, hash)
+		clusterSummaryScope.SetFailureReason(featureID, nil)
 		clusterSummaryScope.SetFailureMessage(featureID, nil)
 	case configv1beta1.FeatureStatusProvisioning:
 		clusterSummaryScope.SetFeatureStatus(featureID, configv1beta1.FeatureStatusProvisioning, hash)
@@ -379,13 +488,36 @@ func (r *ClusterSummaryReconciler) updateFeatureStatus(clusterSummaryScope *scop
 		clusterSummaryScope.SetFeatureStatus(featureID, configv1beta1.FeatureStatusRemoving, hash)
 	case configv1beta1.FeatureStatusFailed, configv1beta1.FeatureStatusFailedNonRetriable:
 		clusterSummaryScope.SetFeatureStatus(featureID, *status, hash)
+		code := string(getMessageCode(statusError))
+		clusterSummaryScope.SetFailureReason(featureID, &code)
 		err := statusError.Error()
 		clusterSummaryScope.SetFailureMessage(featureID, &err)
 	}
 
+	trackTimeToProvision(clusterSummaryScope.ClusterSummary, featureID, *status, hash, now.Time)
+
+	// Re-asserting the very same outcome must not cause a status update. Otherwise every
+	// reconciliation in steady state would write ClusterSummary Status.
+	current := getFeatureSummaryForFeatureID(clusterSummaryScope.ClusterSummary, featureID)
+	if previous != nil && previous.LastAppliedTime != nil && !isFeatureSummaryChanged(previous, current) {
+		return
+	}
+
 	clusterSummaryScope.SetLastAppliedTime(featureID, &now)
 }
 
+// isFeatureSummaryChanged returns true if status, hash or failure of a FeatureSummary changed
+func isFeatureSummaryChanged(previous, current *configv1beta1.FeatureSummary) bool {
+	if current == nil {
+		return true
+	}
+
+	return previous.Status != current.Status ||
+		!reflect.DeepEqual(previous.Hash, current.Hash) ||
+		!reflect.DeepEqual(previous.FailureReason, current.FailureReason) ||
+		!reflect.DeepEqual(previous.FailureMessage, current.FailureMessage)
+}
+
 func (r *ClusterSummaryReconciler) convertResultStatus(result deployer.Result) *configv1beta1.FeatureStatus {
 	switch result.ResultStatus {
 	case deployer.Deployed:
@@ -33,6 +33,7 @@ import (
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	"github.com/projectsveltos/addon-controller/controllers"
@@ -268,6 +269,86 @@ var _ = Describe("ClustersummaryDeployer", func() {
 		Expect(clusterSummary.Status.FeatureSummaries[0].FailureMessage).To(BeNil())
 	})
 
+	It("updateFeatureStatus does not cause status writes when nothing changed", func() {
+		initObjects := []client.Object{
+			clusterSummary,
+			clusterProfile,
+		}
+
+		writes := 0
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).
+			WithObjects(initObjects...).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch,
+				opts ...client.PatchOption) error {
+
+				writes++
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				writes++
+				return c.Update(ctx, obj, opts...)
+			},
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object,
+				patch client.Patch, opts ...client.SubResourcePatchOption) error {
+
+				writes++
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+			SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object,
+				opts ...client.SubResourceUpdateOption) error {
+
+				writes++
+				return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+			},
+		}).Build()
+
+		reconciler := getClusterSummaryReconciler(c, nil)
+
+		hash := []byte(randomString())
+		status := configv1beta1.FeatureStatusProvisioned
+
+		getClusterSummary := func() *configv1beta1.ClusterSummary {
+			currentClusterSummary := &configv1beta1.ClusterSummary{}
+			Expect(c.Get(context.TODO(),
+				types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name},
+				currentClusterSummary)).To(Succeed())
+			return currentClusterSummary
+		}
+
+		reconcile := func() {
+			clusterSummaryScope := getClusterSummaryScope(c, logger, clusterProfile, getClusterSummary())
+			controllers.UpdateFeatureStatus(reconciler, clusterSummaryScope, configv1beta1.FeatureResources, &status,
+				hash, nil, logger)
+			Expect(clusterSummaryScope.PatchObject(context.TODO())).To(Succeed())
+		}
+
+		reconcile()
+		currentClusterSummary := getClusterSummary()
+		Expect(writes).ToNot(BeZero())
+		Expect(len(currentClusterSummary.Status.FeatureSummaries)).To(Equal(1))
+		fs := currentClusterSummary.Status.FeatureSummaries[0]
+		Expect(fs.LastTransitionTime).ToNot(BeNil())
+		Expect(fs.LastAppliedTime).ToNot(BeNil())
+
+		// Steady state: same status and hash produce zero API writes
+		for i := 0; i < 3; i++ {
+			writes = 0
+			reconcile()
+			Expect(writes).To(BeZero())
+			currentClusterSummary = getClusterSummary()
+			Expect(currentClusterSummary.Status.FeatureSummaries[0].LastTransitionTime).To(Equal(fs.LastTransitionTime))
+			Expect(currentClusterSummary.Status.FeatureSummaries[0].LastAppliedTime).To(Equal(fs.LastAppliedTime))
+		}
+
+		// A real transition is persisted
+		writes = 0
+		status = configv1beta1.FeatureStatusProvisioning
+		reconcile()
+		Expect(writes).ToNot(BeZero())
+		currentClusterSummary = getClusterSummary()
+		Expect(currentClusterSummary.Status.FeatureSummaries[0].Status).To(Equal(configv1beta1.FeatureStatusProvisioning))
+	})
+
 	It("deployFeature when feature is deployed and hash has not changed, does nothing", func() {
 		clusterRole := &rbacv1.ClusterRole{
 			ObjectMeta: metav1.ObjectMeta{
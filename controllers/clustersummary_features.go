@@ -48,6 +48,12 @@ func RegisterFeatures(d deployer.DeployerInterface, setupLog logr.Logger) {
 		os.Exit(1)
 	}
 
+	err = d.RegisterFeatureID(string(configv1beta1.FeatureClusterMetadata))
+	if err != nil {
+		setupLog.Error(err, "failed to register feature FeatureClusterMetadata")
+		os.Exit(1)
+	}
+
 	creatFeatureHandlerMaps()
 }
 
@@ -62,6 +68,10 @@ func creatFeatureHandlerMaps() {
 
 	featuresHandlers[configv1beta1.FeatureKustomize] = feature{id: configv1beta1.FeatureKustomize, currentHash: kustomizationHash,
 		deploy: deployKustomizeRefs, undeploy: undeployKustomizeRefs, getRefs: getKustomizationRefs}
+
+	featuresHandlers[configv1beta1.FeatureClusterMetadata] = feature{id: configv1beta1.FeatureClusterMetadata,
+		currentHash: clusterMetadataHash, deploy: deployClusterMetadata, undeploy: undeployClusterMetadata,
+		getRefs: getClusterMetadataRefs}
 }
 
 func getHandlersForFeature(featureID configv1beta1.FeatureID) feature {
@@ -29,6 +29,7 @@ import (
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	sourcev1b2 "github.com/fluxcd/source-controller/api/v1beta2"
 
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 )
@@ -181,6 +182,36 @@ func (r *ClusterSummaryReconciler) requeueClusterSummaryForReference(
 	return requests
 }
 
+// requeueClusterSummaryForSecret is a handler.ToRequestsFunc to be used to enqueue requests for reconciliation
+// for ClusterSummaries referencing the Secret or, when the Secret is a cluster kubeconfig, deploying to that cluster.
+func (r *ClusterSummaryReconciler) requeueClusterSummaryForSecret(
+	ctx context.Context, secret client.Object,
+) []reconcile.Request {
+
+	requests := r.requeueClusterSummaryForReference(ctx, secret)
+
+	cluster, err := getClusterForKubeconfigSecret(ctx, r.Client, secret)
+	if err != nil {
+		r.Logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify whether Secret %s/%s is a kubeconfig: %v",
+			secret.GetNamespace(), secret.GetName(), err))
+		return requests
+	}
+	if cluster == nil {
+		return requests
+	}
+
+	clusterType := libsveltosv1beta1.ClusterTypeCapi
+	if _, ok := cluster.(*libsveltosv1beta1.SveltosCluster); ok {
+		clusterType = libsveltosv1beta1.ClusterTypeSveltos
+	}
+	r.Logger.V(logs.LogDebug).Info(fmt.Sprintf("kubeconfig of cluster %s:%s/%s changed",
+		clusterType, cluster.GetNamespace(), cluster.GetName()))
+	invalidateClusterConnection(cluster.GetNamespace(), cluster.GetName(), clusterType)
+	invalidateRemoteClients(cluster.GetNamespace(), cluster.GetName(), clusterType)
+
+	return append(requests, r.requeueClusterSummaryForACluster(ctx, cluster)...)
+}
+
 // requeueClusterSummaryForCluster is a handler.ToRequestsFunc to be used to enqueue requests for reconciliation
 // for ClusterSummary to update when its own Sveltos Cluster gets updated.
 func (r *ClusterSummaryReconciler) requeueClusterSummaryForSveltosCluster(
@@ -235,3 +266,38 @@ func (r *ClusterSummaryReconciler) requeueClusterSummaryForACluster(
 
 	return requests
 }
+
+// requeueClusterSummaryForMaintenanceWindow is a handler.ToRequestsFunc to be used to enqueue requests for
+// reconciliation for all ClusterSummaries when a MaintenanceWindow changes. A change in the cluster selector
+// can make a cluster not match a window anymore, so all ClusterSummaries are considered.
+func (r *ClusterSummaryReconciler) requeueClusterSummaryForMaintenanceWindow(
+	ctx context.Context, window client.Object,
+) []reconcile.Request {
+
+	logger := r.Logger.WithValues(
+		"objectMapper",
+		"requeueClusterSummaryForMaintenanceWindow",
+		"maintenanceWindow",
+		window.GetName(),
+	)
+
+	logger.V(logs.LogDebug).Info("reacting to MaintenanceWindow change")
+
+	clusterSummaries := &configv1beta1.ClusterSummaryList{}
+	if err := r.List(ctx, clusterSummaries); err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to list ClusterSummaries: %v", err))
+		return nil
+	}
+
+	requests := make([]ctrl.Request, len(clusterSummaries.Items))
+	for i := range clusterSummaries.Items {
+		requests[i] = ctrl.Request{
+			NamespacedName: client.ObjectKey{
+				Namespace: clusterSummaries.Items[i].Namespace,
+				Name:      clusterSummaries.Items[i].Name,
+			},
+		}
+	}
+
+	return requests
+}
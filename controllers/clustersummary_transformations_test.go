@@ -26,6 +26,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -130,4 +131,86 @@ var _ = Describe("ClusterSummaryTransformations map functions", func() {
 		Expect(requests).To(ContainElement(reconcile.Request{NamespacedName: types.NamespacedName{Name: clusterSummary0.Name}}))
 		Expect(requests).To(ContainElement(reconcile.Request{NamespacedName: types.NamespacedName{Name: clusterSummary1.Name}}))
 	})
+
+	It("RequeueClusterSummaryForSecret returns ClusterSummaries for the cluster whose kubeconfig changed", func() {
+		sveltosCluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: namespace,
+			},
+		}
+
+		sveltosKubeconfig := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      sveltosCluster.Name + "-sveltos-kubeconfig",
+				Namespace: namespace,
+			},
+		}
+
+		capiClusterName := randomString()
+		capiKubeconfig := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      capiClusterName + "-kubeconfig",
+				Namespace: namespace,
+				Labels:    map[string]string{clusterv1.ClusterNameLabel: capiClusterName},
+			},
+		}
+
+		otherSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: namespace,
+			},
+		}
+
+		initObjects := []client.Object{
+			sveltosCluster,
+			sveltosKubeconfig,
+			capiKubeconfig,
+			otherSecret,
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+
+		reconciler := &controllers.ClusterSummaryReconciler{
+			Client:       c,
+			Scheme:       scheme,
+			ClusterMap:   make(map[corev1.ObjectReference]*libsveltosset.Set),
+			ReferenceMap: make(map[corev1.ObjectReference]*libsveltosset.Set),
+			PolicyMux:    sync.Mutex{},
+		}
+
+		sveltosClusterSummaryName := randomString()
+		sveltosSet := libsveltosset.Set{}
+		sveltosSet.Insert(&corev1.ObjectReference{APIVersion: configv1beta1.GroupVersion.String(),
+			Kind: configv1beta1.ClusterSummaryKind, Namespace: namespace, Name: sveltosClusterSummaryName})
+		reconciler.ClusterMap[corev1.ObjectReference{APIVersion: libsveltosv1beta1.GroupVersion.String(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, Namespace: namespace, Name: sveltosCluster.Name}] = &sveltosSet
+
+		capiClusterSummaryName := randomString()
+		capiSet := libsveltosset.Set{}
+		capiSet.Insert(&corev1.ObjectReference{APIVersion: configv1beta1.GroupVersion.String(),
+			Kind: configv1beta1.ClusterSummaryKind, Namespace: namespace, Name: capiClusterSummaryName})
+		reconciler.ClusterMap[corev1.ObjectReference{APIVersion: clusterv1.GroupVersion.String(),
+			Kind: "Cluster", Namespace: namespace, Name: capiClusterName}] = &capiSet
+
+		requests := controllers.RequeueClusterSummaryForSecret(reconciler, context.TODO(), sveltosKubeconfig)
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal(sveltosClusterSummaryName))
+
+		requests = controllers.RequeueClusterSummaryForSecret(reconciler, context.TODO(), capiKubeconfig)
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal(capiClusterSummaryName))
+
+		requests = controllers.RequeueClusterSummaryForSecret(reconciler, context.TODO(), otherSecret)
+		Expect(requests).To(BeEmpty())
+
+		// SveltosCluster can store its kubeconfig in a Secret with any name
+		sveltosCluster.Spec.KubeconfigName = otherSecret.Name
+		Expect(c.Update(context.TODO(), sveltosCluster)).To(Succeed())
+
+		requests = controllers.RequeueClusterSummaryForSecret(reconciler, context.TODO(), otherSecret)
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal(sveltosClusterSummaryName))
+	})
 })
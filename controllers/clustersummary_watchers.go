@@ -98,6 +98,13 @@ func initializeManager(l logr.Logger, config *rest.Config, c client.Client) {
 	}
 }
 
+// InitializeWatchers initializes the manager implementing the Watchers. ClusterSummaryReconciler
+// SetupWithManager does it; it needs to be invoked explicitly only when ClusterSummaryReconciler
+// runs without a controller-runtime manager (like in scale tests).
+func InitializeWatchers(l logr.Logger, config *rest.Config, c client.Client) {
+	initializeManager(l, config, c)
+}
+
 // getManager returns the manager instance
 func getManager() *manager {
 	return managerInstance
@@ -0,0 +1,614 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers/chartmanager"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	libsveltostemplate "github.com/projectsveltos/libsveltos/lib/template"
+)
+
+// A ConfigurationSnapshot captures, when created, the management cluster configuration: all
+// ClusterProfiles and Profiles, the ConfigMaps/Secrets they reference and, for each helm release,
+// the ClusterSummary managing it (chartmanager state). The content is stored, compressed, in a
+// Secret in the projectsveltos namespace owned by the ConfigurationSnapshot.
+// Two snapshots can be compared (Spec.CompareTo) and the management cluster configuration can be
+// rolled back to a snapshot (RestoreSnapshotAnnotation). Restoring a snapshot:
+// - creates/updates captured ClusterProfiles, Profiles, ConfigMaps and Secrets;
+// - deletes ClusterProfiles/Profiles created after the snapshot was taken.
+// Chartmanager state is not restored directly, it is rebuilt as ClusterSummaries are reconciled.
+
+const (
+	configurationSnapshotNamePrefix = "sveltos-snapshot-"
+	configurationSnapshotDataKey    = "snapshot.json.gz"
+	configurationSnapshotLabelName  = "projectsveltos.io/configuration-snapshot"
+
+	// configurationSnapshotMaxSize keeps the Secret below the 1MiB etcd object limit
+	configurationSnapshotMaxSize = 900 * 1024
+
+	helmReleaseSnapshotKind = "HelmRelease"
+)
+
+var (
+	errConfigurationSnapshotTooLarge = errors.New("configuration is too large to be captured")
+)
+
+// configurationSnapshotContent is the management cluster configuration captured by a snapshot
+type configurationSnapshotContent struct {
+	ClusterProfiles     []configv1beta1.ClusterProfile `json:"clusterProfiles,omitempty"`
+	Profiles            []configv1beta1.Profile        `json:"profiles,omitempty"`
+	ConfigMaps          []corev1.ConfigMap             `json:"configMaps,omitempty"`
+	Secrets             []corev1.Secret                `json:"secrets,omitempty"`
+	HelmReleaseManagers []helmReleaseManager           `json:"helmReleaseManagers,omitempty"`
+}
+
+// helmReleaseManager is the ClusterSummary managing a helm release in a managed cluster
+type helmReleaseManager struct {
+	// Cluster is in the form clusterType:namespace/name
+	Cluster          string `json:"cluster"`
+	ReleaseNamespace string `json:"releaseNamespace"`
+	ReleaseName      string `json:"releaseName"`
+	ClusterSummary   string `json:"clusterSummary"`
+}
+
+// getConfigurationSnapshotSecretName returns the name of the Secret containing the snapshot content
+func getConfigurationSnapshotSecretName(snapshotName string) string {
+	name := configurationSnapshotNamePrefix + snapshotName
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = fmt.Sprintf("%s%x", configurationSnapshotNamePrefix, sha256.Sum256([]byte(snapshotName)))
+	}
+	return name
+}
+
+// getSnapshotObjectMeta returns the metadata captured for an object
+func getSnapshotObjectMeta(objectMeta *metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:   objectMeta.Namespace,
+		Name:        objectMeta.Name,
+		Labels:      objectMeta.Labels,
+		Annotations: objectMeta.Annotations,
+	}
+}
+
+// captureConfiguration returns the current management cluster configuration
+func captureConfiguration(ctx context.Context, c client.Client, logger logr.Logger,
+) (*configurationSnapshotContent, error) {
+
+	content := &configurationSnapshotContent{}
+	refs := map[referencedResource]bool{}
+
+	clusterProfiles := &configv1beta1.ClusterProfileList{}
+	if err := c.List(ctx, clusterProfiles); err != nil {
+		return nil, err
+	}
+	for i := range clusterProfiles.Items {
+		cp := &clusterProfiles.Items[i]
+		if !cp.DeletionTimestamp.IsZero() {
+			continue
+		}
+		content.ClusterProfiles = append(content.ClusterProfiles, configv1beta1.ClusterProfile{
+			ObjectMeta: getSnapshotObjectMeta(&cp.ObjectMeta),
+			Spec:       cp.Spec,
+		})
+		// References with no namespace are resolved using the cluster namespace, so only the
+		// ones with namespace can be collected here. Others are collected from ClusterSummaries.
+		collectSpecReferencedResources(&cp.Spec, "", nil, refs)
+	}
+
+	profiles := &configv1beta1.ProfileList{}
+	if err := c.List(ctx, profiles); err != nil {
+		return nil, err
+	}
+	for i := range profiles.Items {
+		p := &profiles.Items[i]
+		if !p.DeletionTimestamp.IsZero() {
+			continue
+		}
+		content.Profiles = append(content.Profiles, configv1beta1.Profile{
+			ObjectMeta: getSnapshotObjectMeta(&p.ObjectMeta),
+			Spec:       p.Spec,
+		})
+		collectSpecReferencedResources(&p.Spec, p.Namespace, nil, refs)
+	}
+
+	clusterSummaries := &configv1beta1.ClusterSummaryList{}
+	if err := c.List(ctx, clusterSummaries); err != nil {
+		return nil, err
+	}
+	for i := range clusterSummaries.Items {
+		cs := &clusterSummaries.Items[i]
+		// Names can be templates instantiated using the cluster
+		collectSpecReferencedResources(&cs.Spec.ClusterProfileSpec, cs.Namespace, cs, refs)
+	}
+
+	if err := collectReferencedResourcesContent(ctx, c, refs, content, logger); err != nil {
+		return nil, err
+	}
+
+	managers, err := getHelmReleaseManagers(ctx, c, clusterSummaries.Items)
+	if err != nil {
+		return nil, err
+	}
+	content.HelmReleaseManagers = managers
+
+	sortConfigurationSnapshotContent(content)
+	return content, nil
+}
+
+// collectSpecReferencedResources adds to refs all ConfigMaps/Secrets referenced by spec.
+// When clusterSummary is nil, references whose name is a template are ignored.
+func collectSpecReferencedResources(spec *configv1beta1.Spec, defaultNamespace string,
+	clusterSummary *configv1beta1.ClusterSummary, refs map[referencedResource]bool) {
+
+	add := func(kind, namespace, name string) {
+		if kind != string(libsveltosv1beta1.ConfigMapReferencedResourceKind) &&
+			kind != string(libsveltosv1beta1.SecretReferencedResourceKind) {
+
+			return
+		}
+
+		namespace = libsveltostemplate.GetReferenceResourceNamespace(defaultNamespace, namespace)
+		if namespace == "" {
+			return
+		}
+
+		if clusterSummary != nil {
+			var err error
+			name, err = libsveltostemplate.GetReferenceResourceName(clusterSummary.Spec.ClusterNamespace,
+				clusterSummary.Spec.ClusterName, string(clusterSummary.Spec.ClusterType), name)
+			if err != nil {
+				return
+			}
+		} else if strings.Contains(name, "{{") {
+			return
+		}
+
+		refs[referencedResource{kind: kind, key: types.NamespacedName{Namespace: namespace, Name: name}}] = true
+	}
+
+	for i := range spec.PolicyRefs {
+		add(spec.PolicyRefs[i].Kind, spec.PolicyRefs[i].Namespace, spec.PolicyRefs[i].Name)
+	}
+
+	for i := range spec.KustomizationRefs {
+		kr := &spec.KustomizationRefs[i]
+		add(kr.Kind, kr.Namespace, kr.Name)
+		for j := range kr.ValuesFrom {
+			add(kr.ValuesFrom[j].Kind, kr.ValuesFrom[j].Namespace, kr.ValuesFrom[j].Name)
+		}
+	}
+
+	for i := range spec.HelmCharts {
+		hc := &spec.HelmCharts[i]
+		for j := range hc.ValuesFrom {
+			add(hc.ValuesFrom[j].Kind, hc.ValuesFrom[j].Namespace, hc.ValuesFrom[j].Name)
+		}
+		for _, secret := range getRegistryCredentialsSecrets(defaultNamespace, hc) {
+			add(string(libsveltosv1beta1.SecretReferencedResourceKind), secret.Namespace, secret.Name)
+		}
+	}
+}
+
+// collectReferencedResourcesContent adds to content the referenced ConfigMaps/Secrets.
+// Referenced resources which do not exist are ignored.
+func collectReferencedResourcesContent(ctx context.Context, c client.Client, refs map[referencedResource]bool,
+	content *configurationSnapshotContent, logger logr.Logger) error {
+
+	for ref := range refs {
+		var err error
+		switch ref.kind {
+		case string(libsveltosv1beta1.ConfigMapReferencedResourceKind):
+			configMap := &corev1.ConfigMap{}
+			err = c.Get(ctx, ref.key, configMap)
+			if err == nil {
+				content.ConfigMaps = append(content.ConfigMaps, corev1.ConfigMap{
+					ObjectMeta: getSnapshotObjectMeta(&configMap.ObjectMeta),
+					Data:       configMap.Data,
+					BinaryData: configMap.BinaryData,
+				})
+			}
+		case string(libsveltosv1beta1.SecretReferencedResourceKind):
+			secret := &corev1.Secret{}
+			err = c.Get(ctx, ref.key, secret)
+			if err == nil {
+				content.Secrets = append(content.Secrets, corev1.Secret{
+					ObjectMeta: getSnapshotObjectMeta(&secret.ObjectMeta),
+					Type:       secret.Type,
+					Data:       secret.Data,
+					StringData: secret.StringData,
+				})
+			}
+		}
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				logger.V(logs.LogDebug).Info(fmt.Sprintf("referenced %s %s does not exist", ref.kind, ref.key))
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getHelmReleaseManagers returns, for each helm release, the ClusterSummary currently managing it
+func getHelmReleaseManagers(ctx context.Context, c client.Client,
+	clusterSummaries []configv1beta1.ClusterSummary) ([]helmReleaseManager, error) {
+
+	manager, err := chartmanager.GetChartManagerInstance(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[helmReleaseManager]bool{}
+	var managers []helmReleaseManager
+	for i := range clusterSummaries {
+		cs := &clusterSummaries[i]
+		for j := range cs.Spec.ClusterProfileSpec.HelmCharts {
+			chart := &cs.Spec.ClusterProfileSpec.HelmCharts[j]
+			managerName, err := manager.GetManagerForChart(cs.Spec.ClusterNamespace, cs.Spec.ClusterName,
+				cs.Spec.ClusterType, chart)
+			if err != nil {
+				// No ClusterSummary is managing the release
+				continue
+			}
+
+			m := helmReleaseManager{
+				Cluster: fmt.Sprintf("%s:%s/%s", cs.Spec.ClusterType, cs.Spec.ClusterNamespace,
+					cs.Spec.ClusterName),
+				ReleaseNamespace: chart.ReleaseNamespace,
+				ReleaseName:      chart.ReleaseName,
+				ClusterSummary:   managerName,
+			}
+			if !found[m] {
+				found[m] = true
+				managers = append(managers, m)
+			}
+		}
+	}
+
+	return managers, nil
+}
+
+func sortConfigurationSnapshotContent(content *configurationSnapshotContent) {
+	sort.Slice(content.ClusterProfiles, func(i, j int) bool {
+		return content.ClusterProfiles[i].Name < content.ClusterProfiles[j].Name
+	})
+	sort.Slice(content.Profiles, func(i, j int) bool {
+		return getSnapshotKey(&content.Profiles[i].ObjectMeta) < getSnapshotKey(&content.Profiles[j].ObjectMeta)
+	})
+	sort.Slice(content.ConfigMaps, func(i, j int) bool {
+		return getSnapshotKey(&content.ConfigMaps[i].ObjectMeta) < getSnapshotKey(&content.ConfigMaps[j].ObjectMeta)
+	})
+	sort.Slice(content.Secrets, func(i, j int) bool {
+		return getSnapshotKey(&content.Secrets[i].ObjectMeta) < getSnapshotKey(&content.Secrets[j].ObjectMeta)
+	})
+	sort.Slice(content.HelmReleaseManagers, func(i, j int) bool {
+		mi := &content.HelmReleaseManagers[i]
+		mj := &content.HelmReleaseManagers[j]
+		if mi.Cluster != mj.Cluster {
+			return mi.Cluster < mj.Cluster
+		}
+		if mi.ReleaseNamespace != mj.ReleaseNamespace {
+			return mi.ReleaseNamespace < mj.ReleaseNamespace
+		}
+		return mi.ReleaseName < mj.ReleaseName
+	})
+}
+
+func getSnapshotKey(objectMeta *metav1.ObjectMeta) string {
+	return objectMeta.Namespace + "/" + objectMeta.Name
+}
+
+// storeConfigurationSnapshot stores content, compressed, in the Secret owned by snapshot.
+// Returns the Secret name.
+func storeConfigurationSnapshot(ctx context.Context, c client.Client, snapshot *configv1beta1.ConfigurationSnapshot,
+	content *configurationSnapshotContent) (string, error) {
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	if buf.Len() > configurationSnapshotMaxSize {
+		return "", fmt.Errorf("%w: %d bytes compressed (max %d)", errConfigurationSnapshotTooLarge,
+			buf.Len(), configurationSnapshotMaxSize)
+	}
+
+	name := getConfigurationSnapshotSecretName(snapshot.Name)
+	secret := &corev1.Secret{}
+	err = c.Get(ctx, types.NamespacedName{Namespace: projectsveltos, Name: name}, secret)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: projectsveltos,
+				Name:      name,
+				Labels:    map[string]string{configurationSnapshotLabelName: snapshot.Name},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ConfigurationSnapshotKind,
+						Name:       snapshot.Name,
+						UID:        snapshot.UID,
+					},
+				},
+			},
+			Data: map[string][]byte{configurationSnapshotDataKey: buf.Bytes()},
+		}
+		return name, c.Create(ctx, secret)
+	}
+
+	secret.Data = map[string][]byte{configurationSnapshotDataKey: buf.Bytes()}
+	return name, c.Update(ctx, secret)
+}
+
+// loadConfigurationSnapshot returns the content captured by snapshot
+func loadConfigurationSnapshot(ctx context.Context, c client.Client, snapshot *configv1beta1.ConfigurationSnapshot,
+) (*configurationSnapshotContent, error) {
+
+	if snapshot.Status.CaptureTime == nil || snapshot.Status.SecretName == "" {
+		return nil, fmt.Errorf("ConfigurationSnapshot %s has not been captured yet", snapshot.Name)
+	}
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: projectsveltos, Name: snapshot.Status.SecretName}, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(secret.Data[configurationSnapshotDataKey]))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &configurationSnapshotContent{}
+	if err := json.Unmarshal(data, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// getConfigurationSnapshotEntries returns all objects in content. Key identifies the object, value is
+// the object captured content.
+func getConfigurationSnapshotEntries(content *configurationSnapshotContent,
+) (map[string]configv1beta1.SnapshotDifference, map[string]string) {
+
+	objects := map[string]configv1beta1.SnapshotDifference{}
+	values := map[string]string{}
+
+	add := func(object configv1beta1.SnapshotDifference, value interface{}) {
+		key := strings.Join([]string{object.Kind, object.Cluster, object.Namespace, object.Name}, "|")
+		// json.Marshal sorts map keys, so same content always has same value
+		data, _ := json.Marshal(value)
+		objects[key] = object
+		values[key] = string(data)
+	}
+
+	for i := range content.ClusterProfiles {
+		cp := &content.ClusterProfiles[i]
+		add(configv1beta1.SnapshotDifference{Kind: configv1beta1.ClusterProfileKind, Name: cp.Name}, cp)
+	}
+	for i := range content.Profiles {
+		p := &content.Profiles[i]
+		add(configv1beta1.SnapshotDifference{Kind: configv1beta1.ProfileKind, Namespace: p.Namespace,
+			Name: p.Name}, p)
+	}
+	for i := range content.ConfigMaps {
+		cm := &content.ConfigMaps[i]
+		add(configv1beta1.SnapshotDifference{Kind: string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+			Namespace: cm.Namespace, Name: cm.Name}, cm)
+	}
+	for i := range content.Secrets {
+		s := &content.Secrets[i]
+		add(configv1beta1.SnapshotDifference{Kind: string(libsveltosv1beta1.SecretReferencedResourceKind),
+			Namespace: s.Namespace, Name: s.Name}, s)
+	}
+	for i := range content.HelmReleaseManagers {
+		m := &content.HelmReleaseManagers[i]
+		add(configv1beta1.SnapshotDifference{Kind: helmReleaseSnapshotKind, Cluster: m.Cluster,
+			Namespace: m.ReleaseNamespace, Name: m.ReleaseName}, m.ClusterSummary)
+	}
+
+	return objects, values
+}
+
+// diffConfigurationSnapshots returns the objects which differ between older and newer
+func diffConfigurationSnapshots(older, newer *configurationSnapshotContent) []configv1beta1.SnapshotDifference {
+	olderObjects, olderValues := getConfigurationSnapshotEntries(older)
+	newerObjects, newerValues := getConfigurationSnapshotEntries(newer)
+
+	var differences []configv1beta1.SnapshotDifference
+	for key, object := range newerObjects {
+		olderValue, ok := olderValues[key]
+		switch {
+		case !ok:
+			object.Change = configv1beta1.SnapshotChangeAdded
+		case olderValue != newerValues[key]:
+			object.Change = configv1beta1.SnapshotChangeModified
+		default:
+			continue
+		}
+		differences = append(differences, object)
+	}
+	for key, object := range olderObjects {
+		if _, ok := newerObjects[key]; !ok {
+			object.Change = configv1beta1.SnapshotChangeRemoved
+			differences = append(differences, object)
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		di := &differences[i]
+		dj := &differences[j]
+		if di.Kind != dj.Kind {
+			return di.Kind < dj.Kind
+		}
+		if di.Cluster != dj.Cluster {
+			return di.Cluster < dj.Cluster
+		}
+		if di.Namespace != dj.Namespace {
+			return di.Namespace < dj.Namespace
+		}
+		return di.Name < dj.Name
+	})
+
+	return differences
+}
+
+// restoreConfiguration rolls the management cluster configuration back to content
+func restoreConfiguration(ctx context.Context, c client.Client, content *configurationSnapshotContent,
+	logger logr.Logger) error {
+
+	// ConfigMaps/Secrets first, so restored ClusterProfiles/Profiles find the content they reference
+	for i := range content.ConfigMaps {
+		configMap := &content.ConfigMaps[i]
+		err := restoreObject(ctx, c, configMap.DeepCopy(), &corev1.ConfigMap{},
+			func(current *corev1.ConfigMap) {
+				current.Data = configMap.Data
+				current.BinaryData = configMap.BinaryData
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range content.Secrets {
+		secret := &content.Secrets[i]
+		err := restoreObject(ctx, c, secret.DeepCopy(), &corev1.Secret{},
+			func(current *corev1.Secret) {
+				current.Data = secret.Data
+				current.StringData = secret.StringData
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	captured := map[string]bool{}
+	for i := range content.ClusterProfiles {
+		cp := &content.ClusterProfiles[i]
+		captured[getSnapshotKey(&cp.ObjectMeta)] = true
+		err := restoreObject(ctx, c, cp.DeepCopy(), &configv1beta1.ClusterProfile{},
+			func(current *configv1beta1.ClusterProfile) {
+				current.Spec = *cp.Spec.DeepCopy()
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	clusterProfiles := &configv1beta1.ClusterProfileList{}
+	if err := c.List(ctx, clusterProfiles); err != nil {
+		return err
+	}
+	for i := range clusterProfiles.Items {
+		cp := &clusterProfiles.Items[i]
+		if !captured[getSnapshotKey(&cp.ObjectMeta)] {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("deleting ClusterProfile %s not in snapshot", cp.Name))
+			if err := c.Delete(ctx, cp); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	captured = map[string]bool{}
+	for i := range content.Profiles {
+		p := &content.Profiles[i]
+		captured[getSnapshotKey(&p.ObjectMeta)] = true
+		err := restoreObject(ctx, c, p.DeepCopy(), &configv1beta1.Profile{},
+			func(current *configv1beta1.Profile) {
+				current.Spec = *p.Spec.DeepCopy()
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	profiles := &configv1beta1.ProfileList{}
+	if err := c.List(ctx, profiles); err != nil {
+		return err
+	}
+	for i := range profiles.Items {
+		p := &profiles.Items[i]
+		if !captured[getSnapshotKey(&p.ObjectMeta)] {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("deleting Profile %s/%s not in snapshot", p.Namespace, p.Name))
+			if err := c.Delete(ctx, p); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// restoreObject creates captured if it does not exist. Otherwise labels, annotations and, via
+// setContent, content of the existing object are set to the captured ones.
+func restoreObject[T client.Object](ctx context.Context, c client.Client, captured, current T,
+	setContent func(current T)) error {
+
+	err := c.Get(ctx, client.ObjectKeyFromObject(captured), current)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return c.Create(ctx, captured)
+		}
+		return err
+	}
+
+	current.SetLabels(captured.GetLabels())
+	current.SetAnnotations(captured.GetAnnotations())
+	setContent(current)
+	return c.Update(ctx, current)
+}
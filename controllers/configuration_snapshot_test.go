@@ -0,0 +1,195 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Configuration snapshot", func() {
+	var namespace string
+	var configMap *corev1.ConfigMap
+	var clusterProfile *configv1beta1.ClusterProfile
+	var profile *configv1beta1.Profile
+
+	BeforeEach(func() {
+		namespace = randomString()
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Data:       map[string]string{"policy": randomString()},
+		}
+
+		clusterProfile = &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+			Spec: configv1beta1.Spec{
+				PolicyRefs: []configv1beta1.PolicyRef{
+					{
+						Namespace: configMap.Namespace,
+						Name:      configMap.Name,
+						Kind:      string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+					},
+				},
+			},
+		}
+
+		profile = &configv1beta1.Profile{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Spec: configv1beta1.Spec{
+				SyncMode: configv1beta1.SyncModeContinuous,
+			},
+		}
+	})
+
+	It("captureConfiguration captures profiles and referenced ConfigMaps/Secrets", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "credentials-cluster1"},
+			Type:       libsveltosv1beta1.ClusterProfileSecretType,
+			Data:       map[string][]byte{"token": []byte(randomString())},
+		}
+		unreferenced := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+		}
+		profile.Spec.PolicyRefs = []configv1beta1.PolicyRef{
+			{
+				Name: "credentials-{{ .Cluster.metadata.name }}",
+				Kind: string(libsveltosv1beta1.SecretReferencedResourceKind),
+			},
+		}
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace:   namespace,
+				ClusterName:        "cluster1",
+				ClusterType:        libsveltosv1beta1.ClusterTypeCapi,
+				ClusterProfileSpec: profile.Spec,
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap, secret, unreferenced,
+			clusterProfile, profile, clusterSummary).Build()
+
+		content, err := controllers.CaptureConfiguration(context.TODO(), c, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(len(content.ClusterProfiles)).To(Equal(1))
+		Expect(content.ClusterProfiles[0].Name).To(Equal(clusterProfile.Name))
+		Expect(content.ClusterProfiles[0].ResourceVersion).To(BeEmpty())
+		Expect(len(content.Profiles)).To(Equal(1))
+		Expect(content.Profiles[0].Spec.SyncMode).To(Equal(configv1beta1.SyncModeContinuous))
+		Expect(len(content.ConfigMaps)).To(Equal(1))
+		Expect(content.ConfigMaps[0].Data).To(Equal(configMap.Data))
+		// Secret name is instantiated using the cluster matched by the ClusterSummary
+		Expect(len(content.Secrets)).To(Equal(1))
+		Expect(content.Secrets[0].Name).To(Equal(secret.Name))
+	})
+
+	It("storeConfigurationSnapshot and loadConfigurationSnapshot round trip", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap, clusterProfile).Build()
+
+		content, err := controllers.CaptureConfiguration(context.TODO(), c, logr.Discard())
+		Expect(err).To(BeNil())
+
+		snapshot := &configv1beta1.ConfigurationSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+		}
+		secretName, err := controllers.StoreConfigurationSnapshot(context.TODO(), c, snapshot, content)
+		Expect(err).To(BeNil())
+
+		// Snapshot is not captured yet
+		_, err = controllers.LoadConfigurationSnapshot(context.TODO(), c, snapshot)
+		Expect(err).ToNot(BeNil())
+
+		snapshot.Status.CaptureTime = &metav1.Time{}
+		snapshot.Status.SecretName = secretName
+		loaded, err := controllers.LoadConfigurationSnapshot(context.TODO(), c, snapshot)
+		Expect(err).To(BeNil())
+		Expect(loaded).To(Equal(content))
+	})
+
+	It("diffConfigurationSnapshots reports added, removed and modified objects", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap, clusterProfile).Build()
+		older, err := controllers.CaptureConfiguration(context.TODO(), c, logr.Discard())
+		Expect(err).To(BeNil())
+
+		currentConfigMap := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(configMap), currentConfigMap)).To(Succeed())
+		currentConfigMap.Data = map[string]string{"policy": randomString()}
+		Expect(c.Update(context.TODO(), currentConfigMap)).To(Succeed())
+		Expect(c.Create(context.TODO(), profile)).To(Succeed())
+
+		newer, err := controllers.CaptureConfiguration(context.TODO(), c, logr.Discard())
+		Expect(err).To(BeNil())
+
+		Expect(controllers.DiffConfigurationSnapshots(older, older)).To(BeEmpty())
+		Expect(controllers.DiffConfigurationSnapshots(older, newer)).To(Equal([]configv1beta1.SnapshotDifference{
+			{
+				Kind: string(libsveltosv1beta1.ConfigMapReferencedResourceKind), Namespace: configMap.Namespace,
+				Name: configMap.Name, Change: configv1beta1.SnapshotChangeModified,
+			},
+			{
+				Kind: configv1beta1.ProfileKind, Namespace: profile.Namespace, Name: profile.Name,
+				Change: configv1beta1.SnapshotChangeAdded,
+			},
+		}))
+
+		differences := controllers.DiffConfigurationSnapshots(newer, older)
+		Expect(len(differences)).To(Equal(2))
+		Expect(differences[1].Change).To(Equal(configv1beta1.SnapshotChangeRemoved))
+	})
+
+	It("restoreConfiguration rolls the configuration back to the snapshot", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap, clusterProfile).Build()
+		content, err := controllers.CaptureConfiguration(context.TODO(), c, logr.Discard())
+		Expect(err).To(BeNil())
+
+		// Modify ConfigMap, delete the ClusterProfile and create a Profile
+		currentConfigMap := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(configMap), currentConfigMap)).To(Succeed())
+		currentConfigMap.Data = map[string]string{"policy": randomString()}
+		Expect(c.Update(context.TODO(), currentConfigMap)).To(Succeed())
+		Expect(c.Delete(context.TODO(), clusterProfile)).To(Succeed())
+		Expect(c.Create(context.TODO(), profile)).To(Succeed())
+
+		Expect(controllers.RestoreConfiguration(context.TODO(), c, content, logr.Discard())).To(Succeed())
+
+		Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(configMap), currentConfigMap)).To(Succeed())
+		Expect(currentConfigMap.Data).To(Equal(configMap.Data))
+
+		currentClusterProfile := &configv1beta1.ClusterProfile{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: clusterProfile.Name},
+			currentClusterProfile)).To(Succeed())
+		Expect(currentClusterProfile.Spec.PolicyRefs).To(Equal(clusterProfile.Spec.PolicyRefs))
+
+		err = c.Get(context.TODO(), client.ObjectKeyFromObject(profile), &configv1beta1.Profile{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
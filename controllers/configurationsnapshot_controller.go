@@ -0,0 +1,210 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// ConfigurationSnapshotReconciler reconciles a ConfigurationSnapshot object
+type ConfigurationSnapshotReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Logger logr.Logger
+}
+
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=configurationsnapshots,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=configurationsnapshots/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterprofiles,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=profiles,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clustersummaries,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+
+func (r *ConfigurationSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.V(logs.LogInfo).Info("Reconciling")
+
+	snapshot := &configv1beta1.ConfigurationSnapshot{}
+	if err := r.Get(ctx, req.NamespacedName, snapshot); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		logger.Error(err, "Failed to fetch ConfigurationSnapshot")
+		return reconcile.Result{}, fmt.Errorf("failed to fetch ConfigurationSnapshot %s: %w",
+			req.NamespacedName, err)
+	}
+
+	// The Secret with the snapshot content is owned by the ConfigurationSnapshot and
+	// garbage collected with it
+	if !snapshot.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	return r.reconcileNormal(ctx, snapshot, logger)
+}
+
+func (r *ConfigurationSnapshotReconciler) reconcileNormal(ctx context.Context,
+	snapshot *configv1beta1.ConfigurationSnapshot, logger logr.Logger) (ctrl.Result, error) {
+
+	original := snapshot.DeepCopy()
+
+	err := r.captureIfNeeded(ctx, snapshot, logger)
+	if err == nil {
+		err = r.compareIfNeeded(ctx, snapshot)
+	}
+
+	_, restoreRequested := snapshot.Annotations[configv1beta1.RestoreSnapshotAnnotation]
+	restored := false
+	if err == nil && restoreRequested {
+		err = r.restore(ctx, snapshot, logger)
+		restored = err == nil
+	}
+
+	if err != nil {
+		failureMessage := err.Error()
+		snapshot.Status.FailureMessage = &failureMessage
+	} else {
+		snapshot.Status.FailureMessage = nil
+	}
+
+	if patchErr := r.Status().Patch(ctx, snapshot, client.MergeFrom(original)); patchErr != nil {
+		return reconcile.Result{}, patchErr
+	}
+
+	if restored {
+		current := snapshot.DeepCopy()
+		delete(snapshot.Annotations, configv1beta1.RestoreSnapshotAnnotation)
+		if patchErr := r.Patch(ctx, snapshot, client.MergeFrom(current)); patchErr != nil {
+			return reconcile.Result{}, patchErr
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, errConfigurationSnapshotTooLarge) {
+			// Retrying would not help
+			logger.V(logs.LogInfo).Info(err.Error())
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	logger.V(logs.LogInfo).Info("Reconcile success")
+	return reconcile.Result{}, nil
+}
+
+// captureIfNeeded captures the management cluster configuration, unless already captured
+func (r *ConfigurationSnapshotReconciler) captureIfNeeded(ctx context.Context,
+	snapshot *configv1beta1.ConfigurationSnapshot, logger logr.Logger) error {
+
+	if snapshot.Status.CaptureTime != nil {
+		return nil
+	}
+
+	logger.V(logs.LogInfo).Info("capturing management cluster configuration")
+	content, err := captureConfiguration(ctx, r.Client, logger)
+	if err != nil {
+		return err
+	}
+
+	secretName, err := storeConfigurationSnapshot(ctx, r.Client, snapshot, content)
+	if err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	snapshot.Status.CaptureTime = &now
+	snapshot.Status.SecretName = secretName
+	snapshot.Status.ObjectCount = int32(len(content.ClusterProfiles) + len(content.Profiles) +
+		len(content.ConfigMaps) + len(content.Secrets))
+	return nil
+}
+
+// compareIfNeeded reports the differences between the snapshot in Spec.CompareTo and this one.
+// Snapshot content never changes, so differences are computed only when CompareTo changes.
+func (r *ConfigurationSnapshotReconciler) compareIfNeeded(ctx context.Context,
+	snapshot *configv1beta1.ConfigurationSnapshot) error {
+
+	if snapshot.Spec.CompareTo == "" {
+		snapshot.Status.ComparedTo = ""
+		snapshot.Status.Differences = nil
+		return nil
+	}
+
+	if snapshot.Spec.CompareTo == snapshot.Status.ComparedTo {
+		return nil
+	}
+
+	older := &configv1beta1.ConfigurationSnapshot{}
+	if err := r.Get(ctx, types.NamespacedName{Name: snapshot.Spec.CompareTo}, older); err != nil {
+		return err
+	}
+
+	olderContent, err := loadConfigurationSnapshot(ctx, r.Client, older)
+	if err != nil {
+		return err
+	}
+
+	content, err := loadConfigurationSnapshot(ctx, r.Client, snapshot)
+	if err != nil {
+		return err
+	}
+
+	snapshot.Status.ComparedTo = snapshot.Spec.CompareTo
+	snapshot.Status.Differences = diffConfigurationSnapshots(olderContent, content)
+	return nil
+}
+
+// restore rolls the management cluster configuration back to this snapshot
+func (r *ConfigurationSnapshotReconciler) restore(ctx context.Context,
+	snapshot *configv1beta1.ConfigurationSnapshot, logger logr.Logger) error {
+
+	content, err := loadConfigurationSnapshot(ctx, r.Client, snapshot)
+	if err != nil {
+		return err
+	}
+
+	logger.V(logs.LogInfo).Info("restoring management cluster configuration")
+	if err := restoreConfiguration(ctx, r.Client, content, logger); err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	snapshot.Status.LastRestoreTime = &now
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ConfigurationSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&configv1beta1.ConfigurationSnapshot{}).
+		Complete(r)
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Users embedding this controller can register ConflictResolvers. Resolvers are invoked every time
+// two ClusterProfiles/Profiles try to manage the same helm release (chartmanager) or the same
+// Kubernetes resource in a managed cluster. A resolver can simply be notified (for instance to page
+// a human or open a ticket) or can decide who manages the helm release/resource (for instance to
+// assign it using custom tiers).
+
+// ConflictType indicates what two ClusterProfiles/Profiles are trying to manage
+type ConflictType string
+
+const (
+	// HelmReleaseConflict is a conflict on a helm release
+	HelmReleaseConflict = ConflictType("HelmRelease")
+
+	// ResourceConflict is a conflict on a Kubernetes resource
+	ResourceConflict = ConflictType("Resource")
+)
+
+// Conflict describes two ClusterProfiles/Profiles trying to manage the same helm release or
+// Kubernetes resource in a managed cluster
+type Conflict struct {
+	Type ConflictType
+
+	// Cluster is the managed cluster
+	ClusterNamespace string
+	ClusterName      string
+	ClusterType      libsveltosv1beta1.ClusterType
+
+	// Object is the helm release (only Namespace and Name are set) or the Kubernetes resource
+	Object corev1.ObjectReference
+
+	// CurrentOwner is the ClusterProfile/Profile currently managing Object
+	CurrentOwner     corev1.ObjectReference
+	CurrentOwnerTier int32
+
+	// Claimant is the ClusterProfile/Profile trying to manage Object
+	Claimant     corev1.ObjectReference
+	ClaimantTier int32
+
+	// ConflictPolicy is the claimant ConflictPolicy. Empty for helm releases.
+	ConflictPolicy configv1beta1.ConflictPolicy
+}
+
+// ConflictDecision is how a ConflictResolver resolves a conflict
+type ConflictDecision int
+
+const (
+	// ConflictDecisionDefault lets Sveltos resolve the conflict (using tiers)
+	ConflictDecisionDefault ConflictDecision = iota
+
+	// ConflictDecisionKeepOwner keeps the current owner in charge
+	ConflictDecisionKeepOwner
+
+	// ConflictDecisionTransfer gives ownership to the claimant
+	ConflictDecisionTransfer
+)
+
+// ConflictResolver is invoked when a conflict is detected.
+// Resolvers must be fast, as they are invoked while deploying add-ons. Errors are logged and
+// considered as ConflictDecisionDefault.
+type ConflictResolver interface {
+	ResolveConflict(ctx context.Context, conflict *Conflict) (ConflictDecision, error)
+}
+
+// ConflictResolverFunc is a function implementing ConflictResolver
+type ConflictResolverFunc func(ctx context.Context, conflict *Conflict) (ConflictDecision, error)
+
+// ResolveConflict calls f(ctx, conflict)
+func (f ConflictResolverFunc) ResolveConflict(ctx context.Context, conflict *Conflict) (ConflictDecision, error) {
+	return f(ctx, conflict)
+}
+
+var (
+	conflictResolversMux sync.RWMutex
+	conflictResolvers    []ConflictResolver
+)
+
+// RegisterConflictResolver registers a ConflictResolver. It must be called before the manager is
+// started. All registered resolvers are invoked, in registration order, for every conflict; the
+// first decision other than ConflictDecisionDefault is applied.
+// ConflictPolicy Force and Fail always take precedence: resolvers are notified but their decision
+// is ignored.
+func RegisterConflictResolver(resolver ConflictResolver) {
+	conflictResolversMux.Lock()
+	defer conflictResolversMux.Unlock()
+	conflictResolvers = append(conflictResolvers, resolver)
+}
+
+// ResetConflictResolvers removes all registered ConflictResolvers
+func ResetConflictResolvers() {
+	conflictResolversMux.Lock()
+	defer conflictResolversMux.Unlock()
+	conflictResolvers = nil
+}
+
+// resolveConflict invokes the registered ConflictResolvers and returns true if the claimant must
+// take ownership. claimantWins is the decision Sveltos takes on its own.
+func resolveConflict(ctx context.Context, conflict *Conflict, claimantWins bool, logger logr.Logger) bool {
+	conflictResolversMux.RLock()
+	resolvers := conflictResolvers
+	conflictResolversMux.RUnlock()
+
+	decision := ConflictDecisionDefault
+	for i := range resolvers {
+		current, err := resolvers[i].ResolveConflict(ctx, conflict)
+		if err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("conflict resolver failed: %v", err))
+			continue
+		}
+		if decision == ConflictDecisionDefault {
+			decision = current
+		}
+	}
+
+	if conflict.ConflictPolicy == configv1beta1.ConflictPolicyForce ||
+		conflict.ConflictPolicy == configv1beta1.ConflictPolicyFail {
+
+		return claimantWins
+	}
+
+	switch decision {
+	case ConflictDecisionTransfer:
+		logger.V(logs.LogDebug).Info("conflict resolved by resolver in favor of claimant")
+		return true
+	case ConflictDecisionKeepOwner:
+		logger.V(logs.LogDebug).Info("conflict resolved by resolver in favor of current owner")
+		return false
+	default:
+		return claimantWins
+	}
+}
+
+// getConflictProfileReference returns the reference to a ClusterProfile/Profile given its kind and
+// the name used in owner references (namespace/name for Profiles)
+func getConflictProfileReference(kind, ownerReferenceName string) corev1.ObjectReference {
+	name := getProfileNameFromOwnerReferenceName(ownerReferenceName)
+	return corev1.ObjectReference{
+		APIVersion: configv1beta1.GroupVersion.String(),
+		Kind:       kind,
+		Namespace:  name.Namespace,
+		Name:       name.Name,
+	}
+}
+
+// getClusterSummaryProfileReference returns the reference to the ClusterProfile/Profile owning
+// clusterSummary
+func getClusterSummaryProfileReference(clusterSummary *configv1beta1.ClusterSummary) corev1.ObjectReference {
+	ref, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
+	if err != nil {
+		return corev1.ObjectReference{}
+	}
+
+	profileRef := corev1.ObjectReference{
+		APIVersion: ref.APIVersion,
+		Kind:       ref.Kind,
+		Name:       ref.Name,
+	}
+	if ref.Kind == configv1beta1.ProfileKind {
+		profileRef.Namespace = clusterSummary.Namespace
+	}
+	return profileRef
+}
+
+// getResourceOwnerReference returns the ClusterProfile/Profile among the owners of a deployed resource
+func getResourceOwnerReference(ownerReferences []corev1.ObjectReference) corev1.ObjectReference {
+	for i := range ownerReferences {
+		kind := ownerReferences[i].Kind
+		if kind == configv1beta1.ClusterProfileKind || kind == configv1beta1.ProfileKind {
+			return getConflictProfileReference(kind, ownerReferences[i].Name)
+		}
+	}
+	return corev1.ObjectReference{}
+}
+
+// getHelmReleaseConflict returns the Conflict for a helm release managed by currentManager and
+// claimed by claimingManager
+func getHelmReleaseConflict(claimingManager, currentManager *configv1beta1.ClusterSummary,
+	currentChart *configv1beta1.HelmChart) *Conflict {
+
+	return &Conflict{
+		Type:             HelmReleaseConflict,
+		ClusterNamespace: claimingManager.Spec.ClusterNamespace,
+		ClusterName:      claimingManager.Spec.ClusterName,
+		ClusterType:      claimingManager.Spec.ClusterType,
+		Object: corev1.ObjectReference{
+			Namespace: currentChart.ReleaseNamespace,
+			Name:      currentChart.ReleaseName,
+		},
+		CurrentOwner:     getClusterSummaryProfileReference(currentManager),
+		CurrentOwnerTier: currentManager.Spec.ClusterProfileSpec.Tier,
+		Claimant:         getClusterSummaryProfileReference(claimingManager),
+		ClaimantTier:     claimingManager.Spec.ClusterProfileSpec.Tier,
+	}
+}
+
+// getResourceConflict returns the Conflict for a resource owned by resourceInfo owners and
+// claimed by profile
+func getResourceConflict(clusterSummary *configv1beta1.ClusterSummary, policy *unstructured.Unstructured,
+	resourceInfo *deployer.ResourceInfo, profile client.Object, profileTier int32,
+	conflictPolicy configv1beta1.ConflictPolicy) *Conflict {
+
+	conflict := &Conflict{
+		Type:             ResourceConflict,
+		ClusterNamespace: clusterSummary.Spec.ClusterNamespace,
+		ClusterName:      clusterSummary.Spec.ClusterName,
+		ClusterType:      clusterSummary.Spec.ClusterType,
+		Object: corev1.ObjectReference{
+			APIVersion: policy.GetAPIVersion(),
+			Kind:       policy.GetKind(),
+			Namespace:  policy.GetNamespace(),
+			Name:       policy.GetName(),
+		},
+		Claimant:       getConflictProfileReference(profile.GetObjectKind().GroupVersionKind().Kind, profile.GetName()),
+		ClaimantTier:   profileTier,
+		ConflictPolicy: conflictPolicy,
+	}
+
+	if resourceInfo != nil {
+		conflict.CurrentOwner = getResourceOwnerReference(resourceInfo.OwnerReferences)
+		conflict.CurrentOwnerTier = getTier(resourceInfo.OwnerTier)
+	}
+
+	return conflict
+}
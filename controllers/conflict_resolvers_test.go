@@ -0,0 +1,131 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Conflict resolvers", func() {
+	var conflict *controllers.Conflict
+	var claimant *configv1beta1.ClusterSummary
+	var owner *configv1beta1.ClusterSummary
+
+	getClusterSummary := func(profileName string, tier int32) *configv1beta1.ClusterSummary {
+		return &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       profileName,
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: "cluster-ns",
+				ClusterName:      "cluster",
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+				ClusterProfileSpec: configv1beta1.Spec{
+					Tier: tier,
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		owner = getClusterSummary(randomString(), 100)
+		claimant = getClusterSummary(randomString(), 50)
+		conflict = controllers.GetHelmReleaseConflict(claimant, owner, &configv1beta1.HelmChart{
+			ReleaseNamespace: randomString(),
+			ReleaseName:      randomString(),
+		})
+	})
+
+	AfterEach(func() {
+		controllers.ResetConflictResolvers()
+	})
+
+	It("getHelmReleaseConflict describes the conflict", func() {
+		Expect(conflict.Type).To(Equal(controllers.HelmReleaseConflict))
+		Expect(conflict.ClusterName).To(Equal("cluster"))
+		Expect(conflict.CurrentOwner.Kind).To(Equal(configv1beta1.ClusterProfileKind))
+		Expect(conflict.CurrentOwner.Name).To(Equal(owner.OwnerReferences[0].Name))
+		Expect(conflict.CurrentOwnerTier).To(Equal(int32(100)))
+		Expect(conflict.Claimant.Name).To(Equal(claimant.OwnerReferences[0].Name))
+		Expect(conflict.ClaimantTier).To(Equal(int32(50)))
+	})
+
+	It("resolveConflict uses default decision when no resolver is registered", func() {
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		Expect(controllers.ResolveConflict(context.TODO(), conflict, true, logger)).To(BeTrue())
+		Expect(controllers.ResolveConflict(context.TODO(), conflict, false, logger)).To(BeFalse())
+	})
+
+	It("resolveConflict applies first non default decision and invokes all resolvers", func() {
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		invoked := 0
+		register := func(decision controllers.ConflictDecision, err error) {
+			controllers.RegisterConflictResolver(controllers.ConflictResolverFunc(
+				func(_ context.Context, c *controllers.Conflict) (controllers.ConflictDecision, error) {
+					Expect(c).To(Equal(conflict))
+					invoked++
+					return decision, err
+				}))
+		}
+
+		register(controllers.ConflictDecisionTransfer, errors.New("failed"))
+		register(controllers.ConflictDecisionDefault, nil)
+		register(controllers.ConflictDecisionKeepOwner, nil)
+		register(controllers.ConflictDecisionTransfer, nil)
+
+		Expect(controllers.ResolveConflict(context.TODO(), conflict, true, logger)).To(BeFalse())
+		Expect(invoked).To(Equal(4))
+	})
+
+	It("resolveConflict ignores resolvers decision when ConflictPolicy is Force or Fail", func() {
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		invoked := 0
+		controllers.RegisterConflictResolver(controllers.ConflictResolverFunc(
+			func(_ context.Context, _ *controllers.Conflict) (controllers.ConflictDecision, error) {
+				invoked++
+				return controllers.ConflictDecisionKeepOwner, nil
+			}))
+
+		conflict.ConflictPolicy = configv1beta1.ConflictPolicyForce
+		Expect(controllers.ResolveConflict(context.TODO(), conflict, true, logger)).To(BeTrue())
+
+		conflict.ConflictPolicy = configv1beta1.ConflictPolicyAdopt
+		Expect(controllers.ResolveConflict(context.TODO(), conflict, true, logger)).To(BeFalse())
+		Expect(invoked).To(Equal(2))
+	})
+})
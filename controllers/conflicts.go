@@ -24,8 +24,8 @@ import (
 	"github.com/go-logr/logr"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/clusterdeployer"
 	"github.com/projectsveltos/addon-controller/pkg/scope"
-	"github.com/projectsveltos/libsveltos/lib/deployer"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 )
 
@@ -82,7 +82,7 @@ func requeueOldOwner(ctx context.Context, featureID configv1beta1.FeatureID, clu
 
 	// TODO: change deployer to have an initialize method and a GetDeployer
 	// At this point deployer has been already initialized, so the argurment of GetClient are not important
-	d := deployer.GetClient(ctx, logger, c, 1)
+	d := clusterdeployer.GetClient(ctx, logger, c, clusterdeployer.Options{})
 
 	if d.IsInProgress(clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
 		clusterSummary.Name, string(featureID), clusterSummary.Spec.ClusterType, false) {
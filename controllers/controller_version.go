@@ -0,0 +1,80 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// A ClusterProfile/Profile using fields introduced in a newer controller release can set
+// Spec.MinControllerVersion. An older controller does not deploy such a profile (fields unknown to
+// its CRD schema would be silently dropped) and reports it with the ControllerVersionSupported
+// condition. ClusterSummaries are paused.
+
+// isControllerVersionSupported returns true if this controller satisfies minVersion. Otherwise a message
+// explaining why is returned as well.
+// Controllers whose version is not a semantic version (development builds) satisfy any minVersion.
+func isControllerVersionSupported(minVersion string) (supported bool, message string) {
+	if minVersion == "" {
+		return true, ""
+	}
+
+	required, err := semver.NewVersion(minVersion)
+	if err != nil {
+		return false, fmt.Sprintf("invalid MinControllerVersion %s: %v", minVersion, err)
+	}
+
+	current, err := semver.NewVersion(getVersion())
+	if err != nil {
+		return true, ""
+	}
+
+	if current.LessThan(required) {
+		return false, fmt.Sprintf("controller version %s is older than MinControllerVersion %s",
+			getVersion(), minVersion)
+	}
+
+	return true, ""
+}
+
+// reviseControllerVersionSupported updates the ControllerVersionSupported condition and returns
+// whether the ClusterProfile/Profile can be deployed by this controller.
+// The condition is only reported when Spec.MinControllerVersion is set.
+func reviseControllerVersionSupported(profileScope *scope.ProfileScope, logger logr.Logger) bool {
+	minVersion := profileScope.GetSpec().MinControllerVersion
+	if minVersion == "" {
+		meta.RemoveStatusCondition(&profileScope.GetStatus().Conditions,
+			configv1beta1.ControllerVersionSupportedCondition)
+		return true
+	}
+
+	supported, message := isControllerVersionSupported(minVersion)
+	profileScope.SetControllerVersionSupported(supported, message)
+	if !supported {
+		logger.V(logs.LogInfo).Info(message)
+	}
+
+	return supported
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+)
+
+var _ = Describe("Controller version", func() {
+	AfterEach(func() {
+		controllers.SetVersion("")
+	})
+
+	It("isControllerVersionSupported compares controller version with MinControllerVersion", func() {
+		controllers.SetVersion("v0.38.2")
+
+		supported, _ := controllers.IsControllerVersionSupported("")
+		Expect(supported).To(BeTrue())
+
+		supported, _ = controllers.IsControllerVersionSupported("v0.38.0")
+		Expect(supported).To(BeTrue())
+
+		supported, _ = controllers.IsControllerVersionSupported("0.38.2")
+		Expect(supported).To(BeTrue())
+
+		supported, message := controllers.IsControllerVersionSupported("v0.39.0")
+		Expect(supported).To(BeFalse())
+		Expect(message).To(ContainSubstring("v0.39.0"))
+
+		// Development builds satisfy any version
+		controllers.SetVersion("main")
+		supported, _ = controllers.IsControllerVersionSupported("v0.39.0")
+		Expect(supported).To(BeTrue())
+	})
+
+	It("reviseControllerVersionSupported reports ControllerVersionSupported condition", func() {
+		controllers.SetVersion("v0.38.2")
+
+		clusterProfile := &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString(), Generation: 2},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logr.Discard(),
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+
+		Expect(controllers.ReviseControllerVersionSupported(profileScope, logr.Discard())).To(BeTrue())
+		Expect(meta.FindStatusCondition(clusterProfile.Status.Conditions,
+			configv1beta1.ControllerVersionSupportedCondition)).To(BeNil())
+
+		clusterProfile.Spec.MinControllerVersion = "v0.40.0"
+		Expect(controllers.ReviseControllerVersionSupported(profileScope, logr.Discard())).To(BeFalse())
+		condition := meta.FindStatusCondition(clusterProfile.Status.Conditions,
+			configv1beta1.ControllerVersionSupportedCondition)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(configv1beta1.ControllerVersionTooOldReason))
+
+		clusterProfile.Spec.MinControllerVersion = "v0.38.0"
+		Expect(controllers.ReviseControllerVersionSupported(profileScope, logr.Discard())).To(BeTrue())
+		condition = meta.FindStatusCondition(clusterProfile.Status.Conditions,
+			configv1beta1.ControllerVersionSupportedCondition)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+	})
+})
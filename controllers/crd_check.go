@@ -0,0 +1,128 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+const (
+	capiClusterCRDName = "clusters.cluster.x-k8s.io"
+)
+
+// getRequiredCRDs returns the names of the CRDs addon-controller cannot run without.
+// All of them must serve configv1beta1 version.
+func getRequiredCRDs() []string {
+	group := configv1beta1.GroupVersion.Group
+	return []string{
+		"clusterprofiles." + group,
+		"profiles." + group,
+		"clustersummaries." + group,
+		"clusterconfigurations." + group,
+		"clusterreports." + group,
+	}
+}
+
+// CheckRequiredCRDs verifies all CRDs addon-controller needs are installed, established and
+// serve the version this controller uses. Reader must not be the manager cache (which is not
+// started yet when this is invoked). Use the manager APIReader instead.
+// All problems found are reported in the returned error.
+func CheckRequiredCRDs(ctx context.Context, reader client.Reader) error {
+	problems := make([]string, 0)
+	for _, name := range getRequiredCRDs() {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		err := reader.Get(ctx, types.NamespacedName{Name: name}, crd)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				problems = append(problems, fmt.Sprintf("CRD %s is not installed", name))
+				continue
+			}
+			return fmt.Errorf("failed to get CRD %s: %w", name, err)
+		}
+
+		if err := validateCRD(crd, configv1beta1.GroupVersion.Version); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s. Install (or upgrade) the CRDs shipped with this addon-controller version",
+			strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// CheckCAPIClusterCRD returns true if ClusterAPI Cluster CRD is installed and serves the version
+// this controller uses. If CRD is installed but not compatible, false is returned along with an
+// error describing the problem. Callers are expected to run without ClusterAPI support in such a case.
+func CheckCAPIClusterCRD(ctx context.Context, reader client.Reader) (bool, error) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	err := reader.Get(ctx, types.NamespacedName{Name: capiClusterCRDName}, crd)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := validateCRD(crd, clusterv1.GroupVersion.Version); err != nil {
+		return false, &IncompatibleCRDError{Message: err.Error()}
+	}
+
+	return true, nil
+}
+
+// IncompatibleCRDError is returned when a CRD is installed but cannot be used
+type IncompatibleCRDError struct {
+	Message string
+}
+
+func (e *IncompatibleCRDError) Error() string {
+	return e.Message
+}
+
+// validateCRD returns an error if crd is not established or does not serve version
+func validateCRD(crd *apiextensionsv1.CustomResourceDefinition, version string) error {
+	if !isCRDEstablished(crd) {
+		return fmt.Errorf("CRD %s is not established", crd.Name)
+	}
+
+	served := make([]string, 0)
+	for i := range crd.Spec.Versions {
+		if !crd.Spec.Versions[i].Served {
+			continue
+		}
+		if crd.Spec.Versions[i].Name == version {
+			return nil
+		}
+		served = append(served, crd.Spec.Versions[i].Name)
+	}
+
+	return fmt.Errorf("CRD %s does not serve version %s (served versions: %s)",
+		crd.Name, version, strings.Join(served, ","))
+}
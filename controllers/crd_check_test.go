@@ -0,0 +1,108 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("CRD check", func() {
+	getCRD := func(name string, established bool, versions ...string) *apiextensionsv1.CustomResourceDefinition {
+		crd := &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+		}
+		for i := range versions {
+			crd.Spec.Versions = append(crd.Spec.Versions,
+				apiextensionsv1.CustomResourceDefinitionVersion{Name: versions[i], Served: true})
+		}
+		status := apiextensionsv1.ConditionFalse
+		if established {
+			status = apiextensionsv1.ConditionTrue
+		}
+		crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{
+			{Type: apiextensionsv1.Established, Status: status},
+		}
+		return crd
+	}
+
+	getRequiredCRDs := func() []client.Object {
+		group := configv1beta1.GroupVersion.Group
+		version := configv1beta1.GroupVersion.Version
+		return []client.Object{
+			getCRD("clusterprofiles."+group, true, version),
+			getCRD("profiles."+group, true, version),
+			getCRD("clustersummaries."+group, true, version),
+			getCRD("clusterconfigurations."+group, true, version),
+			getCRD("clusterreports."+group, true, "v1alpha1", version),
+		}
+	}
+
+	It("CheckRequiredCRDs succeeds when all required CRDs are installed", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(getRequiredCRDs()...).Build()
+		Expect(controllers.CheckRequiredCRDs(context.TODO(), c)).To(Succeed())
+	})
+
+	It("CheckRequiredCRDs reports missing, not established and incompatible CRDs", func() {
+		objects := getRequiredCRDs()
+		group := configv1beta1.GroupVersion.Group
+		objects[2] = getCRD("clustersummaries."+group, false, configv1beta1.GroupVersion.Version)
+		objects[3] = getCRD("clusterconfigurations."+group, true, "v1alpha1")
+		// clusterreports CRD is not installed
+		objects = objects[:4]
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+		err := controllers.CheckRequiredCRDs(context.TODO(), c)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("CRD clustersummaries." + group + " is not established"))
+		Expect(err.Error()).To(ContainSubstring("CRD clusterconfigurations." + group + " does not serve version"))
+		Expect(err.Error()).To(ContainSubstring("CRD clusterreports." + group + " is not installed"))
+	})
+
+	It("CheckCAPIClusterCRD reports an incompatible CAPI Cluster CRD", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		installed, err := controllers.CheckCAPIClusterCRD(context.TODO(), c)
+		Expect(err).To(BeNil())
+		Expect(installed).To(BeFalse())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			getCRD("clusters.cluster.x-k8s.io", true, "v1beta1")).Build()
+		installed, err = controllers.CheckCAPIClusterCRD(context.TODO(), c)
+		Expect(err).To(BeNil())
+		Expect(installed).To(BeTrue())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			getCRD("clusters.cluster.x-k8s.io", true, "v1alpha3")).Build()
+		installed, err = controllers.CheckCAPIClusterCRD(context.TODO(), c)
+		Expect(installed).To(BeFalse())
+		var incompatibleErr *controllers.IncompatibleCRDError
+		Expect(errors.As(err, &incompatibleErr)).To(BeTrue())
+	})
+})
@@ -0,0 +1,203 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/crsmigration"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/libsveltos/lib/utils"
+)
+
+// MigrateClusterResourceSets converts ClusterResourceSets to Profiles (see crsmigration.Migrate).
+// Then, in each cluster where a ClusterResourceSet already applied all its resources, those
+// resources are marked as deployed by the Profile. So when the Profile is first deployed no
+// conflict is reported, and resources are owned by the Profile from then on.
+// Resources already deployed by Sveltos are left untouched.
+// Management cluster client and rest config must be set (SetManagementClusterAccess).
+func MigrateClusterResourceSets(ctx context.Context, c client.Client, options crsmigration.MigrateOptions,
+	logger logr.Logger) ([]*crsmigration.Result, error) {
+
+	results, err := crsmigration.Migrate(ctx, c, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.DryRun {
+		return results, nil
+	}
+
+	for i := range results {
+		profile := &configv1beta1.Profile{}
+		err = c.Get(ctx, types.NamespacedName{Namespace: results[i].Profile.Namespace, Name: results[i].Profile.Name},
+			profile)
+		if err != nil {
+			return nil, err
+		}
+		if profile.Labels[crsmigration.MigratedFromLabel] == "" {
+			// Profile existed before migration. Do not mark resources as owned by it.
+			continue
+		}
+
+		for _, clusterName := range results[i].AdoptedClusters {
+			l := logger.WithValues("profile", fmt.Sprintf("%s/%s", profile.Namespace, profile.Name),
+				"cluster", fmt.Sprintf("%s/%s", profile.Namespace, clusterName))
+			err = adoptClusterResourceSetResources(ctx, c, profile, clusterName, l)
+			if err != nil {
+				results[i].Warnings = append(results[i].Warnings,
+					fmt.Sprintf("failed to mark resources in cluster %s as owned by the Profile: %v", clusterName, err))
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// adoptClusterResourceSetResources marks resources, contained in the ConfigMaps/Secrets referenced by profile
+// and already present in the ClusterAPI cluster clusterName, as deployed by profile.
+func adoptClusterResourceSetResources(ctx context.Context, c client.Client, profile *configv1beta1.Profile,
+	clusterName string, logger logr.Logger) error {
+
+	remoteRestConfig, err := getKubernetesRestConfig(ctx, c, profile.Namespace, clusterName, "", "",
+		libsveltosv1beta1.ClusterTypeCapi, logger)
+	if err != nil {
+		return err
+	}
+
+	owner := profile.DeepCopy()
+	owner.APIVersion, owner.Kind = configv1beta1.GroupVersion.WithKind(configv1beta1.ProfileKind).ToAPIVersionAndKind()
+	owner.Name = profileNameToOwnerReferenceName(owner)
+
+	for i := range profile.Spec.PolicyRefs {
+		ref := &profile.Spec.PolicyRefs[i]
+		referencedObject := &corev1.ObjectReference{Kind: ref.Kind, Namespace: profile.Namespace, Name: ref.Name}
+
+		var data map[string]string
+		data, err = getClusterResourceSetReferencedData(ctx, c, referencedObject)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				logger.V(logs.LogInfo).Info(fmt.Sprintf("%s %s/%s not found", ref.Kind, profile.Namespace, ref.Name))
+				continue
+			}
+			return err
+		}
+
+		for k := range data {
+			policies, err := getUnstructured([]byte(data[k]), logger)
+			if err != nil {
+				return err
+			}
+
+			for j := range policies {
+				policy := policies[j]
+				dr, err := utils.GetDynamicResourceInterface(remoteRestConfig, policy.GroupVersionKind(),
+					policy.GetNamespace())
+				if err != nil {
+					return err
+				}
+
+				current, err := dr.Get(ctx, policy.GetName(), metav1.GetOptions{})
+				if err != nil {
+					if apierrors.IsNotFound(err) {
+						// Profile will deploy it
+						continue
+					}
+					return err
+				}
+
+				if !markAsOwnedByProfile(current, policy, referencedObject, owner) {
+					logger.V(logs.LogDebug).Info(fmt.Sprintf("%s %s/%s already deployed by Sveltos",
+						current.GetKind(), current.GetNamespace(), current.GetName()))
+					continue
+				}
+
+				_, err = dr.Update(ctx, current, metav1.UpdateOptions{})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func getClusterResourceSetReferencedData(ctx context.Context, c client.Client,
+	referencedObject *corev1.ObjectReference) (map[string]string, error) {
+
+	key := types.NamespacedName{Namespace: referencedObject.Namespace, Name: referencedObject.Name}
+	if referencedObject.Kind == string(libsveltosv1beta1.ConfigMapReferencedResourceKind) {
+		configMap, err := getConfigMap(ctx, c, key)
+		if err != nil {
+			return nil, err
+		}
+		return configMap.Data, nil
+	}
+
+	secret, err := getSecret(ctx, c, key)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string)
+	for k, value := range secret.Data {
+		section, err := getSecretDataSection(secret, value)
+		if err != nil {
+			return nil, err
+		}
+		data[k] = section
+	}
+	return data, nil
+}
+
+// markAsOwnedByProfile sets on current, the resource deployed by a ClusterResourceSet, same metadata
+// Sveltos sets when deploying policy, contained in referencedObject, because of profile.
+// Returns false, leaving current unchanged, if current is already deployed by Sveltos.
+func markAsOwnedByProfile(current, policy *unstructured.Unstructured, referencedObject *corev1.ObjectReference,
+	profile *configv1beta1.Profile) bool {
+
+	if _, ok := current.GetLabels()[deployer.ReferenceKindLabel]; ok {
+		return false
+	}
+
+	policyHash, err := computePolicyHash(policy)
+	if err != nil {
+		policyHash = ""
+	}
+
+	addLabel(current, deployer.ReferenceKindLabel, referencedObject.Kind)
+	addLabel(current, deployer.ReferenceNameLabel, referencedObject.Name)
+	addLabel(current, deployer.ReferenceNamespaceLabel, referencedObject.Namespace)
+	addLabel(current, reasonLabel, string(configv1beta1.FeatureResources))
+	addAnnotation(current, deployer.PolicyHash, policyHash)
+	addAnnotation(current, deployer.OwnerTier, fmt.Sprintf("%d", profile.Spec.Tier))
+	deployer.AddOwnerReference(current, profile)
+
+	return true
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+	"github.com/projectsveltos/libsveltos/lib/utils"
+)
+
+var _ = Describe("ClusterResourceSet migration", func() {
+	It("markAsOwnedByProfile marks resources deployed by ClusterResourceSet as deployed by the Profile", func() {
+		namespace := randomString()
+		policy, err := utils.GetUnstructured([]byte(fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+  namespace: %s
+data:
+  key: value`, randomString(), namespace)))
+		Expect(err).To(BeNil())
+
+		current := policy.DeepCopy()
+		current.SetResourceVersion("1")
+
+		profile := &configv1beta1.Profile{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       configv1beta1.ProfileKind,
+				APIVersion: configv1beta1.GroupVersion.String(),
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      namespace + "/" + randomString(),
+			},
+			Spec: configv1beta1.Spec{Tier: 100},
+		}
+		referencedObject := &corev1.ObjectReference{
+			Kind:      string(libsveltosv1beta1.SecretReferencedResourceKind),
+			Namespace: namespace,
+			Name:      randomString(),
+		}
+
+		Expect(controllers.MarkAsOwnedByProfile(current, policy, referencedObject, profile)).To(BeTrue())
+		Expect(current.GetLabels()[deployer.ReferenceKindLabel]).To(Equal(referencedObject.Kind))
+		Expect(current.GetLabels()[deployer.ReferenceNameLabel]).To(Equal(referencedObject.Name))
+		Expect(current.GetLabels()[deployer.ReferenceNamespaceLabel]).To(Equal(referencedObject.Namespace))
+		Expect(current.GetAnnotations()[deployer.PolicyHash]).ToNot(BeEmpty())
+		Expect(current.GetAnnotations()[deployer.OwnerTier]).To(Equal("100"))
+		Expect(deployer.IsOwnerReference(current, profile)).To(BeTrue())
+
+		// Resources already deployed by Sveltos are left untouched
+		Expect(controllers.MarkAsOwnedByProfile(current, policy, referencedObject, profile)).To(BeFalse())
+	})
+})
@@ -0,0 +1,284 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/logbuffer"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// When a ClusterProfile/Profile has the CollectDebugBundleAnnotation, a debug bundle is
+// collected in a ConfigMap in the management cluster (in the Profile namespace or, for
+// ClusterProfiles, in the projectsveltos namespace). The ConfigMap is owned by the
+// ClusterProfile/Profile and can be attached to support tickets.
+
+const (
+	debugBundleNamePrefix = "sveltos-debug-"
+
+	debugBundleProfileKey          = "profile.yaml"
+	debugBundleClusterSummariesKey = "clustersummaries.yaml"
+	debugBundleClusterReportsKey   = "clusterreports.yaml"
+	debugBundleDeploymentsKey      = "deployments.txt"
+	debugBundleLogsKey             = "logs.txt"
+	debugBundleTimeKey             = "collectionTime"
+
+	// debugBundleMaxSize keeps the ConfigMap below the 1MiB etcd object limit
+	debugBundleMaxSize = 900 * 1024
+	truncatedMarker    = "\n...(truncated)"
+)
+
+var (
+	debugLogBuffer *logbuffer.Buffer
+)
+
+// SetDebugLogBuffer sets the buffer containing recent controller logs included in debug bundles
+func SetDebugLogBuffer(b *logbuffer.Buffer) {
+	debugLogBuffer = b
+}
+
+// getDebugBundleName returns the name of the ConfigMap containing the debug bundle
+// for a given ClusterProfile/Profile
+func getDebugBundleName(profileKind, profileName string) string {
+	name := debugBundleNamePrefix + strings.ToLower(profileKind) + "-" + profileName
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = fmt.Sprintf("%s%x", debugBundleNamePrefix, sha256.Sum256([]byte(profileKind+profileName)))
+	}
+	return name
+}
+
+// collectDebugBundleIfRequested collects the debug bundle if the ClusterProfile/Profile has the
+// CollectDebugBundleAnnotation. Annotation is removed once the debug bundle is collected.
+// Failing to collect the debug bundle does not fail the reconciliation. Annotation is left and
+// collection is retried at next reconciliation.
+func collectDebugBundleIfRequested(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	logger logr.Logger) {
+
+	annotations := profileScope.Profile.GetAnnotations()
+	if _, ok := annotations[configv1beta1.CollectDebugBundleAnnotation]; !ok {
+		return
+	}
+
+	configMap, err := collectDebugBundle(ctx, c, profileScope)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to collect debug bundle: %v", err))
+		return
+	}
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("debug bundle collected in ConfigMap %s/%s",
+		configMap.Namespace, configMap.Name))
+	delete(annotations, configv1beta1.CollectDebugBundleAnnotation)
+	profileScope.Profile.SetAnnotations(annotations)
+}
+
+func collectDebugBundle(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+) (*corev1.ConfigMap, error) {
+
+	profile := profileScope.Profile
+
+	listOptions := []client.ListOption{}
+	if profileScope.GetKind() == configv1beta1.ClusterProfileKind {
+		listOptions = append(listOptions, client.MatchingLabels{ClusterProfileLabelName: profile.GetName()})
+	} else {
+		listOptions = append(listOptions,
+			client.MatchingLabels{ProfileLabelName: profile.GetName()},
+			client.InNamespace(profile.GetNamespace()))
+	}
+
+	clusterSummaryList := &configv1beta1.ClusterSummaryList{}
+	if err := c.List(ctx, clusterSummaryList, listOptions...); err != nil {
+		return nil, err
+	}
+
+	clusterReportList := &configv1beta1.ClusterReportList{}
+	if err := c.List(ctx, clusterReportList, listOptions...); err != nil {
+		return nil, err
+	}
+
+	profileCopy := profile.DeepCopyObject().(client.Object)
+	profileCopy.SetManagedFields(nil)
+	profileYAML, err := yaml.Marshal(profileCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{profile.GetName()}
+	for i := range clusterSummaryList.Items {
+		clusterSummaryList.Items[i].ManagedFields = nil
+		names = append(names, clusterSummaryList.Items[i].Name)
+	}
+	clusterSummariesYAML, err := yaml.Marshal(clusterSummaryList.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range clusterReportList.Items {
+		clusterReportList.Items[i].ManagedFields = nil
+	}
+	clusterReportsYAML, err := yaml.Marshal(clusterReportList.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	// Order matters: when bundle is too big, last entries get truncated first
+	entries := []struct{ key, value string }{
+		{debugBundleDeploymentsKey, getDebugBundleDeployments(clusterSummaryList.Items)},
+		{debugBundleProfileKey, string(profileYAML)},
+		{debugBundleLogsKey, getDebugBundleLogs(names)},
+		{debugBundleClusterSummariesKey, string(clusterSummariesYAML)},
+		{debugBundleClusterReportsKey, string(clusterReportsYAML)},
+	}
+
+	data := map[string]string{
+		debugBundleTimeKey: time.Now().UTC().Format(time.RFC3339),
+	}
+	available := debugBundleMaxSize
+	for i := range entries {
+		value := entries[i].value
+		if entries[i].key == debugBundleLogsKey {
+			value = keepLast(value, available)
+		} else {
+			value = keepFirst(value, available)
+		}
+		data[entries[i].key] = value
+		available -= len(value)
+	}
+
+	return updateDebugBundle(ctx, c, profileScope, data)
+}
+
+// getDebugBundleDeployments returns, for each cluster and feature, deployment status, hash
+// and the error met deploying in the managed cluster if any
+func getDebugBundleDeployments(clusterSummaries []configv1beta1.ClusterSummary) string {
+	var sb strings.Builder
+	for i := range clusterSummaries {
+		cs := &clusterSummaries[i]
+		sb.WriteString(fmt.Sprintf("%s %s/%s (ClusterSummary %s)\n", cs.Spec.ClusterType,
+			cs.Spec.ClusterNamespace, cs.Spec.ClusterName, cs.Name))
+		for j := range cs.Status.FeatureSummaries {
+			fs := &cs.Status.FeatureSummaries[j]
+			sb.WriteString(fmt.Sprintf("  %s: status=%s hash=%x", fs.FeatureID, fs.Status, fs.Hash))
+			if fs.FailureMessage != nil {
+				sb.WriteString(fmt.Sprintf(" error=%q", *fs.FailureMessage))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// getDebugBundleLogs returns recent controller log lines mentioning any of the names
+func getDebugBundleLogs(names []string) string {
+	if debugLogBuffer == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, line := range debugLogBuffer.Lines() {
+		for i := range names {
+			if strings.Contains(line, names[i]) {
+				sb.WriteString(line)
+				sb.WriteString("\n")
+				break
+			}
+		}
+	}
+	return sb.String()
+}
+
+// keepFirst returns s, truncated to maxLen if longer
+func keepFirst(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	if maxLen <= len(truncatedMarker) {
+		return ""
+	}
+	return s[:maxLen-len(truncatedMarker)] + truncatedMarker
+}
+
+// keepLast returns the lines at the end of s fitting in maxLen
+func keepLast(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	if maxLen <= 0 {
+		return ""
+	}
+	s = s[len(s)-maxLen:]
+	if index := strings.Index(s, "\n"); index >= 0 {
+		s = s[index+1:]
+	}
+	return s
+}
+
+func updateDebugBundle(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	data map[string]string) (*corev1.ConfigMap, error) {
+
+	profile := profileScope.Profile
+	namespace := profile.GetNamespace()
+	labels := map[string]string{ProfileLabelName: profile.GetName()}
+	if profileScope.GetKind() == configv1beta1.ClusterProfileKind {
+		namespace = projectsveltos
+		labels = map[string]string{ClusterProfileLabelName: profile.GetName()}
+	}
+
+	name := getDebugBundleName(profileScope.GetKind(), profile.GetName())
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, configMap)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+				Labels:    labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       profileScope.GetKind(),
+						Name:       profile.GetName(),
+						UID:        profile.GetUID(),
+					},
+				},
+			},
+			Data: data,
+		}
+		return configMap, c.Create(ctx, configMap)
+	}
+
+	configMap.Data = data
+	return configMap, c.Update(ctx, configMap)
+}
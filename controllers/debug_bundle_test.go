@@ -0,0 +1,122 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/logbuffer"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Debug bundle", func() {
+	AfterEach(func() {
+		controllers.SetDebugLogBuffer(nil)
+	})
+
+	It("collectDebugBundleIfRequested collects debug bundle and removes annotation", func() {
+		profile := &configv1beta1.Profile{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       configv1beta1.ProfileKind,
+				APIVersion: configv1beta1.GroupVersion.String(),
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        randomString(),
+				Namespace:   randomString(),
+				Annotations: map[string]string{configv1beta1.CollectDebugBundleAnnotation: "ok"},
+			},
+		}
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: profile.Namespace,
+				Labels:    map[string]string{controllers.ProfileLabelName: profile.Name},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: profile.Namespace,
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+			},
+			Status: configv1beta1.ClusterSummaryStatus{
+				FeatureSummaries: []configv1beta1.FeatureSummary{
+					{
+						FeatureID:      configv1beta1.FeatureHelm,
+						Status:         configv1beta1.FeatureStatusFailed,
+						Hash:           []byte("abc"),
+						FailureMessage: ptr.To("chart not found"),
+					},
+				},
+			},
+		}
+
+		buffer := logbuffer.New(10)
+		controllers.SetDebugLogBuffer(buffer)
+		bufferedLogger := logr.New(buffer.WrapSink(textlogger.NewLogger(textlogger.NewConfig()).GetSink()))
+		bufferedLogger.Info("deploying", "clustersummary", clusterSummary.Name)
+		bufferedLogger.Info("unrelated")
+
+		initObjects := []client.Object{profile, clusterSummary}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logger,
+			Profile:        profile,
+			ControllerName: "profile",
+		})
+		Expect(err).To(BeNil())
+
+		controllers.CollectDebugBundleIfRequested(context.TODO(), c, profileScope, logger)
+		Expect(profile.Annotations).ToNot(HaveKey(configv1beta1.CollectDebugBundleAnnotation))
+
+		configMap := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{
+			Namespace: profile.Namespace,
+			Name:      controllers.GetDebugBundleName(configv1beta1.ProfileKind, profile.Name),
+		}, configMap)).To(Succeed())
+		Expect(configMap.OwnerReferences).To(HaveLen(1))
+		Expect(configMap.OwnerReferences[0].Name).To(Equal(profile.Name))
+		Expect(configMap.Data["profile.yaml"]).To(ContainSubstring(profile.Name))
+		Expect(configMap.Data["clustersummaries.yaml"]).To(ContainSubstring(clusterSummary.Name))
+		Expect(configMap.Data["deployments.txt"]).To(ContainSubstring(`status=Failed hash=616263 error="chart not found"`))
+		Expect(configMap.Data["logs.txt"]).To(ContainSubstring("deploying"))
+		Expect(configMap.Data["logs.txt"]).ToNot(ContainSubstring("unrelated"))
+
+		// Nothing happens when annotation is not set
+		Expect(c.Delete(context.TODO(), configMap)).To(Succeed())
+		controllers.CollectDebugBundleIfRequested(context.TODO(), c, profileScope, logger)
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: configMap.Namespace, Name: configMap.Name},
+			configMap)).ToNot(Succeed())
+	})
+})
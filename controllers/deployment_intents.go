@@ -0,0 +1,281 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Requests to deploy/undeploy features are queued in the deployer, which lives in memory. Results
+// not collected yet are lost on restart, and the feature would be deployed again.
+// So each request is also recorded, as a deployment intent, in a ConfigMap owned by the ClusterSummary
+// (one key per feature and action):
+// - when request is queued, intent is recorded as Queued along with the hash being deployed;
+// - when the worker is done, intent is marked Completed along with the outcome;
+// - when the result is collected, intent is removed.
+// After a restart, if the deployer has no result, the intent is looked at: a Completed intent for
+// the current hash gives the result, so nothing is replayed. A Queued intent means the request was
+// lost before completing and it is queued again.
+// Failing to record an intent never fails a deployment: worst case request is replayed.
+
+const (
+	deploymentIntentsNamePrefix = "sveltos-intents-"
+
+	// deploymentIntentHashOption is the deployer.Options.HandlerOptions key carrying the hash
+	// of the deployment intent being served
+	deploymentIntentHashOption = "deploymentIntentHash"
+)
+
+type deploymentIntentPhase string
+
+const (
+	// deploymentIntentQueued means request is queued or being served
+	deploymentIntentQueued = deploymentIntentPhase("Queued")
+
+	// deploymentIntentCompleted means request was served
+	deploymentIntentCompleted = deploymentIntentPhase("Completed")
+)
+
+type deploymentIntent struct {
+	// Hash is the hash of the feature configuration being deployed. Empty for undeployment
+	Hash  string                `json:"hash,omitempty"`
+	Phase deploymentIntentPhase `json:"phase"`
+
+	// Error, MessageCode and NonRetriable are set if request was served and failed
+	Error        string `json:"error,omitempty"`
+	MessageCode  string `json:"messageCode,omitempty"`
+	NonRetriable bool   `json:"nonRetriable,omitempty"`
+}
+
+// getDeploymentIntentsName returns the name of the ConfigMap containing the deployment intents
+// of a ClusterSummary
+func getDeploymentIntentsName(clusterSummaryName string) string {
+	name := deploymentIntentsNamePrefix + clusterSummaryName
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = fmt.Sprintf("%s%x", deploymentIntentsNamePrefix, sha256.Sum256([]byte(clusterSummaryName)))
+	}
+	return name
+}
+
+func getDeploymentIntentKey(featureID configv1beta1.FeatureID, cleanup bool) string {
+	if cleanup {
+		return string(featureID) + ".undeploy"
+	}
+	return string(featureID) + ".deploy"
+}
+
+// updateDeploymentIntent invokes update on the deployment intent of a feature. Update returns the
+// new intent (nil to remove it) and whether anything changed. ConfigMap is created only to store
+// an intent.
+func updateDeploymentIntent(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	clusterSummaryNamespace, clusterSummaryName string, key string,
+	update func(current *deploymentIntent) (*deploymentIntent, bool)) error {
+
+	// Intents are read from the cache, which might not have seen the ConfigMap just created yet
+	isRetriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+
+	return retry.OnError(retry.DefaultRetry, isRetriable, func() error {
+		configMap := &corev1.ConfigMap{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: clusterSummaryNamespace,
+			Name: getDeploymentIntentsName(clusterSummaryName)}, configMap)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		var current *deploymentIntent
+		if data, ok := configMap.Data[key]; ok {
+			current = &deploymentIntent{}
+			if json.Unmarshal([]byte(data), current) != nil {
+				current = nil
+			}
+		}
+
+		intent, changed := update(current)
+		if !changed {
+			return nil
+		}
+
+		if intent == nil {
+			if configMap.ResourceVersion == "" {
+				return nil
+			}
+			delete(configMap.Data, key)
+			return c.Update(ctx, configMap)
+		}
+
+		data, err := json.Marshal(intent)
+		if err != nil {
+			return err
+		}
+
+		if configMap.ResourceVersion == "" {
+			if clusterSummary == nil {
+				// ClusterSummary is needed for ownership. Nothing to complete anyway.
+				return nil
+			}
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: clusterSummaryNamespace,
+					Name:      getDeploymentIntentsName(clusterSummaryName),
+					Labels:    map[string]string{ClusterSummaryLabelName: clusterSummaryName},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: configv1beta1.GroupVersion.String(),
+							Kind:       configv1beta1.ClusterSummaryKind,
+							Name:       clusterSummary.Name,
+							UID:        clusterSummary.UID,
+						},
+					},
+				},
+				Data: map[string]string{key: string(data)},
+			}
+			return c.Create(ctx, configMap)
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[key] = string(data)
+		return c.Update(ctx, configMap)
+	})
+}
+
+// recordDeploymentIntent records a request to deploy (hash is the hash being deployed) or
+// undeploy a feature is queued
+func recordDeploymentIntent(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	featureID configv1beta1.FeatureID, cleanup bool, hash []byte, logger logr.Logger) {
+
+	intent := &deploymentIntent{Hash: hex.EncodeToString(hash), Phase: deploymentIntentQueued}
+	err := updateDeploymentIntent(ctx, c, clusterSummary, clusterSummary.Namespace, clusterSummary.Name,
+		getDeploymentIntentKey(featureID, cleanup),
+		func(current *deploymentIntent) (*deploymentIntent, bool) {
+			return intent, current == nil || *current != *intent
+		})
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to record deployment intent: %v", err))
+	}
+}
+
+// completeDeploymentIntent records a request was served. Intent is updated only if still
+// queued for the same hash (otherwise a newer request was queued in the meantime).
+func completeDeploymentIntent(ctx context.Context, c client.Client, clusterSummaryNamespace,
+	clusterSummaryName string, featureID configv1beta1.FeatureID, cleanup bool, hash string,
+	deployErr error, logger logr.Logger) {
+
+	err := updateDeploymentIntent(ctx, c, nil, clusterSummaryNamespace, clusterSummaryName,
+		getDeploymentIntentKey(featureID, cleanup),
+		func(current *deploymentIntent) (*deploymentIntent, bool) {
+			if current == nil || current.Phase != deploymentIntentQueued || current.Hash != hash {
+				return nil, false
+			}
+			intent := &deploymentIntent{Hash: hash, Phase: deploymentIntentCompleted}
+			if deployErr != nil {
+				intent.Error = deployErr.Error()
+				intent.MessageCode = string(getMessageCode(deployErr))
+				var nonRetriableErr *NonRetriableError
+				intent.NonRetriable = errors.As(deployErr, &nonRetriableErr)
+			}
+			return intent, true
+		})
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to complete deployment intent: %v", err))
+	}
+}
+
+// removeDeploymentIntent forgets about the deployment intent of a feature once its result
+// is collected
+func removeDeploymentIntent(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	featureID configv1beta1.FeatureID, cleanup bool, logger logr.Logger) {
+
+	err := updateDeploymentIntent(ctx, c, clusterSummary, clusterSummary.Namespace, clusterSummary.Name,
+		getDeploymentIntentKey(featureID, cleanup),
+		func(current *deploymentIntent) (*deploymentIntent, bool) {
+			return nil, current != nil
+		})
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to remove deployment intent: %v", err))
+	}
+}
+
+// getDeploymentIntentResult returns the result of a request served but never collected (for
+// instance because of a restart). Hash is the hash being deployed (nil for undeployment).
+// Nil status means no result is recorded for this hash. A recorded result is returned only once.
+func getDeploymentIntentResult(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	featureID configv1beta1.FeatureID, cleanup bool, hash []byte, logger logr.Logger,
+) (*configv1beta1.FeatureStatus, error) {
+
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: clusterSummary.Namespace,
+		Name: getDeploymentIntentsName(clusterSummary.Name)}, configMap)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get deployment intents: %v", err))
+		}
+		return nil, nil
+	}
+
+	data, ok := configMap.Data[getDeploymentIntentKey(featureID, cleanup)]
+	if !ok {
+		return nil, nil
+	}
+	intent := &deploymentIntent{}
+	if err := json.Unmarshal([]byte(data), intent); err != nil || intent.Hash != hex.EncodeToString(hash) {
+		return nil, nil
+	}
+
+	if intent.Phase != deploymentIntentCompleted {
+		logger.V(logs.LogInfo).Info("deployment intent was not completed. Queueing it again")
+		return nil, nil
+	}
+
+	logger.V(logs.LogDebug).Info("using result recorded in deployment intent")
+	removeDeploymentIntent(ctx, c, clusterSummary, featureID, cleanup, logger)
+
+	status := configv1beta1.FeatureStatusProvisioned
+	if cleanup {
+		status = configv1beta1.FeatureStatusRemoved
+	}
+	if intent.Error == "" {
+		return &status, nil
+	}
+
+	status = configv1beta1.FeatureStatusFailed
+	var resultErr error = errors.New(intent.Error)
+	if intent.NonRetriable {
+		resultErr = &NonRetriableError{Message: intent.Error}
+	}
+	return &status, withMessageCode(configv1beta1.MessageCode(intent.MessageCode), resultErr)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Deployment intents", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+	var c client.Client
+	var hash []byte
+
+	BeforeEach(func() {
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+				UID:       types.UID(randomString()),
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).Build()
+		hash = []byte(randomString())
+	})
+
+	It("getDeploymentIntentResult returns result of completed intent only once", func() {
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		controllers.RecordDeploymentIntent(context.TODO(), c, clusterSummary, configv1beta1.FeatureHelm, false,
+			hash, logger)
+
+		configMap := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: clusterSummary.Namespace,
+			Name: controllers.GetDeploymentIntentsName(clusterSummary.Name)}, configMap)).To(Succeed())
+		Expect(len(configMap.OwnerReferences)).To(Equal(1))
+		Expect(configMap.OwnerReferences[0].UID).To(Equal(clusterSummary.UID))
+
+		// Intent queued but not completed: no result (request must be queued again)
+		status, err := controllers.GetDeploymentIntentResult(context.TODO(), c, clusterSummary,
+			configv1beta1.FeatureHelm, false, hash, logger)
+		Expect(err).To(BeNil())
+		Expect(status).To(BeNil())
+
+		controllers.CompleteDeploymentIntent(context.TODO(), c, clusterSummary.Namespace, clusterSummary.Name,
+			configv1beta1.FeatureHelm, false, hex.EncodeToString(hash), nil, logger)
+
+		// Result recorded for a different hash is ignored
+		status, err = controllers.GetDeploymentIntentResult(context.TODO(), c, clusterSummary,
+			configv1beta1.FeatureHelm, false, []byte(randomString()), logger)
+		Expect(err).To(BeNil())
+		Expect(status).To(BeNil())
+
+		status, err = controllers.GetDeploymentIntentResult(context.TODO(), c, clusterSummary,
+			configv1beta1.FeatureHelm, false, hash, logger)
+		Expect(err).To(BeNil())
+		Expect(status).ToNot(BeNil())
+		Expect(*status).To(Equal(configv1beta1.FeatureStatusProvisioned))
+
+		status, err = controllers.GetDeploymentIntentResult(context.TODO(), c, clusterSummary,
+			configv1beta1.FeatureHelm, false, hash, logger)
+		Expect(err).To(BeNil())
+		Expect(status).To(BeNil())
+	})
+
+	It("getDeploymentIntentResult returns failure recorded in completed intent", func() {
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		controllers.RecordDeploymentIntent(context.TODO(), c, clusterSummary, configv1beta1.FeatureResources, false,
+			hash, logger)
+		controllers.CompleteDeploymentIntent(context.TODO(), c, clusterSummary.Namespace, clusterSummary.Name,
+			configv1beta1.FeatureResources, false, hex.EncodeToString(hash),
+			&controllers.NonRetriableError{Message: "invalid content"}, logger)
+
+		status, err := controllers.GetDeploymentIntentResult(context.TODO(), c, clusterSummary,
+			configv1beta1.FeatureResources, false, hash, logger)
+		Expect(status).ToNot(BeNil())
+		Expect(*status).To(Equal(configv1beta1.FeatureStatusFailed))
+		Expect(err).To(MatchError("invalid content"))
+		var nonRetriableError *controllers.NonRetriableError
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+		Expect(controllers.GetMessageCode(err)).To(Equal(configv1beta1.MessageCodeProvisioningFailedNonRetriable))
+	})
+
+	It("completeDeploymentIntent ignores requests superseded by a newer one", func() {
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		oldHash := []byte(randomString())
+		controllers.RecordDeploymentIntent(context.TODO(), c, clusterSummary, configv1beta1.FeatureKustomize, false,
+			oldHash, logger)
+		controllers.RecordDeploymentIntent(context.TODO(), c, clusterSummary, configv1beta1.FeatureKustomize, false,
+			hash, logger)
+
+		// Request for old hash completes after the new one was queued
+		controllers.CompleteDeploymentIntent(context.TODO(), c, clusterSummary.Namespace, clusterSummary.Name,
+			configv1beta1.FeatureKustomize, false, hex.EncodeToString(oldHash), nil, logger)
+
+		status, err := controllers.GetDeploymentIntentResult(context.TODO(), c, clusterSummary,
+			configv1beta1.FeatureKustomize, false, hash, logger)
+		Expect(err).To(BeNil())
+		Expect(status).To(BeNil())
+
+		controllers.CompleteDeploymentIntent(context.TODO(), c, clusterSummary.Namespace, clusterSummary.Name,
+			configv1beta1.FeatureKustomize, true, "", nil, logger)
+		status, err = controllers.GetDeploymentIntentResult(context.TODO(), c, clusterSummary,
+			configv1beta1.FeatureKustomize, true, nil, logger)
+		Expect(err).To(BeNil())
+		Expect(status).To(BeNil())
+	})
+})
@@ -0,0 +1,223 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/gdexlab/go-render/render"
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// PreDeploymentJobs/PostDeploymentJobs are Jobs run in the managed cluster before/after a feature
+// is deployed. A Job is named after the DeploymentJob and a hash of its content and of the
+// ClusterSummary generation, so it is run once per ClusterSummary Spec. A feature is not deployed
+// (PreDeploymentJobs) or not reported as Provisioned (PostDeploymentJobs) till its Jobs complete.
+// A failed Job fails the feature till ClusterSummary Spec changes.
+
+const (
+	// deploymentJobLabel is added to all Jobs created because of a DeploymentJob
+	deploymentJobLabel = "projectsveltos.io/deployment-job"
+
+	preDeploymentPhase  = "pre"
+	postDeploymentPhase = "post"
+)
+
+// runPreDeploymentJobs runs all PreDeploymentJobs registered for the feature
+func runPreDeploymentJobs(ctx context.Context, c, remoteClient client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID, logger logr.Logger) error {
+
+	return runDeploymentJobs(ctx, c, remoteClient, clusterSummary, featureID, preDeploymentPhase,
+		clusterSummary.Spec.ClusterProfileSpec.PreDeploymentJobs, logger)
+}
+
+// runPostDeploymentJobs runs all PostDeploymentJobs registered for the feature
+func runPostDeploymentJobs(ctx context.Context, c, remoteClient client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID, logger logr.Logger) error {
+
+	return runDeploymentJobs(ctx, c, remoteClient, clusterSummary, featureID, postDeploymentPhase,
+		clusterSummary.Spec.ClusterProfileSpec.PostDeploymentJobs, logger)
+}
+
+func runDeploymentJobs(ctx context.Context, c, remoteClient client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID, phase string,
+	deploymentJobs []configv1beta1.DeploymentJob, logger logr.Logger) error {
+
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+		return nil
+	}
+
+	for i := range deploymentJobs {
+		deploymentJob := &deploymentJobs[i]
+		if deploymentJob.FeatureID != featureID {
+			continue
+		}
+
+		l := logger.WithValues("deploymentJob", deploymentJob.Name, "phase", phase)
+		if err := runDeploymentJob(ctx, c, remoteClient, clusterSummary, deploymentJob, phase, l); err != nil {
+			l.V(logs.LogInfo).Info(fmt.Sprintf("deployment job not completed: %v", err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runDeploymentJob creates the Job in the managed cluster, if not created yet, and returns nil
+// only once such Job has successfully completed.
+func runDeploymentJob(ctx context.Context, c, remoteClient client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, deploymentJob *configv1beta1.DeploymentJob,
+	phase string, logger logr.Logger) error {
+
+	job, err := getDeploymentJob(ctx, c, clusterSummary, deploymentJob, phase, logger)
+	if err != nil {
+		return err
+	}
+
+	currentJob := &batchv1.Job{}
+	err = remoteClient.Get(ctx, types.NamespacedName{Namespace: job.Namespace, Name: job.Name}, currentJob)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("creating job %s/%s", job.Namespace, job.Name))
+		if err := remoteClient.Create(ctx, job); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s-deployment job %s/%s is running", phase, job.Namespace, job.Name)
+	}
+
+	for i := range currentJob.Status.Conditions {
+		condition := &currentJob.Status.Conditions[i]
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return nil
+		case batchv1.JobFailed:
+			return &NonRetriableError{
+				Message: fmt.Sprintf("%s-deployment job %s/%s failed: %s", phase, job.Namespace, job.Name, condition.Message),
+			}
+		}
+	}
+
+	return fmt.Errorf("%s-deployment job %s/%s is running", phase, job.Namespace, job.Name)
+}
+
+// getDeploymentJob returns the Job contained in the ConfigMap/Secret referenced by deploymentJob,
+// with name and labels set.
+func getDeploymentJob(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	deploymentJob *configv1beta1.DeploymentJob, phase string, logger logr.Logger) (*batchv1.Job, error) {
+
+	data, isTemplate, err := getValuesFromResource(ctx, c, clusterSummary, &deploymentJob.JobTemplateRef, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var mgmtResources map[string]*unstructured.Unstructured
+	if isTemplate {
+		mgmtResources, err = collectTemplateResourceRefs(ctx, clusterSummary)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	objects, err := collectContent(ctx, clusterSummary, mgmtResources, data, isTemplate, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objects) != 1 || objects[0].GroupVersionKind() != batchv1.SchemeGroupVersion.WithKind("Job") {
+		return nil, &NonRetriableError{
+			Message: fmt.Sprintf("%s %s/%s referenced by deployment job %s must contain exactly one Job",
+				deploymentJob.JobTemplateRef.Kind, deploymentJob.JobTemplateRef.Namespace,
+				deploymentJob.JobTemplateRef.Name, deploymentJob.Name),
+		}
+	}
+
+	job := &batchv1.Job{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(objects[0].Object, job); err != nil {
+		return nil, err
+	}
+
+	if job.Namespace == "" {
+		job.Namespace = metav1.NamespaceDefault
+	}
+	job.Name = getDeploymentJobName(clusterSummary, deploymentJob, phase, job)
+	job.ResourceVersion = ""
+	addLabel(job, deploymentJobLabel, deploymentJob.Name)
+	addLabel(job, ClusterSummaryLabelName, clusterSummary.Name)
+
+	return job, nil
+}
+
+// getDeploymentJobName returns the name of the Job in the managed cluster. It changes when either
+// the Job or the ClusterSummary Spec changes, so Job is run again.
+func getDeploymentJobName(clusterSummary *configv1beta1.ClusterSummary, deploymentJob *configv1beta1.DeploymentJob,
+	phase string, job *batchv1.Job) string {
+
+	h := sha256.New()
+	config := phase
+	config += fmt.Sprintf("%d", clusterSummary.Generation)
+	config += render.AsCode(job.Spec)
+	h.Write([]byte(config))
+
+	return fmt.Sprintf("%s-%x", deploymentJob.Name, h.Sum(nil)[:5])
+}
+
+// getDeploymentJobsHash returns a string representing the PreDeploymentJobs/PostDeploymentJobs
+// registered for the feature and the content of the referenced ConfigMaps/Secrets.
+func getDeploymentJobsHash(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	featureID configv1beta1.FeatureID, logger logr.Logger) (string, error) {
+
+	var config string
+	for _, deploymentJobs := range [][]configv1beta1.DeploymentJob{
+		clusterSummary.Spec.ClusterProfileSpec.PreDeploymentJobs,
+		clusterSummary.Spec.ClusterProfileSpec.PostDeploymentJobs,
+	} {
+
+		for i := range deploymentJobs {
+			if deploymentJobs[i].FeatureID != featureID {
+				continue
+			}
+			config += render.AsCode(deploymentJobs[i])
+
+			valueFromHash, err := getValuesFromResourceHash(ctx, c, clusterSummary,
+				[]configv1beta1.ValueFrom{deploymentJobs[i].JobTemplateRef}, logger)
+			if err != nil {
+				return "", err
+			}
+			config += valueFromHash
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	migrationJob = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migration
+  namespace: %s
+spec:
+  template:
+    spec:
+      containers:
+      - name: migrate
+        image: busybox
+        command: ["sh", "-c", "echo migrating"]
+      restartPolicy: Never`
+)
+
+var _ = Describe("Deployment jobs", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+	var deploymentJob *configv1beta1.DeploymentJob
+	var configMap *corev1.ConfigMap
+	var jobNamespace string
+
+	BeforeEach(func() {
+		namespace := randomString()
+		jobNamespace = randomString()
+
+		configMap = createConfigMapWithPolicy(namespace, randomString(), fmt.Sprintf(migrationJob, jobNamespace))
+
+		deploymentJob = &configv1beta1.DeploymentJob{
+			Name:      randomString(),
+			FeatureID: configv1beta1.FeatureHelm,
+			JobTemplateRef: configv1beta1.ValueFrom{
+				Namespace: namespace,
+				Name:      configMap.Name,
+				Kind:      string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+			},
+		}
+
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  namespace,
+				Name:       randomString(),
+				Generation: 1,
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: namespace,
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+				ClusterProfileSpec: configv1beta1.Spec{
+					PreDeploymentJobs: []configv1beta1.DeploymentJob{*deploymentJob},
+				},
+			},
+		}
+	})
+
+	It("getDeploymentJob returns the referenced Job named after DeploymentJob", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+
+		job, err := controllers.GetDeploymentJob(context.TODO(), c, clusterSummary, deploymentJob, "pre", logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(job.Namespace).To(Equal(jobNamespace))
+		Expect(job.Name).To(HavePrefix(deploymentJob.Name + "-"))
+		Expect(job.Labels).ToNot(BeNil())
+		Expect(job.Labels[controllers.ClusterSummaryLabelName]).To(Equal(clusterSummary.Name))
+
+		// Job name changes when ClusterSummary Spec changes, so Job is run again
+		clusterSummary.Generation++
+		newJob, err := controllers.GetDeploymentJob(context.TODO(), c, clusterSummary, deploymentJob, "pre", logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(newJob.Name).ToNot(Equal(job.Name))
+
+		// Pre and post deployment jobs have different names
+		postJob, err := controllers.GetDeploymentJob(context.TODO(), c, clusterSummary, deploymentJob, "post", logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(postJob.Name).ToNot(Equal(newJob.Name))
+	})
+
+	It("getDeploymentJob returns an error if referenced resource does not contain a Job", func() {
+		configMap = createConfigMapWithPolicy(configMap.Namespace, configMap.Name,
+			fmt.Sprintf(viewClusterRole, randomString()))
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+
+		_, err := controllers.GetDeploymentJob(context.TODO(), c, clusterSummary, deploymentJob, "pre", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		var nonRetriableError *controllers.NonRetriableError
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+	})
+
+	It("runDeploymentJob creates Job and succeeds only once Job is completed", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+		remoteClient := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&batchv1.Job{}).Build()
+
+		// Job is created and reported as running
+		err := controllers.RunDeploymentJob(context.TODO(), c, remoteClient, clusterSummary, deploymentJob, "pre",
+			logr.Discard())
+		Expect(err).ToNot(BeNil())
+
+		jobs := &batchv1.JobList{}
+		Expect(remoteClient.List(context.TODO(), jobs, client.InNamespace(jobNamespace))).To(Succeed())
+		Expect(len(jobs.Items)).To(Equal(1))
+
+		err = controllers.RunDeploymentJob(context.TODO(), c, remoteClient, clusterSummary, deploymentJob, "pre",
+			logr.Discard())
+		Expect(err).ToNot(BeNil())
+
+		job := &jobs.Items[0]
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+		}
+		Expect(remoteClient.Status().Update(context.TODO(), job)).To(Succeed())
+
+		Expect(controllers.RunDeploymentJob(context.TODO(), c, remoteClient, clusterSummary, deploymentJob, "pre",
+			logr.Discard())).To(Succeed())
+	})
+
+	It("runDeploymentJob returns a non retriable error when Job fails", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+		remoteClient := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&batchv1.Job{}).Build()
+
+		job, err := controllers.GetDeploymentJob(context.TODO(), c, clusterSummary, deploymentJob, "post", logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(remoteClient.Create(context.TODO(), job)).To(Succeed())
+
+		currentJob := &batchv1.Job{}
+		Expect(remoteClient.Get(context.TODO(), types.NamespacedName{Namespace: job.Namespace, Name: job.Name},
+			currentJob)).To(Succeed())
+		currentJob.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: randomString()},
+		}
+		Expect(remoteClient.Status().Update(context.TODO(), currentJob)).To(Succeed())
+
+		err = controllers.RunDeploymentJob(context.TODO(), c, remoteClient, clusterSummary, deploymentJob, "post",
+			logr.Discard())
+		Expect(err).ToNot(BeNil())
+		var nonRetriableError *controllers.NonRetriableError
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+	})
+})
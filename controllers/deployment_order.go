@@ -0,0 +1,94 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+)
+
+// By default features are deployed independently: a feature failing or still being provisioned
+// does not prevent other features from being deployed. Features listed in Spec.DeploymentOrder
+// are instead deployed sequentially. For instance, resources whose CRDs are installed by a helm
+// chart can be deployed only once the helm chart is provisioned.
+
+// featureDeployer deploys a feature to the managed cluster
+type featureDeployer struct {
+	featureID configv1beta1.FeatureID
+	deploy    func(context.Context, *scope.ClusterSummaryScope, logr.Logger) error
+}
+
+// getFeatureDeployers returns the feature deployers in the order features must be deployed.
+// Features in Spec.DeploymentOrder come first, in that order, followed by all other features.
+// The number of features to be deployed sequentially is also returned.
+func (r *ClusterSummaryReconciler) getFeatureDeployers(clusterSummary *configv1beta1.ClusterSummary,
+) (deployers []featureDeployer, sequential int) {
+
+	all := []featureDeployer{
+		{featureID: configv1beta1.FeatureResources, deploy: r.deployResources},
+		{featureID: configv1beta1.FeatureHelm, deploy: r.deployHelm},
+		{featureID: configv1beta1.FeatureKustomize, deploy: r.deployKustomizeRefs},
+		{featureID: configv1beta1.FeatureClusterMetadata, deploy: r.deployClusterMetadata},
+	}
+
+	order := clusterSummary.Spec.ClusterProfileSpec.DeploymentOrder
+	if len(order) == 0 {
+		return all, 0
+	}
+
+	deployers = make([]featureDeployer, 0, len(all))
+	ordered := make(map[configv1beta1.FeatureID]bool, len(order))
+	for i := range order {
+		for j := range all {
+			if all[j].featureID == order[i] && !ordered[order[i]] {
+				deployers = append(deployers, all[j])
+				ordered[order[i]] = true
+			}
+		}
+	}
+	sequential = len(deployers)
+
+	for j := range all {
+		if !ordered[all[j].featureID] {
+			deployers = append(deployers, all[j])
+		}
+	}
+
+	return deployers, sequential
+}
+
+// isFeatureReady returns true if features following featureID in Spec.DeploymentOrder can be
+// deployed. This is the case if feature is provisioned or if feature is not deployed at all.
+// deployErr is the error returned deploying the feature.
+func (r *ClusterSummaryReconciler) isFeatureReady(clusterSummary *configv1beta1.ClusterSummary,
+	featureID configv1beta1.FeatureID, deployErr error) bool {
+
+	if deployErr != nil {
+		return false
+	}
+
+	if !r.isFeatureStatusPresent(clusterSummary, featureID) {
+		// Feature is not deployed
+		return true
+	}
+
+	return r.isFeatureDeployed(clusterSummary, featureID)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Deployment order", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+
+	BeforeEach(func() {
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+			},
+		}
+	})
+
+	getFeatureIDs := func(reconciler *controllers.ClusterSummaryReconciler) ([]string, int) {
+		deployers, sequential := controllers.GetFeatureDeployers(reconciler, clusterSummary)
+		featureIDs := make([]string, len(deployers))
+		for i := range deployers {
+			featureIDs[i] = controllers.GetFeatureID(deployers[i])
+		}
+		return featureIDs, sequential
+	}
+
+	It("getFeatureDeployers returns features in DeploymentOrder first", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme}
+
+		featureIDs, sequential := getFeatureIDs(reconciler)
+		Expect(sequential).To(BeZero())
+		Expect(featureIDs).To(Equal([]string{
+			string(configv1beta1.FeatureResources), string(configv1beta1.FeatureHelm),
+			string(configv1beta1.FeatureKustomize), string(configv1beta1.FeatureClusterMetadata),
+		}))
+
+		clusterSummary.Spec.ClusterProfileSpec.DeploymentOrder = []configv1beta1.FeatureID{
+			configv1beta1.FeatureHelm, configv1beta1.FeatureResources,
+		}
+		featureIDs, sequential = getFeatureIDs(reconciler)
+		Expect(sequential).To(Equal(2))
+		Expect(featureIDs).To(Equal([]string{
+			string(configv1beta1.FeatureHelm), string(configv1beta1.FeatureResources),
+			string(configv1beta1.FeatureKustomize), string(configv1beta1.FeatureClusterMetadata),
+		}))
+	})
+
+	It("isFeatureReady returns true only if feature is provisioned or not deployed", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme}
+
+		// Feature is not deployed
+		Expect(controllers.IsFeatureReady(reconciler, clusterSummary, configv1beta1.FeatureHelm, nil)).To(BeTrue())
+		Expect(controllers.IsFeatureReady(reconciler, clusterSummary, configv1beta1.FeatureHelm,
+			errors.New("request is queued"))).To(BeFalse())
+
+		clusterSummary.Status.FeatureSummaries = []configv1beta1.FeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusFailedNonRetriable},
+		}
+		Expect(controllers.IsFeatureReady(reconciler, clusterSummary, configv1beta1.FeatureHelm, nil)).To(BeFalse())
+
+		clusterSummary.Status.FeatureSummaries[0].Status = configv1beta1.FeatureStatusProvisioned
+		Expect(controllers.IsFeatureReady(reconciler, clusterSummary, configv1beta1.FeatureHelm, nil)).To(BeTrue())
+	})
+})
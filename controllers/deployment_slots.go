@@ -0,0 +1,306 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
+)
+
+// A ClusterProfile/Profile can limit the number of matching clusters add-ons are deployed to
+// at the same time (Spec.MaxConcurrentClusterDeployments). Each ClusterSummary must acquire a
+// deployment slot before deploying and holds it till all features are successfully deployed.
+// ClusterSummaries waiting for a slot are queued in arrival order and report their (approximate)
+// position in Status.QueuePosition. Queue is informational only: any waiting ClusterSummary can
+// get a freed slot.
+// A ClusterSummary which keeps failing does not hold a slot forever. It gives it up (yields) when:
+// - deploying a feature failed deploymentSlotMaxFailures consecutive times;
+// - it has been holding the slot for longer than DeploymentSlotTimeout while others are waiting.
+// A ClusterSummary which yielded its slot is queued behind the ClusterSummaries already waiting, and
+// gets a slot again only once none of those is waiting anymore.
+// Slots are tracked in memory. On restart ClusterSummaries simply acquire slots again.
+
+const (
+	// deploymentSlotMaxFailures is the number of consecutive failures deploying a feature after
+	// which ClusterSummary yields its deployment slot
+	deploymentSlotMaxFailures = 3
+
+	defaultDeploymentSlotTimeout = 30 * time.Minute
+)
+
+// getProfileKey returns the key identifying the ClusterProfile/Profile owning the ClusterSummary
+func getProfileKey(clusterSummary *configv1beta1.ClusterSummary) (*corev1.ObjectReference, error) {
+	ownerRef, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
+	if err != nil {
+		return nil, err
+	}
+
+	profileKey := &corev1.ObjectReference{
+		APIVersion: ownerRef.APIVersion,
+		Kind:       ownerRef.Kind,
+		Name:       ownerRef.Name,
+	}
+	if ownerRef.Kind == configv1beta1.ProfileKind {
+		profileKey.Namespace = clusterSummary.Namespace
+	}
+
+	return profileKey, nil
+}
+
+// acquireDeploymentSlot returns true if ClusterSummary can proceed deploying add-ons. This is the
+// case if profile does not limit concurrent deployments, if ClusterSummary already holds a slot or
+// if a slot is available.
+// ClusterSummary is left queued only while waiting for a slot. Otherwise it would be ahead of
+// ClusterSummaries which yielded their slot forever.
+func (r *ClusterSummaryReconciler) acquireDeploymentSlot(clusterSummary *configv1beta1.ClusterSummary,
+) (bool, error) {
+
+	maxConcurrent := clusterSummary.Spec.ClusterProfileSpec.MaxConcurrentClusterDeployments
+	if maxConcurrent <= 0 {
+		// Profile does not limit concurrent deployments (anymore)
+		r.releaseDeploymentSlot(clusterSummary)
+		return true, nil
+	}
+
+	profileKey, err := getProfileKey(clusterSummary)
+	if err != nil {
+		r.releaseDeploymentSlot(clusterSummary)
+		return false, err
+	}
+
+	clusterSummaryInfo := getKeyFromObject(r.Scheme, clusterSummary)
+
+	r.DeploymentSlotsMux.Lock()
+	defer r.DeploymentSlotsMux.Unlock()
+
+	if r.DeploymentSlots == nil {
+		r.DeploymentSlots = make(map[corev1.ObjectReference]*libsveltosset.Set)
+	}
+	if r.DeploymentQueue == nil {
+		r.DeploymentQueue = make(map[corev1.ObjectReference][]corev1.ObjectReference)
+	}
+	if r.DeploymentSlotsAcquired == nil {
+		r.DeploymentSlotsAcquired = make(map[corev1.ObjectReference]time.Time)
+	}
+	if r.DeploymentSlotsYielded == nil {
+		r.DeploymentSlotsYielded = make(map[corev1.ObjectReference]bool)
+	}
+
+	now := time.Now()
+	if profileSlots, ok := r.DeploymentSlots[*profileKey]; ok && profileSlots.Has(clusterSummaryInfo) {
+		acquired := r.DeploymentSlotsAcquired[*clusterSummaryInfo]
+		if now.Sub(acquired) <= r.getDeploymentSlotTimeout() || len(r.DeploymentQueue[*profileKey]) == 0 {
+			dequeue(r.DeploymentQueue, clusterSummaryInfo)
+			return true, nil
+		}
+		// Held for too long while others are waiting
+		r.yieldSlot(profileKey, clusterSummaryInfo)
+		return false, nil
+	}
+
+	if r.DeploymentSlotsYielded[*clusterSummaryInfo] && !isFirstInQueue(r.DeploymentQueue, profileKey, clusterSummaryInfo) {
+		// Others were waiting when ClusterSummary yielded its slot. They go first.
+		enqueue(r.DeploymentQueue, profileKey, clusterSummaryInfo)
+		return false, nil
+	}
+
+	if !acquireSlot(r.DeploymentSlots, profileKey, clusterSummaryInfo, maxConcurrent) {
+		enqueue(r.DeploymentQueue, profileKey, clusterSummaryInfo)
+		return false, nil
+	}
+
+	r.DeploymentSlotsAcquired[*clusterSummaryInfo] = now
+	delete(r.DeploymentSlotsYielded, *clusterSummaryInfo)
+	dequeue(r.DeploymentQueue, clusterSummaryInfo)
+	return true, nil
+}
+
+// releaseDeploymentSlot frees the deployment slot held, if any, by the ClusterSummary
+func (r *ClusterSummaryReconciler) releaseDeploymentSlot(clusterSummary *configv1beta1.ClusterSummary) {
+	clusterSummaryInfo := getKeyFromObject(r.Scheme, clusterSummary)
+
+	r.DeploymentSlotsMux.Lock()
+	defer r.DeploymentSlotsMux.Unlock()
+
+	releaseSlot(r.DeploymentSlots, clusterSummaryInfo)
+	delete(r.DeploymentSlotsAcquired, *clusterSummaryInfo)
+	delete(r.DeploymentSlotsYielded, *clusterSummaryInfo)
+	dequeue(r.DeploymentQueue, clusterSummaryInfo)
+}
+
+// yieldDeploymentSlotIfFailing makes ClusterSummary yield the deployment slot it holds, if any, if
+// deploying one of its features failed deploymentSlotMaxFailures consecutive times.
+// Returns true if slot was yielded.
+func (r *ClusterSummaryReconciler) yieldDeploymentSlotIfFailing(clusterSummary *configv1beta1.ClusterSummary) bool {
+	failing := false
+	for i := range clusterSummary.Status.FeatureSummaries {
+		if clusterSummary.Status.FeatureSummaries[i].ConsecutiveFailures >= deploymentSlotMaxFailures {
+			failing = true
+			break
+		}
+	}
+	if !failing {
+		return false
+	}
+
+	profileKey, err := getProfileKey(clusterSummary)
+	if err != nil {
+		return false
+	}
+
+	clusterSummaryInfo := getKeyFromObject(r.Scheme, clusterSummary)
+
+	r.DeploymentSlotsMux.Lock()
+	defer r.DeploymentSlotsMux.Unlock()
+
+	profileSlots, ok := r.DeploymentSlots[*profileKey]
+	if !ok || !profileSlots.Has(clusterSummaryInfo) {
+		return false
+	}
+
+	r.yieldSlot(profileKey, clusterSummaryInfo)
+	return true
+}
+
+// yieldSlot frees the slot held by the ClusterSummary and queues it behind the ClusterSummaries
+// already waiting.
+// Must be called with DeploymentSlotsMux held.
+func (r *ClusterSummaryReconciler) yieldSlot(profileKey, clusterSummaryInfo *corev1.ObjectReference) {
+	releaseSlot(r.DeploymentSlots, clusterSummaryInfo)
+	delete(r.DeploymentSlotsAcquired, *clusterSummaryInfo)
+	if r.DeploymentQueue == nil {
+		r.DeploymentQueue = make(map[corev1.ObjectReference][]corev1.ObjectReference)
+	}
+	if r.DeploymentSlotsYielded == nil {
+		r.DeploymentSlotsYielded = make(map[corev1.ObjectReference]bool)
+	}
+	r.DeploymentSlotsYielded[*clusterSummaryInfo] = true
+	enqueue(r.DeploymentQueue, profileKey, clusterSummaryInfo)
+}
+
+func (r *ClusterSummaryReconciler) getDeploymentSlotTimeout() time.Duration {
+	if r.DeploymentSlotTimeout <= 0 {
+		return defaultDeploymentSlotTimeout
+	}
+	return r.DeploymentSlotTimeout
+}
+
+// getDeploymentQueuePosition returns the 1-based position of the ClusterSummary among the ones
+// waiting for a deployment slot. Nil is returned if ClusterSummary is not waiting.
+func (r *ClusterSummaryReconciler) getDeploymentQueuePosition(clusterSummary *configv1beta1.ClusterSummary,
+) *int32 {
+
+	clusterSummaryInfo := getKeyFromObject(r.Scheme, clusterSummary)
+
+	r.DeploymentSlotsMux.Lock()
+	defer r.DeploymentSlotsMux.Unlock()
+
+	for k := range r.DeploymentQueue {
+		queue := r.DeploymentQueue[k]
+		for i := range queue {
+			if queue[i] == *clusterSummaryInfo {
+				position := int32(i + 1)
+				return &position
+			}
+		}
+	}
+
+	return nil
+}
+
+// acquireSlot returns true if ClusterSummary already holds one of the profile slots or if a
+// slot is available (in which case it is assigned to ClusterSummary).
+// Must be called with DeploymentSlotsMux held.
+func acquireSlot(slots map[corev1.ObjectReference]*libsveltosset.Set, profileKey,
+	clusterSummaryInfo *corev1.ObjectReference, maxConcurrent int32) bool {
+
+	profileSlots, ok := slots[*profileKey]
+	if !ok {
+		profileSlots = &libsveltosset.Set{}
+		slots[*profileKey] = profileSlots
+	}
+
+	if profileSlots.Has(clusterSummaryInfo) {
+		return true
+	}
+
+	if int32(profileSlots.Len()) >= maxConcurrent {
+		return false
+	}
+
+	profileSlots.Insert(clusterSummaryInfo)
+	return true
+}
+
+// releaseSlot frees the slot held, if any, by the ClusterSummary.
+// Must be called with DeploymentSlotsMux held.
+func releaseSlot(slots map[corev1.ObjectReference]*libsveltosset.Set, clusterSummaryInfo *corev1.ObjectReference) {
+	// Profile might have been changed/removed. Look for ClusterSummary in all profiles.
+	for k := range slots {
+		slots[k].Erase(clusterSummaryInfo)
+		if slots[k].Len() == 0 {
+			delete(slots, k)
+		}
+	}
+}
+
+// enqueue appends ClusterSummary to the profile queue, unless already queued.
+// Must be called with DeploymentSlotsMux held.
+func enqueue(queues map[corev1.ObjectReference][]corev1.ObjectReference, profileKey,
+	clusterSummaryInfo *corev1.ObjectReference) {
+
+	queue := queues[*profileKey]
+	for i := range queue {
+		if queue[i] == *clusterSummaryInfo {
+			return
+		}
+	}
+	queues[*profileKey] = append(queue, *clusterSummaryInfo)
+}
+
+// isFirstInQueue returns true if ClusterSummary is the first one waiting in the profile queue,
+// or the queue is empty.
+// Must be called with DeploymentSlotsMux held.
+func isFirstInQueue(queues map[corev1.ObjectReference][]corev1.ObjectReference, profileKey,
+	clusterSummaryInfo *corev1.ObjectReference) bool {
+
+	queue := queues[*profileKey]
+	return len(queue) == 0 || queue[0] == *clusterSummaryInfo
+}
+
+// dequeue removes ClusterSummary from the queues.
+// Must be called with DeploymentSlotsMux held.
+func dequeue(queues map[corev1.ObjectReference][]corev1.ObjectReference, clusterSummaryInfo *corev1.ObjectReference) {
+	// Profile might have been changed/removed. Look for ClusterSummary in all profiles.
+	for k := range queues {
+		queue := make([]corev1.ObjectReference, 0, len(queues[k]))
+		for i := range queues[k] {
+			if queues[k][i] != *clusterSummaryInfo {
+				queue = append(queue, queues[k][i])
+			}
+		}
+		if len(queue) == 0 {
+			delete(queues, k)
+		} else {
+			queues[k] = queue
+		}
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Deployment slots", func() {
+	var clusterProfileName string
+
+	BeforeEach(func() {
+		clusterProfileName = randomString()
+	})
+
+	getClusterSummary := func(maxConcurrent int32) *configv1beta1.ClusterSummary {
+		return &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       clusterProfileName,
+						UID:        "1",
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterProfileSpec: configv1beta1.Spec{
+					MaxConcurrentClusterDeployments: maxConcurrent,
+				},
+			},
+		}
+	}
+
+	It("acquireDeploymentSlot limits concurrent deployments per profile", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme}
+
+		cs1 := getClusterSummary(2)
+		cs2 := getClusterSummary(2)
+		cs3 := getClusterSummary(2)
+
+		for _, cs := range []*configv1beta1.ClusterSummary{cs1, cs2} {
+			acquired, err := controllers.AcquireDeploymentSlot(reconciler, cs)
+			Expect(err).To(BeNil())
+			Expect(acquired).To(BeTrue())
+		}
+
+		acquired, err := controllers.AcquireDeploymentSlot(reconciler, cs3)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeFalse())
+
+		// A ClusterSummary holding a slot keeps it
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+
+		controllers.ReleaseDeploymentSlot(reconciler, cs1)
+
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs3)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+	})
+
+	It("acquireDeploymentSlot does not limit deployments when MaxConcurrentClusterDeployments is not set", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme}
+
+		for i := 0; i < 5; i++ {
+			acquired, err := controllers.AcquireDeploymentSlot(reconciler, getClusterSummary(0))
+			Expect(err).To(BeNil())
+			Expect(acquired).To(BeTrue())
+		}
+		Expect(len(reconciler.DeploymentSlots)).To(BeZero())
+	})
+
+	It("getDeploymentQueuePosition reports position of ClusterSummaries waiting for a slot", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme}
+
+		cs1 := getClusterSummary(1)
+		cs2 := getClusterSummary(1)
+		cs3 := getClusterSummary(1)
+
+		for _, cs := range []*configv1beta1.ClusterSummary{cs1, cs2, cs3} {
+			_, err := controllers.AcquireDeploymentSlot(reconciler, cs)
+			Expect(err).To(BeNil())
+		}
+
+		Expect(controllers.GetDeploymentQueuePosition(reconciler, cs1)).To(BeNil())
+		Expect(*controllers.GetDeploymentQueuePosition(reconciler, cs2)).To(Equal(int32(1)))
+		Expect(*controllers.GetDeploymentQueuePosition(reconciler, cs3)).To(Equal(int32(2)))
+
+		// Trying again does not change the position
+		_, err := controllers.AcquireDeploymentSlot(reconciler, cs3)
+		Expect(err).To(BeNil())
+		Expect(*controllers.GetDeploymentQueuePosition(reconciler, cs3)).To(Equal(int32(2)))
+
+		// Any waiting ClusterSummary can get a freed slot
+		controllers.ReleaseDeploymentSlot(reconciler, cs1)
+		acquired, err := controllers.AcquireDeploymentSlot(reconciler, cs3)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+		Expect(controllers.GetDeploymentQueuePosition(reconciler, cs3)).To(BeNil())
+		Expect(*controllers.GetDeploymentQueuePosition(reconciler, cs2)).To(Equal(int32(1)))
+
+		// A deleted ClusterSummary leaves the queue
+		controllers.ReleaseDeploymentSlot(reconciler, cs2)
+		Expect(controllers.GetDeploymentQueuePosition(reconciler, cs2)).To(BeNil())
+		Expect(len(reconciler.DeploymentQueue)).To(BeZero())
+	})
+
+	It("ClusterSummary holding a slot for too long yields it to waiting ones", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme, DeploymentSlotTimeout: time.Minute}
+
+		cs1 := getClusterSummary(1)
+		cs2 := getClusterSummary(1)
+
+		acquired, err := controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+
+		for k := range reconciler.DeploymentSlotsAcquired {
+			reconciler.DeploymentSlotsAcquired[k] = time.Now().Add(-2 * time.Minute)
+		}
+
+		// Nobody is waiting, slot is kept
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs2)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeFalse())
+
+		// cs2 is waiting, cs1 yields its slot and is queued behind cs2
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeFalse())
+		Expect(*controllers.GetDeploymentQueuePosition(reconciler, cs1)).To(Equal(int32(2)))
+
+		// Slot is free, yet cs1 cannot get it back before cs2
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeFalse())
+
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs2)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+
+		controllers.ReleaseDeploymentSlot(reconciler, cs2)
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+		Expect(reconciler.DeploymentSlotsYielded).To(BeEmpty())
+	})
+
+	It("ClusterSummary not waiting for a slot anymore leaves the queue", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme, DeploymentSlotTimeout: time.Minute}
+
+		cs1 := getClusterSummary(1)
+		cs2 := getClusterSummary(1)
+
+		acquired, err := controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs2)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeFalse())
+
+		// cs1 yields its slot and is queued behind cs2
+		for k := range reconciler.DeploymentSlotsAcquired {
+			reconciler.DeploymentSlotsAcquired[k] = time.Now().Add(-2 * time.Minute)
+		}
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeFalse())
+
+		// Profile does not limit concurrent deployments anymore. cs2 does not wait and leaves the queue
+		cs2.Spec.ClusterProfileSpec.MaxConcurrentClusterDeployments = 0
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs2)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+		Expect(controllers.GetDeploymentQueuePosition(reconciler, cs2)).To(BeNil())
+
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+		Expect(controllers.GetDeploymentQueuePosition(reconciler, cs1)).To(BeNil())
+
+		// ClusterSummary whose profile cannot be found does not wait either
+		cs3 := getClusterSummary(1)
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs3)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeFalse())
+		cs3.OwnerReferences = nil
+		_, err = controllers.AcquireDeploymentSlot(reconciler, cs3)
+		Expect(err).ToNot(BeNil())
+		Expect(controllers.GetDeploymentQueuePosition(reconciler, cs3)).To(BeNil())
+		Expect(len(reconciler.DeploymentQueue)).To(BeZero())
+	})
+
+	It("yieldDeploymentSlotIfFailing frees slot of ClusterSummaries which keep failing", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme}
+
+		cs1 := getClusterSummary(1)
+		cs2 := getClusterSummary(1)
+
+		acquired, err := controllers.AcquireDeploymentSlot(reconciler, cs1)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs2)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeFalse())
+
+		cs1.Status.FeatureSummaries = []configv1beta1.FeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusFailed, ConsecutiveFailures: 1},
+		}
+		Expect(controllers.YieldDeploymentSlotIfFailing(reconciler, cs1)).To(BeFalse())
+
+		cs1.Status.FeatureSummaries[0].ConsecutiveFailures = 3
+		Expect(controllers.YieldDeploymentSlotIfFailing(reconciler, cs1)).To(BeTrue())
+		// Slot was already yielded
+		Expect(controllers.YieldDeploymentSlotIfFailing(reconciler, cs1)).To(BeFalse())
+
+		acquired, err = controllers.AcquireDeploymentSlot(reconciler, cs2)
+		Expect(err).To(BeNil())
+		Expect(acquired).To(BeTrue())
+	})
+})
@@ -0,0 +1,90 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/addonclient"
+)
+
+// DescribePath is the diagnostics endpoint path serving ProfileDescriptions:
+//   - /describe/clusterprofiles/<name>
+//   - /describe/profiles/<namespace>/<name>
+const DescribePath = "/describe/"
+
+// describeHandler serves, as JSON, the description of a ClusterProfile/Profile.
+// Descriptions are built from the controller cache.
+type describeHandler struct {
+	c client.Client
+}
+
+// NewDescribeHandler returns an http.Handler serving ProfileDescriptions on DescribePath
+func NewDescribeHandler(c client.Client) http.Handler {
+	return &describeHandler{c: c}
+}
+
+func (h *describeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profileKind, profileNamespace, profileName, ok := parseDescribePath(r.URL.Path)
+	if !ok {
+		http.Error(w, "expected "+DescribePath+"clusterprofiles/<name> or "+DescribePath+
+			"profiles/<namespace>/<name>", http.StatusNotFound)
+		return
+	}
+
+	description, err := addonclient.DescribeProfile(r.Context(), h.c, profileKind, profileNamespace, profileName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(description); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseDescribePath returns the ClusterProfile/Profile identified by path
+func parseDescribePath(path string) (profileKind, profileNamespace, profileName string, ok bool) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, DescribePath), "/"), "/")
+
+	const clusterProfileParts = 2
+	const profileParts = 3
+	switch {
+	case len(parts) == clusterProfileParts && parts[0] == "clusterprofiles" && parts[1] != "":
+		return configv1beta1.ClusterProfileKind, "", parts[1], true
+	case len(parts) == profileParts && parts[0] == "profiles" && parts[1] != "" && parts[2] != "":
+		return configv1beta1.ProfileKind, parts[1], parts[2], true
+	default:
+		return "", "", "", false
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/addonclient"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Describe handler", func() {
+	It("serves ClusterProfile/Profile descriptions", func() {
+		namespace := randomString()
+		profile := &configv1beta1.Profile{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Status: configv1beta1.Status{
+				MatchingClusterRefs: []corev1.ObjectReference{
+					{Namespace: namespace, Name: randomString(), Kind: libsveltosv1beta1.SveltosClusterKind},
+				},
+			},
+		}
+
+		initObjects := []client.Object{profile}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+		server := httptest.NewServer(controllers.NewDescribeHandler(c))
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + controllers.DescribePath + "profiles/" + namespace + "/" + profile.Name)
+		Expect(err).To(BeNil())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		description := &addonclient.ProfileDescription{}
+		Expect(json.NewDecoder(resp.Body).Decode(description)).To(Succeed())
+		Expect(description.Kind).To(Equal(configv1beta1.ProfileKind))
+		Expect(description.Name).To(Equal(profile.Name))
+		Expect(description.State).To(Equal(addonclient.DescribeStateProgressing))
+		Expect(description.Clusters).To(HaveLen(1))
+
+		for _, path := range []string{"clusterprofiles/" + randomString(), "profiles/" + namespace, "unknown"} {
+			notFound, err := http.Get(server.URL + controllers.DescribePath + path)
+			Expect(err).To(BeNil())
+			notFound.Body.Close()
+			Expect(notFound.StatusCode).To(Equal(http.StatusNotFound))
+		}
+	})
+})
@@ -0,0 +1,190 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	driftdetection "github.com/projectsveltos/addon-controller/pkg/drift-detection"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/libsveltos/lib/utils"
+)
+
+// drift-detection-manager is deployed in a cluster (or, for the cluster, in the management cluster)
+// as soon as one ClusterSummary matching the cluster has SyncMode set to ContinuousWithDriftDetection.
+// Once no ClusterSummary for the cluster requires drift detection anymore (ClusterSummaries are
+// deleted or their SyncMode changes) drift-detection-manager is removed.
+// ClusterSummaries requiring drift detection are tracked in memory only to detect SyncMode changes.
+// A SyncMode change happening while addon-controller is not running is not detected.
+
+var (
+	driftDetectionMux sync.Mutex
+	// key: cluster (getDriftDetectionClusterKey); value: names of ClusterSummaries requiring drift detection
+	driftDetectionClusterSummaries = make(map[string]map[string]bool)
+)
+
+func getDriftDetectionClusterKey(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) string {
+
+	return fmt.Sprintf("%s:%s/%s", clusterType, clusterNamespace, clusterName)
+}
+
+// trackDriftDetectionRequirement records whether clusterSummary requires drift-detection-manager
+// to run for its cluster. It returns true if clusterSummary was tracked as requiring it.
+func trackDriftDetectionRequirement(clusterSummary *configv1beta1.ClusterSummary, required bool) bool {
+	key := getDriftDetectionClusterKey(clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+		clusterSummary.Spec.ClusterType)
+
+	driftDetectionMux.Lock()
+	defer driftDetectionMux.Unlock()
+
+	clusterSummaries := driftDetectionClusterSummaries[key]
+	wasRequired := clusterSummaries[clusterSummary.Name]
+
+	if required {
+		if clusterSummaries == nil {
+			clusterSummaries = make(map[string]bool)
+			driftDetectionClusterSummaries[key] = clusterSummaries
+		}
+		clusterSummaries[clusterSummary.Name] = true
+		return wasRequired
+	}
+
+	delete(clusterSummaries, clusterSummary.Name)
+	if len(clusterSummaries) == 0 {
+		delete(driftDetectionClusterSummaries, key)
+	}
+	return wasRequired
+}
+
+// isDriftDetectionRequiredByCluster returns true if at least one ClusterSummary, not marked for deletion,
+// matching the cluster clusterType:clusterNamespace/clusterName has SyncMode set to ContinuousWithDriftDetection
+func isDriftDetectionRequiredByCluster(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType) (bool, error) {
+
+	clusterSummaries := &configv1beta1.ClusterSummaryList{}
+	if err := c.List(ctx, clusterSummaries, client.InNamespace(clusterNamespace)); err != nil {
+		return false, err
+	}
+
+	for i := range clusterSummaries.Items {
+		cs := &clusterSummaries.Items[i]
+		if !cs.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if cs.Spec.ClusterName != clusterName || cs.Spec.ClusterType != clusterType {
+			continue
+		}
+		if cs.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeContinuousWithDriftDetection {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// removeDriftDetectionManagerIfNotRequired removes drift-detection-manager for the cluster matching
+// clusterSummary if no other ClusterSummary for the same cluster requires drift detection.
+// drift-detection-manager is removed from both the managed and the management cluster, as the placement
+// it was deployed with might have changed since.
+func removeDriftDetectionManagerIfNotRequired(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, logger logr.Logger) error {
+
+	clusterNamespace := clusterSummary.Spec.ClusterNamespace
+	clusterName := clusterSummary.Spec.ClusterName
+	clusterType := clusterSummary.Spec.ClusterType
+
+	required, err := isDriftDetectionRequiredByCluster(ctx, c, clusterNamespace, clusterName, clusterType)
+	if err != nil {
+		return err
+	}
+	if required {
+		logger.V(logs.LogDebug).Info("drift-detection-manager still required by other ClusterSummaries")
+		return nil
+	}
+
+	logger.V(logs.LogDebug).Info("no ClusterSummary requires drift detection. Remove drift-detection-manager")
+	if !configv1beta1.IsManagementCluster(clusterName, clusterType) {
+		err = removeDriftDetectionManagerFromManagementCluster(ctx, clusterNamespace, clusterName,
+			clusterType, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	return removeDriftDetectionManagerFromManagedCluster(ctx, c, clusterNamespace, clusterName,
+		clusterType, logger)
+}
+
+// removeDriftDetectionManagerFromManagedCluster removes the drift-detection-manager resources
+// installed in the managed cluster clusterType:clusterNamespace/clusterName.
+// Namespace is left as other Sveltos agents run there.
+func removeDriftDetectionManagerFromManagedCluster(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType,
+	logger logr.Logger) error {
+
+	remoteRestConfig, err := getKubernetesRestConfig(ctx, c, clusterNamespace,
+		clusterName, "", "", clusterType, logger)
+	if err != nil {
+		logger.V(logs.LogInfo).Error(err, "failed to get cluster rest config")
+		return err
+	}
+
+	driftDetectionManagerYAML := string(driftdetection.GetDriftDetectionManagerYAML())
+	driftDetectionManagerYAML = prepareDriftDetectionManagerYAML(driftDetectionManagerYAML, clusterNamespace,
+		clusterName, "", clusterType)
+
+	elements, err := customSplit(driftDetectionManagerYAML)
+	if err != nil {
+		return err
+	}
+	for i := range elements {
+		policy, err := utils.GetUnstructured([]byte(elements[i]))
+		if err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to parse drift detection manager yaml: %v", err))
+			return err
+		}
+
+		if policy.GetKind() == "Namespace" {
+			continue
+		}
+
+		dr, err := utils.GetDynamicResourceInterface(remoteRestConfig, policy.GroupVersionKind(), policy.GetNamespace())
+		if err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get dynamic client: %v", err))
+			return err
+		}
+
+		err = dr.Delete(ctx, policy.GetName(), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to delete resource %s:%s/%s: %v",
+				policy.GetKind(), policy.GetNamespace(), policy.GetName(), err))
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Drift detection cleanup", func() {
+	var clusterNamespace string
+	var clusterName string
+
+	getClusterSummary := func(syncMode configv1beta1.SyncMode) *configv1beta1.ClusterSummary {
+		return &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      randomString(),
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: clusterNamespace,
+				ClusterName:      clusterName,
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+				ClusterProfileSpec: configv1beta1.Spec{
+					SyncMode: syncMode,
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		clusterNamespace = randomString()
+		clusterName = randomString()
+	})
+
+	It("trackDriftDetectionRequirement returns whether ClusterSummary was requiring drift detection", func() {
+		clusterSummary := getClusterSummary(configv1beta1.SyncModeContinuousWithDriftDetection)
+
+		Expect(controllers.TrackDriftDetectionRequirement(clusterSummary, true)).To(BeFalse())
+		Expect(controllers.TrackDriftDetectionRequirement(clusterSummary, true)).To(BeTrue())
+		Expect(controllers.TrackDriftDetectionRequirement(clusterSummary, false)).To(BeTrue())
+		Expect(controllers.TrackDriftDetectionRequirement(clusterSummary, false)).To(BeFalse())
+	})
+
+	It("isDriftDetectionRequiredByCluster considers only ClusterSummaries for the cluster not being deleted", func() {
+		continuous := getClusterSummary(configv1beta1.SyncModeContinuous)
+
+		otherCluster := getClusterSummary(configv1beta1.SyncModeContinuousWithDriftDetection)
+		otherCluster.Spec.ClusterName = randomString()
+
+		deleted := getClusterSummary(configv1beta1.SyncModeContinuousWithDriftDetection)
+		deleted.Finalizers = []string{configv1beta1.ClusterSummaryFinalizer}
+		deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		initObjects := []client.Object{continuous, otherCluster, deleted}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+
+		required, err := controllers.IsDriftDetectionRequiredByCluster(context.TODO(), c, clusterNamespace,
+			clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(required).To(BeFalse())
+
+		Expect(c.Create(context.TODO(), getClusterSummary(configv1beta1.SyncModeContinuousWithDriftDetection))).To(Succeed())
+
+		required, err = controllers.IsDriftDetectionRequiredByCluster(context.TODO(), c, clusterNamespace,
+			clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(required).To(BeTrue())
+	})
+})
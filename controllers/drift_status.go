@@ -0,0 +1,152 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// drift-detection-manager only reports, in the ResourceSummary, which features drifted along
+// with the current hash of each resource. To report which resources drifted, the hashes seen
+// during the previous ResourceSummary collection are kept in memory: resources whose hash changed
+// are the ones which drifted. After a restart, drifted resources cannot be reported until hashes
+// are collected once.
+
+const (
+	// maxRecentDrifts is the number of drifts kept in ClusterSummary Status.Drift.RecentDrifts
+	maxRecentDrifts = 10
+)
+
+var (
+	driftHashesMux sync.Mutex
+	// key: ResourceSummary key (getResourceSummaryKey); value: feature and resource key => hash
+	driftHashes = make(map[string]map[string]string)
+)
+
+func getDriftClusterKey(cluster *corev1.ObjectReference) string {
+	return fmt.Sprintf("%s:%s/%s/", cluster.Kind, cluster.Namespace, cluster.Name)
+}
+
+func getResourceSummaryKey(clusterKey string, rs *libsveltosv1beta1.ResourceSummary) string {
+	return fmt.Sprintf("%s%s/%s", clusterKey, rs.Namespace, rs.Name)
+}
+
+func getDriftedResourceKey(resource *libsveltosv1beta1.Resource) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", resource.Group, resource.Version, resource.Kind,
+		resource.Namespace, resource.Name)
+}
+
+func getResourceSummaryHashes(rs *libsveltosv1beta1.ResourceSummary, featureID configv1beta1.FeatureID,
+) []libsveltosv1beta1.ResourceHash {
+
+	switch featureID {
+	case configv1beta1.FeatureHelm:
+		return rs.Status.HelmResourceHashes
+	case configv1beta1.FeatureKustomize:
+		return rs.Status.KustomizeResourceHashes
+	case configv1beta1.FeatureResources:
+		return rs.Status.ResourceHashes
+	default:
+		return nil
+	}
+}
+
+// getDriftedResources returns the resources deployed because of featureID whose hash, in rs,
+// is different from the one seen during previous collection.
+func getDriftedResources(rsKey string, rs *libsveltosv1beta1.ResourceSummary, featureID configv1beta1.FeatureID,
+) []configv1beta1.DriftedResource {
+
+	driftHashesMux.Lock()
+	defer driftHashesMux.Unlock()
+
+	previous, ok := driftHashes[rsKey]
+	if !ok {
+		return nil
+	}
+
+	resources := make([]configv1beta1.DriftedResource, 0)
+	hashes := getResourceSummaryHashes(rs, featureID)
+	for i := range hashes {
+		resource := &hashes[i].Resource
+		hash, ok := previous[string(featureID)+"/"+getDriftedResourceKey(resource)]
+		if !ok || hash == hashes[i].Hash {
+			continue
+		}
+		resources = append(resources, configv1beta1.DriftedResource{
+			Group:     resource.Group,
+			Version:   resource.Version,
+			Kind:      resource.Kind,
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		})
+	}
+
+	return resources
+}
+
+// storeResourceSummaryHashes stores hashes currently reported in rs
+func storeResourceSummaryHashes(rsKey string, rs *libsveltosv1beta1.ResourceSummary) {
+	current := make(map[string]string)
+	for _, featureID := range []configv1beta1.FeatureID{configv1beta1.FeatureResources,
+		configv1beta1.FeatureKustomize, configv1beta1.FeatureHelm} {
+
+		hashes := getResourceSummaryHashes(rs, featureID)
+		for i := range hashes {
+			current[string(featureID)+"/"+getDriftedResourceKey(&hashes[i].Resource)] = hashes[i].Hash
+		}
+	}
+
+	driftHashesMux.Lock()
+	defer driftHashesMux.Unlock()
+	driftHashes[rsKey] = current
+}
+
+// removeStaleResourceSummaryHashes removes hashes stored for ResourceSummaries in the cluster
+// identified by clusterKey which do not exist anymore
+func removeStaleResourceSummaryHashes(clusterKey string, currentKeys map[string]bool) {
+	driftHashesMux.Lock()
+	defer driftHashesMux.Unlock()
+
+	for key := range driftHashes {
+		if strings.HasPrefix(key, clusterKey) && !currentKeys[key] {
+			delete(driftHashes, key)
+		}
+	}
+}
+
+// recordDrift updates clusterSummary Status.Drift with a new drift event
+func recordDrift(clusterSummary *configv1beta1.ClusterSummary, event *configv1beta1.DriftEvent) {
+	if clusterSummary.Status.Drift == nil {
+		clusterSummary.Status.Drift = &configv1beta1.DriftStatus{}
+	}
+
+	drift := clusterSummary.Status.Drift
+	drift.DriftCount++
+	drift.LastDriftTime = &metav1.Time{Time: event.DetectionTime.Time}
+	drift.RecentDrifts = append(drift.RecentDrifts, *event)
+	if len(drift.RecentDrifts) > maxRecentDrifts {
+		drift.RecentDrifts = drift.RecentDrifts[len(drift.RecentDrifts)-maxRecentDrifts:]
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Drift status", func() {
+	It("getDriftedResources returns resources whose hash changed since previous collection", func() {
+		deployment := libsveltosv1beta1.Resource{Group: "apps", Version: "v1", Kind: "Deployment",
+			Namespace: randomString(), Name: randomString()}
+		service := libsveltosv1beta1.Resource{Version: "v1", Kind: "Service",
+			Namespace: randomString(), Name: randomString()}
+
+		rs := &libsveltosv1beta1.ResourceSummary{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "projectsveltos", Name: randomString()},
+			Status: libsveltosv1beta1.ResourceSummaryStatus{
+				ResourceHashes: []libsveltosv1beta1.ResourceHash{
+					{Resource: deployment, Hash: randomString()},
+					{Resource: service, Hash: randomString()},
+				},
+			},
+		}
+		rsKey := randomString()
+
+		// Nothing collected yet, so drifted resources are unknown
+		Expect(controllers.GetDriftedResources(rsKey, rs, configv1beta1.FeatureResources)).To(BeEmpty())
+
+		controllers.StoreResourceSummaryHashes(rsKey, rs)
+		Expect(controllers.GetDriftedResources(rsKey, rs, configv1beta1.FeatureResources)).To(BeEmpty())
+
+		rs.Status.ResourceHashes[0].Hash = randomString()
+		Expect(controllers.GetDriftedResources(rsKey, rs, configv1beta1.FeatureResources)).To(ConsistOf(
+			configv1beta1.DriftedResource{Group: "apps", Version: "v1", Kind: "Deployment",
+				Namespace: deployment.Namespace, Name: deployment.Name}))
+		Expect(controllers.GetDriftedResources(rsKey, rs, configv1beta1.FeatureHelm)).To(BeEmpty())
+	})
+
+	It("recordDrift updates ClusterSummary drift status keeping only most recent drifts", func() {
+		clusterSummary := &configv1beta1.ClusterSummary{}
+
+		const drifts = 15
+		for i := 0; i < drifts; i++ {
+			controllers.RecordDrift(clusterSummary, &configv1beta1.DriftEvent{
+				FeatureID:     configv1beta1.FeatureHelm,
+				DetectionTime: metav1.Now(),
+				Action:        configv1beta1.DriftActionRedeploy,
+			})
+		}
+
+		Expect(clusterSummary.Status.Drift).ToNot(BeNil())
+		Expect(clusterSummary.Status.Drift.DriftCount).To(Equal(int64(drifts)))
+		Expect(clusterSummary.Status.Drift.LastDriftTime).ToNot(BeNil())
+		Expect(clusterSummary.Status.Drift.RecentDrifts).To(HaveLen(10))
+	})
+})
@@ -0,0 +1,151 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
+)
+
+// When EnforceInterval is set, all features are periodically re-applied even if their hash has
+// not changed. Re-applying is done by resetting the feature hashes in the ClusterSummary Status,
+// so features go through the same path followed when configuration changes.
+// Each ClusterSummary is delayed by a jitter (up to 10% of EnforceInterval) derived from its name,
+// so clusters matching the same profile are not all re-applied together. At most
+// MaxConcurrentEnforcements clusters per profile are re-applied at the same time. An enforcement
+// slot is held till all features are successfully re-applied.
+
+// getEnforceJitter returns the delay, up to 10% of interval, added to interval for this ClusterSummary.
+// It is stable across reconciliations and restarts.
+func getEnforceJitter(clusterSummary *configv1beta1.ClusterSummary, interval time.Duration) time.Duration {
+	maxJitter := interval / 10
+	if maxJitter <= 0 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(clusterSummary.Namespace + "/" + clusterSummary.Name))
+
+	return time.Duration(h.Sum64() % uint64(maxJitter))
+}
+
+// isEnforceEnabled returns true if features must be periodically re-applied to the cluster
+func isEnforceEnabled(clusterSummaryScope *scope.ClusterSummaryScope) bool {
+	interval := clusterSummaryScope.ClusterSummary.Spec.ClusterProfileSpec.EnforceInterval
+	return interval != nil && interval.Duration > 0 && clusterSummaryScope.IsContinuousSync()
+}
+
+// getNextEnforceTime returns when features must be re-applied next. Zero time is returned if
+// features are not periodically re-applied.
+func getNextEnforceTime(clusterSummaryScope *scope.ClusterSummaryScope) time.Time {
+	clusterSummary := clusterSummaryScope.ClusterSummary
+	if !isEnforceEnabled(clusterSummaryScope) || clusterSummary.Status.LastEnforcedTime == nil {
+		return time.Time{}
+	}
+
+	interval := clusterSummary.Spec.ClusterProfileSpec.EnforceInterval.Duration
+	return clusterSummary.Status.LastEnforcedTime.Add(interval + getEnforceJitter(clusterSummary, interval))
+}
+
+// enforceIfDue resets the feature hashes if features must be re-applied now. Returns true if
+// re-applying is due but must wait for an enforcement slot.
+func (r *ClusterSummaryReconciler) enforceIfDue(clusterSummaryScope *scope.ClusterSummaryScope,
+	now time.Time, logger logr.Logger) (bool, error) {
+
+	clusterSummary := clusterSummaryScope.ClusterSummary
+	if !isEnforceEnabled(clusterSummaryScope) {
+		clusterSummary.Status.LastEnforcedTime = nil
+		r.releaseEnforcementSlot(clusterSummary)
+		return false, nil
+	}
+
+	if clusterSummary.Status.LastEnforcedTime == nil {
+		// Start counting from now. Features are being deployed anyhow.
+		clusterSummary.Status.LastEnforcedTime = &metav1.Time{Time: now}
+		return false, nil
+	}
+
+	if now.Before(getNextEnforceTime(clusterSummaryScope)) {
+		return false, nil
+	}
+
+	acquired, err := r.acquireEnforcementSlot(clusterSummary)
+	if err != nil {
+		return false, err
+	}
+	if !acquired {
+		logger.V(logs.LogDebug).Info("maximum number of concurrent enforcements reached for profile. Wait.")
+		return true, nil
+	}
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("enforce interval elapsed (last enforced at %s). Re-applying all features",
+		clusterSummary.Status.LastEnforcedTime.UTC().Format(time.RFC3339)))
+	for i := range clusterSummary.Status.FeatureSummaries {
+		clusterSummary.Status.FeatureSummaries[i].Hash = nil
+	}
+	clusterSummary.Status.LastEnforcedTime = &metav1.Time{Time: now}
+
+	return false, nil
+}
+
+// acquireEnforcementSlot returns true if ClusterSummary can proceed re-applying features. This is
+// the case if profile does not limit concurrent enforcements, if ClusterSummary already holds a slot
+// or if a slot is available.
+func (r *ClusterSummaryReconciler) acquireEnforcementSlot(clusterSummary *configv1beta1.ClusterSummary,
+) (bool, error) {
+
+	maxConcurrent := clusterSummary.Spec.ClusterProfileSpec.MaxConcurrentEnforcements
+	if maxConcurrent <= 0 {
+		return true, nil
+	}
+
+	profileKey, err := getProfileKey(clusterSummary)
+	if err != nil {
+		return false, err
+	}
+
+	clusterSummaryInfo := getKeyFromObject(r.Scheme, clusterSummary)
+
+	r.DeploymentSlotsMux.Lock()
+	defer r.DeploymentSlotsMux.Unlock()
+
+	if r.EnforcementSlots == nil {
+		r.EnforcementSlots = make(map[corev1.ObjectReference]*libsveltosset.Set)
+	}
+
+	return acquireSlot(r.EnforcementSlots, profileKey, clusterSummaryInfo, maxConcurrent), nil
+}
+
+// releaseEnforcementSlot frees the enforcement slot held, if any, by the ClusterSummary
+func (r *ClusterSummaryReconciler) releaseEnforcementSlot(clusterSummary *configv1beta1.ClusterSummary) {
+	clusterSummaryInfo := getKeyFromObject(r.Scheme, clusterSummary)
+
+	r.DeploymentSlotsMux.Lock()
+	defer r.DeploymentSlotsMux.Unlock()
+
+	releaseSlot(r.EnforcementSlots, clusterSummaryInfo)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+)
+
+var _ = Describe("Enforce interval", func() {
+	var clusterProfileName string
+
+	BeforeEach(func() {
+		clusterProfileName = randomString()
+	})
+
+	getClusterSummaryScope := func(maxConcurrent int32, lastEnforced time.Time) *scope.ClusterSummaryScope {
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       clusterProfileName,
+						UID:        "1",
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterProfileSpec: configv1beta1.Spec{
+					SyncMode:                  configv1beta1.SyncModeContinuous,
+					EnforceInterval:           &metav1.Duration{Duration: time.Hour},
+					MaxConcurrentEnforcements: maxConcurrent,
+				},
+			},
+			Status: configv1beta1.ClusterSummaryStatus{
+				LastEnforcedTime: &metav1.Time{Time: lastEnforced},
+				FeatureSummaries: []configv1beta1.FeatureSummary{
+					{FeatureID: configv1beta1.FeatureHelm, Hash: []byte(randomString()),
+						Status: configv1beta1.FeatureStatusProvisioned},
+				},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterSummary).Build()
+		clusterSummaryScope, err := scope.NewClusterSummaryScope(&scope.ClusterSummaryScopeParams{
+			Client:         c,
+			Logger:         logr.Discard(),
+			ClusterSummary: clusterSummary,
+			ControllerName: "clustersummary",
+		})
+		Expect(err).To(BeNil())
+		return clusterSummaryScope
+	}
+
+	It("getEnforceJitter is stable and up to 10% of interval", func() {
+		clusterSummaryScope := getClusterSummaryScope(0, time.Now())
+		clusterSummary := clusterSummaryScope.ClusterSummary
+
+		jitter := controllers.GetEnforceJitter(clusterSummary, time.Hour)
+		Expect(jitter >= 0).To(BeTrue())
+		Expect(jitter < 6*time.Minute).To(BeTrue())
+		Expect(controllers.GetEnforceJitter(clusterSummary, time.Hour)).To(Equal(jitter))
+
+		Expect(controllers.GetNextEnforceTime(clusterSummaryScope)).To(
+			Equal(clusterSummary.Status.LastEnforcedTime.Add(time.Hour + jitter)))
+	})
+
+	It("enforceIfDue resets feature hashes only once EnforceInterval has elapsed", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme}
+		now := time.Now()
+
+		clusterSummaryScope := getClusterSummaryScope(0, now.Add(-time.Minute))
+		waiting, err := controllers.EnforceIfDue(reconciler, clusterSummaryScope, now, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(waiting).To(BeFalse())
+		Expect(clusterSummaryScope.ClusterSummary.Status.FeatureSummaries[0].Hash).ToNot(BeNil())
+
+		clusterSummaryScope = getClusterSummaryScope(0, now.Add(-2*time.Hour))
+		waiting, err = controllers.EnforceIfDue(reconciler, clusterSummaryScope, now, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(waiting).To(BeFalse())
+		Expect(clusterSummaryScope.ClusterSummary.Status.FeatureSummaries[0].Hash).To(BeNil())
+		Expect(clusterSummaryScope.ClusterSummary.Status.LastEnforcedTime.Time).To(Equal(now))
+	})
+
+	It("enforceIfDue limits concurrent enforcements per profile", func() {
+		reconciler := &controllers.ClusterSummaryReconciler{Scheme: scheme}
+		now := time.Now()
+
+		first := getClusterSummaryScope(1, now.Add(-2*time.Hour))
+		waiting, err := controllers.EnforceIfDue(reconciler, first, now, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(waiting).To(BeFalse())
+
+		second := getClusterSummaryScope(1, now.Add(-2*time.Hour))
+		waiting, err = controllers.EnforceIfDue(reconciler, second, now, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(waiting).To(BeTrue())
+		Expect(second.ClusterSummary.Status.FeatureSummaries[0].Hash).ToNot(BeNil())
+
+		// Once first cluster is done re-applying, second one can proceed
+		controllers.ReleaseEnforcementSlot(reconciler, first.ClusterSummary)
+		waiting, err = controllers.EnforceIfDue(reconciler, second, now, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(waiting).To(BeFalse())
+		Expect(second.ClusterSummary.Status.FeatureSummaries[0].Hash).To(BeNil())
+	})
+})
@@ -16,12 +16,27 @@ limitations under the License.
 
 package controllers
 
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
 var (
 	UpdateClusterSummaries                = updateClusterSummaries
 	CreateClusterSummary                  = createClusterSummary
+	AdoptLegacyClusterSummary             = adoptLegacyClusterSummary
 	UpdateClusterSummary                  = updateClusterSummary
 	UpdateClusterConfigurationWithProfile = updateClusterConfigurationWithProfile
+	CreateClusterConfiguration            = createClusterConfiguration
 	CleanClusterConfiguration             = cleanClusterConfiguration
+	CleanClusterConfigurations            = cleanClusterConfigurations
 	CleanClusterReports                   = cleanClusterReports
 	CleanClusterSummaries                 = cleanClusterSummaries
 	UpdateClusterSummarySyncMode          = updateClusterSummarySyncMode
@@ -30,6 +45,7 @@ var (
 	GetMaxUpdate                          = getMaxUpdate
 	ReviseUpdatedAndUpdatingClusters      = reviseUpdatedAndUpdatingClusters
 	GetUpdatedAndUpdatingClusters         = getUpdatedAndUpdatingClusters
+	UpdateClusterSummariesStatus          = updateClusterSummariesStatus
 )
 
 var (
@@ -60,6 +76,10 @@ var (
 	ShouldRedeploy                       = (*ClusterSummaryReconciler).shouldRedeploy
 	CanRemoveFinalizer                   = (*ClusterSummaryReconciler).canRemoveFinalizer
 	ReconcileDelete                      = (*ClusterSummaryReconciler).reconcileDelete
+	AcquireDeploymentSlot                = (*ClusterSummaryReconciler).acquireDeploymentSlot
+	ReleaseDeploymentSlot                = (*ClusterSummaryReconciler).releaseDeploymentSlot
+	YieldDeploymentSlotIfFailing         = (*ClusterSummaryReconciler).yieldDeploymentSlotIfFailing
+	GetDeploymentQueuePosition           = (*ClusterSummaryReconciler).getDeploymentQueuePosition
 	AreDependenciesDeployed              = (*ClusterSummaryReconciler).areDependenciesDeployed
 	SetFailureMessage                    = (*ClusterSummaryReconciler).setFailureMessage
 	ResetFeatureStatus                   = (*ClusterSummaryReconciler).resetFeatureStatus
@@ -67,6 +87,7 @@ var (
 	ConvertResultStatus               = (*ClusterSummaryReconciler).convertResultStatus
 	RequeueClusterSummaryForReference = (*ClusterSummaryReconciler).requeueClusterSummaryForReference
 	RequeueClusterSummaryForCluster   = (*ClusterSummaryReconciler).requeueClusterSummaryForCluster
+	RequeueClusterSummaryForSecret    = (*ClusterSummaryReconciler).requeueClusterSummaryForSecret
 )
 
 var (
@@ -78,6 +99,8 @@ var (
 	GetClusterSummary            = getClusterSummary
 	AddLabel                     = addLabel
 	UpdateResource               = updateResource
+	GetFieldManager              = getFieldManager
+	IsFieldManagerConflict       = isFieldManagerConflict
 	CreateNamespace              = createNamespace
 	GetEntryKey                  = getEntryKey
 	DeployContentOfConfigMap     = deployContentOfConfigMap
@@ -92,16 +115,19 @@ var (
 	UndeployStaleResources       = undeployStaleResources
 	GetDeployedGroupVersionKinds = getDeployedGroupVersionKinds
 	CanDelete                    = canDelete
+	CanDeployResource            = canDeployResource
 	HandleResourceDelete         = handleResourceDelete
 	GetSecret                    = getSecret
 	ReadFiles                    = readFiles
+	GetSourceArtifactPath        = getSourceArtifactPath
 
 	AddExtraLabels      = addExtraLabels
 	AddExtraAnnotations = addExtraAnnotations
 	AdjustNamespace     = adjustNamespace
 
-	ResourcesHash   = resourcesHash
-	GetResourceRefs = getResourceRefs
+	ResourcesHash                          = resourcesHash
+	GetResourceRefs                        = getResourceRefs
+	UpdateClusterReportWithResourceReports = updateClusterReportWithResourceReports
 
 	UndeployKustomizeRefs             = undeployKustomizeRefs
 	KustomizationHash                 = kustomizationHash
@@ -129,13 +155,32 @@ var (
 	GetHelmReferenceResourceHash             = getHelmReferenceResourceHash
 	GetHelmChartValuesHash                   = getHelmChartValuesHash
 	GetCredentialsAndCAFiles                 = getCredentialsAndCAFiles
+	GetTextDiff                              = getTextDiff
+	MergeHelmValues                          = mergeHelmValues
+	IsReleasePending                         = isReleasePending
+	IsPendingReleaseStale                    = isPendingReleaseStale
+	TrackHelmOperation                       = trackHelmOperation
+	IsHelmOperationInProgress                = isHelmOperationInProgress
+	TrackFeatureOperation                    = trackFeatureOperation
+	DrainRemoteOperations                    = drainRemoteOperations
+	IsReleaseInterrupted                     = isReleaseInterrupted
+	TakeInterruptedFeature                   = takeInterruptedFeature
+	GetOperationCheckpointsName              = getOperationCheckpointsName
+	StoreOperationCheckpoints                = storeOperationCheckpoints
+	LoadOperationCheckpoints                 = loadOperationCheckpoints
+	GetMatchingValuesOverrides               = getMatchingValuesOverrides
+	CreateReleaseNamespaces                  = createReleaseNamespaces
 
 	InstantiateTemplateValues = instantiateTemplateValues
 
-	IsCluterSummaryProvisioned = isCluterSummaryProvisioned
-	IsNamespaced               = isNamespaced
-	StringifyMap               = stringifyMap
-	ParseMapFromString         = parseMapFromString
+	IsCluterSummaryProvisioned         = isCluterSummaryProvisioned
+	AreClusterReadinessChecksSatisfied = areClusterReadinessChecksSatisfied
+	RemoveStaleClusterResources        = removeStaleClusterResources
+	RemoveOrphanedClusterResources     = removeOrphanedClusterResources
+	ForEachListItem                    = forEachListItem
+	IsNamespaced                       = isNamespaced
+	StringifyMap                       = stringifyMap
+	ParseMapFromString                 = parseMapFromString
 )
 
 type (
@@ -153,11 +198,18 @@ var (
 	DeployResourceSummaryInCluster                   = deployResourceSummaryInCluster
 	DeployResourceSummaryInstance                    = deployResourceSummaryInstance
 	UpdateDeployedGroupVersionKind                   = updateDeployedGroupVersionKind
+	UpdateResourceInventory                          = updateResourceInventory
+	GetResourceInventory                             = getResourceInventory
+	GetInventoryEntry                                = getInventoryEntry
+	ParseInventoryEntry                              = parseInventoryEntry
 	DeployDriftDetectionManagerInManagementCluster   = deployDriftDetectionManagerInManagementCluster
 	GetDriftDetectionManagerLabels                   = getDriftDetectionManagerLabels
 	RemoveDriftDetectionManagerFromManagementCluster = removeDriftDetectionManagerFromManagementCluster
 	GetDriftDetectionNamespaceInMgmtCluster          = getDriftDetectionNamespaceInMgmtCluster
 	TransformDriftExclusionsToPatches                = transformDriftExclusionsToPatches
+	FilterDriftExcludedResources                     = filterDriftExcludedResources
+	FilterDriftExcludedHelmResources                 = filterDriftExcludedHelmResources
+	GetDriftExcludedKinds                            = getDriftExcludedKinds
 
 	GetResourceSummaryNamespace = getResourceSummaryNamespace
 	GetResourceSummaryName      = getResourceSummaryName
@@ -176,8 +228,9 @@ const (
 )
 
 var (
-	IsHealthy      = isHealthy
-	FetchResources = fetchResources
+	IsHealthy               = isHealthy
+	IsHealthyAccordingToCEL = isHealthyAccordingToCEL
+	FetchResources          = fetchResources
 )
 
 // reloader utils
@@ -194,6 +247,7 @@ var (
 var (
 	GetTemplateResourceName      = getTemplateResourceName
 	GetTemplateResourceNamespace = getTemplateResourceNamespace
+	CollectTemplateResourceRefs  = collectTemplateResourceRefs
 )
 
 var (
@@ -205,3 +259,421 @@ var (
 var (
 	RemoveDuplicates = removeDuplicates
 )
+
+var (
+	GetPropagatedMetadata               = getPropagatedMetadata
+	GetClusterMetadataFieldManager      = getClusterMetadataFieldManager
+	GetDeployedClusterMetadataResources = getDeployedClusterMetadataResources
+	GetClusterMetadataRefs              = getClusterMetadataRefs
+	GetClusterMetadataLastAppliedTime   = getClusterMetadataLastAppliedTime
+)
+
+func GetPropagatedMetadataContent(m *propagatedMetadata) (labels, annotations map[string]string) {
+	return m.labels, m.annotations
+}
+
+// GetDesiredClusterMetadataConfig returns the config hashed for resources with the given labels
+func GetDesiredClusterMetadataConfig(labels map[corev1.ObjectReference]map[string]string) string {
+	desired := make(map[corev1.ObjectReference]*propagatedMetadata, len(labels))
+	for ref := range labels {
+		desired[ref] = &propagatedMetadata{labels: labels[ref]}
+	}
+	return getDesiredClusterMetadataConfig(desired)
+}
+
+var (
+	GetReleaseLabels           = getReleaseLabels
+	IsReleaseDeployedBySveltos = isReleaseDeployedBySveltos
+	CheckReleaseAdoption       = checkReleaseAdoption
+)
+
+var (
+	FindNonConformingField = findNonConformingField
+	GetConformance         = getConformance
+	AssessReleaseReport    = assessReleaseReport
+)
+
+var (
+	GetChangeSummaryName = getChangeSummaryName
+	GetResourceChanges   = getResourceChanges
+	GetReleaseChanges    = getReleaseChanges
+	PublishChangeSummary = publishChangeSummary
+)
+
+var (
+	NormalRequeueAfter = (*RequeuePolicy).normalRequeueAfter
+	DeleteRequeueAfter = (*RequeuePolicy).deleteRequeueAfter
+	RateLimiter        = (*RequeuePolicy).rateLimiter
+)
+
+var (
+	GetChartCacheDir       = getChartCacheDir
+	RecordChartCacheUsage  = recordChartCacheUsage
+	ReleaseChartCacheUsage = releaseChartCacheUsage
+	CollectChartCache      = collectChartCache
+	RemoveStaleTempEntries = removeStaleTempEntries
+)
+
+var (
+	GetCluster                  = getCluster
+	IsClusterPaused             = isClusterPaused
+	GetKubernetesRestConfig     = getKubernetesRestConfig
+	GetKubeconfigFromRestConfig = getKubeconfigFromRestConfig
+)
+
+var (
+	RetryBackoff       = (*RetryPolicy).backoff
+	IsRetryCircuitOpen = (*RetryPolicy).isCircuitOpen
+)
+
+var (
+	BuildOutboundTLSConfig = buildOutboundTLSConfig
+	GetOutboundTLSConfig   = getOutboundTLSConfig
+	GetOutboundTransport   = getOutboundTransport
+)
+
+var (
+	NewChartRepositoryTransport = newChartRepositoryTransport
+	ParseRetryAfter             = parseRetryAfter
+	GetChartPullMessageCode     = getChartPullMessageCode
+)
+
+var (
+	GetDebugBundleName            = getDebugBundleName
+	CollectDebugBundleIfRequested = collectDebugBundleIfRequested
+)
+
+var (
+	ReviseRolloutStatus  = reviseRolloutStatus
+	RolloutPlanCanUpdate = (*rolloutPlan).canUpdate
+	ExceedsMaxFailures   = exceedsMaxFailures
+)
+
+var (
+	ParseCronSchedule         = parseCronSchedule
+	GetMaintenanceWindowState = getMaintenanceWindowState
+	IsInMaintenanceWindow     = isInMaintenanceWindow
+)
+
+var (
+	GetRolloutAbort          = getRolloutAbort
+	RollbackClusterSummaries = rollbackClusterSummaries
+)
+
+var (
+	RunDeploymentJob = runDeploymentJob
+	GetDeploymentJob = getDeploymentJob
+)
+
+var (
+	GetPinnedCharts              = getPinnedCharts
+	ApplyPinnedChartVersions     = applyPinnedChartVersions
+	GetClusterSummaryAnnotations = getClusterSummaryAnnotations
+)
+
+var (
+	FilterClustersByCELSelector = filterClustersByCELSelector
+)
+
+var (
+	GetEnforceJitter       = getEnforceJitter
+	GetNextEnforceTime     = getNextEnforceTime
+	EnforceIfDue           = (*ClusterSummaryReconciler).enforceIfDue
+	ReleaseEnforcementSlot = (*ClusterSummaryReconciler).releaseEnforcementSlot
+)
+
+var (
+	RecordRevision      = recordRevision
+	RollbackIfRequested = rollbackIfRequested
+)
+
+var (
+	GetFeatureDeployers = (*ClusterSummaryReconciler).getFeatureDeployers
+	IsFeatureReady      = (*ClusterSummaryReconciler).isFeatureReady
+)
+
+// GetFeatureID returns the ID of the feature a featureDeployer deploys
+func GetFeatureID(d featureDeployer) string {
+	return string(d.featureID)
+}
+
+var (
+	WithMessageCode = withMessageCode
+	GetMessageCode  = getMessageCode
+)
+
+var (
+	ValidateAdminCanGetReference = validateAdminCanGetReference
+	CollectReferencedObjects     = collectReferencedObjects
+	GetValuesFromResource        = getValuesFromResource
+	PrepareFileSystem            = prepareFileSystem
+)
+
+var (
+	ImpersonateTenant       = impersonateTenant
+	ImpersonateInKubeconfig = impersonateInKubeconfig
+)
+
+var (
+	GetRemoteUserAgent     = getRemoteUserAgent
+	SetRemoteUserAgent     = setRemoteUserAgent
+	GetAppliedProfile      = getAppliedProfile
+	AddAuditAnnotations    = addAuditAnnotations
+	RemoveAuditAnnotations = removeAuditAnnotations
+)
+
+var (
+	GetSourceRefPolicyRef = getSourceRefPolicyRef
+	GetSourceRevision     = getSourceRevision
+	UpdateSourceRevision  = updateSourceRevision
+)
+
+var (
+	ParseProxyURL             = parseProxyURL
+	GetClusterProxyURL        = getClusterProxyURL
+	SetKubeconfigClusterProxy = setKubeconfigClusterProxy
+)
+
+var (
+	ParseCosignPublicKeys         = parseCosignPublicKeys
+	GetRegistryCredentialsSecrets = getRegistryCredentialsSecrets
+)
+
+// VerifyHelmChart verifies the requested chart signature. Registry is reached over plain HTTP
+// if plainHTTP is set
+func VerifyHelmChart(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	requestedChart *configv1beta1.HelmChart, plainHTTP bool, logger logr.Logger,
+) (*configv1beta1.ChartVerification, error) {
+
+	return verifyHelmChart(ctx, c, clusterSummary, requestedChart,
+		&registryClientOptions{plainHTTP: plainHTTP}, logger)
+}
+
+var (
+	GetDeploymentIntentsName  = getDeploymentIntentsName
+	RecordDeploymentIntent    = recordDeploymentIntent
+	CompleteDeploymentIntent  = completeDeploymentIntent
+	GetDeploymentIntentResult = getDeploymentIntentResult
+)
+
+var (
+	RewriteImage                       = rewriteImage
+	RewriteUnstructuredImageRegistries = rewriteUnstructuredImageRegistries
+	GetRegistryMirrors                 = getRegistryMirrors
+	GetHelmPostRenderer                = getHelmPostRenderer
+)
+
+var (
+	GetBaseAddOnsReadyCondition = getBaseAddOnsReadyCondition
+	AreBaseAddOnsReady          = areBaseAddOnsReady
+)
+
+var (
+	GetChartValuesSnapshot  = getChartValuesSnapshot
+	GetSanitizedChartValues = getSanitizedChartValues
+)
+
+var (
+	IsChartVersionConstraint       = isChartVersionConstraint
+	ResolveChartVersionConstraints = resolveChartVersionConstraints
+	GetResolvedSpec                = getResolvedSpec
+)
+
+var (
+	ShouldRunHelmTests = shouldRunHelmTests
+	GetFailedHelmTests = getFailedHelmTests
+)
+
+var (
+	ValuesFromContentHasher = valuesFromContentHasher
+	PolicyRefContentHasher  = policyRefContentHasher
+)
+
+const (
+	DefaultReferencedContentCacheSize = defaultReferencedContentCacheSize
+)
+
+type (
+	ReferencedResource = referencedResource
+)
+
+func NewReferencedResource(kind string, key types.NamespacedName) ReferencedResource {
+	return referencedResource{kind: kind, key: key}
+}
+
+// GetReferencedResourcesHashes returns hashes and fetch errors of the referenced resources
+func GetReferencedResourcesHashes(ctx context.Context, c client.Client, refs []ReferencedResource,
+	hasher *referencedContentHasher) (hashes []string, errs []error) {
+
+	for _, r := range getReferencedResourcesHashes(ctx, c, refs, hasher) {
+		hashes = append(hashes, r.hash)
+		errs = append(errs, r.err)
+	}
+	return hashes, errs
+}
+
+// SetReferencedContentCacheSize sets the size of the referenced content cache and empties it
+// ResetRemoteOperationsDrain lets remote operations start again once drainRemoteOperations was invoked
+func ResetRemoteOperationsDrain() {
+	remoteOperationsMux.Lock()
+	defer remoteOperationsMux.Unlock()
+
+	draining = false
+	abortCtx, abortRemoteOperations = context.WithCancel(context.Background())
+}
+
+func SetReferencedContentCacheSize(size int) {
+	referencedContentCacheSize = size
+	referencedContentCache.reset()
+}
+
+func GetReferencedContentCacheLen() int {
+	return referencedContentCache.len()
+}
+
+// IsReferencedContentCached returns true if the hash of ref content is cached
+func IsReferencedContentCached(ref ReferencedResource, hasher *referencedContentHasher) bool {
+	_, ok := referencedContentCache.get(getReferencedContentCacheKey(&ref, hasher), time.Now())
+	return ok
+}
+
+var (
+	CaptureConfiguration       = captureConfiguration
+	StoreConfigurationSnapshot = storeConfigurationSnapshot
+	LoadConfigurationSnapshot  = loadConfigurationSnapshot
+	DiffConfigurationSnapshots = diffConfigurationSnapshots
+	RestoreConfiguration       = restoreConfiguration
+)
+
+var (
+	ResolveConflict        = resolveConflict
+	GetHelmReleaseConflict = getHelmReleaseConflict
+)
+
+var (
+	RecordAudit            = recordAudit
+	GetResourceAuditRecord = getResourceAuditRecord
+	GetHelmAuditRecord     = getHelmAuditRecord
+)
+
+// ResetAuditSinks removes all registered AuditSinks
+func ResetAuditSinks() {
+	auditSinksMux.Lock()
+	defer auditSinksMux.Unlock()
+	auditSinks = nil
+}
+
+var (
+	IsRolloutCompleted      = isRolloutCompleted
+	NotifyDeploymentFailure = notifyDeploymentFailure
+)
+
+var (
+	IsControllerVersionSupported     = isControllerVersionSupported
+	ReviseControllerVersionSupported = reviseControllerVersionSupported
+)
+
+var (
+	IsProfileReady       = isProfileReady
+	UpdateReadyCondition = updateReadyCondition
+)
+
+var (
+	WithAPIWritesCounter       = withAPIWritesCounter
+	NewAPIWritesCountingClient = newAPIWritesCountingClient
+)
+
+var (
+	UpdateConnectionStatus = updateConnectionStatus
+)
+
+// ResetConnectionProbes forgets all cached managed cluster connection checks
+func ResetConnectionProbes() {
+	connectionProbeMux.Lock()
+	defer connectionProbeMux.Unlock()
+	connectionProbes = map[string]*connectionProbe{}
+}
+
+var (
+	GetCachedRemoteRestConfig = getCachedRemoteRestConfig
+	GetRemoteClient           = getRemoteClient
+	InvalidateRemoteClients   = invalidateRemoteClients
+)
+
+var (
+	SortResourcesForDeployment = sortResourcesForDeployment
+	WaitForCRDsEstablished     = waitForCRDsEstablished
+)
+
+var (
+	IsDriftDetectionInMgmtCluster     = isDriftDetectionInMgmtCluster
+	GetDriftDetectionResources        = getDriftDetectionResources
+	SetDriftDetectionManagerResources = setDriftDetectionManagerResources
+	SetDriftDetectionManagerImage     = setDriftDetectionManagerImage
+)
+
+var (
+	GetDriftedResources        = getDriftedResources
+	StoreResourceSummaryHashes = storeResourceSummaryHashes
+	RecordDrift                = recordDrift
+)
+
+var (
+	TrackDriftDetectionRequirement    = trackDriftDetectionRequirement
+	IsDriftDetectionRequiredByCluster = isDriftDetectionRequiredByCluster
+)
+
+var (
+	GetSecretDataSection = getSecretDataSection
+)
+
+var (
+	MarkAsOwnedByProfile = markAsOwnedByProfile
+)
+
+var (
+	TrackTimeToProvision      = trackTimeToProvision
+	GetClusterSummaryProfile  = getClusterSummaryProfile
+	RemoveTimeToProvisionData = removeTimeToProvisionData
+)
+
+// IsTimeToProvisionTracked returns true if a feature configuration change is being tracked
+func IsTimeToProvisionTracked(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID) bool {
+	provisionStartsMux.Lock()
+	defer provisionStartsMux.Unlock()
+	_, ok := provisionStarts[getProvisionStartKey(clusterSummary, featureID)]
+	return ok
+}
+
+var (
+	RequestPlanIfRequested = requestPlanIfRequested
+	ApplyPlanOption        = applyPlanOption
+	PlanOption             = planOption
+)
+
+var (
+	LocateCachedChart = locateCachedChart
+)
+
+var (
+	RecordClusterConfigurationSnapshot = recordClusterConfigurationSnapshot
+)
+
+var (
+	GetChartRenderKey      = getChartRenderKey
+	GetCachedRenderedChart = getCachedRenderedChart
+	StoreRenderedChart     = storeRenderedChart
+)
+
+func GetChartArchiveLocksLen() int {
+	return chartArchiveLocks.len()
+}
+
+var (
+	RunBounded  = runBounded
+	LockRelease = lockRelease
+)
+
+func GetReleaseLocksLen() int {
+	return releaseLocks.len()
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/fluxcd/pkg/http/fetch"
 	"github.com/fluxcd/pkg/tar"
@@ -59,6 +61,7 @@ func prepareFileSystemWithFluxSource(source sourcev1.Source, logger logr.Logger)
 	// Download artifact and extract files to the tmp dir.
 	err = artifactFetcher.Fetch(source.GetArtifact().URL, source.GetArtifact().Digest, tmpDir)
 	if err != nil {
+		os.RemoveAll(tmpDir)
 		return "", err
 	}
 
@@ -109,3 +112,16 @@ func getSource(ctx context.Context, c client.Client, namespace, sourceName, sour
 			sourceName, sourceKind)
 	}
 }
+
+// getSourceArtifactPath returns the directory, within the extracted artifact of a Flux Source
+// (GitRepository, OCIRepository or Bucket), path points to. Path is relative to the artifact root
+// and cannot point outside of it.
+func getSourceArtifactPath(artifactDir, path string) (string, error) {
+	dirPath := filepath.Join(artifactDir, path)
+	rel, err := filepath.Rel(artifactDir, dirPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &NonRetriableError{Message: fmt.Sprintf("path %q points outside of the source artifact", path)}
+	}
+
+	return dirPath, nil
+}
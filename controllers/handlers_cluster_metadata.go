@@ -0,0 +1,613 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	"github.com/projectsveltos/libsveltos/lib/utils"
+)
+
+// ClusterMetadata feature keeps labels/annotations of resources in the managed cluster in sync
+// with labels/annotations of the Cluster (Spec.ClusterMetadataPropagations).
+// Labels/annotations are set using server side apply with a field manager owned by the
+// ClusterSummary. So only labels/annotations set by Sveltos are ever modified or removed.
+// Resources updated are tracked in the ClusterConfiguration. That is used to remove
+// labels/annotations from resources not targeted anymore. Their LastAppliedTime is the last
+// time labels/annotations on the resource actually changed.
+
+const (
+	clusterMetadataFieldManagerPrefix = "sveltos-metadata-"
+
+	// field manager name cannot be longer than 128 characters
+	maxFieldManagerLength = 128
+
+	// fieldManagerHashLength is the length of the hash suffix of truncated field manager names
+	fieldManagerHashLength = 8
+)
+
+type propagatedMetadata struct {
+	labels      map[string]string
+	annotations map[string]string
+}
+
+func deployClusterMetadata(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, applicant, _ string,
+	clusterType libsveltosv1beta1.ClusterType,
+	o deployer.Options, logger logr.Logger) error {
+
+	clusterSummary, err := configv1beta1.GetClusterSummary(ctx, c, clusterNamespace, applicant)
+	if err != nil {
+		return err
+	}
+
+	remoteRestConfig, logger, err := getRestConfig(ctx, c, clusterSummary, logger)
+	if err != nil {
+		return err
+	}
+
+	logger.V(logs.LogDebug).Info("deploying cluster metadata")
+
+	cluster, err := getCluster(ctx, c, clusterNamespace, clusterName, clusterType)
+	if err != nil {
+		return err
+	}
+
+	profileOwnerRef, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
+	if err != nil {
+		return err
+	}
+
+	previous, err := getDeployedClusterMetadataResources(ctx, c, clusterSummary, profileOwnerRef)
+	if err != nil {
+		return err
+	}
+
+	desired, err := getDesiredClusterMetadata(ctx, remoteRestConfig, clusterSummary, cluster, logger)
+	if err != nil {
+		return err
+	}
+
+	isDryRun := configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode)
+	fieldManager := getClusterMetadataFieldManager(clusterSummary)
+
+	deployed := make([]configv1beta1.Resource, 0, len(desired))
+	for _, ref := range sortedObjectReferences(desired) {
+		applied := false
+		if !isDryRun {
+			applied, err = applyClusterMetadata(ctx, remoteRestConfig, &ref, desired[ref], fieldManager, logger)
+			if err != nil {
+				return err
+			}
+		}
+		lastAppliedTime := getClusterMetadataLastAppliedTime(previous, &ref, applied)
+		deployed = append(deployed, getClusterMetadataResource(&ref, clusterSummary, lastAppliedTime))
+	}
+
+	// Labels/annotations previously set on resources not targeted anymore must be removed
+	for i := range previous {
+		ref := getObjectReferenceFromResource(&previous[i])
+		if _, ok := desired[ref]; ok || isDryRun {
+			continue
+		}
+		_, err = applyClusterMetadata(ctx, remoteRestConfig, &ref, &propagatedMetadata{}, fieldManager, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = updateClusterConfiguration(ctx, c, clusterSummary, profileOwnerRef,
+		configv1beta1.FeatureClusterMetadata, deployed, nil)
+	if err != nil {
+		return err
+	}
+
+	if isDryRun {
+		return &configv1beta1.DryRunReconciliationError{}
+	}
+
+	return nil
+}
+
+func undeployClusterMetadata(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, applicant, _ string,
+	clusterType libsveltosv1beta1.ClusterType,
+	o deployer.Options, logger logr.Logger) error {
+
+	clusterSummary, err := configv1beta1.GetClusterSummary(ctx, c, clusterNamespace, applicant)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	profileOwnerRef, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
+	if err != nil {
+		return err
+	}
+
+	previous, err := getDeployedClusterMetadataResources(ctx, c, clusterSummary, profileOwnerRef)
+	if err != nil {
+		return err
+	}
+
+	isDryRun := configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode)
+
+	if len(previous) != 0 && !isDryRun {
+		remoteRestConfig, logger, err := getRestConfig(ctx, c, clusterSummary, logger)
+		if err != nil {
+			return err
+		}
+
+		logger.V(logs.LogDebug).Info("undeploying cluster metadata")
+
+		fieldManager := getClusterMetadataFieldManager(clusterSummary)
+		for i := range previous {
+			ref := getObjectReferenceFromResource(&previous[i])
+			_, err = applyClusterMetadata(ctx, remoteRestConfig, &ref, &propagatedMetadata{}, fieldManager, logger)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = updateClusterConfiguration(ctx, c, clusterSummary, profileOwnerRef,
+		configv1beta1.FeatureClusterMetadata, []configv1beta1.Resource{}, nil)
+	if err != nil {
+		return err
+	}
+
+	if isDryRun {
+		return &configv1beta1.DryRunReconciliationError{}
+	}
+
+	return nil
+}
+
+// clusterMetadataHash returns the hash of all the ClusterMetadataPropagations, of the Cluster
+// labels/annotations and of the labels/annotations to set on each resource currently matching
+// a propagation target. So feature is redeployed when resources matching a target change.
+func clusterMetadataHash(ctx context.Context, c client.Client, clusterSummaryScope *scope.ClusterSummaryScope,
+	logger logr.Logger) ([]byte, error) {
+
+	clusterProfileSpecHash, err := getClusterProfileSpecHash(ctx, c, clusterSummaryScope.ClusterSummary)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	var config string
+	config += string(clusterProfileSpecHash)
+
+	clusterSummary := clusterSummaryScope.ClusterSummary
+	propagations, err := json.Marshal(clusterSummary.Spec.ClusterProfileSpec.ClusterMetadataPropagations)
+	if err != nil {
+		return nil, err
+	}
+	config += string(propagations)
+
+	cluster, err := getCluster(ctx, c, clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get cluster: %v", err))
+		return nil, err
+	}
+	// fmt prints maps sorted by key
+	config += fmt.Sprintf("%v", cluster.GetLabels())
+	config += fmt.Sprintf("%v", cluster.GetAnnotations())
+
+	if len(clusterSummary.Spec.ClusterProfileSpec.ClusterMetadataPropagations) != 0 {
+		remoteRestConfig, logger, err := getRestConfig(ctx, c, clusterSummary, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		desired, err := getDesiredClusterMetadata(ctx, remoteRestConfig, clusterSummary, cluster, logger)
+		if err != nil {
+			return nil, err
+		}
+		config += getDesiredClusterMetadataConfig(desired)
+	}
+
+	h.Write([]byte(config))
+	return h.Sum(nil), nil
+}
+
+// getDesiredClusterMetadataConfig returns a string representing the targeted resources and the
+// labels/annotations to set on each of those
+func getDesiredClusterMetadataConfig(desired map[corev1.ObjectReference]*propagatedMetadata) string {
+	var config string
+	for _, ref := range sortedObjectReferences(desired) {
+		// fmt prints maps sorted by key
+		config += fmt.Sprintf("%s/%s/%s/%s:%v:%v", ref.APIVersion, ref.Kind, ref.Namespace, ref.Name,
+			desired[ref].labels, desired[ref].annotations)
+	}
+	return config
+}
+
+// getClusterMetadataRefs returns the ConfigMaps/Secrets in the management cluster propagated
+// labels/annotations are instantiated from (TemplateResourceRefs)
+func getClusterMetadataRefs(clusterSummary *configv1beta1.ClusterSummary) []configv1beta1.PolicyRef {
+	if len(clusterSummary.Spec.ClusterProfileSpec.ClusterMetadataPropagations) == 0 {
+		return nil
+	}
+
+	var refs []configv1beta1.PolicyRef
+	for i := range clusterSummary.Spec.ClusterProfileSpec.TemplateResourceRefs {
+		resource := &clusterSummary.Spec.ClusterProfileSpec.TemplateResourceRefs[i].Resource
+		if resource.APIVersion != corev1.SchemeGroupVersion.String() {
+			continue
+		}
+		if resource.Kind != string(libsveltosv1beta1.ConfigMapReferencedResourceKind) &&
+			resource.Kind != string(libsveltosv1beta1.SecretReferencedResourceKind) {
+
+			continue
+		}
+		refs = append(refs, configv1beta1.PolicyRef{
+			Kind:      resource.Kind,
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+		})
+	}
+	return refs
+}
+
+// getClusterMetadataFieldManager returns the field manager used to set labels/annotations
+// on resources in the managed cluster on behalf of this ClusterSummary. Names too long are
+// truncated and suffixed with a hash of the full name, so they stay unique.
+func getClusterMetadataFieldManager(clusterSummary *configv1beta1.ClusterSummary) string {
+	fieldManager := clusterMetadataFieldManagerPrefix + clusterSummary.Name
+	if len(fieldManager) > maxFieldManagerLength {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(fieldManager)))[:fieldManagerHashLength]
+		fieldManager = fieldManager[:maxFieldManagerLength-fieldManagerHashLength-1] + "-" + hash
+	}
+	return fieldManager
+}
+
+// getDesiredClusterMetadata returns, for each resource in the managed cluster targeted by a
+// ClusterMetadataPropagation, the labels/annotations to set.
+func getDesiredClusterMetadata(ctx context.Context, remoteRestConfig *rest.Config,
+	clusterSummary *configv1beta1.ClusterSummary, cluster client.Object, logger logr.Logger,
+) (map[corev1.ObjectReference]*propagatedMetadata, error) {
+
+	mgmtResources, err := collectTemplateResourceRefs(ctx, clusterSummary)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := make(map[corev1.ObjectReference]*propagatedMetadata)
+	propagations := clusterSummary.Spec.ClusterProfileSpec.ClusterMetadataPropagations
+	for i := range propagations {
+		metadata, err := getPropagatedMetadata(ctx, clusterSummary, cluster, &propagations[i],
+			mgmtResources, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		targets, err := getClusterMetadataTargets(ctx, remoteRestConfig, &propagations[i].Target)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := range targets {
+			current, ok := desired[targets[j]]
+			if !ok {
+				current = &propagatedMetadata{}
+				desired[targets[j]] = current
+			}
+			current.labels = mergeMetadata(current.labels, metadata.labels)
+			current.annotations = mergeMetadata(current.annotations, metadata.annotations)
+		}
+	}
+
+	return desired, nil
+}
+
+// getPropagatedMetadata returns labels/annotations a ClusterMetadataPropagation sets.
+// Templates are instantiated using Cluster information.
+func getPropagatedMetadata(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	cluster client.Object, propagation *configv1beta1.ClusterMetadataPropagation,
+	mgmtResources map[string]*unstructured.Unstructured, logger logr.Logger) (*propagatedMetadata, error) {
+
+	metadata := &propagatedMetadata{
+		labels:      copyMetadataKeys(cluster.GetLabels(), propagation.LabelKeys),
+		annotations: copyMetadataKeys(cluster.GetAnnotations(), propagation.AnnotationKeys),
+	}
+
+	labels, err := instantiateMetadataValues(ctx, clusterSummary, propagation.Labels, mgmtResources, logger)
+	if err != nil {
+		return nil, err
+	}
+	metadata.labels = mergeMetadata(metadata.labels, labels)
+
+	annotations, err := instantiateMetadataValues(ctx, clusterSummary, propagation.Annotations,
+		mgmtResources, logger)
+	if err != nil {
+		return nil, err
+	}
+	metadata.annotations = mergeMetadata(metadata.annotations, annotations)
+
+	return metadata, nil
+}
+
+// copyMetadataKeys returns the entries of source whose key is in keys
+func copyMetadataKeys(source map[string]string, keys []string) map[string]string {
+	result := make(map[string]string)
+	for i := range keys {
+		if v, ok := source[keys[i]]; ok {
+			result[keys[i]] = v
+		}
+	}
+	return result
+}
+
+// mergeMetadata adds all entries of src to dst. On conflicts, src wins.
+func mergeMetadata(dst, src map[string]string) map[string]string {
+	if dst == nil {
+		dst = make(map[string]string)
+	}
+	for k := range src {
+		dst[k] = src[k]
+	}
+	return dst
+}
+
+func instantiateMetadataValues(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	values map[string]string, mgmtResources map[string]*unstructured.Unstructured, logger logr.Logger,
+) (map[string]string, error) {
+
+	result := make(map[string]string, len(values))
+	for k := range values {
+		if !strings.Contains(values[k], "{{") {
+			result[k] = values[k]
+			continue
+		}
+
+		instantiatedValue, err := instantiateTemplateValues(ctx, getManagementClusterConfig(),
+			getManagementClusterClient(), clusterSummary.Spec.ClusterType, clusterSummary.Spec.ClusterNamespace,
+			clusterSummary.Spec.ClusterName, clusterSummary.Name, values[k], mgmtResources, logger)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = instantiatedValue
+	}
+
+	return result, nil
+}
+
+// getClusterMetadataTargets returns the resources in the managed cluster matching target.
+// If target name is set, resource is returned only if it exists.
+func getClusterMetadataTargets(ctx context.Context, remoteRestConfig *rest.Config,
+	target *configv1beta1.MetadataPropagationTarget) ([]corev1.ObjectReference, error) {
+
+	gvk := schema.GroupVersionKind{Group: target.Group, Version: target.Version, Kind: target.Kind}
+	dr, err := utils.GetDynamicResourceInterface(remoteRestConfig, gvk, target.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	apiVersion := gvk.GroupVersion().String()
+
+	if target.Name != "" {
+		_, err = dr.Get(ctx, target.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []corev1.ObjectReference{
+			{APIVersion: apiVersion, Kind: target.Kind, Namespace: target.Namespace, Name: target.Name},
+		}, nil
+	}
+
+	list, err := dr.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]corev1.ObjectReference, len(list.Items))
+	for i := range list.Items {
+		result[i] = corev1.ObjectReference{
+			APIVersion: apiVersion, Kind: target.Kind,
+			Namespace: list.Items[i].GetNamespace(), Name: list.Items[i].GetName(),
+		}
+	}
+	return result, nil
+}
+
+// applyClusterMetadata sets labels/annotations on a resource in the managed cluster using
+// server side apply. Labels/annotations previously set by the same field manager and not
+// present anymore are removed. Returns true if resource was modified.
+func applyClusterMetadata(ctx context.Context, remoteRestConfig *rest.Config, ref *corev1.ObjectReference,
+	metadata *propagatedMetadata, fieldManager string, logger logr.Logger) (bool, error) {
+
+	l := logger.WithValues("resourceNamespace", ref.Namespace, "resourceName", ref.Name,
+		"resourceKind", ref.Kind)
+	l.V(logs.LogDebug).Info("updating resource metadata")
+
+	gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	dr, err := utils.GetDynamicResourceInterface(remoteRestConfig, gvk, ref.Namespace)
+	if err != nil {
+		return false, err
+	}
+
+	// Apply would create the resource if it did not exist
+	current, err := dr.Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(ref.APIVersion)
+	u.SetKind(ref.Kind)
+	u.SetNamespace(ref.Namespace)
+	u.SetName(ref.Name)
+	if len(metadata.labels) != 0 {
+		u.SetLabels(metadata.labels)
+	}
+	if len(metadata.annotations) != 0 {
+		u.SetAnnotations(metadata.annotations)
+	}
+
+	data, err := u.MarshalJSON()
+	if err != nil {
+		return false, err
+	}
+
+	forceConflict := true
+	patched, err := dr.Patch(ctx, ref.Name, types.ApplyPatchType, data,
+		metav1.PatchOptions{FieldManager: fieldManager, Force: &forceConflict})
+	if err != nil {
+		return false, err
+	}
+
+	// An apply not changing anything is a no-op and does not change resourceVersion
+	return patched.GetResourceVersion() != current.GetResourceVersion(), nil
+}
+
+// getDeployedClusterMetadataResources returns the resources whose metadata was last updated
+// because of this ClusterSummary, as reported in the ClusterConfiguration
+func getDeployedClusterMetadataResources(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, profileOwnerRef *metav1.OwnerReference,
+) ([]configv1beta1.Resource, error) {
+
+	clusterConfiguration, err := getClusterConfiguration(ctx, c, clusterSummary.Spec.ClusterNamespace,
+		getClusterConfigurationName(clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var features []configv1beta1.Feature
+	if profileOwnerRef.Kind == configv1beta1.ClusterProfileKind {
+		for i := range clusterConfiguration.Status.ClusterProfileResources {
+			if clusterConfiguration.Status.ClusterProfileResources[i].ClusterProfileName == profileOwnerRef.Name {
+				features = clusterConfiguration.Status.ClusterProfileResources[i].Features
+			}
+		}
+	} else {
+		for i := range clusterConfiguration.Status.ProfileResources {
+			if clusterConfiguration.Status.ProfileResources[i].ProfileName == profileOwnerRef.Name {
+				features = clusterConfiguration.Status.ProfileResources[i].Features
+			}
+		}
+	}
+
+	for i := range features {
+		if features[i].FeatureID == configv1beta1.FeatureClusterMetadata {
+			return features[i].Resources, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// getClusterMetadataLastAppliedTime returns the time labels/annotations on the resource last changed.
+// That is now if applied is true, otherwise the time previously reported in the ClusterConfiguration.
+func getClusterMetadataLastAppliedTime(previous []configv1beta1.Resource, ref *corev1.ObjectReference,
+	applied bool) *metav1.Time {
+
+	if !applied {
+		for i := range previous {
+			if getObjectReferenceFromResource(&previous[i]) == *ref {
+				if previous[i].LastAppliedTime != nil {
+					return previous[i].LastAppliedTime
+				}
+				break
+			}
+		}
+	}
+
+	now := metav1.Now()
+	return &now
+}
+
+func getClusterMetadataResource(ref *corev1.ObjectReference,
+	clusterSummary *configv1beta1.ClusterSummary, lastAppliedTime *metav1.Time) configv1beta1.Resource {
+
+	// Labels/annotations are coming from the Cluster
+	owner := corev1.ObjectReference{
+		Namespace:  clusterSummary.Spec.ClusterNamespace,
+		Name:       clusterSummary.Spec.ClusterName,
+		Kind:       clusterKind,
+		APIVersion: clusterv1.GroupVersion.String(),
+	}
+	if clusterSummary.Spec.ClusterType == libsveltosv1beta1.ClusterTypeSveltos {
+		owner.Kind = libsveltosv1beta1.SveltosClusterKind
+		owner.APIVersion = libsveltosv1beta1.GroupVersion.String()
+	}
+
+	gv := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+	return configv1beta1.Resource{
+		Name:            ref.Name,
+		Namespace:       ref.Namespace,
+		Group:           gv.Group,
+		Version:         gv.Version,
+		Kind:            ref.Kind,
+		LastAppliedTime: lastAppliedTime,
+		Owner:           owner,
+	}
+}
+
+func getObjectReferenceFromResource(resource *configv1beta1.Resource) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion: schema.GroupVersion{Group: resource.Group, Version: resource.Version}.String(),
+		Kind:       resource.Kind,
+		Namespace:  resource.Namespace,
+		Name:       resource.Name,
+	}
+}
+
+func sortedObjectReferences(desired map[corev1.ObjectReference]*propagatedMetadata) []corev1.ObjectReference {
+	refs := make([]corev1.ObjectReference, 0, len(desired))
+	for k := range desired {
+		refs = append(refs, k)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return fmt.Sprintf("%s/%s/%s/%s", refs[i].APIVersion, refs[i].Kind, refs[i].Namespace, refs[i].Name) <
+			fmt.Sprintf("%s/%s/%s/%s", refs[j].APIVersion, refs[j].Kind, refs[j].Namespace, refs[j].Name)
+	})
+	return refs
+}
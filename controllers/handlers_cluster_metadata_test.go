@@ -0,0 +1,231 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("ClusterMetadata feature", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+
+	BeforeEach(func() {
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       randomString(),
+						UID:        "1",
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterName: randomString(),
+				ClusterType: libsveltosv1beta1.ClusterTypeSveltos,
+			},
+		}
+		clusterSummary.Spec.ClusterNamespace = clusterSummary.Namespace
+	})
+
+	It("getPropagatedMetadata copies Cluster labels/annotations and sets static ones", func() {
+		cluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      clusterSummary.Spec.ClusterName,
+				Namespace: clusterSummary.Spec.ClusterNamespace,
+				Labels: map[string]string{
+					"region": "west",
+					"env":    "production",
+				},
+				Annotations: map[string]string{
+					"owner": "platform",
+				},
+			},
+		}
+
+		propagation := &configv1beta1.ClusterMetadataPropagation{
+			Target:         configv1beta1.MetadataPropagationTarget{Version: "v1", Kind: "Namespace", Name: "kube-system"},
+			LabelKeys:      []string{"region", "zone"},
+			AnnotationKeys: []string{"owner"},
+			Labels: map[string]string{
+				"env":     "staging",
+				"managed": "sveltos",
+			},
+		}
+
+		metadata, err := controllers.GetPropagatedMetadata(context.TODO(), clusterSummary, cluster, propagation,
+			nil, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+
+		labels, annotations := controllers.GetPropagatedMetadataContent(metadata)
+		// Keys not present on the Cluster are ignored. Cluster labels not listed are not copied.
+		Expect(labels).To(Equal(map[string]string{"region": "west", "env": "staging", "managed": "sveltos"}))
+		Expect(annotations).To(Equal(map[string]string{"owner": "platform"}))
+	})
+
+	It("getClusterMetadataFieldManager returns a valid field manager", func() {
+		fieldManager := controllers.GetClusterMetadataFieldManager(clusterSummary)
+		Expect(strings.HasSuffix(fieldManager, clusterSummary.Name)).To(BeTrue())
+
+		clusterSummary.Name = strings.Repeat("a", 200)
+		fieldManager = controllers.GetClusterMetadataFieldManager(clusterSummary)
+		Expect(len(fieldManager)).To(Equal(128))
+
+		// Long names sharing the same prefix do not collide
+		clusterSummary.Name = strings.Repeat("a", 199) + "b"
+		otherFieldManager := controllers.GetClusterMetadataFieldManager(clusterSummary)
+		Expect(len(otherFieldManager)).To(Equal(128))
+		Expect(otherFieldManager).ToNot(Equal(fieldManager))
+	})
+
+	It("getClusterMetadataRefs returns ConfigMaps/Secrets propagated values are instantiated from", func() {
+		clusterSummary.Spec.ClusterProfileSpec.TemplateResourceRefs = []configv1beta1.TemplateResourceRef{
+			{
+				Resource:   corev1.ObjectReference{APIVersion: "v1", Kind: "ConfigMap", Name: "{{ .Cluster.metadata.name }}"},
+				Identifier: randomString(),
+			},
+			{
+				Resource:   corev1.ObjectReference{APIVersion: "v1", Kind: "Secret", Namespace: randomString(), Name: randomString()},
+				Identifier: randomString(),
+			},
+			{
+				Resource:   corev1.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: randomString()},
+				Identifier: randomString(),
+			},
+		}
+
+		// No propagation, nothing is referenced
+		Expect(controllers.GetClusterMetadataRefs(clusterSummary)).To(BeEmpty())
+
+		clusterSummary.Spec.ClusterProfileSpec.ClusterMetadataPropagations = []configv1beta1.ClusterMetadataPropagation{
+			{Target: configv1beta1.MetadataPropagationTarget{Version: "v1", Kind: "Namespace", Name: "kube-system"}},
+		}
+		refs := controllers.GetClusterMetadataRefs(clusterSummary)
+		Expect(refs).To(HaveLen(2))
+		Expect(refs[0].Kind).To(Equal("ConfigMap"))
+		Expect(refs[0].Name).To(Equal("{{ .Cluster.metadata.name }}"))
+		Expect(refs[1].Kind).To(Equal("Secret"))
+		Expect(refs[1].Namespace).To(Equal(clusterSummary.Spec.ClusterProfileSpec.TemplateResourceRefs[1].Resource.Namespace))
+	})
+
+	It("hashed config changes when resources matching a target change", func() {
+		kubeSystem := corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "kube-system"}
+		defaultNs := corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "default"}
+		labels := map[string]string{"region": "west"}
+
+		config := controllers.GetDesiredClusterMetadataConfig(map[corev1.ObjectReference]map[string]string{
+			kubeSystem: labels,
+		})
+		Expect(controllers.GetDesiredClusterMetadataConfig(map[corev1.ObjectReference]map[string]string{
+			kubeSystem: labels,
+		})).To(Equal(config))
+
+		// A new resource matches the target
+		Expect(controllers.GetDesiredClusterMetadataConfig(map[corev1.ObjectReference]map[string]string{
+			kubeSystem: labels, defaultNs: labels,
+		})).ToNot(Equal(config))
+
+		// A different resource matches the target
+		Expect(controllers.GetDesiredClusterMetadataConfig(map[corev1.ObjectReference]map[string]string{
+			defaultNs: labels,
+		})).ToNot(Equal(config))
+	})
+
+	It("getClusterMetadataLastAppliedTime changes only when resource is modified", func() {
+		ref := corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "kube-system"}
+		lastAppliedTime := metav1.NewTime(time.Now().Add(-time.Hour))
+		previous := []configv1beta1.Resource{
+			{Version: "v1", Kind: "Namespace", Name: "kube-system", LastAppliedTime: &lastAppliedTime},
+		}
+
+		Expect(controllers.GetClusterMetadataLastAppliedTime(previous, &ref, false)).To(Equal(&lastAppliedTime))
+		Expect(controllers.GetClusterMetadataLastAppliedTime(previous, &ref, true).After(lastAppliedTime.Time)).To(BeTrue())
+
+		// Resource targeted for the first time
+		other := corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "default"}
+		Expect(controllers.GetClusterMetadataLastAppliedTime(previous, &other, false).After(lastAppliedTime.Time)).To(BeTrue())
+	})
+
+	It("getDeployedClusterMetadataResources returns resources reported in ClusterConfiguration", func() {
+		profileOwnerRef, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
+		Expect(err).To(BeNil())
+
+		resources := []configv1beta1.Resource{
+			{Version: "v1", Kind: "Namespace", Name: "kube-system"},
+		}
+
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterSummary.Spec.ClusterNamespace,
+				Name: controllers.GetClusterConfigurationName(clusterSummary.Spec.ClusterName,
+					clusterSummary.Spec.ClusterType),
+			},
+			Status: configv1beta1.ClusterConfigurationStatus{
+				ClusterProfileResources: []configv1beta1.ClusterProfileResource{
+					{
+						ClusterProfileName: randomString(),
+						Features: []configv1beta1.Feature{
+							{FeatureID: configv1beta1.FeatureClusterMetadata, Resources: []configv1beta1.Resource{
+								{Version: "v1", Kind: "Node", Name: randomString()},
+							}},
+						},
+					},
+					{
+						ClusterProfileName: profileOwnerRef.Name,
+						Features: []configv1beta1.Feature{
+							{FeatureID: configv1beta1.FeatureResources},
+							{FeatureID: configv1beta1.FeatureClusterMetadata, Resources: resources},
+						},
+					},
+				},
+			},
+		}
+
+		initObjects := []client.Object{clusterConfiguration}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).
+			WithObjects(initObjects...).Build()
+
+		deployed, err := controllers.GetDeployedClusterMetadataResources(context.TODO(), c, clusterSummary,
+			profileOwnerRef)
+		Expect(err).To(BeNil())
+		Expect(deployed).To(Equal(resources))
+
+		Expect(c.Delete(context.TODO(), clusterConfiguration)).To(Succeed())
+		deployed, err = controllers.GetDeployedClusterMetadataResources(context.TODO(), c, clusterSummary,
+			profileOwnerRef)
+		Expect(err).To(BeNil())
+		Expect(deployed).To(BeEmpty())
+	})
+})
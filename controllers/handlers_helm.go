@@ -21,10 +21,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"reflect"
+	"sort"
 	"strconv"
 	"time"
 
@@ -40,7 +42,6 @@ import (
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/downloader"
-	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/repo"
@@ -62,10 +63,8 @@ import (
 	"github.com/projectsveltos/addon-controller/controllers/chartmanager"
 	"github.com/projectsveltos/addon-controller/pkg/scope"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
-	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	"github.com/projectsveltos/libsveltos/lib/deployer"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
-	"github.com/projectsveltos/libsveltos/lib/patcher"
 	libsveltostemplate "github.com/projectsveltos/libsveltos/lib/template"
 	"github.com/projectsveltos/libsveltos/lib/utils"
 )
@@ -101,6 +100,10 @@ type releaseInfo struct {
 	AppVersion       string            `json:"app_version"`
 	ReleaseLabels    map[string]string `json:"release_labels"`
 	Icon             string            `json:"icon"`
+	// Verification is the result of the chart signature verification, if requested
+	Verification *configv1beta1.ChartVerification `json:"verification,omitempty"`
+	// Values is the sanitized snapshot of the values the release is deployed with
+	Values *configv1beta1.ChartValues `json:"values,omitempty"`
 }
 
 func deployHelmCharts(ctx context.Context, c client.Client,
@@ -114,11 +117,17 @@ func deployHelmCharts(ctx context.Context, c client.Client,
 		return err
 	}
 
+	// When planning, handler runs in DryRun mode whatever the ClusterSummary SyncMode
+	applyPlanOption(clusterSummary, o)
+
+	// Charts pinned in this cluster only are deployed at the pinned version
+	applyPinnedChartVersions(clusterSummary)
+
 	startInMgmtCluster := startDriftDetectionInMgmtCluster(o)
 	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeContinuousWithDriftDetection {
 		// Deploy drift detection manager first. Have manager up by the time resourcesummary is created
 		err = deployDriftDetectionManagerInCluster(ctx, c, clusterNamespace, clusterName, applicant,
-			clusterType, startInMgmtCluster, logger)
+			clusterType, startInMgmtCluster, getDriftDetectionResources(clusterSummary, startInMgmtCluster), logger)
 		if err != nil {
 			return err
 		}
@@ -127,7 +136,8 @@ func deployHelmCharts(ctx context.Context, c client.Client,
 		// un-needed reconciliation (Sveltos is updating those resources so we don't want drift-detection to think
 		// a configuration drift is happening)
 		err = deployResourceSummaryInCluster(ctx, c, clusterNamespace, clusterName, clusterSummary.Name, clusterType, nil, nil,
-			[]libsveltosv1beta1.HelmResources{}, clusterSummary.Spec.ClusterProfileSpec.DriftExclusions, logger)
+			[]libsveltosv1beta1.HelmResources{}, clusterSummary.Spec.ClusterProfileSpec.DriftExclusions,
+			clusterSummary.Spec.ClusterProfileSpec.DriftExcludedKinds, logger)
 		if err != nil {
 			logger.V(logs.LogInfo).Error(err, "failed to remove ResourceSummary.")
 			return err
@@ -139,18 +149,16 @@ func deployHelmCharts(ctx context.Context, c client.Client,
 	logger = logger.WithValues("clusterSummary", clusterSummary.Name)
 	logger = logger.WithValues("admin", fmt.Sprintf("%s/%s", adminNamespace, adminName))
 
-	kubeconfigContent, err := clusterproxy.GetSecretData(ctx, c, clusterNamespace, clusterName,
-		adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	kubeconfig, err := getClusterSummaryKubeconfig(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return err
 	}
+	defer os.Remove(kubeconfig)
 
-	var kubeconfig string
-	kubeconfig, err = clusterproxy.CreateKubeconfig(logger, kubeconfigContent)
+	err = runPreDeploymentJobs(ctx, c, remoteClient, clusterSummary, configv1beta1.FeatureHelm, logger)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(kubeconfig)
 
 	err = handleCharts(ctx, clusterSummary, c, remoteClient, kubeconfig, logger)
 	if err != nil {
@@ -170,7 +178,8 @@ func deployHelmCharts(ctx context.Context, c client.Client,
 	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeContinuousWithDriftDetection {
 		// Deploy resourceSummary
 		err = deployResourceSummaryInCluster(ctx, c, clusterNamespace, clusterName, clusterSummary.Name,
-			clusterType, nil, nil, helmResources, clusterSummary.Spec.ClusterProfileSpec.DriftExclusions, logger)
+			clusterType, nil, nil, helmResources, clusterSummary.Spec.ClusterProfileSpec.DriftExclusions,
+			clusterSummary.Spec.ClusterProfileSpec.DriftExcludedKinds, logger)
 		if err != nil {
 			return err
 		}
@@ -193,12 +202,16 @@ func deployHelmCharts(ctx context.Context, c client.Client,
 		return err
 	}
 
-	remoteRestConfig, err := clusterproxy.GetKubernetesRestConfig(ctx, c, clusterNamespace, clusterName,
-		adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	remoteRestConfig, err := getClusterSummaryRestConfig(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return err
 	}
-	return validateHealthPolicies(ctx, remoteRestConfig, clusterSummary, configv1beta1.FeatureHelm, logger)
+	err = runPostDeploymentJobs(ctx, c, remoteClient, clusterSummary, configv1beta1.FeatureHelm, logger)
+	if err != nil {
+		return err
+	}
+
+	return validateHealthPoliciesOnDeploy(ctx, remoteRestConfig, clusterSummary, configv1beta1.FeatureHelm, logger)
 }
 
 func undeployHelmCharts(ctx context.Context, c client.Client,
@@ -223,14 +236,7 @@ func undeployHelmCharts(ctx context.Context, c client.Client,
 
 	logger.V(logs.LogDebug).Info("undeployHelmCharts")
 
-	kubeconfigContent, err := clusterproxy.GetSecretData(ctx, c, clusterNamespace, clusterName,
-		adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
-	if err != nil {
-		return err
-	}
-
-	var kubeconfig string
-	kubeconfig, err = clusterproxy.CreateKubeconfig(logger, kubeconfigContent)
+	kubeconfig, err := getClusterSummaryKubeconfig(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return err
 	}
@@ -281,8 +287,9 @@ func undeployHelmCharts(ctx context.Context, c client.Client,
 
 	// In dry-run mode nothing gets deployed/undeployed. So if this instance used to manage
 	// an helm release and it is now not referencing anymore, do not unsubscribe.
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode != configv1beta1.SyncModeDryRun {
+	if !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		chartManager.RemoveStaleRegistrations(clusterSummary)
+		releaseChartCacheUsage(clusterSummary, logger)
 		return nil
 	}
 
@@ -318,12 +325,8 @@ func uninstallHelmCharts(ctx context.Context, c client.Client, clusterSummary *c
 				chartManager.UnregisterClusterSummaryForChart(clusterSummary, currentChart)
 			} else {
 				// If StopMatchingBehavior is LeavePolicies, do not uninstall helm charts
-				if !clusterSummary.DeletionTimestamp.IsZero() &&
-					clusterSummary.Spec.ClusterProfileSpec.StopMatchingBehavior == configv1beta1.LeavePolicies {
-
-					logger.V(logs.LogInfo).Info("ClusterProfile StopMatchingBehavior set to LeavePolicies")
-				} else {
-					credentialsPath, caPath, err := getCredentialsAndCAFiles(ctx, c,
+				if !isLeavePolicies(clusterSummary, logger) {
+					credentialsPath, caPath, err := getCredentialsAndCAFiles(ctx, c, clusterSummary,
 						clusterSummary.Spec.ClusterNamespace, currentChart)
 					if err != nil {
 						logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to process credentials %v", err))
@@ -342,7 +345,9 @@ func uninstallHelmCharts(ctx context.Context, c client.Client, clusterSummary *c
 						return nil, err
 					}
 					if currentRelease != nil && currentRelease.Status != string(release.StatusUninstalled) {
+						unlock := lockRelease(clusterSummary, currentChart.ReleaseNamespace, currentChart.ReleaseName)
 						err = doUninstallRelease(clusterSummary, currentChart, kubeconfig, registryOptions, logger)
+						unlock()
 						if err != nil {
 							if !errors.Is(err, driver.ErrReleaseNotFound) {
 								return nil, err
@@ -370,7 +375,7 @@ func uninstallHelmCharts(ctx context.Context, c client.Client, clusterSummary *c
 func helmHash(ctx context.Context, c client.Client, clusterSummaryScope *scope.ClusterSummaryScope,
 	logger logr.Logger) ([]byte, error) {
 
-	clusterProfileSpecHash, err := getClusterProfileSpecHash(ctx, clusterSummaryScope.ClusterSummary)
+	clusterProfileSpecHash, err := getClusterProfileSpecHash(ctx, c, clusterSummaryScope.ClusterSummary)
 	if err != nil {
 		return nil, err
 	}
@@ -383,6 +388,9 @@ func helmHash(ctx context.Context, c client.Client, clusterSummaryScope *scope.C
 	if clusterSummary.Spec.ClusterProfileSpec.HelmCharts == nil {
 		return h.Sum(nil), nil
 	}
+	if pinnedCharts := getPinnedCharts(clusterSummary); len(pinnedCharts) > 0 {
+		config += render.AsCode(pinnedCharts)
+	}
 	for i := range clusterSummary.Spec.ClusterProfileSpec.HelmCharts {
 		currentChart := &clusterSummary.Spec.ClusterProfileSpec.HelmCharts[i]
 
@@ -406,6 +414,12 @@ func helmHash(ctx context.Context, c client.Client, clusterSummaryScope *scope.C
 		}
 	}
 
+	deploymentJobsHash, err := getDeploymentJobsHash(ctx, c, clusterSummary, configv1beta1.FeatureHelm, logger)
+	if err != nil {
+		return nil, err
+	}
+	config += deploymentJobsHash
+
 	h.Write([]byte(config))
 	return h.Sum(nil), nil
 }
@@ -413,7 +427,56 @@ func helmHash(ctx context.Context, c client.Client, clusterSummaryScope *scope.C
 func getHelmReferenceResourceHash(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
 	helmChart *configv1beta1.HelmChart, logger logr.Logger) (string, error) {
 
-	return getValuesFromResourceHash(ctx, c, clusterSummary, helmChart.ValuesFrom, logger)
+	config, err := getValuesFromResourceHash(ctx, c, clusterSummary, helmChart.ValuesFrom, logger)
+	if err != nil {
+		return "", err
+	}
+
+	// A cluster label change might change which values overrides are used
+	overrides, err := getMatchingValuesOverrides(ctx, c, clusterSummary, helmChart)
+	if err != nil {
+		return "", err
+	}
+	if len(overrides) > 0 {
+		config += render.AsCode(overrides)
+	}
+
+	// Rotating registry credentials or verification keys must redeploy the chart
+	for _, ref := range getRegistryCredentialsSecrets(clusterSummary.Namespace, helmChart) {
+		secret, err := getSecret(ctx, c, ref)
+		if err == nil {
+			config += getDataSectionHash(secret.Data)
+		}
+	}
+
+	return config, nil
+}
+
+// getRegistryCredentialsSecrets returns the Secrets referenced in the RegistryCredentialsConfig
+// section of a HelmChart (credentials and CA) and in its Verify section (verification keys)
+func getRegistryCredentialsSecrets(clusterNamespace string, requestedChart *configv1beta1.HelmChart,
+) []types.NamespacedName {
+
+	var refs []*corev1.SecretReference
+	if requestedChart.RegistryCredentialsConfig != nil {
+		refs = append(refs, requestedChart.RegistryCredentialsConfig.CredentialsSecretRef,
+			requestedChart.RegistryCredentialsConfig.CASecretRef)
+	}
+	if requestedChart.Verify != nil {
+		refs = append(refs, &requestedChart.Verify.SecretRef)
+	}
+
+	var secrets []types.NamespacedName
+	for _, ref := range refs {
+		if ref == nil {
+			continue
+		}
+		secrets = append(secrets, types.NamespacedName{
+			Namespace: libsveltostemplate.GetReferenceResourceNamespace(clusterNamespace, ref.Namespace),
+			Name:      ref.Name,
+		})
+	}
+	return secrets
 }
 
 func getHelmRefs(clusterSummary *configv1beta1.ClusterSummary) []configv1beta1.PolicyRef {
@@ -434,6 +497,12 @@ func handleCharts(ctx context.Context, clusterSummary *configv1beta1.ClusterSumm
 		return err
 	}
 
+	// Create release namespaces before helm does, so they get the labels and annotations
+	// in Spec.TargetNamespaceMetadata (for instance pod-security labels)
+	if err := createReleaseNamespaces(ctx, remoteClient, clusterSummary); err != nil {
+		return err
+	}
+
 	releaseReports, chartDeployed, deployError := walkChartsAndDeploy(ctx, c, clusterSummary, kubeconfig, logger)
 	// Even if there is a deployment error do not return just yet. Update various status and clean stale resources.
 
@@ -452,7 +521,7 @@ func handleCharts(ctx context.Context, clusterSummary *configv1beta1.ClusterSumm
 		return err
 	}
 	releaseReports = append(releaseReports, undeployedReports...)
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode != configv1beta1.SyncModeDryRun {
+	if !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		chartManager, mgrErr := chartmanager.GetChartManagerInstance(ctx, c)
 		if mgrErr != nil {
 			return mgrErr
@@ -470,8 +539,12 @@ func handleCharts(ctx context.Context, clusterSummary *configv1beta1.ClusterSumm
 	if err != nil {
 		return err
 	}
+
+	publishChangeSummary(ctx, c, remoteClient, clusterSummary, configv1beta1.FeatureHelm,
+		getReleaseChanges(releaseReports), logger)
+
 	// In DryRun mode always return an error.
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return &configv1beta1.DryRunReconciliationError{}
 	}
 
@@ -500,64 +573,113 @@ func walkChartsAndDeploy(ctx context.Context, c client.Client, clusterSummary *c
 		return nil, nil, err
 	}
 
+	helmCharts := clusterSummary.Spec.ClusterProfileSpec.HelmCharts
+
+	// Conflicts are processed first, in order. Charts following a conflict stopping the deployment
+	// are not deployed.
 	conflictErrorMessage := ""
-	releaseReports := make([]configv1beta1.ReleaseReport, 0)
-	chartDeployed := make([]configv1beta1.Chart, 0)
-	for i := range clusterSummary.Spec.ClusterProfileSpec.HelmCharts {
-		currentChart := &clusterSummary.Spec.ClusterProfileSpec.HelmCharts[i]
+	stopOnConflict := false
+	unmanagedReports := make([]*configv1beta1.ReleaseReport, len(helmCharts))
+	toDeploy := make([]int, 0, len(helmCharts))
+	for i := range helmCharts {
+		currentChart := &helmCharts[i]
 		// Eventual conflicts are already resolved before this method is called (in updateStatusForeferencedHelmReleases)
 		// So it is safe to call CanManageChart here
 		if !chartManager.CanManageChart(clusterSummary, currentChart) {
-			var report *configv1beta1.ReleaseReport
-			report, err = createReportForUnmanagedHelmRelease(ctx, c, clusterSummary, currentChart, logger)
+			unmanagedReports[i], err = createReportForUnmanagedHelmRelease(ctx, c, clusterSummary, currentChart, logger)
 			if err != nil {
-				return releaseReports, chartDeployed, err
+				return nil, nil, err
 			}
-			releaseReports = append(releaseReports, *report)
 			conflictErrorMessage += generateConflictForHelmChart(ctx, clusterSummary, currentChart)
 			// error is reported above, in updateHelmChartStatus.
 			if clusterSummary.Spec.ClusterProfileSpec.ContinueOnConflict ||
-				clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+				configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 
 				continue
 			}
 
-			return releaseReports, chartDeployed,
-				deployer.NewConflictError(conflictErrorMessage)
+			stopOnConflict = true
+			break
 		}
+		toDeploy = append(toDeploy, i)
+	}
+
+	currentReleases := make([]*releaseInfo, len(helmCharts))
+	deployReports := make([]*configv1beta1.ReleaseReport, len(helmCharts))
+	errs, started := runBounded(len(toDeploy), getHelmMaxConcurrentReleases(), func(j int) error {
+		i := toDeploy[j]
+		currentChart := &helmCharts[i]
 
-		var report *configv1beta1.ReleaseReport
-		var currentRelease *releaseInfo
-		currentRelease, report, err = handleChart(ctx, clusterSummary, mgmtResources, currentChart, kubeconfig, logger)
+		unlock := lockRelease(clusterSummary, currentChart.ReleaseNamespace, currentChart.ReleaseName)
+		defer unlock()
+
+		currentRelease, report, err := handleChart(ctx, clusterSummary, mgmtResources, currentChart, kubeconfig, logger)
 		if err != nil {
-			return releaseReports, chartDeployed, err
+			return err
 		}
 		err = updateValueHashOnHelmChartSummary(ctx, currentChart, clusterSummary, logger)
 		if err != nil {
-			return releaseReports, chartDeployed, err
+			return err
 		}
 
-		releaseReports = append(releaseReports, *report)
+		currentReleases[i] = currentRelease
+		deployReports[i] = report
+		return nil
+	})
 
+	deployed := make([]bool, len(helmCharts))
+	var deployErr error
+	for j := range toDeploy {
+		if errs[j] != nil && deployErr == nil {
+			deployErr = errs[j]
+		}
+		deployed[toDeploy[j]] = started[j] && errs[j] == nil
+	}
+
+	// Reports are in the order charts are listed, whatever order those were deployed in
+	releaseReports := make([]configv1beta1.ReleaseReport, 0)
+	chartDeployed := make([]configv1beta1.Chart, 0)
+	for i := range helmCharts {
+		currentChart := &helmCharts[i]
+		if unmanagedReports[i] != nil {
+			releaseReports = append(releaseReports, *unmanagedReports[i])
+			continue
+		}
+		if !deployed[i] {
+			continue
+		}
+
+		releaseReports = append(releaseReports, *deployReports[i])
+
+		currentRelease := currentReleases[i]
 		if currentRelease != nil {
 			logger.V(logs.LogInfo).Info(fmt.Sprintf("release %s/%s (version %s) status: %s",
 				currentRelease.ReleaseNamespace, currentRelease.ReleaseName, currentRelease.ChartVersion, currentRelease.Status))
 			if currentRelease.Status == release.StatusDeployed.String() {
-				// Deployed chart is used for updating ClusterConfiguration. There is no ClusterConfiguration for mgmt cluster
+				revision, _ := strconv.ParseInt(currentRelease.Revision, 10, 64)
+				// Deployed chart is used for updating ClusterConfiguration. There is no ClusterConfiguration for mgmt cluster.
+				// Versions are the ones of the deployed release, so concrete even when a version constraint is requested.
 				chartDeployed = append(chartDeployed, configv1beta1.Chart{
 					RepoURL:         currentChart.RepositoryURL,
 					Namespace:       currentRelease.ReleaseNamespace,
 					ReleaseName:     currentRelease.ReleaseName,
 					ChartVersion:    currentRelease.ChartVersion,
 					AppVersion:      currentRelease.AppVersion,
+					Revision:        revision,
 					LastAppliedTime: &currentRelease.Updated,
 					Icon:            currentRelease.Icon,
+					Verification:    currentRelease.Verification,
+					Values:          currentRelease.Values,
 				})
 			}
 		}
 	}
 
-	if conflictErrorMessage != "" {
+	if deployErr != nil {
+		return releaseReports, chartDeployed, deployErr
+	}
+
+	if conflictErrorMessage != "" || stopOnConflict {
 		return releaseReports, chartDeployed, deployer.NewConflictError(conflictErrorMessage)
 	}
 
@@ -608,7 +730,7 @@ func determineChartOwnership(ctx context.Context, c client.Client, claimingHelmM
 	if !chartManager.CanManageChart(claimingHelmManager, currentChart) {
 		// Another ClusterSummay is already managing this chart. Get the:
 		// 1. ClusterSummary managing the chart
-		// 2. Use the ClusterSummary's tiers to decide who should managed it
+		// 2. Use the ClusterSummary's tiers (and registered ConflictResolvers) to decide who should managed it
 		l.V(logs.LogDebug).Info("conflict detected")
 		clusterSummaryManaging, err := chartManager.GetManagerForChart(claimingHelmManager.Spec.ClusterNamespace, claimingHelmManager.Spec.ClusterName,
 			claimingHelmManager.Spec.ClusterType, currentChart)
@@ -624,7 +746,10 @@ func determineChartOwnership(ctx context.Context, c client.Client, claimingHelmM
 			return false, err
 		}
 
-		if hasHigherOwnershipPriority(currentHelmManager.Spec.ClusterProfileSpec.Tier, claimingHelmManager.Spec.ClusterProfileSpec.Tier) {
+		conflict := getHelmReleaseConflict(claimingHelmManager, currentHelmManager, currentChart)
+		claimantWins := hasHigherOwnershipPriority(currentHelmManager.Spec.ClusterProfileSpec.Tier,
+			claimingHelmManager.Spec.ClusterProfileSpec.Tier)
+		if resolveConflict(ctx, conflict, claimantWins, l) {
 			// New ClusterSummary is taking over managing this chart. So reset helmReleaseSummaries for this chart
 			// This needs to happen immediately. helmReleaseSummaries are used by Sveltos to rebuild list of which
 			// clusterSummary is managing an helm chart if pod restarts
@@ -687,6 +812,8 @@ func handleInstall(ctx context.Context, clusterSummary *configv1beta1.ClusterSum
 		ReleaseNamespace: currentChart.ReleaseNamespace, ReleaseName: currentChart.ReleaseName,
 		ChartVersion: currentChart.ChartVersion, Action: string(configv1beta1.InstallHelmAction),
 	}
+	addDryRunDiffToReport(ctx, clusterSummary, mgmtResources, currentChart, kubeconfig, registryOptions,
+		report, logger)
 	return report, nil
 }
 
@@ -723,6 +850,8 @@ func handleUpgrade(ctx context.Context, clusterSummary *configv1beta1.ClusterSum
 		ChartVersion: currentChart.ChartVersion, Action: string(configv1beta1.UpgradeHelmAction),
 		Message: message,
 	}
+	addDryRunDiffToReport(ctx, clusterSummary, mgmtResources, currentChart, kubeconfig, registryOptions,
+		report, logger)
 	return report, nil
 }
 
@@ -748,7 +877,7 @@ func handleChart(ctx context.Context, clusterSummary *configv1beta1.ClusterSumma
 	kubeconfig string, logger logr.Logger) (*releaseInfo, *configv1beta1.ReleaseReport, error) {
 
 	credentialsPath, caPath, err := getCredentialsAndCAFiles(ctx, getManagementClusterClient(),
-		clusterSummary.Spec.ClusterNamespace, currentChart)
+		clusterSummary, clusterSummary.Spec.ClusterNamespace, currentChart)
 	if err != nil {
 		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to process credentials %v", err))
 		return nil, nil, err
@@ -771,6 +900,21 @@ func handleChart(ctx context.Context, clusterSummary *configv1beta1.ClusterSumma
 	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
 		return nil, nil, err
 	}
+
+	// An interrupted helm operation might have left release in a pending state
+	recovered, err := recoverPendingRelease(clusterSummary, currentRelease, currentChart, kubeconfig,
+		registryOptions, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	if recovered {
+		currentRelease, err = getReleaseInfo(currentChart.ReleaseName,
+			currentChart.ReleaseNamespace, kubeconfig, registryOptions, getEnableClientCacheValue(currentChart.Options))
+		if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil, nil, err
+		}
+	}
+
 	var report *configv1beta1.ReleaseReport
 
 	logger = logger.WithValues("releaseNamespace", currentChart.ReleaseNamespace, "releaseName",
@@ -778,6 +922,15 @@ func handleChart(ctx context.Context, clusterSummary *configv1beta1.ClusterSumma
 
 	if currentRelease != nil {
 		logger.V(logs.LogDebug).Info(fmt.Sprintf("current installed version %s", currentChart.ChartVersion))
+		if currentChart.HelmChartAction != configv1beta1.HelmChartActionUninstall &&
+			currentRelease.Status != release.StatusUninstalled.String() {
+
+			err = checkReleaseAdoption(ctx, getManagementClusterClient(), clusterSummary, currentChart,
+				currentRelease, logger)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
 	}
 
 	if registryOptions.credentialsPath != "" {
@@ -792,6 +945,13 @@ func handleChart(ctx context.Context, clusterSummary *configv1beta1.ClusterSumma
 		}
 	}
 
+	// Only signed charts are installed/upgraded
+	verification, err := verifyHelmChart(ctx, getManagementClusterClient(), clusterSummary, currentChart,
+		registryOptions, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if shouldInstall(currentRelease, currentChart) {
 		report, err = handleInstall(ctx, clusterSummary, mgmtResources, currentChart, kubeconfig,
 			registryOptions, logger)
@@ -839,6 +999,14 @@ func handleChart(ctx context.Context, clusterSummary *configv1beta1.ClusterSumma
 	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
 		return nil, nil, err
 	}
+	if currentRelease != nil {
+		currentRelease.Verification = verification
+	}
+
+	err = runHelmTests(ctx, clusterSummary, currentChart, currentRelease, kubeconfig, registryOptions, logger)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	return currentRelease, report, nil
 }
@@ -847,8 +1015,13 @@ func handleChart(ctx context.Context, clusterSummary *configv1beta1.ClusterSumma
 func repoAddOrUpdate(settings *cli.EnvSettings, name, repoURL string, logger logr.Logger) error {
 	logger = logger.WithValues("repoURL", repoURL, "repoName", name)
 
+	getters, err := getHelmGetters(settings)
+	if err != nil {
+		return err
+	}
+
 	entry := &repo.Entry{Name: name, URL: repoURL}
-	chartRepo, err := repo.NewChartRepository(entry, getter.All(settings))
+	chartRepo, err := repo.NewChartRepository(entry, getters)
 	if err != nil {
 		return err
 	}
@@ -863,7 +1036,7 @@ func repoAddOrUpdate(settings *cli.EnvSettings, name, repoURL string, logger log
 	if !registry.IsOCI(entry.URL) {
 		_, err = chartRepo.DownloadIndexFile()
 		if err != nil {
-			return err
+			return withMessageCode(getChartPullMessageCode(err), err)
 		}
 	}
 
@@ -885,10 +1058,17 @@ func installRelease(ctx context.Context, clusterSummary *configv1beta1.ClusterSu
 	values map[string]interface{}, mgmtResources map[string]*unstructured.Unstructured, logger logr.Logger) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
+	ctx, done, err := trackHelmOperation(ctx, clusterSummary, requestedChart.ReleaseNamespace,
+		requestedChart.ReleaseName, "install")
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	if requestedChart.ChartName == "" {
 		return fmt.Errorf("chart name can not be empty")
 	}
@@ -910,22 +1090,29 @@ func installRelease(ctx context.Context, clusterSummary *configv1beta1.ClusterSu
 		return err
 	}
 
-	installClient, err := getHelmInstallClient(requestedChart, kubeconfig, registryOptions, patches)
+	mirrors, err := getRegistryMirrors(ctx, getManagementClusterClient(), clusterSummary)
+	if err != nil {
+		return err
+	}
+
+	installClient, err := getHelmInstallClient(requestedChart, kubeconfig, registryOptions, patches, mirrors)
 	if err != nil {
 		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get helm install client: %v", err))
 		return err
 	}
 
-	cp, err := installClient.ChartPathOptions.LocateChart(chartName, settings)
+	cp, releaseChart, err := locateChart(&installClient.ChartPathOptions, chartName, settings)
 	if err != nil {
 		logger.V(logs.LogDebug).Info("LocateChart failed")
-		return err
+		return withMessageCode(getChartPullMessageCode(err), err)
 	}
+	defer releaseChart()
+	recordChartCacheUsage(clusterSummary, cp)
 
 	chartRequested, err := loader.Load(cp)
 	if err != nil {
 		logger.V(logs.LogDebug).Info("Load failed")
-		return err
+		return withMessageCode(configv1beta1.MessageCodeChartPullFailed, err)
 	}
 
 	validInstallableChart := isChartInstallable(chartRequested)
@@ -933,8 +1120,8 @@ func installRelease(ctx context.Context, clusterSummary *configv1beta1.ClusterSu
 		return fmt.Errorf("chart is not installable")
 	}
 
-	if getDependenciesUpdateValue(requestedChart.Options) {
-		err = checkDependencies(chartRequested, installClient, cp, settings)
+	if installClient.DependencyUpdate {
+		err = checkDependencies(chartRequested, installClient.ChartPathOptions.Keyring, cp, settings)
 		if err != nil {
 			return err
 		}
@@ -945,37 +1132,52 @@ func installRelease(ctx context.Context, clusterSummary *configv1beta1.ClusterSu
 		return fmt.Errorf("%w: failed reloading chart after repo update", err)
 	}
 
+	renderKey := ""
+	if installClient.PostRenderer == nil && !installClient.DependencyUpdate {
+		renderKey = getChartRenderKey(&installClient.ChartPathOptions, chartName, settings,
+			requestedChart.ReleaseNamespace, requestedChart.ReleaseName, chartRequested, values)
+	}
+	cachedChart, rendered := getCachedRenderedChart(renderKey, chartRequested, time.Now())
+	if rendered {
+		logger.V(logs.LogDebug).Info("reusing rendered chart templates")
+		chartRequested = cachedChart
+	}
+
 	installClient.DryRun = false
-	_, err = installClient.RunWithContext(ctx, chartRequested, values)
+	rel, err := installClient.RunWithContext(ctx, chartRequested, values)
 	if err != nil {
 		return err
 	}
 
+	if !rendered {
+		if err := storeRenderedChart(renderKey, rel); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to store rendered chart templates: %v", err))
+		}
+	}
+
 	logger.V(logs.LogDebug).Info("installing release done")
 
 	return nil
 }
 
-func checkDependencies(chartRequested *chart.Chart, installClient *action.Install, cp string, settings *cli.EnvSettings) error {
+// checkDependencies downloads chart dependencies if any is missing.
+func checkDependencies(chartRequested *chart.Chart, keyring, cp string, settings *cli.EnvSettings) error {
 	if req := chartRequested.Metadata.Dependencies; req != nil {
 		err := action.CheckDependencies(chartRequested, req)
 		if err != nil {
-			if installClient.DependencyUpdate {
-				man := &downloader.Manager{
-					ChartPath:        cp,
-					Keyring:          installClient.ChartPathOptions.Keyring,
-					SkipUpdate:       false,
-					Getters:          getter.All(settings),
-					RepositoryConfig: settings.RepositoryConfig,
-					RepositoryCache:  settings.RepositoryCache,
-				}
-				err = man.Update()
-				if err != nil {
-					return err
-				}
-			} else {
-				return nil
+			getters, err := getHelmGetters(settings)
+			if err != nil {
+				return err
 			}
+			man := &downloader.Manager{
+				ChartPath:        cp,
+				Keyring:          keyring,
+				SkipUpdate:       false,
+				Getters:          getters,
+				RepositoryConfig: settings.RepositoryConfig,
+				RepositoryCache:  settings.RepositoryCache,
+			}
+			return man.Update()
 		}
 	}
 
@@ -989,10 +1191,17 @@ func uninstallRelease(clusterSummary *configv1beta1.ClusterSummary,
 	helmChart *configv1beta1.HelmChart, logger logr.Logger) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
+	_, done, err := trackHelmOperation(context.Background(), clusterSummary, releaseNamespace, releaseName,
+		"uninstall")
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	logger = logger.WithValues("release", releaseName, "releaseNamespace", releaseNamespace)
 	logger.V(logs.LogDebug).Info("uninstalling release")
 
@@ -1012,6 +1221,8 @@ func uninstallRelease(clusterSummary *configv1beta1.ClusterSummary,
 	}
 
 	_, err = uninstallClient.Run(releaseName)
+	recordAudit(context.Background(), clusterSummary, getHelmAuditRecord(AuditOperationHelmUninstall,
+		releaseNamespace, releaseName, helmChart), err, logger)
 	if err != nil {
 		return err
 	}
@@ -1029,10 +1240,17 @@ func upgradeRelease(ctx context.Context, clusterSummary *configv1beta1.ClusterSu
 	mgmtResources map[string]*unstructured.Unstructured, logger logr.Logger) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
+	ctx, done, err := trackHelmOperation(ctx, clusterSummary, requestedChart.ReleaseNamespace,
+		requestedChart.ReleaseName, "upgrade")
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	if requestedChart.ChartName == "" {
 		return fmt.Errorf("chart name can not be empty")
 	}
@@ -1064,20 +1282,37 @@ func upgradeRelease(ctx context.Context, clusterSummary *configv1beta1.ClusterSu
 
 	patches = append(patches, driftExclusionPatches...)
 
-	upgradeClient, err := getHelmUpgradeClient(requestedChart, actionConfig, patches)
+	mirrors, err := getRegistryMirrors(ctx, getManagementClusterClient(), clusterSummary)
 	if err != nil {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get helm upgrade client: %v", err))
 		return err
 	}
 
-	cp, err := upgradeClient.ChartPathOptions.LocateChart(chartName, settings)
+	upgradeClient, err := getHelmUpgradeClient(requestedChart, actionConfig, patches, mirrors)
 	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get helm upgrade client: %v", err))
 		return err
 	}
 
+	cp, releaseChart, err := locateChart(&upgradeClient.ChartPathOptions, chartName, settings)
+	if err != nil {
+		return withMessageCode(getChartPullMessageCode(err), err)
+	}
+	defer releaseChart()
+	recordChartCacheUsage(clusterSummary, cp)
+
 	chartRequested, err := loader.Load(cp)
 	if err != nil {
-		return err
+		return withMessageCode(configv1beta1.MessageCodeChartPullFailed, err)
+	}
+	if upgradeClient.DependencyUpdate {
+		err = checkDependencies(chartRequested, upgradeClient.ChartPathOptions.Keyring, cp, settings)
+		if err != nil {
+			return err
+		}
+		// Reload the chart with the updated Chart.lock file.
+		if chartRequested, err = loader.Load(cp); err != nil {
+			return fmt.Errorf("%w: failed reloading chart after repo update", err)
+		}
 	}
 	if req := chartRequested.Metadata.Dependencies; req != nil {
 		err = action.CheckDependencies(chartRequested, req)
@@ -1100,12 +1335,31 @@ func upgradeRelease(ctx context.Context, clusterSummary *configv1beta1.ClusterSu
 		return err
 	}
 
+	renderKey := ""
+	if upgradeClient.PostRenderer == nil && !upgradeClient.DependencyUpdate &&
+		!upgradeClient.ReuseValues && !upgradeClient.ResetThenReuseValues {
+
+		renderKey = getChartRenderKey(&upgradeClient.ChartPathOptions, chartName, settings,
+			requestedChart.ReleaseNamespace, requestedChart.ReleaseName, chartRequested, values)
+	}
+	cachedChart, rendered := getCachedRenderedChart(renderKey, chartRequested, time.Now())
+	if rendered {
+		logger.V(logs.LogDebug).Info("reusing rendered chart templates")
+		chartRequested = cachedChart
+	}
+
 	upgradeClient.DryRun = false
-	_, err = upgradeClient.RunWithContext(ctx, requestedChart.ReleaseName, chartRequested, values)
+	rel, err := upgradeClient.RunWithContext(ctx, requestedChart.ReleaseName, chartRequested, values)
 	if err != nil {
 		return err
 	}
 
+	if !rendered {
+		if err := storeRenderedChart(renderKey, rel); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to store rendered chart templates: %v", err))
+		}
+	}
+
 	logger.V(logs.LogDebug).Info("upgrading release done")
 
 	return nil
@@ -1119,6 +1373,10 @@ func getRegistryClient(namespace string, registryOptions *registryClientOptions,
 ) (*registry.Client, error) {
 
 	settings := getSettings(namespace, registryOptions)
+	if isStrictOutboundTLS() {
+		return getStrictRegistryClient(settings, registryOptions, enableClientCache)
+	}
+
 	if registryOptions.caPath == "" && !registryOptions.skipTLSVerify {
 		options := []registry.ClientOption{
 			registry.ClientOptDebug(settings.Debug),
@@ -1138,6 +1396,30 @@ func getRegistryClient(namespace string, registryOptions *registryClientOptions,
 		registryOptions.skipTLSVerify, registryOptions.credentialsPath, settings.Debug)
 }
 
+// getStrictRegistryClient returns a registry client enforcing strict outbound TLS
+func getStrictRegistryClient(settings *cli.EnvSettings, registryOptions *registryClientOptions,
+	enableClientCache bool) (*registry.Client, error) {
+
+	tlsConfig, err := getOutboundTLSConfig(registryOptions.caPath, registryOptions.skipTLSVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []registry.ClientOption{
+		registry.ClientOptDebug(settings.Debug),
+		registry.ClientOptEnableCache(enableClientCache),
+		registry.ClientOptWriter(os.Stderr),
+		registry.ClientOptHTTPClient(&http.Client{Transport: getOutboundTransport(tlsConfig)}),
+	}
+	if registryOptions.credentialsPath != "" {
+		options = append(options, registry.ClientOptCredentialsFile(registryOptions.credentialsPath))
+	}
+	if registryOptions.plainHTTP {
+		options = append(options, registry.ClientOptPlainHTTP())
+	}
+	return registry.NewClient(options...)
+}
+
 func actionConfigInit(namespace, kubeconfig string, registryOptions *registryClientOptions, enableClientCache bool,
 ) (*action.Configuration, error) {
 
@@ -1148,6 +1430,7 @@ func actionConfigInit(namespace, kubeconfig string, registryOptions *registryCli
 	configFlags.Namespace = &namespace
 	insecure := true
 	configFlags.Insecure = &insecure
+	configFlags.WrapConfigFn = setRemoteUserAgent
 
 	err := actionConfig.Init(configFlags, namespace, "secret", debugf)
 	if err != nil {
@@ -1206,6 +1489,11 @@ func getReleaseInfo(releaseName, releaseNamespace, kubeconfig string, registryOp
 	}
 	element.Updated = t
 
+	element.Values, err = getChartValuesSnapshot(results.Config)
+	if err != nil {
+		return nil, err
+	}
+
 	return element, nil
 }
 
@@ -1312,7 +1600,7 @@ func doInstallRelease(ctx context.Context, clusterSummary *configv1beta1.Cluster
 	kubeconfig string, registryOptions *registryClientOptions, logger logr.Logger) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
@@ -1337,6 +1625,8 @@ func doInstallRelease(ctx context.Context, clusterSummary *configv1beta1.Cluster
 
 	err = installRelease(ctx, clusterSummary, settings, requestedChart, kubeconfig, registryOptions,
 		values, mgmtResources, logger)
+	recordAudit(ctx, clusterSummary, getHelmAuditRecord(AuditOperationHelmInstall, requestedChart.ReleaseNamespace,
+		requestedChart.ReleaseName, requestedChart), err, logger)
 	if err != nil {
 		return err
 	}
@@ -1350,7 +1640,7 @@ func doUninstallRelease(clusterSummary *configv1beta1.ClusterSummary, requestedC
 	kubeconfig string, registryOptions *registryClientOptions, logger logr.Logger) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
@@ -1370,7 +1660,7 @@ func doUpgradeRelease(ctx context.Context, clusterSummary *configv1beta1.Cluster
 	kubeconfig string, registryOptions *registryClientOptions, logger logr.Logger) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
@@ -1395,6 +1685,8 @@ func doUpgradeRelease(ctx context.Context, clusterSummary *configv1beta1.Cluster
 
 	err = upgradeRelease(ctx, clusterSummary, settings, requestedChart, kubeconfig, registryOptions,
 		values, mgmtResources, logger)
+	recordAudit(ctx, clusterSummary, getHelmAuditRecord(AuditOperationHelmUpgrade, requestedChart.ReleaseNamespace,
+		requestedChart.ReleaseName, requestedChart), err, logger)
 	if err != nil {
 		return err
 	}
@@ -1408,7 +1700,7 @@ func updateChartsInClusterConfiguration(ctx context.Context, c client.Client, cl
 	chartDeployed []configv1beta1.Chart, logger logr.Logger) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
@@ -1457,8 +1749,11 @@ func undeployStaleReleases(ctx context.Context, c client.Client, clusterSummary
 				return nil, err
 			}
 
-			if err := uninstallRelease(clusterSummary, managedHelmReleases[i].Name, managedHelmReleases[i].Namespace,
-				kubeconfig, &registryClientOptions{}, nil, logger); err != nil {
+			unlock := lockRelease(clusterSummary, managedHelmReleases[i].Namespace, managedHelmReleases[i].Name)
+			err = uninstallRelease(clusterSummary, managedHelmReleases[i].Name, managedHelmReleases[i].Namespace,
+				kubeconfig, &registryClientOptions{}, nil, logger)
+			unlock()
+			if err != nil {
 				return nil, err
 			}
 
@@ -1484,7 +1779,7 @@ func updateStatusForeferencedHelmReleases(ctx context.Context, c client.Client,
 	clusterSummary *configv1beta1.ClusterSummary, logger logr.Logger) (*configv1beta1.ClusterSummary, bool, error) {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return clusterSummary, false, nil
 	}
 
@@ -1530,6 +1825,7 @@ func updateStatusForeferencedHelmReleases(ctx context.Context, c client.Client,
 					Status:           configv1beta1.HelmChartStatusManaging,
 					ValuesHash:       getValueHashFromHelmChartSummary(currentChart, clusterSummary), // if a value is currently stored, keep it.
 					// after chart is deployed such value will be updated
+					TestResult: getHelmTestResult(clusterSummary, currentChart),
 				}
 				currentlyReferenced[helmInfo(currentChart.ReleaseNamespace, currentChart.ReleaseName)] = true
 			} else {
@@ -1576,7 +1872,7 @@ func updateStatusForNonReferencedHelmReleases(ctx context.Context, c client.Clie
 	clusterSummary *configv1beta1.ClusterSummary) (*configv1beta1.ClusterSummary, error) {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return clusterSummary, nil
 	}
 
@@ -1662,7 +1958,7 @@ func createReportForUnmanagedHelmRelease(ctx context.Context, c client.Client, c
 		report.Message = getHelmChartConflictManager(ctx, c, clusterSummary.Spec.ClusterNamespace,
 			clusterSummaryManagerName, logger)
 		report.Action = string(configv1beta1.ConflictHelmAction)
-	} else if currentChart.HelmChartAction == configv1beta1.HelmChartActionInstall {
+	} else if currentChart.HelmChartAction != configv1beta1.HelmChartActionUninstall {
 		report.Action = string(configv1beta1.InstallHelmAction)
 	} else {
 		report.Message = notInstalledMessage
@@ -1678,33 +1974,19 @@ func updateClusterReportWithHelmReports(ctx context.Context, c client.Client,
 	releaseReports []configv1beta1.ReleaseReport) error {
 
 	// This is no-op unless in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode != configv1beta1.SyncModeDryRun {
+	if !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
-	profileOwnerRef, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
-	if err != nil {
-		return err
-	}
-
-	clusterReportName := getClusterReportName(profileOwnerRef.Kind, profileOwnerRef.Name,
-		clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType)
-
-	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		clusterReport := &configv1beta1.ClusterReport{}
-		err = c.Get(ctx,
-			types.NamespacedName{Namespace: clusterSummary.Spec.ClusterNamespace, Name: clusterReportName}, clusterReport)
-		if err != nil {
-			return err
-		}
-
-		clusterReport.Status.ReleaseReports = releaseReports
-		return c.Status().Update(ctx, clusterReport)
+	return updateClusterReportSection(ctx, c, clusterSummary, func(status *configv1beta1.ClusterReportStatus) {
+		status.ReleaseReports = releaseReports
 	})
-
-	return err
 }
 
+// getInstantiatedValues returns the values for the helm release. Values are merged in order: first
+// HelmChart.Values, then each ConfigMap/Secret referenced in HelmChart.ValuesFrom (keys within a
+// ConfigMap/Secret are processed in alphabetical order), then each HelmChart.ValuesOverrides matching
+// the cluster. When the same value is set more than once, last one wins. Nested maps are merged.
 func getInstantiatedValues(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
 	mgmtResources map[string]*unstructured.Unstructured, requestedChart *configv1beta1.HelmChart,
 	logger logr.Logger) (chartutil.Values, error) {
@@ -1716,36 +1998,86 @@ func getInstantiatedValues(ctx context.Context, clusterSummary *configv1beta1.Cl
 		return nil, err
 	}
 
-	c := getManagementClusterClient()
-	templatedValuesFrom, valuesFrom, err := getHelmChartValuesFrom(ctx, c, clusterSummary, requestedChart, logger)
+	values, err := chartutil.ReadValues([]byte(instantiatedValues))
 	if err != nil {
 		return nil, err
 	}
 
-	for k := range templatedValuesFrom {
-		instantiatedValuesFrom, err := instantiateTemplateValues(ctx, getManagementClusterConfig(), getManagementClusterClient(),
-			clusterSummary.Spec.ClusterType, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
-			requestedChart.ChartName, templatedValuesFrom[k], mgmtResources, logger)
+	c := getManagementClusterClient()
+	for i := range requestedChart.ValuesFrom {
+		data, isTemplate, err := getValuesFromResource(ctx, c, clusterSummary, &requestedChart.ValuesFrom[i], logger)
 		if err != nil {
 			return nil, err
 		}
-		instantiatedValues += fmt.Sprintf("\n\n%s", instantiatedValuesFrom)
+
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			content := data[k]
+			if isTemplate {
+				content, err = instantiateTemplateValues(ctx, getManagementClusterConfig(), c,
+					clusterSummary.Spec.ClusterType, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+					requestedChart.ChartName, content, mgmtResources, logger)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			currentValues, err := chartutil.ReadValues([]byte(content))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse values from %s %s key %s: %w",
+					requestedChart.ValuesFrom[i].Kind, requestedChart.ValuesFrom[i].Name, k, err)
+			}
+			values = mergeHelmValues(values, currentValues)
+		}
 	}
 
-	for k := range valuesFrom {
-		instantiatedValues += fmt.Sprintf("\n\n%s", valuesFrom[k])
+	overrides, err := getMatchingValuesOverrides(ctx, c, clusterSummary, requestedChart)
+	if err != nil {
+		return nil, err
+	}
+	for i := range overrides {
+		content, err := instantiateTemplateValues(ctx, getManagementClusterConfig(), c,
+			clusterSummary.Spec.ClusterType, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+			requestedChart.ChartName, overrides[i].Values, mgmtResources, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		currentValues, err := chartutil.ReadValues([]byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse values override: %w", err)
+		}
+		values = mergeHelmValues(values, currentValues)
 	}
 
-	logger.V(logs.LogDebug).Info(fmt.Sprintf("Deploying helm charts with Values %q", instantiatedValues))
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("Deploying helm charts with Values %v", values))
 
-	return chartutil.ReadValues([]byte(instantiatedValues))
+	return values, nil
 }
 
-// getHelmChartValuesFrom return key-value pair from referenced ConfigMap/Secret
-func getHelmChartValuesFrom(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
-	helmChart *configv1beta1.HelmChart, logger logr.Logger) (templatedValues, nonTemplatedValues map[string]string, err error) {
+// mergeHelmValues merges src into dst. Values in src override the ones in dst. Nested maps are
+// merged recursively.
+func mergeHelmValues(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{})
+	}
+
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[k] = mergeHelmValues(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
 
-	return getValuesFrom(ctx, c, clusterSummary, helmChart.ValuesFrom, false, logger)
+	return dst
 }
 
 // collectResourcesFromManagedHelmChartsForDriftDetection collects resources considering all
@@ -1769,7 +2101,7 @@ func collectResourcesFromManagedHelmChartsForDriftDetection(ctx context.Context,
 		l.V(logs.LogDebug).Info("collecting resources for helm chart")
 		// Conflicts are already resolved by the time this is invoked. So it is safe to call CanManageChart
 		if chartManager.CanManageChart(clusterSummary, currentChart) {
-			credentialsPath, caPath, err := getCredentialsAndCAFiles(ctx, c,
+			credentialsPath, caPath, err := getCredentialsAndCAFiles(ctx, c, clusterSummary,
 				clusterSummary.Spec.ClusterNamespace, currentChart)
 			if err != nil {
 				logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to process credentials %v", err))
@@ -1866,6 +2198,7 @@ func getSettings(namespace string, registryOptions *registryClientOptions) *cli.
 	settings.SetNamespace(namespace)
 	settings.Debug = true
 	settings.RegistryConfig = registryOptions.credentialsPath
+	settings.RepositoryCache = getChartCacheDir()
 
 	return settings
 }
@@ -1902,6 +2235,37 @@ func getCreateNamespaceHelmValue(options *configv1beta1.HelmOptions) bool {
 	return true // for backward compatibility
 }
 
+// shouldCreateReleaseNamespace returns true if the release namespace must be created.
+// HelmChart.CreateNamespace, if set, takes precedence over Options.InstallOptions.CreateNamespace.
+func shouldCreateReleaseNamespace(requestedChart *configv1beta1.HelmChart) bool {
+	if requestedChart.CreateNamespace != nil {
+		return *requestedChart.CreateNamespace
+	}
+
+	return getCreateNamespaceHelmValue(requestedChart.Options)
+}
+
+// createReleaseNamespaces creates, with the labels and annotations in Spec.TargetNamespaceMetadata,
+// the namespaces of the helm releases to install which do not exist yet.
+func createReleaseNamespaces(ctx context.Context, remoteClient client.Client,
+	clusterSummary *configv1beta1.ClusterSummary) error {
+
+	for i := range clusterSummary.Spec.ClusterProfileSpec.HelmCharts {
+		currentChart := &clusterSummary.Spec.ClusterProfileSpec.HelmCharts[i]
+		if currentChart.HelmChartAction == configv1beta1.HelmChartActionUninstall ||
+			!shouldCreateReleaseNamespace(currentChart) {
+
+			continue
+		}
+
+		if err := createNamespace(ctx, remoteClient, clusterSummary, currentChart.ReleaseNamespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func getSkipCRDsHelmValue(options *configv1beta1.HelmOptions) bool {
 	if options != nil {
 		return options.SkipCRDs
@@ -2049,7 +2413,7 @@ func getRecreateValue(options *configv1beta1.HelmOptions) bool {
 }
 
 func getHelmInstallClient(requestedChart *configv1beta1.HelmChart, kubeconfig string,
-	registryOptions *registryClientOptions, patches []libsveltosv1beta1.Patch,
+	registryOptions *registryClientOptions, patches []libsveltosv1beta1.Patch, mirrors map[string]string,
 ) (*action.Install, error) {
 
 	actionConfig, err := actionConfigInit(requestedChart.ReleaseNamespace, kubeconfig, registryOptions,
@@ -2064,7 +2428,7 @@ func getHelmInstallClient(requestedChart *configv1beta1.HelmChart, kubeconfig st
 	installClient.Version = requestedChart.ChartVersion
 	installClient.Wait = getWaitHelmValue(requestedChart.Options)
 	installClient.WaitForJobs = getWaitForJobsHelmValue(requestedChart.Options)
-	installClient.CreateNamespace = getCreateNamespaceHelmValue(requestedChart.Options)
+	installClient.CreateNamespace = shouldCreateReleaseNamespace(requestedChart)
 	installClient.SkipCRDs = getSkipCRDsHelmValue(requestedChart.Options)
 	installClient.Atomic = getAtomicHelmValue(requestedChart.Options)
 	installClient.DisableHooks = getDisableHooksHelmValue(requestedChart.Options)
@@ -2076,21 +2440,22 @@ func getHelmInstallClient(requestedChart *configv1beta1.HelmChart, kubeconfig st
 		}
 	}
 	installClient.Replace = getReplaceValue(requestedChart.Options)
-	installClient.Labels = getLabelsValue(requestedChart.Options)
+	installClient.DependencyUpdate = getDependenciesUpdateValue(requestedChart.Options)
+	installClient.Labels = getReleaseLabels(requestedChart.Options)
 	installClient.Description = getDescriptionValue(requestedChart.Options)
 	if actionConfig.RegistryClient != nil {
 		installClient.SetRegistryClient(actionConfig.RegistryClient)
 	}
 
-	if len(patches) > 0 {
-		installClient.PostRenderer = &patcher.CustomPatchPostRenderer{Patches: patches}
+	if postRenderer := getHelmPostRenderer(patches, mirrors); postRenderer != nil {
+		installClient.PostRenderer = postRenderer
 	}
 
 	return installClient, nil
 }
 
 func getHelmUpgradeClient(requestedChart *configv1beta1.HelmChart, actionConfig *action.Configuration,
-	patches []libsveltosv1beta1.Patch) (*action.Upgrade, error) {
+	patches []libsveltosv1beta1.Patch, mirrors map[string]string) (*action.Upgrade, error) {
 
 	upgradeClient := action.NewUpgrade(actionConfig)
 	upgradeClient.Install = true
@@ -2113,19 +2478,20 @@ func getHelmUpgradeClient(requestedChart *configv1beta1.HelmChart, actionConfig
 	upgradeClient.ReuseValues = getReuseValues(requestedChart.Options)
 	upgradeClient.ResetThenReuseValues = getResetThenReuseValues(requestedChart.Options)
 	upgradeClient.Force = getForceValue(requestedChart.Options)
-	upgradeClient.Labels = getLabelsValue(requestedChart.Options)
+	upgradeClient.Labels = getReleaseLabels(requestedChart.Options)
 	upgradeClient.Description = getDescriptionValue(requestedChart.Options)
 	upgradeClient.MaxHistory = getMaxHistoryValue(requestedChart.Options)
 	upgradeClient.CleanupOnFail = getCleanupOnFailValue(requestedChart.Options)
 	upgradeClient.SubNotes = getSubNotesValue(requestedChart.Options)
 	upgradeClient.Recreate = getRecreateValue(requestedChart.Options)
+	upgradeClient.DependencyUpdate = getDependenciesUpdateValue(requestedChart.Options)
 
 	if actionConfig.RegistryClient != nil {
 		upgradeClient.SetRegistryClient(actionConfig.RegistryClient)
 	}
 
-	if len(patches) > 0 {
-		upgradeClient.PostRenderer = &patcher.CustomPatchPostRenderer{Patches: patches}
+	if postRenderer := getHelmPostRenderer(patches, mirrors); postRenderer != nil {
+		upgradeClient.PostRenderer = postRenderer
 	}
 
 	return upgradeClient, nil
@@ -2157,7 +2523,7 @@ func addExtraMetadata(ctx context.Context, requestedChart *configv1beta1.HelmCha
 	registryOptions *registryClientOptions, logger logr.Logger) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
@@ -2210,7 +2576,7 @@ func addExtraMetadata(ctx context.Context, requestedChart *configv1beta1.HelmCha
 		addExtraLabels(r, clusterSummary.Spec.ClusterProfileSpec.ExtraLabels)
 		addExtraAnnotations(r, clusterSummary.Spec.ClusterProfileSpec.ExtraAnnotations)
 
-		err = updateResource(ctx, dr, clusterSummary, r, []string{}, logger)
+		err = updateResource(ctx, dr, clusterSummary, r, []string{}, defaultFieldManager, true, logger)
 		if err != nil {
 			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to update resource %s %s/%s: %v",
 				r.GetKind(), r.GetNamespace(), r.GetName(), err))
@@ -2301,16 +2667,21 @@ func getValueHashFromHelmChartSummary(requestedChart *configv1beta1.HelmChart,
 	return nil
 }
 
-func getCredentialsAndCAFiles(ctx context.Context, c client.Client, clusterNamespace string,
-	requestedChart *configv1beta1.HelmChart) (credentialsPath, caPath string, err error) {
+// getCredentialsAndCAFiles writes registry credentials and CA certificate, if any, to temporary files.
+// Returns an error if tenant admin which created clusterSummary is not allowed to get those Secrets.
+func getCredentialsAndCAFiles(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	clusterNamespace string, requestedChart *configv1beta1.HelmChart) (credentialsPath, caPath string, err error) {
 
-	credentialsPath, err = createFileWithCredentials(ctx, c, clusterNamespace, requestedChart)
+	credentialsPath, err = createFileWithCredentials(ctx, c, clusterSummary, clusterNamespace, requestedChart)
 	if err != nil {
 		return "", "", err
 	}
 
-	caPath, err = createFileWithCA(ctx, c, clusterNamespace, requestedChart)
+	caPath, err = createFileWithCA(ctx, c, clusterSummary, clusterNamespace, requestedChart)
 	if err != nil {
+		if credentialsPath != "" {
+			os.Remove(credentialsPath)
+		}
 		return "", "", err
 	}
 
@@ -2319,8 +2690,8 @@ func getCredentialsAndCAFiles(ctx context.Context, c client.Client, clusterNames
 
 // createFileWithCredentials fetches the credentials from a Secret and writes it to a temporary file.
 // Returns the path to the temporary file.
-func createFileWithCredentials(ctx context.Context, c client.Client, clusterNamespace string,
-	requestedChart *configv1beta1.HelmChart) (string, error) {
+func createFileWithCredentials(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	clusterNamespace string, requestedChart *configv1beta1.HelmChart) (string, error) {
 
 	if requestedChart.RegistryCredentialsConfig == nil ||
 		requestedChart.RegistryCredentialsConfig.CredentialsSecretRef == nil {
@@ -2331,8 +2702,14 @@ func createFileWithCredentials(ctx context.Context, c client.Client, clusterName
 	namespace := libsveltostemplate.GetReferenceResourceNamespace(
 		clusterNamespace, requestedChart.RegistryCredentialsConfig.CredentialsSecretRef.Namespace)
 
+	err := validateAdminCanGetReference(ctx, c, clusterSummary, string(libsveltosv1beta1.SecretReferencedResourceKind),
+		namespace, credSecretRef.Name)
+	if err != nil {
+		return "", err
+	}
+
 	secret := &corev1.Secret{}
-	err := c.Get(ctx,
+	err = c.Get(ctx,
 		types.NamespacedName{
 			Namespace: namespace,
 			Name:      credSecretRef.Name,
@@ -2365,8 +2742,8 @@ func createFileWithCredentials(ctx context.Context, c client.Client, clusterName
 
 // createFileWithCA fetches the CA certificate from a Secret and writes it to a temporary file.
 // Returns the path to the temporary file.
-func createFileWithCA(ctx context.Context, c client.Client, clusterNamespace string,
-	requestedChart *configv1beta1.HelmChart) (string, error) {
+func createFileWithCA(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	clusterNamespace string, requestedChart *configv1beta1.HelmChart) (string, error) {
 
 	if requestedChart.RegistryCredentialsConfig == nil {
 		return "", nil
@@ -2379,8 +2756,14 @@ func createFileWithCA(ctx context.Context, c client.Client, clusterNamespace str
 	namespace := libsveltostemplate.GetReferenceResourceNamespace(
 		clusterNamespace, requestedChart.RegistryCredentialsConfig.CASecretRef.Namespace)
 
+	err := validateAdminCanGetReference(ctx, c, clusterSummary, string(libsveltosv1beta1.SecretReferencedResourceKind),
+		namespace, requestedChart.RegistryCredentialsConfig.CASecretRef.Name)
+	if err != nil {
+		return "", err
+	}
+
 	secret := &corev1.Secret{}
-	err := c.Get(ctx,
+	err = c.Get(ctx,
 		types.NamespacedName{
 			Namespace: namespace,
 			Name:      requestedChart.RegistryCredentialsConfig.CASecretRef.Name,
@@ -24,6 +24,8 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,6 +33,7 @@ import (
 	"github.com/gdexlab/go-render/render"
 	"helm.sh/helm/v3/pkg/release"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2/textlogger"
@@ -128,6 +131,142 @@ var _ = Describe("HandlersHelm", func() {
 		Expect(controllers.ShouldInstall(nil, requestChart)).To(BeFalse())
 	})
 
+	It("isReleasePending returns true only for releases in a pending state", func() {
+		Expect(controllers.IsReleasePending(nil)).To(BeFalse())
+		Expect(controllers.IsReleasePending(&controllers.ReleaseInfo{
+			Status: release.StatusDeployed.String()})).To(BeFalse())
+		Expect(controllers.IsReleasePending(&controllers.ReleaseInfo{
+			Status: release.StatusPendingInstall.String()})).To(BeTrue())
+		Expect(controllers.IsReleasePending(&controllers.ReleaseInfo{
+			Status: release.StatusPendingUpgrade.String()})).To(BeTrue())
+	})
+
+	It("isPendingReleaseStale returns true only once release has been pending longer than timeout", func() {
+		now := time.Now()
+		requestChart := &configv1beta1.HelmChart{}
+		currentRelease := &controllers.ReleaseInfo{
+			Status:  release.StatusPendingUpgrade.String(),
+			Updated: metav1.Time{Time: now.Add(-time.Minute)},
+		}
+
+		// Might be in progress in another controller instance
+		Expect(controllers.IsPendingReleaseStale(currentRelease, requestChart, now)).To(BeFalse())
+		Expect(controllers.IsPendingReleaseStale(currentRelease, requestChart, now.Add(time.Hour))).To(BeTrue())
+
+		// Helm operation timeout is taken into account
+		requestChart.Options = &configv1beta1.HelmOptions{Timeout: &metav1.Duration{Duration: time.Hour}}
+		Expect(controllers.IsPendingReleaseStale(currentRelease, requestChart, now.Add(time.Hour))).To(BeFalse())
+
+		Expect(controllers.IsPendingReleaseStale(&controllers.ReleaseInfo{}, requestChart, now)).To(BeTrue())
+	})
+
+	It("trackHelmOperation returns a context not canceled with the parent one", func() {
+		releaseNamespace := randomString()
+		releaseName := randomString()
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: randomString(),
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+			},
+		}
+		otherClusterSummary := clusterSummary.DeepCopy()
+		otherClusterSummary.Spec.ClusterName = randomString()
+
+		parentCtx, cancel := context.WithCancel(context.TODO())
+		opCtx, done, err := controllers.TrackHelmOperation(parentCtx, clusterSummary, releaseNamespace, releaseName,
+			"install")
+		Expect(err).To(BeNil())
+		Expect(controllers.IsHelmOperationInProgress(clusterSummary, releaseNamespace, releaseName)).To(BeTrue())
+
+		// Same release in another cluster is a different release
+		Expect(controllers.IsHelmOperationInProgress(otherClusterSummary, releaseNamespace, releaseName)).To(BeFalse())
+		_, otherDone, err := controllers.TrackHelmOperation(context.TODO(), otherClusterSummary, releaseNamespace,
+			releaseName, "upgrade")
+		Expect(err).To(BeNil())
+		otherDone()
+		Expect(controllers.IsHelmOperationInProgress(clusterSummary, releaseNamespace, releaseName)).To(BeTrue())
+
+		cancel()
+		Expect(opCtx.Err()).To(BeNil())
+
+		done()
+		Expect(controllers.IsHelmOperationInProgress(clusterSummary, releaseNamespace, releaseName)).To(BeFalse())
+		Expect(opCtx.Err()).ToNot(BeNil())
+	})
+
+	It("remote operations aborted on shutdown are checkpointed and read by next controller instance", func() {
+		releaseNamespace := randomString()
+		releaseName := randomString()
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: randomString(),
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+			},
+		}
+
+		helmCtx, helmDone, err := controllers.TrackHelmOperation(context.TODO(), clusterSummary, releaseNamespace,
+			releaseName, "install")
+		Expect(err).To(BeNil())
+		resourcesCtx, resourcesDone, err := controllers.TrackFeatureOperation(context.TODO(), clusterSummary,
+			configv1beta1.FeatureResources, "apply")
+		Expect(err).To(BeNil())
+
+		// Operations return once aborted
+		go func() {
+			<-helmCtx.Done()
+			helmDone()
+		}()
+		go func() {
+			<-resourcesCtx.Done()
+			resourcesDone()
+		}()
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		defer controllers.ResetRemoteOperationsDrain()
+		checkpoints := controllers.DrainRemoteOperations(time.Millisecond, logger)
+		Expect(checkpoints).To(HaveLen(2))
+
+		// No operation is started while draining
+		_, _, err = controllers.TrackFeatureOperation(context.TODO(), clusterSummary,
+			configv1beta1.FeatureKustomize, "apply")
+		Expect(err).ToNot(BeNil())
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		configMapName := controllers.GetOperationCheckpointsName("")
+		Expect(controllers.StoreOperationCheckpoints(context.TODO(), c, configMapName, checkpoints)).To(Succeed())
+
+		Expect(controllers.IsReleaseInterrupted(clusterSummary, releaseNamespace, releaseName)).To(BeFalse())
+		Expect(controllers.LoadOperationCheckpoints(context.TODO(), c, c, configMapName, logger)).To(Succeed())
+		Expect(controllers.IsReleaseInterrupted(clusterSummary, releaseNamespace, releaseName)).To(BeTrue())
+
+		// Checkpoints are read only once
+		configMap := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "projectsveltos", Name: configMapName},
+			configMap)).To(Succeed())
+		Expect(configMap.Data).To(BeEmpty())
+
+		Expect(controllers.TakeInterruptedFeature(clusterSummary, configv1beta1.FeatureResources)).To(BeTrue())
+		Expect(controllers.TakeInterruptedFeature(clusterSummary, configv1beta1.FeatureResources)).To(BeFalse())
+		Expect(controllers.TakeInterruptedFeature(clusterSummary, configv1beta1.FeatureHelm)).To(BeTrue())
+		Expect(controllers.TakeInterruptedFeature(clusterSummary, configv1beta1.FeatureKustomize)).To(BeFalse())
+
+		// Once an operation on the release completes, release is not pending because of the aborted one
+		controllers.ResetRemoteOperationsDrain()
+		_, done, err := controllers.TrackHelmOperation(context.TODO(), clusterSummary, releaseNamespace,
+			releaseName, "uninstall")
+		Expect(err).To(BeNil())
+		done()
+		Expect(controllers.IsReleaseInterrupted(clusterSummary, releaseNamespace, releaseName)).To(BeFalse())
+	})
+
 	It("shouldUninstall returns false when there is no current release installed", func() {
 		requestChart := &configv1beta1.HelmChart{
 			ChartVersion:    "v2.5.3",
@@ -565,6 +704,44 @@ var _ = Describe("HandlersHelm", func() {
 		}
 		Expect(found).To(BeTrue())
 	})
+
+	It("createReleaseNamespaces creates namespaces of helm releases to install", func() {
+		createNamespace := true
+		doNotCreateNamespace := false
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterProfileSpec: configv1beta1.Spec{
+					TargetNamespaceMetadata: &configv1beta1.NamespaceMetadata{
+						Labels: map[string]string{"pod-security.kubernetes.io/enforce": "baseline"},
+					},
+					HelmCharts: []configv1beta1.HelmChart{
+						{ReleaseNamespace: randomString(), ReleaseName: randomString()},
+						{ReleaseNamespace: randomString(), ReleaseName: randomString(), CreateNamespace: &createNamespace,
+							Options: &configv1beta1.HelmOptions{InstallOptions: configv1beta1.HelmInstallOptions{CreateNamespace: false}}},
+						{ReleaseNamespace: randomString(), ReleaseName: randomString(), CreateNamespace: &doNotCreateNamespace},
+						{ReleaseNamespace: randomString(), ReleaseName: randomString(),
+							HelmChartAction: configv1beta1.HelmChartActionUninstall},
+					},
+				},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		Expect(controllers.CreateReleaseNamespaces(context.TODO(), c, clusterSummary)).To(Succeed())
+
+		for i, created := range []bool{true, true, false, false} {
+			ns := &corev1.Namespace{}
+			err := c.Get(context.TODO(),
+				types.NamespacedName{Name: clusterSummary.Spec.ClusterProfileSpec.HelmCharts[i].ReleaseNamespace}, ns)
+			if !created {
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				continue
+			}
+			Expect(err).To(BeNil())
+			Expect(ns.Labels).To(HaveKeyWithValue("pod-security.kubernetes.io/enforce", "baseline"))
+		}
+	})
 })
 
 var _ = Describe("Hash methods", func() {
@@ -844,7 +1021,7 @@ var _ = Describe("Hash methods", func() {
 		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
 
 		credentialsPath, caPath, err := controllers.GetCredentialsAndCAFiles(context.TODO(), c,
-			randomString(), &requestedChart)
+			&configv1beta1.ClusterSummary{}, randomString(), &requestedChart)
 		Expect(err).To(BeNil())
 		Expect(credentialsPath).ToNot(BeEmpty())
 		verifyFileContent(credentialsPath, credentialsBytes)
@@ -853,6 +1030,69 @@ var _ = Describe("Hash methods", func() {
 		verifyFileContent(caPath, caByte)
 		Expect(os.Remove(caPath)).To(Succeed())
 	})
+
+	It("mergeHelmValues merges nested values with last one winning", func() {
+		dst := map[string]interface{}{
+			"replicas": 1,
+			"image": map[string]interface{}{
+				"repository": "nginx",
+				"tag":        "1.25",
+			},
+			"ports": []interface{}{80},
+		}
+		src := map[string]interface{}{
+			"image": map[string]interface{}{
+				"tag": "1.27",
+			},
+			"ports": []interface{}{8080},
+		}
+
+		merged := controllers.MergeHelmValues(dst, src)
+		Expect(merged).To(Equal(map[string]interface{}{
+			"replicas": 1,
+			"image": map[string]interface{}{
+				"repository": "nginx",
+				"tag":        "1.27",
+			},
+			"ports": []interface{}{8080},
+		}))
+
+		Expect(controllers.MergeHelmValues(nil, src)).To(Equal(src))
+	})
+
+	It("getTextDiff returns only added and removed lines", func() {
+		from := "replicas: 1\nimage: nginx:1.25\nport: 80\n"
+		to := "replicas: 3\nimage: nginx:1.25\nport: 80\nhost: example.com\n"
+
+		Expect(controllers.GetTextDiff(from, from)).To(BeEmpty())
+		Expect(controllers.GetTextDiff(from, to)).To(Equal(
+			"+replicas: 3\n-replicas: 1\n+host: example.com"))
+		Expect(controllers.GetTextDiff("", "a: b\n")).To(Equal("+a: b"))
+	})
+
+	It("getTextDiff handles large manifests", func() {
+		const numLines = 20000
+		from := make([]string, numLines)
+		to := make([]string, numLines)
+		other := make([]string, numLines)
+		for i := 0; i < numLines; i++ {
+			from[i] = fmt.Sprintf("line-%d", i)
+			to[i] = from[i]
+			other[i] = fmt.Sprintf("other-%d", i)
+		}
+		to[100] = "changed"
+		to[numLines/2] = "changed"
+
+		// Few differences: minimal diff is computed
+		Expect(controllers.GetTextDiff(strings.Join(from, "\n"), strings.Join(to, "\n"))).To(Equal(
+			fmt.Sprintf("+changed\n-line-100\n+changed\n-line-%d", numLines/2)))
+
+		// Everything changed: all lines are reported as replaced and diff is truncated
+		diff := strings.Split(controllers.GetTextDiff(strings.Join(from, "\n"), strings.Join(other, "\n")), "\n")
+		Expect(diff).To(HaveLen(501))
+		Expect(diff[0]).To(Equal("+other-0"))
+		Expect(diff[500]).To(Equal(fmt.Sprintf("... (%d more lines)", 2*numLines-500)))
+	})
 })
 
 func verifyFileContent(filePath string, data []byte) {
@@ -49,7 +49,6 @@ import (
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	"github.com/projectsveltos/addon-controller/pkg/scope"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
-	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	"github.com/projectsveltos/libsveltos/lib/deployer"
 	"github.com/projectsveltos/libsveltos/lib/funcmap"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
@@ -98,6 +97,9 @@ func deployKustomizeRefs(ctx context.Context, c client.Client,
 		return err
 	}
 
+	// When planning, handler runs in DryRun mode whatever the ClusterSummary SyncMode
+	applyPlanOption(clusterSummary, o)
+
 	remoteRestConfig, logger, err := getRestConfig(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return err
@@ -111,6 +113,18 @@ func deployKustomizeRefs(ctx context.Context, c client.Client,
 		return err
 	}
 
+	err = runPreDeploymentJobs(ctx, c, remoteClient, clusterSummary, configv1beta1.FeatureKustomize, logger)
+	if err != nil {
+		return err
+	}
+
+	// From now on resources are applied to the managed cluster. Let those be applied on shutdown.
+	ctx, done, err := trackFeatureOperation(ctx, clusterSummary, configv1beta1.FeatureKustomize, "apply")
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	localResourceReports, remoteResourceReports, deployError := deployEachKustomizeRefs(ctx, c, remoteRestConfig,
 		clusterSummary, logger)
 
@@ -151,6 +165,11 @@ func deployKustomizeRefs(ctx context.Context, c client.Client,
 	if err != nil {
 		return err
 	}
+	clusterSummary, err = updateResourceInventory(ctx, clusterSummary, configv1beta1.FeatureKustomize,
+		localResourceReports, remoteResourceReports, logger)
+	if err != nil {
+		return err
+	}
 	remoteResourceReports = append(remoteResourceReports, undeployed...)
 
 	err = handleWatchers(ctx, clusterSummary, localResourceReports, featureHandler)
@@ -163,13 +182,16 @@ func deployKustomizeRefs(ctx context.Context, c client.Client,
 		return err
 	}
 
+	publishChangeSummary(ctx, c, remoteClient, clusterSummary, configv1beta1.FeatureKustomize,
+		getResourceChanges(remoteResourceReports), logger)
+
 	err = handleKustomizeResourceSummaryDeployment(ctx, clusterSummary, clusterNamespace, clusterName,
 		clusterType, remoteDeployed, logger)
 	if err != nil {
 		return err
 	}
 
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return &configv1beta1.DryRunReconciliationError{}
 	}
 
@@ -177,7 +199,12 @@ func deployKustomizeRefs(ctx context.Context, c client.Client,
 		return deployError
 	}
 
-	return validateHealthPolicies(ctx, remoteRestConfig, clusterSummary, configv1beta1.FeatureKustomize, logger)
+	err = runPostDeploymentJobs(ctx, c, remoteClient, clusterSummary, configv1beta1.FeatureKustomize, logger)
+	if err != nil {
+		return err
+	}
+
+	return validateHealthPoliciesOnDeploy(ctx, remoteRestConfig, clusterSummary, configv1beta1.FeatureKustomize, logger)
 }
 
 func cleanStaleKustomizeResources(ctx context.Context, remoteRestConfig *rest.Config, remoteClient client.Client,
@@ -230,14 +257,12 @@ func undeployKustomizeRefs(ctx context.Context, c client.Client,
 		return err
 	}
 
-	remoteRestConfig, err := clusterproxy.GetKubernetesRestConfig(ctx, c, clusterNamespace, clusterName,
-		adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	remoteRestConfig, err := getClusterSummaryRestConfig(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return err
 	}
 
-	remoteClient, err := clusterproxy.GetKubernetesClient(ctx, c, clusterNamespace, clusterName,
-		adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	remoteClient, err := getClusterSummaryClient(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return err
 	}
@@ -272,7 +297,7 @@ func undeployKustomizeRefs(ctx context.Context, c client.Client,
 		return err
 	}
 
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return &configv1beta1.DryRunReconciliationError{}
 	}
 
@@ -286,7 +311,7 @@ func undeployKustomizeRefs(ctx context.Context, c client.Client,
 func kustomizationHash(ctx context.Context, c client.Client, clusterSummaryScope *scope.ClusterSummaryScope,
 	logger logr.Logger) ([]byte, error) {
 
-	clusterProfileSpecHash, err := getClusterProfileSpecHash(ctx, clusterSummaryScope.ClusterSummary)
+	clusterProfileSpecHash, err := getClusterProfileSpecHash(ctx, c, clusterSummaryScope.ClusterSummary)
 	if err != nil {
 		return nil, err
 	}
@@ -325,6 +350,12 @@ func kustomizationHash(ctx context.Context, c client.Client, clusterSummaryScope
 		}
 	}
 
+	deploymentJobsHash, err := getDeploymentJobsHash(ctx, c, clusterSummary, configv1beta1.FeatureKustomize, logger)
+	if err != nil {
+		return nil, err
+	}
+	config += deploymentJobsHash
+
 	h.Write([]byte(config))
 	return h.Sum(nil), nil
 }
@@ -482,7 +513,10 @@ func deployKustomizeRef(ctx context.Context, c client.Client, remoteRestConfig *
 	logger.V(logs.LogDebug).Info(fmt.Sprintf("using path %s", instantiatedPath))
 
 	// check build path exists
-	dirPath := filepath.Join(tmpDir, instantiatedPath)
+	dirPath, err := getSourceArtifactPath(tmpDir, instantiatedPath)
+	if err != nil {
+		return nil, nil, err
+	}
 	_, err = os.Stat(dirPath)
 	if err != nil {
 		err = fmt.Errorf("kustomization path not found: %w", err)
@@ -519,6 +553,13 @@ func prepareFileSystem(ctx context.Context, c client.Client,
 		return "", err
 	}
 
+	// Tenant admins can only reference resources they are allowed to read
+	err = validateAdminCanGetReference(ctx, c, clusterSummary, kustomizationRef.Kind, namespace, name)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(err.Error())
+		return "", err
+	}
+
 	source, err := getSource(ctx, c, namespace, name, kustomizationRef.Kind)
 	if err != nil {
 		return "", err
@@ -545,11 +586,22 @@ func prepareFileSystemWithConfigMap(ctx context.Context, c client.Client,
 		return "", err
 	}
 
+	// Tenant admins can only reference resources they are allowed to read
+	err = validateAdminCanGetReference(ctx, c, clusterSummary, kustomizationRef.Kind, namespace, name)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(err.Error())
+		return "", err
+	}
+
 	configMap, err := getConfigMap(ctx, c, types.NamespacedName{Namespace: namespace, Name: name})
 	if err != nil {
 		return "", err
 	}
 
+	if err := validateReferenceOptIn(configMap, kustomizationRef.Kind); err != nil {
+		return "", err
+	}
+
 	return prepareFileSystemWithData(configMap.BinaryData, kustomizationRef, logger)
 }
 
@@ -566,11 +618,22 @@ func prepareFileSystemWithSecret(ctx context.Context, c client.Client,
 		return "", err
 	}
 
+	// Tenant admins can only reference resources they are allowed to read
+	err = validateAdminCanGetReference(ctx, c, clusterSummary, kustomizationRef.Kind, namespace, name)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(err.Error())
+		return "", err
+	}
+
 	secret, err := getSecret(ctx, c, types.NamespacedName{Namespace: namespace, Name: name})
 	if err != nil {
 		return "", err
 	}
 
+	if err := validateReferenceOptIn(secret, kustomizationRef.Kind); err != nil {
+		return "", err
+	}
+
 	return prepareFileSystemWithData(secret.Data, kustomizationRef, logger)
 }
 
@@ -596,12 +659,14 @@ func prepareFileSystemWithData(binaryData map[string][]byte,
 	err = os.WriteFile(filePath, binaryTarGz, permission0600)
 	if err != nil {
 		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to write file %s: %v", filePath, err))
+		os.RemoveAll(tmpDir)
 		return "", err
 	}
 
 	err = extractTarGz(filePath, tmpDir)
 	if err != nil {
 		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to extract tar.gz: %v", err))
+		os.RemoveAll(tmpDir)
 		return "", err
 	}
 
@@ -708,7 +773,7 @@ func deployKustomizeResources(ctx context.Context, c client.Client, remoteRestCo
 		Name:      kustomizationRef.Name,
 	}
 	localReports, err = deployUnstructured(ctx, true, localConfig, c, objectsToDeployLocally,
-		ref, configv1beta1.FeatureKustomize, clusterSummary, mgmtResources, []string{}, logger)
+		ref, configv1beta1.FeatureKustomize, clusterSummary, mgmtResources, []string{}, configv1beta1.ConflictPolicyAdopt, logger)
 	if err != nil {
 		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to deploy to management cluster %v", err))
 		return localReports, nil, err
@@ -720,7 +785,7 @@ func deployKustomizeResources(ctx context.Context, c client.Client, remoteRestCo
 	}
 
 	remoteReports, err = deployUnstructured(ctx, false, remoteRestConfig, remoteClient, objectsToDeployRemotely,
-		ref, configv1beta1.FeatureKustomize, clusterSummary, mgmtResources, []string{}, logger)
+		ref, configv1beta1.FeatureKustomize, clusterSummary, mgmtResources, []string{}, configv1beta1.ConflictPolicyAdopt, logger)
 	if err != nil {
 		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to deploy to remote cluster %v", err))
 		return localReports, remoteReports, err
@@ -738,9 +803,8 @@ func cleanKustomizeResources(ctx context.Context, isMgmtCluster bool, destRestCo
 		key := getPolicyInfo(&resourceReports[i].Resource)
 		currentPolicies[key] = resourceReports[i].Resource
 	}
-	undeployed, err := undeployStaleResources(ctx, isMgmtCluster, destRestConfig, destClient,
-		configv1beta1.FeatureKustomize, clusterSummary,
-		getDeployedGroupVersionKinds(clusterSummary, configv1beta1.FeatureKustomize), currentPolicies, logger)
+	undeployed, err := pruneStaleResources(ctx, isMgmtCluster, destRestConfig, destClient,
+		configv1beta1.FeatureKustomize, clusterSummary, currentPolicies, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -756,7 +820,8 @@ func handleDriftDetectionManagerDeploymentForKustomize(ctx context.Context, clus
 	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeContinuousWithDriftDetection {
 		// Deploy drift detection manager first. Have manager up by the time resourcesummary is created
 		err := deployDriftDetectionManagerInCluster(ctx, getManagementClusterClient(), clusterNamespace,
-			clusterName, clusterSummary.Name, clusterType, startInMgmtCluster, logger)
+			clusterName, clusterSummary.Name, clusterType, startInMgmtCluster,
+			getDriftDetectionResources(clusterSummary, startInMgmtCluster), logger)
 		if err != nil {
 			return err
 		}
@@ -811,7 +876,8 @@ func deployResourceSummaryWithKustomizeResources(ctx context.Context, c client.C
 	}
 
 	return deployResourceSummaryInCluster(ctx, c, clusterNamespace, clusterName, clusterSummary.Name,
-		clusterType, nil, resources, nil, clusterSummary.Spec.ClusterProfileSpec.DriftExclusions, logger)
+		clusterType, nil, resources, nil, clusterSummary.Spec.ClusterProfileSpec.DriftExclusions,
+		clusterSummary.Spec.ClusterProfileSpec.DriftExcludedKinds, logger)
 }
 
 // deployEachKustomizeRefs walks KustomizationRefs and deploys resources
@@ -29,13 +29,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	"github.com/projectsveltos/addon-controller/pkg/scope"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
-	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	"github.com/projectsveltos/libsveltos/lib/deployer"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 	libsveltostemplate "github.com/projectsveltos/libsveltos/lib/template"
@@ -54,6 +52,9 @@ func deployResources(ctx context.Context, c client.Client,
 		return err
 	}
 
+	// When planning, handler runs in DryRun mode whatever the ClusterSummary SyncMode
+	applyPlanOption(clusterSummary, o)
+
 	remoteRestConfig, logger, err := getRestConfig(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return err
@@ -65,6 +66,24 @@ func deployResources(ctx context.Context, c client.Client,
 		return err
 	}
 
+	err = runPreDeploymentJobs(ctx, c, remoteClient, clusterSummary, configv1beta1.FeatureResources, logger)
+	if err != nil {
+		return err
+	}
+
+	// Get SourceRef revision before deploying, so reported revision is never more recent than the deployed one
+	sourceRevision, err := getSourceRevision(ctx, c, clusterSummary, logger)
+	if err != nil {
+		return err
+	}
+
+	// From now on resources are applied to the managed cluster. Let those be applied on shutdown.
+	ctx, done, err := trackFeatureOperation(ctx, clusterSummary, configv1beta1.FeatureResources, "apply")
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	localResourceReports, remoteResourceReports, deployError := deployPolicyRefs(ctx, c, remoteRestConfig,
 		clusterSummary, featureHandler, logger)
 
@@ -105,6 +124,11 @@ func deployResources(ctx context.Context, c client.Client,
 	if err != nil {
 		return err
 	}
+	clusterSummary, err = updateResourceInventory(ctx, clusterSummary, configv1beta1.FeatureResources,
+		localResourceReports, remoteResourceReports, logger)
+	if err != nil {
+		return err
+	}
 	remoteResourceReports = append(remoteResourceReports, undeployed...)
 
 	err = handleWatchers(ctx, clusterSummary, localResourceReports, featureHandler)
@@ -117,13 +141,16 @@ func deployResources(ctx context.Context, c client.Client,
 		return err
 	}
 
+	publishChangeSummary(ctx, c, remoteClient, clusterSummary, featureHandler.id,
+		getResourceChanges(remoteResourceReports), logger)
+
 	err = handleResourceSummaryDeployment(ctx, clusterSummary, clusterNamespace, clusterName,
 		clusterType, remoteDeployed, logger)
 	if err != nil {
 		return err
 	}
 
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return &configv1beta1.DryRunReconciliationError{}
 	}
 
@@ -131,7 +158,17 @@ func deployResources(ctx context.Context, c client.Client,
 		return deployError
 	}
 
-	return validateHealthPolicies(ctx, remoteRestConfig, clusterSummary, configv1beta1.FeatureResources, logger)
+	err = updateSourceRevision(ctx, c, clusterSummary, profileOwnerRef, sourceRevision, logger)
+	if err != nil {
+		return err
+	}
+
+	err = runPostDeploymentJobs(ctx, c, remoteClient, clusterSummary, configv1beta1.FeatureResources, logger)
+	if err != nil {
+		return err
+	}
+
+	return validateHealthPoliciesOnDeploy(ctx, remoteRestConfig, clusterSummary, configv1beta1.FeatureResources, logger)
 }
 
 func cleanStaleResources(ctx context.Context, remoteRestConfig *rest.Config, remoteClient client.Client,
@@ -164,7 +201,8 @@ func handleDriftDetectionManagerDeployment(ctx context.Context, clusterSummary *
 	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeContinuousWithDriftDetection {
 		// Deploy drift detection manager first. Have manager up by the time resourcesummary is created
 		err := deployDriftDetectionManagerInCluster(ctx, getManagementClusterClient(), clusterNamespace,
-			clusterName, clusterSummary.Name, clusterType, startInMgmtCluster, logger)
+			clusterName, clusterSummary.Name, clusterType, startInMgmtCluster,
+			getDriftDetectionResources(clusterSummary, startInMgmtCluster), logger)
 		if err != nil {
 			return err
 		}
@@ -249,9 +287,8 @@ func cleanPolicyRefResources(ctx context.Context, isMgmtCluster bool, destRestCo
 		currentPolicies[key] = resourceReports[i].Resource
 	}
 
-	undeployed, err := undeployStaleResources(ctx, isMgmtCluster, destRestConfig, destClient, configv1beta1.FeatureResources,
-		clusterSummary, getDeployedGroupVersionKinds(clusterSummary, configv1beta1.FeatureResources),
-		currentPolicies, logger)
+	undeployed, err := pruneStaleResources(ctx, isMgmtCluster, destRestConfig, destClient, configv1beta1.FeatureResources,
+		clusterSummary, currentPolicies, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -278,14 +315,12 @@ func undeployResources(ctx context.Context, c client.Client,
 
 	logger.V(logs.LogDebug).Info("undeployResources")
 
-	remoteClient, err := clusterproxy.GetKubernetesClient(ctx, c, clusterNamespace, clusterName,
-		adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	remoteClient, err := getClusterSummaryClient(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return err
 	}
 
-	remoteRestConfig, err := clusterproxy.GetKubernetesRestConfig(ctx, c, clusterNamespace, clusterName,
-		adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	remoteRestConfig, err := getClusterSummaryRestConfig(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return err
 	}
@@ -330,7 +365,7 @@ func undeployResources(ctx context.Context, c client.Client,
 		return err
 	}
 
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return &configv1beta1.DryRunReconciliationError{}
 	}
 
@@ -344,7 +379,7 @@ func undeployResources(ctx context.Context, c client.Client,
 func resourcesHash(ctx context.Context, c client.Client, clusterSummaryScope *scope.ClusterSummaryScope,
 	logger logr.Logger) ([]byte, error) {
 
-	clusterProfileSpecHash, err := getClusterProfileSpecHash(ctx, clusterSummaryScope.ClusterSummary)
+	clusterProfileSpecHash, err := getClusterProfileSpecHash(ctx, c, clusterSummaryScope.ClusterSummary)
 	if err != nil {
 		return nil, err
 	}
@@ -354,8 +389,13 @@ func resourcesHash(ctx context.Context, c client.Client, clusterSummaryScope *sc
 	config += string(clusterProfileSpecHash)
 
 	clusterSummary := clusterSummaryScope.ClusterSummary
-	for i := range clusterSummary.Spec.ClusterProfileSpec.PolicyRefs {
-		reference := &clusterSummary.Spec.ClusterProfileSpec.PolicyRefs[i]
+	refs := getResourceRefs(clusterSummary)
+
+	// Instantiate all names first, so referenced ConfigMaps/Secrets can be fetched concurrently
+	keys := make([]types.NamespacedName, len(refs))
+	var contentRefs []referencedResource
+	for i := range refs {
+		reference := &refs[i]
 		namespace := libsveltostemplate.GetReferenceResourceNamespace(
 			clusterSummaryScope.Namespace(), reference.Namespace)
 
@@ -367,29 +407,41 @@ func resourcesHash(ctx context.Context, c client.Client, clusterSummaryScope *sc
 			return nil, err
 		}
 
-		if reference.Kind == string(libsveltosv1beta1.ConfigMapReferencedResourceKind) {
-			configmap := &corev1.ConfigMap{}
-			err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, configmap)
-			if err == nil {
-				config += getConfigMapHash(configmap)
-			}
-		} else if reference.Kind == string(libsveltosv1beta1.SecretReferencedResourceKind) {
-			secret := &corev1.Secret{}
-			err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret)
+		keys[i] = types.NamespacedName{Namespace: namespace, Name: name}
+		if reference.Kind == string(libsveltosv1beta1.ConfigMapReferencedResourceKind) ||
+			reference.Kind == string(libsveltosv1beta1.SecretReferencedResourceKind) {
+
+			contentRefs = append(contentRefs, referencedResource{kind: reference.Kind, key: keys[i]})
+		}
+	}
+	contentHashes := getReferencedResourcesHashes(ctx, c, contentRefs, policyRefContentHasher)
+
+	for i := range refs {
+		reference := &refs[i]
+		namespace := keys[i].Namespace
+		name := keys[i].Name
+
+		var err error
+		if reference.Kind == string(libsveltosv1beta1.ConfigMapReferencedResourceKind) ||
+			reference.Kind == string(libsveltosv1beta1.SecretReferencedResourceKind) {
+
+			// contentHashes follows refs order
+			err = contentHashes[0].err
 			if err == nil {
-				config += getSecretHash(secret)
+				config += contentHashes[0].hash
 			}
+			contentHashes = contentHashes[1:]
 		} else {
 			var source client.Object
 			source, err = getSource(ctx, c, namespace, name, reference.Kind)
-			if err == nil && source == nil {
+			if err == nil && source != nil {
 				s := source.(sourcev1.Source)
 				if s.GetArtifact() != nil {
 					config += s.GetArtifact().Revision
 				}
-			}
-			if source.GetAnnotations() != nil {
-				config += getDataSectionHash(source.GetAnnotations())
+				if source.GetAnnotations() != nil {
+					config += getDataSectionHash(source.GetAnnotations())
+				}
 			}
 		}
 		if err != nil {
@@ -411,12 +463,26 @@ func resourcesHash(ctx context.Context, c client.Client, clusterSummaryScope *sc
 		}
 	}
 
+	deploymentJobsHash, err := getDeploymentJobsHash(ctx, c, clusterSummary, configv1beta1.FeatureResources, logger)
+	if err != nil {
+		return nil, err
+	}
+	config += deploymentJobsHash
+
 	h.Write([]byte(config))
 	return h.Sum(nil), nil
 }
 
+// getResourceRefs returns PolicyRefs along with the PolicyRef corresponding to SourceRef, if set
 func getResourceRefs(clusterSummary *configv1beta1.ClusterSummary) []configv1beta1.PolicyRef {
-	return clusterSummary.Spec.ClusterProfileSpec.PolicyRefs
+	sourceRef := getSourceRefPolicyRef(clusterSummary)
+	if sourceRef == nil {
+		return clusterSummary.Spec.ClusterProfileSpec.PolicyRefs
+	}
+
+	refs := make([]configv1beta1.PolicyRef, 0, len(clusterSummary.Spec.ClusterProfileSpec.PolicyRefs)+1)
+	refs = append(refs, clusterSummary.Spec.ClusterProfileSpec.PolicyRefs...)
+	return append(refs, *sourceRef)
 }
 
 // updateClusterReportWithResourceReports updates ClusterReport Status with ResourceReports.
@@ -426,35 +492,17 @@ func updateClusterReportWithResourceReports(ctx context.Context, c client.Client
 	featureID configv1beta1.FeatureID) error {
 
 	// This is no-op unless in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode != configv1beta1.SyncModeDryRun {
+	if !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
-	profileOwnerRef, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
-	if err != nil {
-		return err
-	}
-
-	clusterReportName := getClusterReportName(profileOwnerRef.Kind, profileOwnerRef.Name,
-		clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType)
-
-	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		clusterReport := &configv1beta1.ClusterReport{}
-		err = c.Get(ctx,
-			types.NamespacedName{Namespace: clusterSummary.Spec.ClusterNamespace, Name: clusterReportName}, clusterReport)
-		if err != nil {
-			return err
-		}
-
+	return updateClusterReportSection(ctx, c, clusterSummary, func(status *configv1beta1.ClusterReportStatus) {
 		if featureID == configv1beta1.FeatureResources {
-			clusterReport.Status.ResourceReports = resourceReports
+			status.ResourceReports = resourceReports
 		} else if featureID == configv1beta1.FeatureKustomize {
-			clusterReport.Status.KustomizeResourceReports = resourceReports
+			status.KustomizeResourceReports = resourceReports
 		}
-
-		return c.Status().Update(ctx, clusterReport)
 	})
-	return err
 }
 
 func deployResourceSummary(ctx context.Context, c client.Client,
@@ -476,7 +524,8 @@ func deployResourceSummary(ctx context.Context, c client.Client,
 	}
 
 	return deployResourceSummaryInCluster(ctx, c, clusterNamespace, clusterName, clusterSummary.Name,
-		clusterType, resources, nil, nil, clusterSummary.Spec.ClusterProfileSpec.DriftExclusions, logger)
+		clusterType, resources, nil, nil, clusterSummary.Spec.ClusterProfileSpec.DriftExclusions,
+		clusterSummary.Spec.ClusterProfileSpec.DriftExcludedKinds, logger)
 }
 
 // deployPolicyRefs deploys in a managed Cluster the policies contained in the Data section of each
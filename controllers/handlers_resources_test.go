@@ -25,6 +25,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	"github.com/gdexlab/go-render/render"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -304,6 +305,77 @@ var _ = Describe("HandlersResource", func() {
 		Expect(clusterSummary.Status.DeployedGVKs[0].DeployedGroupVersionKind).To(ContainElement(
 			fmt.Sprintf("%s.%s.%s", remoteReports[0].Resource.Kind, remoteReports[0].Resource.Version, remoteReports[0].Resource.Group)))
 	})
+
+	It("updateResourceInventory records deployed resources per feature", func() {
+		Expect(waitForObject(context.TODO(), testEnv.Client, clusterProfile)).To(Succeed())
+
+		remoteReports := []configv1beta1.ResourceReport{
+			{
+				Resource: configv1beta1.Resource{
+					Name: randomString(), Namespace: randomString(), Version: "v1", Kind: "ConfigMap",
+				},
+				Action: string(configv1beta1.CreateResourceAction),
+			},
+			{
+				Resource: configv1beta1.Resource{
+					Name: randomString(), Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole",
+				},
+				Action: string(configv1beta1.NoResourceAction),
+			},
+		}
+		localReports := []configv1beta1.ResourceReport{
+			{
+				Resource: configv1beta1.Resource{
+					Name: randomString(), Namespace: randomString(), Group: "apps", Version: "v1", Kind: "Deployment",
+				},
+				Action: string(configv1beta1.UpdateResourceAction),
+			},
+		}
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		_, err := controllers.UpdateResourceInventory(context.TODO(), clusterSummary, configv1beta1.FeatureKustomize,
+			nil, remoteReports[:1], logger)
+		Expect(err).To(BeNil())
+
+		_, err = controllers.UpdateResourceInventory(context.TODO(), clusterSummary, configv1beta1.FeatureResources,
+			localReports, remoteReports, logger)
+		Expect(err).To(BeNil())
+
+		// wait for cache to sync
+		Eventually(func() bool {
+			err := testEnv.Get(context.TODO(),
+				types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name},
+				clusterSummary)
+			return err == nil && len(clusterSummary.Status.DeployedGVKs) == 2
+		}, timeout, pollingInterval).Should(BeTrue())
+
+		// Kustomize inventory is not affected by Resources inventory
+		Expect(controllers.GetResourceInventory(clusterSummary, configv1beta1.FeatureKustomize, false)).To(
+			ConsistOf(controllers.GetInventoryEntry(&remoteReports[0].Resource)))
+		Expect(controllers.GetResourceInventory(clusterSummary, configv1beta1.FeatureKustomize, true)).To(BeNil())
+
+		Expect(controllers.GetResourceInventory(clusterSummary, configv1beta1.FeatureResources, false)).To(
+			ConsistOf(controllers.GetInventoryEntry(&remoteReports[0].Resource),
+				controllers.GetInventoryEntry(&remoteReports[1].Resource)))
+		Expect(controllers.GetResourceInventory(clusterSummary, configv1beta1.FeatureResources, true)).To(
+			ConsistOf(controllers.GetInventoryEntry(&localReports[0].Resource)))
+		Expect(controllers.GetResourceInventory(clusterSummary, configv1beta1.FeatureHelm, false)).To(BeNil())
+	})
+
+	It("parseInventoryEntry parses entries created by getInventoryEntry", func() {
+		resources := []configv1beta1.Resource{
+			{Name: randomString(), Namespace: randomString(), Version: "v1", Kind: "ConfigMap"},
+			{Name: randomString(), Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+		}
+		for i := range resources {
+			resource, err := controllers.ParseInventoryEntry(controllers.GetInventoryEntry(&resources[i]))
+			Expect(err).To(BeNil())
+			Expect(*resource).To(Equal(resources[i]))
+		}
+
+		_, err := controllers.ParseInventoryEntry(randomString())
+		Expect(err).ToNot(BeNil())
+	})
 })
 
 var _ = Describe("Hash methods", func() {
@@ -404,4 +476,63 @@ var _ = Describe("Hash methods", func() {
 		Expect(err).To(BeNil())
 		Expect(reflect.DeepEqual(hash, expectHash)).To(BeTrue())
 	})
+
+	It("ResourcesHash changes when a referenced Flux Source artifact changes", func() {
+		gitRepository := &sourcev1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+		}
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: randomString(),
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: randomString(),
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+				ClusterProfileSpec: configv1beta1.Spec{
+					PolicyRefs: []configv1beta1.PolicyRef{
+						{
+							Namespace: gitRepository.Namespace, Name: gitRepository.Name,
+							Kind: sourcev1.GitRepositoryKind, Path: randomString(),
+						},
+					},
+				},
+			},
+		}
+
+		// Referenced GitRepository does not exist yet
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterSummary).Build()
+
+		clusterSummaryScope, err := scope.NewClusterSummaryScope(&scope.ClusterSummaryScopeParams{
+			Client:         c,
+			Logger:         textlogger.NewLogger(textlogger.NewConfig()),
+			ClusterSummary: clusterSummary,
+			ControllerName: "clustersummary",
+		})
+		Expect(err).To(BeNil())
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		missingHash, err := controllers.ResourcesHash(context.TODO(), c, clusterSummaryScope, logger)
+		Expect(err).To(BeNil())
+
+		gitRepository.Status.Artifact = &sourcev1.Artifact{Revision: randomString()}
+		Expect(c.Create(context.TODO(), gitRepository)).To(Succeed())
+
+		hash, err := controllers.ResourcesHash(context.TODO(), c, clusterSummaryScope, logger)
+		Expect(err).To(BeNil())
+		Expect(hash).ToNot(Equal(missingHash))
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: gitRepository.Namespace, Name: gitRepository.Name},
+			gitRepository)).To(Succeed())
+		gitRepository.Status.Artifact = &sourcev1.Artifact{Revision: randomString()}
+		Expect(c.Update(context.TODO(), gitRepository)).To(Succeed())
+
+		newHash, err := controllers.ResourcesHash(context.TODO(), c, clusterSummaryScope, logger)
+		Expect(err).To(BeNil())
+		Expect(newHash).ToNot(Equal(hash))
+	})
 })
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -42,17 +43,15 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/util/retry"
+	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
-	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	"github.com/projectsveltos/libsveltos/lib/deployer"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 	"github.com/projectsveltos/libsveltos/lib/patcher"
@@ -66,6 +65,17 @@ const (
 	clusterSummaryAnnotation = "projectsveltos.io/clustersummary"
 	subresourcesAnnotation   = "projectsveltos.io/subresources"
 	pathAnnotation           = "path"
+	conflictPolicyAnnotation = "projectsveltos.io/conflict-policy"
+)
+
+const (
+	// defaultFieldManager is the field manager used for resources not deployed on behalf
+	// of a specific ClusterProfile/Profile. Before field managers per profile were introduced,
+	// it was used for all resources.
+	defaultFieldManager = "application/apply-patch"
+
+	// profileFieldManagerPrefix is the prefix of the field manager of each ClusterProfile/Profile
+	profileFieldManagerPrefix = "sveltos-"
 )
 
 func getClusterSummaryAnnotationValue(clusterSummary *configv1beta1.ClusterSummary) string {
@@ -74,13 +84,14 @@ func getClusterSummaryAnnotationValue(clusterSummary *configv1beta1.ClusterSumma
 		clusterSummary.Spec.ClusterName)
 }
 
-// createNamespace creates a namespace if it does not exist already
+// createNamespace creates a namespace if it does not exist already. Labels and annotations
+// in Spec.TargetNamespaceMetadata are set on the created namespace.
 // No action in DryRun mode.
 func createNamespace(ctx context.Context, clusterClient client.Client,
 	clusterSummary *configv1beta1.ClusterSummary, namespaceName string) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
@@ -96,6 +107,10 @@ func createNamespace(ctx context.Context, clusterClient client.Client,
 					Name: namespaceName,
 				},
 			}
+			if metadata := clusterSummary.Spec.ClusterProfileSpec.TargetNamespaceMetadata; metadata != nil {
+				ns.Labels = metadata.Labels
+				ns.Annotations = metadata.Annotations
+			}
 			return clusterClient.Create(ctx, ns)
 		}
 		return err
@@ -127,7 +142,13 @@ func deployContentOfSecret(ctx context.Context, deployingToMgmtCluster bool, des
 
 	data := make(map[string]string)
 	for key, value := range secret.Data {
-		data[key] = string(value)
+		section, err := getSecretDataSection(secret, value)
+		if err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to parse Secret %s/%s key %s: %v",
+				secret.Namespace, secret.Name, key, err))
+			return nil, err
+		}
+		data[key] = section
 	}
 
 	return deployContent(ctx, deployingToMgmtCluster, destConfig, destClient, secret, data,
@@ -163,7 +184,10 @@ func deployContentOfSource(ctx context.Context, deployingToMgmtCluster bool, des
 	logger.V(logs.LogDebug).Info(fmt.Sprintf("using path %s", instantiatedPath))
 
 	// check build path exists
-	dirPath := filepath.Join(tmpDir, instantiatedPath)
+	dirPath, err := getSourceArtifactPath(tmpDir, instantiatedPath)
+	if err != nil {
+		return nil, err
+	}
 	_, err = os.Stat(dirPath)
 	if err != nil {
 		logger.Error(err, "source path not found")
@@ -181,6 +205,9 @@ func deployContentOfSource(ctx context.Context, deployingToMgmtCluster bool, des
 		clusterSummary, mgmtResources, logger)
 }
 
+// readFiles returns the content of all files in dir and its subdirectories, keyed by file name
+// (or by path relative to dir when more than one file has the same name).
+// Hidden files and directories (for instance .git or .sourceignore) are skipped.
 func readFiles(dir string) (map[string]string, error) {
 	files := make(map[string]string)
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -188,12 +215,26 @@ func readFiles(dir string) (map[string]string, error) {
 			return err
 		}
 
+		if path != dir && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		if !info.IsDir() {
 			content, err := os.ReadFile(path)
 			if err != nil {
 				return err
 			}
-			files[filepath.Base(path)] = string(content)
+			key := filepath.Base(path)
+			if _, ok := files[key]; ok {
+				key, err = filepath.Rel(dir, path)
+				if err != nil {
+					return err
+				}
+			}
+			files[key] = string(content)
 		}
 		return nil
 	})
@@ -217,14 +258,95 @@ func applySubresources(ctx context.Context, dr dynamic.ResourceInterface,
 	return err
 }
 
-// updateResource creates or updates a resource in a Cluster.
+// getFieldManager returns the server-side apply field manager used when deploying resources
+// on behalf of profile. Each ClusterProfile/Profile has its own field manager, so the API server
+// tracks which fields each one owns and detects conflicts with other controllers.
+// profile name is expected in the owner reference form (namespace/name for Profiles).
+func getFieldManager(profile client.Object) string {
+	fieldManager := fmt.Sprintf("%s%s-%s", profileFieldManagerPrefix,
+		strings.ToLower(profile.GetObjectKind().GroupVersionKind().Kind), profile.GetName())
+	if len(fieldManager) <= maxFieldManagerLength {
+		return fieldManager
+	}
+
+	// Field manager length is limited. Keep the name recognizable and unique.
+	h := sha256.Sum256([]byte(fieldManager))
+	suffix := fmt.Sprintf("-%x", h[:8])
+	return fieldManager[:maxFieldManagerLength-len(suffix)] + suffix
+}
+
+// isFieldManagerConflict returns true if err was returned by a server-side apply request
+// because some fields are owned by a different field manager.
+func isFieldManagerConflict(err error) bool {
+	if !apierrors.IsConflict(err) {
+		return false
+	}
+
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) || status.Status().Details == nil {
+		return false
+	}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type == metav1.CauseTypeFieldManagerConflict {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFieldManager removes, from object managedFields, the entries applied by fieldManager.
+// Resources used to be applied by Sveltos with defaultFieldManager. Once a resource is applied with
+// the profile field manager, the old entries are dropped. Otherwise fields removed from the profile
+// would still be owned by defaultFieldManager and never removed from the resource.
+func removeFieldManager(ctx context.Context, dr dynamic.ResourceInterface,
+	object *unstructured.Unstructured, fieldManager string, logger logr.Logger) error {
+
+	managedFields := object.GetManagedFields()
+	filtered := make([]metav1.ManagedFieldsEntry, 0, len(managedFields))
+	for i := range managedFields {
+		if managedFields[i].Manager == fieldManager &&
+			managedFields[i].Operation == metav1.ManagedFieldsOperationApply {
+
+			continue
+		}
+		filtered = append(filtered, managedFields[i])
+	}
+
+	if len(filtered) == len(managedFields) || len(filtered) == 0 {
+		return nil
+	}
+
+	// resourceVersion makes sure managedFields are not changed in the meantime
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"managedFields":   filtered,
+			"resourceVersion": object.GetResourceVersion(),
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	_, err = dr.Patch(ctx, object.GetName(), types.MergePatchType, data, metav1.PatchOptions{})
+	if apierrors.IsConflict(err) {
+		// Resource was modified. Old field manager will be removed next time resource is applied.
+		logger.V(logs.LogDebug).Info(fmt.Sprintf("failed to remove field manager %s: %v", fieldManager, err))
+		return nil
+	}
+	return err
+}
+
+// updateResource creates or updates a resource in a Cluster using server-side apply with
+// fieldManager as field manager. When force is false, the apply fails if any field is owned
+// by a different field manager.
 // No action in DryRun mode.
 func updateResource(ctx context.Context, dr dynamic.ResourceInterface,
 	clusterSummary *configv1beta1.ClusterSummary, object *unstructured.Unstructured, subresources []string,
-	logger logr.Logger) error {
+	fieldManager string, force bool, logger logr.Logger) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
@@ -232,10 +354,9 @@ func updateResource(ctx context.Context, dr dynamic.ResourceInterface,
 		"resourceGVK", object.GetObjectKind().GroupVersionKind(), "subresources", subresources)
 	l.V(logs.LogDebug).Info("deploying policy")
 
-	forceConflict := true
 	options := metav1.PatchOptions{
-		FieldManager: "application/apply-patch",
-		Force:        &forceConflict,
+		FieldManager: fieldManager,
+		Force:        &force,
 	}
 
 	// When operating in SyncModeContinuousWithDriftDetection mode and DriftExclusions are specified,
@@ -274,11 +395,18 @@ func updateResource(ctx context.Context, dr dynamic.ResourceInterface,
 		return err
 	}
 
-	_, err = dr.Patch(ctx, object.GetName(), types.ApplyPatchType, data, options)
+	applied, err := dr.Patch(ctx, object.GetName(), types.ApplyPatchType, data, options)
 	if err != nil {
 		return err
 	}
 
+	if fieldManager != defaultFieldManager {
+		err = removeFieldManager(ctx, dr, applied, defaultFieldManager, l)
+		if err != nil {
+			return err
+		}
+	}
+
 	return applySubresources(ctx, dr, object, subresources, &options)
 }
 
@@ -332,7 +460,8 @@ func deployContent(ctx context.Context, deployingToMgmtCluster bool, destConfig
 	}
 
 	return deployUnstructured(ctx, deployingToMgmtCluster, destConfig, destClient, resources, ref,
-		configv1beta1.FeatureResources, clusterSummary, mgmtResources, subresources, logger)
+		configv1beta1.FeatureResources, clusterSummary, mgmtResources, subresources,
+		getConflictPolicy(referencedObject), logger)
 }
 
 // adjustNamespace fixes namespace.
@@ -366,15 +495,18 @@ func adjustNamespace(policy *unstructured.Unstructured, destConfig *rest.Config)
 func deployUnstructured(ctx context.Context, deployingToMgmtCluster bool, destConfig *rest.Config,
 	destClient client.Client, referencedUnstructured []*unstructured.Unstructured, referencedObject *corev1.ObjectReference,
 	featureID configv1beta1.FeatureID, clusterSummary *configv1beta1.ClusterSummary, mgmtResources map[string]*unstructured.Unstructured,
-	subresources []string, logger logr.Logger) (reports []configv1beta1.ResourceReport, err error) {
+	subresources []string, conflictPolicy configv1beta1.ConflictPolicy, logger logr.Logger,
+) (reports []configv1beta1.ResourceReport, err error) {
 
 	profile, profileTier, err := configv1beta1.GetProfileOwnerAndTier(ctx, getManagementClusterClient(), clusterSummary)
 	if err != nil {
 		return nil, err
 	}
+	appliedProfile := getAppliedProfile(profile)
 	if profile.GetObjectKind().GroupVersionKind().Kind == configv1beta1.ProfileKind {
 		profile.SetName(profileNameToOwnerReferenceName(profile))
 	}
+	fieldManager := getFieldManager(profile)
 
 	patches, err := initiatePatches(ctx, clusterSummary, "patch", mgmtResources, logger)
 	if err != nil {
@@ -389,11 +521,32 @@ func deployUnstructured(ctx context.Context, deployingToMgmtCluster bool, destCo
 		}
 	}
 
+	// Registry mirrors are for images pulled by the managed cluster
+	if !deployingToMgmtCluster {
+		mirrors, err := getRegistryMirrors(ctx, getManagementClusterClient(), clusterSummary)
+		if err != nil {
+			return nil, err
+		}
+		rewriteUnstructuredImageRegistries(referencedUnstructured, mirrors)
+	}
+
+	// Namespaces and CRDs are deployed first. CRDs deployed are tracked so that, before deploying
+	// any other resource (which might be an instance of those), Sveltos waits for them to be established.
+	referencedUnstructured = sortResourcesForDeployment(referencedUnstructured)
+	deployedCRDs := make([]string, 0)
+
 	conflictErrorMsg := ""
 	reports = make([]configv1beta1.ResourceReport, 0)
 	for i := range referencedUnstructured {
 		policy := referencedUnstructured[i]
 
+		if len(deployedCRDs) > 0 && !isCRD(policy) {
+			if err := waitForCRDsEstablished(ctx, destClient, deployedCRDs, crdEstablishedTimeout, logger); err != nil {
+				return reports, err
+			}
+			deployedCRDs = make([]string, 0)
+		}
+
 		err := adjustNamespace(policy, destConfig)
 		if err != nil {
 			return nil, err
@@ -417,13 +570,14 @@ func deployUnstructured(ctx context.Context, deployingToMgmtCluster bool, destCo
 
 		var resourceInfo *deployer.ResourceInfo
 		var requeue bool
-		resourceInfo, requeue, err = canDeployResource(ctx, dr, policy, referencedObject, profile, profileTier, logger)
+		resourceInfo, requeue, err = canDeployResource(ctx, dr, clusterSummary, policy, referencedObject, profile,
+			profileTier, conflictPolicy, logger)
 		if err != nil {
 			var conflictErr *deployer.ConflictError
 			ok := errors.As(err, &conflictErr)
 			if ok {
 				conflictResourceReport := generateConflictResourceReport(ctx, dr, resource)
-				if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+				if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 					reports = append(reports, *conflictResourceReport)
 					continue
 				} else {
@@ -437,8 +591,20 @@ func deployUnstructured(ctx context.Context, deployingToMgmtCluster bool, destCo
 			return reports, err
 		}
 
+		if isAssessOnlyMode(clusterSummary) {
+			// Nothing is deployed. Only compare with the object currently in the managed cluster
+			var assessmentReport *configv1beta1.ResourceReport
+			assessmentReport, err = generateAssessmentReport(ctx, dr, policy, resource)
+			if err != nil {
+				return reports, err
+			}
+			reports = append(reports, *assessmentReport)
+			continue
+		}
+
 		addMetadata(policy, resourceInfo.ResourceVersion, profile,
 			clusterSummary.Spec.ClusterProfileSpec.ExtraLabels, clusterSummary.Spec.ClusterProfileSpec.ExtraAnnotations)
+		addAuditAnnotations(policy, appliedProfile, profile.GetGeneration())
 
 		if deployingToMgmtCluster {
 			// When deploying resources in the management cluster, just setting (Cluster)Profile as OwnerReference is
@@ -457,13 +623,41 @@ func deployUnstructured(ctx context.Context, deployingToMgmtCluster bool, destCo
 			}
 		}
 
-		err = updateResource(ctx, dr, clusterSummary, policy, subresources, logger)
+		// With ConflictPolicy Fail, fields owned by other controllers are never taken over
+		force := conflictPolicy != configv1beta1.ConflictPolicyFail
+		err = updateResource(ctx, dr, clusterSummary, policy, subresources, fieldManager, force, logger)
+		if !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+			record := getResourceAuditRecord(AuditOperationApply, featureID, policy)
+			record.PreviousHash = resourceInfo.Hash
+			record.Hash = policyHash
+			recordAudit(ctx, clusterSummary, record, err, logger)
+		}
 		if err != nil {
+			if isFieldManagerConflict(err) {
+				conflictErrorMsg += fmt.Sprintf("%s %s/%s: %v. ", policy.GetKind(), policy.GetNamespace(),
+					policy.GetName(), err)
+				if clusterSummary.Spec.ClusterProfileSpec.ContinueOnConflict {
+					continue
+				}
+				return reports, deployer.NewConflictError(conflictErrorMsg)
+			}
 			return reports, err
 		}
 
+		if isCRD(policy) && !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+			deployedCRDs = append(deployedCRDs, policy.GetName())
+		}
+
 		resource.LastAppliedTime = &metav1.Time{Time: time.Now()}
-		reports = append(reports, *generateResourceReport(policyHash, resourceInfo, resource))
+		report := generateResourceReport(policyHash, resourceInfo, resource)
+		if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) &&
+			report.Action == string(configv1beta1.UpdateResourceAction) {
+
+			if err = refineDryRunUpdateReport(ctx, dr, policy, report); err != nil {
+				return reports, err
+			}
+		}
+		reports = append(reports, *report)
 	}
 
 	if conflictErrorMsg != "" {
@@ -554,10 +748,19 @@ func requeueAllOldOwners(ctx context.Context, profileOwners []corev1.ObjectRefer
 // - if resource is currently already deployed in the managed cluster but owned by different (Cluster)Profile
 // => it can be updated only if current (Cluster)Profile tier is lower than profile currently deploying the resource
 //
+// ConflictPolicy changes those rules:
+// - Adopt (default) => rules above apply. Existing resources not deployed by Sveltos are taken over;
+// - Fail => any existing resource not already owned by this (Cluster)Profile is a conflict, regardless of tier;
+// - Force => conflicts are always resolved in favor of this (Cluster)Profile.
+//
+// Registered ConflictResolvers are invoked for any conflict and can override the decision
+// when ConflictPolicy is Adopt.
+//
 // If resource cannot be deployed, return a ConflictError.
 // If any other error occurs while doing those verification, the error is returned
-func canDeployResource(ctx context.Context, dr dynamic.ResourceInterface, policy *unstructured.Unstructured,
-	referencedObject *corev1.ObjectReference, profile client.Object, profileTier int32, logger logr.Logger,
+func canDeployResource(ctx context.Context, dr dynamic.ResourceInterface,
+	clusterSummary *configv1beta1.ClusterSummary, policy *unstructured.Unstructured, referencedObject *corev1.ObjectReference, profile client.Object, profileTier int32,
+	conflictPolicy configv1beta1.ConflictPolicy, logger logr.Logger,
 ) (resourceInfo *deployer.ResourceInfo, requeueOldOwner bool, err error) {
 
 	l := logger.WithValues("resource",
@@ -569,7 +772,18 @@ func canDeployResource(ctx context.Context, dr dynamic.ResourceInterface, policy
 		ok := errors.As(err, &conflictErr)
 		if ok {
 			// There is a conflict.
-			if hasHigherOwnershipPriority(getTier(resourceInfo.OwnerTier), profileTier) {
+			claimantWins := conflictPolicy == configv1beta1.ConflictPolicyForce ||
+				(conflictPolicy != configv1beta1.ConflictPolicyFail &&
+					hasHigherOwnershipPriority(getTier(resourceInfo.OwnerTier), profileTier))
+			conflict := getResourceConflict(clusterSummary, policy, resourceInfo, profile, profileTier,
+				conflictPolicy)
+			claimantWins = resolveConflict(ctx, conflict, claimantWins, l)
+
+			if conflictPolicy == configv1beta1.ConflictPolicyForce {
+				l.V(logs.LogDebug).Info("conflict detected but conflict policy is Force. Taking ownership")
+				return resourceInfo, true, nil
+			}
+			if claimantWins {
 				l.V(logs.LogDebug).Info("conflict detected but resource ownership can change")
 				// Because of tier, ownership must change. Which also means current ClusterProfile/Profile
 				// owning the resource must be requeued for reconciliation
@@ -583,10 +797,31 @@ func canDeployResource(ctx context.Context, dr dynamic.ResourceInterface, policy
 		return nil, false, err
 	}
 
+	if conflictPolicy == configv1beta1.ConflictPolicyFail && resourceInfo.ResourceVersion != "" &&
+		!isOwnedByProfile(resourceInfo.OwnerReferences, profile) {
+
+		l.V(logs.LogDebug).Info("resource exists and conflict policy is Fail")
+		return resourceInfo, false, deployer.NewConflictError(
+			fmt.Sprintf("conflict: policy (kind: %s) %s/%s already exists and is not deployed by %s %s (conflictPolicy: %s).\n",
+				policy.GetKind(), policy.GetNamespace(), policy.GetName(),
+				profile.GetObjectKind().GroupVersionKind().Kind, profile.GetName(), conflictPolicy))
+	}
+
 	// There was no conflict. Resource can be deployed.
 	return resourceInfo, false, nil
 }
 
+// isOwnedByProfile returns true if profile is one of the owners
+func isOwnedByProfile(ownerReferences []corev1.ObjectReference, profile client.Object) bool {
+	kind := profile.GetObjectKind().GroupVersionKind().Kind
+	for i := range ownerReferences {
+		if ownerReferences[i].Kind == kind && ownerReferences[i].Name == profile.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
 func generateResourceReport(policyHash string, resourceInfo *deployer.ResourceInfo,
 	resource *configv1beta1.Resource) *configv1beta1.ResourceReport {
 
@@ -853,7 +1088,7 @@ func getClusterSummaryAndClusterClient(ctx context.Context, clusterNamespace, cl
 	}
 
 	// Get CAPI Cluster
-	cluster, err := clusterproxy.GetCluster(ctx, c, clusterSummary.Spec.ClusterNamespace,
+	cluster, err := getCluster(ctx, c, clusterSummary.Spec.ClusterNamespace,
 		clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType)
 	if err != nil {
 		return nil, nil, err
@@ -865,9 +1100,7 @@ func getClusterSummaryAndClusterClient(ctx context.Context, clusterNamespace, cl
 		return nil, nil, fmt.Errorf("cluster is marked for deletion")
 	}
 
-	adminNamespace, adminName := getClusterSummaryAdmin(clusterSummary)
-	clusterClient, err := clusterproxy.GetKubernetesClient(ctx, c, clusterSummary.Spec.ClusterNamespace,
-		clusterSummary.Spec.ClusterName, adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	clusterClient, err := getClusterSummaryClient(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -888,6 +1121,36 @@ func appendPathAnnotations(object client.Object, reference *configv1beta1.Policy
 	object.SetAnnotations(annotations)
 }
 
+// appendConflictPolicyAnnotation stores, on the in-memory copy of the referenced resource,
+// the ConflictPolicy of the PolicyRef. It is consumed when content is deployed.
+func appendConflictPolicyAnnotation(object client.Object, reference *configv1beta1.PolicyRef) {
+	if object == nil {
+		return
+	}
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if reference.ConflictPolicy == "" {
+		delete(annotations, conflictPolicyAnnotation)
+	} else {
+		annotations[conflictPolicyAnnotation] = string(reference.ConflictPolicy)
+	}
+	object.SetAnnotations(annotations)
+}
+
+// getConflictPolicy returns the ConflictPolicy set by appendConflictPolicyAnnotation.
+// Defaults to Adopt.
+func getConflictPolicy(object client.Object) configv1beta1.ConflictPolicy {
+	annotations := object.GetAnnotations()
+	if annotations != nil {
+		if value, ok := annotations[conflictPolicyAnnotation]; ok && value != "" {
+			return configv1beta1.ConflictPolicy(value)
+		}
+	}
+	return configv1beta1.ConflictPolicyAdopt
+}
+
 // collectReferencedObjects collects all referenced configMaps/secrets in control cluster
 // local contains all configMaps/Secrets whose content need to be deployed locally (in the management cluster)
 // remote contains all configMap/Secrets whose content need to be deployed remotely (in the managed cluster)
@@ -912,6 +1175,14 @@ func collectReferencedObjects(ctx context.Context, controlClusterClient client.C
 			return nil, nil, err
 		}
 
+		// Tenant admins can only reference resources they are allowed to read
+		err = validateAdminCanGetReference(ctx, controlClusterClient, clusterSummary,
+			reference.Kind, namespace, name)
+		if err != nil {
+			logger.V(logs.LogInfo).Info(err.Error())
+			return nil, nil, err
+		}
+
 		if reference.Kind == string(libsveltosv1beta1.ConfigMapReferencedResourceKind) {
 			object, err = getConfigMap(ctx, controlClusterClient,
 				types.NamespacedName{Namespace: namespace, Name: name})
@@ -932,6 +1203,15 @@ func collectReferencedObjects(ctx context.Context, controlClusterClient client.C
 			return nil, nil, err
 		}
 
+		// Referenced ConfigMaps/Secrets might be required to opt-in
+		err = validateReferenceOptIn(object, reference.Kind)
+		if err != nil {
+			logger.V(logs.LogInfo).Info(err.Error())
+			return nil, nil, err
+		}
+
+		appendConflictPolicyAnnotation(object, reference)
+
 		if reference.DeploymentType == configv1beta1.DeploymentTypeLocal {
 			local = append(local, object)
 		} else {
@@ -1053,14 +1333,10 @@ func undeployStaleResources(ctx context.Context, isMgmtCluster bool,
 
 	undeployed := make([]configv1beta1.ResourceReport, 0)
 
-	dc := discovery.NewDiscoveryClientForConfigOrDie(remoteConfig)
-	groupResources, err := restmapper.GetAPIGroupResources(dc)
+	mapper, d, err := getRemoteRESTMapperAndDynamicClient(remoteConfig)
 	if err != nil {
 		return nil, err
 	}
-	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
-
-	d := dynamic.NewForConfigOrDie(remoteConfig)
 
 	labelSelector := metav1.LabelSelector{
 		MatchLabels: map[string]string{reasonLabel: string(featureID)},
@@ -1093,6 +1369,10 @@ func undeployStaleResources(ctx context.Context, isMgmtCluster bool,
 
 		list, err := d.Resource(resourceId).List(ctx, listOptions)
 		if err != nil {
+			// RESTMapper is cached and might not know yet CRD was removed
+			if apierrors.IsNotFound(err) {
+				continue
+			}
 			return nil, err
 		}
 
@@ -1140,7 +1420,7 @@ func undeployStaleResource(ctx context.Context, isMgmtCluster bool, remoteClient
 	// If in DryRun do not withdrawn any policy.
 	// If this ClusterSummary is the only OwnerReference and it is not deploying this policy anymore,
 	// policy would be withdrawn
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		if canDelete(&r, currentPolicies) && deployer.IsOnlyOwnerReference(&r, profile) &&
 			!isLeavePolicies(clusterSummary, logger) {
 
@@ -1175,19 +1455,32 @@ func handleResourceDelete(ctx context.Context, remoteClient client.Client, polic
 	clusterSummary *configv1beta1.ClusterSummary, logger logr.Logger) error {
 
 	// If mode is set to LeavePolicies, leave policies in the workload cluster.
-	// Remove all labels added by Sveltos.
+	// Remove all labels and annotations added by Sveltos, so the resource is handed off
+	// and can later be claimed by any profile without conflicts.
 	if isLeavePolicies(clusterSummary, logger) {
 		l := policy.GetLabels()
 		delete(l, deployer.ReferenceKindLabel)
 		delete(l, deployer.ReferenceNameLabel)
 		delete(l, deployer.ReferenceNamespaceLabel)
+		delete(l, reasonLabel)
 		policy.SetLabels(l)
+
+		annotations := policy.GetAnnotations()
+		delete(annotations, deployer.PolicyHash)
+		delete(annotations, deployer.OwnerTier)
+		delete(annotations, clusterSummaryAnnotation)
+		policy.SetAnnotations(annotations)
+		removeAuditAnnotations(policy)
 		return remoteClient.Update(ctx, policy)
 	}
 
 	logger.V(logs.LogDebug).Info(fmt.Sprintf("removing resource %s %s/%s",
 		policy.GetObjectKind().GroupVersionKind().Kind, policy.GetNamespace(), policy.GetName()))
-	return remoteClient.Delete(ctx, policy)
+	err := remoteClient.Delete(ctx, policy)
+	recordAudit(ctx, clusterSummary,
+		getResourceAuditRecord(AuditOperationDelete, configv1beta1.FeatureID(policy.GetLabels()[reasonLabel]), policy),
+		err, logger)
+	return err
 }
 
 // canDelete returns true if a policy can be deleted. For a policy to be deleted:
@@ -1290,13 +1583,14 @@ func updateClusterConfiguration(ctx context.Context, c client.Client,
 	chartDeployed []configv1beta1.Chart) error {
 
 	// No-op in DryRun mode
-	if clusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun {
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
 		return nil
 	}
 
+	var clusterConfiguration *configv1beta1.ClusterConfiguration
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		// Get ClusterConfiguration for CAPI Cluster
-		clusterConfiguration := &configv1beta1.ClusterConfiguration{}
+		clusterConfiguration = &configv1beta1.ClusterConfiguration{}
 		err := c.Get(ctx,
 			types.NamespacedName{
 				Namespace: clusterSummary.Spec.ClusterNamespace,
@@ -1305,6 +1599,7 @@ func updateClusterConfiguration(ctx context.Context, c client.Client,
 			clusterConfiguration)
 		if err != nil {
 			if apierrors.IsNotFound(err) && !clusterSummary.DeletionTimestamp.IsZero() {
+				clusterConfiguration = nil
 				return nil
 			}
 			return err
@@ -1326,6 +1621,9 @@ func updateClusterConfiguration(ctx context.Context, c client.Client,
 		}
 	})
 
+	if err == nil && clusterConfiguration != nil {
+		recordClusterConfigurationSnapshotOrLog(ctx, c, clusterConfiguration)
+	}
 	return err
 }
 
@@ -1440,13 +1738,46 @@ func getSecret(ctx context.Context, c client.Client, secretName types.Namespaced
 		return nil, err
 	}
 
-	if secret.Type != libsveltosv1beta1.ClusterProfileSecretType {
+	if !isSupportedSecretType(secret) {
 		return nil, libsveltosv1beta1.ErrSecretTypeNotSupported
 	}
 
 	return secret, nil
 }
 
+// isSupportedSecretType returns true if secret can be referenced by a ClusterProfile/Profile.
+// Besides Sveltos own type, Secrets of ClusterAPI ClusterResourceSet type are accepted so
+// Secrets created for ClusterResourceSets can be referenced without being converted.
+func isSupportedSecretType(secret *corev1.Secret) bool {
+	return secret.Type == libsveltosv1beta1.ClusterProfileSecretType ||
+		secret.Type == addonsv1.ClusterResourceSetSecretType
+}
+
+// getSecretDataSection returns the content of a Secret Data key. In ClusterResourceSet Secrets,
+// a key can contain a JSON list of resources. Such a list is converted to the resources it contains
+// separated by '---'.
+func getSecretDataSection(secret *corev1.Secret, value []byte) (string, error) {
+	if secret.Type != addonsv1.ClusterResourceSetSecretType {
+		return string(value), nil
+	}
+
+	trimmed := bytes.TrimSpace(value)
+	if !bytes.HasPrefix(trimmed, []byte("[")) {
+		return string(value), nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(trimmed, &items); err != nil {
+		return "", err
+	}
+
+	sections := make([]string, len(items))
+	for i := range items {
+		sections[i] = string(items[i])
+	}
+	return strings.Join(sections, "\n---\n"), nil
+}
+
 func generateConflictResourceReport(ctx context.Context, dr dynamic.ResourceInterface,
 	resource *configv1beta1.Resource) *configv1beta1.ResourceReport {
 
@@ -1521,10 +1852,8 @@ func appendDeployedGroupVersionKinds(clusterSummary *configv1beta1.ClusterSummar
 		return
 	}
 
-	if fdi == nil {
-		clusterSummary.Status.DeployedGVKs = make([]configv1beta1.FeatureDeploymentInfo, 0)
-	}
-
+	// Do not reset entries of other features: those contain their deployed GroupVersionKinds
+	// and resources inventory
 	clusterSummary.Status.DeployedGVKs = append(
 		clusterSummary.Status.DeployedGVKs,
 		configv1beta1.FeatureDeploymentInfo{
@@ -1561,8 +1890,7 @@ func getRestConfig(ctx context.Context, c client.Client, clusterSummary *configv
 		WithValues("clusterSummary", clusterSummary.Name).WithValues("admin", fmt.Sprintf("%s/%s", adminNamespace, adminName))
 
 	logger.V(logs.LogDebug).Info("get remote restConfig")
-	remoteRestConfig, err := clusterproxy.GetKubernetesRestConfig(ctx, c, clusterNamespace, clusterName,
-		adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	remoteRestConfig, err := getClusterSummaryRestConfig(ctx, c, clusterSummary, logger)
 	if err != nil {
 		return nil, logger, err
 	}
@@ -1573,7 +1901,7 @@ func getRestConfig(ctx context.Context, c client.Client, clusterSummary *configv
 func getValuesFromResourceHash(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
 	valuesFrom []configv1beta1.ValueFrom, logger logr.Logger) (string, error) {
 
-	var config string
+	refs := make([]referencedResource, 0, len(valuesFrom))
 	for i := range valuesFrom {
 		namespace := libsveltostemplate.GetReferenceResourceNamespace(
 			clusterSummary.Namespace, valuesFrom[i].Namespace)
@@ -1586,20 +1914,21 @@ func getValuesFromResourceHash(ctx context.Context, c client.Client, clusterSumm
 			return "", err
 		}
 
-		if valuesFrom[i].Kind == string(libsveltosv1beta1.ConfigMapReferencedResourceKind) {
-			configMap, err := getConfigMap(ctx, c,
-				types.NamespacedName{Namespace: namespace, Name: name})
-			if err == nil {
-				config += getDataSectionHash(configMap.Data)
-				config += getDataSectionHash(configMap.BinaryData)
-			}
-		} else if valuesFrom[i].Kind == string(libsveltosv1beta1.SecretReferencedResourceKind) {
-			secret, err := getSecret(ctx, c,
-				types.NamespacedName{Namespace: namespace, Name: name})
-			if err == nil {
-				config += getDataSectionHash(secret.Data)
-				config += getDataSectionHash(secret.StringData)
-			}
+		if valuesFrom[i].Kind == string(libsveltosv1beta1.ConfigMapReferencedResourceKind) ||
+			valuesFrom[i].Kind == string(libsveltosv1beta1.SecretReferencedResourceKind) {
+
+			refs = append(refs, referencedResource{
+				kind: valuesFrom[i].Kind,
+				key:  types.NamespacedName{Namespace: namespace, Name: name},
+			})
+		}
+	}
+
+	// Resources which cannot be fetched do not contribute to the hash
+	var config string
+	for _, r := range getReferencedResourcesHashes(ctx, c, refs, valuesFromContentHasher) {
+		if r.err == nil {
+			config += r.hash
 		}
 	}
 
@@ -1620,82 +1949,101 @@ func getValuesFrom(ctx context.Context, c client.Client, clusterSummary *configv
 	template = make(map[string]string)
 	nonTemplate = make(map[string]string)
 	for i := range valuesFrom {
-		namespace := libsveltostemplate.GetReferenceResourceNamespace(
-			clusterSummary.Namespace, valuesFrom[i].Namespace)
-
-		name, err := libsveltostemplate.GetReferenceResourceName(clusterSummary.Spec.ClusterNamespace,
-			clusterSummary.Spec.ClusterName, string(clusterSummary.Spec.ClusterType), valuesFrom[i].Name)
+		data, isTemplate, err := getValuesFromResource(ctx, c, clusterSummary, &valuesFrom[i], logger)
 		if err != nil {
-			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to instantiate name for %s %s/%s: %v",
-				valuesFrom[i].Kind, valuesFrom[i].Namespace, valuesFrom[i].Name, err))
 			return nil, nil, err
 		}
 
-		if valuesFrom[i].Kind == string(libsveltosv1beta1.ConfigMapReferencedResourceKind) {
-			configMap, err := getConfigMap(ctx, c, types.NamespacedName{Namespace: namespace, Name: name})
-			if err != nil {
-				msg := fmt.Sprintf("failed to get ConfigMap %s/%s", namespace, name)
-				logger.V(logs.LogInfo).Info(fmt.Sprintf("%s: %v", msg, err))
-				if apierrors.IsNotFound(err) {
-					msg := fmt.Sprintf("Referenced resource: %s %s/%s does not exist",
-						libsveltosv1beta1.ConfigMapReferencedResourceKind, namespace, name)
-					logger.V(logs.LogInfo).Info(msg)
-					return nil, nil, &NonRetriableError{Message: msg}
-				}
-				return nil, nil, errors.Wrapf(err, msg)
-			}
+		current := nonTemplate
+		if isTemplate {
+			current = template
+		}
 
-			if instantiateTemplate(configMap, logger) {
-				for key, value := range configMap.Data {
-					if overrideKeys {
-						template[key] = value
-					} else {
-						addToMap(template, key, value)
-					}
-				}
+		for key, value := range data {
+			if overrideKeys {
+				current[key] = value
 			} else {
-				for key, value := range configMap.Data {
-					if overrideKeys {
-						nonTemplate[key] = value
-					} else {
-						addToMap(nonTemplate, key, value)
-					}
-				}
+				addToMap(current, key, value)
 			}
-		} else if valuesFrom[i].Kind == string(libsveltosv1beta1.SecretReferencedResourceKind) {
-			secret, err := getSecret(ctx, c, types.NamespacedName{Namespace: namespace, Name: name})
-			if err != nil {
-				msg := fmt.Sprintf("failed to get Secret %s/%s", namespace, name)
-				logger.V(logs.LogInfo).Info(fmt.Sprintf("%s: %v", msg, err))
-				if apierrors.IsNotFound(err) {
-					msg := fmt.Sprintf("Referenced resource: %s %s/%s does not exist",
-						libsveltosv1beta1.SecretReferencedResourceKind, namespace, name)
-					logger.V(logs.LogInfo).Info(msg)
-					return nil, nil, &NonRetriableError{Message: msg}
-				}
-				return nil, nil, errors.Wrapf(err, msg)
+		}
+	}
+
+	return template, nonTemplate, nil
+}
+
+// getValuesFromResource returns the data section of the ConfigMap/Secret referenced by valueFrom
+// and whether such content is a template which needs to be instantiated.
+func getValuesFromResource(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	valueFrom *configv1beta1.ValueFrom, logger logr.Logger) (data map[string]string, isTemplate bool, err error) {
+
+	namespace := libsveltostemplate.GetReferenceResourceNamespace(
+		clusterSummary.Namespace, valueFrom.Namespace)
+
+	name, err := libsveltostemplate.GetReferenceResourceName(clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, string(clusterSummary.Spec.ClusterType), valueFrom.Name)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to instantiate name for %s %s/%s: %v",
+			valueFrom.Kind, valueFrom.Namespace, valueFrom.Name, err))
+		return nil, false, err
+	}
+
+	// Tenant admins can only reference resources they are allowed to read
+	err = validateAdminCanGetReference(ctx, c, clusterSummary, valueFrom.Kind, namespace, name)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(err.Error())
+		return nil, false, err
+	}
+
+	data = make(map[string]string)
+	if valueFrom.Kind == string(libsveltosv1beta1.ConfigMapReferencedResourceKind) {
+		configMap, err := getConfigMap(ctx, c, types.NamespacedName{Namespace: namespace, Name: name})
+		if err != nil {
+			msg := fmt.Sprintf("failed to get ConfigMap %s/%s", namespace, name)
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("%s: %v", msg, err))
+			if apierrors.IsNotFound(err) {
+				msg := fmt.Sprintf("Referenced resource: %s %s/%s does not exist",
+					libsveltosv1beta1.ConfigMapReferencedResourceKind, namespace, name)
+				logger.V(logs.LogInfo).Info(msg)
+				return nil, false, &NonRetriableError{Message: msg}
 			}
-			if instantiateTemplate(secret, logger) {
-				for key, value := range secret.Data {
-					if overrideKeys {
-						template[key] = string(value)
-					} else {
-						addToMap(template, key, string(value))
-					}
-				}
-			} else {
-				for key, value := range secret.Data {
-					if overrideKeys {
-						nonTemplate[key] = string(value)
-					} else {
-						addToMap(nonTemplate, key, string(value))
-					}
-				}
+			return nil, false, errors.Wrapf(err, msg)
+		}
+
+		if err := validateReferenceOptIn(configMap, valueFrom.Kind); err != nil {
+			logger.V(logs.LogInfo).Info(err.Error())
+			return nil, false, err
+		}
+
+		for key, value := range configMap.Data {
+			data[key] = value
+		}
+		return data, instantiateTemplate(configMap, logger), nil
+	} else if valueFrom.Kind == string(libsveltosv1beta1.SecretReferencedResourceKind) {
+		secret, err := getSecret(ctx, c, types.NamespacedName{Namespace: namespace, Name: name})
+		if err != nil {
+			msg := fmt.Sprintf("failed to get Secret %s/%s", namespace, name)
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("%s: %v", msg, err))
+			if apierrors.IsNotFound(err) {
+				msg := fmt.Sprintf("Referenced resource: %s %s/%s does not exist",
+					libsveltosv1beta1.SecretReferencedResourceKind, namespace, name)
+				logger.V(logs.LogInfo).Info(msg)
+				return nil, false, &NonRetriableError{Message: msg}
 			}
+			return nil, false, errors.Wrapf(err, msg)
 		}
+
+		if err := validateReferenceOptIn(secret, valueFrom.Kind); err != nil {
+			logger.V(logs.LogInfo).Info(err.Error())
+			return nil, false, err
+		}
+
+		for key, value := range secret.Data {
+			data[key] = string(value)
+		}
+		return data, instantiateTemplate(secret, logger), nil
 	}
 
-	return template, nonTemplate, nil
+	return data, false, nil
 }
 
 func addToMap(m map[string]string, key, value string) {
@@ -1734,7 +2082,9 @@ func initiatePatches(ctx context.Context, clusterSummary *configv1beta1.ClusterS
 	return
 }
 
-func getClusterProfileSpecHash(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary) ([]byte, error) {
+func getClusterProfileSpecHash(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+) ([]byte, error) {
+
 	h := sha256.New()
 	var config string
 
@@ -1756,6 +2106,13 @@ func getClusterProfileSpecHash(ctx context.Context, clusterSummary *configv1beta
 		// Use the version. This will cause drift-detection, Sveltos CRDs
 		// to be redeployed on upgrade
 		config += getVersion()
+
+		// If placement, image or compute resources change, drift-detection-manager needs to be redeployed
+		config += string(clusterProfileSpec.DriftDetectionPlacement)
+		config += getDriftDetectionImage()
+		if clusterProfileSpec.DriftDetectionResources != nil {
+			config += render.AsCode(clusterProfileSpec.DriftDetectionResources)
+		}
 	}
 
 	mgmtResources, err := collectTemplateResourceRefs(ctx, clusterSummary)
@@ -1770,6 +2127,16 @@ func getClusterProfileSpecHash(ctx context.Context, clusterSummary *configv1beta
 		config += render.AsCode(clusterProfileSpec.Patches)
 	}
 
+	// If registry mirrors change, images need to be rewritten
+	if clusterProfileSpec.RegistryMirrors != nil {
+		config += render.AsCode(clusterProfileSpec.RegistryMirrors)
+	}
+	registryMirrors, err := getClusterRegistryMirrorsAnnotation(ctx, c, clusterSummary)
+	if err != nil {
+		return nil, err
+	}
+	config += registryMirrors
+
 	// If drift-detectionmanager configuration is in a ConfigMap. fetch ConfigMap and use its Data
 	// section in the hash evaluation.
 	if driftDetectionConfigMap := getDriftDetectionConfigMap(); driftDetectionConfigMap != "" {
@@ -18,6 +18,7 @@ package controllers_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -32,11 +33,14 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2/textlogger"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -597,6 +601,23 @@ var _ = Describe("HandlersUtils", func() {
 		Expect(apierrors.IsNotFound(err)).To(BeTrue())
 	})
 
+	It("createNamespace sets TargetNamespaceMetadata on created namespace", func() {
+		initObjects := []client.Object{}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).WithObjects(initObjects...).Build()
+
+		clusterSummary.Spec.ClusterProfileSpec.TargetNamespaceMetadata = &configv1beta1.NamespaceMetadata{
+			Labels:      map[string]string{"pod-security.kubernetes.io/enforce": "restricted"},
+			Annotations: map[string]string{randomString(): randomString()},
+		}
+		Expect(controllers.CreateNamespace(context.TODO(), c, clusterSummary, namespace)).To(BeNil())
+
+		currentNs := &corev1.Namespace{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Name: namespace}, currentNs)).To(Succeed())
+		Expect(currentNs.Labels).To(HaveKeyWithValue("pod-security.kubernetes.io/enforce", "restricted"))
+		Expect(currentNs.Annotations).To(Equal(clusterSummary.Spec.ClusterProfileSpec.TargetNamespaceMetadata.Annotations))
+	})
+
 	It("createNamespace returns no error if namespace already exists", func() {
 		ns := &corev1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
@@ -623,7 +644,7 @@ var _ = Describe("HandlersUtils", func() {
 
 		// following will successfully create deployment
 		Expect(controllers.UpdateResource(context.TODO(), dr, clusterSummary, u, nil,
-			textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
+			"application/apply-patch", true, textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
 
 		currentDeployment := &appsv1.Deployment{}
 		Eventually(func() bool {
@@ -664,7 +685,7 @@ var _ = Describe("HandlersUtils", func() {
 
 		// New deploy will not override replicas
 		Expect(controllers.UpdateResource(context.TODO(), dr, clusterSummary, u, nil,
-			textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
+			"application/apply-patch", true, textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
 
 		Consistently(func() bool {
 			err := testEnv.Get(context.TODO(),
@@ -685,7 +706,7 @@ var _ = Describe("HandlersUtils", func() {
 
 		// following will successfully create deployment
 		Expect(controllers.UpdateResource(context.TODO(), dr, clusterSummary, u, nil,
-			textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
+			"application/apply-patch", true, textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
 
 		currentDeployment := &appsv1.Deployment{}
 		Eventually(func() bool {
@@ -734,7 +755,7 @@ var _ = Describe("HandlersUtils", func() {
 
 		// New deploy will not override replicas
 		Expect(controllers.UpdateResource(context.TODO(), dr, clusterSummary, u, []string{"status"},
-			textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
+			"application/apply-patch", true, textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
 
 		Consistently(func() bool {
 			err := testEnv.Get(context.TODO(),
@@ -1229,6 +1250,87 @@ var _ = Describe("HandlersUtils", func() {
 		Expect(controllers.CanDelete(depl, map[string]configv1beta1.Resource{name: {}})).To(BeFalse())
 	})
 
+	It("canDeployResource honors ConflictPolicy", func() {
+		// ConfigMap exists in the cluster and was not deployed by Sveltos
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      randomString(),
+			},
+			Data: map[string]string{randomString(): randomString()},
+		}
+		Expect(testEnv.Create(context.TODO(), configMap)).To(Succeed())
+		Expect(waitForObject(context.TODO(), testEnv.Client, configMap)).To(Succeed())
+
+		policy, err := runtime.DefaultUnstructuredConverter.ToUnstructured(configMap)
+		Expect(err).To(BeNil())
+		u := &unstructured.Unstructured{Object: policy}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+
+		dr, err := utils.GetDynamicResourceInterface(testEnv.Config, u.GroupVersionKind(), u.GetNamespace())
+		Expect(err).To(BeNil())
+
+		referencedObject := &corev1.ObjectReference{
+			Kind:      string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+			Namespace: namespace,
+			Name:      randomString(),
+		}
+
+		Expect(addTypeInformationToObject(scheme, clusterProfile)).To(Succeed())
+		tier := int32(100)
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		// Adopt: existing resource is taken over
+		_, requeue, err := controllers.CanDeployResource(context.TODO(), dr, clusterSummary, u, referencedObject, clusterProfile,
+			tier, configv1beta1.ConflictPolicyAdopt, logger)
+		Expect(err).To(BeNil())
+		Expect(requeue).To(BeFalse())
+
+		// Fail: existing resource not deployed by this ClusterProfile is a conflict
+		_, _, err = controllers.CanDeployResource(context.TODO(), dr, clusterSummary, u, referencedObject, clusterProfile,
+			tier, configv1beta1.ConflictPolicyFail, logger)
+		Expect(err).ToNot(BeNil())
+		var conflictErr *deployer.ConflictError
+		Expect(errors.As(err, &conflictErr)).To(BeTrue())
+
+		// ConfigMap is now owned by a different ClusterProfile
+		currentConfigMap := &corev1.ConfigMap{}
+		Expect(testEnv.Get(context.TODO(), types.NamespacedName{Namespace: configMap.Namespace, Name: configMap.Name},
+			currentConfigMap)).To(Succeed())
+		currentConfigMap.Labels = map[string]string{
+			deployer.ReferenceKindLabel:      referencedObject.Kind,
+			deployer.ReferenceNamespaceLabel: referencedObject.Namespace,
+			deployer.ReferenceNameLabel:      referencedObject.Name,
+		}
+		// Same tier: tier alone does not allow ownership to change
+		currentConfigMap.Annotations = map[string]string{
+			deployer.OwnerTier: fmt.Sprintf("%d", tier),
+		}
+		currentConfigMap.OwnerReferences = []metav1.OwnerReference{
+			{
+				APIVersion: configv1beta1.GroupVersion.String(),
+				Kind:       configv1beta1.ClusterProfileKind,
+				Name:       randomString(),
+				UID:        types.UID(randomString()),
+			},
+		}
+		Expect(testEnv.Update(context.TODO(), currentConfigMap)).To(Succeed())
+
+		Eventually(func() error {
+			_, _, err = controllers.CanDeployResource(context.TODO(), dr, clusterSummary, u, referencedObject, clusterProfile,
+				tier, configv1beta1.ConflictPolicyAdopt, logger)
+			return err
+		}, timeout, pollingInterval).ShouldNot(BeNil())
+		Expect(errors.As(err, &conflictErr)).To(BeTrue())
+
+		// Force: conflict is resolved in favor of this ClusterProfile and old owner is requeued
+		_, requeue, err = controllers.CanDeployResource(context.TODO(), dr, clusterSummary, u, referencedObject, clusterProfile,
+			tier, configv1beta1.ConflictPolicyForce, logger)
+		Expect(err).To(BeNil())
+		Expect(requeue).To(BeTrue())
+	})
+
 	It("addExtraLabels adds extra labels on unstructured", func() {
 		u := &unstructured.Unstructured{}
 		extraLabels := map[string]string{
@@ -1343,6 +1445,40 @@ metadata:
 		Expect(v).To(Equal(deplTemplate))
 	})
 
+	It("readFiles keeps files with same name and skips hidden files", func() {
+		dir, err := os.MkdirTemp("", "my-temp-dir")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+
+		const permission0600 = 0600
+		const subdir = "subdir"
+		const hiddenDir = ".git"
+		Expect(os.MkdirAll(filepath.Join(dir, subdir), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dir, hiddenDir), 0755)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(dir, "policy.yaml"), []byte(serviceTemplate), permission0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, subdir, "policy.yaml"), []byte(deplTemplate), permission0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, ".sourceignore"), []byte("*.md"), permission0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, hiddenDir, "config"), []byte("git"), permission0600)).To(Succeed())
+
+		result, err := controllers.ReadFiles(dir)
+		Expect(err).To(BeNil())
+		Expect(len(result)).To(Equal(2))
+		Expect(result["policy.yaml"]).To(Equal(serviceTemplate))
+		Expect(result[filepath.Join(subdir, "policy.yaml")]).To(Equal(deplTemplate))
+	})
+
+	It("getSourceArtifactPath does not allow paths outside of the artifact", func() {
+		dir, err := controllers.GetSourceArtifactPath("/tmp/artifact", "./policies/nginx")
+		Expect(err).To(BeNil())
+		Expect(dir).To(Equal("/tmp/artifact/policies/nginx"))
+
+		_, err = controllers.GetSourceArtifactPath("/tmp/artifact", "../other")
+		Expect(err).ToNot(BeNil())
+		var nonRetriableError *controllers.NonRetriableError
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+	})
+
 	It("handleResourceDelete leaves policies on Cluster when mode is LeavePolicies", func() {
 		randomKey := randomString()
 		randomValue := randomString()
@@ -1354,8 +1490,14 @@ metadata:
 					deployer.ReferenceKindLabel:      randomString(),
 					deployer.ReferenceNameLabel:      randomString(),
 					deployer.ReferenceNamespaceLabel: randomString(),
+					"projectsveltos.io/reason":       string(configv1beta1.FeatureResources),
 					randomKey:                        randomValue,
 				},
+				Annotations: map[string]string{
+					deployer.PolicyHash: randomString(),
+					deployer.OwnerTier:  "100",
+					randomKey:           randomValue,
+				},
 			},
 		}
 		Expect(addTypeInformationToObject(scheme, depl)).To(Succeed())
@@ -1382,6 +1524,10 @@ metadata:
 		v, ok := currentDepl.Labels[randomKey]
 		Expect(ok).To(BeTrue())
 		Expect(v).To(Equal(randomValue))
+		Expect(len(currentDepl.Annotations)).To(Equal(1))
+		v, ok = currentDepl.Annotations[randomKey]
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal(randomValue))
 	})
 
 	It("collectContent collect contents with no error even when there are section with just comments", func() {
@@ -1555,6 +1701,87 @@ status:
 		Expect(serviceOut.Spec.Selector).To(Not(BeNil()))
 		Expect(serviceOut.Spec.Selector[key]).To(Equal(value))
 	})
+
+	It("getFieldManager returns a distinct field manager per ClusterProfile/Profile", func() {
+		clusterProfile := &configv1beta1.ClusterProfile{
+			TypeMeta:   metav1.TypeMeta{Kind: configv1beta1.ClusterProfileKind},
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+		}
+		Expect(controllers.GetFieldManager(clusterProfile)).To(Equal("sveltos-clusterprofile-" + clusterProfile.Name))
+
+		profile := &configv1beta1.Profile{
+			TypeMeta:   metav1.TypeMeta{Kind: configv1beta1.ProfileKind},
+			ObjectMeta: metav1.ObjectMeta{Name: clusterProfile.Name},
+		}
+		Expect(controllers.GetFieldManager(profile)).ToNot(Equal(controllers.GetFieldManager(clusterProfile)))
+
+		// Field manager cannot be longer than 128 characters
+		longName := strings.Repeat("a", 253)
+		clusterProfile.Name = longName
+		fieldManager := controllers.GetFieldManager(clusterProfile)
+		Expect(len(fieldManager)).To(Equal(128))
+		clusterProfile.Name = longName[:252] + "b"
+		Expect(controllers.GetFieldManager(clusterProfile)).ToNot(Equal(fieldManager))
+	})
+
+	It("isFieldManagerConflict returns true only for server-side apply conflicts", func() {
+		gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+		applyConflict := apierrors.NewApplyConflict([]metav1.StatusCause{
+			{
+				Type:    metav1.CauseTypeFieldManagerConflict,
+				Message: `conflict with "kube-controller-manager"`,
+				Field:   ".spec.replicas",
+			},
+		}, "Apply failed with 1 conflict")
+		Expect(controllers.IsFieldManagerConflict(applyConflict)).To(BeTrue())
+
+		Expect(controllers.IsFieldManagerConflict(apierrors.NewConflict(gr, randomString(),
+			errors.New("the object has been modified")))).To(BeFalse())
+		Expect(controllers.IsFieldManagerConflict(apierrors.NewNotFound(gr, randomString()))).To(BeFalse())
+	})
+
+	It("getSecret accepts ClusterResourceSet Secrets and getSecretDataSection splits JSON lists", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Type: addonsv1.ClusterResourceSetSecretType,
+			Data: map[string][]byte{
+				"resources": []byte(`[{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"foo"}},` +
+					`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"bar"}}]`),
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+		currentSecret, err := controllers.GetSecret(context.TODO(), c,
+			types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name})
+		Expect(err).To(BeNil())
+
+		section, err := controllers.GetSecretDataSection(currentSecret, currentSecret.Data["resources"])
+		Expect(err).To(BeNil())
+		elements, err := controllers.CustomSplit(section)
+		Expect(err).To(BeNil())
+		Expect(len(elements)).To(Equal(2))
+		for i, name := range []string{"foo", "bar"} {
+			policy, err := utils.GetUnstructured([]byte(elements[i]))
+			Expect(err).To(BeNil())
+			Expect(policy.GetName()).To(Equal(name))
+		}
+
+		opaque := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: secret.Namespace,
+				Name:      randomString(),
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+		Expect(c.Create(context.TODO(), opaque)).To(Succeed())
+		_, err = controllers.GetSecret(context.TODO(), c,
+			types.NamespacedName{Namespace: opaque.Namespace, Name: opaque.Name})
+		Expect(err).To(MatchError(libsveltosv1beta1.ErrSecretTypeNotSupported))
+	})
 })
 
 // validateResourceReports validates that number of resourceResources with certain actions
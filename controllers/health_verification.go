@@ -0,0 +1,153 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/verifier"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// When the health verifier is started, ValidateHealths checks are not run by the workers deploying
+// features. Once a feature is deployed, its health checks are queued to the health verifier, which has
+// its own pool of workers and rate limit, and the feature is reported as Provisioned only once those
+// checks pass. So slow health checks in one cluster do not consume deployment concurrency.
+// When the health verifier is not started, health checks are run right after deploying a feature.
+
+var (
+	healthVerifierMux sync.RWMutex
+	healthVerifier    *verifier.Verifier
+)
+
+// StartHealthVerifier starts the pool of workers verifying health of deployed features.
+func StartHealthVerifier(ctx context.Context, logger logr.Logger, options verifier.Options) {
+	healthVerifierMux.Lock()
+	defer healthVerifierMux.Unlock()
+
+	if healthVerifier != nil {
+		return
+	}
+
+	healthVerifier = verifier.New(ctx, logger, options)
+	registerWorkerPoolMetrics(verifyPool, healthVerifier.Stats, logger)
+}
+
+func getHealthVerifier() *verifier.Verifier {
+	healthVerifierMux.RLock()
+	defer healthVerifierMux.RUnlock()
+
+	return healthVerifier
+}
+
+// hasHealthChecks returns true if any ValidateHealths check is registered for featureID
+func hasHealthChecks(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID) bool {
+	for i := range clusterSummary.Spec.ClusterProfileSpec.ValidateHealths {
+		if clusterSummary.Spec.ClusterProfileSpec.ValidateHealths[i].FeatureID == featureID {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHealthPoliciesOnDeploy is invoked by the workers deploying features. Health checks are run only
+// if the health verifier is not started. Otherwise they are left to the health verifier.
+func validateHealthPoliciesOnDeploy(ctx context.Context, remoteConfig *rest.Config,
+	clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID, logger logr.Logger) error {
+
+	if getHealthVerifier() != nil {
+		return nil
+	}
+
+	return validateHealthPolicies(ctx, remoteConfig, clusterSummary, featureID, logger)
+}
+
+func getHealthVerificationKey(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID,
+	hash []byte) string {
+
+	return fmt.Sprintf("%s/%s:%s:%x", clusterSummary.Namespace, clusterSummary.Name, featureID, hash)
+}
+
+// verifyFeatureHealth combines the deployment status of a feature with the health verification status.
+// When deployment completes, health verification is queued and feature is reported as provisioning till
+// health checks are verified. status and statusError are the deployment status. Nil status means neither
+// deployment nor health verification results are available.
+func verifyFeatureHealth(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID,
+	hash []byte, status *configv1beta1.FeatureStatus, statusError error, logger logr.Logger,
+) (*configv1beta1.FeatureStatus, error) {
+
+	v := getHealthVerifier()
+	if v == nil || !hasHealthChecks(clusterSummary, featureID) ||
+		configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+
+		return status, statusError
+	}
+
+	key := getHealthVerificationKey(clusterSummary, featureID, hash)
+	provisioning := configv1beta1.FeatureStatusProvisioning
+
+	if status != nil {
+		if *status == configv1beta1.FeatureStatusProvisioned {
+			logger.V(logs.LogDebug).Info("feature deployed. Queueing health verification")
+			v.Verify(key, getHealthVerifyFunc(clusterSummary.DeepCopy(), featureID, logger))
+			return &provisioning, nil
+		}
+		return status, statusError
+	}
+
+	result := v.GetResult(key)
+	switch result.Status {
+	case verifier.InProgress:
+		logger.V(logs.LogDebug).Info("health verification in progress")
+		return &provisioning, nil
+	case verifier.Done:
+		if result.Err != nil {
+			failed := configv1beta1.FeatureStatusFailed
+			return &failed, result.Err
+		}
+		provisioned := configv1beta1.FeatureStatusProvisioned
+		return &provisioned, nil
+	default:
+		return nil, nil
+	}
+}
+
+func getHealthVerifyFunc(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID,
+	logger logr.Logger) verifier.VerifyFunc {
+
+	return func(ctx context.Context) error {
+		start := time.Now()
+		defer func() {
+			healthVerificationDurationHistogram.Observe(time.Since(start).Seconds())
+		}()
+
+		remoteRestConfig, err := getClusterSummaryRestConfig(ctx, getManagementClusterClient(),
+			clusterSummary, logger)
+		if err != nil {
+			return err
+		}
+
+		return validateHealthPolicies(ctx, remoteRestConfig, clusterSummary, featureID, logger)
+	}
+}
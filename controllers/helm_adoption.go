@@ -0,0 +1,130 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// A helm release might already exist in a managed cluster before any ClusterProfile/Profile
+// references it (installed out-of-band, for instance by the cluster provisioner).
+// Sveltos adds sveltosReleaseLabel to every release it installs or upgrades. Releases without
+// such label and not listed in the ClusterConfiguration were not installed by Sveltos.
+// Those are adopted when HelmChartAction is Manage. When HelmChartAction is Install, those are
+// adopted as well unless adoption is disallowed (--disallow-helm-release-adoption).
+
+const (
+	// sveltosReleaseLabel is the helm release label identifying releases installed/upgraded by Sveltos
+	sveltosReleaseLabel      = "projectsveltos.io/managed-by"
+	sveltosReleaseLabelValue = "sveltos"
+)
+
+var (
+	disallowHelmReleaseAdoption bool
+)
+
+// SetDisallowHelmReleaseAdoption when set to true prevents helm releases installed out-of-band
+// from being adopted unless HelmChartAction is set to Manage
+func SetDisallowHelmReleaseAdoption(disallow bool) {
+	disallowHelmReleaseAdoption = disallow
+}
+
+func getDisallowHelmReleaseAdoption() bool {
+	return disallowHelmReleaseAdoption
+}
+
+// getReleaseLabels returns the labels to set on the helm release. Those are the labels
+// requested in the HelmOptions and the label marking the release as installed by Sveltos.
+func getReleaseLabels(options *configv1beta1.HelmOptions) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range getLabelsValue(options) {
+		labels[k] = v
+	}
+	labels[sveltosReleaseLabel] = sveltosReleaseLabelValue
+	return labels
+}
+
+// isReleaseDeployedBySveltos returns true if the helm release was installed by Sveltos.
+// Releases installed before Sveltos started labeling releases are found in the ClusterConfiguration.
+func isReleaseDeployedBySveltos(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, currentRelease *releaseInfo) (bool, error) {
+
+	if currentRelease.ReleaseLabels[sveltosReleaseLabel] == sveltosReleaseLabelValue {
+		return true, nil
+	}
+
+	clusterConfiguration, err := getClusterConfiguration(ctx, c, clusterSummary.Spec.ClusterNamespace,
+		getClusterConfigurationName(clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	features := make([]configv1beta1.Feature, 0)
+	for i := range clusterConfiguration.Status.ClusterProfileResources {
+		features = append(features, clusterConfiguration.Status.ClusterProfileResources[i].Features...)
+	}
+	for i := range clusterConfiguration.Status.ProfileResources {
+		features = append(features, clusterConfiguration.Status.ProfileResources[i].Features...)
+	}
+
+	for i := range features {
+		if features[i].FeatureID != configv1beta1.FeatureHelm {
+			continue
+		}
+		for j := range features[i].Charts {
+			chart := &features[i].Charts[j]
+			if chart.Namespace == currentRelease.ReleaseNamespace && chart.ReleaseName == currentRelease.ReleaseName {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// checkReleaseAdoption returns an error if the helm release currently installed in the managed
+// cluster was not installed by Sveltos and cannot be adopted.
+func checkReleaseAdoption(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	currentChart *configv1beta1.HelmChart, currentRelease *releaseInfo, logger logr.Logger) error {
+
+	deployedBySveltos, err := isReleaseDeployedBySveltos(ctx, c, clusterSummary, currentRelease)
+	if err != nil {
+		return err
+	}
+	if deployedBySveltos {
+		return nil
+	}
+
+	if currentChart.HelmChartAction != configv1beta1.HelmChartActionManage && getDisallowHelmReleaseAdoption() {
+		return fmt.Errorf("helm release %s/%s was not installed by Sveltos. Set helmChartAction to %s to adopt it",
+			currentRelease.ReleaseNamespace, currentRelease.ReleaseName, configv1beta1.HelmChartActionManage)
+	}
+
+	logger.V(logs.LogInfo).Info("adopting helm release not installed by Sveltos")
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Helm release adoption", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+	var currentRelease *controllers.ReleaseInfo
+
+	BeforeEach(func() {
+		namespace := randomString()
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: namespace,
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: namespace,
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+			},
+		}
+
+		currentRelease = &controllers.ReleaseInfo{
+			ReleaseName:      randomString(),
+			ReleaseNamespace: randomString(),
+			ChartVersion:     "1.0.0",
+		}
+	})
+
+	AfterEach(func() {
+		controllers.SetDisallowHelmReleaseAdoption(false)
+	})
+
+	It("getReleaseLabels marks release as installed by Sveltos", func() {
+		options := &configv1beta1.HelmOptions{Labels: map[string]string{"team": "platform"}}
+		labels := controllers.GetReleaseLabels(options)
+		Expect(labels).To(HaveKeyWithValue("team", "platform"))
+		Expect(labels).To(HaveKeyWithValue("projectsveltos.io/managed-by", "sveltos"))
+		// Requested labels are not modified
+		Expect(options.Labels).To(HaveLen(1))
+
+		Expect(controllers.GetReleaseLabels(nil)).To(HaveLen(1))
+	})
+
+	It("isReleaseDeployedBySveltos uses release labels and ClusterConfiguration", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		deployed, err := controllers.IsReleaseDeployedBySveltos(context.TODO(), c, clusterSummary, currentRelease)
+		Expect(err).To(BeNil())
+		Expect(deployed).To(BeFalse())
+
+		currentRelease.ReleaseLabels = map[string]string{"projectsveltos.io/managed-by": "sveltos"}
+		deployed, err = controllers.IsReleaseDeployedBySveltos(context.TODO(), c, clusterSummary, currentRelease)
+		Expect(err).To(BeNil())
+		Expect(deployed).To(BeTrue())
+
+		// Release installed by Sveltos before releases were labeled
+		currentRelease.ReleaseLabels = nil
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterSummary.Spec.ClusterNamespace,
+				Name: controllers.GetClusterConfigurationName(clusterSummary.Spec.ClusterName,
+					clusterSummary.Spec.ClusterType),
+			},
+			Status: configv1beta1.ClusterConfigurationStatus{
+				ProfileResources: []configv1beta1.ProfileResource{
+					{
+						ProfileName: randomString(),
+						Features: []configv1beta1.Feature{
+							{
+								FeatureID: configv1beta1.FeatureHelm,
+								Charts: []configv1beta1.Chart{
+									{
+										RepoURL:      randomString(),
+										ReleaseName:  currentRelease.ReleaseName,
+										Namespace:    currentRelease.ReleaseNamespace,
+										ChartVersion: currentRelease.ChartVersion,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		initObjects := []client.Object{clusterConfiguration}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).
+			WithObjects(initObjects...).Build()
+
+		deployed, err = controllers.IsReleaseDeployedBySveltos(context.TODO(), c, clusterSummary, currentRelease)
+		Expect(err).To(BeNil())
+		Expect(deployed).To(BeTrue())
+	})
+
+	It("checkReleaseAdoption adopts releases unless adoption is disallowed", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		installChart := &configv1beta1.HelmChart{
+			ReleaseName:      currentRelease.ReleaseName,
+			ReleaseNamespace: currentRelease.ReleaseNamespace,
+			HelmChartAction:  configv1beta1.HelmChartActionInstall,
+		}
+		manageChart := installChart.DeepCopy()
+		manageChart.HelmChartAction = configv1beta1.HelmChartActionManage
+
+		Expect(controllers.CheckReleaseAdoption(context.TODO(), c, clusterSummary, installChart,
+			currentRelease, logger)).To(Succeed())
+		Expect(controllers.CheckReleaseAdoption(context.TODO(), c, clusterSummary, manageChart,
+			currentRelease, logger)).To(Succeed())
+
+		controllers.SetDisallowHelmReleaseAdoption(true)
+		Expect(controllers.CheckReleaseAdoption(context.TODO(), c, clusterSummary, installChart,
+			currentRelease, logger)).ToNot(Succeed())
+		Expect(controllers.CheckReleaseAdoption(context.TODO(), c, clusterSummary, manageChart,
+			currentRelease, logger)).To(Succeed())
+
+		// Releases installed by Sveltos are always managed
+		currentRelease.ReleaseLabels = map[string]string{"projectsveltos.io/managed-by": "sveltos"}
+		Expect(controllers.CheckReleaseAdoption(context.TODO(), c, clusterSummary, installChart,
+			currentRelease, logger)).To(Succeed())
+	})
+})
@@ -0,0 +1,564 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// In-flight remote operations (helm install/upgrade/uninstall, resources and kustomize applies) against
+// managed clusters must not be interrupted as soon as the controller receives SIGTERM, otherwise helm
+// releases are left in a pending state and all following operations on those releases fail, and
+// resources are only partially applied.
+// Remote operations run with a context which is not canceled on shutdown. On shutdown, the drainer
+// waits up to the configured grace period for those to complete and then aborts the remaining ones.
+// For each feature with an aborted operation, a checkpoint is persisted in a ConfigMap. Once elected,
+// the next leader reads (and removes) those checkpoints: interrupted features are redeployed right away,
+// without waiting for any backoff, and helm releases left pending by an aborted operation are recovered
+// immediately.
+// Releases left pending by a previous instance of the controller which could not persist a checkpoint
+// (for instance, it was killed) are recovered by recoverPendingRelease as well. Which operations are
+// in-flight is only known to the controller instance running them. So such a pending release is recovered
+// only once it has been pending (since helm release LastDeployed) for longer than the helm operation
+// could possibly take.
+
+const (
+	// defaultPendingReleaseTimeout is the time after which a pending release, whose HelmOptions do not
+	// set a timeout, is considered left pending by an interrupted helm operation
+	defaultPendingReleaseTimeout = 15 * time.Minute
+
+	operationCheckpointsNamespace     = "projectsveltos"
+	operationCheckpointsConfigMapName = "addon-controller-drain-checkpoints"
+
+	// operationCheckpointsTimeout bounds the time spent persisting checkpoints on shutdown
+	operationCheckpointsTimeout = 3 * time.Second
+)
+
+var (
+	// errShuttingDown is returned when a new remote operation is requested while controller is draining
+	errShuttingDown = errors.New("controller is shutting down. Operation not started")
+
+	shutdownGracePeriod time.Duration
+
+	remoteOperationsMux sync.Mutex
+	remoteOperations    = map[string]*remoteOperation{} // key: getHelmOperationKey or getFeatureOperationKey
+	remoteOperationsWG  sync.WaitGroup
+	draining            bool
+
+	// interruptedFeatures and interruptedReleases contain the features (getFeatureOperationKey) and
+	// helm releases (getHelmOperationKey) whose operation was aborted by a previous controller instance.
+	// Guarded by remoteOperationsMux.
+	interruptedFeatures = map[string]bool{}
+	interruptedReleases = map[string]bool{}
+
+	// abortCtx is canceled once grace period expires. All in-flight remote operations are then aborted.
+	abortCtx, abortRemoteOperations = context.WithCancel(context.Background())
+)
+
+// remoteOperation is an operation in-flight against a managed cluster
+type remoteOperation struct {
+	clusterSummaryNamespace string
+	clusterSummaryName      string
+	featureID               configv1beta1.FeatureID
+	// releaseKey is the getHelmOperationKey of the helm release. Empty for features other than helm.
+	releaseKey string
+	operation  string
+}
+
+// operationCheckpoint records that a feature of a ClusterSummary was being deployed when the controller
+// shut down and the operations which had to be aborted
+type operationCheckpoint struct {
+	ClusterSummaryNamespace string                  `json:"clusterSummaryNamespace"`
+	ClusterSummaryName      string                  `json:"clusterSummaryName"`
+	FeatureID               configv1beta1.FeatureID `json:"featureID"`
+
+	// Releases contains the getHelmOperationKey of the helm releases whose operation was aborted
+	Releases []string `json:"releases,omitempty"`
+
+	// Time is when the operations were aborted
+	Time metav1.Time `json:"time"`
+}
+
+// SetShutdownGracePeriod sets the maximum time in-flight remote operations are given to complete
+// once controller is asked to shut down.
+func SetShutdownGracePeriod(d time.Duration) {
+	shutdownGracePeriod = d
+}
+
+// getHelmOperationKey returns the key identifying a helm release in the ClusterSummary's cluster.
+// Releases with same namespace/name in different clusters are different releases.
+func getHelmOperationKey(clusterSummary *configv1beta1.ClusterSummary, releaseNamespace, releaseName string) string {
+	return getReleaseLockKey(clusterSummary, releaseNamespace, releaseName)
+}
+
+// getFeatureOperationKey returns the key identifying a feature of a ClusterSummary
+func getFeatureOperationKey(clusterSummaryNamespace, clusterSummaryName string,
+	featureID configv1beta1.FeatureID) string {
+
+	return fmt.Sprintf("%s/%s:%s", clusterSummaryNamespace, clusterSummaryName, featureID)
+}
+
+// trackRemoteOperation registers an in-flight remote operation. Returned context is not canceled when
+// ctx is, but only when the shutdown grace period expires. The returned function must be invoked once
+// the operation completes.
+func trackRemoteOperation(ctx context.Context, key string, op *remoteOperation) (context.Context, func(), error) {
+	remoteOperationsMux.Lock()
+	defer remoteOperationsMux.Unlock()
+
+	if draining {
+		return nil, nil, errShuttingDown
+	}
+
+	remoteOperations[key] = op
+	remoteOperationsWG.Add(1)
+
+	opCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(abortCtx, cancel)
+
+	done := func() {
+		aborted := opCtx.Err() != nil
+		stop()
+		cancel()
+
+		remoteOperationsMux.Lock()
+		if remoteOperations[key] == op {
+			delete(remoteOperations, key)
+		}
+		// Operation completed: release is not pending because of the aborted one anymore
+		if !aborted && op.releaseKey != "" {
+			delete(interruptedReleases, op.releaseKey)
+		}
+		remoteOperationsMux.Unlock()
+		remoteOperationsWG.Done()
+	}
+
+	return opCtx, done, nil
+}
+
+// trackHelmOperation registers an in-flight helm operation. See trackRemoteOperation.
+func trackHelmOperation(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	releaseNamespace, releaseName, operation string) (context.Context, func(), error) {
+
+	key := getHelmOperationKey(clusterSummary, releaseNamespace, releaseName)
+	return trackRemoteOperation(ctx, key, &remoteOperation{
+		clusterSummaryNamespace: clusterSummary.Namespace,
+		clusterSummaryName:      clusterSummary.Name,
+		featureID:               configv1beta1.FeatureHelm,
+		releaseKey:              key,
+		operation:               operation,
+	})
+}
+
+// trackFeatureOperation registers an in-flight apply of the resources of a feature other than helm.
+// See trackRemoteOperation.
+func trackFeatureOperation(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	featureID configv1beta1.FeatureID, operation string) (context.Context, func(), error) {
+
+	return trackRemoteOperation(ctx,
+		getFeatureOperationKey(clusterSummary.Namespace, clusterSummary.Name, featureID),
+		&remoteOperation{
+			clusterSummaryNamespace: clusterSummary.Namespace,
+			clusterSummaryName:      clusterSummary.Name,
+			featureID:               featureID,
+			operation:               operation,
+		})
+}
+
+// isHelmOperationInProgress returns true if this controller instance is currently
+// running a helm operation for the release in the ClusterSummary's cluster
+func isHelmOperationInProgress(clusterSummary *configv1beta1.ClusterSummary,
+	releaseNamespace, releaseName string) bool {
+
+	remoteOperationsMux.Lock()
+	defer remoteOperationsMux.Unlock()
+
+	_, ok := remoteOperations[getHelmOperationKey(clusterSummary, releaseNamespace, releaseName)]
+	return ok
+}
+
+// takeInterruptedFeature returns true if a previous controller instance aborted the deployment of
+// the ClusterSummary feature when shutting down. It returns true only once.
+func takeInterruptedFeature(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID) bool {
+	remoteOperationsMux.Lock()
+	defer remoteOperationsMux.Unlock()
+
+	key := getFeatureOperationKey(clusterSummary.Namespace, clusterSummary.Name, featureID)
+	interrupted := interruptedFeatures[key]
+	delete(interruptedFeatures, key)
+	return interrupted
+}
+
+// isReleaseInterrupted returns true if a previous controller instance aborted an operation on the
+// release in the ClusterSummary's cluster when shutting down, and no operation completed on it since
+func isReleaseInterrupted(clusterSummary *configv1beta1.ClusterSummary, releaseNamespace, releaseName string) bool {
+	remoteOperationsMux.Lock()
+	defer remoteOperationsMux.Unlock()
+
+	return interruptedReleases[getHelmOperationKey(clusterSummary, releaseNamespace, releaseName)]
+}
+
+// forgetInterruptedRelease records the release in the ClusterSummary's cluster is not pending
+// because of an aborted operation anymore
+func forgetInterruptedRelease(clusterSummary *configv1beta1.ClusterSummary, releaseNamespace, releaseName string) {
+	remoteOperationsMux.Lock()
+	defer remoteOperationsMux.Unlock()
+
+	delete(interruptedReleases, getHelmOperationKey(clusterSummary, releaseNamespace, releaseName))
+}
+
+// getOperationCheckpointsName returns the name of the ConfigMap checkpoints of the controller
+// instances managing shardKey are persisted to
+func getOperationCheckpointsName(shardKey string) string {
+	if shardKey == "" {
+		return operationCheckpointsConfigMapName
+	}
+
+	// ShardKey might contain characters not valid in a ConfigMap name
+	const hashLength = 8
+	hash := sha256.Sum256([]byte(shardKey))
+	return fmt.Sprintf("%s-%x", operationCheckpointsConfigMapName, hash[:hashLength])
+}
+
+// RemoteOperationsDrainer is a manager Runnable which, on shutdown, waits for in-flight
+// remote operations to complete and persists checkpoints of the ones it has to abort.
+type RemoteOperationsDrainer struct {
+	mgr           ctrl.Manager
+	configMapName string
+	logger        logr.Logger
+}
+
+// NewRemoteOperationsDrainer returns a RemoteOperationsDrainer. When manager is stopped, it drains
+// in-flight remote operations. Operations still running after the grace period set with
+// SetShutdownGracePeriod are aborted.
+func NewRemoteOperationsDrainer(mgr ctrl.Manager, shardKey string, logger logr.Logger) *RemoteOperationsDrainer {
+	return &RemoteOperationsDrainer{mgr: mgr, configMapName: getOperationCheckpointsName(shardKey), logger: logger}
+}
+
+// NeedLeaderElection returns false so that drainer runs on every replica
+func (d *RemoteOperationsDrainer) NeedLeaderElection() bool {
+	return false
+}
+
+func (d *RemoteOperationsDrainer) Start(ctx context.Context) error {
+	<-ctx.Done()
+	checkpoints := drainRemoteOperations(shutdownGracePeriod, d.logger)
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	// Manager context is canceled already
+	storeCtx, cancel := context.WithTimeout(context.Background(), operationCheckpointsTimeout)
+	defer cancel()
+	if err := storeOperationCheckpoints(storeCtx, d.mgr.GetClient(), d.configMapName, checkpoints); err != nil {
+		d.logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to persist checkpoints of aborted operations: %v", err))
+	}
+	return nil
+}
+
+// OperationCheckpointsLoader is a manager Runnable which, once elected, reads the checkpoints
+// persisted by the RemoteOperationsDrainer of the previous leader.
+type OperationCheckpointsLoader struct {
+	mgr           ctrl.Manager
+	configMapName string
+	logger        logr.Logger
+}
+
+// NewOperationCheckpointsLoader returns an OperationCheckpointsLoader
+func NewOperationCheckpointsLoader(mgr ctrl.Manager, shardKey string, logger logr.Logger) *OperationCheckpointsLoader {
+	return &OperationCheckpointsLoader{mgr: mgr, configMapName: getOperationCheckpointsName(shardKey), logger: logger}
+}
+
+// NeedLeaderElection returns true so that checkpoints are read by the leader, once the previous
+// one persisted those
+func (l *OperationCheckpointsLoader) NeedLeaderElection() bool {
+	return true
+}
+
+func (l *OperationCheckpointsLoader) Start(ctx context.Context) error {
+	// Failing to read checkpoints is not fatal: pending releases are recovered once stale anyway
+	err := loadOperationCheckpoints(ctx, l.mgr.GetAPIReader(), l.mgr.GetClient(), l.configMapName, l.logger)
+	if err != nil {
+		l.logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to load checkpoints of aborted operations: %v", err))
+	}
+	return nil
+}
+
+// drainRemoteOperations prevents new remote operations from starting and waits up to gracePeriod
+// for the in-flight ones to complete. Remaining operations are then aborted. Returns, per feature,
+// the checkpoint of the aborted operations.
+func drainRemoteOperations(gracePeriod time.Duration, logger logr.Logger) []operationCheckpoint {
+	remoteOperationsMux.Lock()
+	draining = true
+	for k, op := range remoteOperations {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("waiting for %s %s of %s to complete", op.featureID, op.operation, k))
+	}
+	remoteOperationsMux.Unlock()
+
+	completed := make(chan struct{})
+	go func() {
+		remoteOperationsWG.Wait()
+		close(completed)
+	}()
+
+	select {
+	case <-completed:
+		logger.V(logs.LogInfo).Info("all in-flight remote operations completed")
+		return nil
+	case <-time.After(gracePeriod):
+	}
+
+	remoteOperationsMux.Lock()
+	for k, op := range remoteOperations {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("aborting %s %s of %s. It will be resumed on restart",
+			op.featureID, op.operation, k))
+	}
+	checkpoints := getOperationCheckpoints(remoteOperations, time.Now())
+	remoteOperationsMux.Unlock()
+
+	abortRemoteOperations()
+
+	// Give aborted operations a chance to return
+	const abortTimeout = 5 * time.Second
+	select {
+	case <-completed:
+	case <-time.After(abortTimeout):
+	}
+
+	return checkpoints
+}
+
+// getOperationCheckpoints groups operations per ClusterSummary feature
+func getOperationCheckpoints(operations map[string]*remoteOperation, now time.Time) []operationCheckpoint {
+	perFeature := map[string]*operationCheckpoint{}
+	for _, op := range operations {
+		key := getFeatureOperationKey(op.clusterSummaryNamespace, op.clusterSummaryName, op.featureID)
+		checkpoint, ok := perFeature[key]
+		if !ok {
+			checkpoint = &operationCheckpoint{
+				ClusterSummaryNamespace: op.clusterSummaryNamespace,
+				ClusterSummaryName:      op.clusterSummaryName,
+				FeatureID:               op.featureID,
+				Time:                    metav1.NewTime(now),
+			}
+			perFeature[key] = checkpoint
+		}
+		if op.releaseKey != "" {
+			checkpoint.Releases = append(checkpoint.Releases, op.releaseKey)
+		}
+	}
+
+	checkpoints := make([]operationCheckpoint, 0, len(perFeature))
+	for _, checkpoint := range perFeature {
+		sort.Strings(checkpoint.Releases)
+		checkpoints = append(checkpoints, *checkpoint)
+	}
+	return checkpoints
+}
+
+// getOperationCheckpointKey returns the ConfigMap key of the checkpoint of a feature.
+// Names might contain characters not valid in a ConfigMap key.
+func getOperationCheckpointKey(checkpoint *operationCheckpoint) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(getFeatureOperationKey(checkpoint.ClusterSummaryNamespace,
+		checkpoint.ClusterSummaryName, checkpoint.FeatureID))))
+}
+
+// storeOperationCheckpoints persists checkpoints, one key per feature, in the ConfigMap configMapName.
+// Checkpoints previously persisted there are replaced.
+func storeOperationCheckpoints(ctx context.Context, c client.Client, configMapName string,
+	checkpoints []operationCheckpoint) error {
+
+	data := make(map[string]string, len(checkpoints))
+	for i := range checkpoints {
+		value, err := json.Marshal(&checkpoints[i])
+		if err != nil {
+			return err
+		}
+		data[getOperationCheckpointKey(&checkpoints[i])] = string(value)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: operationCheckpointsNamespace, Name: configMapName}, configMap)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: operationCheckpointsNamespace,
+				Name:      configMapName,
+			},
+			Data: data,
+		}
+		return c.Create(ctx, configMap)
+	}
+
+	configMap.Data = data
+	return c.Update(ctx, configMap)
+}
+
+// loadOperationCheckpoints reads, and then removes, the checkpoints persisted in the ConfigMap configMapName.
+// Features and helm releases those contain are then considered interrupted.
+func loadOperationCheckpoints(ctx context.Context, reader client.Reader, c client.Client, configMapName string,
+	logger logr.Logger) error {
+
+	configMap := &corev1.ConfigMap{}
+	err := reader.Get(ctx, types.NamespacedName{Namespace: operationCheckpointsNamespace, Name: configMapName},
+		configMap)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	remoteOperationsMux.Lock()
+	for key, value := range configMap.Data {
+		checkpoint := &operationCheckpoint{}
+		if err := json.Unmarshal([]byte(value), checkpoint); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("ignoring invalid checkpoint %s: %v", key, err))
+			continue
+		}
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("%s of ClusterSummary %s/%s was interrupted at %s",
+			checkpoint.FeatureID, checkpoint.ClusterSummaryNamespace, checkpoint.ClusterSummaryName,
+			checkpoint.Time.UTC().Format(time.RFC3339)))
+		interruptedFeatures[getFeatureOperationKey(checkpoint.ClusterSummaryNamespace,
+			checkpoint.ClusterSummaryName, checkpoint.FeatureID)] = true
+		for _, releaseKey := range checkpoint.Releases {
+			interruptedReleases[releaseKey] = true
+		}
+	}
+	remoteOperationsMux.Unlock()
+
+	if len(configMap.Data) == 0 {
+		return nil
+	}
+
+	// Checkpoints are read only once
+	configMap.Data = nil
+	return c.Update(ctx, configMap)
+}
+
+// isReleasePending returns true if release is in any of the pending states
+func isReleasePending(currentRelease *releaseInfo) bool {
+	if currentRelease == nil {
+		return false
+	}
+
+	return currentRelease.Status == release.StatusPendingInstall.String() ||
+		currentRelease.Status == release.StatusPendingUpgrade.String() ||
+		currentRelease.Status == release.StatusPendingRollback.String()
+}
+
+// getPendingReleaseTimeout returns the time after which a release pending because of requestedChart
+// is considered left pending by an interrupted helm operation
+func getPendingReleaseTimeout(requestedChart *configv1beta1.HelmChart) time.Duration {
+	timeout := defaultPendingReleaseTimeout
+	if t := getTimeoutValue(requestedChart.Options); t != nil && 2*t.Duration > timeout {
+		timeout = 2 * t.Duration
+	}
+	return timeout
+}
+
+// isPendingReleaseStale returns true if currentRelease has been pending for longer than any helm
+// operation on it could take. Releases with no last deployed time are considered stale.
+func isPendingReleaseStale(currentRelease *releaseInfo, requestedChart *configv1beta1.HelmChart,
+	now time.Time) bool {
+
+	if currentRelease.Updated.IsZero() {
+		return true
+	}
+	return now.Sub(currentRelease.Updated.Time) > getPendingReleaseTimeout(requestedChart)
+}
+
+// recoverPendingRelease handles releases left in a pending state by an interrupted helm operation
+// (for instance, controller was killed). A release pending its first install is uninstalled, any other
+// pending release is rolled back to the previous revision. Following reconciliation will then
+// install/upgrade release as requested. A release pending for less than getPendingReleaseTimeout, and whose
+// operation was not aborted on shutdown, might still be deployed by another controller instance: it is left
+// untouched and an error is returned.
+// Returns true if a recovery action was taken.
+func recoverPendingRelease(clusterSummary *configv1beta1.ClusterSummary, currentRelease *releaseInfo,
+	requestedChart *configv1beta1.HelmChart, kubeconfig string, registryOptions *registryClientOptions,
+	logger logr.Logger) (bool, error) {
+
+	if !isReleasePending(currentRelease) {
+		return false, nil
+	}
+
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+		return false, nil
+	}
+
+	// Operation is currently in progress. Release is not stuck.
+	if isHelmOperationInProgress(clusterSummary, currentRelease.ReleaseNamespace, currentRelease.ReleaseName) {
+		return false, nil
+	}
+
+	logger = logger.WithValues("release", currentRelease.ReleaseName, "releaseNamespace",
+		currentRelease.ReleaseNamespace, "status", currentRelease.Status)
+
+	// Operation aborted by a previous controller instance on shutdown is not going to complete. Otherwise
+	// operation might be in progress in another controller instance (for instance the previous leader).
+	interrupted := isReleaseInterrupted(clusterSummary, currentRelease.ReleaseNamespace, currentRelease.ReleaseName)
+	if !interrupted && !isPendingReleaseStale(currentRelease, requestedChart, time.Now()) {
+		logger.V(logs.LogDebug).Info("release is pending. Waiting for helm operation to complete")
+		return false, fmt.Errorf("release %s/%s is %s since %s", currentRelease.ReleaseNamespace,
+			currentRelease.ReleaseName, currentRelease.Status, currentRelease.Updated.UTC().Format(time.RFC3339))
+	}
+
+	if currentRelease.Status == release.StatusPendingInstall.String() && currentRelease.Revision == "1" {
+		logger.V(logs.LogInfo).Info("uninstalling release left pending by an interrupted install")
+		return true, uninstallRelease(clusterSummary, currentRelease.ReleaseName, currentRelease.ReleaseNamespace,
+			kubeconfig, registryOptions, requestedChart, logger)
+	}
+
+	logger.V(logs.LogInfo).Info("rolling back release left pending by an interrupted operation")
+	actionConfig, err := actionConfigInit(currentRelease.ReleaseNamespace, kubeconfig, registryOptions,
+		getEnableClientCacheValue(requestedChart.Options))
+	if err != nil {
+		return false, err
+	}
+
+	rollbackClient := action.NewRollback(actionConfig)
+	rollbackClient.Version = 0 // previous revision
+	if err := rollbackClient.Run(currentRelease.ReleaseName); err != nil {
+		return true, err
+	}
+
+	forgetInterruptedRelease(clusterSummary, currentRelease.ReleaseNamespace, currentRelease.ReleaseName)
+	return true, nil
+}
@@ -0,0 +1,397 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// helmServerDryRun instructs helm to validate the rendered manifest against the
+	// managed cluster API server without persisting anything.
+	helmServerDryRun = "server"
+
+	// maxDiffLines limits the number of lines a diff can contain. Diff is truncated
+	// to keep ClusterReport size under control
+	maxDiffLines = 500
+
+	// maxDiffEditDistance limits the number of added plus removed lines a minimal diff is computed for.
+	// Memory and time needed are proportional to it, not to the size of the manifests.
+	maxDiffEditDistance = 2 * maxDiffLines
+)
+
+// addDryRunDiffToReport renders requested helm chart (helm template + server-side dry-run) and
+// attaches to the report the diff with the helm release currently deployed in the managed cluster.
+// Failing to render the chart is not considered an error. The report will contain a message instead.
+func addDryRunDiffToReport(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	mgmtResources map[string]*unstructured.Unstructured, requestedChart *configv1beta1.HelmChart,
+	kubeconfig string, registryOptions *registryClientOptions, report *configv1beta1.ReleaseReport,
+	logger logr.Logger) {
+
+	if !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+		return
+	}
+
+	manifestDiff, valuesDiff, err := getHelmDryRunDiff(ctx, clusterSummary, mgmtResources, requestedChart,
+		kubeconfig, registryOptions, logger)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to evaluate helm dry-run diff: %v", err))
+		msg := fmt.Sprintf("failed to evaluate diff: %v", err)
+		if report.Message != "" {
+			msg = report.Message + ". " + msg
+		}
+		report.Message = msg
+		return
+	}
+
+	report.ManifestDiff = manifestDiff
+	report.ValuesDiff = valuesDiff
+	assessReleaseReport(clusterSummary, report)
+}
+
+// getHelmDryRunDiff returns the diff between the manifest/values of the helm release currently deployed
+// in the managed cluster and the manifest/values which would be deployed using requestedChart.
+func getHelmDryRunDiff(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	mgmtResources map[string]*unstructured.Unstructured, requestedChart *configv1beta1.HelmChart,
+	kubeconfig string, registryOptions *registryClientOptions, logger logr.Logger,
+) (manifestDiff, valuesDiff string, err error) {
+
+	settings := getSettings(requestedChart.ReleaseNamespace, registryOptions)
+
+	err = repoAddOrUpdate(settings, requestedChart.RepositoryName, requestedChart.RepositoryURL, logger)
+	if err != nil {
+		return "", "", err
+	}
+
+	values, err := getInstantiatedValues(ctx, clusterSummary, mgmtResources, requestedChart, logger)
+	if err != nil {
+		return "", "", err
+	}
+
+	chartName, _, err := getHelmChartAndRepoName(requestedChart.ChartName, requestedChart.RepositoryURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	actionConfig, err := actionConfigInit(requestedChart.ReleaseNamespace, kubeconfig, registryOptions,
+		getEnableClientCacheValue(requestedChart.Options))
+	if err != nil {
+		return "", "", err
+	}
+
+	patches, err := initiatePatches(ctx, clusterSummary, requestedChart.ChartName, mgmtResources, logger)
+	if err != nil {
+		return "", "", err
+	}
+
+	mirrors, err := getRegistryMirrors(ctx, getManagementClusterClient(), clusterSummary)
+	if err != nil {
+		return "", "", err
+	}
+
+	var currentRelease *release.Release
+	currentRelease, err = action.NewGet(actionConfig).Run(requestedChart.ReleaseName)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return "", "", err
+	}
+
+	var renderedRelease *release.Release
+	if currentRelease == nil {
+		installClient, err := getHelmInstallClient(requestedChart, kubeconfig, registryOptions, patches, mirrors)
+		if err != nil {
+			return "", "", err
+		}
+		installClient.DryRun = true
+		installClient.DryRunOption = helmServerDryRun
+
+		cp, releaseChart, err := locateChart(&installClient.ChartPathOptions, chartName, settings)
+		if err != nil {
+			return "", "", err
+		}
+		defer releaseChart()
+		chartRequested, err := loader.Load(cp)
+		if err != nil {
+			return "", "", err
+		}
+		renderedRelease, err = installClient.RunWithContext(ctx, chartRequested, values)
+		if err != nil {
+			return "", "", err
+		}
+	} else {
+		upgradeClient, err := getHelmUpgradeClient(requestedChart, actionConfig, patches, mirrors)
+		if err != nil {
+			return "", "", err
+		}
+		upgradeClient.DryRun = true
+		upgradeClient.DryRunOption = helmServerDryRun
+
+		cp, releaseChart, err := locateChart(&upgradeClient.ChartPathOptions, chartName, settings)
+		if err != nil {
+			return "", "", err
+		}
+		defer releaseChart()
+		chartRequested, err := loader.Load(cp)
+		if err != nil {
+			return "", "", err
+		}
+		renderedRelease, err = upgradeClient.RunWithContext(ctx, requestedChart.ReleaseName, chartRequested, values)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	currentManifest := ""
+	currentValues := map[string]interface{}{}
+	if currentRelease != nil {
+		currentManifest = currentRelease.Manifest
+		currentValues = currentRelease.Config
+	}
+
+	// ClusterReports can be read by users not allowed to read the Secrets values come from.
+	// Values diff only contains sanitized values.
+	secretValuesPaths, err := getSecretValuesPaths(ctx, clusterSummary, mgmtResources, requestedChart, logger)
+	if err != nil {
+		return "", "", err
+	}
+
+	manifestDiff = getTextDiff(currentManifest, renderedRelease.Manifest)
+	valuesDiff = getTextDiff(getSanitizedChartValues(currentValues, secretValuesPaths),
+		getSanitizedChartValues(values, secretValuesPaths))
+	return manifestDiff, valuesDiff, nil
+}
+
+// getSecretValuesPaths returns the paths (see flattenChartValues) of the values requestedChart
+// gets from Secrets (ValuesFrom)
+func getSecretValuesPaths(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	mgmtResources map[string]*unstructured.Unstructured, requestedChart *configv1beta1.HelmChart,
+	logger logr.Logger) (map[string]bool, error) {
+
+	c := getManagementClusterClient()
+	paths := make(map[string]bool)
+	for i := range requestedChart.ValuesFrom {
+		valueFrom := &requestedChart.ValuesFrom[i]
+		if valueFrom.Kind != string(libsveltosv1beta1.SecretReferencedResourceKind) {
+			continue
+		}
+
+		data, isTemplate, err := getValuesFromResource(ctx, c, clusterSummary, valueFrom, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		for k := range data {
+			content := data[k]
+			if isTemplate {
+				content, err = instantiateTemplateValues(ctx, getManagementClusterConfig(), c,
+					clusterSummary.Spec.ClusterType, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+					requestedChart.ChartName, content, mgmtResources, logger)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			secretValues, err := chartutil.ReadValues([]byte(content))
+			if err != nil {
+				return nil, err
+			}
+
+			flattened := make(map[string]string)
+			flattenChartValues("", secretValues.AsMap(), false, flattened)
+			for path := range flattened {
+				paths[path] = true
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// getTextDiff returns a line based diff between from and to.
+// Removed lines are prefixed with "-", added lines with "+". Unchanged lines are omitted.
+// Returns an empty string if there is no difference.
+func getTextDiff(from, to string) string {
+	if from == to {
+		return ""
+	}
+
+	fromLines := splitLines(from)
+	toLines := splitLines(to)
+
+	// Skip common prefix and suffix
+	start := 0
+	for start < len(fromLines) && start < len(toLines) && fromLines[start] == toLines[start] {
+		start++
+	}
+	fromEnd, toEnd := len(fromLines), len(toLines)
+	for fromEnd > start && toEnd > start && fromLines[fromEnd-1] == toLines[toEnd-1] {
+		fromEnd--
+		toEnd--
+	}
+	fromLines = fromLines[start:fromEnd]
+	toLines = toLines[start:toEnd]
+
+	diff, ok := myersDiff(fromLines, toLines, maxDiffEditDistance)
+	if !ok {
+		// Too many differences. Report all lines as replaced rather than computing a minimal diff.
+		diff = make([]string, 0, min(len(fromLines)+len(toLines), maxDiffLines))
+		for i := 0; i < len(toLines) && len(diff) < maxDiffLines; i++ {
+			diff = append(diff, "+"+toLines[i])
+		}
+		for i := 0; i < len(fromLines) && len(diff) < maxDiffLines; i++ {
+			diff = append(diff, "-"+fromLines[i])
+		}
+		if truncated := len(fromLines) + len(toLines) - len(diff); truncated > 0 {
+			diff = append(diff, fmt.Sprintf("... (%d more lines)", truncated))
+		}
+		return strings.Join(diff, "\n")
+	}
+
+	if len(diff) > maxDiffLines {
+		truncated := len(diff) - maxDiffLines
+		diff = append(diff[:maxDiffLines], fmt.Sprintf("... (%d more lines)", truncated))
+	}
+
+	return strings.Join(diff, "\n")
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// myersDiff returns the lines added to (prefixed with "+") and removed from (prefixed with "-") a
+// to obtain b, using Myers' algorithm. Within each block of changes, added lines come first.
+// Returns false if more than maxEdits lines would be added or removed. Memory used is
+// O(maxEdits^2) regardless of the length of a and b.
+func myersDiff(a, b []string, maxEdits int) ([]string, bool) {
+	n, m := len(a), len(b)
+	offset := maxEdits + 1
+	v := make([]int, 2*maxEdits+3)
+
+	// trace[d][k+d] is the furthest x reached on diagonal k (x - y) with d edits
+	trace := make([][]int, 0)
+	for d := 0; d <= maxEdits; d++ {
+		done := false
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		if done {
+			return myersBacktrack(a, b, trace), true
+		}
+	}
+
+	return nil, false
+}
+
+// myersBacktrack walks trace backward, from the end of a and b, collecting the edits
+func myersBacktrack(a, b []string, trace [][]int) []string {
+	type edit struct {
+		added bool
+		line  string
+	}
+
+	edits := make([]edit, 0, len(trace))
+	// blockEnds marks edits (in reverse order) followed, in forward order, by an unchanged line
+	blockEnds := make([]bool, 0, len(trace))
+
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		previous := trace[d-1]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && previous[k-1+d-1] < previous[k+1+d-1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := previous[prevK+d-1]
+		prevY := prevX - prevK
+
+		snake := false
+		for x > prevX && y > prevY {
+			x--
+			y--
+			snake = true
+		}
+		if x == prevX {
+			edits = append(edits, edit{added: true, line: b[prevY]})
+		} else {
+			edits = append(edits, edit{added: false, line: a[prevX]})
+		}
+		blockEnds = append(blockEnds, snake)
+		x, y = prevX, prevY
+	}
+
+	// Edits are in reverse order. Emit each block of contiguous changes with added lines first.
+	diff := make([]string, 0, len(edits))
+	added, removed := make([]string, 0), make([]string, 0)
+	flush := func() {
+		diff = append(diff, added...)
+		diff = append(diff, removed...)
+		added, removed = added[:0], removed[:0]
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		if edits[i].added {
+			added = append(added, "+"+edits[i].line)
+		} else {
+			removed = append(removed, "-"+edits[i].line)
+		}
+		if blockEnds[i] {
+			flush()
+		}
+	}
+	flush()
+
+	return diff
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sync"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// By default helm charts referenced by a ClusterSummary are deployed one after the other, in the
+// order they are listed. With many charts, rollout time in each cluster is the sum of the time
+// taken by each chart. When --helm-max-concurrent-releases is higher than one, up to that many
+// charts of a ClusterSummary are installed/upgraded concurrently. Charts depending on each other
+// being deployed in order (for instance a chart installing CRDs used by another one) require
+// leaving it to one.
+// Any operation on a helm release (install, upgrade, uninstall) holds a per-release lock, so
+// operations on the same release in the same cluster are always serialized.
+
+const (
+	defaultHelmMaxConcurrentReleases = 1
+)
+
+var (
+	helmMaxConcurrentReleases = defaultHelmMaxConcurrentReleases
+
+	// releaseLocks contains, per helm release (getReleaseLockKey), the lock held while operating on it.
+	// Locks are removed once not held anymore, so releases of deleted clusters/profiles do not accumulate.
+	releaseLocks = newKeyedMutex()
+)
+
+// SetHelmMaxConcurrentReleases sets the maximum number of helm charts of a ClusterSummary
+// deployed concurrently. Values lower than one are treated as one.
+func SetHelmMaxConcurrentReleases(n int) {
+	if n < 1 {
+		n = 1
+	}
+	helmMaxConcurrentReleases = n
+}
+
+func getHelmMaxConcurrentReleases() int {
+	return helmMaxConcurrentReleases
+}
+
+// getReleaseLockKey returns the key identifying a helm release in the ClusterSummary's cluster
+func getReleaseLockKey(clusterSummary *configv1beta1.ClusterSummary, releaseNamespace, releaseName string) string {
+	return fmt.Sprintf("%s:%s/%s:%s/%s", clusterSummary.Spec.ClusterType, clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, releaseNamespace, releaseName)
+}
+
+// lockRelease acquires the lock of the helm release in the ClusterSummary's cluster.
+// Returns the function releasing it.
+func lockRelease(clusterSummary *configv1beta1.ClusterSummary, releaseNamespace, releaseName string) func() {
+	return releaseLocks.lock(getReleaseLockKey(clusterSummary, releaseNamespace, releaseName))
+}
+
+// runBounded invokes process for each index in [0, count) using at most workers goroutines.
+// Once process fails, no other index is started; indexes already started are waited for.
+// Returns, per index, the error process returned. Indexes never started have a nil error and
+// are reported in started as false.
+// With a single worker indexes are processed in order, stopping at the first failure.
+func runBounded(count, workers int, process func(i int) error) (errs []error, started []bool) {
+	errs = make([]error, count)
+	started = make([]bool, count)
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mux sync.Mutex
+	next := 0
+	failed := false
+	// getNext returns the next index to process, or -1 if none is left or a failure happened
+	getNext := func() int {
+		mux.Lock()
+		defer mux.Unlock()
+		if failed || next >= count {
+			return -1
+		}
+		i := next
+		next++
+		started[i] = true
+		return i
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < count; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := getNext(); i != -1; i = getNext() {
+				if err := process(i); err != nil {
+					mux.Lock()
+					errs[i] = err
+					failed = true
+					mux.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errs, started
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Helm parallel deployment", func() {
+	It("runBounded processes all indexes with at most the requested number of workers", func() {
+		const count = 10
+		const workers = 3
+
+		var running, maxRunning int32
+		processed := make([]bool, count)
+		errs, started := controllers.RunBounded(count, workers, func(i int) error {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				previous := atomic.LoadInt32(&maxRunning)
+				if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			processed[i] = true
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+
+		Expect(atomic.LoadInt32(&maxRunning)).To(BeNumerically("<=", workers))
+		Expect(atomic.LoadInt32(&maxRunning)).To(BeNumerically(">", 1))
+		for i := 0; i < count; i++ {
+			Expect(processed[i]).To(BeTrue())
+			Expect(started[i]).To(BeTrue())
+			Expect(errs[i]).To(BeNil())
+		}
+	})
+
+	It("runBounded with one worker processes indexes in order and stops at first failure", func() {
+		order := make([]int, 0)
+		errs, started := controllers.RunBounded(5, 1, func(i int) error {
+			order = append(order, i)
+			if i == 2 {
+				return errors.New("failed")
+			}
+			return nil
+		})
+
+		Expect(order).To(Equal([]int{0, 1, 2}))
+		Expect(errs[2]).ToNot(BeNil())
+		Expect(started).To(Equal([]bool{true, true, true, false, false}))
+	})
+
+	It("lockRelease serializes operations on the same release only", func() {
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: randomString(),
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+			},
+		}
+		releaseNamespace := randomString()
+
+		unlock := controllers.LockRelease(clusterSummary, releaseNamespace, "first")
+
+		// A different release is not blocked
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			controllers.LockRelease(clusterSummary, releaseNamespace, "second")()
+			close(done)
+		}()
+		Eventually(done).Should(BeClosed())
+
+		// Same release is blocked till released
+		var wg sync.WaitGroup
+		var acquired int32
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			controllers.LockRelease(clusterSummary, releaseNamespace, "first")()
+			atomic.StoreInt32(&acquired, 1)
+		}()
+		Consistently(func() int32 { return atomic.LoadInt32(&acquired) }, 200*time.Millisecond).Should(Equal(int32(0)))
+
+		unlock()
+		wg.Wait()
+		Expect(atomic.LoadInt32(&acquired)).To(Equal(int32(1)))
+
+		// Locks not held anymore are removed
+		Expect(controllers.GetReleaseLocksLen()).To(BeZero())
+	})
+})
@@ -0,0 +1,95 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sort"
+	"strings"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// Operators can pin, in a single cluster, a helm chart to a version different from the one in the
+// ClusterProfile/Profile by setting the PinnedChartVersionsAnnotation on the ClusterSummary.
+// The pinned version is only applied in memory when deploying helm charts: ClusterSummary Spec is
+// left untouched. Pinned charts are reported in the ClusterProfile/Profile Status till the
+// annotation is removed.
+
+// getPinnedChartVersions returns, per release (<release namespace>/<release name>), the version
+// set in the PinnedChartVersionsAnnotation. Malformed entries are ignored.
+func getPinnedChartVersions(clusterSummary *configv1beta1.ClusterSummary) map[string]string {
+	value, ok := clusterSummary.Annotations[configv1beta1.PinnedChartVersionsAnnotation]
+	if !ok {
+		return nil
+	}
+
+	pinned := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		release, version, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || version == "" || strings.Count(release, "/") != 1 {
+			continue
+		}
+		pinned[release] = version
+	}
+
+	return pinned
+}
+
+func getPinnedChartKey(releaseNamespace, releaseName string) string {
+	return releaseNamespace + "/" + releaseName
+}
+
+// getPinnedCharts returns the helm charts in ClusterSummary Spec pinned to a different version
+func getPinnedCharts(clusterSummary *configv1beta1.ClusterSummary) []configv1beta1.PinnedChart {
+	pinned := getPinnedChartVersions(clusterSummary)
+	if len(pinned) == 0 {
+		return nil
+	}
+
+	var result []configv1beta1.PinnedChart
+	for i := range clusterSummary.Spec.ClusterProfileSpec.HelmCharts {
+		chart := &clusterSummary.Spec.ClusterProfileSpec.HelmCharts[i]
+		version, ok := pinned[getPinnedChartKey(chart.ReleaseNamespace, chart.ReleaseName)]
+		if !ok || version == chart.ChartVersion {
+			continue
+		}
+		result = append(result, configv1beta1.PinnedChart{
+			ReleaseNamespace: chart.ReleaseNamespace,
+			ReleaseName:      chart.ReleaseName,
+			ChartVersion:     version,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return getPinnedChartKey(result[i].ReleaseNamespace, result[i].ReleaseName) <
+			getPinnedChartKey(result[j].ReleaseNamespace, result[j].ReleaseName)
+	})
+
+	return result
+}
+
+// applyPinnedChartVersions overrides, in the ClusterSummary in memory only, the version of pinned
+// helm charts
+func applyPinnedChartVersions(clusterSummary *configv1beta1.ClusterSummary) {
+	pinned := getPinnedChartVersions(clusterSummary)
+	for i := range clusterSummary.Spec.ClusterProfileSpec.HelmCharts {
+		chart := &clusterSummary.Spec.ClusterProfileSpec.HelmCharts[i]
+		if version, ok := pinned[getPinnedChartKey(chart.ReleaseNamespace, chart.ReleaseName)]; ok {
+			chart.ChartVersion = version
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Helm chart pinning", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+
+	BeforeEach(func() {
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterProfileSpec: configv1beta1.Spec{
+					HelmCharts: []configv1beta1.HelmChart{
+						{
+							RepositoryURL: "https://kyverno.github.io/kyverno/", RepositoryName: "kyverno",
+							ChartName: "kyverno/kyverno", ChartVersion: "v3.2.6",
+							ReleaseName: "kyverno-latest", ReleaseNamespace: "kyverno",
+						},
+						{
+							RepositoryURL: "https://prometheus-community.github.io/helm-charts", RepositoryName: "prometheus",
+							ChartName: "prometheus/prometheus", ChartVersion: "25.24.0",
+							ReleaseName: "prometheus", ReleaseNamespace: "prometheus",
+						},
+					},
+				},
+			},
+		}
+	})
+
+	It("getPinnedCharts returns charts pinned to a version different from the one in Spec", func() {
+		Expect(controllers.GetPinnedCharts(clusterSummary)).To(BeEmpty())
+
+		clusterSummary.Annotations = map[string]string{
+			configv1beta1.PinnedChartVersionsAnnotation: fmt.Sprintf("%s, %s,%s,%s",
+				"prometheus/prometheus=25.20.0",
+				"kyverno/kyverno-latest=v3.2.6", // same version as Spec
+				"nginx/nginx=1.0.0",             // not in Spec
+				"malformed"),
+		}
+
+		pinned := controllers.GetPinnedCharts(clusterSummary)
+		Expect(pinned).To(HaveLen(1))
+		Expect(pinned[0]).To(Equal(configv1beta1.PinnedChart{
+			ReleaseNamespace: "prometheus", ReleaseName: "prometheus", ChartVersion: "25.20.0",
+		}))
+	})
+
+	It("applyPinnedChartVersions overrides version of pinned charts only", func() {
+		clusterSummary.Annotations = map[string]string{
+			configv1beta1.PinnedChartVersionsAnnotation: "kyverno/kyverno-latest=v3.1.0",
+		}
+
+		controllers.ApplyPinnedChartVersions(clusterSummary)
+		Expect(clusterSummary.Spec.ClusterProfileSpec.HelmCharts[0].ChartVersion).To(Equal("v3.1.0"))
+		Expect(clusterSummary.Spec.ClusterProfileSpec.HelmCharts[1].ChartVersion).To(Equal("25.24.0"))
+	})
+
+	It("getClusterSummaryAnnotations preserves pinned chart versions annotation", func() {
+		clusterProfile := &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        randomString(),
+				Annotations: map[string]string{randomString(): randomString()},
+			},
+		}
+
+		value := "kyverno/kyverno-latest=v3.1.0"
+		clusterSummary.Annotations = map[string]string{
+			configv1beta1.PinnedChartVersionsAnnotation: value,
+			randomString(): randomString(),
+		}
+
+		annotations := controllers.GetClusterSummaryAnnotations(clusterProfile, clusterSummary)
+		Expect(annotations).To(HaveLen(2))
+		Expect(annotations[configv1beta1.PinnedChartVersionsAnnotation]).To(Equal(value))
+		for k := range clusterProfile.Annotations {
+			Expect(annotations[k]).To(Equal(clusterProfile.Annotations[k]))
+		}
+	})
+})
@@ -0,0 +1,203 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// When HelmChart.Tests is set, the chart tests (helm test) are run as a verification step every time
+// a new release revision is deployed. Result is recorded in the ClusterSummary HelmReleaseSummaries.
+// Unless IgnoreFailures is set, failed tests fail the Helm feature (MessageCodeHelmTestFailed) and
+// tests are run again on next attempt.
+
+const (
+	defaultHelmTestsTimeout = 5 * time.Minute
+)
+
+func getHelmTestsTimeout(tests *configv1beta1.HelmChartTests) time.Duration {
+	if tests.Timeout != nil {
+		return tests.Timeout.Duration
+	}
+	return defaultHelmTestsTimeout
+}
+
+// getHelmTestResult returns the test result recorded for the release in ClusterSummary Status
+func getHelmTestResult(clusterSummary *configv1beta1.ClusterSummary, requestedChart *configv1beta1.HelmChart,
+) *configv1beta1.HelmTestResult {
+
+	for i := range clusterSummary.Status.HelmReleaseSummaries {
+		rs := &clusterSummary.Status.HelmReleaseSummaries[i]
+		if rs.ReleaseName == requestedChart.ReleaseName &&
+			rs.ReleaseNamespace == requestedChart.ReleaseNamespace {
+
+			return rs.TestResult
+		}
+	}
+	return nil
+}
+
+// shouldRunHelmTests returns true if tests are requested and the release revision was not tested
+// yet (or tests failed and failures are not ignored)
+func shouldRunHelmTests(clusterSummary *configv1beta1.ClusterSummary, requestedChart *configv1beta1.HelmChart,
+	currentRelease *releaseInfo) bool {
+
+	if requestedChart.Tests == nil || getDisableHooksHelmValue(requestedChart.Options) ||
+		requestedChart.HelmChartAction == configv1beta1.HelmChartActionUninstall {
+
+		return false
+	}
+
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+		return false
+	}
+
+	if currentRelease == nil || currentRelease.Status != release.StatusDeployed.String() {
+		return false
+	}
+
+	revision, _ := strconv.ParseInt(currentRelease.Revision, 10, 64)
+	result := getHelmTestResult(clusterSummary, requestedChart)
+	if result == nil || result.Revision != revision {
+		return true
+	}
+
+	return !result.Succeeded && !requestedChart.Tests.IgnoreFailures
+}
+
+// getFailedHelmTests returns the names of the test hooks which failed
+func getFailedHelmTests(rel *release.Release) []string {
+	if rel == nil {
+		return nil
+	}
+
+	var failed []string
+	for _, hook := range rel.Hooks {
+		isTest := false
+		for _, event := range hook.Events {
+			if event == release.HookTest {
+				isTest = true
+				break
+			}
+		}
+		if isTest && hook.LastRun.Phase == release.HookPhaseFailed {
+			failed = append(failed, hook.Name)
+		}
+	}
+
+	sort.Strings(failed)
+	return failed
+}
+
+// runHelmTests runs the chart tests against the deployed release, if needed, and records the result.
+// An error is returned if tests failed and failures are not ignored.
+func runHelmTests(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	requestedChart *configv1beta1.HelmChart, currentRelease *releaseInfo, kubeconfig string,
+	registryOptions *registryClientOptions, logger logr.Logger) error {
+
+	if !shouldRunHelmTests(clusterSummary, requestedChart, currentRelease) {
+		return nil
+	}
+
+	_, done, err := trackHelmOperation(ctx, clusterSummary, requestedChart.ReleaseNamespace,
+		requestedChart.ReleaseName, "test")
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	actionConfig, err := actionConfigInit(requestedChart.ReleaseNamespace, kubeconfig, registryOptions,
+		getEnableClientCacheValue(requestedChart.Options))
+	if err != nil {
+		return err
+	}
+
+	logger.V(logs.LogDebug).Info("running helm tests")
+	testClient := action.NewReleaseTesting(actionConfig)
+	testClient.Namespace = requestedChart.ReleaseNamespace
+	testClient.Timeout = getHelmTestsTimeout(requestedChart.Tests)
+	rel, testErr := testClient.Run(requestedChart.ReleaseName)
+
+	revision, _ := strconv.ParseInt(currentRelease.Revision, 10, 64)
+	result := &configv1beta1.HelmTestResult{
+		Revision:    revision,
+		Succeeded:   testErr == nil,
+		FailedTests: getFailedHelmTests(rel),
+		LastRunTime: metav1.Now(),
+	}
+	if testErr != nil {
+		failureMessage := testErr.Error()
+		result.FailureMessage = &failureMessage
+	}
+
+	if err := updateTestResultOnHelmChartSummary(ctx, requestedChart, clusterSummary, result); err != nil {
+		return err
+	}
+
+	if testErr == nil {
+		logger.V(logs.LogDebug).Info("helm tests succeeded")
+		return nil
+	}
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("helm tests failed: %v", testErr))
+	if requestedChart.Tests.IgnoreFailures {
+		return nil
+	}
+	return withMessageCode(configv1beta1.MessageCodeHelmTestFailed,
+		fmt.Errorf("tests of helm release %s/%s failed: %w", requestedChart.ReleaseNamespace,
+			requestedChart.ReleaseName, testErr))
+}
+
+func updateTestResultOnHelmChartSummary(ctx context.Context, requestedChart *configv1beta1.HelmChart,
+	clusterSummary *configv1beta1.ClusterSummary, result *configv1beta1.HelmTestResult) error {
+
+	c := getManagementClusterClient()
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		currentClusterSummary := &configv1beta1.ClusterSummary{}
+		err := c.Get(ctx,
+			types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name}, currentClusterSummary)
+		if err != nil {
+			return err
+		}
+
+		for i := range currentClusterSummary.Status.HelmReleaseSummaries {
+			rs := &currentClusterSummary.Status.HelmReleaseSummaries[i]
+			if rs.ReleaseName == requestedChart.ReleaseName &&
+				rs.ReleaseNamespace == requestedChart.ReleaseNamespace {
+
+				rs.TestResult = result
+			}
+		}
+
+		return c.Status().Update(ctx, currentClusterSummary)
+	})
+}
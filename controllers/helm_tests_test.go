@@ -0,0 +1,144 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Helm chart tests", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+	var helmChart *configv1beta1.HelmChart
+	var currentRelease *controllers.ReleaseInfo
+
+	BeforeEach(func() {
+		helmChart = &configv1beta1.HelmChart{
+			RepositoryURL:    randomString(),
+			RepositoryName:   randomString(),
+			ChartName:        randomString(),
+			ChartVersion:     "1.0.0",
+			ReleaseName:      randomString(),
+			ReleaseNamespace: randomString(),
+			Tests:            &configv1beta1.HelmChartTests{},
+		}
+
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterProfileSpec: configv1beta1.Spec{
+					HelmCharts: []configv1beta1.HelmChart{*helmChart},
+				},
+			},
+			Status: configv1beta1.ClusterSummaryStatus{
+				HelmReleaseSummaries: []configv1beta1.HelmChartSummary{
+					{
+						ReleaseName:      helmChart.ReleaseName,
+						ReleaseNamespace: helmChart.ReleaseNamespace,
+						Status:           configv1beta1.HelmChartStatusManaging,
+					},
+				},
+			},
+		}
+
+		currentRelease = &controllers.ReleaseInfo{
+			ReleaseName:      helmChart.ReleaseName,
+			ReleaseNamespace: helmChart.ReleaseNamespace,
+			Revision:         "2",
+			Status:           release.StatusDeployed.String(),
+		}
+	})
+
+	It("shouldRunHelmTests returns false when tests are not requested", func() {
+		helmChart.Tests = nil
+		Expect(controllers.ShouldRunHelmTests(clusterSummary, helmChart, currentRelease)).To(BeFalse())
+	})
+
+	It("shouldRunHelmTests returns false when hooks are disabled", func() {
+		helmChart.Options = &configv1beta1.HelmOptions{DisableHooks: true}
+		Expect(controllers.ShouldRunHelmTests(clusterSummary, helmChart, currentRelease)).To(BeFalse())
+	})
+
+	It("shouldRunHelmTests returns false when release is not deployed", func() {
+		currentRelease.Status = release.StatusFailed.String()
+		Expect(controllers.ShouldRunHelmTests(clusterSummary, helmChart, currentRelease)).To(BeFalse())
+		Expect(controllers.ShouldRunHelmTests(clusterSummary, helmChart, nil)).To(BeFalse())
+	})
+
+	It("shouldRunHelmTests returns true only when release revision was not tested yet", func() {
+		Expect(controllers.ShouldRunHelmTests(clusterSummary, helmChart, currentRelease)).To(BeTrue())
+
+		clusterSummary.Status.HelmReleaseSummaries[0].TestResult = &configv1beta1.HelmTestResult{
+			Revision:  1,
+			Succeeded: true,
+		}
+		Expect(controllers.ShouldRunHelmTests(clusterSummary, helmChart, currentRelease)).To(BeTrue())
+
+		clusterSummary.Status.HelmReleaseSummaries[0].TestResult.Revision = 2
+		Expect(controllers.ShouldRunHelmTests(clusterSummary, helmChart, currentRelease)).To(BeFalse())
+	})
+
+	It("shouldRunHelmTests runs failed tests again unless failures are ignored", func() {
+		clusterSummary.Status.HelmReleaseSummaries[0].TestResult = &configv1beta1.HelmTestResult{
+			Revision:  2,
+			Succeeded: false,
+		}
+		Expect(controllers.ShouldRunHelmTests(clusterSummary, helmChart, currentRelease)).To(BeTrue())
+
+		helmChart.Tests.IgnoreFailures = true
+		Expect(controllers.ShouldRunHelmTests(clusterSummary, helmChart, currentRelease)).To(BeFalse())
+	})
+
+	It("getFailedHelmTests returns the failed test hooks", func() {
+		rel := &release.Release{
+			Hooks: []*release.Hook{
+				{
+					Name:    "test-connection",
+					Events:  []release.HookEvent{release.HookTest},
+					LastRun: release.HookExecution{Phase: release.HookPhaseFailed},
+				},
+				{
+					Name:    "test-api",
+					Events:  []release.HookEvent{release.HookTest},
+					LastRun: release.HookExecution{Phase: release.HookPhaseSucceeded},
+				},
+				{
+					Name:    "migrate",
+					Events:  []release.HookEvent{release.HookPreUpgrade},
+					LastRun: release.HookExecution{Phase: release.HookPhaseFailed},
+				},
+				{
+					Name:    "test-auth",
+					Events:  []release.HookEvent{release.HookTest},
+					LastRun: release.HookExecution{Phase: release.HookPhaseFailed},
+				},
+			},
+		}
+
+		Expect(controllers.GetFailedHelmTests(rel)).To(Equal([]string{"test-auth", "test-connection"}))
+		Expect(controllers.GetFailedHelmTests(nil)).To(BeNil())
+	})
+})
@@ -0,0 +1,158 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// The values a helm release is deployed with are reported in ClusterConfiguration, so operators can
+// confirm which values a cluster received (for instance during a templated rollout). Values can contain
+// secrets, so only a sanitized snapshot is reported: a hash of all values, and the values flattened
+// with the sensitive ones redacted.
+
+const (
+	redactedChartValue = "<redacted>"
+
+	// maxChartValuesEntries is the maximum number of values reported per release
+	maxChartValuesEntries = 100
+
+	// maxChartValueLength is the maximum length of each reported value
+	maxChartValueLength = 256
+)
+
+// sensitiveChartValueKeys are the substrings which, when found in any key (lower case, with - and _
+// removed) of a value path, cause the value to be redacted
+var sensitiveChartValueKeys = []string{
+	"password", "passwd", "secret", "token", "apikey", "accesskey", "privatekey", "credential",
+}
+
+// isSensitiveChartValueKey returns true if a value with this key might contain a secret
+func isSensitiveChartValueKey(key string) bool {
+	key = strings.ToLower(key)
+	key = strings.NewReplacer("-", "", "_", "").Replace(key)
+	for i := range sensitiveChartValueKeys {
+		if strings.Contains(key, sensitiveChartValueKeys[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenChartValues flattens values using dot notation (list elements are referenced by index,
+// for instance tolerations[0].key). Values under a sensitive key are redacted.
+func flattenChartValues(prefix string, value interface{}, sensitive bool, result map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			flattenChartValues(path, v[k], sensitive || isSensitiveChartValueKey(k), result)
+		}
+	case []interface{}:
+		for i := range v {
+			flattenChartValues(fmt.Sprintf("%s[%d]", prefix, i), v[i], sensitive, result)
+		}
+	default:
+		if sensitive {
+			result[prefix] = redactedChartValue
+			return
+		}
+		s := fmt.Sprintf("%v", v)
+		if v == nil {
+			s = "null"
+		}
+		if len(s) > maxChartValueLength {
+			s = s[:maxChartValueLength] + "..."
+		}
+		result[prefix] = s
+	}
+}
+
+// getChartValuesSnapshot returns the sanitized snapshot of the values a release is deployed with.
+// Nil is returned if release has no values.
+func getChartValuesSnapshot(values map[string]interface{}) (*configv1beta1.ChartValues, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	// json.Marshal sorts map keys, so hash does not depend on map iteration order
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	flattened := make(map[string]string)
+	flattenChartValues("", values, false, flattened)
+
+	snapshot := &configv1beta1.ChartValues{
+		Hash:   fmt.Sprintf("sha256:%x", sha256.Sum256(data)),
+		Values: flattened,
+	}
+
+	if len(flattened) > maxChartValuesEntries {
+		// Keep the first entries in alphabetical order so the snapshot is stable
+		keys := make([]string, 0, len(flattened))
+		for k := range flattened {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		snapshot.Values = make(map[string]string, maxChartValuesEntries)
+		for _, k := range keys[:maxChartValuesEntries] {
+			snapshot.Values[k] = flattened[k]
+		}
+		snapshot.Truncated = true
+	}
+
+	return snapshot, nil
+}
+
+// getSanitizedChartValues returns values flattened, one "path: value" line per value sorted by path,
+// with sensitive values and values at redactedPaths redacted.
+func getSanitizedChartValues(values map[string]interface{}, redactedPaths map[string]bool) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	flattened := make(map[string]string)
+	flattenChartValues("", values, false, flattened)
+
+	keys := make([]string, 0, len(flattened))
+	for k := range flattened {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		value := flattened[k]
+		if redactedPaths[k] {
+			value = redactedChartValue
+		}
+		sb.WriteString(fmt.Sprintf("%s: %s\n", k, value))
+	}
+	return sb.String()
+}
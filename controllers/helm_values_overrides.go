@@ -0,0 +1,69 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// HelmChart ValuesOverrides allow a single profile to express small per-cluster differences.
+// The overrides matching the cluster labels are merged after Values and ValuesFrom. Which overrides
+// match is part of the helm hash, so a cluster label change causes the helm release to be upgraded.
+
+// getMatchingValuesOverrides returns, in order, the ValuesOverrides of helmChart whose ClusterSelector
+// matches the labels of the cluster clusterSummary is for
+func getMatchingValuesOverrides(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	helmChart *configv1beta1.HelmChart) ([]configv1beta1.HelmValuesOverride, error) {
+
+	if len(helmChart.ValuesOverrides) == 0 {
+		return nil, nil
+	}
+
+	cluster, err := getCluster(ctx, c, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+		clusterSummary.Spec.ClusterType)
+	if err != nil {
+		// Nothing can be deployed in a cluster which does not exist anymore
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	clusterLabels := labels.Set(cluster.GetLabels())
+
+	matching := make([]configv1beta1.HelmValuesOverride, 0)
+	for i := range helmChart.ValuesOverrides {
+		override := &helmChart.ValuesOverrides[i]
+		selector, err := metav1.LabelSelectorAsSelector(&override.ClusterSelector.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clusterSelector in valuesOverrides[%d] of helm chart %s: %w",
+				i, helmChart.ReleaseName, err)
+		}
+		if selector.Matches(clusterLabels) {
+			matching = append(matching, *override)
+		}
+	}
+
+	return matching, nil
+}
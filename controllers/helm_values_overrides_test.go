@@ -0,0 +1,105 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Helm values overrides", func() {
+	It("getMatchingValuesOverrides returns, in order, the overrides matching cluster labels", func() {
+		cluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+				Labels:    map[string]string{"env": "prod", "region": "eu"},
+			},
+		}
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{Namespace: cluster.Namespace, Name: randomString()},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: cluster.Namespace,
+				ClusterName:      cluster.Name,
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+			},
+		}
+
+		helmChart := &configv1beta1.HelmChart{
+			ReleaseName: randomString(),
+			ValuesOverrides: []configv1beta1.HelmValuesOverride{
+				{
+					ClusterSelector: libsveltosv1beta1.Selector{
+						LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "staging"}},
+					},
+					Values: "controller:\n  ingressClass: staging",
+				},
+				{
+					ClusterSelector: libsveltosv1beta1.Selector{
+						LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+					},
+					Values: "controller:\n  ingressClass: prod",
+				},
+				{
+					ClusterSelector: libsveltosv1beta1.Selector{
+						LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu"}},
+					},
+					Values: "controller:\n  replicas: 3",
+				},
+			},
+		}
+
+		initObjects := []client.Object{cluster, clusterSummary}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+
+		overrides, err := controllers.GetMatchingValuesOverrides(context.TODO(), c, clusterSummary, helmChart)
+		Expect(err).To(BeNil())
+		Expect(overrides).To(HaveLen(2))
+		Expect(overrides[0].Values).To(Equal(helmChart.ValuesOverrides[1].Values))
+		Expect(overrides[1].Values).To(Equal(helmChart.ValuesOverrides[2].Values))
+
+		// A label change changes which overrides are used, and so the hash of the helm chart
+		hash, err := controllers.GetHelmReferenceResourceHash(context.TODO(), c, clusterSummary, helmChart,
+			textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+
+		cluster.Labels = map[string]string{"env": "staging"}
+		Expect(c.Update(context.TODO(), cluster)).To(Succeed())
+
+		overrides, err = controllers.GetMatchingValuesOverrides(context.TODO(), c, clusterSummary, helmChart)
+		Expect(err).To(BeNil())
+		Expect(overrides).To(HaveLen(1))
+		Expect(overrides[0].Values).To(Equal(helmChart.ValuesOverrides[0].Values))
+
+		newHash, err := controllers.GetHelmReferenceResourceHash(context.TODO(), c, clusterSummary, helmChart,
+			textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+		Expect(newHash).ToNot(Equal(hash))
+	})
+})
@@ -0,0 +1,105 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Helm values snapshot", func() {
+	It("getChartValuesSnapshot flattens values and redacts sensitive ones", func() {
+		values := map[string]interface{}{
+			"replicaCount": 2,
+			"image": map[string]interface{}{
+				"repository": "nginx",
+				"tag":        "1.25",
+			},
+			"tolerations": []interface{}{
+				map[string]interface{}{"key": "node-role", "effect": "NoSchedule"},
+			},
+			"auth": map[string]interface{}{
+				"adminPassword": "do-not-show",
+				"existingSecret": map[string]interface{}{
+					"name": "credentials",
+				},
+			},
+			"api_token": "do-not-show",
+		}
+
+		snapshot, err := controllers.GetChartValuesSnapshot(values)
+		Expect(err).To(BeNil())
+		Expect(snapshot).ToNot(BeNil())
+		Expect(snapshot.Hash).To(HavePrefix("sha256:"))
+		Expect(snapshot.Truncated).To(BeFalse())
+		Expect(snapshot.Values).To(Equal(map[string]string{
+			"replicaCount":             "2",
+			"image.repository":         "nginx",
+			"image.tag":                "1.25",
+			"tolerations[0].key":       "node-role",
+			"tolerations[0].effect":    "NoSchedule",
+			"auth.adminPassword":       "<redacted>",
+			"auth.existingSecret.name": "<redacted>",
+			"api_token":                "<redacted>",
+		}))
+
+		// Hash covers redacted values as well
+		values["api_token"] = "another-token"
+		otherSnapshot, err := controllers.GetChartValuesSnapshot(values)
+		Expect(err).To(BeNil())
+		Expect(otherSnapshot.Hash).ToNot(Equal(snapshot.Hash))
+		Expect(otherSnapshot.Values).To(Equal(snapshot.Values))
+	})
+
+	It("getChartValuesSnapshot limits the number of values reported", func() {
+		snapshot, err := controllers.GetChartValuesSnapshot(nil)
+		Expect(err).To(BeNil())
+		Expect(snapshot).To(BeNil())
+
+		values := map[string]interface{}{}
+		for i := 0; i < 150; i++ {
+			values[fmt.Sprintf("key%03d", i)] = i
+		}
+
+		snapshot, err = controllers.GetChartValuesSnapshot(values)
+		Expect(err).To(BeNil())
+		Expect(snapshot.Truncated).To(BeTrue())
+		Expect(len(snapshot.Values)).To(Equal(100))
+		Expect(snapshot.Values).To(HaveKeyWithValue("key000", "0"))
+		Expect(snapshot.Values).ToNot(HaveKey("key149"))
+	})
+
+	It("getSanitizedChartValues redacts sensitive values and values at redacted paths", func() {
+		Expect(controllers.GetSanitizedChartValues(nil, nil)).To(BeEmpty())
+
+		values := map[string]interface{}{
+			"replicas": 2,
+			"database": map[string]interface{}{
+				"host":     "db.example.com",
+				"password": "supersecret",
+			},
+			"license": "from-a-secret",
+		}
+
+		Expect(controllers.GetSanitizedChartValues(values, map[string]bool{"license": true})).To(Equal(
+			"database.host: db.example.com\ndatabase.password: <redacted>\nlicense: <redacted>\nreplicas: 2\n"))
+	})
+})
@@ -0,0 +1,506 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"oras.land/oras-go/pkg/registry/remote/auth"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	libsveltostemplate "github.com/projectsveltos/libsveltos/lib/template"
+)
+
+// When HelmChart.Verify is set, the chart signature is verified before the chart is installed or
+// upgraded. Failing verification fails the Helm feature (MessageCodeChartVerificationFailed).
+// - Provenance: chart is downloaded along with its provenance file (.prov) and verified using helm
+// against the PGP keyring contained in the referenced Secret.
+// - Cosign: OCI charts only. The cosign signature (stored in the registry with the tag
+// sha256-<manifest digest>.sig) is fetched and verified against the public keys contained in
+// the referenced Secret.
+// Result of a successful verification is reported in ClusterConfiguration.
+
+const (
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	// maximum size of the documents (manifests and signature payloads) downloaded to verify
+	// cosign signatures
+	maxCosignDocumentSize = 4 * 1024 * 1024
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// cosignPayload is the payload signed by cosign (simple signing format)
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// verifyHelmChart verifies the signature of the requested chart. Returns the verification result
+// or nil if verification is not requested.
+func verifyHelmChart(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	requestedChart *configv1beta1.HelmChart, registryOptions *registryClientOptions, logger logr.Logger,
+) (*configv1beta1.ChartVerification, error) {
+
+	if requestedChart.Verify == nil ||
+		requestedChart.HelmChartAction == configv1beta1.HelmChartActionUninstall {
+
+		return nil, nil
+	}
+
+	provider := requestedChart.Verify.Provider
+	if provider == "" {
+		provider = configv1beta1.HelmChartVerificationProviderProvenance
+	}
+
+	logger = logger.WithValues("verificationProvider", provider)
+	logger.V(logs.LogDebug).Info("verifying chart signature")
+
+	secret, err := getHelmChartVerifySecret(ctx, c, clusterSummary.Spec.ClusterNamespace, requestedChart)
+	if err != nil {
+		return nil, withMessageCode(configv1beta1.MessageCodeChartVerificationFailed, err)
+	}
+
+	var verification *configv1beta1.ChartVerification
+	switch provider {
+	case configv1beta1.HelmChartVerificationProviderCosign:
+		verification, err = verifyChartCosignSignature(ctx, c, clusterSummary.Spec.ClusterNamespace,
+			requestedChart, registryOptions, secret)
+	default:
+		verification, err = verifyChartProvenance(requestedChart, registryOptions, secret, logger)
+	}
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("chart signature verification failed: %v", err))
+		var repoErr *chartRepositoryError
+		if errors.As(err, &repoErr) {
+			return nil, withMessageCode(getChartPullMessageCode(err), err)
+		}
+		return nil, withMessageCode(configv1beta1.MessageCodeChartVerificationFailed,
+			fmt.Errorf("chart signature verification failed: %w", err))
+	}
+
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("chart signed by %s", verification.SignedBy))
+	return verification, nil
+}
+
+// getHelmChartVerifySecret returns the Secret referenced by HelmChart.Verify
+func getHelmChartVerifySecret(ctx context.Context, c client.Client, clusterNamespace string,
+	requestedChart *configv1beta1.HelmChart) (*corev1.Secret, error) {
+
+	namespace := libsveltostemplate.GetReferenceResourceNamespace(clusterNamespace,
+		requestedChart.Verify.SecretRef.Namespace)
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: requestedChart.Verify.SecretRef.Name},
+		secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(secret.Data) == 0 {
+		return nil, fmt.Errorf("secret %s/%s referenced in HelmChart verify section contains no data",
+			namespace, requestedChart.Verify.SecretRef.Name)
+	}
+
+	return secret, nil
+}
+
+// getSortedSecretData returns the values of the Secret data section, ordered by key
+func getSortedSecretData(secret *corev1.Secret) [][]byte {
+	keys := make([]string, 0, len(secret.Data))
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([][]byte, len(keys))
+	for i := range keys {
+		values[i] = secret.Data[keys[i]]
+	}
+	return values
+}
+
+// verifyChartProvenance downloads the chart along with its provenance file and verifies it
+// against the keyring contained in secret.
+func verifyChartProvenance(requestedChart *configv1beta1.HelmChart, registryOptions *registryClientOptions,
+	secret *corev1.Secret, logger logr.Logger) (*configv1beta1.ChartVerification, error) {
+
+	keyringPath, err := createTemporaryFile("keyring-*.gpg", bytes.Join(getSortedSecretData(secret), nil))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(keyringPath)
+
+	settings := getSettings(requestedChart.ReleaseNamespace, registryOptions)
+	err = repoAddOrUpdate(settings, requestedChart.RepositoryName, requestedChart.RepositoryURL, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	chartName, repoURL, err := getHelmChartAndRepoName(requestedChart.ChartName, requestedChart.RepositoryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	registryClient, err := getRegistryClient(requestedChart.ReleaseNamespace, registryOptions,
+		getEnableClientCacheValue(requestedChart.Options))
+	if err != nil {
+		return nil, err
+	}
+
+	pullClient := action.NewInstall(&action.Configuration{RegistryClient: registryClient})
+	pullClient.SetRegistryClient(registryClient)
+	pullClient.RepoURL = repoURL
+	pullClient.Version = requestedChart.ChartVersion
+	pullClient.Verify = true
+	pullClient.Keyring = keyringPath
+
+	// With Verify set, chart is downloaded along with its provenance file and verified
+	cp, releaseChart, err := locateChart(&pullClient.ChartPathOptions, chartName, settings)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseChart()
+
+	verification, err := downloader.VerifyChart(cp, keyringPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &configv1beta1.ChartVerification{
+		Provider: configv1beta1.HelmChartVerificationProviderProvenance,
+		Digest:   verification.FileHash,
+	}
+	if verification.SignedBy != nil {
+		identities := make([]string, 0, len(verification.SignedBy.Identities))
+		for name := range verification.SignedBy.Identities {
+			identities = append(identities, name)
+		}
+		sort.Strings(identities)
+		if len(identities) > 0 {
+			result.SignedBy = identities[0]
+		}
+	}
+
+	return result, nil
+}
+
+// verifyChartCosignSignature verifies the cosign signature of an OCI chart against the public
+// keys contained in secret.
+func verifyChartCosignSignature(ctx context.Context, c client.Client, clusterNamespace string,
+	requestedChart *configv1beta1.HelmChart, registryOptions *registryClientOptions, secret *corev1.Secret,
+) (*configv1beta1.ChartVerification, error) {
+
+	if !registry.IsOCI(requestedChart.RepositoryURL) {
+		return nil, &NonRetriableError{Message: "cosign verification is supported only for charts in OCI registries"}
+	}
+	if requestedChart.ChartVersion == "" {
+		return nil, &NonRetriableError{Message: "cosign verification requires chart version"}
+	}
+
+	publicKeys, err := parseCosignPublicKeys(secret)
+	if err != nil {
+		return nil, &NonRetriableError{Message: err.Error()}
+	}
+
+	ociClient, err := getCosignRegistryClient(ctx, c, clusterNamespace, requestedChart, registryOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	chartRef, _, err := getHelmChartAndRepoName(requestedChart.ChartName, requestedChart.RepositoryURL)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(chartRef)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "https"
+	if registryOptions.plainHTTP {
+		scheme = "http"
+	}
+	repository := strings.TrimPrefix(u.Path, "/")
+	baseURL := fmt.Sprintf("%s://%s/v2/%s", scheme, u.Host, repository)
+	ctx = auth.WithScopes(ctx, auth.ScopeRepository(repository, auth.ActionPull))
+
+	// OCI tags can not contain '+'. Helm replaces it with '_'
+	tag := strings.ReplaceAll(requestedChart.ChartVersion, "+", "_")
+	chartManifest, err := fetchOCIDocument(ctx, ociClient, fmt.Sprintf("%s/manifests/%s", baseURL, tag))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(chartManifest)
+	chartDigest := "sha256:" + hex.EncodeToString(sum[:])
+
+	signatureManifest, err := fetchOCIDocument(ctx, ociClient,
+		fmt.Sprintf("%s/manifests/sha256-%s.sig", baseURL, hex.EncodeToString(sum[:])))
+	if err != nil {
+		var repoErr *chartRepositoryError
+		if errors.As(err, &repoErr) && repoErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("no cosign signature found for chart %s", chartDigest)
+		}
+		return nil, err
+	}
+
+	manifest := &ociManifest{}
+	if err := json.Unmarshal(signatureManifest, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse cosign signature manifest: %w", err)
+	}
+
+	for i := range manifest.Layers {
+		layer := &manifest.Layers[i]
+		signature, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+
+		var payload []byte
+		payload, err = fetchOCIDocument(ctx, ociClient, fmt.Sprintf("%s/blobs/%s", baseURL, layer.Digest))
+		if err != nil {
+			return nil, err
+		}
+
+		signedBy, verifyErr := verifyCosignSignature(payload, layer.Digest, signature, chartDigest, publicKeys)
+		if verifyErr != nil {
+			err = verifyErr
+			continue
+		}
+
+		return &configv1beta1.ChartVerification{
+			Provider: configv1beta1.HelmChartVerificationProviderCosign,
+			SignedBy: signedBy,
+			Digest:   chartDigest,
+		}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no cosign signature found for chart %s", chartDigest)
+}
+
+// verifyCosignSignature verifies payload is a cosign payload for chartDigest signed by one of
+// publicKeys. Returns the fingerprint of the key payload is signed with.
+func verifyCosignSignature(payload []byte, payloadDigest, signature, chartDigest string,
+	publicKeys []crypto.PublicKey) (string, error) {
+
+	sum := sha256.Sum256(payload)
+	if payloadDigest != "sha256:"+hex.EncodeToString(sum[:]) {
+		return "", fmt.Errorf("cosign payload digest mismatch")
+	}
+
+	p := &cosignPayload{}
+	if err := json.Unmarshal(payload, p); err != nil {
+		return "", fmt.Errorf("failed to parse cosign payload: %w", err)
+	}
+	if p.Critical.Image.DockerManifestDigest != chartDigest {
+		return "", fmt.Errorf("cosign signature is for %s not for chart %s",
+			p.Critical.Image.DockerManifestDigest, chartDigest)
+	}
+
+	rawSignature, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode cosign signature: %w", err)
+	}
+
+	for i := range publicKeys {
+		if verifySignature(publicKeys[i], payload, sum[:], rawSignature) {
+			return getPublicKeyFingerprint(publicKeys[i])
+		}
+	}
+
+	return "", fmt.Errorf("cosign signature not made by any of the trusted public keys")
+}
+
+func verifySignature(publicKey crypto.PublicKey, payload, digest, signature []byte) bool {
+	switch k := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest, signature)
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, signature) == nil {
+			return true
+		}
+		return rsa.VerifyPSS(k, crypto.SHA256, digest, signature, nil) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, signature)
+	default:
+		return false
+	}
+}
+
+func getPublicKeyFingerprint(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// parseCosignPublicKeys returns the PEM encoded public keys contained in secret
+func parseCosignPublicKeys(secret *corev1.Secret) ([]crypto.PublicKey, error) {
+	var publicKeys []crypto.PublicKey
+	for _, data := range getSortedSecretData(secret) {
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type != "PUBLIC KEY" {
+				continue
+			}
+			publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse public key in secret %s/%s: %w",
+					secret.Namespace, secret.Name, err)
+			}
+			publicKeys = append(publicKeys, publicKey)
+		}
+	}
+
+	if len(publicKeys) == 0 {
+		return nil, fmt.Errorf("secret %s/%s contains no PEM encoded public key", secret.Namespace, secret.Name)
+	}
+	return publicKeys, nil
+}
+
+// getCosignRegistryClient returns the client used to fetch chart signatures from the OCI registry.
+// Client uses the registry credentials and TLS settings of the HelmChart.
+func getCosignRegistryClient(ctx context.Context, c client.Client, clusterNamespace string,
+	requestedChart *configv1beta1.HelmChart, registryOptions *registryClientOptions) (*auth.Client, error) {
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: registryOptions.skipTLSVerify, //nolint: gosec // explicitly requested
+	}
+	if isStrictOutboundTLS() {
+		var err error
+		tlsConfig, err = getOutboundTLSConfig(registryOptions.caPath, registryOptions.skipTLSVerify)
+		if err != nil {
+			return nil, err
+		}
+	} else if registryOptions.caPath != "" {
+		caCert, err := os.ReadFile(registryOptions.caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %w", registryOptions.caPath, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse CA file %s", registryOptions.caPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	credential := auth.EmptyCredential
+	if requestedChart.RegistryCredentialsConfig != nil &&
+		requestedChart.RegistryCredentialsConfig.CredentialsSecretRef != nil {
+
+		ref := requestedChart.RegistryCredentialsConfig.CredentialsSecretRef
+		secret := &corev1.Secret{}
+		err := c.Get(ctx,
+			types.NamespacedName{
+				Namespace: libsveltostemplate.GetReferenceResourceNamespace(clusterNamespace, ref.Namespace),
+				Name:      ref.Name,
+			},
+			secret)
+		if err != nil {
+			return nil, err
+		}
+		credential.Username, credential.Password, _, err =
+			getUsernameAndPasswordFromSecret(requestedChart.RepositoryURL, secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &auth.Client{
+		Client: &http.Client{Transport: getOutboundTransport(tlsConfig)},
+		Header: http.Header{"User-Agent": {getRemoteUserAgent()}},
+		Cache:  auth.NewCache(),
+		Credential: func(context.Context, string) (auth.Credential, error) {
+			return credential, nil
+		},
+	}, nil
+}
+
+// fetchOCIDocument fetches a manifest or a blob from an OCI registry
+func fetchOCIDocument(ctx context.Context, ociClient *auth.Client, documentURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join([]string{
+		"application/vnd.oci.image.manifest.v1+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+		"*/*",
+	}, ", "))
+
+	resp, err := ociClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &chartRepositoryError{URL: documentURL, StatusCode: resp.StatusCode, Status: resp.Status,
+			Attempts: 1}
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxCosignDocumentSize))
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Helm chart verification", func() {
+	var signingKey *ecdsa.PrivateKey
+	var server *httptest.Server
+	var clusterSummary *configv1beta1.ClusterSummary
+
+	const chartManifest = `{"schemaVersion":2,"config":{"mediaType":"application/vnd.cncf.helm.config.v1+json"}}`
+
+	getPEMPublicKey := func(key *ecdsa.PrivateKey) []byte {
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		Expect(err).To(BeNil())
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+
+	getDigest := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	// startRegistry starts an OCI registry serving chart charts/nginx:1.0.0 signed with signingKey
+	startRegistry := func() *httptest.Server {
+		chartDigest := getDigest([]byte(chartManifest))
+		payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"charts/nginx"},`+
+			`"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`,
+			chartDigest))
+		payloadSum := sha256.Sum256(payload)
+		signature, err := ecdsa.SignASN1(rand.Reader, signingKey, payloadSum[:])
+		Expect(err).To(BeNil())
+
+		signatureManifest := fmt.Sprintf(`{"schemaVersion":2,"layers":[{"mediaType":`+
+			`"application/vnd.dev.cosign.simplesigning.v1+json","digest":%q,"size":%d,"annotations":{%q:%q}}]}`,
+			getDigest(payload), len(payload), "dev.cosignproject.cosign/signature",
+			base64.StdEncoding.EncodeToString(signature))
+
+		// cosign stores signature with tag sha256-<chart manifest digest>.sig
+		signatureTag := strings.Replace(chartDigest, ":", "-", 1) + ".sig"
+		documents := map[string][]byte{
+			"/v2/charts/nginx/manifests/1.0.0":             []byte(chartManifest),
+			"/v2/charts/nginx/manifests/" + signatureTag:   []byte(signatureManifest),
+			"/v2/charts/nginx/blobs/" + getDigest(payload): payload,
+		}
+
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			document, ok := documents[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(document)
+		}))
+	}
+
+	getHelmChart := func(secret *corev1.Secret) *configv1beta1.HelmChart {
+		return &configv1beta1.HelmChart{
+			RepositoryURL:    "oci://" + strings.TrimPrefix(server.URL, "http://") + "/charts",
+			RepositoryName:   randomString(),
+			ChartName:        "nginx",
+			ChartVersion:     "1.0.0",
+			ReleaseName:      randomString(),
+			ReleaseNamespace: randomString(),
+			Verify: &configv1beta1.HelmChartVerify{
+				Provider: configv1beta1.HelmChartVerificationProviderCosign,
+				SecretRef: corev1.SecretReference{
+					Namespace: secret.Namespace,
+					Name:      secret.Name,
+				},
+			},
+		}
+	}
+
+	getSecret := func(publicKeys ...[]byte) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Data: map[string][]byte{},
+		}
+		for i := range publicKeys {
+			secret.Data[fmt.Sprintf("key%d.pub", i)] = publicKeys[i]
+		}
+		return secret
+	}
+
+	BeforeEach(func() {
+		var err error
+		signingKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).To(BeNil())
+
+		server = startRegistry()
+
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: randomString(),
+				ClusterName:      randomString(),
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("verifyHelmChart verifies cosign signature of OCI charts", func() {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).To(BeNil())
+
+		secret := getSecret(getPEMPublicKey(otherKey), getPEMPublicKey(signingKey))
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+		verification, err := controllers.VerifyHelmChart(context.TODO(), c, clusterSummary, getHelmChart(secret),
+			true, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+		Expect(verification).ToNot(BeNil())
+		Expect(verification.Provider).To(Equal(configv1beta1.HelmChartVerificationProviderCosign))
+		Expect(verification.Digest).To(Equal(getDigest([]byte(chartManifest))))
+
+		der, err := x509.MarshalPKIXPublicKey(&signingKey.PublicKey)
+		Expect(err).To(BeNil())
+		Expect(verification.SignedBy).To(Equal(getDigest(der)))
+	})
+
+	It("verifyHelmChart fails if chart is not signed by any trusted key", func() {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).To(BeNil())
+
+		secret := getSecret(getPEMPublicKey(otherKey))
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+		_, err = controllers.VerifyHelmChart(context.TODO(), c, clusterSummary, getHelmChart(secret),
+			true, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).ToNot(BeNil())
+		Expect(controllers.GetMessageCode(err)).To(Equal(configv1beta1.MessageCodeChartVerificationFailed))
+	})
+
+	It("verifyHelmChart fails if chart has no signature", func() {
+		secret := getSecret(getPEMPublicKey(signingKey))
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+		// Chart is stored in registry but signature is not
+		server.Close()
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v2/charts/nginx/manifests/1.0.0" {
+				_, _ = w.Write([]byte(chartManifest))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+
+		_, err := controllers.VerifyHelmChart(context.TODO(), c, clusterSummary, getHelmChart(secret),
+			true, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("no cosign signature found"))
+		Expect(controllers.GetMessageCode(err)).To(Equal(configv1beta1.MessageCodeChartVerificationFailed))
+	})
+
+	It("verifyHelmChart is a no-op when verification is not requested", func() {
+		secret := getSecret(getPEMPublicKey(signingKey))
+		helmChart := getHelmChart(secret)
+		helmChart.Verify = nil
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		verification, err := controllers.VerifyHelmChart(context.TODO(), c, clusterSummary, helmChart,
+			true, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).To(BeNil())
+		Expect(verification).To(BeNil())
+	})
+
+	It("verifyHelmChart does not support cosign for charts in HTTP repositories", func() {
+		secret := getSecret(getPEMPublicKey(signingKey))
+		helmChart := getHelmChart(secret)
+		helmChart.RepositoryURL = server.URL
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+		_, err := controllers.VerifyHelmChart(context.TODO(), c, clusterSummary, helmChart,
+			true, textlogger.NewLogger(textlogger.NewConfig()))
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("supported only for charts in OCI registries"))
+	})
+
+	It("parseCosignPublicKeys returns all PEM encoded public keys", func() {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).To(BeNil())
+
+		secret := getSecret(append(getPEMPublicKey(signingKey), getPEMPublicKey(otherKey)...))
+		publicKeys, err := controllers.ParseCosignPublicKeys(secret)
+		Expect(err).To(BeNil())
+		Expect(len(publicKeys)).To(Equal(2))
+
+		_, err = controllers.ParseCosignPublicKeys(getSecret([]byte("not a key")))
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("getRegistryCredentialsSecrets includes the verification Secret", func() {
+		secret := getSecret(getPEMPublicKey(signingKey))
+		helmChart := getHelmChart(secret)
+
+		secrets := controllers.GetRegistryCredentialsSecrets(clusterSummary.Spec.ClusterNamespace, helmChart)
+		Expect(secrets).To(ContainElement(types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}))
+	})
+})
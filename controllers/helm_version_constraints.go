@@ -0,0 +1,336 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// A HelmChart ChartVersion can be a semver constraint (for instance ">=2.5.0 <3.0.0") or latest
+// instead of an exact version. Constraints are resolved, when ClusterProfile/Profile is reconciled,
+// to the highest version published in the repository index (OCI registry tags for OCI charts).
+// Resolved versions are recorded in the ClusterProfile/Profile Status and ClusterSummaries are given
+// the resolved Spec. So when a newer matching version is published, it is rolled out like any other
+// Spec change (MaxUpdate and RolloutRings are respected). The versions actually deployed (chart and
+// app version, release revision) are reported in ClusterConfiguration.
+// Repository indexes are fetched at most once every chartIndexRefreshInterval.
+
+const (
+	defaultChartIndexRefreshInterval = 10 * time.Minute
+
+	// latestChartVersion requests the highest published chart version
+	latestChartVersion = "latest"
+)
+
+var (
+	chartIndexRefreshInterval = defaultChartIndexRefreshInterval
+
+	resolvedChartVersionsMux   sync.Mutex
+	resolvedChartVersionsCache = map[string]*resolvedChartVersionCacheEntry{}
+)
+
+type resolvedChartVersionCacheEntry struct {
+	version   string
+	fetchTime time.Time
+}
+
+// SetChartIndexRefreshInterval sets how often helm repository indexes are fetched to resolve
+// helm chart version constraints. Non positive values mean default is used.
+func SetChartIndexRefreshInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultChartIndexRefreshInterval
+	}
+	chartIndexRefreshInterval = interval
+}
+
+func getChartIndexRefreshInterval() time.Duration {
+	return chartIndexRefreshInterval
+}
+
+// isChartVersionConstraint returns true if chartVersion is not an exact version
+func isChartVersionConstraint(chartVersion string) bool {
+	if chartVersion == "" {
+		return false
+	}
+	_, err := semver.NewVersion(chartVersion)
+	return err != nil
+}
+
+// hasChartVersionConstraints returns true if any helm chart in spec uses a version constraint
+func hasChartVersionConstraints(spec *configv1beta1.Spec) bool {
+	for i := range spec.HelmCharts {
+		if isChartVersionConstraint(spec.HelmCharts[i].ChartVersion) {
+			return true
+		}
+	}
+	return false
+}
+
+// getChartVersionConstraint returns the semver constraint corresponding to chartVersion
+func getChartVersionConstraint(chartVersion string) string {
+	if strings.EqualFold(chartVersion, latestChartVersion) {
+		// Empty constraint matches any version
+		return ""
+	}
+	return chartVersion
+}
+
+// getResolvedChartVersionCacheKey returns the key of a resolved version. Tenant admin is part of the
+// key, so a version resolved with registry credentials a tenant can get is not reused by another one.
+func getResolvedChartVersionCacheKey(clusterSummary *configv1beta1.ClusterSummary,
+	requestedChart *configv1beta1.HelmChart) string {
+
+	adminNamespace, adminName := getClusterSummaryAdmin(clusterSummary)
+	return fmt.Sprintf("%s|%s|%s|%s/%s", requestedChart.RepositoryURL, requestedChart.ChartName,
+		requestedChart.ChartVersion, adminNamespace, adminName)
+}
+
+// getChartVersionMatchingConstraint returns the highest published version of the chart matching
+// the ChartVersion constraint. Repository is contacted only if last result is older than
+// chartIndexRefreshInterval.
+func getChartVersionMatchingConstraint(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, namespace string, requestedChart *configv1beta1.HelmChart,
+	logger logr.Logger) (string, error) {
+
+	key := getResolvedChartVersionCacheKey(clusterSummary, requestedChart)
+
+	resolvedChartVersionsMux.Lock()
+	entry, ok := resolvedChartVersionsCache[key]
+	resolvedChartVersionsMux.Unlock()
+	if ok && time.Since(entry.fetchTime) < getChartIndexRefreshInterval() {
+		return entry.version, nil
+	}
+
+	version, err := fetchChartVersionMatchingConstraint(ctx, c, clusterSummary, namespace, requestedChart)
+	if err != nil {
+		return "", err
+	}
+
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("chart %s version constraint %q resolved to %s",
+		requestedChart.ChartName, requestedChart.ChartVersion, version))
+
+	resolvedChartVersionsMux.Lock()
+	resolvedChartVersionsCache[key] = &resolvedChartVersionCacheEntry{version: version, fetchTime: time.Now()}
+	resolvedChartVersionsMux.Unlock()
+
+	return version, nil
+}
+
+// fetchChartVersionMatchingConstraint looks for the highest version matching the ChartVersion
+// constraint in the repository index (tags for OCI registries)
+func fetchChartVersionMatchingConstraint(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, namespace string, requestedChart *configv1beta1.HelmChart,
+) (string, error) {
+
+	credentialsPath, caPath, err := getCredentialsAndCAFiles(ctx, c, clusterSummary, namespace, requestedChart)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if credentialsPath != "" {
+			os.Remove(credentialsPath)
+		}
+		if caPath != "" {
+			os.Remove(caPath)
+		}
+	}()
+
+	registryOptions := &registryClientOptions{
+		credentialsPath: credentialsPath, caPath: caPath,
+		skipTLSVerify: getInsecureSkipTLSVerify(requestedChart),
+		plainHTTP:     getPlainHTTP(requestedChart),
+	}
+
+	if registry.IsOCI(requestedChart.RepositoryURL) {
+		return fetchOCIChartVersionMatchingConstraint(namespace, requestedChart, registryOptions)
+	}
+
+	return fetchRepoChartVersionMatchingConstraint(namespace, requestedChart, registryOptions)
+}
+
+func fetchOCIChartVersionMatchingConstraint(namespace string, requestedChart *configv1beta1.HelmChart,
+	registryOptions *registryClientOptions) (string, error) {
+
+	registryClient, err := getRegistryClient(namespace, registryOptions, false)
+	if err != nil {
+		return "", err
+	}
+
+	ref := path.Join(strings.TrimPrefix(requestedChart.RepositoryURL, fmt.Sprintf("%s://", registry.OCIScheme)),
+		requestedChart.ChartName)
+	tags, err := registryClient.Tags(ref)
+	if err != nil {
+		return "", withMessageCode(getChartPullMessageCode(err), err)
+	}
+
+	// Tags are sorted from highest to lowest version
+	version, err := registry.GetTagMatchingVersionOrConstraint(tags,
+		getChartVersionConstraint(requestedChart.ChartVersion))
+	if err != nil {
+		return "", &NonRetriableError{
+			Message: fmt.Sprintf("no version of chart %s matches %q", requestedChart.ChartName,
+				requestedChart.ChartVersion),
+		}
+	}
+	return version, nil
+}
+
+func fetchRepoChartVersionMatchingConstraint(namespace string, requestedChart *configv1beta1.HelmChart,
+	registryOptions *registryClientOptions) (string, error) {
+
+	settings := getSettings(namespace, registryOptions)
+	getters, err := getHelmGetters(settings)
+	if err != nil {
+		return "", err
+	}
+
+	entry := &repo.Entry{
+		Name:                  requestedChart.RepositoryName,
+		URL:                   requestedChart.RepositoryURL,
+		CAFile:                registryOptions.caPath,
+		InsecureSkipTLSverify: registryOptions.skipTLSVerify,
+	}
+	chartRepo, err := repo.NewChartRepository(entry, getters)
+	if err != nil {
+		return "", err
+	}
+
+	// Index is downloaded in a private directory so it never races with helm install/upgrade
+	// refreshing the same repository
+	chartRepo.CachePath, err = os.MkdirTemp("", "sveltos-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(chartRepo.CachePath)
+
+	indexPath, err := chartRepo.DownloadIndexFile()
+	if err != nil {
+		return "", withMessageCode(getChartPullMessageCode(err), err)
+	}
+
+	index, err := repo.LoadIndexFile(indexPath)
+	if err != nil {
+		return "", err
+	}
+
+	// LoadIndexFile sorts versions from highest to lowest
+	chartVersion, err := index.Get(requestedChart.ChartName, getChartVersionConstraint(requestedChart.ChartVersion))
+	if err != nil {
+		return "", &NonRetriableError{
+			Message: fmt.Sprintf("no version of chart %s matches %q", requestedChart.ChartName,
+				requestedChart.ChartVersion),
+		}
+	}
+	return chartVersion.Version, nil
+}
+
+// findResolvedChartVersion returns the resolution of the ChartVersion constraint of requestedChart
+func findResolvedChartVersion(resolved []configv1beta1.ResolvedChartVersion,
+	requestedChart *configv1beta1.HelmChart) *configv1beta1.ResolvedChartVersion {
+
+	for i := range resolved {
+		if resolved[i].ReleaseNamespace == requestedChart.ReleaseNamespace &&
+			resolved[i].ReleaseName == requestedChart.ReleaseName &&
+			resolved[i].VersionConstraint == requestedChart.ChartVersion {
+
+			return &resolved[i]
+		}
+	}
+	return nil
+}
+
+// resolveChartVersionConstraints resolves the ChartVersion constraints of all helm charts in the
+// ClusterProfile/Profile and records the result in Status. If resolution fails, the previously
+// resolved version (if any) is kept.
+func resolveChartVersionConstraints(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	logger logr.Logger) {
+
+	spec := profileScope.GetSpec()
+	previous := profileScope.GetStatus().ResolvedChartVersions
+
+	// ClusterSummaries carry the ClusterProfile/Profile tenant admin labels. Registry credentials
+	// are verified against the same tenant admin.
+	admin := &configv1beta1.ClusterSummary{
+		ObjectMeta: metav1.ObjectMeta{Labels: profileScope.Profile.GetLabels()},
+	}
+
+	var resolved []configv1beta1.ResolvedChartVersion
+	for i := range spec.HelmCharts {
+		requestedChart := &spec.HelmCharts[i]
+		if !isChartVersionConstraint(requestedChart.ChartVersion) {
+			continue
+		}
+
+		current := configv1beta1.ResolvedChartVersion{
+			ReleaseNamespace:  requestedChart.ReleaseNamespace,
+			ReleaseName:       requestedChart.ReleaseName,
+			VersionConstraint: requestedChart.ChartVersion,
+		}
+
+		version, err := getChartVersionMatchingConstraint(ctx, c, admin, profileScope.Namespace(), requestedChart, logger)
+		if err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to resolve chart %s version constraint %q: %v",
+				requestedChart.ChartName, requestedChart.ChartVersion, err))
+			failureMessage := err.Error()
+			current.FailureMessage = &failureMessage
+			if p := findResolvedChartVersion(previous, requestedChart); p != nil {
+				current.ChartVersion = p.ChartVersion
+			}
+		} else {
+			current.ChartVersion = version
+		}
+
+		resolved = append(resolved, current)
+	}
+
+	profileScope.GetStatus().ResolvedChartVersions = resolved
+}
+
+// getResolvedSpec returns the ClusterProfile/Profile Spec with the ChartVersion constraints replaced
+// by the versions they resolved to. This is the Spec given to ClusterSummaries.
+func getResolvedSpec(profileScope *scope.ProfileScope) *configv1beta1.Spec {
+	resolved := profileScope.GetStatus().ResolvedChartVersions
+	if len(resolved) == 0 {
+		return profileScope.GetSpec()
+	}
+
+	spec := profileScope.GetSpec().DeepCopy()
+	for i := range spec.HelmCharts {
+		requestedChart := &spec.HelmCharts[i]
+		if r := findResolvedChartVersion(resolved, requestedChart); r != nil && r.ChartVersion != "" {
+			requestedChart.ChartVersion = r.ChartVersion
+		}
+	}
+	return spec
+}
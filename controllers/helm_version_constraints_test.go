@@ -0,0 +1,196 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+)
+
+var _ = Describe("Helm chart version constraints", func() {
+	var server *httptest.Server
+	var clusterProfile *configv1beta1.ClusterProfile
+	var c client.Client
+
+	// startRepository starts a helm repository whose index contains chart nginx with given versions
+	startRepository := func(versions ...string) *httptest.Server {
+		index := "apiVersion: v1\nentries:\n  nginx:\n"
+		for _, v := range versions {
+			index += fmt.Sprintf("  - apiVersion: v2\n    name: nginx\n    version: %s\n"+
+				"    urls:\n    - nginx-%s.tgz\n", v, v)
+		}
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/index.yaml" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(index))
+		}))
+	}
+
+	getHelmChart := func(chartVersion string) configv1beta1.HelmChart {
+		return configv1beta1.HelmChart{
+			RepositoryURL:    server.URL,
+			RepositoryName:   randomString(),
+			ChartName:        "nginx",
+			ChartVersion:     chartVersion,
+			ReleaseName:      randomString(),
+			ReleaseNamespace: randomString(),
+		}
+	}
+
+	getProfileScope := func() *scope.ProfileScope {
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logr.Discard(),
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+		return profileScope
+	}
+
+	BeforeEach(func() {
+		server = startRepository("2.4.0", "2.5.1", "2.6.0", "3.0.0")
+
+		clusterProfile = &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: randomString(),
+			},
+		}
+		Expect(addTypeInformationToObject(scheme, clusterProfile)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("isChartVersionConstraint returns true only for constraints", func() {
+		Expect(controllers.IsChartVersionConstraint("1.2.3")).To(BeFalse())
+		Expect(controllers.IsChartVersionConstraint("v1.2.3")).To(BeFalse())
+		Expect(controllers.IsChartVersionConstraint("")).To(BeFalse())
+		Expect(controllers.IsChartVersionConstraint(">=2.5.0 <3.0.0")).To(BeTrue())
+		Expect(controllers.IsChartVersionConstraint("~2.5")).To(BeTrue())
+		Expect(controllers.IsChartVersionConstraint("latest")).To(BeTrue())
+	})
+
+	It("resolveChartVersionConstraints resolves latest to the highest published version", func() {
+		clusterProfile.Spec.HelmCharts = []configv1beta1.HelmChart{getHelmChart("latest")}
+
+		profileScope := getProfileScope()
+		controllers.ResolveChartVersionConstraints(context.TODO(), c, profileScope, logr.Discard())
+
+		resolved := profileScope.GetStatus().ResolvedChartVersions
+		Expect(len(resolved)).To(Equal(1))
+		Expect(resolved[0].ChartVersion).To(Equal("3.0.0"))
+		Expect(controllers.GetResolvedSpec(profileScope).HelmCharts[0].ChartVersion).To(Equal("3.0.0"))
+	})
+
+	It("resolveChartVersionConstraints resolves constraints to the highest matching version", func() {
+		exact := getHelmChart("2.4.0")
+		constrained := getHelmChart(">=2.5.0 <3.0.0")
+		clusterProfile.Spec.HelmCharts = []configv1beta1.HelmChart{exact, constrained}
+
+		profileScope := getProfileScope()
+		controllers.ResolveChartVersionConstraints(context.TODO(), c, profileScope, logr.Discard())
+
+		resolved := profileScope.GetStatus().ResolvedChartVersions
+		Expect(len(resolved)).To(Equal(1))
+		Expect(resolved[0].ReleaseNamespace).To(Equal(constrained.ReleaseNamespace))
+		Expect(resolved[0].ReleaseName).To(Equal(constrained.ReleaseName))
+		Expect(resolved[0].VersionConstraint).To(Equal(constrained.ChartVersion))
+		Expect(resolved[0].ChartVersion).To(Equal("2.6.0"))
+		Expect(resolved[0].FailureMessage).To(BeNil())
+
+		// Spec is left untouched, resolved Spec has the resolved version
+		Expect(profileScope.GetSpec().HelmCharts[1].ChartVersion).To(Equal(constrained.ChartVersion))
+		spec := controllers.GetResolvedSpec(profileScope)
+		Expect(spec.HelmCharts[0].ChartVersion).To(Equal("2.4.0"))
+		Expect(spec.HelmCharts[1].ChartVersion).To(Equal("2.6.0"))
+	})
+
+	It("resolveChartVersionConstraints keeps previously resolved version when resolution fails", func() {
+		constrained := getHelmChart("~2.5")
+		constrained.RepositoryURL = server.URL + "/missing"
+		clusterProfile.Spec.HelmCharts = []configv1beta1.HelmChart{constrained}
+		clusterProfile.Status.ResolvedChartVersions = []configv1beta1.ResolvedChartVersion{
+			{
+				ReleaseNamespace:  constrained.ReleaseNamespace,
+				ReleaseName:       constrained.ReleaseName,
+				VersionConstraint: constrained.ChartVersion,
+				ChartVersion:      "2.5.0",
+			},
+		}
+
+		profileScope := getProfileScope()
+		controllers.ResolveChartVersionConstraints(context.TODO(), c, profileScope, logr.Discard())
+
+		resolved := profileScope.GetStatus().ResolvedChartVersions
+		Expect(len(resolved)).To(Equal(1))
+		Expect(resolved[0].ChartVersion).To(Equal("2.5.0"))
+		Expect(resolved[0].FailureMessage).ToNot(BeNil())
+
+		spec := controllers.GetResolvedSpec(profileScope)
+		Expect(spec.HelmCharts[0].ChartVersion).To(Equal("2.5.0"))
+	})
+
+	It("resolveChartVersionConstraints reports constraints no published version matches", func() {
+		clusterProfile.Spec.HelmCharts = []configv1beta1.HelmChart{getHelmChart(">=4.0.0")}
+
+		profileScope := getProfileScope()
+		controllers.ResolveChartVersionConstraints(context.TODO(), c, profileScope, logr.Discard())
+
+		resolved := profileScope.GetStatus().ResolvedChartVersions
+		Expect(len(resolved)).To(Equal(1))
+		Expect(resolved[0].ChartVersion).To(BeEmpty())
+		Expect(resolved[0].FailureMessage).To(Equal(ptr.To(`no version of chart nginx matches ">=4.0.0"`)))
+
+		// Not resolved constraints are left for helm to resolve
+		spec := controllers.GetResolvedSpec(profileScope)
+		Expect(spec.HelmCharts[0].ChartVersion).To(Equal(">=4.0.0"))
+	})
+
+	It("resolved versions are forgotten once Spec uses an exact version", func() {
+		constrained := getHelmChart(">=2.5.0 <3.0.0")
+		clusterProfile.Spec.HelmCharts = []configv1beta1.HelmChart{constrained}
+
+		profileScope := getProfileScope()
+		controllers.ResolveChartVersionConstraints(context.TODO(), c, profileScope, logr.Discard())
+		Expect(len(profileScope.GetStatus().ResolvedChartVersions)).To(Equal(1))
+
+		profileScope.GetSpec().HelmCharts[0].ChartVersion = "2.4.0"
+		controllers.ResolveChartVersionConstraints(context.TODO(), c, profileScope, logr.Discard())
+		Expect(profileScope.GetStatus().ResolvedChartVersions).To(BeNil())
+		Expect(controllers.GetResolvedSpec(profileScope).HelmCharts[0].ChartVersion).To(Equal("2.4.0"))
+	})
+})
@@ -0,0 +1,70 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+)
+
+// keyedMutex is a set of mutexes, one per key. A key's mutex only exists while held or
+// waited for, so keys used once (deleted clusters, old chart versions) do not accumulate.
+type keyedMutex struct {
+	mux   sync.Mutex
+	locks map[string]*refCountedMutex
+}
+
+type refCountedMutex struct {
+	sync.Mutex
+	// refs is the number of goroutines holding or waiting for the mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: map[string]*refCountedMutex{}}
+}
+
+// lock acquires the mutex for key. Returns the function releasing it.
+func (k *keyedMutex) lock(key string) func() {
+	k.mux.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &refCountedMutex{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mux.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		k.mux.Lock()
+		defer k.mux.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+	}
+}
+
+// len returns the number of keys currently held or waited for
+func (k *keyedMutex) len() int {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	return len(k.locks)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// When the kubeconfig Secret of a managed cluster is rotated, what is cached for the cluster
+// (rest configs, clients and the connection check result) is dropped and all ClusterSummaries
+// for the cluster are reconciled, so deployments which failed with the old credentials are
+// retried right away instead of once their backoff expires.
+// Helm kubeconfigs are always built from the kubeconfig Secret. Clients are cached per identity
+// (credentials included), so clients using the old credentials are never used again anyway.
+
+const (
+	sveltosKubeconfigSecretNamePostfix = "-sveltos-kubeconfig"
+	capiKubeconfigSecretNamePostfix    = "-kubeconfig"
+)
+
+// getClusterForKubeconfigSecret returns the managed cluster whose kubeconfig is stored in secret.
+// Nil is returned if secret is not a cluster kubeconfig Secret.
+func getClusterForKubeconfigSecret(ctx context.Context, c client.Client, secret client.Object,
+) (client.Object, error) {
+
+	sveltosClusters := &libsveltosv1beta1.SveltosClusterList{}
+	if err := c.List(ctx, sveltosClusters, client.InNamespace(secret.GetNamespace())); err != nil {
+		return nil, err
+	}
+	for i := range sveltosClusters.Items {
+		cluster := &sveltosClusters.Items[i]
+		secretName := cluster.Spec.KubeconfigName
+		if secretName == "" {
+			secretName = cluster.Name + sveltosKubeconfigSecretNamePostfix
+		}
+		if secretName == secret.GetName() {
+			return cluster, nil
+		}
+	}
+
+	// ClusterAPI stores the kubeconfig in Secret <cluster name>-kubeconfig
+	clusterName := secret.GetLabels()[clusterv1.ClusterNameLabel]
+	if clusterName != "" && secret.GetName() == clusterName+capiKubeconfigSecretNamePostfix {
+		return &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: secret.GetNamespace(), Name: clusterName},
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// invalidateClusterConnection drops what is cached about the connection to the managed cluster
+func invalidateClusterConnection(clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType) {
+	prefix := getConnectionProbeClusterKey(clusterNamespace, clusterName, clusterType)
+
+	connectionProbeMux.Lock()
+	defer connectionProbeMux.Unlock()
+	for key := range connectionProbes {
+		if strings.HasPrefix(key, prefix) {
+			delete(connectionProbes, key)
+		}
+	}
+}
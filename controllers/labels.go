@@ -18,20 +18,22 @@ package controllers
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectsveltos/addon-controller/pkg/addonclient"
 )
 
 const (
 	// ClusterSummaryLabelName is added to each policy deployed by a ClusterSummary
 	// instance to a CAPI Cluster
-	ClusterSummaryLabelName = "projectsveltos.io/cluster-summary-name"
+	ClusterSummaryLabelName = addonclient.ClusterSummaryLabelName
 
 	// ClusterProfileLabelName is added to all ClusterSummary instances created
 	// by a ClusterProfile instance
-	ClusterProfileLabelName = "projectsveltos.io/cluster-profile-name"
+	ClusterProfileLabelName = addonclient.ClusterProfileLabelName
 
 	// ProfileLabelName is added to all ClusterSummary instances created
 	// by a Profile instance
-	ProfileLabelName = "projectsveltos.io/profile-name"
+	ProfileLabelName = addonclient.ProfileLabelName
 )
 
 // addLabel adds label to an object
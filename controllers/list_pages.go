@@ -0,0 +1,82 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultListPageSize = 500
+)
+
+var (
+	listPageSize int64 = defaultListPageSize
+)
+
+// SetListPageSize sets the maximum number of objects fetched per List call when listing
+// cluster-wide resources (ClusterSummaries, ClusterConfigurations, ClusterReports).
+// Zero disables pagination.
+func SetListPageSize(size int64) {
+	if size < 0 {
+		size = 0
+	}
+	listPageSize = size
+}
+
+// forEachListItem lists objects in pages of listPageSize objects, invoking process for
+// each object. Only one page is kept in memory at any given time.
+// Reader must not be the manager cache: the cache does not support continue tokens and
+// would silently truncate results to the first page. Use the manager APIReader instead.
+func forEachListItem(ctx context.Context, reader client.Reader, list client.ObjectList,
+	process func(obj client.Object) error, opts ...client.ListOption) error {
+
+	continueToken := ""
+	for {
+		pageOptions := append([]client.ListOption{}, opts...)
+		if listPageSize > 0 {
+			pageOptions = append(pageOptions, client.Limit(listPageSize), client.Continue(continueToken))
+		}
+
+		if err := reader.List(ctx, list, pageOptions...); err != nil {
+			return err
+		}
+
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+
+		for i := range items {
+			obj, ok := items[i].(client.Object)
+			if !ok {
+				continue
+			}
+			if err := process(obj); err != nil {
+				return err
+			}
+		}
+
+		continueToken = list.GetContinue()
+		if listPageSize == 0 || continueToken == "" {
+			return nil
+		}
+	}
+}
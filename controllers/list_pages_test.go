@@ -0,0 +1,110 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+// pagingReader serves ClusterSummaryList in pages, like the API server does (fake client ignores
+// Limit/Continue). Continue token is the index of the first item of next page.
+type pagingReader struct {
+	client.Reader
+	calls int
+}
+
+func (r *pagingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	r.calls++
+
+	listOptions := &client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+
+	all := &configv1beta1.ClusterSummaryList{}
+	if err := r.Reader.List(ctx, all); err != nil {
+		return err
+	}
+
+	start := 0
+	if listOptions.Continue != "" {
+		var err error
+		start, err = strconv.Atoi(listOptions.Continue)
+		Expect(err).To(BeNil())
+	}
+	end := start + int(listOptions.Limit)
+	if listOptions.Limit == 0 || end > len(all.Items) {
+		end = len(all.Items)
+	}
+
+	page := list.(*configv1beta1.ClusterSummaryList)
+	page.Items = all.Items[start:end]
+	page.Continue = ""
+	if end < len(all.Items) {
+		page.Continue = strconv.Itoa(end)
+	}
+	return nil
+}
+
+var _ = Describe("List pages", func() {
+	AfterEach(func() {
+		controllers.SetListPageSize(500)
+	})
+
+	It("forEachListItem processes all objects one page at a time", func() {
+		const objects = 5
+		initObjects := make([]client.Object, objects)
+		for i := range initObjects {
+			initObjects[i] = &configv1beta1.ClusterSummary{
+				ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()},
+			}
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+		reader := &pagingReader{Reader: c}
+
+		processed := map[string]bool{}
+		process := func(obj client.Object) error {
+			processed[obj.GetNamespace()+"/"+obj.GetName()] = true
+			return nil
+		}
+
+		controllers.SetListPageSize(2)
+		Expect(controllers.ForEachListItem(context.TODO(), reader, &configv1beta1.ClusterSummaryList{},
+			process)).To(Succeed())
+		Expect(reader.calls).To(Equal(3))
+		Expect(len(processed)).To(Equal(objects))
+
+		// Pagination disabled: one single List
+		processed = map[string]bool{}
+		reader.calls = 0
+		controllers.SetListPageSize(0)
+		Expect(controllers.ForEachListItem(context.TODO(), reader, &configv1beta1.ClusterSummaryList{},
+			process)).To(Succeed())
+		Expect(reader.calls).To(Equal(1))
+		Expect(len(processed)).To(Equal(objects))
+	})
+})
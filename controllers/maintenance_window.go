@@ -0,0 +1,253 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// A MaintenanceWindow restricts when add-ons can be deployed to the clusters it matches.
+// Clusters matching no MaintenanceWindow can be updated at any time. Clusters matching at least
+// one can be updated only while one of the matching windows is open. Outside, ClusterSummary is
+// considered paused: ClusterSummary Spec keeps being updated by the owner ClusterProfile/Profile
+// and pending changes are deployed once a window opens.
+// Windows do not apply to DryRun mode (nothing is changed in the managed cluster) nor to
+// ClusterSummary deletion.
+
+// maxScheduleLookahead is how far in the future the next window opening is searched for
+const maxScheduleLookahead = 5 * 366 * 24 * time.Hour
+
+// cronSchedule is a parsed cron expression (minute hour day-of-month month day-of-week)
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar/dowStar are set when day-of-month/day-of-week is "*". When both are
+	// restricted, a day matches if either one matches (like cron does).
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{
+	{min: 0, max: 59}, // minute
+	{min: 0, max: 23}, // hour
+	{min: 1, max: 31}, // day of month
+	{min: 1, max: 12}, // month
+	{min: 0, max: 7},  // day of week (0 and 7 are Sunday)
+}
+
+// parseCronSchedule parses a standard 5 fields cron expression. Each field
+// supports "*", values, ranges (a-b), steps (*/n, a-b/n) and comma separated lists.
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule %q: expected %d fields, found %d",
+			expr, len(cronFields), len(fields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i := range fields {
+		var err error
+		bits[i], err = parseCronField(fields[i], cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+		}
+	}
+
+	// Sunday can be either 0 or 7
+	dow := bits[4]
+	if dow&(1<<7) != 0 {
+		dow |= 1
+	}
+
+	return &cronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     dow,
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		low, high := bounds.min, bounds.max
+		if rangePart != "*" {
+			var err error
+			values := strings.SplitN(rangePart, "-", 2)
+			low, err = strconv.Atoi(values[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			high = low
+			if len(values) == 2 {
+				high, err = strconv.Atoi(values[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid value in %q", part)
+				}
+			} else if step != 1 {
+				// a/n means from a to max every n
+				high = bounds.max
+			}
+		}
+
+		if low < bounds.min || high > bounds.max || low > high {
+			return 0, fmt.Errorf("value out of range [%d-%d] in %q", bounds.min, bounds.max, part)
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next returns the first activation time at or after t. Zero time is returned if there
+// is none within maxScheduleLookahead.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.UTC()
+	if truncated := t.Truncate(time.Minute); !truncated.Equal(t) {
+		t = truncated.Add(time.Minute)
+	}
+
+	limit := t.Add(maxScheduleLookahead)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// getMaintenanceWindowState returns whether a window opened at schedule and lasting duration is
+// open at now and, if not, when it opens next (zero if never).
+func getMaintenanceWindowState(schedule *cronSchedule, duration time.Duration, now time.Time,
+) (open bool, nextOpen time.Time) {
+
+	if duration > 0 {
+		// The last activation opening a window still open is after now-duration
+		start := schedule.next(now.Add(-duration).Add(time.Nanosecond))
+		if !start.IsZero() && !start.After(now) {
+			return true, time.Time{}
+		}
+	}
+
+	return false, schedule.next(now)
+}
+
+// isInMaintenanceWindow returns true if add-ons can be deployed to the cluster at now. If not,
+// it also returns when the first window matching the cluster opens next (zero if never).
+func isInMaintenanceWindow(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType, now time.Time) (bool, time.Time, error) {
+
+	windows := &configv1beta1.MaintenanceWindowList{}
+	if err := c.List(ctx, windows); err != nil {
+		return false, time.Time{}, err
+	}
+	if len(windows.Items) == 0 {
+		return true, time.Time{}, nil
+	}
+
+	cluster, err := getCluster(ctx, c, clusterNamespace, clusterName, clusterType)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, time.Time{}, nil
+		}
+		return false, time.Time{}, err
+	}
+	clusterLabels := labels.Set(cluster.GetLabels())
+
+	matching := false
+	var nextOpen time.Time
+	for i := range windows.Items {
+		window := &windows.Items[i]
+
+		selector, err := window.Spec.ClusterSelector.ToSelector()
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		if !selector.Matches(clusterLabels) {
+			continue
+		}
+		matching = true
+
+		schedule, err := parseCronSchedule(window.Spec.Schedule)
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("MaintenanceWindow %s: %w", window.Name, err)
+		}
+
+		open, next := getMaintenanceWindowState(schedule, window.Spec.Duration.Duration, now)
+		if open {
+			return true, time.Time{}, nil
+		}
+		if !next.IsZero() && (nextOpen.IsZero() || next.Before(nextOpen)) {
+			nextOpen = next
+		}
+	}
+
+	return !matching, nextOpen, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Maintenance windows", func() {
+	It("parseCronSchedule rejects invalid schedules", func() {
+		for _, schedule := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+			"* * * 13 *", "* * * * 8", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+			_, err := controllers.ParseCronSchedule(schedule)
+			Expect(err).ToNot(BeNil(), schedule)
+		}
+
+		_, err := controllers.ParseCronSchedule("0,30 1-5/2 * 1,6 0-7")
+		Expect(err).To(BeNil())
+	})
+
+	It("getMaintenanceWindowState returns whether window is open and when it opens next", func() {
+		// Every Saturday at 22:00 UTC
+		schedule, err := controllers.ParseCronSchedule("0 22 * * 6")
+		Expect(err).To(BeNil())
+
+		saturday := time.Date(2024, time.September, 14, 0, 0, 0, 0, time.UTC)
+		Expect(saturday.Weekday()).To(Equal(time.Saturday))
+		opening := saturday.Add(22 * time.Hour)
+
+		open, next := controllers.GetMaintenanceWindowState(schedule, 4*time.Hour, saturday.Add(10*time.Hour))
+		Expect(open).To(BeFalse())
+		Expect(next).To(Equal(opening))
+
+		open, _ = controllers.GetMaintenanceWindowState(schedule, 4*time.Hour, opening)
+		Expect(open).To(BeTrue())
+
+		// Window spans midnight
+		open, _ = controllers.GetMaintenanceWindowState(schedule, 4*time.Hour, opening.Add(3*time.Hour))
+		Expect(open).To(BeTrue())
+
+		open, next = controllers.GetMaintenanceWindowState(schedule, 4*time.Hour, opening.Add(4*time.Hour))
+		Expect(open).To(BeFalse())
+		Expect(next).To(Equal(opening.Add(7 * 24 * time.Hour)))
+	})
+
+	It("isInMaintenanceWindow considers only windows matching the cluster", func() {
+		namespace := randomString()
+		cluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      randomString(),
+				Labels:    map[string]string{"env": "production"},
+			},
+		}
+
+		now := time.Date(2024, time.September, 14, 10, 0, 0, 0, time.UTC)
+
+		// Cluster matches no window: it can always be updated
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster).Build()
+		inWindow, _, err := controllers.IsInMaintenanceWindow(context.TODO(), c, cluster.Namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos, now)
+		Expect(err).To(BeNil())
+		Expect(inWindow).To(BeTrue())
+
+		otherWindow := &configv1beta1.MaintenanceWindow{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+			Spec: configv1beta1.MaintenanceWindowSpec{
+				ClusterSelector: libsveltosv1beta1.Selector{
+					LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "staging"}},
+				},
+				Schedule: "0 0 1 1 *",
+				Duration: metav1.Duration{Duration: time.Hour},
+			},
+		}
+		productionWindow := &configv1beta1.MaintenanceWindow{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+			Spec: configv1beta1.MaintenanceWindowSpec{
+				ClusterSelector: libsveltosv1beta1.Selector{
+					LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "production"}},
+				},
+				Schedule: "0 22 * * *",
+				Duration: metav1.Duration{Duration: 2 * time.Hour},
+			},
+		}
+
+		initObjects := []client.Object{cluster, otherWindow, productionWindow}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+
+		inWindow, next, err := controllers.IsInMaintenanceWindow(context.TODO(), c, cluster.Namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos, now)
+		Expect(err).To(BeNil())
+		Expect(inWindow).To(BeFalse())
+		Expect(next).To(Equal(time.Date(2024, time.September, 14, 22, 0, 0, 0, time.UTC)))
+
+		inWindow, _, err = controllers.IsInMaintenanceWindow(context.TODO(), c, cluster.Namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos, now.Add(13*time.Hour))
+		Expect(err).To(BeNil())
+		Expect(inWindow).To(BeTrue())
+	})
+})
@@ -18,28 +18,102 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
 
 var (
 	managementClusterClient client.Client
 	managementClusterConfig *rest.Config
 	driftdetectionConfigMap string
+	driftDetectionImage     string
+	driftExcludedKinds      []configv1beta1.DriftExcludedKind
+	remoteRestConfigGetter  RemoteRestConfigGetter
+	remoteClientGetter      RemoteClientGetter
 )
 
+// RemoteRestConfigGetter returns the rest config to access a managed cluster. Returning a nil
+// rest config (and no error) means the default access (kubeconfig Secret of the cluster) is used.
+type RemoteRestConfigGetter func(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (*rest.Config, error)
+
+// RemoteClientGetter returns the client to access a managed cluster. Returning a nil client
+// (and no error) means the rest config to access the cluster is used to build one.
+type RemoteClientGetter func(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (client.Client, error)
+
 func SetManagementClusterAccess(c client.Client, config *rest.Config) {
 	managementClusterClient = c
 	managementClusterConfig = config
 }
 
+// SetRemoteRestConfigGetter overrides how the rest config to access managed clusters is obtained.
+// It is meant for tests, where managed clusters are for instance envtest API servers
+// (see pkg/workloadcluster). Tenant admins are not impersonated when the getter is used.
+// Passing nil restores the default behavior.
+func SetRemoteRestConfigGetter(getter RemoteRestConfigGetter) {
+	remoteRestConfigGetter = getter
+}
+
+// SetRemoteClientGetter overrides how the client to access managed clusters is obtained.
+// It is meant for scale tests, where managed clusters are fake clients (see pkg/scaletest).
+// Only code paths using a client (not a rest config) to access managed clusters use it.
+// Passing nil restores the default behavior.
+func SetRemoteClientGetter(getter RemoteClientGetter) {
+	remoteClientGetter = getter
+}
+
 func SetDriftdetectionConfigMap(name string) {
 	driftdetectionConfigMap = name
 }
 
+// SetDriftDetectionImage sets the image used for drift-detection-manager, replacing the
+// one in the embedded drift-detection-manager YAML (for instance to pull it from a registry
+// reachable from air-gapped clusters). Empty means embedded image is used.
+func SetDriftDetectionImage(image string) {
+	driftDetectionImage = image
+}
+
+// SetDriftExcludedKinds sets the kinds excluded from configuration drift tracking for all
+// ClusterProfiles/Profiles. Each entry is in the form apiVersion/Kind (for instance v1/Event
+// or batch/v1/Job). Version can be set to * to exclude all versions (for instance batch/*/Job).
+func SetDriftExcludedKinds(kinds []string) error {
+	driftExcludedKinds = make([]configv1beta1.DriftExcludedKind, 0, len(kinds))
+	for i := range kinds {
+		entry := strings.TrimSpace(kinds[i])
+		if entry == "" {
+			continue
+		}
+		index := strings.LastIndex(entry, "/")
+		if index <= 0 || index == len(entry)-1 {
+			return fmt.Errorf("invalid drift excluded kind %q. Expected format is apiVersion/Kind", entry)
+		}
+		gv, err := schema.ParseGroupVersion(entry[:index])
+		if err != nil {
+			return fmt.Errorf("invalid drift excluded kind %q: %w", entry, err)
+		}
+		if gv.Version == "*" {
+			gv.Version = ""
+		}
+		driftExcludedKinds = append(driftExcludedKinds, configv1beta1.DriftExcludedKind{
+			Group:   gv.Group,
+			Version: gv.Version,
+			Kind:    entry[index+1:],
+		})
+	}
+
+	return nil
+}
+
 func getManagementClusterConfig() *rest.Config {
 	return managementClusterConfig
 }
@@ -52,6 +126,14 @@ func getDriftDetectionConfigMap() string {
 	return driftdetectionConfigMap
 }
 
+func getDriftDetectionImage() string {
+	return driftDetectionImage
+}
+
+func getDriftExcludedKinds() []configv1beta1.DriftExcludedKind {
+	return driftExcludedKinds
+}
+
 func collectDriftDetectionConfigMap(ctx context.Context, name string) (*corev1.ConfigMap, error) {
 	c := getManagementClusterClient()
 	configMap := &corev1.ConfigMap{}
@@ -0,0 +1,267 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+)
+
+// A ClusterProfile/Profile can target the management cluster itself by listing, in ClusterRefs, a
+// SveltosCluster named configv1beta1.ManagementClusterName. No such SveltosCluster exists: the
+// functions in this file wrap the clusterproxy ones and, for this pseudo-cluster, short-circuit
+// to the in-cluster client instead of looking for the cluster and its kubeconfig Secret.
+// The management cluster is always considered ready and never paused.
+
+// getCluster returns the cluster. For the management cluster an in-memory SveltosCluster is returned.
+func getCluster(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (client.Object, error) {
+
+	if configv1beta1.IsManagementCluster(clusterName, clusterType) {
+		return getManagementClusterObject(clusterNamespace), nil
+	}
+
+	return clusterproxy.GetCluster(ctx, c, clusterNamespace, clusterName, clusterType)
+}
+
+func getManagementClusterObject(clusterNamespace string) *libsveltosv1beta1.SveltosCluster {
+	return &libsveltosv1beta1.SveltosCluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       libsveltosv1beta1.SveltosClusterKind,
+			APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: clusterNamespace,
+			Name:      configv1beta1.ManagementClusterName,
+		},
+		Status: libsveltosv1beta1.SveltosClusterStatus{
+			Ready: true,
+		},
+	}
+}
+
+func isClusterReadyToBeConfigured(ctx context.Context, c client.Client, cluster *corev1.ObjectReference,
+	logger logr.Logger) (bool, error) {
+
+	if configv1beta1.IsManagementCluster(cluster.Name, clusterproxy.GetClusterType(cluster)) {
+		return true, nil
+	}
+
+	return clusterproxy.IsClusterReadyToBeConfigured(ctx, c, cluster, logger)
+}
+
+func isClusterPaused(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (bool, error) {
+
+	if configv1beta1.IsManagementCluster(clusterName, clusterType) {
+		return false, nil
+	}
+
+	return clusterproxy.IsClusterPaused(ctx, c, clusterNamespace, clusterName, clusterType)
+}
+
+// getKubernetesRestConfig returns the rest config to access the cluster. For the management
+// cluster, a copy of the in-cluster rest config is returned.
+func getKubernetesRestConfig(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, adminNamespace, adminName string,
+	clusterType libsveltosv1beta1.ClusterType, logger logr.Logger) (*rest.Config, error) {
+
+	if configv1beta1.IsManagementCluster(clusterName, clusterType) {
+		if err := validateManagementClusterAdmin(adminNamespace, adminName); err != nil {
+			return nil, err
+		}
+		return setRemoteUserAgent(rest.CopyConfig(getManagementClusterConfig())), nil
+	}
+
+	restConfig, err := getRemoteRestConfigOverride(ctx, clusterNamespace, clusterName, clusterType)
+	if err != nil {
+		return nil, err
+	}
+	if restConfig == nil {
+		restConfig, err = getCachedRemoteRestConfig(clusterNamespace, clusterName, adminNamespace, adminName,
+			clusterType, func() (*rest.Config, error) {
+				return clusterproxy.GetKubernetesRestConfig(ctx, c, clusterNamespace, clusterName,
+					adminNamespace, adminName, clusterType, logger)
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = setClusterProxy(ctx, c, restConfig, clusterNamespace, clusterName, clusterType)
+	if err != nil {
+		return nil, err
+	}
+
+	return setRemoteUserAgent(restConfig), nil
+}
+
+// getKubernetesClient returns a client to access the cluster. For the management cluster, a
+// client reading directly from the API server, using the in-cluster rest config, is returned.
+func getKubernetesClient(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, adminNamespace, adminName string,
+	clusterType libsveltosv1beta1.ClusterType, logger logr.Logger) (client.Client, error) {
+
+	if configv1beta1.IsManagementCluster(clusterName, clusterType) {
+		restConfig, err := getKubernetesRestConfig(ctx, c, clusterNamespace, clusterName,
+			adminNamespace, adminName, clusterType, logger)
+		if err != nil {
+			return nil, err
+		}
+		return getRemoteClient(restConfig, c.Scheme())
+	}
+
+	if remoteClientGetter != nil {
+		remoteClient, err := remoteClientGetter(ctx, clusterNamespace, clusterName, clusterType)
+		if err != nil || remoteClient != nil {
+			return remoteClient, err
+		}
+	}
+
+	// Clients are built from the rest config so that every API call carries the addon-controller User-Agent
+	restConfig, err := getKubernetesRestConfig(ctx, c, clusterNamespace, clusterName,
+		adminNamespace, adminName, clusterType, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return getRemoteClient(restConfig, c.Scheme())
+}
+
+// getKubeconfig writes the kubeconfig to access the cluster in a temporary file and returns
+// its path. Caller must remove the file. For the management cluster, the kubeconfig is built
+// from the in-cluster rest config.
+func getKubeconfig(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName, adminNamespace, adminName string,
+	clusterType libsveltosv1beta1.ClusterType, logger logr.Logger) (string, error) {
+
+	var kubeconfigContent []byte
+	var err error
+	if configv1beta1.IsManagementCluster(clusterName, clusterType) {
+		if err = validateManagementClusterAdmin(adminNamespace, adminName); err != nil {
+			return "", err
+		}
+		kubeconfigContent, err = getKubeconfigFromRestConfig(getManagementClusterConfig())
+	} else {
+		var restConfig *rest.Config
+		restConfig, err = getRemoteRestConfigOverride(ctx, clusterNamespace, clusterName, clusterType)
+		if err != nil {
+			return "", err
+		}
+		if restConfig != nil {
+			kubeconfigContent, err = getKubeconfigFromRestConfig(restConfig)
+		} else {
+			kubeconfigContent, err = clusterproxy.GetSecretData(ctx, c, clusterNamespace, clusterName,
+				adminNamespace, adminName, clusterType, logger)
+		}
+		if err == nil {
+			kubeconfigContent, err = setKubeconfigClusterProxy(ctx, c, kubeconfigContent,
+				clusterNamespace, clusterName, clusterType)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return clusterproxy.CreateKubeconfig(logger, kubeconfigContent)
+}
+
+// getRemoteRestConfigOverride returns the rest config returned by the RemoteRestConfigGetter, if any is set.
+// Nil is returned if default access to the cluster must be used.
+func getRemoteRestConfigOverride(ctx context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (*rest.Config, error) {
+
+	if remoteRestConfigGetter == nil {
+		return nil, nil
+	}
+
+	restConfig, err := remoteRestConfigGetter(ctx, clusterNamespace, clusterName, clusterType)
+	if err != nil || restConfig == nil {
+		return nil, err
+	}
+
+	return rest.CopyConfig(restConfig), nil
+}
+
+// validateManagementClusterAdmin returns an error if a tenant admin is set. Tenant admins
+// cannot target the management cluster, as the in-cluster client has the controller permissions.
+func validateManagementClusterAdmin(adminNamespace, adminName string) error {
+	if adminName != "" {
+		return fmt.Errorf("management cluster cannot be targeted by tenant admin %s/%s",
+			adminNamespace, adminName)
+	}
+	return nil
+}
+
+// getKubeconfigFromRestConfig returns a kubeconfig equivalent to restConfig
+func getKubeconfigFromRestConfig(restConfig *rest.Config) ([]byte, error) {
+	const name = configv1beta1.ManagementClusterName
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[name] = &clientcmdapi.Cluster{
+		Server:                   restConfig.Host,
+		TLSServerName:            restConfig.ServerName,
+		InsecureSkipTLSVerify:    restConfig.Insecure,
+		CertificateAuthority:     restConfig.CAFile,
+		CertificateAuthorityData: restConfig.CAData,
+	}
+	kubeconfig.AuthInfos[name] = &clientcmdapi.AuthInfo{
+		ClientCertificate:     restConfig.CertFile,
+		ClientCertificateData: restConfig.CertData,
+		ClientKey:             restConfig.KeyFile,
+		ClientKeyData:         restConfig.KeyData,
+		Token:                 restConfig.BearerToken,
+		TokenFile:             restConfig.BearerTokenFile,
+		Username:              restConfig.Username,
+		Password:              restConfig.Password,
+		Exec:                  restConfig.ExecProvider,
+		AuthProvider:          restConfig.AuthProvider,
+	}
+	kubeconfig.Contexts[name] = &clientcmdapi.Context{
+		Cluster:  name,
+		AuthInfo: name,
+	}
+	kubeconfig.CurrentContext = name
+
+	return clientcmd.Write(*kubeconfig)
+}
+
+// isManagementClusterTargeted returns true if any ClusterSummary targets the management cluster
+func isManagementClusterTargeted(ctx context.Context, c client.Client) (bool, error) {
+	clusterSummaries := &configv1beta1.ClusterSummaryList{}
+	err := c.List(ctx, clusterSummaries, client.MatchingLabels{
+		configv1beta1.ClusterNameLabel: configv1beta1.ManagementClusterName,
+		configv1beta1.ClusterTypeLabel: string(libsveltosv1beta1.ClusterTypeSveltos),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(clusterSummaries.Items) > 0, nil
+}
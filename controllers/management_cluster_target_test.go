@@ -0,0 +1,86 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Management cluster target", func() {
+	It("getCluster returns a ready SveltosCluster for the management cluster", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		namespace := randomString()
+
+		cluster, err := controllers.GetCluster(context.TODO(), c, namespace,
+			configv1beta1.ManagementClusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(cluster.GetNamespace()).To(Equal(namespace))
+		sveltosCluster, ok := cluster.(*libsveltosv1beta1.SveltosCluster)
+		Expect(ok).To(BeTrue())
+		Expect(sveltosCluster.Status.Ready).To(BeTrue())
+
+		paused, err := controllers.IsClusterPaused(context.TODO(), c, namespace,
+			configv1beta1.ManagementClusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(paused).To(BeFalse())
+
+		// A CAPI Cluster with same name is not the management cluster
+		_, err = controllers.GetCluster(context.TODO(), c, namespace,
+			configv1beta1.ManagementClusterName, libsveltosv1beta1.ClusterTypeCapi)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("getKubernetesRestConfig rejects tenant admins for the management cluster", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+		_, err := controllers.GetKubernetesRestConfig(context.TODO(), c, randomString(),
+			configv1beta1.ManagementClusterName, randomString(), randomString(),
+			libsveltosv1beta1.ClusterTypeSveltos, logr.Discard())
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("getKubeconfigFromRestConfig returns an equivalent kubeconfig", func() {
+		restConfig := &rest.Config{
+			Host:            "https://10.0.0.1:443",
+			BearerTokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+			TLSClientConfig: rest.TLSClientConfig{
+				CAFile: "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt",
+			},
+		}
+
+		data, err := controllers.GetKubeconfigFromRestConfig(restConfig)
+		Expect(err).To(BeNil())
+
+		config, err := clientcmd.RESTConfigFromKubeConfig(data)
+		Expect(err).To(BeNil())
+		Expect(config.Host).To(Equal(restConfig.Host))
+		Expect(config.BearerTokenFile).To(Equal(restConfig.BearerTokenFile))
+		Expect(config.CAFile).To(Equal(restConfig.CAFile))
+	})
+})
@@ -0,0 +1,67 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+)
+
+// codedError is an error carrying the MessageCode reported in FeatureSummary.FailureReason
+// and in the feature condition message when deploying a feature fails.
+type codedError struct {
+	code configv1beta1.MessageCode
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withMessageCode wraps err with code. Nil is returned if err is nil.
+func withMessageCode(code configv1beta1.MessageCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// getMessageCode returns the MessageCode classifying a deployment error
+func getMessageCode(err error) configv1beta1.MessageCode {
+	var codedErr *codedError
+	if errors.As(err, &codedErr) {
+		return codedErr.code
+	}
+
+	var conflictErr *deployer.ConflictError
+	if errors.As(err, &conflictErr) {
+		return configv1beta1.MessageCodeResourceConflict
+	}
+
+	var nonRetriableErr *NonRetriableError
+	if errors.As(err, &nonRetriableErr) {
+		return configv1beta1.MessageCodeProvisioningFailedNonRetriable
+	}
+
+	return configv1beta1.MessageCodeProvisioningFailed
+}
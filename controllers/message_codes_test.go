@@ -0,0 +1,57 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+)
+
+var _ = Describe("Message codes", func() {
+	It("getMessageCode classifies deployment errors", func() {
+		Expect(controllers.WithMessageCode(configv1beta1.MessageCodeChartPullFailed, nil)).To(BeNil())
+
+		err := controllers.WithMessageCode(configv1beta1.MessageCodeChartPullFailed, errors.New(randomString()))
+		Expect(controllers.GetMessageCode(err)).To(Equal(configv1beta1.MessageCodeChartPullFailed))
+
+		// Code survives wrapping
+		err = fmt.Errorf("failed to deploy: %w", err)
+		Expect(controllers.GetMessageCode(err)).To(Equal(configv1beta1.MessageCodeChartPullFailed))
+
+		err = deployer.NewConflictError(randomString())
+		Expect(controllers.GetMessageCode(err)).To(Equal(configv1beta1.MessageCodeResourceConflict))
+
+		err = &controllers.NonRetriableError{Message: randomString()}
+		Expect(controllers.GetMessageCode(err)).To(Equal(configv1beta1.MessageCodeProvisioningFailedNonRetriable))
+
+		err = errors.New(randomString())
+		Expect(controllers.GetMessageCode(err)).To(Equal(configv1beta1.MessageCodeProvisioningFailed))
+	})
+
+	It("FormatMessage and GetMessageCode round trip", func() {
+		message := configv1beta1.FormatMessage(configv1beta1.MessageCodeRollbackFailed, randomString())
+		Expect(configv1beta1.GetMessageCode(message)).To(Equal(configv1beta1.MessageCodeRollbackFailed))
+		Expect(configv1beta1.GetMessageCode(randomString())).To(BeEmpty())
+	})
+})
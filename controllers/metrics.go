@@ -14,9 +14,12 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -46,12 +49,229 @@ var (
 			Buckets:   []float64{1, 10, 30, 60, 120, 180, 240},
 		},
 	)
+
+	chartCacheSizeGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "projectsveltos",
+			Name:      "chart_cache_size_bytes",
+			Help:      "Size of the helm repository cache",
+		},
+	)
+
+	chartCacheFilesGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "projectsveltos",
+			Name:      "chart_cache_files",
+			Help:      "Number of files in the helm repository cache",
+		},
+	)
+
+	chartCacheEvictionsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "projectsveltos",
+			Name:      "chart_cache_evictions_total",
+			Help:      "Number of files evicted from the helm repository cache",
+		},
+	)
+
+	chartArchiveCacheHitsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "projectsveltos",
+			Name:      "chart_archive_cache_hits_total",
+			Help:      "Number of times a chart archive was reused from the helm repository cache instead of downloaded",
+		},
+	)
+
+	chartRenderCacheHitsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "projectsveltos",
+			Name:      "chart_render_cache_hits_total",
+			Help:      "Number of times chart templates rendered for a release were reused instead of rendered again",
+		},
+	)
+
+	reconcileAPIWritesHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "projectsveltos",
+			Name:      "reconcile_api_writes",
+			Help:      "Number of API writes (create, update, patch, delete) issued by a reconciliation",
+			Buckets:   []float64{0, 1, 2, 5, 10, 20, 50},
+		},
+		[]string{"controller"},
+	)
+
+	driftCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "projectsveltos",
+			Name:      "configuration_drifts_total",
+			Help:      "Number of configuration drifts detected in managed clusters",
+		},
+		[]string{"cluster_type", "cluster_namespace", "cluster_name", "feature"},
+	)
+
+	timeToProvisionHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "projectsveltos",
+			Name:      "feature_time_to_provision_seconds",
+			Help:      "Time from a feature configuration change to the feature being provisioned",
+			Buckets:   []float64{1, 10, 30, 60, 120, 300, 600, 1800, 3600},
+		},
+		[]string{"cluster_type", "cluster_namespace", "cluster_name", "profile", "feature"},
+	)
+
+	healthVerificationDurationHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "projectsveltos",
+			Name:      "health_verification_time_seconds",
+			Help:      "Verify health of features deployed on a workload cluster duration distribution",
+			Buckets:   []float64{1, 10, 30, 60, 120, 180, 240},
+		},
+	)
+)
+
+const (
+	// deployPool is the pool of workers deploying features
+	deployPool = "deploy"
+	// verifyPool is the pool of workers verifying health of deployed features
+	verifyPool = "verify"
 )
 
 //nolint:gochecknoinits // forced pattern, can't workaround
 func init() {
 	// Register custom metrics with the global prometheus registry
-	metrics.Registry.MustRegister(programResourceDurationHistogram, programChartDurationHistogram)
+	metrics.Registry.MustRegister(programResourceDurationHistogram, programChartDurationHistogram,
+		chartCacheSizeGauge, chartCacheFilesGauge, chartCacheEvictionsCounter, chartArchiveCacheHitsCounter,
+		chartRenderCacheHitsCounter, healthVerificationDurationHistogram,
+		reconcileAPIWritesHistogram, driftCounter, timeToProvisionHistogram)
+}
+
+// observeReconcileAPIWrites reports the number of API writes issued by a reconciliation
+func observeReconcileAPIWrites(controllerName string, writes *atomic.Int64) {
+	reconcileAPIWritesHistogram.WithLabelValues(controllerName).Observe(float64(writes.Load()))
+}
+
+// recordDriftMetric counts a configuration drift detected in the cluster clusterSummary is for
+func recordDriftMetric(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID) {
+	driftCounter.WithLabelValues(string(clusterSummary.Spec.ClusterType), clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, string(featureID)).Inc()
+}
+
+// provisionStart is the time a feature configuration (identified by hash) changed
+type provisionStart struct {
+	hash []byte
+	time time.Time
+}
+
+var (
+	provisionStartsMux sync.Mutex
+	// key: ClusterSummary and feature; value: when current feature configuration changed
+	provisionStarts = make(map[string]*provisionStart)
+)
+
+func getProvisionStartKey(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID) string {
+	return fmt.Sprintf("%s/%s/%s", clusterSummary.Namespace, clusterSummary.Name, featureID)
+}
+
+// getClusterSummaryProfile returns the ClusterProfile/Profile owning clusterSummary in the form
+// kind/name for ClusterProfiles and kind/namespace/name for Profiles
+func getClusterSummaryProfile(clusterSummary *configv1beta1.ClusterSummary) string {
+	for i := range clusterSummary.OwnerReferences {
+		ref := &clusterSummary.OwnerReferences[i]
+		switch ref.Kind {
+		case configv1beta1.ClusterProfileKind:
+			return fmt.Sprintf("%s/%s", ref.Kind, ref.Name)
+		case configv1beta1.ProfileKind:
+			return fmt.Sprintf("%s/%s/%s", ref.Kind, clusterSummary.Namespace, ref.Name)
+		}
+	}
+	return ""
+}
+
+// trackTimeToProvision is invoked every time a feature status is updated. The time a new feature
+// configuration (hash) is first seen is recorded. Once the feature is provisioned with that hash,
+// the time elapsed since is observed.
+// Time is tracked in memory only: configuration changes pending on restart are not observed.
+func trackTimeToProvision(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID,
+	status configv1beta1.FeatureStatus, hash []byte, now time.Time) {
+
+	key := getProvisionStartKey(clusterSummary, featureID)
+
+	provisionStartsMux.Lock()
+	defer provisionStartsMux.Unlock()
+
+	switch status {
+	case configv1beta1.FeatureStatusRemoving, configv1beta1.FeatureStatusRemoved:
+		delete(provisionStarts, key)
+		return
+	case configv1beta1.FeatureStatusProvisioned:
+		start, ok := provisionStarts[key]
+		if !ok || !bytes.Equal(start.hash, hash) {
+			return
+		}
+		delete(provisionStarts, key)
+		timeToProvisionHistogram.WithLabelValues(string(clusterSummary.Spec.ClusterType),
+			clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+			getClusterSummaryProfile(clusterSummary), string(featureID)).Observe(now.Sub(start.time).Seconds())
+	default:
+		if start, ok := provisionStarts[key]; ok && bytes.Equal(start.hash, hash) {
+			return
+		}
+		provisionStarts[key] = &provisionStart{hash: hash, time: now}
+	}
+}
+
+// removeTimeToProvisionData forgets the feature configuration changes tracked for clusterSummary
+func removeTimeToProvisionData(clusterSummary *configv1beta1.ClusterSummary) {
+	prefix := fmt.Sprintf("%s/%s/", clusterSummary.Namespace, clusterSummary.Name)
+
+	provisionStartsMux.Lock()
+	defer provisionStartsMux.Unlock()
+	for key := range provisionStarts {
+		if strings.HasPrefix(key, prefix) {
+			delete(provisionStarts, key)
+		}
+	}
+}
+
+// registerWorkerPoolMetrics registers, for a pool of workers, gauges reporting the number of requests
+// queued and the number of busy workers. stats is invoked every time metrics are collected.
+func registerWorkerPoolMetrics(pool string, stats func() (queued, busy int), logger logr.Logger) {
+	queuedGauge := prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace:   "projectsveltos",
+			Name:        "worker_pool_queued_requests",
+			Help:        "Number of requests waiting for a worker of the pool",
+			ConstLabels: prometheus.Labels{"pool": pool},
+		},
+		func() float64 {
+			queued, _ := stats()
+			return float64(queued)
+		},
+	)
+
+	busyGauge := prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace:   "projectsveltos",
+			Name:        "worker_pool_busy_workers",
+			Help:        "Number of workers of the pool currently serving a request",
+			ConstLabels: prometheus.Labels{"pool": pool},
+		},
+		func() float64 {
+			_, busy := stats()
+			return float64(busy)
+		},
+	)
+
+	for _, collector := range []prometheus.Collector{queuedGauge, busyGauge} {
+		if err := metrics.Registry.Register(collector); err != nil {
+			logCollectorError(err, logger)
+		}
+	}
+}
+
+// RegisterDeployPoolMetrics registers metrics for the pool of workers deploying features
+func RegisterDeployPoolMetrics(stats func() (queued, busy int), logger logr.Logger) {
+	registerWorkerPoolMetrics(deployPool, stats, logger)
 }
 
 func newResourceHistogram(clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType,
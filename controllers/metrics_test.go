@@ -0,0 +1,85 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Metrics", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+
+	BeforeEach(func() {
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: configv1beta1.ProfileKind, Name: randomString()},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterName: randomString(),
+				ClusterType: libsveltosv1beta1.ClusterTypeCapi,
+			},
+		}
+		clusterSummary.Spec.ClusterNamespace = clusterSummary.Namespace
+	})
+
+	It("getClusterSummaryProfile returns the profile owning the ClusterSummary", func() {
+		Expect(controllers.GetClusterSummaryProfile(clusterSummary)).To(Equal(
+			configv1beta1.ProfileKind + "/" + clusterSummary.Namespace + "/" + clusterSummary.OwnerReferences[0].Name))
+
+		clusterSummary.OwnerReferences[0].Kind = configv1beta1.ClusterProfileKind
+		Expect(controllers.GetClusterSummaryProfile(clusterSummary)).To(Equal(
+			configv1beta1.ClusterProfileKind + "/" + clusterSummary.OwnerReferences[0].Name))
+	})
+
+	It("trackTimeToProvision tracks a configuration change till feature is provisioned", func() {
+		hash := []byte(randomString())
+		now := time.Now()
+
+		controllers.TrackTimeToProvision(clusterSummary, configv1beta1.FeatureHelm,
+			configv1beta1.FeatureStatusProvisioning, hash, now)
+		Expect(controllers.IsTimeToProvisionTracked(clusterSummary, configv1beta1.FeatureHelm)).To(BeTrue())
+		Expect(controllers.IsTimeToProvisionTracked(clusterSummary, configv1beta1.FeatureResources)).To(BeFalse())
+
+		// Provisioned with a different configuration
+		controllers.TrackTimeToProvision(clusterSummary, configv1beta1.FeatureHelm,
+			configv1beta1.FeatureStatusProvisioned, []byte(randomString()), now.Add(time.Second))
+		Expect(controllers.IsTimeToProvisionTracked(clusterSummary, configv1beta1.FeatureHelm)).To(BeTrue())
+
+		controllers.TrackTimeToProvision(clusterSummary, configv1beta1.FeatureHelm,
+			configv1beta1.FeatureStatusProvisioned, hash, now.Add(time.Minute))
+		Expect(controllers.IsTimeToProvisionTracked(clusterSummary, configv1beta1.FeatureHelm)).To(BeFalse())
+
+		controllers.TrackTimeToProvision(clusterSummary, configv1beta1.FeatureResources,
+			configv1beta1.FeatureStatusFailed, hash, now)
+		Expect(controllers.IsTimeToProvisionTracked(clusterSummary, configv1beta1.FeatureResources)).To(BeTrue())
+		controllers.RemoveTimeToProvisionData(clusterSummary)
+		Expect(controllers.IsTimeToProvisionTracked(clusterSummary, configv1beta1.FeatureResources)).To(BeFalse())
+	})
+})
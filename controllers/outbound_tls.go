@@ -0,0 +1,303 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// When strict outbound TLS is enabled, every connection the addon-controller opens towards
+// helm chart repositories and OCI registries:
+// - uses at least the configured TLS version (TLS 1.2 by default);
+// - only negotiates the configured cipher suites (FIPS approved AES-GCM suites by default);
+// - optionally presents a client certificate (mTLS);
+// - optionally verifies the server presents a pinned public key.
+
+// OutboundTLSOptions configures TLS for outbound connections to chart repositories and OCI registries
+type OutboundTLSOptions struct {
+	// Strict enables the enforcement. When false all other fields are ignored.
+	Strict bool
+
+	// MinVersion is the minimum TLS version. Either VersionTLS12 or VersionTLS13.
+	MinVersion string
+
+	// CipherSuites is the list of allowed cipher suites (Go names). Only used with TLS 1.2.
+	// If empty, FIPS approved AES-GCM ECDHE cipher suites are used.
+	CipherSuites []string
+
+	// ClientCertFile and ClientKeyFile, if set, are presented to servers requesting
+	// a client certificate
+	ClientCertFile string
+	ClientKeyFile  string
+
+	// PinnedPublicKeys is a list of hex encoded SHA256 of the SubjectPublicKeyInfo of
+	// accepted certificates. If set, the server chain must contain at least one of those.
+	PinnedPublicKeys []string
+}
+
+var (
+	// outboundTLSConfig is nil unless strict outbound TLS is enabled
+	outboundTLSConfig *tls.Config
+)
+
+var approvedCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// SetOutboundTLSOptions validates options and configures TLS used for all connections
+// to chart repositories and OCI registries
+func SetOutboundTLSOptions(options *OutboundTLSOptions) error {
+	outboundTLSConfig = nil
+	if options == nil || !options.Strict {
+		return nil
+	}
+
+	tlsConfig, err := buildOutboundTLSConfig(options)
+	if err != nil {
+		return err
+	}
+
+	outboundTLSConfig = tlsConfig
+	return nil
+}
+
+func buildOutboundTLSConfig(options *OutboundTLSOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	switch options.MinVersion {
+	case "", "VersionTLS12":
+	case "VersionTLS13":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported TLS min version %q. Supported values: VersionTLS12, VersionTLS13",
+			options.MinVersion)
+	}
+
+	cipherSuites, err := getCipherSuites(options.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.CipherSuites = cipherSuites
+
+	if options.ClientCertFile != "" || options.ClientKeyFile != "" {
+		if options.ClientCertFile == "" || options.ClientKeyFile == "" {
+			return nil, errors.New("both client certificate and client key must be set")
+		}
+		cert, err := tls.LoadX509KeyPair(options.ClientCertFile, options.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(options.PinnedPublicKeys) > 0 {
+		pins := make(map[string]bool, len(options.PinnedPublicKeys))
+		for i := range options.PinnedPublicKeys {
+			pin := strings.ToLower(strings.TrimSpace(options.PinnedPublicKeys[i]))
+			if b, err := hex.DecodeString(pin); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("invalid pinned public key %q. Expected hex encoded SHA256",
+					options.PinnedPublicKeys[i])
+			}
+			pins[pin] = true
+		}
+		tlsConfig.VerifyPeerCertificate = verifyPinnedPublicKeys(pins)
+	}
+
+	return tlsConfig, nil
+}
+
+func getCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return approvedCipherSuites, nil
+	}
+
+	// Only secure cipher suites can be selected
+	available := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		available[cs.Name] = cs.ID
+	}
+
+	cipherSuites := make([]uint16, 0, len(names))
+	for i := range names {
+		id, ok := available[strings.TrimSpace(names[i])]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", names[i])
+		}
+		cipherSuites = append(cipherSuites, id)
+	}
+
+	return cipherSuites, nil
+}
+
+// verifyPinnedPublicKeys returns a function verifying at least one certificate presented by
+// the server has a pinned public key. It runs after standard chain verification.
+func verifyPinnedPublicKeys(pins map[string]bool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		for i := range rawCerts {
+			cert, err := x509.ParseCertificate(rawCerts[i])
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			if pins[hex.EncodeToString(sum[:])] {
+				return nil
+			}
+		}
+		return errors.New("server certificate chain does not contain any pinned public key")
+	}
+}
+
+func isStrictOutboundTLS() bool {
+	return outboundTLSConfig != nil
+}
+
+// getOutboundTLSConfig returns a copy of the strict outbound TLS configuration. If caPath is
+// set, server certificates are verified using that CA.
+func getOutboundTLSConfig(caPath string, skipTLSVerify bool) (*tls.Config, error) {
+	tlsConfig := outboundTLSConfig.Clone()
+	if caPath != "" {
+		caCert, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %w", caPath, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse CA file %s", caPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	tlsConfig.InsecureSkipVerify = skipTLSVerify
+
+	return tlsConfig, nil
+}
+
+func getOutboundTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		DisableCompression: true,
+		Proxy:              http.ProxyFromEnvironment,
+		TLSClientConfig:    tlsConfig,
+	}
+}
+
+// getHelmGetters returns the getters used to download repository indexes and charts.
+// http(s) getters retry requests rate limited or failed by the repository (see chart_repository_retry.go).
+// With strict outbound TLS, http(s) getters use the strict TLS configuration.
+func getHelmGetters(settings *cli.EnvSettings) (getter.Providers, error) {
+	providers := getter.All(settings)
+
+	var tlsConfig *tls.Config
+	if isStrictOutboundTLS() {
+		var err error
+		tlsConfig, err = getOutboundTLSConfig("", false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	transport := newChartRepositoryTransport(getOutboundTransport(tlsConfig))
+
+	httpProvider := getter.Provider{
+		Schemes: []string{"http", "https"},
+		New: func(options ...getter.Option) (getter.Getter, error) {
+			return getter.NewHTTPGetter(append(options, getter.WithTransport(transport))...)
+		},
+	}
+
+	// ByScheme returns the first provider supporting a scheme
+	return append(getter.Providers{httpProvider}, providers...), nil
+}
+
+// locateChart downloads the chart and returns its local path. Same as helm LocateChart
+// but, for charts coming from http(s) repositories, using getHelmGetters (so with retries
+// and, if enabled, strict outbound TLS enforced).
+// Charts from OCI registries are pulled with the registry client, already configured
+// with strict outbound TLS.
+// Chart archives already downloaded are reused (see locateCachedChart). Returned function must be
+// invoked once done loading the chart archive.
+func locateChart(chartPathOptions *action.ChartPathOptions, name string, settings *cli.EnvSettings,
+) (string, func(), error) {
+
+	return locateCachedChart(chartPathOptions, name, settings, func() (string, error) {
+		return downloadChart(chartPathOptions, name, settings)
+	})
+}
+
+// downloadChart downloads the chart and returns its local path
+func downloadChart(chartPathOptions *action.ChartPathOptions, name string, settings *cli.EnvSettings,
+) (string, error) {
+
+	if registry.IsOCI(name) || chartPathOptions.RepoURL != "" {
+		return chartPathOptions.LocateChart(name, settings)
+	}
+
+	// Local charts
+	if _, err := os.Stat(name); err == nil || filepath.IsAbs(name) || strings.HasPrefix(name, ".") {
+		return chartPathOptions.LocateChart(name, settings)
+	}
+
+	getters, err := getHelmGetters(settings)
+	if err != nil {
+		return "", err
+	}
+
+	dl := downloader.ChartDownloader{
+		Out:     os.Stdout,
+		Keyring: chartPathOptions.Keyring,
+		Getters: getters,
+		Options: []getter.Option{
+			getter.WithPassCredentialsAll(chartPathOptions.PassCredentialsAll),
+			getter.WithBasicAuth(chartPathOptions.Username, chartPathOptions.Password),
+		},
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+	}
+	if chartPathOptions.Verify {
+		dl.Verify = downloader.VerifyAlways
+	}
+
+	const permissions = 0o755
+	if err := os.MkdirAll(settings.RepositoryCache, permissions); err != nil {
+		return "", err
+	}
+
+	filename, _, err := dl.DownloadTo(strings.TrimSpace(name), strings.TrimSpace(chartPathOptions.Version),
+		settings.RepositoryCache)
+	if err != nil {
+		return "", err
+	}
+
+	return filename, nil
+}
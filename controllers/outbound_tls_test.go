@@ -0,0 +1,116 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Outbound TLS", func() {
+	var server *httptest.Server
+	var caPath string
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		caPath = filepath.Join(GinkgoT().TempDir(), "ca.crt")
+		Expect(os.WriteFile(caPath,
+			pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+			0o600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(controllers.SetOutboundTLSOptions(nil)).To(Succeed())
+	})
+
+	It("buildOutboundTLSConfig validates options", func() {
+		tlsConfig, err := controllers.BuildOutboundTLSConfig(&controllers.OutboundTLSOptions{Strict: true})
+		Expect(err).To(BeNil())
+		Expect(tlsConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+		Expect(tlsConfig.CipherSuites).ToNot(BeEmpty())
+		Expect(tlsConfig.VerifyPeerCertificate).To(BeNil())
+
+		tlsConfig, err = controllers.BuildOutboundTLSConfig(&controllers.OutboundTLSOptions{
+			Strict:       true,
+			MinVersion:   "VersionTLS13",
+			CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+		})
+		Expect(err).To(BeNil())
+		Expect(tlsConfig.MinVersion).To(Equal(uint16(tls.VersionTLS13)))
+		Expect(tlsConfig.CipherSuites).To(Equal([]uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}))
+
+		_, err = controllers.BuildOutboundTLSConfig(&controllers.OutboundTLSOptions{MinVersion: "VersionTLS10"})
+		Expect(err).ToNot(BeNil())
+
+		// Insecure cipher suites are not accepted
+		_, err = controllers.BuildOutboundTLSConfig(&controllers.OutboundTLSOptions{
+			CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+		})
+		Expect(err).ToNot(BeNil())
+
+		_, err = controllers.BuildOutboundTLSConfig(&controllers.OutboundTLSOptions{ClientCertFile: caPath})
+		Expect(err).ToNot(BeNil())
+
+		_, err = controllers.BuildOutboundTLSConfig(&controllers.OutboundTLSOptions{PinnedPublicKeys: []string{"abc"}})
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("only connects to servers presenting a pinned public key", func() {
+		sum := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+
+		Expect(controllers.SetOutboundTLSOptions(&controllers.OutboundTLSOptions{
+			Strict:           true,
+			PinnedPublicKeys: []string{hex.EncodeToString(sum[:])},
+		})).To(Succeed())
+
+		tlsConfig, err := controllers.GetOutboundTLSConfig(caPath, false)
+		Expect(err).To(BeNil())
+		httpClient := &http.Client{Transport: controllers.GetOutboundTransport(tlsConfig)}
+		resp, err := httpClient.Get(server.URL)
+		Expect(err).To(BeNil())
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		otherPin := sha256.Sum256([]byte(randomString()))
+		Expect(controllers.SetOutboundTLSOptions(&controllers.OutboundTLSOptions{
+			Strict:           true,
+			PinnedPublicKeys: []string{hex.EncodeToString(otherPin[:])},
+		})).To(Succeed())
+
+		tlsConfig, err = controllers.GetOutboundTLSConfig(caPath, false)
+		Expect(err).To(BeNil())
+		httpClient = &http.Client{Transport: controllers.GetOutboundTransport(tlsConfig)}
+		_, err = httpClient.Get(server.URL) //nolint: bodyclose // request is expected to fail
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("pinned public key"))
+	})
+})
@@ -0,0 +1,204 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// When a ClusterProfile/Profile, not in DryRun mode, has the PlanAnnotation, a plan is requested:
+// - ClusterReports of the ClusterProfile/Profile are recreated, one per matching cluster, and marked
+// with the PlanAnnotation. Those are not removed because SyncMode is not DryRun;
+// - PlanAnnotation is set on each ClusterSummary and removed from the ClusterProfile/Profile.
+// Each ClusterSummary then invokes, synchronously, the feature handlers on a copy of itself with SyncMode
+// set to DryRun. So ClusterReport is filled in exactly as in DryRun mode while real deployments keep going.
+// Plan ClusterReports are left till next plan request or till the ClusterProfile/Profile is deleted.
+
+const (
+	// planOption is the deployer.Options.HandlerOptions key asking feature handlers to run in DryRun mode
+	planOption = "plan"
+)
+
+// requestPlanIfRequested requests a plan if the ClusterProfile/Profile has the PlanAnnotation.
+// Annotation is removed once the plan is requested. Failing to request the plan does not fail the
+// reconciliation. Annotation is left and request is retried at next reconciliation.
+func requestPlanIfRequested(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	logger logr.Logger) {
+
+	annotations := profileScope.Profile.GetAnnotations()
+	value, ok := annotations[configv1beta1.PlanAnnotation]
+	if !ok {
+		return
+	}
+
+	// In DryRun mode ClusterReports are always kept up to date
+	if !profileScope.IsDryRunSync() {
+		if err := requestPlan(ctx, c, profileScope, value); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to request plan: %v", err))
+			return
+		}
+		logger.V(logs.LogInfo).Info("plan requested")
+	}
+
+	delete(annotations, configv1beta1.PlanAnnotation)
+	profileScope.Profile.SetAnnotations(annotations)
+}
+
+func requestPlan(ctx context.Context, c client.Client, profileScope *scope.ProfileScope, value string) error {
+	profile := profileScope.Profile
+
+	// Start from scratch. ClusterReports from a previous plan might be for clusters not matching anymore.
+	if err := cleanClusterReports(ctx, c, profile, false); err != nil {
+		return err
+	}
+
+	for i := range profileScope.GetStatus().MatchingClusterRefs {
+		cluster := profileScope.GetStatus().MatchingClusterRefs[i]
+		err := createClusterReportWithAnnotations(ctx, c, profile, &cluster,
+			map[string]string{configv1beta1.PlanAnnotation: value})
+		if err != nil {
+			return err
+		}
+	}
+
+	listOptions := []client.ListOption{}
+	if profileScope.GetKind() == configv1beta1.ClusterProfileKind {
+		listOptions = append(listOptions, client.MatchingLabels{ClusterProfileLabelName: profile.GetName()})
+	} else {
+		listOptions = append(listOptions,
+			client.MatchingLabels{ProfileLabelName: profile.GetName()},
+			client.InNamespace(profile.GetNamespace()))
+	}
+
+	clusterSummaryList := &configv1beta1.ClusterSummaryList{}
+	if err := c.List(ctx, clusterSummaryList, listOptions...); err != nil {
+		return err
+	}
+
+	for i := range clusterSummaryList.Items {
+		clusterSummary := &clusterSummaryList.Items[i]
+		if !clusterSummary.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		patch := client.MergeFrom(clusterSummary.DeepCopy())
+		if clusterSummary.Annotations == nil {
+			clusterSummary.Annotations = make(map[string]string)
+		}
+		clusterSummary.Annotations[configv1beta1.PlanAnnotation] = value
+		if err := c.Patch(ctx, clusterSummary, patch); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isPlanClusterReport returns true if clusterReport was created because a plan was requested
+func isPlanClusterReport(clusterReport *configv1beta1.ClusterReport) bool {
+	_, ok := clusterReport.Annotations[configv1beta1.PlanAnnotation]
+	return ok
+}
+
+// applyPlanOption sets SyncMode to DryRun on clusterSummary, an in-memory copy, if handler was
+// invoked to plan
+func applyPlanOption(clusterSummary *configv1beta1.ClusterSummary, o deployer.Options) {
+	if _, ok := o.HandlerOptions[planOption]; !ok {
+		return
+	}
+
+	clusterSummary.Spec.ClusterProfileSpec.SyncMode = configv1beta1.SyncModeDryRun
+}
+
+// planIfRequested fills in the ClusterReport if ClusterSummary has the PlanAnnotation.
+// Annotation is removed whether plan succeeds or not: a failure is logged and plan can be requested again.
+func (r *ClusterSummaryReconciler) planIfRequested(ctx context.Context, clusterSummaryScope *scope.ClusterSummaryScope,
+	logger logr.Logger) {
+
+	clusterSummary := clusterSummaryScope.ClusterSummary
+	if _, ok := clusterSummary.Annotations[configv1beta1.PlanAnnotation]; !ok {
+		return
+	}
+
+	if !configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) {
+		if err := r.plan(ctx, clusterSummary, logger); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to plan: %v", err))
+		} else {
+			logger.V(logs.LogInfo).Info("plan available in ClusterReport")
+		}
+	}
+
+	delete(clusterSummary.Annotations, configv1beta1.PlanAnnotation)
+}
+
+// plan invokes, in DryRun mode, the handler of each feature ClusterSummary deploys or deployed
+func (r *ClusterSummaryReconciler) plan(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	logger logr.Logger) error {
+
+	options := deployer.Options{HandlerOptions: map[string]string{planOption: "true"}}
+	for _, featureID := range r.getPlannedFeatures(clusterSummary) {
+		f := getHandlersForFeature(featureID)
+		err := f.deploy(ctx, r.Client, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+			clusterSummary.Name, string(featureID), clusterSummary.Spec.ClusterType, options,
+			logger.WithValues("feature", featureID))
+		if err != nil {
+			var dryRunErr *configv1beta1.DryRunReconciliationError
+			if errors.As(err, &dryRunErr) {
+				// Handlers always return this error in DryRun mode
+				continue
+			}
+			return fmt.Errorf("feature %s: %w", featureID, err)
+		}
+	}
+
+	return nil
+}
+
+// getPlannedFeatures returns the features reporting in ClusterReport which are either configured
+// or were deployed (so resources which would be removed are reported)
+func (r *ClusterSummaryReconciler) getPlannedFeatures(clusterSummary *configv1beta1.ClusterSummary,
+) []configv1beta1.FeatureID {
+
+	spec := &clusterSummary.Spec.ClusterProfileSpec
+	configured := map[configv1beta1.FeatureID]bool{
+		configv1beta1.FeatureResources: getResourceRefs(clusterSummary) != nil,
+		configv1beta1.FeatureHelm:      spec.HelmCharts != nil,
+		configv1beta1.FeatureKustomize: spec.KustomizationRefs != nil,
+	}
+
+	features := make([]configv1beta1.FeatureID, 0, len(configured))
+	for _, featureID := range []configv1beta1.FeatureID{configv1beta1.FeatureResources,
+		configv1beta1.FeatureHelm, configv1beta1.FeatureKustomize} {
+
+		if configured[featureID] || r.isFeatureStatusPresent(clusterSummary, featureID) {
+			features = append(features, featureID)
+		}
+	}
+
+	return features
+}
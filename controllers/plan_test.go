@@ -0,0 +1,145 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+)
+
+var _ = Describe("Plan", func() {
+	It("requestPlanIfRequested creates plan ClusterReports and passes request to ClusterSummaries", func() {
+		namespace := randomString()
+		clusterName := randomString()
+		profile := &configv1beta1.Profile{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       configv1beta1.ProfileKind,
+				APIVersion: configv1beta1.GroupVersion.String(),
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        randomString(),
+				Namespace:   namespace,
+				Annotations: map[string]string{configv1beta1.PlanAnnotation: "now"},
+			},
+			Spec: configv1beta1.Spec{
+				SyncMode: configv1beta1.SyncModeContinuous,
+			},
+			Status: configv1beta1.Status{
+				MatchingClusterRefs: []corev1.ObjectReference{
+					{
+						Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String(),
+						Namespace: namespace, Name: clusterName,
+					},
+				},
+			},
+		}
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: profile.Namespace,
+				Labels:    map[string]string{controllers.ProfileLabelName: profile.Name},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: profile.Namespace,
+				ClusterName:      clusterName,
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+			},
+		}
+
+		// ClusterReport left by a previous plan for a cluster not matching anymore
+		staleClusterReport := &configv1beta1.ClusterReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        randomString(),
+				Namespace:   profile.Namespace,
+				Labels:      map[string]string{controllers.ProfileLabelName: profile.Name},
+				Annotations: map[string]string{configv1beta1.PlanAnnotation: "before"},
+			},
+		}
+
+		initObjects := []client.Object{profile, clusterSummary, staleClusterReport}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logger,
+			Profile:        profile,
+			ControllerName: "profile",
+		})
+		Expect(err).To(BeNil())
+
+		controllers.RequestPlanIfRequested(context.TODO(), c, profileScope, logger)
+		Expect(profile.Annotations).ToNot(HaveKey(configv1beta1.PlanAnnotation))
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: staleClusterReport.Namespace,
+			Name: staleClusterReport.Name}, staleClusterReport)).ToNot(Succeed())
+
+		clusterReport := &configv1beta1.ClusterReport{}
+		clusterReportName := controllers.GetClusterReportName(configv1beta1.ProfileKind, profile.Name,
+			clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: profile.Namespace, Name: clusterReportName},
+			clusterReport)).To(Succeed())
+		Expect(clusterReport.Annotations).To(HaveKeyWithValue(configv1beta1.PlanAnnotation, "now"))
+
+		currentClusterSummary := &configv1beta1.ClusterSummary{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name},
+			currentClusterSummary)).To(Succeed())
+		Expect(currentClusterSummary.Annotations).To(HaveKeyWithValue(configv1beta1.PlanAnnotation, "now"))
+
+		// Plan ClusterReports are not removed because SyncMode is not DryRun
+		Expect(controllers.CleanClusterReports(context.TODO(), c, profile, true)).To(Succeed())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: profile.Namespace, Name: clusterReportName},
+			clusterReport)).To(Succeed())
+
+		Expect(controllers.CleanClusterReports(context.TODO(), c, profile, false)).To(Succeed())
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: profile.Namespace, Name: clusterReportName},
+			clusterReport)).ToNot(Succeed())
+	})
+
+	It("applyPlanOption sets SyncMode to DryRun only when planning", func() {
+		clusterSummary := &configv1beta1.ClusterSummary{
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterProfileSpec: configv1beta1.Spec{
+					SyncMode: configv1beta1.SyncModeContinuousWithDriftDetection,
+				},
+			},
+		}
+
+		controllers.ApplyPlanOption(clusterSummary, deployer.Options{})
+		Expect(clusterSummary.Spec.ClusterProfileSpec.SyncMode).To(Equal(configv1beta1.SyncModeContinuousWithDriftDetection))
+
+		controllers.ApplyPlanOption(clusterSummary,
+			deployer.Options{HandlerOptions: map[string]string{controllers.PlanOption: "true"}})
+		Expect(clusterSummary.Spec.ClusterProfileSpec.SyncMode).To(Equal(configv1beta1.SyncModeDryRun))
+	})
+})
@@ -25,6 +25,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -51,6 +52,8 @@ type ProfileReconciler struct {
 	Scheme               *runtime.Scheme
 	ConcurrentReconciles int
 	Logger               logr.Logger
+	RequeuePolicy        RequeuePolicy
+	EventRecorder        record.EventRecorder
 
 	// use a Mutex to update Map as MaxConcurrentReconciles is higher than one
 	Mux sync.Mutex
@@ -103,7 +106,7 @@ type ProfileReconciler struct {
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=profiles,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=profiles/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=profiles/finalizers,verbs=update;patch
-//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clustersummaries,verbs=get;list;update;create;delete
+//+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clustersummaries,verbs=get;list;watch;update;create;delete
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterreports,verbs=get;list;update;create;watch;delete
 //+kubebuilder:rbac:groups=config.projectsveltos.io,resources=clusterconfigurations,verbs=get;list;update;create;watch;delete
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;watch;list
@@ -117,6 +120,9 @@ func (r *ProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_
 	logger := ctrl.LoggerFrom(ctx)
 	logger.V(logs.LogInfo).Info("Reconciling")
 
+	ctx, apiWrites := withAPIWritesCounter(ctx)
+	defer observeReconcileAPIWrites("profile", apiWrites)
+
 	// Fecth the Profile instance
 	profile := &configv1beta1.Profile{}
 	if err := r.Get(ctx, req.NamespacedName, profile); err != nil {
@@ -169,7 +175,7 @@ func (r *ProfileReconciler) reconcileDelete(
 
 	if err := reconcileDeleteCommon(ctx, r.Client, profileScope,
 		configv1beta1.ProfileFinalizer, logger); err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: deleteRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.deleteRequeueAfter()}
 	}
 
 	r.cleanMaps(profileScope)
@@ -187,21 +193,21 @@ func (r *ProfileReconciler) reconcileNormal(
 
 	if !controllerutil.ContainsFinalizer(profileScope.Profile, configv1beta1.ProfileFinalizer) {
 		if err := addFinalizer(ctx, profileScope, configv1beta1.ProfileFinalizer); err != nil {
-			return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+			return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 		}
 	}
 
 	// Limit the search of matching cluster to the Profile namespace
-	matchingCluster, err := getMatchingClusters(ctx, r.Client, profileScope.Profile.GetNamespace(),
-		profileScope.GetSelector(), profileScope.GetSpec().ClusterRefs, logger)
+	matchingCluster, err := getProfileMatchingClusters(ctx, r.Client, profileScope.Profile.GetNamespace(),
+		profileScope, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 	}
 
 	// Get all clusters from referenced Sets
 	clusterSetClusters, err := r.getClustersFromSets(ctx, profileScope.Namespace(), profileScope.GetSpec().SetRefs, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 	}
 	matchingCluster = append(matchingCluster, clusterSetClusters...)
 
@@ -209,8 +215,14 @@ func (r *ProfileReconciler) reconcileNormal(
 
 	r.updateMaps(profileScope)
 
-	if err := reconcileNormalCommon(ctx, r.Client, profileScope, logger); err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+	if err := reconcileNormalCommon(ctx, r.Client, r.EventRecorder, profileScope, logger); err != nil {
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
+	}
+
+	if hasChartVersionConstraints(profileScope.GetSpec()) {
+		// Periodically look for newly published chart versions matching the constraints
+		logger.V(logs.LogInfo).Info("Reconcile success")
+		return reconcile.Result{RequeueAfter: getChartIndexRefreshInterval()}
 	}
 
 	logger.V(logs.LogInfo).Info("Reconcile success")
@@ -219,10 +231,13 @@ func (r *ProfileReconciler) reconcileNormal(
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Client = newAPIWritesCountingClient(r.Client)
+
 	c, err := ctrl.NewControllerManagedBy(mgr).
 		For(&configv1beta1.Profile{}).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: r.ConcurrentReconciles,
+			RateLimiter:             r.RequeuePolicy.rateLimiter(),
 		}).
 		Watches(&libsveltosv1beta1.Set{},
 			handler.EnqueueRequestsFromMapFunc(r.requeueProfileForSet),
@@ -236,6 +251,12 @@ func (r *ProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				SveltosClusterPredicates(mgr.GetLogger().WithValues("predicate", "sveltosclusterpredicate")),
 			),
 		).
+		Watches(&configv1beta1.ClusterSummary{},
+			handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(), &configv1beta1.Profile{}),
+			builder.WithPredicates(
+				ClusterSummaryPredicates(mgr.GetLogger().WithValues("predicate", "clustersummarypredicate")),
+			),
+		).
 		Build(r)
 	if err != nil {
 		return errors.Wrap(err, "error creating controller")
@@ -288,6 +309,10 @@ func (r *ProfileReconciler) limitReferencesToNamespace(profile *configv1beta1.Pr
 		profile.Spec.PolicyRefs[i].Namespace = profile.Namespace
 	}
 
+	if profile.Spec.SourceRef != nil {
+		profile.Spec.SourceRef.Namespace = profile.Namespace
+	}
+
 	for i := range profile.Spec.KustomizationRefs {
 		profile.Spec.KustomizationRefs[i].Namespace = profile.Namespace
 		r.limitKustomizationRefsToNamespace(profile, &profile.Spec.KustomizationRefs[i])
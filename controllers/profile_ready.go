@@ -0,0 +1,71 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+)
+
+// ClusterProfile/Profile report a Ready condition, so that CI pipelines can run
+// "kubectl wait --for=condition=Ready clusterprofile/<name>" after changing the Spec.
+// Ready is true only when every matching cluster has all features provisioned for the current
+// revision. Status.ObservedGeneration and the condition ObservedGeneration report the generation
+// the condition was computed for.
+
+// updateReadyCondition sets the Ready condition from the aggregated ClusterSummaries status.
+// Must be called after updateClusterSummariesStatus.
+func updateReadyCondition(profileScope *scope.ProfileScope) {
+	ready, reason, message := isProfileReady(profileScope.GetStatus())
+	profileScope.SetReady(ready, reason, message)
+}
+
+// isProfileReady returns whether all matching clusters are provisioned with the current revision,
+// along with the reason and message of the Ready condition.
+func isProfileReady(status *configv1beta1.Status) (ready bool, reason, message string) {
+	total := len(status.MatchingClusterRefs)
+
+	provisioned := 0
+	failed := 0
+	if status.ClusterSummaries != nil {
+		for i := range status.ClusterSummaries.Clusters {
+			cluster := &status.ClusterSummaries.Clusters[i]
+			switch cluster.State {
+			case configv1beta1.ClusterDeploymentStateFailed:
+				failed++
+			case configv1beta1.ClusterDeploymentStateProvisioned:
+				// A cluster still running a previous revision is not ready
+				if status.Revision == 0 || cluster.ObservedRevision == status.Revision {
+					provisioned++
+				}
+			}
+		}
+	}
+
+	message = fmt.Sprintf("%d/%d clusters provisioned", provisioned, total)
+	switch {
+	case failed > 0:
+		return false, configv1beta1.ClustersFailedReason,
+			fmt.Sprintf("%s, %d failed", message, failed)
+	case provisioned < total:
+		return false, configv1beta1.ClustersProvisioningReason, message
+	default:
+		return true, configv1beta1.AllClustersProvisionedReason, message
+	}
+}
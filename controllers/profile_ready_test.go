@@ -0,0 +1,117 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Profile Ready condition", func() {
+	var clusters []corev1.ObjectReference
+
+	BeforeEach(func() {
+		clusters = []corev1.ObjectReference{
+			{Namespace: randomString(), Name: randomString(), Kind: libsveltosv1beta1.SveltosClusterKind},
+			{Namespace: randomString(), Name: randomString(), Kind: libsveltosv1beta1.SveltosClusterKind},
+		}
+	})
+
+	It("isProfileReady is true only when all clusters are provisioned with current revision", func() {
+		status := &configv1beta1.Status{
+			MatchingClusterRefs: clusters,
+			Revision:            2,
+			ClusterSummaries: &configv1beta1.ClusterSummariesStatus{
+				Clusters: []configv1beta1.ClusterDeploymentStatus{
+					{Cluster: clusters[0], State: configv1beta1.ClusterDeploymentStateProvisioned, ObservedRevision: 2},
+					{Cluster: clusters[1], State: configv1beta1.ClusterDeploymentStateProvisioned, ObservedRevision: 1},
+				},
+			},
+		}
+
+		ready, reason, message := controllers.IsProfileReady(status)
+		Expect(ready).To(BeFalse())
+		Expect(reason).To(Equal(configv1beta1.ClustersProvisioningReason))
+		Expect(message).To(Equal("1/2 clusters provisioned"))
+
+		status.ClusterSummaries.Clusters[1].ObservedRevision = 2
+		ready, reason, _ = controllers.IsProfileReady(status)
+		Expect(ready).To(BeTrue())
+		Expect(reason).To(Equal(configv1beta1.AllClustersProvisionedReason))
+
+		status.ClusterSummaries.Clusters[1].State = configv1beta1.ClusterDeploymentStateFailed
+		ready, reason, _ = controllers.IsProfileReady(status)
+		Expect(ready).To(BeFalse())
+		Expect(reason).To(Equal(configv1beta1.ClustersFailedReason))
+
+		// Matching clusters whose ClusterSummary status is not aggregated yet are not ready
+		status.ClusterSummaries = nil
+		ready, _, _ = controllers.IsProfileReady(status)
+		Expect(ready).To(BeFalse())
+
+		// Nothing to deploy
+		status.MatchingClusterRefs = nil
+		ready, _, _ = controllers.IsProfileReady(status)
+		Expect(ready).To(BeTrue())
+	})
+
+	It("updateReadyCondition sets Ready condition and ObservedGeneration", func() {
+		clusterProfile := &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString(), Generation: 3},
+			Status: configv1beta1.Status{
+				MatchingClusterRefs: clusters[:1],
+				ClusterSummaries: &configv1beta1.ClusterSummariesStatus{
+					Clusters: []configv1beta1.ClusterDeploymentStatus{
+						{Cluster: clusters[0], State: configv1beta1.ClusterDeploymentStateProvisioning},
+					},
+				},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logr.Discard(),
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+
+		controllers.UpdateReadyCondition(profileScope)
+		Expect(clusterProfile.Status.ObservedGeneration).To(Equal(int64(3)))
+		condition := meta.FindStatusCondition(clusterProfile.Status.Conditions, configv1beta1.ReadyCondition)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.ObservedGeneration).To(Equal(int64(3)))
+
+		clusterProfile.Status.ClusterSummaries.Clusters[0].State = configv1beta1.ClusterDeploymentStateProvisioned
+		controllers.UpdateReadyCondition(profileScope)
+		condition = meta.FindStatusCondition(clusterProfile.Status.Conditions, configv1beta1.ReadyCondition)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+	})
+})
@@ -0,0 +1,158 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Every time ClusterProfile/Profile Spec changes, a new revision is recorded in Status.RevisionHistory.
+// ClusterSummaries keep the revision their ClusterProfileSpec was rendered from (Spec.ProfileRevision)
+// and, once all features are provisioned, report it as Status.ObservedRevision.
+// Setting RollbackToRevisionAnnotation restores the Spec of a revision in the history. As for any
+// Spec change, ClusterSummaries are then re-rendered (honoring MaxUpdate, RolloutRings, etc.). Like
+// "kubectl rollout undo" does for Deployments, the restored revision becomes the latest one.
+
+const (
+	// maxRevisionHistory is the maximum number of revisions kept in Status.RevisionHistory
+	maxRevisionHistory = 10
+
+	rolledBackReason     = "RolledBack"
+	rollbackFailedReason = "RollbackFailed"
+)
+
+// recordRevision records current Spec (whose hash is hash) in the revision history, if not
+// already the latest revision, and sets Status.Revision.
+func recordRevision(profileScope *scope.ProfileScope, hash []byte, now time.Time) {
+	status := profileScope.GetStatus()
+	history := status.RevisionHistory
+
+	next := int64(1)
+	if len(history) > 0 {
+		latest := &history[len(history)-1]
+		if bytes.Equal(latest.Hash, hash) {
+			status.Revision = latest.Revision
+			return
+		}
+		next = latest.Revision + 1
+	}
+
+	// Spec might match an older revision (for instance after a rollback). That revision is
+	// replaced by the new one.
+	revisions := make([]configv1beta1.ProfileRevision, 0, len(history)+1)
+	for i := range history {
+		if !bytes.Equal(history[i].Hash, hash) {
+			revisions = append(revisions, history[i])
+		}
+	}
+	revisions = append(revisions, configv1beta1.ProfileRevision{
+		Revision:     next,
+		Hash:         hash,
+		CreationTime: metav1.NewTime(now),
+		Spec:         *profileScope.GetSpec().DeepCopy(),
+	})
+
+	if len(revisions) > maxRevisionHistory {
+		revisions = revisions[len(revisions)-maxRevisionHistory:]
+	}
+
+	status.RevisionHistory = revisions
+	status.Revision = next
+}
+
+// getProfileRevision returns the revision from the revision history. Nil is returned if
+// revision is not in the history.
+func getProfileRevision(status *configv1beta1.Status, revision int64) *configv1beta1.ProfileRevision {
+	for i := range status.RevisionHistory {
+		if status.RevisionHistory[i].Revision == revision {
+			return &status.RevisionHistory[i]
+		}
+	}
+	return nil
+}
+
+// getRevisionForSpec returns the revision of spec in the revision history. Zero is returned if
+// spec is not in the history.
+func getRevisionForSpec(status *configv1beta1.Status, spec *configv1beta1.Spec) int64 {
+	for i := range status.RevisionHistory {
+		if reflect.DeepEqual(status.RevisionHistory[i].Spec, *spec) {
+			return status.RevisionHistory[i].Revision
+		}
+	}
+	return 0
+}
+
+// rollbackIfRequested restores the Spec of the revision set in RollbackToRevisionAnnotation.
+// Annotation is always removed, even if revision is not valid.
+func rollbackIfRequested(recorder record.EventRecorder, profileScope *scope.ProfileScope, logger logr.Logger) {
+	annotations := profileScope.Profile.GetAnnotations()
+	value, ok := annotations[configv1beta1.RollbackToRevisionAnnotation]
+	if !ok {
+		return
+	}
+
+	delete(annotations, configv1beta1.RollbackToRevisionAnnotation)
+	profileScope.Profile.SetAnnotations(annotations)
+
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		msg := fmt.Sprintf("invalid revision %q", value)
+		logger.V(logs.LogInfo).Info(msg)
+		recordRollbackEvent(recorder, profileScope, corev1.EventTypeWarning, rollbackFailedReason, msg)
+		return
+	}
+
+	profileRevision := getProfileRevision(profileScope.GetStatus(), revision)
+	if profileRevision == nil {
+		msg := fmt.Sprintf("revision %d is not in the revision history", revision)
+		logger.V(logs.LogInfo).Info(msg)
+		recordRollbackEvent(recorder, profileScope, corev1.EventTypeWarning, rollbackFailedReason, msg)
+		return
+	}
+
+	logger.V(logs.LogInfo).Info(fmt.Sprintf("rolling back to revision %d", revision))
+	*profileScope.GetSpec() = *profileRevision.Spec.DeepCopy()
+	recordRollbackEvent(recorder, profileScope, corev1.EventTypeNormal, rolledBackReason,
+		fmt.Sprintf("rolled back to revision %d", revision))
+}
+
+func recordRollbackEvent(recorder record.EventRecorder, profileScope *scope.ProfileScope,
+	eventType, reason, message string) {
+
+	if recorder == nil {
+		return
+	}
+
+	code := configv1beta1.MessageCodeRolledBack
+	if reason == rollbackFailedReason {
+		code = configv1beta1.MessageCodeRollbackFailed
+	}
+	recorder.Event(profileScope.Profile, eventType, reason, configv1beta1.FormatMessage(code, message))
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Profile revisions", func() {
+	var clusterProfile *configv1beta1.ClusterProfile
+
+	BeforeEach(func() {
+		clusterProfile = &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: randomString(),
+			},
+			Spec: configv1beta1.Spec{
+				SyncMode: configv1beta1.SyncModeContinuous,
+				Tier:     100,
+			},
+		}
+		Expect(addTypeInformationToObject(scheme, clusterProfile)).To(Succeed())
+	})
+
+	getProfileScope := func(c client.Client) *scope.ProfileScope {
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logr.Discard(),
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+		return profileScope
+	}
+
+	It("recordRevision records a new revision only when Spec changes", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+		profileScope := getProfileScope(c)
+
+		controllers.RecordRevision(profileScope, []byte("tier100"), time.Now())
+		Expect(clusterProfile.Status.Revision).To(Equal(int64(1)))
+		Expect(clusterProfile.Status.RevisionHistory).To(HaveLen(1))
+		Expect(clusterProfile.Status.RevisionHistory[0].Spec.Tier).To(Equal(int32(100)))
+
+		// Spec has not changed
+		controllers.RecordRevision(profileScope, []byte("tier100"), time.Now())
+		Expect(clusterProfile.Status.Revision).To(Equal(int64(1)))
+		Expect(clusterProfile.Status.RevisionHistory).To(HaveLen(1))
+
+		clusterProfile.Spec.Tier = 50
+		controllers.RecordRevision(profileScope, []byte("tier50"), time.Now())
+		Expect(clusterProfile.Status.Revision).To(Equal(int64(2)))
+		Expect(clusterProfile.Status.RevisionHistory).To(HaveLen(2))
+
+		// Spec is back to revision 1 which becomes revision 3
+		clusterProfile.Spec.Tier = 100
+		controllers.RecordRevision(profileScope, []byte("tier100"), time.Now())
+		Expect(clusterProfile.Status.Revision).To(Equal(int64(3)))
+		Expect(clusterProfile.Status.RevisionHistory).To(HaveLen(2))
+		Expect(clusterProfile.Status.RevisionHistory[0].Revision).To(Equal(int64(2)))
+		Expect(clusterProfile.Status.RevisionHistory[1].Revision).To(Equal(int64(3)))
+
+		// Only most recent revisions are kept
+		for i := 0; i < 20; i++ {
+			clusterProfile.Spec.Tier = int32(i)
+			controllers.RecordRevision(profileScope, []byte(fmt.Sprintf("tier%d", i)), time.Now())
+		}
+		Expect(clusterProfile.Status.Revision).To(Equal(int64(23)))
+		Expect(len(clusterProfile.Status.RevisionHistory)).To(BeNumerically("<", 20))
+		last := clusterProfile.Status.RevisionHistory[len(clusterProfile.Status.RevisionHistory)-1]
+		Expect(last.Revision).To(Equal(int64(23)))
+		Expect(last.Spec.Tier).To(Equal(int32(19)))
+	})
+
+	It("rollbackIfRequested restores the Spec of the requested revision", func() {
+		clusterProfile.Status.Revision = 2
+		clusterProfile.Status.RevisionHistory = []configv1beta1.ProfileRevision{
+			{
+				Revision:     1,
+				Hash:         []byte(randomString()),
+				CreationTime: metav1.Now(),
+				Spec:         configv1beta1.Spec{SyncMode: configv1beta1.SyncModeContinuous, Tier: 10},
+			},
+			{
+				Revision:     2,
+				Hash:         []byte(randomString()),
+				CreationTime: metav1.Now(),
+				Spec:         *clusterProfile.Spec.DeepCopy(),
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+		profileScope := getProfileScope(c)
+
+		// Revision not in history. Spec is not changed
+		clusterProfile.Annotations = map[string]string{configv1beta1.RollbackToRevisionAnnotation: "5"}
+		controllers.RollbackIfRequested(nil, profileScope, logr.Discard())
+		Expect(clusterProfile.Annotations).ToNot(HaveKey(configv1beta1.RollbackToRevisionAnnotation))
+		Expect(clusterProfile.Spec.Tier).To(Equal(int32(100)))
+
+		clusterProfile.Annotations = map[string]string{configv1beta1.RollbackToRevisionAnnotation: "1"}
+		controllers.RollbackIfRequested(nil, profileScope, logr.Discard())
+		Expect(clusterProfile.Annotations).ToNot(HaveKey(configv1beta1.RollbackToRevisionAnnotation))
+		Expect(clusterProfile.Spec.Tier).To(Equal(int32(10)))
+	})
+
+	It("ClusterSummaries record the revision they are rendered from", func() {
+		clusterProfile.Status.Revision = 3
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+		profileScope := getProfileScope(c)
+
+		cluster := corev1.ObjectReference{Namespace: randomString(), Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		Expect(controllers.CreateClusterSummary(context.TODO(), c, profileScope, &cluster)).To(Succeed())
+
+		clusterSummary, err := controllers.GetClusterSummary(context.TODO(), c, configv1beta1.ClusterProfileKind,
+			clusterProfile.Name, cluster.Namespace, cluster.Name, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(clusterSummary.Spec.ProfileRevision).To(Equal(int64(3)))
+
+		// Only the revision changed
+		clusterProfile.Status.Revision = 4
+		Expect(controllers.UpdateClusterSummary(context.TODO(), c, profileScope, &cluster)).To(Succeed())
+
+		clusterSummary, err = controllers.GetClusterSummary(context.TODO(), c, configv1beta1.ClusterProfileKind,
+			clusterProfile.Name, cluster.Namespace, cluster.Name, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(clusterSummary.Spec.ProfileRevision).To(Equal(int64(4)))
+	})
+})
@@ -22,7 +22,10 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dariubs/percent"
 	"github.com/gdexlab/go-render/render"
@@ -33,12 +36,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/addonclient"
 	"github.com/projectsveltos/addon-controller/pkg/scope"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
@@ -145,19 +151,19 @@ func updateClusterConfigurationProfileResources(ctx context.Context, c client.Cl
 	return err
 }
 
-// updateClusterConfigurationOwnerReferences adds profile as owner of ClusterConfiguration
+// updateClusterConfigurationOwnerReferences adds profile as owner of ClusterConfiguration.
+// It also backfills cluster name/type labels and Spec on ClusterConfigurations created before
+// those were set at creation time.
 func updateClusterConfigurationOwnerReferences(ctx context.Context, c client.Client,
-	profile client.Object, clusterConfiguration *configv1beta1.ClusterConfiguration) error {
+	profile client.Object, clusterConfiguration *configv1beta1.ClusterConfiguration,
+	cluster *corev1.ObjectReference) error {
 
-	if util.IsOwnedByObject(clusterConfiguration, profile) {
-		return nil
-	}
+	labels := getClusterConfigurationLabels(profile, cluster)
+	spec := getClusterConfigurationSpec(cluster)
+	if util.IsOwnedByObject(clusterConfiguration, profile) && hasLabels(clusterConfiguration, labels) &&
+		reflect.DeepEqual(clusterConfiguration.Spec, spec) {
 
-	ownerRef := metav1.OwnerReference{
-		Kind:       profile.GetObjectKind().GroupVersionKind().Kind,
-		UID:        profile.GetUID(),
-		APIVersion: configv1beta1.GroupVersion.String(),
-		Name:       profile.GetName(),
+		return nil
 	}
 
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -167,12 +173,83 @@ func updateClusterConfigurationOwnerReferences(ctx context.Context, c client.Cli
 			return err
 		}
 
-		currentClusterConfiguration.OwnerReferences = util.EnsureOwnerRef(clusterConfiguration.OwnerReferences, ownerRef)
+		currentClusterConfiguration.OwnerReferences = util.EnsureOwnerRef(currentClusterConfiguration.OwnerReferences,
+			getProfileOwnerReference(profile))
+		if currentClusterConfiguration.Labels == nil {
+			currentClusterConfiguration.Labels = map[string]string{}
+		}
+		for k := range labels {
+			currentClusterConfiguration.Labels[k] = labels[k]
+		}
+		currentClusterConfiguration.Spec = spec
 		return c.Update(ctx, currentClusterConfiguration)
 	})
 	return err
 }
 
+// getProfileOwnerReference returns the OwnerReference to set on objects owned by a ClusterProfile/Profile
+func getProfileOwnerReference(profile client.Object) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		Kind:       profile.GetObjectKind().GroupVersionKind().Kind,
+		UID:        profile.GetUID(),
+		APIVersion: configv1beta1.GroupVersion.String(),
+		Name:       profile.GetName(),
+	}
+}
+
+// getClusterConfigurationLabels returns the labels a ClusterConfiguration for cluster owned
+// by profile must have
+func getClusterConfigurationLabels(profile client.Object, cluster *corev1.ObjectReference) map[string]string {
+	labels := map[string]string{
+		configv1beta1.ClusterNameLabel: cluster.Name,
+		configv1beta1.ClusterTypeLabel: string(clusterproxy.GetClusterType(cluster)),
+	}
+	if ownerLabel, ok := getClusterConfigurationOwnerLabel(profile); ok {
+		labels[ownerLabel] = "true"
+	}
+	return labels
+}
+
+const (
+	clusterProfileOwnerLabelPrefix = "clusterprofile.projectsveltos.io/"
+	profileOwnerLabelPrefix        = "profile.projectsveltos.io/"
+)
+
+// getClusterConfigurationOwnerLabel returns the label set on ClusterConfigurations owned by profile.
+// It allows listing only the ClusterConfigurations owned by a ClusterProfile/Profile.
+// Second return value is false if profile name cannot be used as label key (longer than 63 characters).
+func getClusterConfigurationOwnerLabel(profile client.Object) (string, bool) {
+	prefix := clusterProfileOwnerLabelPrefix
+	if profile.GetObjectKind().GroupVersionKind().Kind == configv1beta1.ProfileKind {
+		prefix = profileOwnerLabelPrefix
+	}
+
+	key := prefix + profile.GetName()
+	if len(validation.IsQualifiedName(key)) != 0 {
+		return "", false
+	}
+	return key, true
+}
+
+// getClusterConfigurationSpec returns the Spec a ClusterConfiguration for cluster must have
+func getClusterConfigurationSpec(cluster *corev1.ObjectReference) configv1beta1.ClusterConfigurationSpec {
+	return configv1beta1.ClusterConfigurationSpec{
+		ClusterName: cluster.Name,
+		ClusterType: clusterproxy.GetClusterType(cluster),
+	}
+}
+
+// hasLabels returns true if obj has all labels with the same values
+func hasLabels(obj client.Object, labels map[string]string) bool {
+	current := obj.GetLabels()
+	for k := range labels {
+		if v, ok := current[k]; !ok || v != labels[k] {
+			return false
+		}
+	}
+	return true
+}
+
 // updateClusterConfiguration updates if necessary ClusterConfiguration given a
 // ClusterProfile/Profile and a matching Sveltos/Cluster.
 // Update consists in:
@@ -187,7 +264,7 @@ func updateClusterConfigurationWithProfile(ctx context.Context, c client.Client,
 		return err
 	}
 
-	err = updateClusterConfigurationOwnerReferences(ctx, c, profile, clusterConfiguration)
+	err = updateClusterConfigurationOwnerReferences(ctx, c, profile, clusterConfiguration, cluster)
 	if err != nil {
 		return err
 	}
@@ -200,28 +277,45 @@ func updateClusterConfigurationWithProfile(ctx context.Context, c client.Client,
 	return nil
 }
 
-// createClusterConfiguration creates ClusterConfiguration given a Sveltos/Cluster.
-// If already existing, return nil
-func createClusterConfiguration(ctx context.Context, c client.Client, cluster *corev1.ObjectReference) error {
+// createClusterConfiguration creates ClusterConfiguration given a ClusterProfile/Profile and a
+// matching Sveltos/Cluster. ClusterConfiguration is created with cluster labels and with the
+// ClusterProfile/Profile as OwnerReference, so it is never left unowned. Its Status section for
+// the ClusterProfile/Profile is then initialized right away.
+// If already existing, return nil (updateClusterConfigurationWithProfile takes care of it).
+func createClusterConfiguration(ctx context.Context, c client.Client, profile client.Object,
+	cluster *corev1.ObjectReference) error {
+
+	name := getClusterConfigurationName(cluster.Name, clusterproxy.GetClusterType(cluster))
+
+	// Avoid issuing a create, rejected by the API server, at every reconciliation
+	_, err := getClusterConfiguration(ctx, c, cluster.Namespace, name)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
 	clusterConfiguration := &configv1beta1.ClusterConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: cluster.Namespace,
-			Name:      getClusterConfigurationName(cluster.Name, clusterproxy.GetClusterType(cluster)),
-			Labels: map[string]string{
-				configv1beta1.ClusterNameLabel: cluster.Name,
-				configv1beta1.ClusterTypeLabel: string(clusterproxy.GetClusterType(cluster)),
-			},
+			Namespace:       cluster.Namespace,
+			Name:            name,
+			Labels:          getClusterConfigurationLabels(profile, cluster),
+			OwnerReferences: []metav1.OwnerReference{getProfileOwnerReference(profile)},
 		},
+		Spec: getClusterConfigurationSpec(cluster),
 	}
 
-	err := c.Create(ctx, clusterConfiguration)
+	err = c.Create(ctx, clusterConfiguration)
 	if err != nil {
 		if apierrors.IsAlreadyExists(err) {
 			return nil
 		}
+		return err
 	}
 
-	return err
+	// Status is a subresource and cannot be set on create.
+	return updateClusterConfigurationProfileResources(ctx, c, profile, clusterConfiguration)
 }
 
 // updateClusterConfigurations for each Sveltos/Cluster currently matching ClusterProfile/Profile:
@@ -233,7 +327,7 @@ func updateClusterConfigurations(ctx context.Context, c client.Client, profileSc
 		cluster := profileScope.GetStatus().MatchingClusterRefs[i]
 
 		// Create ClusterConfiguration if not already existing.
-		err := createClusterConfiguration(ctx, c, &cluster)
+		err := createClusterConfiguration(ctx, c, profileScope.Profile, &cluster)
 		if err != nil {
 			profileScope.Logger.Error(err, fmt.Sprintf("failed to create ClusterConfiguration for cluster %s/%s",
 				cluster.Namespace, cluster.Name))
@@ -252,10 +346,17 @@ func updateClusterConfigurations(ctx context.Context, c client.Client, profileSc
 	return nil
 }
 
+// fullyScannedProfiles contains the UIDs of the ClusterProfiles/Profiles for which all ClusterConfigurations
+// have already been examined once by cleanClusterConfigurations. ClusterConfigurations created by older
+// versions do not have the owner label, so first time all ClusterConfigurations are listed.
+var fullyScannedProfiles sync.Map
+
 // cleanClusterConfigurations finds all ClusterConfigurations currently owned by ClusterProfile/Profile.
 // For each such ClusterConfigurations, if corresponding Cluster is not a match anymore:
 // - remove (Cluster)Profile as OwnerReference
 // - if no more OwnerReferences are left, delete ClusterConfigurations
+// Only ClusterConfigurations with the owner label are listed, besides the first time a
+// ClusterProfile/Profile is processed.
 func cleanClusterConfigurations(ctx context.Context, c client.Client, profileScope *scope.ProfileScope) error {
 	clusterConfigurationList := &configv1beta1.ClusterConfigurationList{}
 
@@ -265,6 +366,13 @@ func cleanClusterConfigurations(ctx context.Context, c client.Client, profileSco
 			client.InNamespace(profileScope.Profile.GetNamespace()))
 	}
 
+	profileUID := profileScope.Profile.GetUID()
+	ownerLabel, ok := getClusterConfigurationOwnerLabel(profileScope.Profile)
+	_, fullyScanned := fullyScannedProfiles.Load(profileUID)
+	if ok && fullyScanned {
+		listOptions = append(listOptions, client.MatchingLabels{ownerLabel: "true"})
+	}
+
 	matchingClusterMap := make(map[string]bool)
 
 	info := func(namespace, clusterConfigurationName string) string {
@@ -294,6 +402,10 @@ func cleanClusterConfigurations(ctx context.Context, c client.Client, profileSco
 			return err
 		}
 	}
+
+	if ok && !fullyScanned {
+		fullyScannedProfiles.Store(profileUID, true)
+	}
 	return nil
 }
 
@@ -318,18 +430,16 @@ func cleanClusterConfiguration(ctx context.Context, c client.Client, profile cli
 func cleanClusterConfigurationOwnerReferences(ctx context.Context, c client.Client, profile client.Object,
 	clusterConfiguration *configv1beta1.ClusterConfiguration) error {
 
-	ownerRef := metav1.OwnerReference{
-		Kind:       profile.GetObjectKind().GroupVersionKind().Kind,
-		UID:        profile.GetUID(),
-		APIVersion: configv1beta1.GroupVersion.String(),
-		Name:       profile.GetName(),
-	}
+	ownerRef := getProfileOwnerReference(profile)
 
 	if !util.IsOwnedByObject(clusterConfiguration, profile) {
 		return nil
 	}
 
 	clusterConfiguration.OwnerReferences = util.RemoveOwnerRef(clusterConfiguration.OwnerReferences, ownerRef)
+	if ownerLabel, ok := getClusterConfigurationOwnerLabel(profile); ok {
+		delete(clusterConfiguration.Labels, ownerLabel)
+	}
 	if len(clusterConfiguration.OwnerReferences) == 0 {
 		return c.Delete(ctx, clusterConfiguration)
 	} else {
@@ -340,10 +450,13 @@ func cleanClusterConfigurationOwnerReferences(ctx context.Context, c client.Clie
 func cleanClusterConfigurationProfileResources(ctx context.Context, c client.Client, profile client.Object,
 	clusterConfiguration *configv1beta1.ClusterConfiguration) error {
 
+	var currentClusterConfiguration *configv1beta1.ClusterConfiguration
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		currentClusterConfiguration, err := getClusterConfiguration(ctx, c,
+		var err error
+		currentClusterConfiguration, err = getClusterConfiguration(ctx, c,
 			clusterConfiguration.Namespace, clusterConfiguration.Name)
 		if err != nil {
+			currentClusterConfiguration = nil
 			// If ClusterConfiguration is not found, nothing to do here.
 			// ClusterConfiguration is removed if (Cluster)Profile was the last owner.
 			if apierrors.IsNotFound(err) {
@@ -359,6 +472,11 @@ func cleanClusterConfigurationProfileResources(ctx context.Context, c client.Cli
 			return cleanProfileResources(ctx, c, profile, currentClusterConfiguration)
 		}
 	})
+
+	if err == nil && currentClusterConfiguration != nil {
+		// Add-ons deployed by profile are not listed anymore
+		recordClusterConfigurationSnapshotOrLog(ctx, c, currentClusterConfiguration)
+	}
 	return err
 }
 
@@ -435,21 +553,54 @@ func updateClusterSummary(ctx context.Context, c client.Client, profileScope *sc
 		return err
 	}
 
-	if reflect.DeepEqual(profileScope.GetSpec(), clusterSummary.Spec.ClusterProfileSpec) &&
-		reflect.DeepEqual(profileScope.Profile.GetAnnotations(), clusterSummary.Annotations) {
+	annotations := getClusterSummaryAnnotations(profileScope.Profile, clusterSummary)
+	spec := getResolvedSpec(profileScope)
+	if reflect.DeepEqual(spec, clusterSummary.Spec.ClusterProfileSpec) &&
+		profileScope.GetStatus().Revision == clusterSummary.Spec.ProfileRevision &&
+		reflect.DeepEqual(annotations, clusterSummary.Annotations) {
 		// Nothing has changed
 		return nil
 	}
 
-	clusterSummary.Annotations = profileScope.Profile.GetAnnotations()
-	clusterSummary.Spec.ClusterProfileSpec = *profileScope.GetSpec()
+	clusterSummary.Spec.ClusterProfileSpec = *spec
+	clusterSummary.Spec.ProfileRevision = profileScope.GetStatus().Revision
 	clusterSummary.Spec.ClusterType = clusterproxy.GetClusterType(cluster)
 	addClusterSummaryLabels(clusterSummary, profileScope, cluster)
 	// Copy annotation. Paused annotation might be set on ClusterProfile.
-	clusterSummary.Annotations = profileScope.Profile.GetAnnotations()
+	clusterSummary.Annotations = annotations
 	return c.Update(ctx, clusterSummary)
 }
 
+// getClusterSummaryAnnotations returns the annotations ClusterSummary should have: all annotations
+// of the owner ClusterProfile/Profile plus the per-cluster annotations (pause, pinned chart versions,
+// pending plan), if currently set on the ClusterSummary.
+func getClusterSummaryAnnotations(profile client.Object, clusterSummary *configv1beta1.ClusterSummary,
+) map[string]string {
+
+	annotations := profile.GetAnnotations()
+
+	var result map[string]string
+	for _, key := range []string{configv1beta1.PausedAnnotation, configv1beta1.PinnedChartVersionsAnnotation,
+		configv1beta1.PlanAnnotation} {
+		v, ok := clusterSummary.Annotations[key]
+		if !ok {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string, len(annotations)+1)
+			for k := range annotations {
+				result[k] = annotations[k]
+			}
+		}
+		result[key] = v
+	}
+
+	if result == nil {
+		return annotations
+	}
+	return result
+}
+
 func addClusterSummaryLabels(clusterSummary *configv1beta1.ClusterSummary, profileScope *scope.ProfileScope,
 	cluster *corev1.ObjectReference) {
 
@@ -502,7 +653,8 @@ func createClusterSummary(ctx context.Context, c client.Client, profileScope *sc
 		Spec: configv1beta1.ClusterSummarySpec{
 			ClusterNamespace:   cluster.Namespace,
 			ClusterName:        cluster.Name,
-			ClusterProfileSpec: *profileScope.GetSpec(),
+			ClusterProfileSpec: *getResolvedSpec(profileScope),
+			ProfileRevision:    profileScope.GetStatus().Revision,
 		},
 	}
 
@@ -512,7 +664,47 @@ func createClusterSummary(ctx context.Context, c client.Client, profileScope *sc
 	// Copy annotation. Paused annotation might be set on ClusterProfile.
 	clusterSummary.Annotations = profileScope.Profile.GetAnnotations()
 
-	return c.Create(ctx, clusterSummary)
+	err := c.Create(ctx, clusterSummary)
+	if apierrors.IsAlreadyExists(err) {
+		// Name is taken by the ClusterSummary of another ClusterProfile/Profile or cluster whose concatenated
+		// names collide (ClusterSummary for this ClusterProfile/Profile and cluster was not found by labels).
+		clusterSummary.Name = addonclient.GetHashedClusterSummaryName(profileScope.GetKind(), profileScope.Name(),
+			cluster.Name, cluster.APIVersion == libsveltosv1beta1.GroupVersion.String())
+		return c.Create(ctx, clusterSummary)
+	}
+	return err
+}
+
+// adoptLegacyClusterSummary looks for a ClusterSummary, created by ClusterProfile/Profile for cluster, with
+// the legacy name (see GetLegacyClusterSummaryName) and missing the labels ClusterSummaries are looked up
+// by. If one is found, labels are added so it keeps being used (ClusterSummaries cannot be renamed and
+// replacing one would withdraw and then redeploy all add-ons). Returns true if one was adopted.
+func adoptLegacyClusterSummary(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	cluster *corev1.ObjectReference) (bool, error) {
+
+	clusterSummary := &configv1beta1.ClusterSummary{}
+	err := c.Get(ctx, types.NamespacedName{
+		Namespace: cluster.Namespace,
+		Name: addonclient.GetLegacyClusterSummaryName(profileScope.GetKind(), profileScope.Name(),
+			cluster.Name, cluster.APIVersion == libsveltosv1beta1.GroupVersion.String()),
+	}, clusterSummary)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	ref, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
+	if err != nil || ref.Kind != profileScope.GetKind() || ref.Name != profileScope.Name() ||
+		clusterSummary.Spec.ClusterNamespace != cluster.Namespace || clusterSummary.Spec.ClusterName != cluster.Name {
+
+		// Not created by this ClusterProfile/Profile for this cluster
+		return false, nil
+	}
+
+	addClusterSummaryLabels(clusterSummary, profileScope, cluster)
+	return true, c.Update(ctx, clusterSummary)
 }
 
 // updateClusterSummaries for each Sveltos/Cluster currently matching ClusterProfile/Profile:
@@ -521,6 +713,7 @@ func createClusterSummary(ctx context.Context, c client.Client, profileScope *sc
 // Return an error if due to MaxUpdate not all ClusterSummaries are synced
 func updateClusterSummaries(ctx context.Context, c client.Client, profileScope *scope.ProfileScope) error {
 	currentHash := getProfileSpecHash(profileScope)
+	recordRevision(profileScope, currentHash, time.Now())
 
 	// Remove Status.UpdatedClusters if hash is different
 	if !reflect.DeepEqual(profileScope.GetStatus().UpdatedClusters.Hash, currentHash) {
@@ -539,6 +732,27 @@ func updateClusterSummaries(ctx context.Context, c client.Client, profileScope *
 
 	updatedClusters, updatingClusters := getUpdatedAndUpdatingClusters(profileScope)
 
+	plan, err := reviseRolloutStatus(ctx, c, profileScope, currentHash, updatedClusters, updatingClusters, time.Now())
+	if err != nil {
+		return err
+	}
+
+	abort, err := getRolloutAbort(ctx, c, profileScope, updatingClusters, plan)
+	if err != nil {
+		return err
+	}
+	rollback := profileScope.GetSpec().RollbackOnRolloutAbort
+	if abort != nil {
+		profileScope.SetRolloutAborted(abort.reason, abort.message)
+		if rollback {
+			if err := rollbackClusterSummaries(ctx, c, profileScope, profileScope.Logger); err != nil {
+				return err
+			}
+		}
+	} else {
+		profileScope.SetRolloutAborted("", "")
+	}
+
 	maxUpdate := getMaxUpdate(profileScope)
 
 	skippedUpdate := false
@@ -550,7 +764,7 @@ func updateClusterSummaries(ctx context.Context, c client.Client, profileScope *
 		logger := profileScope.Logger
 		logger = logger.WithValues("cluster", fmt.Sprintf("%s:%s/%s", cluster.Kind, cluster.Namespace, cluster.Name))
 
-		ready, err := clusterproxy.IsClusterReadyToBeConfigured(ctx, c, &cluster, profileScope.Logger)
+		ready, err := isClusterReadyToBeConfigured(ctx, c, &cluster, profileScope.Logger)
 		if err != nil {
 			return err
 		}
@@ -564,10 +778,24 @@ func updateClusterSummaries(ctx context.Context, c client.Client, profileScope *
 			continue
 		}
 
+		// once rollout is aborted, no more clusters are updated. Clusters being rolled back are not updated either.
+		if abort != nil && (rollback || !updatingClusters.Has(&cluster)) {
+			logger.V(logs.LogDebug).Info("Rollout is aborted")
+			skippedUpdate = true
+			continue
+		}
+
+		// if rolloutRings are set, clusters in a ring are updated only once previous rings are completed
+		if !plan.canUpdate(&cluster, updatingClusters.Has(&cluster)) {
+			logger.V(logs.LogDebug).Info("Cluster rollout ring is not being updated")
+			skippedUpdate = true
+			continue
+		}
+
 		if maxUpdate != 0 {
 			// maxUpdate is set. Skip paused clusters (which would not be updated anyhow as set to paused)
 			// and try to pcik any non paused cluster
-			isClusterPaused, err := clusterproxy.IsClusterPaused(ctx, c, cluster.Namespace,
+			isClusterPaused, err := isClusterPaused(ctx, c, cluster.Namespace,
 				cluster.Name, clusterproxy.GetClusterType(&cluster))
 			if err != nil {
 				logger.V(logs.LogDebug).Info(fmt.Sprintf("failed to verify if cluster is paused: %v", err))
@@ -606,11 +834,20 @@ func updateClusterSummaries(ctx context.Context, c client.Client, profileScope *
 		profileScope.GetStatus().UpdatingClusters.Hash = currentHash
 	}
 
+	if abort != nil {
+		return fmt.Errorf("rollout aborted: %s", abort.message)
+	}
+
 	if skippedUpdate {
 		return fmt.Errorf("not all clusters updated yet. %d still being updated",
 			len(profileScope.GetStatus().UpdatingClusters.Clusters))
 	}
 
+	// Spec was rolled out to all matching clusters. Keep it to roll clusters back if a future rollout is aborted
+	if !profileScope.IsDryRunSync() {
+		profileScope.GetStatus().LastRolledOutSpec = getResolvedSpec(profileScope).DeepCopy()
+	}
+
 	// If all ClusterSummaries have been updated, reset Updated and Updating
 	profileScope.GetStatus().UpdatedClusters = configv1beta1.Clusters{}
 	profileScope.GetStatus().UpdatingClusters = configv1beta1.Clusters{}
@@ -629,6 +866,15 @@ func patchClusterSummary(ctx context.Context, c client.Client, profileScope *sco
 		cluster.Name, clusterproxy.GetClusterType(cluster))
 	if err != nil {
 		if apierrors.IsNotFound(err) {
+			var adopted bool
+			adopted, err = adoptLegacyClusterSummary(ctx, c, profileScope, cluster)
+			if err != nil {
+				logger.Error(err, "failed to adopt ClusterSummary")
+				return err
+			}
+			if adopted {
+				return nil
+			}
 			err = createClusterSummary(ctx, c, profileScope, cluster)
 			if err != nil {
 				logger.Error(err, "failed to create ClusterSummary")
@@ -685,14 +931,18 @@ func cleanClusterSummaries(ctx context.Context, c client.Client, profileScope *s
 		if util.IsOwnedByObject(cs, profileScope.Profile) {
 			if _, ok := matching[getClusterInfo(cs.Spec.ClusterNamespace, cs.Spec.ClusterName, cs.Spec.ClusterType)]; !ok {
 				foundClusterSummaries = true
-				err := c.Delete(ctx, cs)
-				if err != nil {
-					profileScope.Logger.Error(err, fmt.Sprintf("failed to update ClusterSummary for cluster %s/%s",
-						cs.Namespace, cs.Name))
-					return err
+				// Do not issue a delete at every reconciliation while ClusterSummary is being deleted
+				if cs.DeletionTimestamp.IsZero() {
+					err := c.Delete(ctx, cs)
+					if err != nil {
+						profileScope.Logger.Error(err, fmt.Sprintf("failed to update ClusterSummary for cluster %s/%s",
+							cs.Namespace, cs.Name))
+						return err
+					}
 				}
 			}
 		}
+		// ClusterSummaries being deleted must withdraw add-ons honoring current SyncMode
 		if err := updateClusterSummarySyncMode(ctx, c, cs, profileScope.GetSpec().SyncMode); err != nil {
 			return err
 		}
@@ -722,8 +972,17 @@ func updateClusterSummarySyncMode(ctx context.Context, c client.Client,
 		return err
 	}
 
+	if currentClusterSummary.Spec.ClusterProfileSpec.SyncMode == syncMode {
+		return nil
+	}
+
+	patch := client.MergeFrom(currentClusterSummary.DeepCopy())
+	currentClusterSummary.Spec.ClusterProfileSpec.SyncMode = syncMode
+	if err := c.Patch(ctx, currentClusterSummary, patch); err != nil {
+		return err
+	}
 	clusterSummary.Spec.ClusterProfileSpec.SyncMode = syncMode
-	return c.Update(ctx, clusterSummary)
+	return nil
 }
 
 // ClusterReports
@@ -732,6 +991,7 @@ func updateClusterSummarySyncMode(ctx context.Context, c client.Client,
 // - if syncMode is DryRun, creates corresponding ClusterReport if one does not exist already;
 // - if syncMode is DryRun, deletes ClusterReports for any Sveltos/Cluster not matching anymore;
 // - if syncMode is not DryRun, deletes ClusterReports created by this ClusterProfile instance
+// (but the ones created because a plan was requested)
 func updateClusterReports(ctx context.Context, c client.Client, profileScope *scope.ProfileScope) error {
 	if profileScope.IsDryRunSync() {
 		err := createClusterReports(ctx, c, profileScope)
@@ -741,7 +1001,7 @@ func updateClusterReports(ctx context.Context, c client.Client, profileScope *sc
 		}
 	} else {
 		// delete all ClusterReports created by this ClusterProfile/Profile instance
-		err := cleanClusterReports(ctx, c, profileScope.Profile)
+		err := cleanClusterReports(ctx, c, profileScope.Profile, true)
 		if err != nil {
 			profileScope.Logger.Error(err, "failed to delete ClusterReports")
 			return err
@@ -766,41 +1026,80 @@ func createClusterReports(ctx context.Context, c client.Client, profileScope *sc
 }
 
 // createClusterReport creates ClusterReport given a Sveltos/Cluster.
-// If already existing, return nil
+// If already existing, Spec.ClusterType is backfilled if not set yet.
 func createClusterReport(ctx context.Context, c client.Client, profile client.Object,
 	cluster *corev1.ObjectReference) error {
 
+	return createClusterReportWithAnnotations(ctx, c, profile, cluster, nil)
+}
+
+// createClusterReportWithAnnotations creates ClusterReport, with annotations, given a Sveltos/Cluster.
+// If already existing, Spec.ClusterType is backfilled if not set yet.
+func createClusterReportWithAnnotations(ctx context.Context, c client.Client, profile client.Object,
+	cluster *corev1.ObjectReference, annotations map[string]string) error {
+
 	clusterType := clusterproxy.GetClusterType(cluster)
 
+	labels := map[string]string{
+		configv1beta1.ClusterNameLabel: cluster.Name,
+		configv1beta1.ClusterTypeLabel: string(clusterType),
+	}
+	// Label must match the one cleanClusterReports selects on
+	if profile.GetObjectKind().GroupVersionKind().Kind == configv1beta1.ClusterProfileKind {
+		labels[ClusterProfileLabelName] = profile.GetName()
+	} else {
+		labels[ProfileLabelName] = profile.GetName()
+	}
+
 	clusterReport := &configv1beta1.ClusterReport{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
 			Name: getClusterReportName(profile.GetObjectKind().GroupVersionKind().Kind, profile.GetName(),
 				cluster.Name, clusterType),
-			Labels: map[string]string{
-				ClusterProfileLabelName:        profile.GetName(),
-				configv1beta1.ClusterNameLabel: cluster.Name,
-				configv1beta1.ClusterTypeLabel: string(clusterproxy.GetClusterType(cluster)),
-			},
+			Labels:      labels,
+			Annotations: annotations,
 		},
 		Spec: configv1beta1.ClusterReportSpec{
 			ClusterNamespace: cluster.Namespace,
 			ClusterName:      cluster.Name,
+			ClusterType:      clusterType,
 		},
 	}
 
 	err := c.Create(ctx, clusterReport)
 	if err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			return nil
+			return backfillClusterReportClusterType(ctx, c, clusterReport.Namespace, clusterReport.Name, clusterType)
 		}
 	}
 
 	return err
 }
 
+// backfillClusterReportClusterType sets Spec.ClusterType on ClusterReports created before
+// the field was introduced
+func backfillClusterReportClusterType(ctx context.Context, c client.Client, namespace, name string,
+	clusterType libsveltosv1beta1.ClusterType) error {
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		currentClusterReport := &configv1beta1.ClusterReport{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, currentClusterReport)
+		if err != nil {
+			return err
+		}
+
+		if currentClusterReport.Spec.ClusterType != "" {
+			return nil
+		}
+
+		currentClusterReport.Spec.ClusterType = clusterType
+		return c.Update(ctx, currentClusterReport)
+	})
+}
+
 // cleanClusterReports deletes ClusterReports created by this ClusterProfile/Profile instance.
-func cleanClusterReports(ctx context.Context, c client.Client, profile client.Object) error {
+// If keepPlans is set, ClusterReports created because a plan was requested are not deleted.
+func cleanClusterReports(ctx context.Context, c client.Client, profile client.Object, keepPlans bool) error {
 	listOptions := []client.ListOption{}
 
 	if profile.GetObjectKind().GroupVersionKind().Kind == configv1beta1.ClusterProfileKind {
@@ -819,23 +1118,28 @@ func cleanClusterReports(ctx context.Context, c client.Client, profile client.Ob
 
 	for i := range clusterReportList.Items {
 		cr := &clusterReportList.Items[i]
+		if keepPlans && isPlanClusterReport(cr) {
+			continue
+		}
 		err = c.Delete(ctx, cr)
 		if err != nil {
 			if !apierrors.IsNotFound(err) {
 				return err
 			}
 		}
+		removeClusterReportLock(types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name})
 	}
 
 	return nil
 }
 
-// getProfileSpecHash returns hash of current clusterProfile/Profile Spec
+// getProfileSpecHash returns hash of current clusterProfile/Profile Spec (with helm chart version
+// constraints resolved, so a newly resolved version is rolled out like a Spec change)
 func getProfileSpecHash(profileScope *scope.ProfileScope) []byte {
 	h := sha256.New()
 	var config string
 
-	config += render.AsCode(profileScope.GetSpec())
+	config += render.AsCode(getResolvedSpec(profileScope))
 
 	h.Write([]byte(config))
 	return h.Sum(nil)
@@ -960,6 +1264,129 @@ func reviseUpdatingClusterList(ctx context.Context, c client.Client, profileScop
 	return nil
 }
 
+// updateClusterSummariesStatus sets ClusterProfile/Profile Status.ClusterSummaries with the
+// deployment state of each matching cluster
+func updateClusterSummariesStatus(ctx context.Context, c client.Client, profileScope *scope.ProfileScope) error {
+	summariesStatus := &configv1beta1.ClusterSummariesStatus{
+		Clusters: make([]configv1beta1.ClusterDeploymentStatus, 0),
+	}
+
+	_, updatingClusters := getUpdatedAndUpdatingClusters(profileScope)
+	queued := int32(0)
+
+	for i := range profileScope.GetStatus().MatchingClusterRefs {
+		cluster := &profileScope.GetStatus().MatchingClusterRefs[i]
+
+		clusterSummary, err := getClusterSummary(ctx, c, profileScope.GetKind(), profileScope.Name(),
+			cluster.Namespace, cluster.Name, clusterproxy.GetClusterType(cluster))
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			// ClusterSummary not created yet (for instance cluster is not ready yet)
+			clusterSummary = nil
+		}
+
+		clusterStatus := getClusterDeploymentStatus(clusterSummary)
+		clusterStatus.Cluster = *cluster
+		if isClusterSummaryQueued(profileScope, clusterSummary, updatingClusters.Has(cluster)) {
+			// Clusters are walked in the same order clusters are picked to be updated
+			queued++
+			position := queued
+			clusterStatus.State = configv1beta1.ClusterDeploymentStateQueued
+			clusterStatus.QueuePosition = &position
+			clusterStatus.FailureMessage = nil
+		}
+		if len(clusterStatus.PinnedCharts) > 0 {
+			summariesStatus.Pinned++
+		}
+		switch clusterStatus.State {
+		case configv1beta1.ClusterDeploymentStateProvisioned:
+			summariesStatus.Provisioned++
+		case configv1beta1.ClusterDeploymentStateFailed:
+			summariesStatus.Failed++
+		case configv1beta1.ClusterDeploymentStateQueued:
+			summariesStatus.Queued++
+		default:
+			summariesStatus.Provisioning++
+		}
+		summariesStatus.Clusters = append(summariesStatus.Clusters, *clusterStatus)
+	}
+
+	sort.Slice(summariesStatus.Clusters, func(i, j int) bool {
+		ci := &summariesStatus.Clusters[i].Cluster
+		cj := &summariesStatus.Clusters[j].Cluster
+		if ci.Namespace != cj.Namespace {
+			return ci.Namespace < cj.Namespace
+		}
+		if ci.Name != cj.Name {
+			return ci.Name < cj.Name
+		}
+		return ci.Kind < cj.Kind
+	})
+
+	profileScope.GetStatus().ClusterSummaries = summariesStatus
+	return nil
+}
+
+// isClusterSummaryQueued returns true if ClusterSummary is still to be updated to the current
+// ClusterProfile/Profile Spec because of MaxUpdate or RolloutRings.
+func isClusterSummaryQueued(profileScope *scope.ProfileScope, clusterSummary *configv1beta1.ClusterSummary,
+	isUpdating bool) bool {
+
+	if clusterSummary == nil || isUpdating {
+		return false
+	}
+
+	// ClusterSummaries are never updated in OneTime mode. Once rollout is aborted, ClusterSummaries
+	// are not updated till Spec changes.
+	if profileScope.IsOneTimeSync() || profileScope.IsRolloutAborted() {
+		return false
+	}
+
+	return !reflect.DeepEqual(*getResolvedSpec(profileScope), clusterSummary.Spec.ClusterProfileSpec)
+}
+
+// getClusterDeploymentStatus returns the condensed deployment state of a ClusterSummary.
+// A nil ClusterSummary is considered Provisioning.
+func getClusterDeploymentStatus(clusterSummary *configv1beta1.ClusterSummary) *configv1beta1.ClusterDeploymentStatus {
+	status := &configv1beta1.ClusterDeploymentStatus{
+		State: configv1beta1.ClusterDeploymentStateProvisioning,
+	}
+
+	if clusterSummary == nil {
+		return status
+	}
+
+	status.ObservedRevision = clusterSummary.Status.ObservedRevision
+	status.PinnedCharts = getPinnedCharts(clusterSummary)
+
+	if clusterSummary.Status.QueuePosition != nil {
+		// Waiting for a deployment slot (MaxConcurrentClusterDeployments)
+		position := *clusterSummary.Status.QueuePosition
+		status.State = configv1beta1.ClusterDeploymentStateQueued
+		status.QueuePosition = &position
+		return status
+	}
+
+	for i := range clusterSummary.Status.FeatureSummaries {
+		fs := &clusterSummary.Status.FeatureSummaries[i]
+		if fs.Status == configv1beta1.FeatureStatusFailed ||
+			fs.Status == configv1beta1.FeatureStatusFailedNonRetriable {
+
+			status.State = configv1beta1.ClusterDeploymentStateFailed
+			status.FailureMessage = fs.FailureMessage
+			return status
+		}
+	}
+
+	if isCluterSummaryProvisioned(clusterSummary) {
+		status.State = configv1beta1.ClusterDeploymentStateProvisioned
+	}
+
+	return status
+}
+
 func addFinalizer(ctx context.Context, profileScope *scope.ProfileScope, finalizer string) error {
 	controllerutil.AddFinalizer(profileScope.Profile, finalizer)
 	if err := profileScope.PatchObject(ctx); err != nil {
@@ -995,7 +1422,8 @@ func reconcileDeleteCommon(ctx context.Context, c client.Client, profileScope *s
 	}
 
 	profile := profileScope.Profile
-	if err := cleanClusterReports(ctx, c, profile); err != nil {
+	fullyScannedProfiles.Delete(profile.GetUID())
+	if err := cleanClusterReports(ctx, c, profile, false); err != nil {
 		logger.V(logs.LogInfo).Error(err, "failed to clean ClusterReports")
 		return err
 	}
@@ -1013,8 +1441,18 @@ func reconcileDeleteCommon(ctx context.Context, c client.Client, profileScope *s
 	return nil
 }
 
-func reconcileNormalCommon(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
-	logger logr.Logger) error {
+func reconcileNormalCommon(ctx context.Context, c client.Client, recorder record.EventRecorder,
+	profileScope *scope.ProfileScope, logger logr.Logger) error {
+
+	collectDebugBundleIfRequested(ctx, c, profileScope, logger)
+
+	if !reviseControllerVersionSupported(profileScope, logger) {
+		// Do not partially honor a Spec written for a newer controller
+		return nil
+	}
+
+	rollbackIfRequested(recorder, profileScope, logger)
+	resolveChartVersionConstraints(ctx, c, profileScope, logger)
 
 	// For each matching Sveltos/Cluster, create/update corresponding ClusterConfiguration
 	if err := updateClusterConfigurations(ctx, c, profileScope); err != nil {
@@ -1026,11 +1464,30 @@ func reconcileNormalCommon(ctx context.Context, c client.Client, profileScope *s
 		logger.V(logs.LogInfo).Error(err, "failed to update ClusterReports")
 		return err
 	}
+	requestPlanIfRequested(ctx, c, profileScope, logger)
 	// For each matching Sveltos/Cluster, create/update corresponding ClusterSummary
-	if err := updateClusterSummaries(ctx, c, profileScope); err != nil {
-		logger.V(logs.LogInfo).Error(err, "failed to update ClusterSummaries")
+	wasAborted := profileScope.IsRolloutAborted()
+	wasCompleted := isRolloutCompleted(profileScope)
+	updateErr := updateClusterSummaries(ctx, c, profileScope)
+	if !wasAborted && profileScope.IsRolloutAborted() {
+		recordRolloutAborted(recorder, profileScope)
+	}
+	if !wasCompleted && isRolloutCompleted(profileScope) {
+		notifyRolloutCompleted(ctx, c, profileScope, logger)
+	}
+
+	// Aggregate ClusterSummaries status. This is done even if not all ClusterSummaries are
+	// updated yet (MaxUpdate) so rollout progress is always reported.
+	if err := updateClusterSummariesStatus(ctx, c, profileScope); err != nil {
+		logger.V(logs.LogInfo).Error(err, "failed to aggregate ClusterSummaries status")
 		return err
 	}
+	updateReadyCondition(profileScope)
+
+	if updateErr != nil {
+		logger.V(logs.LogInfo).Error(updateErr, "failed to update ClusterSummaries")
+		return updateErr
+	}
 
 	// For Sveltos/Cluster not matching, deletes corresponding ClusterSummary
 	if err := cleanClusterSummaries(ctx, c, profileScope); err != nil {
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -39,6 +40,7 @@ import (
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	"github.com/projectsveltos/addon-controller/controllers"
 	"github.com/projectsveltos/addon-controller/internal/test/helpers/external"
+	"github.com/projectsveltos/addon-controller/pkg/addonclient"
 	"github.com/projectsveltos/addon-controller/pkg/scope"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 )
@@ -194,6 +196,51 @@ var _ = Describe("Profile: Reconciler", func() {
 		Expect(len(currentClusterConfiguration.Status.ClusterProfileResources)).To(Equal(1))
 	})
 
+	It("CreateClusterConfiguration creates ClusterConfiguration with labels, OwnerReference and Status", func() {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+			},
+		}
+
+		initObjects := []client.Object{
+			clusterProfile,
+			ns,
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&configv1beta1.ClusterConfiguration{}).
+			WithObjects(initObjects...).Build()
+
+		clusterRef := corev1.ObjectReference{Namespace: matchingCluster.Namespace, Name: matchingCluster.Name,
+			Kind: clusterKind, APIVersion: clusterv1.GroupVersion.String()}
+		Expect(controllers.CreateClusterConfiguration(context.TODO(), c, clusterProfile, &clusterRef)).To(Succeed())
+		// Calling it again is a no-op
+		Expect(controllers.CreateClusterConfiguration(context.TODO(), c, clusterProfile, &clusterRef)).To(Succeed())
+
+		currentClusterConfiguration := &configv1beta1.ClusterConfiguration{}
+		Expect(c.Get(context.TODO(),
+			types.NamespacedName{
+				Namespace: matchingCluster.Namespace,
+				Name:      controllers.GetClusterConfigurationName(matchingCluster.Name, libsveltosv1beta1.ClusterTypeCapi),
+			},
+			currentClusterConfiguration)).To(Succeed())
+
+		Expect(currentClusterConfiguration.Labels).ToNot(BeNil())
+		Expect(currentClusterConfiguration.Labels[configv1beta1.ClusterNameLabel]).To(Equal(matchingCluster.Name))
+		Expect(currentClusterConfiguration.Labels[configv1beta1.ClusterTypeLabel]).To(
+			Equal(string(libsveltosv1beta1.ClusterTypeCapi)))
+
+		Expect(currentClusterConfiguration.Spec.ClusterName).To(Equal(matchingCluster.Name))
+		Expect(currentClusterConfiguration.Spec.ClusterType).To(Equal(libsveltosv1beta1.ClusterTypeCapi))
+
+		Expect(len(currentClusterConfiguration.OwnerReferences)).To(Equal(1))
+		Expect(currentClusterConfiguration.OwnerReferences[0].Name).To(Equal(clusterProfile.Name))
+
+		Expect(len(currentClusterConfiguration.Status.ClusterProfileResources)).To(Equal(1))
+		Expect(currentClusterConfiguration.Status.ClusterProfileResources[0].ClusterProfileName).To(
+			Equal(clusterProfile.Name))
+	})
+
 	It("CleanClusterConfiguration idempotently removes ClusterProfile as OwnerReference and from Status.ClusterProfileResources", func() {
 		ns := &corev1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
@@ -284,6 +331,88 @@ var _ = Describe("Profile: Reconciler", func() {
 		Expect(len(currentClusterConfiguration.Status.ClusterProfileResources)).To(Equal(0))
 	})
 
+	It("CleanClusterConfigurations removes ClusterProfile as owner of ClusterConfigurations not matching anymore", func() {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+			},
+		}
+
+		clusterProfile.UID = types.UID(randomString())
+
+		// ClusterConfiguration created before the owner label was introduced. A second owner
+		// is present so ClusterConfiguration is not deleted when ClusterProfile is removed as owner.
+		legacyClusterConfiguration := &configv1beta1.ClusterConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: nonMatchingCluster.Namespace,
+				Name:      controllers.GetClusterConfigurationName(nonMatchingCluster.Name, libsveltosv1beta1.ClusterTypeCapi),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       clusterProfile.Name,
+						APIVersion: configv1beta1.GroupVersion.String(),
+						UID:        clusterProfile.UID,
+					},
+					{
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       randomString(),
+						APIVersion: configv1beta1.GroupVersion.String(),
+						UID:        types.UID(randomString()),
+					},
+				},
+			},
+		}
+
+		initObjects := []client.Object{
+			clusterProfile,
+			ns,
+			legacyClusterConfiguration,
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&configv1beta1.ClusterConfiguration{}).
+			WithObjects(initObjects...).Build()
+
+		clusterRef := corev1.ObjectReference{Namespace: matchingCluster.Namespace, Name: matchingCluster.Name,
+			Kind: clusterKind, APIVersion: clusterv1.GroupVersion.String()}
+		Expect(controllers.CreateClusterConfiguration(context.TODO(), c, clusterProfile, &clusterRef)).To(Succeed())
+
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{}
+		clusterConfigurationName := types.NamespacedName{
+			Namespace: matchingCluster.Namespace,
+			Name:      controllers.GetClusterConfigurationName(matchingCluster.Name, libsveltosv1beta1.ClusterTypeCapi),
+		}
+		Expect(c.Get(context.TODO(), clusterConfigurationName, clusterConfiguration)).To(Succeed())
+		Expect(clusterConfiguration.Labels).To(HaveKeyWithValue("clusterprofile.projectsveltos.io/"+clusterProfile.Name, "true"))
+
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logger,
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+		profileScope.SetMatchingClusterRefs([]corev1.ObjectReference{clusterRef})
+
+		// First time all ClusterConfigurations are considered, including the ones without owner label
+		Expect(controllers.CleanClusterConfigurations(context.TODO(), c, profileScope)).To(Succeed())
+
+		currentClusterConfiguration := &configv1beta1.ClusterConfiguration{}
+		Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(legacyClusterConfiguration),
+			currentClusterConfiguration)).To(Succeed())
+		Expect(len(currentClusterConfiguration.OwnerReferences)).To(Equal(1))
+		Expect(currentClusterConfiguration.OwnerReferences[0].Name).ToNot(Equal(clusterProfile.Name))
+
+		Expect(c.Get(context.TODO(), clusterConfigurationName, currentClusterConfiguration)).To(Succeed())
+
+		// No cluster is a match anymore. ClusterConfiguration, found by owner label, is deleted.
+		profileScope.SetMatchingClusterRefs(nil)
+		Expect(controllers.CleanClusterConfigurations(context.TODO(), c, profileScope)).To(Succeed())
+
+		err = c.Get(context.TODO(), clusterConfigurationName, currentClusterConfiguration)
+		Expect(err).ToNot(BeNil())
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
 	It("CreateClusterSummary creates ClusterSummary with proper fields", func() {
 		initObjects := []client.Object{
 			clusterProfile,
@@ -322,6 +451,108 @@ var _ = Describe("Profile: Reconciler", func() {
 		Expect(owner.Kind).To(Equal(clusterProfile.Kind))
 	})
 
+	It("updateClusterSummariesStatus aggregates ClusterSummaries deployment state", func() {
+		matchingClusterRef := corev1.ObjectReference{
+			Namespace:  matchingCluster.Namespace,
+			Name:       matchingCluster.Name,
+			APIVersion: clusterv1.GroupVersion.String(),
+			Kind:       clusterKind,
+		}
+		nonMatchingClusterRef := corev1.ObjectReference{
+			Namespace:  nonMatchingCluster.Namespace,
+			Name:       nonMatchingCluster.Name,
+			APIVersion: clusterv1.GroupVersion.String(),
+			Kind:       clusterKind,
+		}
+
+		initObjects := []client.Object{
+			clusterProfile,
+			matchingCluster,
+			nonMatchingCluster,
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).WithObjects(initObjects...).Build()
+
+		clusterProfileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logger,
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+
+		Expect(controllers.CreateClusterSummary(context.TODO(), c, clusterProfileScope, &matchingClusterRef)).To(Succeed())
+
+		clusterSummaryList := &configv1beta1.ClusterSummaryList{}
+		Expect(c.List(context.TODO(), clusterSummaryList)).To(Succeed())
+		Expect(len(clusterSummaryList.Items)).To(Equal(1))
+		clusterSummary := &clusterSummaryList.Items[0]
+		failureMessage := randomString()
+		clusterSummary.Status.FeatureSummaries = []configv1beta1.FeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusFailed, FailureMessage: &failureMessage},
+		}
+		Expect(c.Update(context.TODO(), clusterSummary)).To(Succeed())
+
+		// No ClusterSummary exists for nonMatchingCluster yet
+		clusterProfile.Status.MatchingClusterRefs = []corev1.ObjectReference{matchingClusterRef, nonMatchingClusterRef}
+		Expect(controllers.UpdateClusterSummariesStatus(context.TODO(), c, clusterProfileScope)).To(Succeed())
+
+		status := clusterProfile.Status.ClusterSummaries
+		Expect(status).ToNot(BeNil())
+		Expect(status.Failed).To(Equal(int32(1)))
+		Expect(status.Provisioning).To(Equal(int32(1)))
+		Expect(status.Provisioned).To(Equal(int32(0)))
+		Expect(len(status.Clusters)).To(Equal(2))
+		for i := range status.Clusters {
+			if status.Clusters[i].Cluster.Name == matchingCluster.Name {
+				Expect(status.Clusters[i].State).To(Equal(configv1beta1.ClusterDeploymentStateFailed))
+				Expect(status.Clusters[i].FailureMessage).ToNot(BeNil())
+				Expect(*status.Clusters[i].FailureMessage).To(Equal(failureMessage))
+			} else {
+				Expect(status.Clusters[i].State).To(Equal(configv1beta1.ClusterDeploymentStateProvisioning))
+			}
+		}
+
+		clusterSummary.Status.FeatureSummaries = []configv1beta1.FeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusProvisioned},
+		}
+		Expect(c.Update(context.TODO(), clusterSummary)).To(Succeed())
+
+		clusterProfile.Status.MatchingClusterRefs = []corev1.ObjectReference{matchingClusterRef}
+		Expect(controllers.UpdateClusterSummariesStatus(context.TODO(), c, clusterProfileScope)).To(Succeed())
+		status = clusterProfile.Status.ClusterSummaries
+		Expect(status.Provisioned).To(Equal(int32(1)))
+		Expect(status.Failed).To(Equal(int32(0)))
+		Expect(len(status.Clusters)).To(Equal(1))
+
+		// ClusterSummary waiting for a deployment slot is queued
+		position := int32(3)
+		clusterSummary.Status.QueuePosition = &position
+		Expect(c.Update(context.TODO(), clusterSummary)).To(Succeed())
+		Expect(controllers.UpdateClusterSummariesStatus(context.TODO(), c, clusterProfileScope)).To(Succeed())
+		status = clusterProfile.Status.ClusterSummaries
+		Expect(status.Queued).To(Equal(int32(1)))
+		Expect(status.Clusters[0].State).To(Equal(configv1beta1.ClusterDeploymentStateQueued))
+		Expect(*status.Clusters[0].QueuePosition).To(Equal(position))
+
+		// ClusterSummary not updated yet to current Spec (for instance because of MaxUpdate) is queued
+		clusterSummary.Status.QueuePosition = nil
+		Expect(c.Update(context.TODO(), clusterSummary)).To(Succeed())
+		clusterProfile.Spec.Tier++
+		Expect(controllers.UpdateClusterSummariesStatus(context.TODO(), c, clusterProfileScope)).To(Succeed())
+		status = clusterProfile.Status.ClusterSummaries
+		Expect(status.Queued).To(Equal(int32(1)))
+		Expect(status.Clusters[0].State).To(Equal(configv1beta1.ClusterDeploymentStateQueued))
+		Expect(*status.Clusters[0].QueuePosition).To(Equal(int32(1)))
+
+		// Cluster being updated is not queued
+		clusterProfile.Status.UpdatingClusters.Clusters = []corev1.ObjectReference{matchingClusterRef}
+		Expect(controllers.UpdateClusterSummariesStatus(context.TODO(), c, clusterProfileScope)).To(Succeed())
+		status = clusterProfile.Status.ClusterSummaries
+		Expect(status.Queued).To(Equal(int32(0)))
+		Expect(status.Provisioned).To(Equal(int32(1)))
+	})
+
 	It("UpdateClusterSummary updates ClusterSummary with proper fields when ClusterProfile syncmode set to continuous", func() {
 		sveltosCluster := &libsveltosv1beta1.SveltosCluster{
 			ObjectMeta: metav1.ObjectMeta{
@@ -823,6 +1054,7 @@ var _ = Describe("Profile: Reconciler", func() {
 		Expect(err).To(BeNil())
 		// No other ClusterReports are created
 		Expect(len(currentClusterReportList.Items)).To(Equal(1))
+		Expect(currentClusterReportList.Items[0].Spec.ClusterType).To(Equal(libsveltosv1beta1.ClusterTypeCapi))
 	})
 
 	It("updateClusterReports does not create ClusterReport for matching cluster in non dryRun mode", func() {
@@ -887,7 +1119,7 @@ var _ = Describe("Profile: Reconciler", func() {
 
 		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).WithObjects(initObjects...).Build()
 
-		Expect(controllers.CleanClusterReports(context.TODO(), c, clusterProfile)).To(Succeed())
+		Expect(controllers.CleanClusterReports(context.TODO(), c, clusterProfile, false)).To(Succeed())
 		// ClusterReport1 is gone
 		currentClusterReport := &configv1beta1.ClusterReport{}
 		err := c.Get(context.TODO(),
@@ -969,7 +1201,7 @@ var _ = Describe("Profile: Reconciler", func() {
 
 		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).WithObjects(initObjects...).Build()
 
-		Expect(controllers.CleanClusterReports(context.TODO(), c, &profile)).To(Succeed())
+		Expect(controllers.CleanClusterReports(context.TODO(), c, &profile, false)).To(Succeed())
 		// ClusterReport1 is gone
 		currentClusterReport := &configv1beta1.ClusterReport{}
 		err := c.Get(context.TODO(),
@@ -1335,4 +1567,109 @@ var _ = Describe("Profile: Reconciler", func() {
 		Expect(c.List(context.TODO(), clusterSummaries)).To(Succeed())
 		Expect(len(clusterSummaries.Items)).To(Equal(2))
 	})
+
+	It("adoptLegacyClusterSummary adds labels to ClusterSummary created with legacy name", func() {
+		cluster := &corev1.ObjectReference{
+			Namespace: namespace, Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String(),
+		}
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cluster.Namespace,
+				Name: addonclient.GetLegacyClusterSummaryName(configv1beta1.ClusterProfileKind, clusterProfile.Name,
+					cluster.Name, true),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ClusterProfileKind,
+						Name:       clusterProfile.Name,
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: cluster.Namespace,
+				ClusterName:      cluster.Name,
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+			},
+		}
+
+		initObjects := []client.Object{clusterProfile, clusterSummary}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logger,
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+
+		// A ClusterSummary created for another cluster is not adopted
+		adopted, err := controllers.AdoptLegacyClusterSummary(context.TODO(), c, profileScope,
+			&corev1.ObjectReference{Namespace: cluster.Namespace, Name: randomString(),
+				Kind: cluster.Kind, APIVersion: cluster.APIVersion})
+		Expect(err).To(BeNil())
+		Expect(adopted).To(BeFalse())
+
+		adopted, err = controllers.AdoptLegacyClusterSummary(context.TODO(), c, profileScope, cluster)
+		Expect(err).To(BeNil())
+		Expect(adopted).To(BeTrue())
+
+		// ClusterSummary is now found by labels
+		Expect(controllers.UpdateClusterSummary(context.TODO(), c, profileScope, cluster)).To(Succeed())
+		clusterSummaries := &configv1beta1.ClusterSummaryList{}
+		Expect(c.List(context.TODO(), clusterSummaries)).To(Succeed())
+		Expect(len(clusterSummaries.Items)).To(Equal(1))
+		Expect(clusterSummaries.Items[0].Labels).To(HaveKeyWithValue(controllers.ClusterProfileLabelName,
+			clusterProfile.Name))
+		Expect(clusterSummaries.Items[0].Labels).To(HaveKeyWithValue(configv1beta1.ClusterNameLabel, cluster.Name))
+	})
+
+	It("createClusterSummary uses hashed name when name is taken", func() {
+		cluster := &corev1.ObjectReference{
+			Namespace: namespace, Name: "capi-" + randomString(),
+			Kind: clusterKind, APIVersion: clusterv1.GroupVersion.String(),
+		}
+		// ClusterSummary created by ClusterProfile <clusterProfile.Name>-capi for cluster <cluster.Name
+		// without capi- prefix> has same name
+		otherProfileName := clusterProfile.Name + "-capi"
+		otherClusterName := strings.TrimPrefix(cluster.Name, "capi-")
+		name := controllers.GetClusterSummaryName(configv1beta1.ClusterProfileKind, clusterProfile.Name,
+			cluster.Name, false)
+		Expect(controllers.GetClusterSummaryName(configv1beta1.ClusterProfileKind, otherProfileName,
+			otherClusterName, false)).To(Equal(name))
+
+		other := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cluster.Namespace,
+				Name:      name,
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: cluster.Namespace,
+				ClusterName:      otherClusterName,
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+			},
+		}
+
+		initObjects := []client.Object{clusterProfile, other}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logger,
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+
+		Expect(controllers.CreateClusterSummary(context.TODO(), c, profileScope, cluster)).To(Succeed())
+
+		clusterSummary, err := controllers.GetClusterSummary(context.TODO(), c, configv1beta1.ClusterProfileKind,
+			clusterProfile.Name, cluster.Namespace, cluster.Name, libsveltosv1beta1.ClusterTypeCapi)
+		Expect(err).To(BeNil())
+		Expect(clusterSummary.Name).To(Equal(addonclient.GetHashedClusterSummaryName(configv1beta1.ClusterProfileKind,
+			clusterProfile.Name, cluster.Name, false)))
+		Expect(clusterSummary.Spec.ClusterName).To(Equal(cluster.Name))
+	})
 })
@@ -0,0 +1,190 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// Feature hashes include the content of every referenced ConfigMap/Secret. For profiles with many
+// references, those are fetched concurrently by a small pool of workers. The hash of the content is
+// cached per resourceVersion, so a resource which did not change is not hashed again. The cache is
+// bounded, least recently used entries (for instance of resources not referenced anymore) are evicted.
+
+const (
+	// referencedResourcesWorkers is the number of workers fetching referenced ConfigMaps/Secrets
+	referencedResourcesWorkers = 5
+
+	defaultReferencedContentCacheSize = 4096
+)
+
+var (
+	referencedContentCacheSize = defaultReferencedContentCacheSize
+	referencedContentCache     = newLRUCache[*referencedContentCacheEntry](
+		func() int { return referencedContentCacheSize }, nil)
+)
+
+type referencedContentCacheEntry struct {
+	resourceVersion string
+	hash            string
+}
+
+// referencedResource is a ConfigMap/Secret whose content is part of a feature hash
+type referencedResource struct {
+	kind string
+	key  types.NamespacedName
+}
+
+// referencedResourceHash is the hash of a referencedResource content. Err is set if the
+// resource could not be fetched.
+type referencedResourceHash struct {
+	hash string
+	err  error
+}
+
+// referencedContentHasher hashes the content of a ConfigMap/Secret. Name identifies the hasher
+// in the content cache.
+type referencedContentHasher struct {
+	name       string
+	configMap  func(configMap *corev1.ConfigMap) string
+	secret     func(secret *corev1.Secret) string
+	secretType bool // when set, Secrets of a type not supported by Sveltos are rejected
+}
+
+var (
+	// valuesFromContentHasher hashes ConfigMaps/Secrets referenced in ValuesFrom
+	valuesFromContentHasher = &referencedContentHasher{
+		name: "valuesFrom",
+		configMap: func(configMap *corev1.ConfigMap) string {
+			return getDataSectionHash(configMap.Data) + getDataSectionHash(configMap.BinaryData)
+		},
+		secret: func(secret *corev1.Secret) string {
+			return getDataSectionHash(secret.Data) + getDataSectionHash(secret.StringData)
+		},
+		secretType: true,
+	}
+
+	// policyRefContentHasher hashes ConfigMaps/Secrets referenced in PolicyRefs
+	policyRefContentHasher = &referencedContentHasher{
+		name:      "policyRef",
+		configMap: getConfigMapHash,
+		secret:    getSecretHash,
+	}
+)
+
+// getReferencedResourcesHashes fetches the referenced ConfigMaps/Secrets concurrently and returns
+// the hash of their content. Result i corresponds to refs[i].
+func getReferencedResourcesHashes(ctx context.Context, c client.Client, refs []referencedResource,
+	hasher *referencedContentHasher) []referencedResourceHash {
+
+	result := make([]referencedResourceHash, len(refs))
+
+	workers := referencedResourcesWorkers
+	if len(refs) < workers {
+		workers = len(refs)
+	}
+	if workers <= 1 {
+		for i := range refs {
+			result[i].hash, result[i].err = getReferencedResourceHash(ctx, c, &refs[i], hasher)
+		}
+		return result
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				// Each worker writes a different element, no lock needed
+				result[i].hash, result[i].err = getReferencedResourceHash(ctx, c, &refs[i], hasher)
+			}
+		}()
+	}
+
+	for i := range refs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return result
+}
+
+// getReferencedResourceHash returns the hash of a referenced ConfigMap/Secret content. If the resource
+// did not change since last time (same resourceVersion), the cached hash is returned.
+func getReferencedResourceHash(ctx context.Context, c client.Client, ref *referencedResource,
+	hasher *referencedContentHasher) (string, error) {
+
+	var obj client.Object
+	switch ref.kind {
+	case string(libsveltosv1beta1.ConfigMapReferencedResourceKind):
+		obj = &corev1.ConfigMap{}
+	case string(libsveltosv1beta1.SecretReferencedResourceKind):
+		obj = &corev1.Secret{}
+	default:
+		return "", fmt.Errorf("unsupported kind %s", ref.kind)
+	}
+
+	if err := c.Get(ctx, ref.key, obj); err != nil {
+		return "", err
+	}
+
+	if secret, ok := obj.(*corev1.Secret); ok && hasher.secretType && !isSupportedSecretType(secret) {
+
+		return "", libsveltosv1beta1.ErrSecretTypeNotSupported
+	}
+
+	cacheKey := getReferencedContentCacheKey(ref, hasher)
+	resourceVersion := obj.GetResourceVersion()
+	now := time.Now()
+	if resourceVersion != "" {
+		entry, ok := referencedContentCache.get(cacheKey, now)
+		if ok && entry.resourceVersion == resourceVersion {
+			return entry.hash, nil
+		}
+	}
+
+	var hash string
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		hash = hasher.configMap(o)
+	case *corev1.Secret:
+		hash = hasher.secret(o)
+	}
+
+	if resourceVersion != "" {
+		referencedContentCache.add(cacheKey, &referencedContentCacheEntry{resourceVersion: resourceVersion, hash: hash},
+			now)
+	}
+
+	return hash, nil
+}
+
+func getReferencedContentCacheKey(ref *referencedResource, hasher *referencedContentHasher) string {
+	return fmt.Sprintf("%s|%s|%s", hasher.name, ref.kind, ref.key.String())
+}
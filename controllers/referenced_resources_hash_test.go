@@ -0,0 +1,176 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Referenced resources hash", func() {
+	var namespace string
+
+	BeforeEach(func() {
+		namespace = randomString()
+	})
+
+	It("getReferencedResourcesHashes returns hashes in references order", func() {
+		const count = 20
+
+		objects := make([]client.Object, 0, count)
+		refs := make([]controllers.ReferencedResource, 0, count+1)
+		for i := 0; i < count; i++ {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+				Data:       map[string]string{"index": strconv.Itoa(i)},
+			}
+			objects = append(objects, configMap)
+			refs = append(refs, controllers.NewReferencedResource(
+				string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+				types.NamespacedName{Namespace: namespace, Name: configMap.Name}))
+		}
+		refs = append(refs, controllers.NewReferencedResource(
+			string(libsveltosv1beta1.SecretReferencedResourceKind),
+			types.NamespacedName{Namespace: namespace, Name: randomString()}))
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+		hashes, errs := controllers.GetReferencedResourcesHashes(context.TODO(), c, refs,
+			controllers.ValuesFromContentHasher)
+		Expect(len(hashes)).To(Equal(count + 1))
+		for i := 0; i < count; i++ {
+			Expect(errs[i]).To(BeNil())
+			Expect(hashes[i]).To(Equal(strconv.Quote(strconv.Itoa(i))))
+		}
+		Expect(apierrors.IsNotFound(errs[count])).To(BeTrue())
+	})
+
+	It("getReferencedResourcesHashes detects content changes", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Type:       libsveltosv1beta1.ClusterProfileSecretType,
+			Data:       map[string][]byte{"key": []byte("value")},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+		refs := []controllers.ReferencedResource{
+			controllers.NewReferencedResource(string(libsveltosv1beta1.SecretReferencedResourceKind),
+				types.NamespacedName{Namespace: namespace, Name: secret.Name}),
+		}
+
+		hashes, errs := controllers.GetReferencedResourcesHashes(context.TODO(), c, refs,
+			controllers.PolicyRefContentHasher)
+		Expect(errs[0]).To(BeNil())
+		previous := hashes[0]
+
+		// Same resourceVersion, cached hash is returned
+		hashes, errs = controllers.GetReferencedResourcesHashes(context.TODO(), c, refs,
+			controllers.PolicyRefContentHasher)
+		Expect(errs[0]).To(BeNil())
+		Expect(hashes[0]).To(Equal(previous))
+
+		currentSecret := &corev1.Secret{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: secret.Name},
+			currentSecret)).To(Succeed())
+		currentSecret.Data["key"] = []byte("new-value")
+		Expect(c.Update(context.TODO(), currentSecret)).To(Succeed())
+
+		hashes, errs = controllers.GetReferencedResourcesHashes(context.TODO(), c, refs,
+			controllers.PolicyRefContentHasher)
+		Expect(errs[0]).To(BeNil())
+		Expect(hashes[0]).ToNot(Equal(previous))
+	})
+
+	It("getReferencedResourcesHashes rejects Secrets of the wrong type for ValuesFrom", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Data:       map[string][]byte{"key": []byte("value")},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+		refs := []controllers.ReferencedResource{
+			controllers.NewReferencedResource(string(libsveltosv1beta1.SecretReferencedResourceKind),
+				types.NamespacedName{Namespace: namespace, Name: secret.Name}),
+		}
+
+		_, errs := controllers.GetReferencedResourcesHashes(context.TODO(), c, refs,
+			controllers.ValuesFromContentHasher)
+		Expect(errs[0]).To(MatchError(libsveltosv1beta1.ErrSecretTypeNotSupported))
+
+		_, errs = controllers.GetReferencedResourcesHashes(context.TODO(), c, refs,
+			controllers.PolicyRefContentHasher)
+		Expect(errs[0]).To(BeNil())
+	})
+
+	It("getReferencedResourcesHashes evicts least recently used hashes", func() {
+		const size = 3
+		controllers.SetReferencedContentCacheSize(size)
+		defer controllers.SetReferencedContentCacheSize(controllers.DefaultReferencedContentCacheSize)
+
+		objects := make([]client.Object, 0, size+2)
+		refs := make([]controllers.ReferencedResource, 0, size+2)
+		for i := 0; i < size+2; i++ {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+				Data:       map[string]string{"index": strconv.Itoa(i)},
+			}
+			objects = append(objects, configMap)
+			refs = append(refs, controllers.NewReferencedResource(
+				string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+				types.NamespacedName{Namespace: namespace, Name: configMap.Name}))
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+		// Resources referenced by profiles do not grow the cache past its size
+		for i := range refs {
+			_, errs := controllers.GetReferencedResourcesHashes(context.TODO(), c, refs[i:i+1],
+				controllers.PolicyRefContentHasher)
+			Expect(errs[0]).To(BeNil())
+			Expect(controllers.GetReferencedContentCacheLen()).To(Equal(min(i+1, size)))
+		}
+
+		// Oldest ones were evicted
+		for i := range refs {
+			Expect(controllers.IsReferencedContentCached(refs[i], controllers.PolicyRefContentHasher)).To(Equal(i >= 2))
+		}
+
+		// Using an entry makes it the most recently used one
+		_, errs := controllers.GetReferencedResourcesHashes(context.TODO(), c, refs[2:3],
+			controllers.PolicyRefContentHasher)
+		Expect(errs[0]).To(BeNil())
+		_, errs = controllers.GetReferencedResourcesHashes(context.TODO(), c, refs[:1],
+			controllers.PolicyRefContentHasher)
+		Expect(errs[0]).To(BeNil())
+		Expect(controllers.IsReferencedContentCached(refs[0], controllers.PolicyRefContentHasher)).To(BeTrue())
+		Expect(controllers.IsReferencedContentCached(refs[2], controllers.PolicyRefContentHasher)).To(BeTrue())
+		Expect(controllers.IsReferencedContentCached(refs[3], controllers.PolicyRefContentHasher)).To(BeFalse())
+		Expect(controllers.GetReferencedContentCacheLen()).To(Equal(size))
+	})
+})
@@ -0,0 +1,258 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/postrender"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	uyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/patcher"
+)
+
+// Registry mirrors allow the same profile to be deployed in air-gapped clusters. Before resources
+// (and Helm chart rendered manifests) are applied, the registry of every container image is rewritten
+// to the corresponding mirror, if any. Mirrors are taken from:
+// - ClusterProfile/Profile Spec.RegistryMirrors;
+// - configv1beta1.RegistryMirrorsAnnotation on the Cluster. This takes precedence.
+
+const (
+	defaultImageRegistry = "docker.io"
+)
+
+// Containers are looked for in any field with one of those names, so images are rewritten in
+// Pods, workloads (Deployments, CronJobs, ...) and any custom resource embedding a PodSpec.
+var containerFields = []string{"containers", "initContainers", "ephemeralContainers"}
+
+// normalizeRegistry returns the canonical name of a registry, so that for instance
+// index.docker.io and docker.io are considered the same registry
+func normalizeRegistry(registry string) string {
+	registry = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(registry), "/"))
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io":
+		return defaultImageRegistry
+	}
+	return registry
+}
+
+// getRegistryMirrors returns, for the cluster matching clusterSummary, the mirror of each registry,
+// indexed by normalized registry name. Mirrors set on the Cluster via RegistryMirrorsAnnotation take
+// precedence over the ones in the ClusterProfile/Profile.
+func getRegistryMirrors(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+) (map[string]string, error) {
+
+	mirrors := make(map[string]string)
+	for i := range clusterSummary.Spec.ClusterProfileSpec.RegistryMirrors {
+		mirror := &clusterSummary.Spec.ClusterProfileSpec.RegistryMirrors[i]
+		mirrors[normalizeRegistry(mirror.Registry)] = strings.TrimSuffix(mirror.Mirror, "/")
+	}
+
+	value, err := getClusterRegistryMirrorsAnnotation(ctx, c, clusterSummary)
+	if err != nil || value == "" {
+		return mirrors, err
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		registry, mirror, found := strings.Cut(entry, "=")
+		registry = strings.TrimSpace(registry)
+		mirror = strings.TrimSuffix(strings.TrimSpace(mirror), "/")
+		if !found || registry == "" || mirror == "" {
+			return nil, &NonRetriableError{
+				Message: fmt.Sprintf("cluster %s/%s annotation %s: invalid entry %q. Expected <registry>=<mirror>",
+					clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+					configv1beta1.RegistryMirrorsAnnotation, entry),
+			}
+		}
+		mirrors[normalizeRegistry(registry)] = mirror
+	}
+
+	return mirrors, nil
+}
+
+// getClusterRegistryMirrorsAnnotation returns the value of RegistryMirrorsAnnotation on the cluster
+// matching clusterSummary
+func getClusterRegistryMirrorsAnnotation(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary) (string, error) {
+
+	if configv1beta1.IsManagementCluster(clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType) {
+		return "", nil
+	}
+
+	cluster, err := getCluster(ctx, c, clusterSummary.Spec.ClusterNamespace, clusterSummary.Spec.ClusterName,
+		clusterSummary.Spec.ClusterType)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return cluster.GetAnnotations()[configv1beta1.RegistryMirrorsAnnotation], nil
+}
+
+// rewriteImage returns image with its registry replaced by the corresponding mirror. Image is
+// returned unchanged if no mirror is defined for its registry.
+func rewriteImage(image string, mirrors map[string]string) string {
+	registry := defaultImageRegistry
+	repository := image
+
+	// First path component is a registry only if it looks like a host
+	if first, rest, found := strings.Cut(image, "/"); found &&
+		(strings.ContainsAny(first, ".:") || first == "localhost") {
+
+		registry = normalizeRegistry(first)
+		repository = rest
+	}
+
+	mirror, ok := mirrors[registry]
+	if !ok {
+		return image
+	}
+
+	// Official images on docker.io live in the library repository
+	if registry == defaultImageRegistry && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	return mirror + "/" + repository
+}
+
+// rewriteImageRegistries rewrites, in place, the image of all containers in object
+func rewriteImageRegistries(object interface{}, mirrors map[string]string) {
+	switch v := object.(type) {
+	case map[string]interface{}:
+		for _, field := range containerFields {
+			containers, ok := v[field].([]interface{})
+			if !ok {
+				continue
+			}
+			for i := range containers {
+				container, ok := containers[i].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if image, ok := container["image"].(string); ok && image != "" {
+					container["image"] = rewriteImage(image, mirrors)
+				}
+			}
+		}
+		for k := range v {
+			rewriteImageRegistries(v[k], mirrors)
+		}
+	case []interface{}:
+		for i := range v {
+			rewriteImageRegistries(v[i], mirrors)
+		}
+	}
+}
+
+// rewriteUnstructuredImageRegistries rewrites the registry of all container images in objects
+func rewriteUnstructuredImageRegistries(objects []*unstructured.Unstructured, mirrors map[string]string) {
+	if len(mirrors) == 0 {
+		return
+	}
+
+	for i := range objects {
+		rewriteImageRegistries(objects[i].Object, mirrors)
+	}
+}
+
+// registryMirrorPostRenderer is a Helm post renderer rewriting the registry of all container images
+// in the rendered manifests
+type registryMirrorPostRenderer struct {
+	mirrors map[string]string
+}
+
+func (r *registryMirrorPostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	modifiedManifests := &bytes.Buffer{}
+
+	decoder := uyaml.NewYAMLOrJSONDecoder(renderedManifests, 4096)
+	for {
+		object := map[string]interface{}{}
+		if err := decoder.Decode(&object); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if len(object) == 0 {
+			continue
+		}
+
+		rewriteImageRegistries(object, r.mirrors)
+
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		modifiedManifests.WriteString("---\n")
+		modifiedManifests.Write(data)
+	}
+
+	return modifiedManifests, nil
+}
+
+// chainedPostRenderer runs post renderers in sequence
+type chainedPostRenderer []postrender.PostRenderer
+
+func (c chainedPostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	var err error
+	for i := range c {
+		renderedManifests, err = c[i].Run(renderedManifests)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return renderedManifests, nil
+}
+
+// getHelmPostRenderer returns the post renderer applying patches and then registry mirrors.
+// Nil is returned if there is nothing to post render.
+func getHelmPostRenderer(patches []libsveltosv1beta1.Patch, mirrors map[string]string) postrender.PostRenderer {
+	postRenderers := chainedPostRenderer{}
+	if len(patches) > 0 {
+		postRenderers = append(postRenderers, &patcher.CustomPatchPostRenderer{Patches: patches})
+	}
+	if len(mirrors) > 0 {
+		postRenderers = append(postRenderers, &registryMirrorPostRenderer{mirrors: mirrors})
+	}
+
+	switch len(postRenderers) {
+	case 0:
+		return nil
+	case 1:
+		return postRenderers[0]
+	default:
+		return postRenderers
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/utils"
+)
+
+const (
+	deploymentWithImages = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: default
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.36
+      containers:
+      - name: nginx
+        image: ghcr.io/nginx/nginx:1.25
+      - name: sidecar
+        image: quay.io/prometheus/node-exporter:v1.8.0`
+)
+
+var _ = Describe("Registry mirrors", func() {
+	mirrors := map[string]string{
+		"docker.io":           "mirror.example.com/dockerhub",
+		"ghcr.io":             "mirror.example.com/ghcr",
+		"localhost:5000":      "mirror.example.com/local",
+		"registry.k8s.io":     "mirror.example.com/k8s",
+		"not-used.example.io": "mirror.example.com/not-used",
+	}
+
+	It("rewriteImage replaces registry with mirror", func() {
+		Expect(controllers.RewriteImage("nginx", mirrors)).To(Equal("mirror.example.com/dockerhub/library/nginx"))
+		Expect(controllers.RewriteImage("nginx@sha256:abcd", mirrors)).To(
+			Equal("mirror.example.com/dockerhub/library/nginx@sha256:abcd"))
+		Expect(controllers.RewriteImage("bitnami/redis:7.2", mirrors)).To(
+			Equal("mirror.example.com/dockerhub/bitnami/redis:7.2"))
+		Expect(controllers.RewriteImage("index.docker.io/library/nginx:1.25", mirrors)).To(
+			Equal("mirror.example.com/dockerhub/library/nginx:1.25"))
+		Expect(controllers.RewriteImage("ghcr.io/org/app:v1", mirrors)).To(Equal("mirror.example.com/ghcr/org/app:v1"))
+		Expect(controllers.RewriteImage("localhost:5000/app", mirrors)).To(Equal("mirror.example.com/local/app"))
+
+		// No mirror for quay.io
+		Expect(controllers.RewriteImage("quay.io/org/app:v1", mirrors)).To(Equal("quay.io/org/app:v1"))
+	})
+
+	It("rewriteUnstructuredImageRegistries rewrites images of all containers", func() {
+		deployment, err := utils.GetUnstructured([]byte(deploymentWithImages))
+		Expect(err).To(BeNil())
+
+		controllers.RewriteUnstructuredImageRegistries([]*unstructured.Unstructured{deployment}, mirrors)
+
+		initContainers, found, err := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "initContainers")
+		Expect(err).To(BeNil())
+		Expect(found).To(BeTrue())
+		Expect(initContainers[0].(map[string]interface{})["image"]).To(Equal("mirror.example.com/dockerhub/library/busybox:1.36"))
+
+		containers, found, err := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+		Expect(err).To(BeNil())
+		Expect(found).To(BeTrue())
+		Expect(containers[0].(map[string]interface{})["image"]).To(Equal("mirror.example.com/ghcr/nginx/nginx:1.25"))
+		Expect(containers[1].(map[string]interface{})["image"]).To(Equal("quay.io/prometheus/node-exporter:v1.8.0"))
+	})
+
+	It("getRegistryMirrors merges profile mirrors with the ones set on the cluster", func() {
+		cluster := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+				Annotations: map[string]string{
+					configv1beta1.RegistryMirrorsAnnotation: "docker.io=cluster-mirror.example.com/dockerhub/, quay.io=cluster-mirror.example.com/quay",
+				},
+			},
+		}
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cluster.Namespace,
+				Name:      randomString(),
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: cluster.Namespace,
+				ClusterName:      cluster.Name,
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+				ClusterProfileSpec: configv1beta1.Spec{
+					RegistryMirrors: []configv1beta1.RegistryMirror{
+						{Registry: "index.docker.io", Mirror: "mirror.example.com/dockerhub"},
+						{Registry: "ghcr.io", Mirror: "mirror.example.com/ghcr"},
+					},
+				},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster).Build()
+		registryMirrors, err := controllers.GetRegistryMirrors(context.TODO(), c, clusterSummary)
+		Expect(err).To(BeNil())
+		Expect(registryMirrors).To(Equal(map[string]string{
+			"docker.io": "cluster-mirror.example.com/dockerhub",
+			"ghcr.io":   "mirror.example.com/ghcr",
+			"quay.io":   "cluster-mirror.example.com/quay",
+		}))
+
+		cluster.Annotations = map[string]string{configv1beta1.RegistryMirrorsAnnotation: "docker.io"}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster).Build()
+		_, err = controllers.GetRegistryMirrors(context.TODO(), c, clusterSummary)
+		Expect(err).ToNot(BeNil())
+		var nonRetriableError *controllers.NonRetriableError
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+	})
+
+	It("getHelmPostRenderer rewrites images in rendered manifests", func() {
+		Expect(controllers.GetHelmPostRenderer(nil, nil)).To(BeNil())
+
+		postRenderer := controllers.GetHelmPostRenderer(nil, mirrors)
+		Expect(postRenderer).ToNot(BeNil())
+
+		manifests, err := postRenderer.Run(bytes.NewBufferString("---\n" + deploymentWithImages + "\n---\n"))
+		Expect(err).To(BeNil())
+		Expect(manifests.String()).To(ContainSubstring("image: mirror.example.com/ghcr/nginx/nginx:1.25"))
+		Expect(manifests.String()).To(ContainSubstring("image: mirror.example.com/dockerhub/library/busybox:1.36"))
+	})
+})
@@ -32,7 +32,6 @@ import (
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
-	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 )
 
@@ -204,7 +203,7 @@ func updateReloaderWithDeployedResources(ctx context.Context, c client.Client,
 
 	// Ignore admin. Deploying Reloaders must be done as Sveltos.
 	// There is no need to ask tenant to be granted Reloader permissions
-	remoteClient, err := clusterproxy.GetKubernetesClient(ctx, c, clusterSummary.Spec.ClusterNamespace,
+	remoteClient, err := getKubernetesClient(ctx, c, clusterSummary.Spec.ClusterNamespace,
 		clusterSummary.Spec.ClusterName, "", "", clusterSummary.Spec.ClusterType, logger)
 	if err != nil {
 		return err
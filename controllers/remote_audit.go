@@ -0,0 +1,86 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Changes made by addon-controller in managed clusters are attributed to it:
+// - every API call uses a distinctive User-Agent, so managed cluster audit logs identify this controller;
+// - every deployed resource carries annotations with the profile, its generation and the controller
+// version that last applied it.
+
+const (
+	remoteUserAgentName = "sveltos-addon-controller"
+
+	// appliedByAnnotation contains the controller (and its version) which last applied the resource
+	appliedByAnnotation = "projectsveltos.io/applied-by"
+
+	// appliedProfileAnnotation contains the ClusterProfile/Profile which last applied the resource
+	appliedProfileAnnotation = "projectsveltos.io/applied-profile"
+
+	// appliedProfileGenerationAnnotation contains the generation of the ClusterProfile/Profile
+	// when the resource was last applied
+	appliedProfileGenerationAnnotation = "projectsveltos.io/applied-profile-generation"
+)
+
+// getRemoteUserAgent returns the User-Agent used for all API calls towards managed clusters
+func getRemoteUserAgent() string {
+	v := getVersion()
+	if v == "" {
+		v = "unknown"
+	}
+	return fmt.Sprintf("%s/%s", remoteUserAgentName, v)
+}
+
+// setRemoteUserAgent sets the addon-controller User-Agent on restConfig
+func setRemoteUserAgent(restConfig *rest.Config) *rest.Config {
+	if restConfig != nil {
+		restConfig.UserAgent = getRemoteUserAgent()
+	}
+	return restConfig
+}
+
+// getAppliedProfile returns the value of the appliedProfileAnnotation for profile
+func getAppliedProfile(profile client.Object) string {
+	kind := profile.GetObjectKind().GroupVersionKind().Kind
+	if profile.GetNamespace() == "" {
+		return fmt.Sprintf("%s/%s", kind, profile.GetName())
+	}
+	return fmt.Sprintf("%s/%s/%s", kind, profile.GetNamespace(), profile.GetName())
+}
+
+// addAuditAnnotations adds to policy the annotations attributing the change to addon-controller
+// and to the profile applying it. appliedProfile is the value returned by getAppliedProfile.
+func addAuditAnnotations(policy client.Object, appliedProfile string, profileGeneration int64) {
+	addAnnotation(policy, appliedByAnnotation, getRemoteUserAgent())
+	addAnnotation(policy, appliedProfileAnnotation, appliedProfile)
+	addAnnotation(policy, appliedProfileGenerationAnnotation, fmt.Sprintf("%d", profileGeneration))
+}
+
+// removeAuditAnnotations removes annotations added by addAuditAnnotations
+func removeAuditAnnotations(policy client.Object) {
+	annotations := policy.GetAnnotations()
+	delete(annotations, appliedByAnnotation)
+	delete(annotations, appliedProfileAnnotation)
+	delete(annotations, appliedProfileGenerationAnnotation)
+	policy.SetAnnotations(annotations)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Remote audit", func() {
+	It("setRemoteUserAgent sets addon-controller User-Agent", func() {
+		restConfig := controllers.SetRemoteUserAgent(&rest.Config{UserAgent: randomString()})
+		Expect(restConfig.UserAgent).To(Equal(controllers.GetRemoteUserAgent()))
+		Expect(restConfig.UserAgent).To(HavePrefix("sveltos-addon-controller/"))
+
+		Expect(controllers.SetRemoteUserAgent(nil)).To(BeNil())
+	})
+
+	It("getAppliedProfile identifies ClusterProfiles and Profiles", func() {
+		clusterProfile := &configv1beta1.ClusterProfile{
+			TypeMeta:   metav1.TypeMeta{Kind: configv1beta1.ClusterProfileKind},
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+		}
+		Expect(controllers.GetAppliedProfile(clusterProfile)).To(
+			Equal(configv1beta1.ClusterProfileKind + "/" + clusterProfile.Name))
+
+		profile := &configv1beta1.Profile{
+			TypeMeta:   metav1.TypeMeta{Kind: configv1beta1.ProfileKind},
+			ObjectMeta: metav1.ObjectMeta{Namespace: randomString(), Name: randomString()},
+		}
+		Expect(controllers.GetAppliedProfile(profile)).To(
+			Equal(configv1beta1.ProfileKind + "/" + profile.Namespace + "/" + profile.Name))
+	})
+
+	It("addAuditAnnotations and removeAuditAnnotations manage audit annotations", func() {
+		randomKey := randomString()
+		randomValue := randomString()
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   randomString(),
+				Name:        randomString(),
+				Annotations: map[string]string{randomKey: randomValue},
+			},
+		}
+
+		appliedProfile := configv1beta1.ClusterProfileKind + "/" + randomString()
+		controllers.AddAuditAnnotations(cm, appliedProfile, 3)
+		Expect(cm.Annotations).To(HaveKeyWithValue("projectsveltos.io/applied-by", controllers.GetRemoteUserAgent()))
+		Expect(cm.Annotations).To(HaveKeyWithValue("projectsveltos.io/applied-profile", appliedProfile))
+		Expect(cm.Annotations).To(HaveKeyWithValue("projectsveltos.io/applied-profile-generation", "3"))
+
+		controllers.RemoveAuditAnnotations(cm)
+		Expect(cm.Annotations).To(Equal(map[string]string{randomKey: randomValue}))
+	})
+})
@@ -0,0 +1,400 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// Building what is needed to access a managed cluster (reading and parsing the kubeconfig Secret,
+// running API discovery for a RESTMapper, creating HTTP transports) for every operation is
+// expensive at fleet scale. Two size-bounded LRU caches, whose entries expire after a TTL, avoid it:
+// - rest configs built from kubeconfig Secrets, keyed by cluster and admin;
+// - HTTP client, RESTMapper, dynamic client and client, keyed by the identity a rest config
+// authenticates as (API server, credentials and impersonated user).
+// Entries of a cluster are dropped when its kubeconfig Secret changes. Rest configs are always
+// returned as copies, so callers can modify them (for instance to impersonate a tenant).
+// The RESTMapper reloads API discovery when it does not know a kind, so CRDs installed after
+// the entry was created are found.
+
+const (
+	defaultRemoteClientCacheSize = 256
+	defaultRemoteClientCacheTTL  = 10 * time.Minute
+)
+
+// RemoteClientCacheOptions configures the cache of clients to access managed clusters
+type RemoteClientCacheOptions struct {
+	// Size is the maximum number of entries. Least recently used entries are evicted first.
+	// Zero means default (256). A negative value disables the cache.
+	Size int
+
+	// TTL is how long an entry is used before being rebuilt. Zero means default (10m).
+	TTL time.Duration
+}
+
+var (
+	remoteClientCacheOptions = RemoteClientCacheOptions{}
+
+	// remoteRestConfigs contains rest configs built from kubeconfig Secrets.
+	// Key: getConnectionProbeClusterKey + admin
+	remoteRestConfigs = newLRUCache[*rest.Config](getRemoteClientCacheSize, getRemoteClientCacheTTL)
+
+	// remoteClientSets contains clients to access clusters. Key: host|identity hash
+	remoteClientSets = newLRUCache[*remoteClientSet](getRemoteClientCacheSize, getRemoteClientCacheTTL)
+)
+
+// SetRemoteClientCacheOptions sets the options of the cache of clients to access managed clusters.
+// Nil resets to defaults. Cached entries are dropped.
+func SetRemoteClientCacheOptions(options *RemoteClientCacheOptions) error {
+	if options == nil {
+		remoteClientCacheOptions = RemoteClientCacheOptions{}
+	} else {
+		if options.TTL < 0 {
+			return fmt.Errorf("invalid remote client cache TTL %s", options.TTL)
+		}
+		remoteClientCacheOptions = *options
+	}
+
+	remoteRestConfigs.reset()
+	remoteClientSets.reset()
+	return nil
+}
+
+func getRemoteClientCacheSize() int {
+	if remoteClientCacheOptions.Size == 0 {
+		return defaultRemoteClientCacheSize
+	}
+	return remoteClientCacheOptions.Size
+}
+
+func getRemoteClientCacheTTL() time.Duration {
+	if remoteClientCacheOptions.TTL == 0 {
+		return defaultRemoteClientCacheTTL
+	}
+	return remoteClientCacheOptions.TTL
+}
+
+// remoteClientSet contains what is needed to access a cluster with a given identity
+type remoteClientSet struct {
+	mapper  meta.RESTMapper
+	dynamic dynamic.Interface
+
+	httpClient *http.Client
+	config     *rest.Config
+
+	mux     sync.Mutex
+	clients map[*runtime.Scheme]client.Client
+}
+
+func newRemoteClientSet(config *rest.Config) (*remoteClientSet, error) {
+	config = rest.CopyConfig(config)
+
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dc, err := discovery.NewDiscoveryClientForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := dynamic.NewForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteClientSet{
+		mapper:     &reloadingRESTMapper{restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc))},
+		dynamic:    d,
+		httpClient: httpClient,
+		config:     config,
+		clients:    map[*runtime.Scheme]client.Client{},
+	}, nil
+}
+
+// getClient returns a client using scheme
+func (s *remoteClientSet) getClient(scheme *runtime.Scheme) (client.Client, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if c, ok := s.clients[scheme]; ok {
+		return c, nil
+	}
+
+	c, err := client.New(s.config, client.Options{Scheme: scheme, Mapper: s.mapper, HTTPClient: s.httpClient})
+	if err != nil {
+		return nil, err
+	}
+	s.clients[scheme] = c
+	return c, nil
+}
+
+// getRemoteClientSet returns, from the cache when possible, the clients to access the cluster
+// with the identity of config
+func getRemoteClientSet(config *rest.Config) (*remoteClientSet, error) {
+	key, ok := getRestConfigIdentity(config)
+	if !ok || getRemoteClientCacheSize() < 0 {
+		return newRemoteClientSet(config)
+	}
+
+	now := time.Now()
+	if clientSet, ok := remoteClientSets.get(key, now); ok {
+		return clientSet, nil
+	}
+
+	clientSet, err := newRemoteClientSet(config)
+	if err != nil {
+		return nil, err
+	}
+	remoteClientSets.add(key, clientSet, now)
+	return clientSet, nil
+}
+
+// getRemoteRESTMapperAndDynamicClient returns, from the cache when possible, a RESTMapper and a
+// dynamic client for config
+func getRemoteRESTMapperAndDynamicClient(config *rest.Config) (meta.RESTMapper, dynamic.Interface, error) {
+	clientSet, err := getRemoteClientSet(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientSet.mapper, clientSet.dynamic, nil
+}
+
+// getRemoteClient returns, from the cache when possible, a client for config
+func getRemoteClient(config *rest.Config, scheme *runtime.Scheme) (client.Client, error) {
+	clientSet, err := getRemoteClientSet(config)
+	if err != nil {
+		return nil, err
+	}
+	return clientSet.getClient(scheme)
+}
+
+// getCachedRemoteRestConfig returns a copy of the rest config built by build for the cluster and
+// admin. build is invoked only if no valid entry is cached.
+func getCachedRemoteRestConfig(clusterNamespace, clusterName, adminNamespace, adminName string,
+	clusterType libsveltosv1beta1.ClusterType, build func() (*rest.Config, error)) (*rest.Config, error) {
+
+	if getRemoteClientCacheSize() < 0 {
+		return build()
+	}
+
+	key := getConnectionProbeClusterKey(clusterNamespace, clusterName, clusterType) +
+		fmt.Sprintf("%s/%s", adminNamespace, adminName)
+
+	now := time.Now()
+	if config, ok := remoteRestConfigs.get(key, now); ok {
+		return rest.CopyConfig(config), nil
+	}
+
+	config, err := build()
+	if err != nil {
+		return nil, err
+	}
+	remoteRestConfigs.add(key, rest.CopyConfig(config), now)
+	return config, nil
+}
+
+// invalidateRemoteClients drops the rest configs and clients cached for the cluster
+func invalidateRemoteClients(clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType) {
+	prefix := getConnectionProbeClusterKey(clusterNamespace, clusterName, clusterType)
+
+	hosts := map[string]bool{}
+	for _, config := range remoteRestConfigs.removeIf(func(key string) bool { return strings.HasPrefix(key, prefix) }) {
+		hosts[config.Host] = true
+	}
+
+	remoteClientSets.removeIf(func(key string) bool {
+		return hosts[key[:strings.LastIndex(key, "|")]]
+	})
+}
+
+// getRestConfigIdentity returns a key identifying the API server and the identity config
+// authenticates as. False is returned if config cannot be safely shared (custom transport or dialer).
+func getRestConfigIdentity(config *rest.Config) (string, bool) {
+	if config.WrapTransport != nil || config.Dial != nil || config.Transport != nil {
+		return "", false
+	}
+
+	proxyURL := ""
+	if config.Proxy != nil {
+		hostURL, err := url.Parse(config.Host)
+		if err != nil {
+			return "", false
+		}
+		u, err := config.Proxy(&http.Request{URL: hostURL})
+		if err != nil {
+			return "", false
+		}
+		if u != nil {
+			proxyURL = u.String()
+		}
+	}
+
+	fields := []any{config.APIPath, config.Username, config.Password, config.BearerToken, config.BearerTokenFile,
+		config.TLSClientConfig.Insecure, config.TLSClientConfig.ServerName,
+		config.TLSClientConfig.CAFile, config.TLSClientConfig.CertFile, config.TLSClientConfig.KeyFile,
+		config.TLSClientConfig.CAData, config.TLSClientConfig.CertData, config.TLSClientConfig.KeyData,
+		config.Impersonate.UserName, config.Impersonate.UID, config.Impersonate.Groups, config.Impersonate.Extra,
+		proxyURL, config.UserAgent, config.QPS, config.Burst, config.Timeout}
+	if config.ExecProvider != nil {
+		fields = append(fields, *config.ExecProvider)
+	}
+	if config.AuthProvider != nil {
+		fields = append(fields, *config.AuthProvider)
+	}
+
+	h := sha256.New()
+	for i := range fields {
+		fmt.Fprintf(h, "%v|", fields[i])
+	}
+
+	return fmt.Sprintf("%s|%x", config.Host, h.Sum(nil)), true
+}
+
+// reloadingRESTMapper is a RESTMapper caching API discovery and reloading it when a kind is not found
+type reloadingRESTMapper struct {
+	*restmapper.DeferredDiscoveryRESTMapper
+}
+
+func (m *reloadingRESTMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	mapping, err := m.DeferredDiscoveryRESTMapper.RESTMapping(gk, versions...)
+	if meta.IsNoMatchError(err) {
+		m.Reset()
+		return m.DeferredDiscoveryRESTMapper.RESTMapping(gk, versions...)
+	}
+	return mapping, err
+}
+
+func (m *reloadingRESTMapper) RESTMappings(gk schema.GroupKind, versions ...string) ([]*meta.RESTMapping, error) {
+	mappings, err := m.DeferredDiscoveryRESTMapper.RESTMappings(gk, versions...)
+	if meta.IsNoMatchError(err) {
+		m.Reset()
+		return m.DeferredDiscoveryRESTMapper.RESTMappings(gk, versions...)
+	}
+	return mappings, err
+}
+
+type lruEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+// lruCache is a size-bounded cache evicting least recently used entries first. Entries expire
+// ttl after being added. Nil ttl means entries never expire.
+type lruCache[V any] struct {
+	mux     sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+
+	size func() int
+	ttl  func() time.Duration
+}
+
+func newLRUCache[V any](size func() int, ttl func() time.Duration) *lruCache[V] {
+	return &lruCache[V]{entries: map[string]*list.Element{}, order: list.New(), size: size, ttl: ttl}
+}
+
+func (l *lruCache[V]) get(key string, now time.Time) (V, bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	var zero V
+	element, ok := l.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := element.Value.(*lruEntry[V])
+	if !entry.expires.IsZero() && !now.Before(entry.expires) {
+		l.order.Remove(element)
+		delete(l.entries, key)
+		return zero, false
+	}
+	l.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (l *lruCache[V]) add(key string, value V, now time.Time) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if element, ok := l.entries[key]; ok {
+		l.order.Remove(element)
+	}
+	entry := &lruEntry[V]{key: key, value: value}
+	if l.ttl != nil {
+		entry.expires = now.Add(l.ttl())
+	}
+	l.entries[key] = l.order.PushFront(entry)
+
+	for l.order.Len() > l.size() {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+// removeIf removes, and returns, all entries whose key matches
+func (l *lruCache[V]) removeIf(matches func(key string) bool) []V {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	removed := make([]V, 0)
+	for key, element := range l.entries {
+		if matches(key) {
+			removed = append(removed, element.Value.(*lruEntry[V]).value)
+			l.order.Remove(element)
+			delete(l.entries, key)
+		}
+	}
+	return removed
+}
+
+func (l *lruCache[V]) len() int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.order.Len()
+}
+
+func (l *lruCache[V]) reset() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.entries = map[string]*list.Element{}
+	l.order = list.New()
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Remote client cache", func() {
+	var clusterNamespace string
+	var builds int
+
+	build := func() (*rest.Config, error) {
+		builds++
+		return &rest.Config{Host: "https://" + randomString() + ":6443"}, nil
+	}
+
+	BeforeEach(func() {
+		clusterNamespace = randomString()
+		builds = 0
+	})
+
+	AfterEach(func() {
+		Expect(controllers.SetRemoteClientCacheOptions(nil)).To(Succeed())
+	})
+
+	It("getCachedRemoteRestConfig builds rest config only once and returns copies", func() {
+		clusterName := randomString()
+
+		config, err := controllers.GetCachedRemoteRestConfig(clusterNamespace, clusterName, "", "",
+			libsveltosv1beta1.ClusterTypeSveltos, build)
+		Expect(err).To(BeNil())
+		config.Impersonate.UserName = randomString()
+
+		cached, err := controllers.GetCachedRemoteRestConfig(clusterNamespace, clusterName, "", "",
+			libsveltosv1beta1.ClusterTypeSveltos, build)
+		Expect(err).To(BeNil())
+		Expect(builds).To(Equal(1))
+		Expect(cached.Host).To(Equal(config.Host))
+		Expect(cached.Impersonate.UserName).To(BeEmpty())
+
+		// Each admin has its own rest config
+		_, err = controllers.GetCachedRemoteRestConfig(clusterNamespace, clusterName, randomString(), randomString(),
+			libsveltosv1beta1.ClusterTypeSveltos, build)
+		Expect(err).To(BeNil())
+		Expect(builds).To(Equal(2))
+
+		// Once kubeconfig changes, rest config is built again
+		controllers.InvalidateRemoteClients(clusterNamespace, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		_, err = controllers.GetCachedRemoteRestConfig(clusterNamespace, clusterName, "", "",
+			libsveltosv1beta1.ClusterTypeSveltos, build)
+		Expect(err).To(BeNil())
+		Expect(builds).To(Equal(3))
+	})
+
+	It("getCachedRemoteRestConfig evicts least recently used and expired entries", func() {
+		Expect(controllers.SetRemoteClientCacheOptions(&controllers.RemoteClientCacheOptions{Size: 2})).To(Succeed())
+
+		clusterNames := []string{randomString(), randomString(), randomString()}
+		for i := range clusterNames {
+			_, err := controllers.GetCachedRemoteRestConfig(clusterNamespace, clusterNames[i], "", "",
+				libsveltosv1beta1.ClusterTypeCapi, build)
+			Expect(err).To(BeNil())
+		}
+		Expect(builds).To(Equal(3))
+
+		// clusterNames[0] was evicted
+		_, err := controllers.GetCachedRemoteRestConfig(clusterNamespace, clusterNames[2], "", "",
+			libsveltosv1beta1.ClusterTypeCapi, build)
+		Expect(err).To(BeNil())
+		Expect(builds).To(Equal(3))
+		_, err = controllers.GetCachedRemoteRestConfig(clusterNamespace, clusterNames[0], "", "",
+			libsveltosv1beta1.ClusterTypeCapi, build)
+		Expect(err).To(BeNil())
+		Expect(builds).To(Equal(4))
+
+		Expect(controllers.SetRemoteClientCacheOptions(
+			&controllers.RemoteClientCacheOptions{TTL: time.Millisecond})).To(Succeed())
+		_, err = controllers.GetCachedRemoteRestConfig(clusterNamespace, clusterNames[0], "", "",
+			libsveltosv1beta1.ClusterTypeCapi, build)
+		Expect(err).To(BeNil())
+		time.Sleep(2 * time.Millisecond)
+		_, err = controllers.GetCachedRemoteRestConfig(clusterNamespace, clusterNames[0], "", "",
+			libsveltosv1beta1.ClusterTypeCapi, build)
+		Expect(err).To(BeNil())
+		Expect(builds).To(Equal(6))
+	})
+
+	It("getRemoteClient shares clients among rest configs with same identity only", func() {
+		config := &rest.Config{Host: "https://" + randomString() + ":6443", BearerToken: randomString()}
+
+		c, err := controllers.GetRemoteClient(rest.CopyConfig(config), scheme)
+		Expect(err).To(BeNil())
+
+		sameIdentity, err := controllers.GetRemoteClient(rest.CopyConfig(config), scheme)
+		Expect(err).To(BeNil())
+		Expect(sameIdentity).To(BeIdenticalTo(c))
+
+		impersonated := rest.CopyConfig(config)
+		impersonated.Impersonate.UserName = randomString()
+		otherIdentity, err := controllers.GetRemoteClient(impersonated, scheme)
+		Expect(err).To(BeNil())
+		Expect(otherIdentity).ToNot(BeIdenticalTo(c))
+
+		rotated := rest.CopyConfig(config)
+		rotated.BearerToken = randomString()
+		otherIdentity, err = controllers.GetRemoteClient(rotated, scheme)
+		Expect(err).To(BeNil())
+		Expect(otherIdentity).ToNot(BeIdenticalTo(c))
+	})
+})
@@ -0,0 +1,83 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// baseFailureBackoff is the initial delay before a failed request is retried
+	baseFailureBackoff = 5 * time.Millisecond
+
+	// defaultMaxFailureBackoff is the maximum delay before a failed request is retried
+	defaultMaxFailureBackoff = 1000 * time.Second
+
+	// overall retry rate (not per item) and bucket size
+	retryQPS        = 10
+	retryBucketSize = 100
+)
+
+// RequeuePolicy configures how often a reconciler requeues requests.
+// Zero values mean the default is used.
+type RequeuePolicy struct {
+	// NormalRequeueAfter is how long to wait before reconciling again when reconciliation
+	// could not complete (for instance cluster not ready yet or features failed to deploy)
+	NormalRequeueAfter time.Duration
+
+	// DeleteRequeueAfter is how long to wait before reconciling again when deletion
+	// could not complete
+	DeleteRequeueAfter time.Duration
+
+	// MaxFailureBackoff caps the exponential backoff applied to requests failing reconciliation
+	MaxFailureBackoff time.Duration
+}
+
+func (p *RequeuePolicy) normalRequeueAfter() time.Duration {
+	if p.NormalRequeueAfter == 0 {
+		return normalRequeueAfter
+	}
+	return p.NormalRequeueAfter
+}
+
+func (p *RequeuePolicy) deleteRequeueAfter() time.Duration {
+	if p.DeleteRequeueAfter == 0 {
+		return deleteRequeueAfter
+	}
+	return p.DeleteRequeueAfter
+}
+
+// rateLimiter returns the rate limiter to use for the reconciler workqueue. Same as controller-runtime
+// default one but with configurable maximum failure backoff.
+func (p *RequeuePolicy) rateLimiter() workqueue.TypedRateLimiter[reconcile.Request] {
+	maxFailureBackoff := p.MaxFailureBackoff
+	if maxFailureBackoff == 0 {
+		maxFailureBackoff = defaultMaxFailureBackoff
+	}
+	if maxFailureBackoff < baseFailureBackoff {
+		maxFailureBackoff = baseFailureBackoff
+	}
+
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](baseFailureBackoff, maxFailureBackoff),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(retryQPS), retryBucketSize)},
+	)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("RequeuePolicy", func() {
+	It("uses defaults when not set", func() {
+		policy := &controllers.RequeuePolicy{}
+		Expect(controllers.NormalRequeueAfter(policy)).To(Equal(10 * time.Second))
+		Expect(controllers.DeleteRequeueAfter(policy)).To(Equal(10 * time.Second))
+
+		policy = &controllers.RequeuePolicy{
+			NormalRequeueAfter: time.Minute,
+			DeleteRequeueAfter: 30 * time.Second,
+		}
+		Expect(controllers.NormalRequeueAfter(policy)).To(Equal(time.Minute))
+		Expect(controllers.DeleteRequeueAfter(policy)).To(Equal(30 * time.Second))
+	})
+
+	It("rateLimiter caps failure backoff", func() {
+		policy := &controllers.RequeuePolicy{MaxFailureBackoff: time.Second}
+		rateLimiter := controllers.RateLimiter(policy)
+
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: randomString()}}
+		var delay time.Duration
+		const failures = 20
+		for i := 0; i < failures; i++ {
+			delay = rateLimiter.When(request)
+		}
+		Expect(delay).To(Equal(time.Second))
+
+		rateLimiter.Forget(request)
+		Expect(rateLimiter.NumRequeues(request)).To(BeZero())
+	})
+})
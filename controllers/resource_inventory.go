@@ -0,0 +1,240 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Resources deployed because of the Resources and Kustomize features are recorded, per feature,
+// in the ClusterSummary Status (inventory). When a resource is not deployed anymore (for instance
+// a manifest was removed from a referenced ConfigMap) it is pruned using the inventory.
+// Until an inventory is recorded (for instance ClusterSummaries created by previous versions) all
+// resources of any GroupVersionKind ever deployed are listed instead.
+
+// getInventoryEntry returns the inventory entry for a resource, in the form
+// kind.version.group:namespace:name
+func getInventoryEntry(resource *configv1beta1.Resource) string {
+	return fmt.Sprintf("%s.%s.%s:%s:%s", resource.Kind, resource.Version, resource.Group,
+		resource.Namespace, resource.Name)
+}
+
+// parseInventoryEntry parses an entry created by getInventoryEntry
+func parseInventoryEntry(entry string) (*configv1beta1.Resource, error) {
+	const expectedSections = 3
+	sections := strings.Split(entry, ":")
+	if len(sections) != expectedSections {
+		return nil, fmt.Errorf("invalid inventory entry %q", entry)
+	}
+
+	gvk, _ := schema.ParseKindArg(sections[0])
+	if gvk == nil || gvk.Kind == "" || gvk.Version == "" || sections[2] == "" {
+		return nil, fmt.Errorf("invalid inventory entry %q", entry)
+	}
+
+	return &configv1beta1.Resource{
+		Kind:      gvk.Kind,
+		Version:   gvk.Version,
+		Group:     gvk.Group,
+		Namespace: sections[1],
+		Name:      sections[2],
+	}, nil
+}
+
+// getResourceInventory returns the inventory of resources deployed, because of featureID, either in the
+// management cluster or in the managed cluster. Nil is returned if no inventory was ever recorded.
+func getResourceInventory(clusterSummary *configv1beta1.ClusterSummary, featureID configv1beta1.FeatureID,
+	isMgmtCluster bool) []string {
+
+	fdi := getFeatureDeploymentInfoForFeatureID(clusterSummary, featureID)
+	if fdi == nil {
+		return nil
+	}
+
+	if isMgmtCluster {
+		return fdi.DeployedManagementClusterResources
+	}
+	return fdi.DeployedResources
+}
+
+func getInventoryEntries(resourceReports []configv1beta1.ResourceReport) []string {
+	entries := make([]string, 0, len(resourceReports))
+	for i := range resourceReports {
+		action := configv1beta1.ResourceAction(resourceReports[i].Action)
+		if action == configv1beta1.DeleteResourceAction || action == configv1beta1.ConflictResourceAction {
+			continue
+		}
+		entries = append(entries, getInventoryEntry(&resourceReports[i].Resource))
+	}
+	return unique(entries)
+}
+
+// updateResourceInventory records in the ClusterSummary Status the resources currently deployed
+// because of featureID. It must be invoked only once stale resources have been pruned.
+// No action in DryRun and AssessOnly modes.
+func updateResourceInventory(ctx context.Context, clusterSummary *configv1beta1.ClusterSummary,
+	featureID configv1beta1.FeatureID, localResourceReports, remoteResourceReports []configv1beta1.ResourceReport,
+	logger logr.Logger) (*configv1beta1.ClusterSummary, error) {
+
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) ||
+		isAssessOnlyMode(clusterSummary) {
+
+		return clusterSummary, nil
+	}
+
+	logger.V(logs.LogDebug).Info("update status with deployed resources inventory")
+	localEntries := getInventoryEntries(localResourceReports)
+	remoteEntries := getInventoryEntries(remoteResourceReports)
+
+	c := getManagementClusterClient()
+
+	currentClusterSummary := &configv1beta1.ClusterSummary{}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := c.Get(ctx,
+			types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name},
+			currentClusterSummary)
+		if err != nil {
+			return err
+		}
+
+		fdi := getFeatureDeploymentInfoForFeatureID(currentClusterSummary, featureID)
+		if fdi == nil {
+			if len(localEntries) == 0 && len(remoteEntries) == 0 {
+				return nil
+			}
+			currentClusterSummary.Status.DeployedGVKs = append(currentClusterSummary.Status.DeployedGVKs,
+				configv1beta1.FeatureDeploymentInfo{FeatureID: featureID})
+			fdi = &currentClusterSummary.Status.DeployedGVKs[len(currentClusterSummary.Status.DeployedGVKs)-1]
+		}
+
+		fdi.DeployedManagementClusterResources = localEntries
+		fdi.DeployedResources = remoteEntries
+
+		return c.Status().Update(ctx, currentClusterSummary)
+	})
+
+	return currentClusterSummary, err
+}
+
+// pruneStaleResources removes resources previously deployed because of featureID and not
+// part of currentPolicies anymore.
+func pruneStaleResources(ctx context.Context, isMgmtCluster bool, destRestConfig *rest.Config,
+	destClient client.Client, featureID configv1beta1.FeatureID, clusterSummary *configv1beta1.ClusterSummary,
+	currentPolicies map[string]configv1beta1.Resource, logger logr.Logger) ([]configv1beta1.ResourceReport, error) {
+
+	inventory := getResourceInventory(clusterSummary, featureID, isMgmtCluster)
+	if inventory == nil {
+		return undeployStaleResources(ctx, isMgmtCluster, destRestConfig, destClient, featureID,
+			clusterSummary, getDeployedGroupVersionKinds(clusterSummary, featureID), currentPolicies, logger)
+	}
+
+	return undeployStaleInventoryResources(ctx, isMgmtCluster, destRestConfig, destClient,
+		clusterSummary, inventory, currentPolicies, logger)
+}
+
+// undeployStaleInventoryResources removes resources in the inventory which are not part of
+// currentPolicies anymore. Only resources still owned by the ClusterSummary's profile are removed.
+func undeployStaleInventoryResources(ctx context.Context, isMgmtCluster bool,
+	destRestConfig *rest.Config, destClient client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	inventory []string, currentPolicies map[string]configv1beta1.Resource, logger logr.Logger,
+) ([]configv1beta1.ResourceReport, error) {
+
+	logger.V(logs.LogDebug).Info("removing stale resources using inventory")
+
+	profile, _, err := configv1beta1.GetProfileOwnerAndTier(ctx, getManagementClusterClient(), clusterSummary)
+	if err != nil {
+		return nil, err
+	}
+	if profile.GetObjectKind().GroupVersionKind().Kind == configv1beta1.ProfileKind {
+		profile.SetName(profileNameToOwnerReferenceName(profile))
+	}
+
+	stale := make([]*configv1beta1.Resource, 0)
+	for i := range inventory {
+		resource, err := parseInventoryEntry(inventory[i])
+		if err != nil {
+			logger.V(logs.LogInfo).Info(err.Error())
+			continue
+		}
+		if _, ok := currentPolicies[getPolicyInfo(resource)]; ok {
+			continue
+		}
+		stale = append(stale, resource)
+	}
+
+	undeployed := make([]configv1beta1.ResourceReport, 0)
+	if len(stale) == 0 {
+		return undeployed, nil
+	}
+
+	mapper, d, err := getRemoteRESTMapperAndDynamicClient(destRestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range stale {
+		gvk := schema.GroupVersionKind{Group: stale[i].Group, Version: stale[i].Version, Kind: stale[i].Kind}
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			// if CRD does not exist anymore, no instance can be left
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		var dr dynamic.ResourceInterface = d.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			dr = d.Resource(mapping.Resource).Namespace(stale[i].Namespace)
+		}
+
+		u, err := dr.Get(ctx, stale[i].Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		rr, err := undeployStaleResource(ctx, isMgmtCluster, destClient, profile, clusterSummary,
+			*u, currentPolicies, logger)
+		if err != nil {
+			return nil, err
+		}
+		if rr != nil {
+			undeployed = append(undeployed, *rr)
+		}
+	}
+
+	return undeployed, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// Resources contained in a referenced ConfigMap/Secret/Flux Source are deployed in this order:
+// Namespaces first, then CustomResourceDefinitions, then everything else. Before deploying the
+// first resource which is neither a Namespace nor a CRD, Sveltos waits for all CRDs just deployed
+// to be Established. So a CRD and instances of it can be contained in the same PolicyRef.
+
+const (
+	crdEstablishedTimeout      = 30 * time.Second
+	crdEstablishedPollInterval = 500 * time.Millisecond
+)
+
+// getDeploymentPriority returns the priority of a resource. Resources with lower priority
+// are deployed first.
+func getDeploymentPriority(resource *unstructured.Unstructured) int {
+	gvk := resource.GroupVersionKind()
+	switch {
+	case gvk.Group == "" && gvk.Kind == "Namespace":
+		return 0
+	case isCRD(resource):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func isCRD(resource *unstructured.Unstructured) bool {
+	gvk := resource.GroupVersionKind()
+	return gvk.Group == apiextensionsv1.GroupName && gvk.Kind == "CustomResourceDefinition"
+}
+
+// sortResourcesForDeployment sorts resources so that Namespaces and CRDs are deployed before
+// any other resource. Order of resources with the same priority is preserved.
+func sortResourcesForDeployment(resources []*unstructured.Unstructured) []*unstructured.Unstructured {
+	sorted := make([]*unstructured.Unstructured, len(resources))
+	copy(sorted, resources)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return getDeploymentPriority(sorted[i]) < getDeploymentPriority(sorted[j])
+	})
+	return sorted
+}
+
+// isCRDEstablished returns true if crd Established condition is true
+func isCRDEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for i := range crd.Status.Conditions {
+		condition := &crd.Status.Conditions[i]
+		if condition.Type == apiextensionsv1.Established {
+			return condition.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// waitForCRDsEstablished waits for all CRDs in crdNames to be Established.
+// An error is returned if that does not happen within timeout.
+func waitForCRDsEstablished(ctx context.Context, c client.Client, crdNames []string,
+	timeout time.Duration, logger logr.Logger) error {
+
+	if len(crdNames) == 0 {
+		return nil
+	}
+
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("waiting for CRDs %s to be established",
+		strings.Join(crdNames, ",")))
+
+	var notEstablished []string
+	err := wait.PollUntilContextTimeout(ctx, crdEstablishedPollInterval, timeout, true,
+		func(ctx context.Context) (bool, error) {
+			notEstablished = make([]string, 0)
+			for i := range crdNames {
+				crd := &apiextensionsv1.CustomResourceDefinition{}
+				err := c.Get(ctx, types.NamespacedName{Name: crdNames[i]}, crd)
+				if err != nil {
+					if apierrors.IsNotFound(err) {
+						notEstablished = append(notEstablished, crdNames[i])
+						continue
+					}
+					return false, err
+				}
+				if !isCRDEstablished(crd) {
+					notEstablished = append(notEstablished, crdNames[i])
+				}
+			}
+			return len(notEstablished) == 0, nil
+		})
+	if err != nil && len(notEstablished) > 0 {
+		return fmt.Errorf("CRDs %s are not established: %w", strings.Join(notEstablished, ","), err)
+	}
+	return err
+}
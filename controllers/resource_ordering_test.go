@@ -0,0 +1,95 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Resource ordering", func() {
+	getResource := func(group, kind, name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(schema.GroupVersionKind{Group: group, Version: "v1", Kind: kind})
+		u.SetName(name)
+		return u
+	}
+
+	getCRD := func(name string, established bool) *apiextensionsv1.CustomResourceDefinition {
+		status := apiextensionsv1.ConditionFalse
+		if established {
+			status = apiextensionsv1.ConditionTrue
+		}
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{
+				Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{
+					{Type: apiextensionsv1.Established, Status: status},
+				},
+			},
+		}
+	}
+
+	It("sortResourcesForDeployment deploys Namespaces and CRDs first preserving order", func() {
+		resources := []*unstructured.Unstructured{
+			getResource("example.com", "Widget", "w1"),
+			getResource("apiextensions.k8s.io", "CustomResourceDefinition", "widgets.example.com"),
+			getResource("", "ConfigMap", "c1"),
+			getResource("", "Namespace", "ns1"),
+			getResource("example.com", "Widget", "w2"),
+			getResource("apiextensions.k8s.io", "CustomResourceDefinition", "gadgets.example.com"),
+		}
+
+		sorted := controllers.SortResourcesForDeployment(resources)
+		names := make([]string, len(sorted))
+		for i := range sorted {
+			names[i] = sorted[i].GetName()
+		}
+		Expect(names).To(Equal([]string{"ns1", "widgets.example.com", "gadgets.example.com", "w1", "c1", "w2"}))
+		// Original slice is not modified
+		Expect(resources[0].GetName()).To(Equal("w1"))
+	})
+
+	It("waitForCRDsEstablished returns an error if CRDs are not established in time", func() {
+		established := getCRD(randomString()+".example.com", true)
+		notEstablished := getCRD(randomString()+".example.com", false)
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(established, notEstablished).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		Expect(controllers.WaitForCRDsEstablished(context.TODO(), c, []string{established.Name},
+			time.Second, logger)).To(Succeed())
+
+		err := controllers.WaitForCRDsEstablished(context.TODO(), c, []string{established.Name, notEstablished.Name},
+			time.Second, logger)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring(notEstablished.Name))
+		Expect(err.Error()).ToNot(ContainSubstring(established.Name))
+	})
+})
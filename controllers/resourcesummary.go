@@ -25,6 +25,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -34,7 +35,6 @@ import (
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	driftdetection "github.com/projectsveltos/addon-controller/pkg/drift-detection"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
-	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	"github.com/projectsveltos/libsveltos/lib/crd"
 	"github.com/projectsveltos/libsveltos/lib/logsettings"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
@@ -44,6 +44,9 @@ import (
 
 const (
 	projectsveltos = "projectsveltos"
+
+	// driftDetectionManagerContainerName is the name of the drift-detection-manager container
+	driftDetectionManagerContainerName = "manager"
 )
 
 func getResourceSummaryNamespace() string {
@@ -60,7 +63,7 @@ func getResourceSummaryName(clusterSummaryNamespace, clusterSummaryName string)
 
 func deployDriftDetectionManagerInCluster(ctx context.Context, c client.Client,
 	clusterNamespace, clusterName, applicant string, clusterType libsveltosv1beta1.ClusterType,
-	startInMgmtCluster bool, logger logr.Logger) error {
+	startInMgmtCluster bool, resources *corev1.ResourceRequirements, logger logr.Logger) error {
 
 	logger = logger.WithValues("clustersummary", applicant)
 	logger = logger.WithValues("cluster", fmt.Sprintf("%s:%s/%s", clusterType, clusterNamespace, clusterName))
@@ -72,7 +75,7 @@ func deployDriftDetectionManagerInCluster(ctx context.Context, c client.Client,
 	}
 
 	// Sveltos resources are deployed using cluster-admin role.
-	remoteRestConfig, err := clusterproxy.GetKubernetesRestConfig(ctx, c, clusterNamespace,
+	remoteRestConfig, err := getKubernetesRestConfig(ctx, c, clusterNamespace,
 		clusterName, "", "", clusterType, logger)
 	if err != nil {
 		logger.V(logs.LogInfo).Error(err, "failed to get cluster rest config")
@@ -85,30 +88,39 @@ func deployDriftDetectionManagerInCluster(ctx context.Context, c client.Client,
 	}
 
 	logger.V(logs.LogDebug).Info("Deploying drift-detection-manager")
-	// Deploy DriftDetectionManager
-	if startInMgmtCluster {
+	// Deploy DriftDetectionManager. When the management cluster itself is the managed cluster,
+	// drift-detection-manager is deployed as in any managed cluster, using the in-cluster config.
+	if startInMgmtCluster && !configv1beta1.IsManagementCluster(clusterName, clusterType) {
 		restConfig := getManagementClusterConfig()
 		return deployDriftDetectionManagerInManagementCluster(ctx, restConfig, clusterNamespace,
-			clusterName, "do-not-send-reports", clusterType, patches, logger)
+			clusterName, "do-not-send-reports", clusterType, patches, resources, logger)
 	}
 
 	return deployDriftDetectionManager(ctx, remoteRestConfig, clusterNamespace,
-		clusterName, "do-not-send-reports", clusterType, patches, logger)
+		clusterName, "do-not-send-reports", clusterType, patches, resources, logger)
 }
 
 func deployResourceSummaryInCluster(ctx context.Context, c client.Client,
 	clusterNamespace, clusterName, applicant string, clusterType libsveltosv1beta1.ClusterType,
 	resources []libsveltosv1beta1.Resource, kustomizeResources []libsveltosv1beta1.Resource,
 	helmResources []libsveltosv1beta1.HelmResources, driftExclusions []configv1beta1.DriftExclusion,
-	logger logr.Logger) error {
+	driftExcludedKinds []configv1beta1.DriftExcludedKind, logger logr.Logger) error {
 
 	logger = logger.WithValues("clustersummary", applicant)
 	logger.V(logs.LogDebug).Info("deploy resourcesummary")
 
+	// Resources of excluded kinds are neither tracked for drift nor part of the inventory
+	excludedKinds := make([]configv1beta1.DriftExcludedKind, 0)
+	excludedKinds = append(excludedKinds, getDriftExcludedKinds()...)
+	excludedKinds = append(excludedKinds, driftExcludedKinds...)
+	resources = filterDriftExcludedResources(resources, excludedKinds)
+	kustomizeResources = filterDriftExcludedResources(kustomizeResources, excludedKinds)
+	helmResources = filterDriftExcludedHelmResources(helmResources, excludedKinds)
+
 	// ResourceSummary is a Sveltos resource created in managed clusters.
 	// Sveltos resources are always created using cluster-admin so that admin does not need to be
 	// given such permissions.
-	remoteClient, err := clusterproxy.GetKubernetesClient(ctx, c, clusterNamespace, clusterName, "", "",
+	remoteClient, err := getKubernetesClient(ctx, c, clusterNamespace, clusterName, "", "",
 		clusterType, logger)
 	if err != nil {
 		return err
@@ -221,7 +233,7 @@ func prepareDriftDetectionManagerYAML(driftDetectionManagerYAML, clusterNamespac
 // deployDriftDetectionManager deploys drift-detection-manager in the managed cluster
 func deployDriftDetectionManager(ctx context.Context, remoteRestConfig *rest.Config,
 	clusterNamespace, clusterName, mode string, clusterType libsveltosv1beta1.ClusterType,
-	patches []libsveltosv1beta1.Patch, logger logr.Logger) error {
+	patches []libsveltosv1beta1.Patch, resources *corev1.ResourceRequirements, logger logr.Logger) error {
 
 	logger.V(logs.LogDebug).Info("deploy drift-detection-manager in managed cluster")
 	driftDetectionManagerYAML := string(driftdetection.GetDriftDetectionManagerYAML())
@@ -229,7 +241,8 @@ func deployDriftDetectionManager(ctx context.Context, remoteRestConfig *rest.Con
 	driftDetectionManagerYAML = prepareDriftDetectionManagerYAML(driftDetectionManagerYAML, clusterNamespace,
 		clusterName, mode, clusterType)
 
-	return deployDriftDetectionManagerResources(ctx, remoteRestConfig, driftDetectionManagerYAML, nil, patches, logger)
+	return deployDriftDetectionManagerResources(ctx, remoteRestConfig, driftDetectionManagerYAML, nil, patches,
+		resources, logger)
 }
 
 // deployDriftDetectionManagerInManagementCluster deploys drift-detection-manager in the management cluster
@@ -238,7 +251,7 @@ func deployDriftDetectionManager(ctx context.Context, remoteRestConfig *rest.Con
 // Those instances are all running in the "projectsveltos" namespace.
 func deployDriftDetectionManagerInManagementCluster(ctx context.Context, restConfig *rest.Config,
 	clusterNamespace, clusterName, mode string, clusterType libsveltosv1beta1.ClusterType,
-	patches []libsveltosv1beta1.Patch, logger logr.Logger) error {
+	patches []libsveltosv1beta1.Patch, resources *corev1.ResourceRequirements, logger logr.Logger) error {
 
 	logger.V(logs.LogDebug).Info("deploy drift-detection-manager in management cluster")
 	driftDetectionManagerYAML := string(driftdetection.GetDriftDetectionManagerInMgmtClusterYAML())
@@ -258,12 +271,13 @@ func deployDriftDetectionManagerInManagementCluster(ctx context.Context, restCon
 	}
 
 	driftDetectionManagerYAML = strings.ReplaceAll(driftDetectionManagerYAML, "$NAME", name)
-	return deployDriftDetectionManagerResources(ctx, restConfig, driftDetectionManagerYAML, lbls, patches, logger)
+	return deployDriftDetectionManagerResources(ctx, restConfig, driftDetectionManagerYAML, lbls, patches,
+		resources, logger)
 }
 
 func deployDriftDetectionManagerResources(ctx context.Context, restConfig *rest.Config,
 	driftDetectionManagerYAML string, lbls map[string]string, patches []libsveltosv1beta1.Patch,
-	logger logr.Logger) error {
+	resources *corev1.ResourceRequirements, logger logr.Logger) error {
 
 	elements, err := customSplit(driftDetectionManagerYAML)
 	if err != nil {
@@ -276,6 +290,14 @@ func deployDriftDetectionManagerResources(ctx context.Context, restConfig *rest.
 			return err
 		}
 
+		// Image is set before patches are applied, so patches can still override it
+		if image := getDriftDetectionImage(); image != "" {
+			err = setDriftDetectionManagerImage(policy, image)
+			if err != nil {
+				return err
+			}
+		}
+
 		if lbls != nil {
 			// Add extra labels
 			currentLabels := policy.GetLabels()
@@ -302,6 +324,16 @@ func deployDriftDetectionManagerResources(ctx context.Context, restConfig *rest.
 			referencedUnstructured = append(referencedUnstructured, policy)
 		}
 
+		// Compute resources requested by the ClusterSummary take precedence over patches
+		if resources != nil {
+			for j := range referencedUnstructured {
+				err = setDriftDetectionManagerResources(referencedUnstructured[j], resources)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
 		err = deployDriftDetectionManagerPatchedResources(ctx, restConfig, referencedUnstructured, logger)
 		if err != nil {
 			return err
@@ -311,6 +343,44 @@ func deployDriftDetectionManagerResources(ctx context.Context, restConfig *rest.
 	return nil
 }
 
+// setDriftDetectionManagerResources sets resources on the manager container of the
+// drift-detection-manager Deployment. Any other resource is left untouched.
+func setDriftDetectionManagerResources(u *unstructured.Unstructured, resources *corev1.ResourceRequirements) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(resources)
+	if err != nil {
+		return err
+	}
+
+	return setDriftDetectionManagerContainerField(u, "resources", content)
+}
+
+// setDriftDetectionManagerImage sets image on the manager container of the
+// drift-detection-manager Deployment. Any other resource is left untouched.
+func setDriftDetectionManagerImage(u *unstructured.Unstructured, image string) error {
+	return setDriftDetectionManagerContainerField(u, "image", image)
+}
+
+func setDriftDetectionManagerContainerField(u *unstructured.Unstructured, field string, value interface{}) error {
+	if u.GetKind() != "Deployment" {
+		return nil
+	}
+
+	containers, found, err := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "containers")
+	if err != nil || !found {
+		return err
+	}
+
+	for i := range containers {
+		container, ok := containers[i].(map[string]interface{})
+		if !ok || container["name"] != driftDetectionManagerContainerName {
+			continue
+		}
+		container[field] = value
+	}
+
+	return unstructured.SetNestedSlice(u.Object, containers, "spec", "template", "spec", "containers")
+}
+
 func deployDriftDetectionManagerPatchedResources(ctx context.Context, restConfig *rest.Config,
 	referencedUnstructured []*unstructured.Unstructured, logger logr.Logger) error {
 
@@ -412,6 +482,57 @@ func deployResourceSummaryInstance(ctx context.Context, remoteClient client.Clie
 	return remoteClient.Update(ctx, currentResourceSummary)
 }
 
+// isDriftExcludedKind returns true if resource is of any of the excluded kinds
+func isDriftExcludedKind(resource *libsveltosv1beta1.Resource, excludedKinds []configv1beta1.DriftExcludedKind) bool {
+	for i := range excludedKinds {
+		if excludedKinds[i].Kind != resource.Kind || excludedKinds[i].Group != resource.Group {
+			continue
+		}
+		if excludedKinds[i].Version == "" || excludedKinds[i].Version == resource.Version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterDriftExcludedResources returns resources which are not of any of the excluded kinds.
+// A nil slice is returned as is (nil means the section of the ResourceSummary must not be changed).
+func filterDriftExcludedResources(resources []libsveltosv1beta1.Resource,
+	excludedKinds []configv1beta1.DriftExcludedKind) []libsveltosv1beta1.Resource {
+
+	if resources == nil || len(excludedKinds) == 0 {
+		return resources
+	}
+
+	filtered := make([]libsveltosv1beta1.Resource, 0, len(resources))
+	for i := range resources {
+		if !isDriftExcludedKind(&resources[i], excludedKinds) {
+			filtered = append(filtered, resources[i])
+		}
+	}
+
+	return filtered
+}
+
+// filterDriftExcludedHelmResources removes, from resources deployed by each helm chart, the ones
+// of any of the excluded kinds
+func filterDriftExcludedHelmResources(helmResources []libsveltosv1beta1.HelmResources,
+	excludedKinds []configv1beta1.DriftExcludedKind) []libsveltosv1beta1.HelmResources {
+
+	if helmResources == nil || len(excludedKinds) == 0 {
+		return helmResources
+	}
+
+	filtered := make([]libsveltosv1beta1.HelmResources, len(helmResources))
+	for i := range helmResources {
+		filtered[i] = helmResources[i]
+		filtered[i].Resources = filterDriftExcludedResources(helmResources[i].Resources, excludedKinds)
+	}
+
+	return filtered
+}
+
 // transformDriftExclusionPathsToPatches transforms a DriftExclusion instance to a Patch instance.
 // Operation is always set to remove (the goal of a DriftExclusion is to not consider, so to remove, a path
 // during configuration drift evaluation).
@@ -27,6 +27,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -51,6 +52,20 @@ func collectAndProcessResourceSummaries(ctx context.Context, c client.Client, sh
 			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get clusters: %v", err))
 		}
 
+		// The management cluster, when targeted, belongs to the default shard
+		if shardkey == "" {
+			targeted, err := isManagementClusterTargeted(ctx, c)
+			if err != nil {
+				logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to verify if management cluster is targeted: %v", err))
+			} else if targeted {
+				clusterList = append(clusterList, corev1.ObjectReference{
+					Name:       configv1beta1.ManagementClusterName,
+					Kind:       libsveltosv1beta1.SveltosClusterKind,
+					APIVersion: libsveltosv1beta1.GroupVersion.String(),
+				})
+			}
+		}
+
 		for i := range clusterList {
 			cluster := &clusterList[i]
 			err = collectResourceSummariesFromCluster(ctx, c, cluster, version, logger)
@@ -76,7 +91,7 @@ func collectResourceSummariesFromCluster(ctx context.Context, c client.Client,
 		APIVersion: cluster.APIVersion,
 		Kind:       cluster.Kind,
 	}
-	ready, err := clusterproxy.IsClusterReadyToBeConfigured(ctx, c, clusterRef, logger)
+	ready, err := isClusterReadyToBeConfigured(ctx, c, clusterRef, logger)
 	if err != nil {
 		logger.V(logs.LogDebug).Info("cluster is not ready yet")
 		return err
@@ -88,7 +103,7 @@ func collectResourceSummariesFromCluster(ctx context.Context, c client.Client,
 
 	// Use cluster-admin role to collect Sveltos resources from managed clusters
 	var remoteClient client.Client
-	remoteClient, err = clusterproxy.GetKubernetesClient(ctx, c, cluster.Namespace, cluster.Name, "", "",
+	remoteClient, err = getKubernetesClient(ctx, c, cluster.Namespace, cluster.Name, "", "",
 		clusterproxy.GetClusterType(clusterRef), logger)
 	if err != nil {
 		return err
@@ -120,20 +135,26 @@ func collectResourceSummariesFromCluster(ctx context.Context, c client.Client,
 
 	l := logger.WithValues("cluster", fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name))
 
+	clusterKey := getDriftClusterKey(cluster)
+	currentKeys := make(map[string]bool)
 	for i := range rsList.Items {
 		rs := &rsList.Items[i]
 		if !rs.DeletionTimestamp.IsZero() {
 			// ignore deleted ClassifierReport
 			continue
 		}
+		rsKey := getResourceSummaryKey(clusterKey, rs)
+		currentKeys[rsKey] = true
 		if rs.Status.ResourcesChanged || rs.Status.HelmResourcesChanged || rs.Status.KustomizeResourcesChanged {
 			// process resourceSummary
-			err = processResourceSummary(ctx, c, remoteClient, rs, l)
+			err = processResourceSummary(ctx, c, remoteClient, rs, rsKey, l)
 			if err != nil {
 				return err
 			}
 		}
+		storeResourceSummaryHashes(rsKey, rs)
 	}
+	removeStaleResourceSummaryHashes(clusterKey, currentKeys)
 
 	return nil
 }
@@ -154,7 +175,7 @@ func isResourceSummaryInstalled(ctx context.Context, c client.Client) (bool, err
 }
 
 func processResourceSummary(ctx context.Context, c, remoteClient client.Client,
-	rs *libsveltosv1beta1.ResourceSummary, logger logr.Logger) error {
+	rs *libsveltosv1beta1.ResourceSummary, rsKey string, logger logr.Logger) error {
 
 	if rs.Labels == nil {
 		logger.V(logs.LogInfo).Info("labels not set. Cannot process it")
@@ -175,8 +196,12 @@ func processResourceSummary(ctx context.Context, c, remoteClient client.Client,
 		return nil
 	}
 
+	detectionTime := metav1.Now()
+	var clusterSummary *configv1beta1.ClusterSummary
+	var drifted []configv1beta1.FeatureID
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		clusterSummary := &configv1beta1.ClusterSummary{}
+		clusterSummary = &configv1beta1.ClusterSummary{}
+		drifted = make([]configv1beta1.FeatureID, 0)
 		err := c.Get(ctx, types.NamespacedName{Namespace: clusterSummaryNamespace, Name: clusterSummaryName},
 			clusterSummary)
 		if err != nil {
@@ -199,22 +224,34 @@ func processResourceSummary(ctx context.Context, c, remoteClient client.Client,
 					l.V(logs.LogDebug).Info("redeploy helm")
 					clusterSummary.Status.FeatureSummaries[i].Hash = nil
 					clusterSummary.Status.FeatureSummaries[i].Status = configv1beta1.FeatureStatusProvisioning
+					drifted = append(drifted, configv1beta1.FeatureHelm)
 				}
 			} else if clusterSummary.Status.FeatureSummaries[i].FeatureID == configv1beta1.FeatureResources {
 				if rs.Status.ResourcesChanged {
 					l.V(logs.LogDebug).Info("redeploy resources")
 					clusterSummary.Status.FeatureSummaries[i].Hash = nil
 					clusterSummary.Status.FeatureSummaries[i].Status = configv1beta1.FeatureStatusProvisioning
+					drifted = append(drifted, configv1beta1.FeatureResources)
 				}
 			} else if clusterSummary.Status.FeatureSummaries[i].FeatureID == configv1beta1.FeatureKustomize {
 				if rs.Status.KustomizeResourcesChanged {
 					l.V(logs.LogDebug).Info("redeploy kustomization resources")
 					clusterSummary.Status.FeatureSummaries[i].Hash = nil
 					clusterSummary.Status.FeatureSummaries[i].Status = configv1beta1.FeatureStatusProvisioning
+					drifted = append(drifted, configv1beta1.FeatureKustomize)
 				}
 			}
 		}
 
+		for i := range drifted {
+			recordDrift(clusterSummary, &configv1beta1.DriftEvent{
+				FeatureID:     drifted[i],
+				Resources:     getDriftedResources(rsKey, rs, drifted[i]),
+				DetectionTime: detectionTime,
+				Action:        configv1beta1.DriftActionRedeploy,
+			})
+		}
+
 		err = c.Status().Update(ctx, clusterSummary)
 		if err != nil {
 			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to update ClusterSummary status: %v", err))
@@ -227,6 +264,10 @@ func processResourceSummary(ctx context.Context, c, remoteClient client.Client,
 		return err
 	}
 
+	for i := range drifted {
+		recordDriftMetric(clusterSummary, drifted[i])
+	}
+
 	return resetResourceSummaryStatus(ctx, remoteClient, rs, logger)
 }
 
@@ -246,5 +287,6 @@ func resetResourceSummaryStatus(ctx context.Context, remoteClient client.Client,
 	logger.V(logs.LogDebug).Info("reset resourceSummary status")
 	resourceSummary.Status.ResourcesChanged = false
 	resourceSummary.Status.HelmResourcesChanged = false
+	resourceSummary.Status.KustomizeResourcesChanged = false
 	return remoteClient.Status().Update(ctx, resourceSummary)
 }
@@ -28,7 +28,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2/textlogger"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -37,7 +39,9 @@ import (
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	"github.com/projectsveltos/addon-controller/controllers"
+	driftdetection "github.com/projectsveltos/addon-controller/pkg/drift-detection"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/utils"
 )
 
 var _ = Describe("ResourceSummary Deployer", func() {
@@ -121,7 +125,7 @@ var _ = Describe("ResourceSummary Deployer", func() {
 		// Just verify result is success (testEnv is used to simulate both management and workload cluster and because
 		// classifier is expected in the management cluster, above line is required
 		Expect(controllers.DeployResourceSummaryInCluster(context.TODO(), testEnv.Client, cluster.Namespace, cluster.Name,
-			clusterSummaryName, libsveltosv1beta1.ClusterTypeCapi, nil, nil, nil, nil,
+			clusterSummaryName, libsveltosv1beta1.ClusterTypeCapi, nil, nil, nil, nil, nil,
 			textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
 
 		// Eventual loop so testEnv Cache is synced
@@ -138,7 +142,7 @@ var _ = Describe("ResourceSummary Deployer", func() {
 		clusterType := libsveltosv1beta1.ClusterTypeSveltos
 
 		Expect(controllers.DeployDriftDetectionManagerInManagementCluster(context.TODO(), testEnv.Config,
-			clusterNamespace, clusterName, "", clusterType, nil,
+			clusterNamespace, clusterName, "", clusterType, nil, nil,
 			textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
 
 		expectedLabels := controllers.GetDriftDetectionManagerLabels(clusterNamespace, clusterName, clusterType)
@@ -272,6 +276,119 @@ var _ = Describe("ResourceSummary Deployer", func() {
 		}
 		Expect(patches).To(ContainElement(expectedPatch))
 	})
+	It("filterDriftExcludedResources removes resources of excluded kinds", func() {
+		resources := []libsveltosv1beta1.Resource{
+			{Kind: "Event", Version: "v1", Namespace: randomString(), Name: randomString()},
+			{Kind: "Job", Group: "batch", Version: "v1", Namespace: randomString(), Name: randomString()},
+			{Kind: "Deployment", Group: "apps", Version: "v1", Namespace: randomString(), Name: randomString()},
+		}
+
+		excludedKinds := []configv1beta1.DriftExcludedKind{
+			{Kind: "Event", Version: "v1"},
+			{Kind: "Job", Group: "batch"},
+		}
+
+		filtered := controllers.FilterDriftExcludedResources(resources, excludedKinds)
+		Expect(filtered).To(HaveLen(1))
+		Expect(filtered[0]).To(Equal(resources[2]))
+
+		Expect(controllers.FilterDriftExcludedResources(nil, excludedKinds)).To(BeNil())
+
+		helmResources := []libsveltosv1beta1.HelmResources{
+			{ChartName: randomString(), ReleaseName: randomString(), ReleaseNamespace: randomString(),
+				Resources: resources},
+		}
+		filteredHelm := controllers.FilterDriftExcludedHelmResources(helmResources, excludedKinds)
+		Expect(filteredHelm).To(HaveLen(1))
+		Expect(filteredHelm[0].Resources).To(HaveLen(1))
+		Expect(helmResources[0].Resources).To(HaveLen(len(resources)))
+	})
+
+	It("SetDriftExcludedKinds parses apiVersion/Kind entries", func() {
+		Expect(controllers.SetDriftExcludedKinds([]string{"v1/Event", "batch/*/Job",
+			"coordination.k8s.io/v1/Lease"})).To(Succeed())
+		Expect(controllers.GetDriftExcludedKinds()).To(Equal([]configv1beta1.DriftExcludedKind{
+			{Version: "v1", Kind: "Event"},
+			{Group: "batch", Kind: "Job"},
+			{Group: "coordination.k8s.io", Version: "v1", Kind: "Lease"},
+		}))
+
+		Expect(controllers.SetDriftExcludedKinds([]string{"Event"})).ToNot(Succeed())
+
+		Expect(controllers.SetDriftExcludedKinds(nil)).To(Succeed())
+		Expect(controllers.GetDriftExcludedKinds()).To(BeEmpty())
+	})
+
+	It("DriftDetectionPlacement and DriftDetectionResources are honored", func() {
+		clusterSummary := &configv1beta1.ClusterSummary{}
+		Expect(controllers.IsDriftDetectionInMgmtCluster(clusterSummary, true)).To(BeTrue())
+		Expect(controllers.IsDriftDetectionInMgmtCluster(clusterSummary, false)).To(BeFalse())
+		Expect(controllers.GetDriftDetectionResources(clusterSummary, true)).To(BeNil())
+
+		clusterSummary.Spec.ClusterProfileSpec.DriftDetectionPlacement =
+			configv1beta1.DriftDetectionPlacementManagedCluster
+		Expect(controllers.IsDriftDetectionInMgmtCluster(clusterSummary, true)).To(BeFalse())
+
+		clusterSummary.Spec.ClusterProfileSpec.DriftDetectionPlacement =
+			configv1beta1.DriftDetectionPlacementManagementCluster
+		Expect(controllers.IsDriftDetectionInMgmtCluster(clusterSummary, false)).To(BeTrue())
+
+		mgmtClusterResources := &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		}
+		clusterSummary.Spec.ClusterProfileSpec.DriftDetectionResources = &configv1beta1.DriftDetectionResources{
+			ManagementCluster: mgmtClusterResources,
+		}
+		Expect(controllers.GetDriftDetectionResources(clusterSummary, true)).To(Equal(mgmtClusterResources))
+		Expect(controllers.GetDriftDetectionResources(clusterSummary, false)).To(BeNil())
+
+		elements, err := controllers.CustomSplit(string(driftdetection.GetDriftDetectionManagerInMgmtClusterYAML()))
+		Expect(err).To(BeNil())
+		found := false
+		for i := range elements {
+			u, err := utils.GetUnstructured([]byte(elements[i]))
+			Expect(err).To(BeNil())
+			Expect(controllers.SetDriftDetectionManagerResources(u, mgmtClusterResources)).To(Succeed())
+			if u.GetKind() != "Deployment" {
+				continue
+			}
+			found = true
+			depl := &appsv1.Deployment{}
+			Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), depl)).To(Succeed())
+			for j := range depl.Spec.Template.Spec.Containers {
+				if depl.Spec.Template.Spec.Containers[j].Name == "manager" {
+					limit := depl.Spec.Template.Spec.Containers[j].Resources.Limits[corev1.ResourceMemory]
+					Expect(limit.String()).To(Equal("512Mi"))
+				}
+			}
+		}
+		Expect(found).To(BeTrue())
+	})
+
+	It("setDriftDetectionManagerImage sets the image of drift-detection-manager container", func() {
+		image := "registry.example.com/projectsveltos/drift-detection-manager:" + randomString()
+
+		elements, err := controllers.CustomSplit(string(driftdetection.GetDriftDetectionManagerYAML()))
+		Expect(err).To(BeNil())
+		found := false
+		for i := range elements {
+			u, err := utils.GetUnstructured([]byte(elements[i]))
+			Expect(err).To(BeNil())
+			Expect(controllers.SetDriftDetectionManagerImage(u, image)).To(Succeed())
+			if u.GetKind() != "Deployment" {
+				continue
+			}
+			found = true
+			depl := &appsv1.Deployment{}
+			Expect(runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), depl)).To(Succeed())
+			for j := range depl.Spec.Template.Spec.Containers {
+				if depl.Spec.Template.Spec.Containers[j].Name == "manager" {
+					Expect(depl.Spec.Template.Spec.Containers[j].Image).To(Equal(image))
+				}
+			}
+		}
+		Expect(found).To(BeTrue())
+	})
 })
 
 func prepareCluster() *clusterv1.Cluster {
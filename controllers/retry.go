@@ -0,0 +1,121 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultRetryBaseBackoff = 10 * time.Second
+	defaultRetryMaxBackoff  = 5 * time.Minute
+)
+
+// RetryPolicy configures how deploying a feature in a managed cluster is retried after a failure.
+// Each consecutive failure doubles the time before next attempt, from BaseBackoff up to MaxBackoff.
+// After MaxAttempts consecutive failures the circuit opens: no attempt is made for CircuitBreakDuration.
+// Once elapsed a single attempt is made; if it fails the circuit opens again.
+// Zero values mean the default is used.
+type RetryPolicy struct {
+	// BaseBackoff is the time to wait before retrying after the first failure
+	BaseBackoff time.Duration
+
+	// MaxBackoff caps the time to wait before retrying
+	MaxBackoff time.Duration
+
+	// Jitter, between 0 and 1, randomly increases each backoff by up to Jitter*backoff
+	// so clusters failing together are not retried all at the same time
+	Jitter float64
+
+	// MaxAttempts is the number of consecutive failures after which the circuit opens.
+	// Zero disables circuit breaking.
+	MaxAttempts int32
+
+	// CircuitBreakDuration is how long no attempt is made once circuit is open.
+	// Defaults to MaxBackoff.
+	CircuitBreakDuration time.Duration
+}
+
+// retryAfterError is returned when deploying a feature failed and the next attempt
+// must wait till after is elapsed
+type retryAfterError struct {
+	after time.Duration
+	err   error
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("%v. Retrying in %s", e.err, e.after.Round(time.Second))
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+func (p *RetryPolicy) baseBackoff() time.Duration {
+	if p.BaseBackoff <= 0 {
+		return defaultRetryBaseBackoff
+	}
+	return p.BaseBackoff
+}
+
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+	if maxBackoff < p.baseBackoff() {
+		maxBackoff = p.baseBackoff()
+	}
+	return maxBackoff
+}
+
+// isCircuitOpen returns true if, after consecutiveFailures, no attempt must be made
+// for CircuitBreakDuration
+func (p *RetryPolicy) isCircuitOpen(consecutiveFailures int32) bool {
+	return p.MaxAttempts > 0 && consecutiveFailures >= p.MaxAttempts
+}
+
+// backoff returns how long to wait before next attempt after consecutiveFailures (at least 1)
+func (p *RetryPolicy) backoff(consecutiveFailures int32) time.Duration {
+	if p.isCircuitOpen(consecutiveFailures) {
+		if p.CircuitBreakDuration > 0 {
+			return p.CircuitBreakDuration
+		}
+		return p.maxBackoff()
+	}
+
+	backoff := p.baseBackoff()
+	for i := int32(1); i < consecutiveFailures && backoff < p.maxBackoff(); i++ {
+		backoff *= 2
+	}
+	if backoff > p.maxBackoff() {
+		backoff = p.maxBackoff()
+	}
+
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		//nolint: gosec // no need for a cryptographically secure random number
+		backoff += time.Duration(rand.Float64() * jitter * float64(backoff))
+	}
+
+	return backoff
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("RetryPolicy", func() {
+	It("backoff doubles on each failure up to max backoff", func() {
+		policy := &controllers.RetryPolicy{}
+		Expect(controllers.RetryBackoff(policy, 1)).To(Equal(10 * time.Second))
+		Expect(controllers.RetryBackoff(policy, 2)).To(Equal(20 * time.Second))
+		Expect(controllers.RetryBackoff(policy, 3)).To(Equal(40 * time.Second))
+		Expect(controllers.RetryBackoff(policy, 100)).To(Equal(5 * time.Minute))
+
+		policy = &controllers.RetryPolicy{BaseBackoff: time.Second, MaxBackoff: 3 * time.Second}
+		Expect(controllers.RetryBackoff(policy, 1)).To(Equal(time.Second))
+		Expect(controllers.RetryBackoff(policy, 2)).To(Equal(2 * time.Second))
+		Expect(controllers.RetryBackoff(policy, 3)).To(Equal(3 * time.Second))
+	})
+
+	It("backoff adds jitter", func() {
+		policy := &controllers.RetryPolicy{BaseBackoff: time.Minute, Jitter: 0.5}
+		for i := 0; i < 10; i++ {
+			backoff := controllers.RetryBackoff(policy, 1)
+			Expect(backoff).To(BeNumerically(">=", time.Minute))
+			Expect(backoff).To(BeNumerically("<=", 90*time.Second))
+		}
+	})
+
+	It("opens circuit after max attempts", func() {
+		policy := &controllers.RetryPolicy{}
+		Expect(controllers.IsRetryCircuitOpen(policy, 100)).To(BeFalse())
+
+		policy = &controllers.RetryPolicy{MaxAttempts: 3, CircuitBreakDuration: time.Hour}
+		Expect(controllers.IsRetryCircuitOpen(policy, 2)).To(BeFalse())
+		Expect(controllers.RetryBackoff(policy, 2)).To(Equal(20 * time.Second))
+		Expect(controllers.IsRetryCircuitOpen(policy, 3)).To(BeTrue())
+		Expect(controllers.RetryBackoff(policy, 3)).To(Equal(time.Hour))
+
+		policy.CircuitBreakDuration = 0
+		Expect(controllers.RetryBackoff(policy, 3)).To(Equal(5 * time.Minute))
+	})
+})
@@ -0,0 +1,174 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
+)
+
+// A rollout (updating matching clusters after ClusterProfile/Profile Spec changes) is aborted when
+// either more than RolloutFailureThreshold clusters fail or a RolloutRing exceeds its MaxFailures.
+// Once aborted, no more clusters are updated till Spec changes again. The RolloutAborted condition
+// is set and an event is emitted.
+// If RollbackOnRolloutAbort is set, clusters already updated by the aborted rollout are rolled back
+// to the last Spec rolled out to all matching clusters (Status.LastRolledOutSpec).
+
+// rolloutAbort describes why a rollout was aborted
+type rolloutAbort struct {
+	reason  string
+	message string
+}
+
+// getRolloutAbort returns why rollout of current Spec is aborted or nil if it is not.
+func getRolloutAbort(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	updatingClusters *libsveltosset.Set, plan *rolloutPlan) (*rolloutAbort, error) {
+
+	// Once aborted, rollout stays aborted till Spec changes
+	if profileScope.IsRolloutAborted() {
+		condition := meta.FindStatusCondition(profileScope.GetStatus().Conditions,
+			configv1beta1.RolloutAbortedCondition)
+		return &rolloutAbort{reason: condition.Reason, message: condition.Message}, nil
+	}
+
+	if plan != nil && plan.aborted {
+		ring := profileScope.GetSpec().RolloutRings[plan.allowed].Name
+		return &rolloutAbort{
+			reason: configv1beta1.RolloutRingAbortedReason,
+			message: configv1beta1.FormatMessage(configv1beta1.MessageCodeRolloutRingAborted,
+				fmt.Sprintf("more than MaxFailures clusters failed in ring %s", ring)),
+		}, nil
+	}
+
+	threshold := profileScope.GetSpec().RolloutFailureThreshold
+	if threshold == nil {
+		return nil, nil
+	}
+
+	matching := len(profileScope.GetStatus().MatchingClusterRefs)
+	maxFailures, err := intstr.GetScaledValueFromIntOrPercent(threshold, matching, false)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := 0
+	clusters := updatingClusters.Items()
+	for i := range clusters {
+		isFailed, err := isClusterDeploymentFailed(ctx, c, profileScope, &clusters[i])
+		if err != nil {
+			return nil, err
+		}
+		if isFailed {
+			failed++
+		}
+	}
+
+	if failed <= maxFailures {
+		return nil, nil
+	}
+
+	return &rolloutAbort{
+		reason: configv1beta1.FailureThresholdExceededReason,
+		message: configv1beta1.FormatMessage(configv1beta1.MessageCodeFailureThresholdExceeded,
+			fmt.Sprintf("%d of %d matching clusters failed to be updated", failed, matching)),
+	}, nil
+}
+
+// rollbackClusterSummaries rolls clusters updated by the aborted rollout back to LastRolledOutSpec
+func rollbackClusterSummaries(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	logger logr.Logger) error {
+
+	spec := profileScope.GetStatus().LastRolledOutSpec
+	if spec == nil {
+		logger.V(logs.LogInfo).Info("no Spec was ever rolled out to all matching clusters. Cannot roll back.")
+		return nil
+	}
+
+	if profileScope.IsOneTimeSync() {
+		return nil
+	}
+
+	status := profileScope.GetStatus()
+	clusters := make([]corev1.ObjectReference, 0, len(status.UpdatedClusters.Clusters)+len(status.UpdatingClusters.Clusters))
+	clusters = append(clusters, status.UpdatedClusters.Clusters...)
+	clusters = append(clusters, status.UpdatingClusters.Clusters...)
+
+	for i := range clusters {
+		if err := rollbackClusterSummary(ctx, c, profileScope, &clusters[i], spec); err != nil {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to roll back cluster %s:%s/%s: %v",
+				clusters[i].Kind, clusters[i].Namespace, clusters[i].Name, err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rollbackClusterSummary(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	cluster *corev1.ObjectReference, spec *configv1beta1.Spec) error {
+
+	clusterSummary, err := getClusterSummary(ctx, c, profileScope.GetKind(), profileScope.Name(),
+		cluster.Namespace, cluster.Name, clusterproxy.GetClusterType(cluster))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if reflect.DeepEqual(*spec, clusterSummary.Spec.ClusterProfileSpec) {
+		return nil
+	}
+
+	clusterSummary.Spec.ClusterProfileSpec = *spec
+	clusterSummary.Spec.ProfileRevision = getRevisionForSpec(profileScope.GetStatus(), spec)
+	return c.Update(ctx, clusterSummary)
+}
+
+// recordRolloutAborted emits an event on the ClusterProfile/Profile reporting rollout was aborted
+func recordRolloutAborted(recorder record.EventRecorder, profileScope *scope.ProfileScope) {
+	if recorder == nil {
+		return
+	}
+
+	condition := meta.FindStatusCondition(profileScope.GetStatus().Conditions,
+		configv1beta1.RolloutAbortedCondition)
+	if condition == nil {
+		return
+	}
+
+	message := condition.Message
+	if profileScope.GetSpec().RollbackOnRolloutAbort {
+		message += ". Rolling back updated clusters"
+	}
+	recorder.Event(profileScope.Profile, corev1.EventTypeWarning, configv1beta1.RolloutAbortedCondition, message)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
+)
+
+var _ = Describe("Rollout abort", func() {
+	var clusterProfile *configv1beta1.ClusterProfile
+	var failedClusterRef corev1.ObjectReference
+	var healthyClusterRef corev1.ObjectReference
+
+	BeforeEach(func() {
+		namespace := randomString()
+		failedClusterRef = corev1.ObjectReference{Namespace: namespace, Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		healthyClusterRef = corev1.ObjectReference{Namespace: namespace, Name: randomString(),
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+
+		threshold := intstr.FromInt32(0)
+		clusterProfile = &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: randomString(),
+			},
+			Spec: configv1beta1.Spec{
+				SyncMode:                configv1beta1.SyncModeContinuous,
+				Tier:                    10,
+				RolloutFailureThreshold: &threshold,
+				RollbackOnRolloutAbort:  true,
+			},
+			Status: configv1beta1.Status{
+				MatchingClusterRefs: []corev1.ObjectReference{failedClusterRef, healthyClusterRef},
+				LastRolledOutSpec: &configv1beta1.Spec{
+					SyncMode: configv1beta1.SyncModeContinuous,
+					Tier:     100,
+				},
+			},
+		}
+		Expect(addTypeInformationToObject(scheme, clusterProfile)).To(Succeed())
+	})
+
+	prepareEnvironment := func() (client.Client, *scope.ProfileScope) {
+		initObjects := []client.Object{clusterProfile}
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(clusterProfile, &configv1beta1.ClusterSummary{}).
+			WithObjects(initObjects...).Build()
+
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logr.Discard(),
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+
+		for _, ref := range []corev1.ObjectReference{failedClusterRef, healthyClusterRef} {
+			Expect(controllers.CreateClusterSummary(context.TODO(), c, profileScope, &ref)).To(Succeed())
+		}
+
+		clusterSummary, err := controllers.GetClusterSummary(context.TODO(), c, configv1beta1.ClusterProfileKind,
+			clusterProfile.Name, failedClusterRef.Namespace, failedClusterRef.Name, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		clusterSummary.Status.FeatureSummaries = []configv1beta1.FeatureSummary{
+			{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusFailed},
+		}
+		Expect(c.Status().Update(context.TODO(), clusterSummary)).To(Succeed())
+
+		return c, profileScope
+	}
+
+	It("getRolloutAbort aborts rollout when failures exceed RolloutFailureThreshold", func() {
+		c, profileScope := prepareEnvironment()
+
+		updating := &libsveltosset.Set{}
+		updating.Insert(&healthyClusterRef)
+		abort, err := controllers.GetRolloutAbort(context.TODO(), c, profileScope, updating, nil)
+		Expect(err).To(BeNil())
+		Expect(abort).To(BeNil())
+
+		updating.Insert(&failedClusterRef)
+		abort, err = controllers.GetRolloutAbort(context.TODO(), c, profileScope, updating, nil)
+		Expect(err).To(BeNil())
+		Expect(abort).ToNot(BeNil())
+
+		// Without a threshold, rollout is never aborted
+		clusterProfile.Spec.RolloutFailureThreshold = nil
+		abort, err = controllers.GetRolloutAbort(context.TODO(), c, profileScope, updating, nil)
+		Expect(err).To(BeNil())
+		Expect(abort).To(BeNil())
+
+		// Once aborted, rollout stays aborted for current generation
+		profileScope.SetRolloutAborted(configv1beta1.FailureThresholdExceededReason, randomString())
+		abort, err = controllers.GetRolloutAbort(context.TODO(), c, profileScope, &libsveltosset.Set{}, nil)
+		Expect(err).To(BeNil())
+		Expect(abort).ToNot(BeNil())
+	})
+
+	It("rollbackClusterSummaries rolls clusters updated by the rollout back to LastRolledOutSpec", func() {
+		c, profileScope := prepareEnvironment()
+
+		clusterProfile.Status.UpdatingClusters = configv1beta1.Clusters{
+			Clusters: []corev1.ObjectReference{failedClusterRef},
+		}
+
+		Expect(controllers.RollbackClusterSummaries(context.TODO(), c, profileScope, logr.Discard())).To(Succeed())
+
+		clusterSummary, err := controllers.GetClusterSummary(context.TODO(), c, configv1beta1.ClusterProfileKind,
+			clusterProfile.Name, failedClusterRef.Namespace, failedClusterRef.Name, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(reflect.DeepEqual(clusterSummary.Spec.ClusterProfileSpec, *clusterProfile.Status.LastRolledOutSpec)).To(BeTrue())
+
+		// Clusters not updated by the rollout are left untouched
+		clusterSummary, err = controllers.GetClusterSummary(context.TODO(), c, configv1beta1.ClusterProfileKind,
+			clusterProfile.Name, healthyClusterRef.Namespace, healthyClusterRef.Name, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(reflect.DeepEqual(clusterSummary.Spec.ClusterProfileSpec, clusterProfile.Spec)).To(BeTrue())
+	})
+})
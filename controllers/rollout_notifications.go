@@ -0,0 +1,381 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// ClusterProfile/Profile Spec.Notifications are sent:
+// - when the ClusterProfile/Profile finishes rolling out to all matching clusters;
+// - when deploying a feature in a cluster fails FailureThreshold consecutive times.
+// Notifications are sent in background. Failures to send are logged and not retried.
+
+const (
+	defaultNotificationFailureThreshold = 3
+	notificationTimeout                 = 10 * time.Second
+
+	notificationURLKey      = "url"
+	notificationHostKey     = "host"
+	notificationFromKey     = "from"
+	notificationToKey       = "to"
+	notificationUsernameKey = "username"
+	notificationPasswordKey = "password"
+)
+
+type rolloutEventType string
+
+const (
+	rolloutCompletedEvent = rolloutEventType("RolloutCompleted")
+	deploymentFailedEvent = rolloutEventType("DeploymentFailed")
+)
+
+// rolloutEvent is the content of a notification. It is posted as is to Webhook notifications.
+type rolloutEvent struct {
+	Type rolloutEventType `json:"type"`
+	Time time.Time        `json:"time"`
+
+	ProfileKind      string `json:"profileKind"`
+	ProfileNamespace string `json:"profileNamespace,omitempty"`
+	ProfileName      string `json:"profileName"`
+
+	// Clusters is the number of clusters the profile was rolled out to. Set for RolloutCompleted.
+	Clusters int `json:"clusters,omitempty"`
+
+	// Cluster, Feature, ConsecutiveFailures and Error are set for DeploymentFailed
+	ClusterNamespace    string                        `json:"clusterNamespace,omitempty"`
+	ClusterName         string                        `json:"clusterName,omitempty"`
+	ClusterType         libsveltosv1beta1.ClusterType `json:"clusterType,omitempty"`
+	Feature             configv1beta1.FeatureID       `json:"feature,omitempty"`
+	ConsecutiveFailures int32                         `json:"consecutiveFailures,omitempty"`
+	Error               string                        `json:"error,omitempty"`
+}
+
+func (e *rolloutEvent) profile() string {
+	if e.ProfileNamespace == "" {
+		return fmt.Sprintf("%s %s", e.ProfileKind, e.ProfileName)
+	}
+	return fmt.Sprintf("%s %s/%s", e.ProfileKind, e.ProfileNamespace, e.ProfileName)
+}
+
+// subject returns a one line description of the event
+func (e *rolloutEvent) subject() string {
+	if e.Type == rolloutCompletedEvent {
+		return fmt.Sprintf("Sveltos: %s rolled out", e.profile())
+	}
+	return fmt.Sprintf("Sveltos: %s failed to deploy in cluster %s/%s", e.profile(), e.ClusterNamespace, e.ClusterName)
+}
+
+// message returns a human readable description of the event
+func (e *rolloutEvent) message() string {
+	if e.Type == rolloutCompletedEvent {
+		return fmt.Sprintf("%s finished rolling out to all %d matching clusters", e.profile(), e.Clusters)
+	}
+	return fmt.Sprintf("%s failed to deploy %s in cluster %s:%s/%s %d consecutive times: %s",
+		e.profile(), e.Feature, e.ClusterType, e.ClusterNamespace, e.ClusterName, e.ConsecutiveFailures, e.Error)
+}
+
+// isRolloutCompleted returns true if the ClusterProfile/Profile is deployed, with its current
+// Spec, to all matching clusters
+func isRolloutCompleted(profileScope *scope.ProfileScope) bool {
+	status := profileScope.GetStatus()
+	if len(status.MatchingClusterRefs) == 0 {
+		return false
+	}
+
+	updatedClusters, _ := getUpdatedAndUpdatingClusters(profileScope)
+	for i := range status.MatchingClusterRefs {
+		if !updatedClusters.Has(&status.MatchingClusterRefs[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// notifyRolloutCompleted sends all ClusterProfile/Profile notifications reporting the rollout
+// is completed
+func notifyRolloutCompleted(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	logger logr.Logger) {
+
+	notifications := profileScope.GetSpec().Notifications
+	if len(notifications) == 0 {
+		return
+	}
+
+	event := &rolloutEvent{
+		Type:             rolloutCompletedEvent,
+		Time:             time.Now().UTC(),
+		ProfileKind:      profileScope.GetKind(),
+		ProfileNamespace: profileScope.Namespace(),
+		ProfileName:      profileScope.Name(),
+		Clusters:         len(profileScope.GetStatus().MatchingClusterRefs),
+	}
+
+	sendRolloutNotifications(ctx, c, notifications, event, logger)
+}
+
+// notifyDeploymentFailure sends the ClusterProfile/Profile notifications whose FailureThreshold
+// is consecutiveFailures
+func notifyDeploymentFailure(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	featureID configv1beta1.FeatureID, consecutiveFailures int32, deployErr error, logger logr.Logger) {
+
+	notifications := make([]configv1beta1.RolloutNotification, 0)
+	for i := range clusterSummary.Spec.ClusterProfileSpec.Notifications {
+		n := &clusterSummary.Spec.ClusterProfileSpec.Notifications[i]
+		if getNotificationFailureThreshold(n) == consecutiveFailures {
+			notifications = append(notifications, *n)
+		}
+	}
+	if len(notifications) == 0 {
+		return
+	}
+
+	profileRef, err := configv1beta1.GetProfileOwnerReference(clusterSummary)
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to get profile owner: %v", err))
+		return
+	}
+
+	event := &rolloutEvent{
+		Type:                deploymentFailedEvent,
+		Time:                time.Now().UTC(),
+		ProfileKind:         profileRef.Kind,
+		ProfileName:         profileRef.Name,
+		ClusterNamespace:    clusterSummary.Spec.ClusterNamespace,
+		ClusterName:         clusterSummary.Spec.ClusterName,
+		ClusterType:         clusterSummary.Spec.ClusterType,
+		Feature:             featureID,
+		ConsecutiveFailures: consecutiveFailures,
+	}
+	if profileRef.Kind == configv1beta1.ProfileKind {
+		event.ProfileNamespace = clusterSummary.Namespace
+	}
+	if deployErr != nil {
+		event.Error = deployErr.Error()
+	}
+
+	sendRolloutNotifications(ctx, c, notifications, event, logger)
+}
+
+func getNotificationFailureThreshold(notification *configv1beta1.RolloutNotification) int32 {
+	if notification.FailureThreshold == 0 {
+		return defaultNotificationFailureThreshold
+	}
+	return notification.FailureThreshold
+}
+
+// sendRolloutNotifications sends, in background, event to all notifications
+func sendRolloutNotifications(ctx context.Context, c client.Client,
+	notifications []configv1beta1.RolloutNotification, event *rolloutEvent, logger logr.Logger) {
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		for i := range notifications {
+			l := logger.WithValues("notification", notifications[i].Name)
+			if err := sendRolloutNotification(ctx, c, &notifications[i], event); err != nil {
+				l.V(logs.LogInfo).Info(fmt.Sprintf("failed to send notification: %v", err))
+				continue
+			}
+			l.V(logs.LogDebug).Info(fmt.Sprintf("sent %s notification", event.Type))
+		}
+	}()
+}
+
+// sendRolloutNotification sends event to notification
+func sendRolloutNotification(ctx context.Context, c client.Client,
+	notification *configv1beta1.RolloutNotification, event *rolloutEvent) error {
+
+	secret, err := getNotificationSecret(ctx, c, notification, event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notificationTimeout)
+	defer cancel()
+
+	switch notification.Type {
+	case configv1beta1.RolloutNotificationTypeSlack, configv1beta1.RolloutNotificationTypeTeams:
+		// Both Slack and Teams incoming webhooks accept a text message
+		return postNotification(ctx, secret, map[string]string{"text": event.message()})
+	case configv1beta1.RolloutNotificationTypeWebhook:
+		return postNotification(ctx, secret, event)
+	case configv1beta1.RolloutNotificationTypeSMTP:
+		return sendMailNotification(secret, event)
+	default:
+		return fmt.Errorf("unsupported notification type %s", notification.Type)
+	}
+}
+
+// getNotificationSecret returns the Secret referenced by notification. Profiles can only
+// reference Secrets in their own namespace.
+func getNotificationSecret(ctx context.Context, c client.Client, notification *configv1beta1.RolloutNotification,
+	event *rolloutEvent) (*corev1.Secret, error) {
+
+	namespace := notification.SecretRef.Namespace
+	if event.ProfileKind == configv1beta1.ProfileKind {
+		namespace = event.ProfileNamespace
+	}
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: notification.SecretRef.Name}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Secret %s/%s: %w", namespace, notification.SecretRef.Name, err)
+	}
+	return secret, nil
+}
+
+func getNotificationSecretValue(secret *corev1.Secret, key string, required bool) (string, error) {
+	value, ok := secret.Data[key]
+	if !ok && required {
+		return "", fmt.Errorf("Secret %s/%s does not contain key %s", secret.Namespace, secret.Name, key)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// postNotification POSTs body, encoded in JSON, to the URL contained in secret
+func postNotification(ctx context.Context, secret *corev1.Secret, body any) error {
+	url, err := getNotificationSecretValue(secret, notificationURLKey, true)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var tlsConfig *tls.Config
+	if isStrictOutboundTLS() {
+		tlsConfig = outboundTLSConfig.Clone()
+	}
+	httpClient := &http.Client{Transport: getOutboundTransport(tlsConfig)}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("notification endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendMailNotification sends event by email using the SMTP server contained in secret
+func sendMailNotification(secret *corev1.Secret, event *rolloutEvent) error {
+	values := map[string]string{}
+	for _, key := range []string{notificationHostKey, notificationFromKey, notificationToKey} {
+		value, err := getNotificationSecretValue(secret, key, true)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	username, _ := getNotificationSecretValue(secret, notificationUsernameKey, false)
+	password, _ := getNotificationSecretValue(secret, notificationPasswordKey, false)
+
+	address := values[notificationHostKey]
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP host %s: %w", address, err)
+	}
+
+	recipients := make([]string, 0)
+	for _, to := range strings.Split(values[notificationToKey], ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			recipients = append(recipients, to)
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", address, notificationTimeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(notificationTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	smtpClient, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer smtpClient.Close()
+
+	if ok, _ := smtpClient.Extension("STARTTLS"); ok {
+		if err := smtpClient.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if username != "" {
+		if err := smtpClient.Auth(smtp.PlainAuth("", username, password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := smtpClient.Mail(values[notificationFromKey]); err != nil {
+		return err
+	}
+	for i := range recipients {
+		if err := smtpClient.Rcpt(recipients[i]); err != nil {
+			return err
+		}
+	}
+
+	w, err := smtpClient.Data()
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		values[notificationFromKey], strings.Join(recipients, ", "), event.subject(), event.message())
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return smtpClient.Quit()
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Rollout notifications", func() {
+	It("isRolloutCompleted returns true only when all matching clusters are updated", func() {
+		clusterRefs := []corev1.ObjectReference{
+			{Namespace: randomString(), Name: randomString(), Kind: libsveltosv1beta1.SveltosClusterKind,
+				APIVersion: libsveltosv1beta1.GroupVersion.String()},
+			{Namespace: randomString(), Name: randomString(), Kind: libsveltosv1beta1.SveltosClusterKind,
+				APIVersion: libsveltosv1beta1.GroupVersion.String()},
+		}
+
+		clusterProfile := &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(clusterProfile).Build()
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logr.Discard(),
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+
+		Expect(controllers.IsRolloutCompleted(profileScope)).To(BeFalse())
+
+		clusterProfile.Status.MatchingClusterRefs = clusterRefs
+		clusterProfile.Status.UpdatedClusters.Clusters = clusterRefs[:1]
+		Expect(controllers.IsRolloutCompleted(profileScope)).To(BeFalse())
+
+		clusterProfile.Status.UpdatedClusters.Clusters = clusterRefs
+		Expect(controllers.IsRolloutCompleted(profileScope)).To(BeTrue())
+	})
+
+	It("notifyDeploymentFailure posts to notifications whose threshold is reached", func() {
+		type slackMessage struct {
+			Text string `json:"text"`
+		}
+		slackMessages := make(chan slackMessage, 10)
+		slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			var msg slackMessage
+			Expect(json.NewDecoder(r.Body).Decode(&msg)).To(Succeed())
+			slackMessages <- msg
+		}))
+		defer slack.Close()
+
+		webhookEvents := make(chan map[string]any, 10)
+		webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			event := map[string]any{}
+			Expect(json.NewDecoder(r.Body).Decode(&event)).To(Succeed())
+			webhookEvents <- event
+		}))
+		defer webhook.Close()
+
+		namespace := randomString()
+		slackSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Data:       map[string][]byte{"url": []byte(slack.URL)},
+		}
+		webhookSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Data:       map[string][]byte{"url": []byte(webhook.URL)},
+		}
+
+		profileName := randomString()
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      randomString(),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: configv1beta1.GroupVersion.String(),
+						Kind:       configv1beta1.ProfileKind,
+						Name:       profileName,
+					},
+				},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: namespace,
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+				ClusterProfileSpec: configv1beta1.Spec{
+					Notifications: []configv1beta1.RolloutNotification{
+						{
+							Name: "slack", Type: configv1beta1.RolloutNotificationTypeSlack,
+							// Profiles can only reference Secrets in their namespace
+							SecretRef: corev1.SecretReference{Namespace: randomString(), Name: slackSecret.Name},
+						},
+						{
+							Name: "webhook", Type: configv1beta1.RolloutNotificationTypeWebhook,
+							SecretRef:        corev1.SecretReference{Name: webhookSecret.Name},
+							FailureThreshold: 5,
+						},
+					},
+				},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(slackSecret, webhookSecret).Build()
+
+		// Default threshold is 3
+		controllers.NotifyDeploymentFailure(context.TODO(), c, clusterSummary, configv1beta1.FeatureHelm,
+			2, errors.New("chart not found"), logr.Discard())
+		Consistently(slackMessages, "1s").ShouldNot(Receive())
+
+		controllers.NotifyDeploymentFailure(context.TODO(), c, clusterSummary, configv1beta1.FeatureHelm,
+			3, errors.New("chart not found"), logr.Discard())
+		var msg slackMessage
+		Eventually(slackMessages).Should(Receive(&msg))
+		Expect(msg.Text).To(ContainSubstring(profileName))
+		Expect(msg.Text).To(ContainSubstring(clusterSummary.Spec.ClusterName))
+		Expect(msg.Text).To(ContainSubstring("chart not found"))
+		Expect(webhookEvents).ToNot(Receive())
+
+		controllers.NotifyDeploymentFailure(context.TODO(), c, clusterSummary, configv1beta1.FeatureHelm,
+			5, errors.New("chart not found"), logr.Discard())
+		var event map[string]any
+		Eventually(webhookEvents).Should(Receive(&event))
+		Expect(event["type"]).To(Equal("DeploymentFailed"))
+		Expect(event["profileName"]).To(Equal(profileName))
+		Expect(event["profileNamespace"]).To(Equal(namespace))
+		Expect(event["consecutiveFailures"]).To(BeEquivalentTo(5))
+		Expect(slackMessages).ToNot(Receive())
+	})
+})
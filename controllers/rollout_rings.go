@@ -0,0 +1,252 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
+	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
+)
+
+// When RolloutRings are set, matching clusters are partitioned in rings. When ClusterProfile/Profile
+// Spec changes, clusters in a ring are updated only once all clusters in previous rings are updated
+// and the SoakTime of previous ring has elapsed. If more clusters than MaxFailures fail in a ring,
+// the rollout is aborted: clusters in following rings are not updated till Spec changes again.
+// Rollout progress is kept in Status.Rollout, which is reset every time Spec changes.
+
+// rolloutPlan indicates which matching clusters can be updated
+type rolloutPlan struct {
+	// rings contains, per matching cluster, the index of the ring cluster belongs to
+	rings map[corev1.ObjectReference]int
+	// allowed is the index of the last ring whose clusters can be updated
+	allowed int
+	// aborted is set when rollout is aborted in ring allowed. Only clusters of that ring already
+	// being updated can be updated then.
+	aborted bool
+}
+
+// canUpdate returns true if cluster can be updated. A nil plan allows any cluster.
+func (p *rolloutPlan) canUpdate(cluster *corev1.ObjectReference, updating bool) bool {
+	if p == nil {
+		return true
+	}
+
+	ring := p.rings[getRolloutClusterKey(cluster)]
+	if ring < p.allowed {
+		return true
+	}
+	if ring > p.allowed {
+		return false
+	}
+	return !p.aborted || updating
+}
+
+func getRolloutClusterKey(cluster *corev1.ObjectReference) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		Namespace:  cluster.Namespace,
+		Name:       cluster.Name,
+		Kind:       cluster.Kind,
+		APIVersion: cluster.APIVersion,
+	}
+}
+
+// reviseRolloutStatus updates Status.Rollout with the progress of each ring and returns
+// the rolloutPlan. Nil is returned if no RolloutRings are set.
+func reviseRolloutStatus(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	currentHash []byte, updatedClusters, updatingClusters *libsveltosset.Set, now time.Time,
+) (*rolloutPlan, error) {
+
+	rings := profileScope.GetSpec().RolloutRings
+	status := profileScope.GetStatus()
+	if len(rings) == 0 {
+		status.Rollout = nil
+		return nil, nil
+	}
+
+	if status.Rollout == nil || !reflect.DeepEqual(status.Rollout.Hash, currentHash) ||
+		len(status.Rollout.Rings) != len(rings) {
+
+		status.Rollout = &configv1beta1.RolloutStatus{
+			Hash:  currentHash,
+			Rings: make([]configv1beta1.RolloutRingStatus, len(rings)),
+		}
+		for i := range rings {
+			status.Rollout.Rings[i] = configv1beta1.RolloutRingStatus{
+				Name:  rings[i].Name,
+				Phase: configv1beta1.RolloutRingPhasePending,
+			}
+		}
+	}
+
+	selectors := make([]labels.Selector, len(rings))
+	for i := range rings {
+		selector, err := rings[i].ClusterSelector.ToSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors[i] = selector
+	}
+
+	ringsStatus := status.Rollout.Rings
+	for i := range ringsStatus {
+		ringsStatus[i].Clusters = 0
+		ringsStatus[i].Updated = 0
+		ringsStatus[i].Failed = 0
+	}
+
+	plan := &rolloutPlan{rings: make(map[corev1.ObjectReference]int)}
+	// pending contains, per ring, the number of ready clusters not updated yet
+	pending := make([]int, len(rings))
+	for i := range status.MatchingClusterRefs {
+		cluster := &status.MatchingClusterRefs[i]
+
+		ring, err := getClusterRing(ctx, c, cluster, selectors)
+		if err != nil {
+			return nil, err
+		}
+		plan.rings[getRolloutClusterKey(cluster)] = ring
+
+		ringStatus := &ringsStatus[ring]
+		ringStatus.Clusters++
+
+		switch {
+		case updatedClusters.Has(cluster):
+			ringStatus.Updated++
+		case updatingClusters.Has(cluster):
+			failed, err := isClusterDeploymentFailed(ctx, c, profileScope, cluster)
+			if err != nil {
+				return nil, err
+			}
+			if failed {
+				ringStatus.Failed++
+			}
+			pending[ring]++
+		case ringStatus.Phase == configv1beta1.RolloutRingPhaseCompleted:
+			// Once a ring is completed, its clusters are kept in sync
+			ringStatus.Updated++
+		default:
+			ready, err := isClusterReadyToBeConfigured(ctx, c, cluster, profileScope.Logger)
+			if err != nil {
+				return nil, err
+			}
+			if ready {
+				pending[ring]++
+			}
+		}
+	}
+
+	plan.allowed = len(rings) - 1
+	for i := range rings {
+		ringStatus := &ringsStatus[i]
+
+		if ringStatus.Phase == configv1beta1.RolloutRingPhaseCompleted {
+			continue
+		}
+
+		if ringStatus.Phase == configv1beta1.RolloutRingPhaseAborted || exceedsMaxFailures(&rings[i], ringStatus) {
+			ringStatus.Phase = configv1beta1.RolloutRingPhaseAborted
+			plan.allowed = i
+			plan.aborted = true
+			return plan, nil
+		}
+
+		if ringStatus.Phase != configv1beta1.RolloutRingPhaseSoaking {
+			if pending[i] > 0 {
+				ringStatus.Phase = configv1beta1.RolloutRingPhaseProgressing
+				plan.allowed = i
+				return plan, nil
+			}
+			ringStatus.Phase = configv1beta1.RolloutRingPhaseSoaking
+			ringStatus.CompletionTime = &metav1.Time{Time: now}
+		}
+
+		if rings[i].SoakTime != nil && now.Before(ringStatus.CompletionTime.Add(rings[i].SoakTime.Duration)) {
+			plan.allowed = i
+			return plan, nil
+		}
+		ringStatus.Phase = configv1beta1.RolloutRingPhaseCompleted
+	}
+
+	return plan, nil
+}
+
+// getClusterRing returns the index of the first ring whose selector matches cluster labels.
+// Clusters matching no ring (or not existing anymore) belong to the last ring.
+func getClusterRing(ctx context.Context, c client.Client, cluster *corev1.ObjectReference,
+	selectors []labels.Selector) (int, error) {
+
+	last := len(selectors) - 1
+
+	clusterObj, err := getCluster(ctx, c, cluster.Namespace, cluster.Name, clusterproxy.GetClusterType(cluster))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return last, nil
+		}
+		return 0, err
+	}
+
+	for i := range selectors {
+		if selectors[i].Matches(labels.Set(clusterObj.GetLabels())) {
+			return i, nil
+		}
+	}
+
+	return last, nil
+}
+
+// isClusterDeploymentFailed returns true if deploying add-ons in cluster failed
+func isClusterDeploymentFailed(ctx context.Context, c client.Client, profileScope *scope.ProfileScope,
+	cluster *corev1.ObjectReference) (bool, error) {
+
+	clusterSummary, err := getClusterSummary(ctx, c, profileScope.GetKind(), profileScope.Name(),
+		cluster.Namespace, cluster.Name, clusterproxy.GetClusterType(cluster))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return getClusterDeploymentStatus(clusterSummary).State == configv1beta1.ClusterDeploymentStateFailed, nil
+}
+
+// exceedsMaxFailures returns true if more clusters than MaxFailures failed in the ring
+func exceedsMaxFailures(ring *configv1beta1.RolloutRing, ringStatus *configv1beta1.RolloutRingStatus) bool {
+	if ring.MaxFailures == nil {
+		return false
+	}
+
+	maxFailures, err := intstr.GetScaledValueFromIntOrPercent(ring.MaxFailures, int(ringStatus.Clusters), false)
+	if err != nil {
+		// There is a validation on format accepted so this should never happen
+		return false
+	}
+
+	return int(ringStatus.Failed) > maxFailures
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/scope"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
+)
+
+var _ = Describe("Rollout rings", func() {
+	It("reviseRolloutStatus moves to next ring once previous ring is completed and soaked", func() {
+		namespace := randomString()
+		canary := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      randomString(),
+				Labels:    map[string]string{"env": "canary"},
+			},
+			Status: libsveltosv1beta1.SveltosClusterStatus{Ready: true},
+		}
+		production := &libsveltosv1beta1.SveltosCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      randomString(),
+				Labels:    map[string]string{"env": "production"},
+			},
+			Status: libsveltosv1beta1.SveltosClusterStatus{Ready: true},
+		}
+
+		canaryRef := corev1.ObjectReference{Namespace: canary.Namespace, Name: canary.Name,
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+		productionRef := corev1.ObjectReference{Namespace: production.Namespace, Name: production.Name,
+			Kind: libsveltosv1beta1.SveltosClusterKind, APIVersion: libsveltosv1beta1.GroupVersion.String()}
+
+		clusterProfile := &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: randomString(),
+			},
+			Spec: configv1beta1.Spec{
+				RolloutRings: []configv1beta1.RolloutRing{
+					{
+						Name: "canary",
+						ClusterSelector: libsveltosv1beta1.Selector{
+							LabelSelector: metav1.LabelSelector{
+								MatchLabels: map[string]string{"env": "canary"},
+							},
+						},
+						SoakTime: &metav1.Duration{Duration: time.Hour},
+					},
+					{
+						Name: "broad",
+					},
+				},
+			},
+			Status: configv1beta1.Status{
+				MatchingClusterRefs: []corev1.ObjectReference{canaryRef, productionRef},
+			},
+		}
+
+		Expect(addTypeInformationToObject(scheme, clusterProfile)).To(Succeed())
+
+		initObjects := []client.Object{canary, production, clusterProfile}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).
+			WithObjects(initObjects...).Build()
+
+		profileScope, err := scope.NewProfileScope(scope.ProfileScopeParams{
+			Client:         c,
+			Logger:         logr.Discard(),
+			Profile:        clusterProfile,
+			ControllerName: "clusterprofile",
+		})
+		Expect(err).To(BeNil())
+
+		hash := []byte(randomString())
+		now := time.Now()
+
+		// Nothing updated yet: only canary ring can be updated
+		plan, err := controllers.ReviseRolloutStatus(context.TODO(), c, profileScope, hash,
+			&libsveltosset.Set{}, &libsveltosset.Set{}, now)
+		Expect(err).To(BeNil())
+		Expect(controllers.RolloutPlanCanUpdate(plan, &canaryRef, false)).To(BeTrue())
+		Expect(controllers.RolloutPlanCanUpdate(plan, &productionRef, false)).To(BeFalse())
+		rollout := clusterProfile.Status.Rollout
+		Expect(rollout).ToNot(BeNil())
+		Expect(rollout.Rings[0].Phase).To(Equal(configv1beta1.RolloutRingPhaseProgressing))
+		Expect(rollout.Rings[0].Clusters).To(Equal(int32(1)))
+		Expect(rollout.Rings[1].Phase).To(Equal(configv1beta1.RolloutRingPhasePending))
+
+		// Canary cluster updated: canary ring is soaking
+		updated := &libsveltosset.Set{}
+		updated.Insert(&canaryRef)
+		plan, err = controllers.ReviseRolloutStatus(context.TODO(), c, profileScope, hash,
+			updated, &libsveltosset.Set{}, now)
+		Expect(err).To(BeNil())
+		Expect(controllers.RolloutPlanCanUpdate(plan, &productionRef, false)).To(BeFalse())
+		Expect(rollout.Rings[0].Phase).To(Equal(configv1beta1.RolloutRingPhaseSoaking))
+		Expect(rollout.Rings[0].Updated).To(Equal(int32(1)))
+
+		// Soak time elapsed: broad ring can be updated
+		plan, err = controllers.ReviseRolloutStatus(context.TODO(), c, profileScope, hash,
+			updated, &libsveltosset.Set{}, now.Add(2*time.Hour))
+		Expect(err).To(BeNil())
+		Expect(controllers.RolloutPlanCanUpdate(plan, &productionRef, false)).To(BeTrue())
+		Expect(rollout.Rings[0].Phase).To(Equal(configv1beta1.RolloutRingPhaseCompleted))
+		Expect(rollout.Rings[1].Phase).To(Equal(configv1beta1.RolloutRingPhaseProgressing))
+
+		// Spec change resets rollout
+		_, err = controllers.ReviseRolloutStatus(context.TODO(), c, profileScope, []byte(randomString()),
+			&libsveltosset.Set{}, &libsveltosset.Set{}, now)
+		Expect(err).To(BeNil())
+		Expect(clusterProfile.Status.Rollout.Rings[0].Phase).To(Equal(configv1beta1.RolloutRingPhaseProgressing))
+		Expect(clusterProfile.Status.Rollout.Rings[1].Phase).To(Equal(configv1beta1.RolloutRingPhasePending))
+	})
+
+	It("exceedsMaxFailures considers MaxFailures as absolute number or percentage", func() {
+		maxFailures := intstr.FromString("20%")
+		ring := &configv1beta1.RolloutRing{Name: randomString(), MaxFailures: &maxFailures}
+
+		Expect(controllers.ExceedsMaxFailures(ring,
+			&configv1beta1.RolloutRingStatus{Clusters: 10, Failed: 2})).To(BeFalse())
+		Expect(controllers.ExceedsMaxFailures(ring,
+			&configv1beta1.RolloutRingStatus{Clusters: 10, Failed: 3})).To(BeTrue())
+
+		maxFailures = intstr.FromInt32(0)
+		Expect(controllers.ExceedsMaxFailures(ring,
+			&configv1beta1.RolloutRingStatus{Clusters: 10, Failed: 1})).To(BeTrue())
+
+		ring.MaxFailures = nil
+		Expect(controllers.ExceedsMaxFailures(ring,
+			&configv1beta1.RolloutRingStatus{Clusters: 10, Failed: 10})).To(BeFalse())
+	})
+})
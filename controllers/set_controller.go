@@ -47,6 +47,7 @@ type SetReconciler struct {
 	Scheme               *runtime.Scheme
 	ConcurrentReconciles int
 	Logger               logr.Logger
+	RequeuePolicy        RequeuePolicy
 
 	// use a Mutex to update Map as MaxConcurrentReconciles is higher than one
 	Mux sync.Mutex
@@ -147,14 +148,14 @@ func (r *SetReconciler) reconcileNormal(
 	matchingCluster, err := getMatchingClusters(ctx, r.Client, setScope.Set.GetNamespace(),
 		setScope.GetSelector(), setScope.GetSpec().ClusterRefs, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 	}
 
 	setScope.SetMatchingClusterRefs(matchingCluster)
 
 	err = selectClusters(ctx, r.Client, setScope, logger)
 	if err != nil {
-		return reconcile.Result{Requeue: true, RequeueAfter: normalRequeueAfter}
+		return reconcile.Result{Requeue: true, RequeueAfter: r.RequeuePolicy.normalRequeueAfter()}
 	}
 
 	r.updateMaps(setScope)
@@ -169,6 +170,7 @@ func (r *SetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&libsveltosv1beta1.Set{}).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: r.ConcurrentReconciles,
+			RateLimiter:             r.RequeuePolicy.rateLimiter(),
 		}).
 		Watches(&libsveltosv1beta1.SveltosCluster{},
 			handler.EnqueueRequestsFromMapFunc(r.requeueSetForSveltosCluster),
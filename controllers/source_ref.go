@@ -0,0 +1,170 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+	libsveltostemplate "github.com/projectsveltos/libsveltos/lib/template"
+)
+
+// ClusterProfile/Profile SourceRef is handled by the Resources feature as an extra PolicyRef (remote
+// deployment, Adopt conflict policy). Being a Flux Source, its artifact revision is part of the
+// Resources feature hash, so resources are re-deployed on every new revision. The revision resources
+// were last successfully deployed from is reported in ClusterSummary and ClusterConfiguration Status.
+
+// getSourceRefPolicyRef returns the PolicyRef corresponding to ClusterProfileSpec.SourceRef.
+// Nil is returned if SourceRef is not set.
+func getSourceRefPolicyRef(clusterSummary *configv1beta1.ClusterSummary) *configv1beta1.PolicyRef {
+	sourceRef := clusterSummary.Spec.ClusterProfileSpec.SourceRef
+	if sourceRef == nil {
+		return nil
+	}
+
+	kind := sourceRef.Kind
+	if kind == "" {
+		kind = sourcev1.GitRepositoryKind
+	}
+
+	return &configv1beta1.PolicyRef{
+		Namespace:      sourceRef.Namespace,
+		Name:           sourceRef.Name,
+		Kind:           kind,
+		Path:           sourceRef.Path,
+		DeploymentType: configv1beta1.DeploymentTypeRemote,
+		ConflictPolicy: configv1beta1.ConflictPolicyAdopt,
+	}
+}
+
+// getSourceRevision returns the current artifact revision of the ClusterProfileSpec.SourceRef.
+// An empty revision is returned if SourceRef is not set, or the Source does not exist or has no
+// artifact yet.
+func getSourceRevision(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	logger logr.Logger) (string, error) {
+
+	reference := getSourceRefPolicyRef(clusterSummary)
+	if reference == nil {
+		return "", nil
+	}
+
+	namespace := libsveltostemplate.GetReferenceResourceNamespace(clusterSummary.Namespace, reference.Namespace)
+	name, err := libsveltostemplate.GetReferenceResourceName(clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, string(clusterSummary.Spec.ClusterType), reference.Name)
+	if err != nil {
+		return "", err
+	}
+
+	source, err := getSource(ctx, c, namespace, name, reference.Kind)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.V(logs.LogInfo).Info(fmt.Sprintf("%s %s/%s does not exist yet",
+				reference.Kind, namespace, name))
+			return "", nil
+		}
+		return "", err
+	}
+	if source == nil {
+		return "", nil
+	}
+
+	s, ok := source.(sourcev1.Source)
+	if !ok || s.GetArtifact() == nil {
+		return "", nil
+	}
+	return s.GetArtifact().Revision, nil
+}
+
+// updateSourceRevision reports, in ClusterSummary and ClusterConfiguration Status, the SourceRef
+// revision resources were deployed from.
+// No action in DryRun and AssessOnly modes.
+func updateSourceRevision(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	profileOwnerRef *metav1.OwnerReference, revision string, logger logr.Logger) error {
+
+	if configv1beta1.IsDryRunSyncMode(clusterSummary.Spec.ClusterProfileSpec.SyncMode) ||
+		isAssessOnlyMode(clusterSummary) {
+
+		return nil
+	}
+
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("source revision %q", revision))
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		currentClusterSummary := &configv1beta1.ClusterSummary{}
+		err := c.Get(ctx,
+			types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name},
+			currentClusterSummary)
+		if err != nil {
+			return err
+		}
+
+		if currentClusterSummary.Status.SourceRevision == revision {
+			return nil
+		}
+		currentClusterSummary.Status.SourceRevision = revision
+		return c.Status().Update(ctx, currentClusterSummary)
+	})
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{}
+		err := c.Get(ctx,
+			types.NamespacedName{
+				Namespace: clusterSummary.Spec.ClusterNamespace,
+				Name:      getClusterConfigurationName(clusterSummary.Spec.ClusterName, clusterSummary.Spec.ClusterType),
+			},
+			clusterConfiguration)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		index, err := configv1beta1.GetClusterConfigurationSectionIndex(clusterConfiguration, profileOwnerRef.Kind,
+			profileOwnerRef.Name)
+		if err != nil {
+			return err
+		}
+
+		if profileOwnerRef.Kind == configv1beta1.ClusterProfileKind {
+			if clusterConfiguration.Status.ClusterProfileResources[index].SourceRevision == revision {
+				return nil
+			}
+			clusterConfiguration.Status.ClusterProfileResources[index].SourceRevision = revision
+		} else {
+			if clusterConfiguration.Status.ProfileResources[index].SourceRevision == revision {
+				return nil
+			}
+			clusterConfiguration.Status.ProfileResources[index].SourceRevision = revision
+		}
+
+		return c.Status().Update(ctx, clusterConfiguration)
+	})
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("SourceRef", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+	var gitRepository *sourcev1.GitRepository
+
+	BeforeEach(func() {
+		gitRepository = &sourcev1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Status: sourcev1.GitRepositoryStatus{
+				Artifact: &sourcev1.Artifact{
+					Revision: randomString(),
+				},
+			},
+		}
+
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: randomString(),
+				ClusterName:      randomString(),
+				ClusterType:      libsveltosv1beta1.ClusterTypeCapi,
+				ClusterProfileSpec: configv1beta1.Spec{
+					PolicyRefs: []configv1beta1.PolicyRef{
+						{Namespace: randomString(), Name: randomString(), Kind: "ConfigMap"},
+					},
+					SourceRef: &configv1beta1.SourceRef{
+						Namespace: gitRepository.Namespace,
+						Name:      gitRepository.Name,
+						Path:      randomString(),
+					},
+				},
+			},
+		}
+	})
+
+	It("getResourceRefs includes SourceRef as a PolicyRef", func() {
+		refs := controllers.GetResourceRefs(clusterSummary)
+		Expect(len(refs)).To(Equal(2))
+		Expect(refs[0]).To(Equal(clusterSummary.Spec.ClusterProfileSpec.PolicyRefs[0]))
+		Expect(refs[1].Kind).To(Equal(sourcev1.GitRepositoryKind))
+		Expect(refs[1].Namespace).To(Equal(gitRepository.Namespace))
+		Expect(refs[1].Name).To(Equal(gitRepository.Name))
+		Expect(refs[1].Path).To(Equal(clusterSummary.Spec.ClusterProfileSpec.SourceRef.Path))
+		Expect(refs[1].DeploymentType).To(Equal(configv1beta1.DeploymentTypeRemote))
+
+		clusterSummary.Spec.ClusterProfileSpec.SourceRef = nil
+		Expect(controllers.GetSourceRefPolicyRef(clusterSummary)).To(BeNil())
+		Expect(controllers.GetResourceRefs(clusterSummary)).To(Equal(clusterSummary.Spec.ClusterProfileSpec.PolicyRefs))
+	})
+
+	It("getSourceRevision returns SourceRef artifact revision", func() {
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		revision, err := controllers.GetSourceRevision(context.TODO(), c, clusterSummary, logger)
+		Expect(err).To(BeNil())
+		Expect(revision).To(BeEmpty())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(gitRepository).Build()
+		revision, err = controllers.GetSourceRevision(context.TODO(), c, clusterSummary, logger)
+		Expect(err).To(BeNil())
+		Expect(revision).To(Equal(gitRepository.Status.Artifact.Revision))
+	})
+
+	It("updateSourceRevision reports revision in ClusterSummary and ClusterConfiguration Status", func() {
+		clusterProfileName := randomString()
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterSummary.Spec.ClusterNamespace,
+				Name: controllers.GetClusterConfigurationName(clusterSummary.Spec.ClusterName,
+					clusterSummary.Spec.ClusterType),
+			},
+			Status: configv1beta1.ClusterConfigurationStatus{
+				ClusterProfileResources: []configv1beta1.ClusterProfileResource{
+					{ClusterProfileName: clusterProfileName},
+				},
+			},
+		}
+
+		initObjects := []client.Object{clusterSummary, clusterConfiguration}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(initObjects...).
+			WithObjects(initObjects...).Build()
+
+		profileOwnerRef := &metav1.OwnerReference{
+			Kind: configv1beta1.ClusterProfileKind,
+			Name: clusterProfileName,
+		}
+		revision := randomString()
+		Expect(controllers.UpdateSourceRevision(context.TODO(), c, clusterSummary, profileOwnerRef, revision,
+			textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
+
+		currentClusterSummary := &configv1beta1.ClusterSummary{}
+		Expect(c.Get(context.TODO(),
+			types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name},
+			currentClusterSummary)).To(Succeed())
+		Expect(currentClusterSummary.Status.SourceRevision).To(Equal(revision))
+
+		currentClusterConfiguration := &configv1beta1.ClusterConfiguration{}
+		Expect(c.Get(context.TODO(),
+			types.NamespacedName{Namespace: clusterConfiguration.Namespace, Name: clusterConfiguration.Name},
+			currentClusterConfiguration)).To(Succeed())
+		Expect(currentClusterConfiguration.Status.ClusterProfileResources[0].SourceRevision).To(Equal(revision))
+	})
+})
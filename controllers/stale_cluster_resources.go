@@ -0,0 +1,230 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	toolscache "k8s.io/client-go/tools/cache"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// ClusterConfigurations, ClusterReports and ClusterSummaries are created for each matching cluster.
+// Those are normally removed when ClusterProfiles/Profiles are reconciled after a cluster is deleted.
+// StaleClusterResourcesCollector removes them as soon as a cluster is deleted and periodically looks
+// for instances orphaned while controller was down.
+
+const (
+	staleClusterResourcesCollectionInterval = 10 * time.Minute
+)
+
+// StaleClusterResourcesCollector removes ClusterConfigurations, ClusterReports and ClusterSummaries
+// referencing a SveltosCluster/Cluster which does not exist anymore.
+type StaleClusterResourcesCollector struct {
+	mgr    ctrl.Manager
+	logger logr.Logger
+}
+
+// NewStaleClusterResourcesCollector returns a StaleClusterResourcesCollector. It must be added to
+// the manager. Deletion of ClusterAPI Clusters is watched only once WatchForCAPI is invoked.
+func NewStaleClusterResourcesCollector(mgr ctrl.Manager, logger logr.Logger) *StaleClusterResourcesCollector {
+	return &StaleClusterResourcesCollector{mgr: mgr, logger: logger}
+}
+
+// NeedLeaderElection returns true so that only the leader removes stale resources
+func (s *StaleClusterResourcesCollector) NeedLeaderElection() bool {
+	return true
+}
+
+func (s *StaleClusterResourcesCollector) Start(ctx context.Context) error {
+	if err := s.watchClusterDeletion(ctx, &libsveltosv1beta1.SveltosCluster{},
+		libsveltosv1beta1.ClusterTypeSveltos); err != nil {
+		return err
+	}
+
+	for {
+		err := removeOrphanedClusterResources(ctx, s.mgr.GetAPIReader(), s.mgr.GetClient(), s.logger)
+		if err != nil {
+			s.logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to remove orphaned cluster resources: %v", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(staleClusterResourcesCollectionInterval):
+		}
+	}
+}
+
+// WatchForCAPI starts watching ClusterAPI Cluster deletions
+func (s *StaleClusterResourcesCollector) WatchForCAPI(_ ctrl.Manager, _ controller.Controller) error {
+	return s.watchClusterDeletion(context.TODO(), &clusterv1.Cluster{}, libsveltosv1beta1.ClusterTypeCapi)
+}
+
+// GetController returns nil. StaleClusterResourcesCollector does not use a controller to watch
+// cluster deletions.
+func (s *StaleClusterResourcesCollector) GetController() controller.Controller {
+	return nil
+}
+
+func (s *StaleClusterResourcesCollector) watchClusterDeletion(ctx context.Context, cluster client.Object,
+	clusterType libsveltosv1beta1.ClusterType) error {
+
+	informer, err := s.mgr.GetCache().GetInformer(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	_, err = informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			deleted, ok := obj.(client.Object)
+			if !ok {
+				return
+			}
+			go func() {
+				err := removeStaleClusterResources(context.Background(), s.mgr.GetClient(),
+					deleted.GetNamespace(), deleted.GetName(), clusterType, s.logger)
+				if err != nil {
+					s.logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to remove resources for deleted cluster %s/%s: %v",
+						deleted.GetNamespace(), deleted.GetName(), err))
+				}
+			}()
+		},
+	})
+
+	return err
+}
+
+// removeStaleClusterResources removes ClusterConfigurations, ClusterReports and ClusterSummaries
+// created for a cluster, if such cluster does not exist anymore.
+func removeStaleClusterResources(ctx context.Context, c client.Client, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType, logger logr.Logger) error {
+
+	_, err := getCluster(ctx, c, clusterNamespace, clusterName, clusterType)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		if meta.IsNoMatchError(err) {
+			// Cluster kind is not installed. Nothing can be said about cluster existence
+			return nil
+		}
+		return err
+	}
+
+	logger = logger.WithValues("cluster", fmt.Sprintf("%s:%s/%s", clusterType, clusterNamespace, clusterName))
+	logger.V(logs.LogDebug).Info("removing resources for cluster not existing anymore")
+
+	listOptions := []client.ListOption{
+		client.InNamespace(clusterNamespace),
+		client.MatchingLabels{
+			configv1beta1.ClusterNameLabel: clusterName,
+			configv1beta1.ClusterTypeLabel: string(clusterType),
+		},
+	}
+
+	lists := []client.ObjectList{
+		&configv1beta1.ClusterSummaryList{},
+		&configv1beta1.ClusterReportList{},
+		&configv1beta1.ClusterConfigurationList{},
+	}
+
+	for i := range lists {
+		if err := c.List(ctx, lists[i], listOptions...); err != nil {
+			return err
+		}
+
+		items, err := meta.ExtractList(lists[i])
+		if err != nil {
+			return err
+		}
+
+		for j := range items {
+			obj, ok := items[j].(client.Object)
+			if !ok {
+				continue
+			}
+			if !obj.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+			logger.V(logs.LogDebug).Info(fmt.Sprintf("deleting %T %s", obj, obj.GetName()))
+			if err := c.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeOrphanedClusterResources finds all clusters referenced by existing ClusterConfigurations,
+// ClusterReports and ClusterSummaries and removes those instances if cluster does not exist anymore.
+// Instances are listed, using reader, in pages so memory stays bounded no matter how many exist.
+func removeOrphanedClusterResources(ctx context.Context, reader client.Reader, c client.Client,
+	logger logr.Logger) error {
+
+	type clusterInfo struct {
+		namespace   string
+		name        string
+		clusterType libsveltosv1beta1.ClusterType
+	}
+
+	clusters := make(map[clusterInfo]bool)
+
+	lists := []client.ObjectList{
+		&configv1beta1.ClusterSummaryList{},
+		&configv1beta1.ClusterReportList{},
+		&configv1beta1.ClusterConfigurationList{},
+	}
+
+	for i := range lists {
+		err := forEachListItem(ctx, reader, lists[i], func(obj client.Object) error {
+			clusters[clusterInfo{
+				namespace:   obj.GetNamespace(),
+				name:        obj.GetLabels()[configv1beta1.ClusterNameLabel],
+				clusterType: libsveltosv1beta1.ClusterType(obj.GetLabels()[configv1beta1.ClusterTypeLabel]),
+			}] = true
+			return nil
+		}, client.HasLabels{configv1beta1.ClusterNameLabel, configv1beta1.ClusterTypeLabel})
+		if err != nil {
+			return err
+		}
+	}
+
+	for k := range clusters {
+		if err := removeStaleClusterResources(ctx, c, k.namespace, k.name, k.clusterType, logger); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
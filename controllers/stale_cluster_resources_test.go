@@ -0,0 +1,120 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Stale cluster resources", func() {
+	var namespace string
+
+	BeforeEach(func() {
+		namespace = randomString()
+	})
+
+	getClusterResources := func(clusterName string) []client.Object {
+		labels := map[string]string{
+			configv1beta1.ClusterNameLabel: clusterName,
+			configv1beta1.ClusterTypeLabel: string(libsveltosv1beta1.ClusterTypeCapi),
+		}
+
+		return []client.Object{
+			&configv1beta1.ClusterConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString(), Labels: labels},
+			},
+			&configv1beta1.ClusterReport{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString(), Labels: labels},
+			},
+			&configv1beta1.ClusterSummary{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString(), Labels: labels},
+			},
+		}
+	}
+
+	isPresent := func(c client.Client, obj client.Object) bool {
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, obj)
+		if err != nil {
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			return false
+		}
+		return true
+	}
+
+	It("removeStaleClusterResources removes resources only if cluster does not exist", func() {
+		cluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+		}
+
+		existing := getClusterResources(cluster.Name)
+		initObjects := append([]client.Object{cluster}, existing...)
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		Expect(controllers.RemoveStaleClusterResources(context.TODO(), c, namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeCapi, logger)).To(Succeed())
+		for i := range existing {
+			Expect(isPresent(c, existing[i])).To(BeTrue())
+		}
+
+		Expect(c.Delete(context.TODO(), cluster)).To(Succeed())
+
+		Expect(controllers.RemoveStaleClusterResources(context.TODO(), c, namespace, cluster.Name,
+			libsveltosv1beta1.ClusterTypeCapi, logger)).To(Succeed())
+		for i := range existing {
+			Expect(isPresent(c, existing[i])).To(BeFalse())
+		}
+	})
+
+	It("removeOrphanedClusterResources removes resources for all clusters not existing anymore", func() {
+		cluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+		}
+
+		existing := getClusterResources(cluster.Name)
+		orphaned := getClusterResources(randomString())
+
+		initObjects := append([]client.Object{cluster}, existing...)
+		initObjects = append(initObjects, orphaned...)
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(initObjects...).Build()
+
+		Expect(controllers.RemoveOrphanedClusterResources(context.TODO(), c, c,
+			textlogger.NewLogger(textlogger.NewConfig()))).To(Succeed())
+
+		for i := range existing {
+			Expect(isPresent(c, existing[i])).To(BeTrue())
+		}
+		for i := range orphaned {
+			Expect(isPresent(c, orphaned[i])).To(BeFalse())
+		}
+	})
+})
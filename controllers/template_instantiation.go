@@ -30,8 +30,8 @@ import (
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
-	"github.com/projectsveltos/libsveltos/lib/clusterproxy"
 	"github.com/projectsveltos/libsveltos/lib/funcmap"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
 	"github.com/projectsveltos/libsveltos/lib/utils"
@@ -109,7 +109,7 @@ func fecthClusterObjects(ctx context.Context, config *rest.Config, c client.Clie
 	logger.V(logs.LogInfo).Info(fmt.Sprintf("Fetch cluster %s: %s/%s",
 		clusterType, clusterNamespace, clusterName))
 
-	genericCluster, err := clusterproxy.GetCluster(ctx, c, clusterNamespace, clusterName, clusterType)
+	genericCluster, err := getCluster(ctx, c, clusterNamespace, clusterName, clusterType)
 	if err != nil {
 		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to fetch cluster %v", err))
 		return nil, err
@@ -179,13 +179,14 @@ func instantiateTemplateValues(ctx context.Context, config *rest.Config, c clien
 	templateName := getTemplateName(clusterNamespace, clusterName, requestorName)
 	tmpl, err := template.New(templateName).Option("missingkey=error").Funcs(funcMap).Parse(values)
 	if err != nil {
-		return "", err
+		return "", withMessageCode(configv1beta1.MessageCodeTemplateFailed, err)
 	}
 
 	var buffer bytes.Buffer
 
 	if err := tmpl.Execute(&buffer, objects); err != nil {
-		return "", errors.Wrapf(err, "error executing template %q", values)
+		return "", withMessageCode(configv1beta1.MessageCodeTemplateFailed,
+			errors.Wrapf(err, "error executing template %q", values))
 	}
 	instantiatedValues := buffer.String()
 
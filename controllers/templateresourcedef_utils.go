@@ -104,6 +104,12 @@ func collectTemplateResourceRefs(ctx context.Context, clusterSummary *configv1be
 			return nil, err
 		}
 
+		err = validateAdminCanGetTemplateResource(ctx, getManagementClusterClient(), clusterSummary,
+			ref.Resource.GroupVersionKind(), ref.Resource.Namespace, ref.Resource.Name)
+		if err != nil {
+			return nil, err
+		}
+
 		dr, err := utils.GetDynamicResourceInterface(restConfig, ref.Resource.GroupVersionKind(), ref.Resource.Namespace)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,170 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1b2 "github.com/fluxcd/source-controller/api/v1beta2"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// ClusterProfiles/Profiles created by a tenant admin carry the admin ServiceAccount in the
+// ServiceAccountNameLabel/ServiceAccountNamespaceLabel labels. addon-controller reads referenced
+// ConfigMaps/Secrets with its own permissions, so a tenant could otherwise reference, and have
+// deployed into its managed clusters, resources owned by other tenants.
+// Before using a referenced resource, a SubjectAccessReview verifies the tenant admin is allowed
+// to get it. Tenants are granted access to their namespaces with regular RBAC.
+// Optionally, referenced ConfigMaps/Secrets must also opt-in, carrying labels matching a
+// selector configured at startup, before their content is used.
+
+var (
+	referencedResourceSelector labels.Selector
+)
+
+// SetReferencedResourceSelector sets the label selector referenced ConfigMaps/Secrets must match
+// for their content to be deployed (for instance projectsveltos.io/policy=true).
+// Empty selector disables the check.
+func SetReferencedResourceSelector(selector string) error {
+	if selector == "" {
+		referencedResourceSelector = nil
+		return nil
+	}
+
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return fmt.Errorf("invalid referenced resource selector %q: %w", selector, err)
+	}
+	referencedResourceSelector = parsed
+	return nil
+}
+
+// getReferencedResource returns the resource for a referenced kind
+func getReferencedResource(kind string) schema.GroupResource {
+	switch kind {
+	case string(libsveltosv1beta1.ConfigMapReferencedResourceKind):
+		return schema.GroupResource{Resource: "configmaps"}
+	case string(libsveltosv1beta1.SecretReferencedResourceKind):
+		return schema.GroupResource{Resource: "secrets"}
+	case sourcev1.GitRepositoryKind:
+		return schema.GroupResource{Group: sourcev1.GroupVersion.Group, Resource: "gitrepositories"}
+	case sourcev1b2.OCIRepositoryKind:
+		return schema.GroupResource{Group: sourcev1b2.GroupVersion.Group, Resource: "ocirepositories"}
+	case sourcev1b2.BucketKind:
+		return schema.GroupResource{Group: sourcev1b2.GroupVersion.Group, Resource: "buckets"}
+	}
+	return schema.GroupResource{}
+}
+
+// validateAdminCanGetReference returns an error if ClusterSummary was created by a tenant admin
+// who is not allowed to get the referenced resource. Nothing is verified if there is no tenant admin.
+// Must be invoked before any referenced resource (PolicyRefs, helm ValuesFrom, KustomizationRefs,
+// registry credentials) is read.
+func validateAdminCanGetReference(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, kind, namespace, name string) error {
+
+	return validateAdminCanGet(ctx, c, clusterSummary, kind, getReferencedResource(kind), namespace, name)
+}
+
+// validateAdminCanGetTemplateResource returns an error if ClusterSummary was created by a tenant admin
+// who is not allowed to get a TemplateResourceRefs resource. Resource is found using c RESTMapper.
+func validateAdminCanGetTemplateResource(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, gvk schema.GroupVersionKind, namespace, name string) error {
+
+	if _, adminName := getClusterSummaryAdmin(clusterSummary); adminName == "" {
+		return nil
+	}
+
+	mapping, err := c.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return err
+	}
+
+	return validateAdminCanGet(ctx, c, clusterSummary, gvk.Kind, mapping.Resource.GroupResource(), namespace, name)
+}
+
+func validateAdminCanGet(ctx context.Context, c client.Client, clusterSummary *configv1beta1.ClusterSummary,
+	kind string, resource schema.GroupResource, namespace, name string) error {
+
+	adminNamespace, adminName := getClusterSummaryAdmin(clusterSummary)
+	if adminName == "" {
+		return nil
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User: fmt.Sprintf("system:serviceaccount:%s:%s", adminNamespace, adminName),
+			Groups: []string{
+				"system:serviceaccounts",
+				fmt.Sprintf("system:serviceaccounts:%s", adminNamespace),
+				"system:authenticated",
+			},
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "get",
+				Group:     resource.Group,
+				Resource:  resource.Resource,
+				Name:      name,
+			},
+		},
+	}
+
+	if err := c.Create(ctx, review); err != nil {
+		return err
+	}
+
+	if !review.Status.Allowed {
+		// Denial won't go away retrying. Tenant admin must be granted access or reference changed.
+		return &NonRetriableError{
+			Message: fmt.Sprintf("tenant admin %s/%s is not allowed to get %s %s/%s",
+				adminNamespace, adminName, kind, namespace, name),
+		}
+	}
+
+	return nil
+}
+
+// validateReferenceOptIn returns an error if a referenced resource selector is configured and
+// the referenced ConfigMap/Secret labels do not match it.
+func validateReferenceOptIn(object client.Object, kind string) error {
+	if referencedResourceSelector == nil || object == nil {
+		return nil
+	}
+
+	if kind != string(libsveltosv1beta1.ConfigMapReferencedResourceKind) &&
+		kind != string(libsveltosv1beta1.SecretReferencedResourceKind) {
+
+		return nil
+	}
+
+	if !referencedResourceSelector.Matches(labels.Set(object.GetLabels())) {
+		return &NonRetriableError{
+			Message: fmt.Sprintf("referenced %s %s/%s does not match required labels %q",
+				kind, object.GetNamespace(), object.GetName(), referencedResourceSelector.String()),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("Tenant authorization", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+	var tenantNamespace string
+	var reviews []authorizationv1.SubjectAccessReview
+	var c client.Client
+
+	BeforeEach(func() {
+		tenantNamespace = randomString()
+		reviews = nil
+
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+			},
+		}
+
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+
+		// Tenant admin is only allowed to get resources in its own namespace
+		c = fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				reviews = append(reviews, *review)
+				review.Status.Allowed = review.Spec.ResourceAttributes.Namespace == tenantNamespace
+				return nil
+			},
+		}).Build()
+	})
+
+	It("validateAdminCanGetReference does nothing when there is no tenant admin", func() {
+		Expect(controllers.ValidateAdminCanGetReference(context.TODO(), c, clusterSummary,
+			string(libsveltosv1beta1.SecretReferencedResourceKind), randomString(), randomString())).To(Succeed())
+		Expect(reviews).To(BeEmpty())
+	})
+
+	It("validateAdminCanGetReference verifies tenant admin can get referenced resource", func() {
+		adminName := randomString()
+		clusterSummary.Labels = map[string]string{
+			libsveltosv1beta1.ServiceAccountNamespaceLabel: tenantNamespace,
+			libsveltosv1beta1.ServiceAccountNameLabel:      adminName,
+		}
+
+		name := randomString()
+		Expect(controllers.ValidateAdminCanGetReference(context.TODO(), c, clusterSummary,
+			string(libsveltosv1beta1.SecretReferencedResourceKind), tenantNamespace, name)).To(Succeed())
+		Expect(reviews).To(HaveLen(1))
+		Expect(reviews[0].Spec.User).To(Equal(fmt.Sprintf("system:serviceaccount:%s:%s", tenantNamespace, adminName)))
+		Expect(reviews[0].Spec.ResourceAttributes.Verb).To(Equal("get"))
+		Expect(reviews[0].Spec.ResourceAttributes.Resource).To(Equal("secrets"))
+		Expect(reviews[0].Spec.ResourceAttributes.Name).To(Equal(name))
+
+		// Resources in other tenants' namespaces cannot be referenced
+		err := controllers.ValidateAdminCanGetReference(context.TODO(), c, clusterSummary,
+			string(libsveltosv1beta1.ConfigMapReferencedResourceKind), randomString(), randomString())
+		Expect(err).To(HaveOccurred())
+		var nonRetriableError *controllers.NonRetriableError
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+		Expect(reviews).To(HaveLen(2))
+		Expect(reviews[1].Spec.ResourceAttributes.Resource).To(Equal("configmaps"))
+	})
+
+	It("helm ValuesFrom and KustomizationRefs are verified before being read", func() {
+		clusterSummary.Labels = map[string]string{
+			libsveltosv1beta1.ServiceAccountNamespaceLabel: tenantNamespace,
+			libsveltosv1beta1.ServiceAccountNameLabel:      randomString(),
+		}
+
+		// Secret is in another tenant's namespace
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: randomString(),
+				Name:      randomString(),
+			},
+			Type: libsveltosv1beta1.ClusterProfileSecretType,
+			Data: map[string][]byte{"values": []byte("password: secret")},
+		}
+		Expect(c.Create(context.TODO(), secret)).To(Succeed())
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		var nonRetriableError *controllers.NonRetriableError
+
+		data, _, err := controllers.GetValuesFromResource(context.TODO(), c, clusterSummary,
+			&configv1beta1.ValueFrom{
+				Kind:      string(libsveltosv1beta1.SecretReferencedResourceKind),
+				Namespace: secret.Namespace,
+				Name:      secret.Name,
+			}, logger)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+		Expect(data).To(BeNil())
+
+		_, err = controllers.PrepareFileSystem(context.TODO(), c,
+			&configv1beta1.KustomizationRef{
+				Kind:      string(libsveltosv1beta1.SecretReferencedResourceKind),
+				Namespace: secret.Namespace,
+				Name:      secret.Name,
+			}, clusterSummary, logger)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+
+		Expect(reviews).To(HaveLen(2))
+		Expect(reviews[0].Spec.ResourceAttributes.Namespace).To(Equal(secret.Namespace))
+		Expect(reviews[1].Spec.ResourceAttributes.Resource).To(Equal("secrets"))
+	})
+
+	It("TemplateResourceRefs and registry credentials are verified before being read", func() {
+		clusterSummary.Labels = map[string]string{
+			libsveltosv1beta1.ServiceAccountNamespaceLabel: tenantNamespace,
+			libsveltosv1beta1.ServiceAccountNameLabel:      randomString(),
+		}
+
+		otherNamespace := randomString()
+		clusterSummary.Spec.ClusterProfileSpec.TemplateResourceRefs = []configv1beta1.TemplateResourceRef{
+			{
+				Resource: corev1.ObjectReference{
+					APIVersion: "v1",
+					Kind:       "ConfigMap",
+					Namespace:  otherNamespace,
+					Name:       randomString(),
+				},
+				Identifier: randomString(),
+			},
+		}
+
+		controllers.SetManagementClusterAccess(c, nil)
+		defer controllers.SetManagementClusterAccess(testEnv.Client, testEnv.Config)
+
+		var nonRetriableError *controllers.NonRetriableError
+		_, err := controllers.CollectTemplateResourceRefs(context.TODO(), clusterSummary)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+		Expect(reviews).To(HaveLen(1))
+		Expect(reviews[0].Spec.ResourceAttributes.Namespace).To(Equal(otherNamespace))
+		Expect(reviews[0].Spec.ResourceAttributes.Resource).To(Equal("configmaps"))
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: otherNamespace,
+				Name:      randomString(),
+			},
+			Data: map[string][]byte{"ca.crt": []byte(randomString())},
+		}
+		Expect(c.Create(context.TODO(), secret)).To(Succeed())
+
+		credentialsPath, caPath, err := controllers.GetCredentialsAndCAFiles(context.TODO(), c, clusterSummary,
+			randomString(), &configv1beta1.HelmChart{
+				RegistryCredentialsConfig: &configv1beta1.RegistryCredentialsConfig{
+					CASecretRef: &corev1.SecretReference{
+						Namespace: secret.Namespace,
+						Name:      secret.Name,
+					},
+				},
+			})
+		Expect(err).To(HaveOccurred())
+		Expect(errors.As(err, &nonRetriableError)).To(BeTrue())
+		Expect(credentialsPath).To(BeEmpty())
+		Expect(caPath).To(BeEmpty())
+		Expect(reviews).To(HaveLen(2))
+		Expect(reviews[1].Spec.ResourceAttributes.Namespace).To(Equal(secret.Namespace))
+		Expect(reviews[1].Spec.ResourceAttributes.Resource).To(Equal("secrets"))
+	})
+
+	It("collectReferencedObjects requires referenced ConfigMaps/Secrets to match referenced resource selector", func() {
+		Expect(controllers.SetReferencedResourceSelector("projectsveltos.io/policy=true")).To(Succeed())
+		defer func() {
+			Expect(controllers.SetReferencedResourceSelector("")).To(Succeed())
+		}()
+
+		Expect(controllers.SetReferencedResourceSelector("projectsveltos.io/policy in")).ToNot(Succeed())
+
+		optedIn := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: tenantNamespace,
+				Name:      randomString(),
+				Labels:    map[string]string{"projectsveltos.io/policy": "true"},
+			},
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: tenantNamespace,
+				Name:      randomString(),
+			},
+			Type: libsveltosv1beta1.ClusterProfileSecretType,
+		}
+		Expect(c.Create(context.TODO(), optedIn)).To(Succeed())
+		Expect(c.Create(context.TODO(), secret)).To(Succeed())
+
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+		_, remote, err := controllers.CollectReferencedObjects(context.TODO(), c, clusterSummary,
+			[]configv1beta1.PolicyRef{
+				{
+					Kind:      string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+					Namespace: optedIn.Namespace,
+					Name:      optedIn.Name,
+				},
+			}, logger)
+		Expect(err).To(BeNil())
+		Expect(remote).To(HaveLen(1))
+
+		// Secret does not carry the label. Its content cannot be deployed
+		_, _, err = controllers.CollectReferencedObjects(context.TODO(), c, clusterSummary,
+			[]configv1beta1.PolicyRef{
+				{
+					Kind:      string(libsveltosv1beta1.SecretReferencedResourceKind),
+					Namespace: secret.Namespace,
+					Name:      secret.Name,
+				},
+			}, logger)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("does not match required labels"))
+	})
+})
@@ -0,0 +1,145 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// When ClusterProfile/Profile Spec.TenantRef is set, add-ons are deployed in (and removed from)
+// the managed cluster impersonating the tenant ServiceAccount. The rest config, client and
+// kubeconfig used for that are built as usual (eventually for the tenant admin) and then
+// configured to impersonate the tenant ServiceAccount.
+// Sveltos own resources (ResourceSummary, Reloader) are always managed without impersonation.
+
+// getClusterSummaryTenant returns the ServiceAccount to impersonate in the managed cluster.
+// Empty name is returned if no impersonation is requested.
+func getClusterSummaryTenant(clusterSummary *configv1beta1.ClusterSummary) (namespace, name string) {
+	tenantRef := clusterSummary.Spec.ClusterProfileSpec.TenantRef
+	if tenantRef == nil {
+		return "", ""
+	}
+
+	return tenantRef.ServiceAccountNamespace, tenantRef.ServiceAccountName
+}
+
+// getTenantUserName returns the user name to impersonate for clusterSummary. Empty if no
+// impersonation is requested.
+func getTenantUserName(clusterSummary *configv1beta1.ClusterSummary) string {
+	namespace, name := getClusterSummaryTenant(clusterSummary)
+	if name == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name)
+}
+
+// impersonateTenant configures restConfig to impersonate the tenant ServiceAccount, if any.
+func impersonateTenant(restConfig *rest.Config, clusterSummary *configv1beta1.ClusterSummary) *rest.Config {
+	userName := getTenantUserName(clusterSummary)
+	if userName == "" {
+		return restConfig
+	}
+
+	restConfig = rest.CopyConfig(restConfig)
+	restConfig.Impersonate = rest.ImpersonationConfig{
+		UserName: userName,
+	}
+	return restConfig
+}
+
+// getClusterSummaryRestConfig returns the rest config used to deploy ClusterSummary add-ons in
+// the managed cluster.
+func getClusterSummaryRestConfig(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, logger logr.Logger) (*rest.Config, error) {
+
+	adminNamespace, adminName := getClusterSummaryAdmin(clusterSummary)
+	remoteRestConfig, err := getKubernetesRestConfig(ctx, c, clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return impersonateTenant(remoteRestConfig, clusterSummary), nil
+}
+
+// getClusterSummaryClient returns the client used to deploy ClusterSummary add-ons in
+// the managed cluster.
+func getClusterSummaryClient(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, logger logr.Logger) (client.Client, error) {
+
+	if getTenantUserName(clusterSummary) == "" {
+		adminNamespace, adminName := getClusterSummaryAdmin(clusterSummary)
+		return getKubernetesClient(ctx, c, clusterSummary.Spec.ClusterNamespace,
+			clusterSummary.Spec.ClusterName, adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	}
+
+	remoteRestConfig, err := getClusterSummaryRestConfig(ctx, c, clusterSummary, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return getRemoteClient(remoteRestConfig, c.Scheme())
+}
+
+// getClusterSummaryKubeconfig writes the kubeconfig used to deploy ClusterSummary helm charts in
+// the managed cluster in a temporary file and returns its path. Caller must remove the file.
+func getClusterSummaryKubeconfig(ctx context.Context, c client.Client,
+	clusterSummary *configv1beta1.ClusterSummary, logger logr.Logger) (string, error) {
+
+	adminNamespace, adminName := getClusterSummaryAdmin(clusterSummary)
+	kubeconfig, err := getKubeconfig(ctx, c, clusterSummary.Spec.ClusterNamespace,
+		clusterSummary.Spec.ClusterName, adminNamespace, adminName, clusterSummary.Spec.ClusterType, logger)
+	if err != nil {
+		return "", err
+	}
+
+	userName := getTenantUserName(clusterSummary)
+	if userName == "" {
+		return kubeconfig, nil
+	}
+
+	if err := impersonateInKubeconfig(kubeconfig, userName); err != nil {
+		os.Remove(kubeconfig)
+		return "", err
+	}
+
+	return kubeconfig, nil
+}
+
+// impersonateInKubeconfig modifies the kubeconfig file so that all its users impersonate userName
+func impersonateInKubeconfig(kubeconfig, userName string) error {
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	for name := range config.AuthInfos {
+		config.AuthInfos[name].Impersonate = userName
+	}
+
+	return clientcmd.WriteToFile(*config, kubeconfig)
+}
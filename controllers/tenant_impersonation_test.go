@@ -0,0 +1,85 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"fmt"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+)
+
+var _ = Describe("Tenant impersonation", func() {
+	var clusterSummary *configv1beta1.ClusterSummary
+
+	BeforeEach(func() {
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      randomString(),
+				Namespace: randomString(),
+			},
+		}
+	})
+
+	It("impersonateTenant impersonates tenant ServiceAccount only when TenantRef is set", func() {
+		restConfig := &rest.Config{Host: "https://" + randomString()}
+
+		Expect(controllers.ImpersonateTenant(restConfig, clusterSummary).Impersonate.UserName).To(BeEmpty())
+
+		clusterSummary.Spec.ClusterProfileSpec.TenantRef = &configv1beta1.TenantRef{
+			ServiceAccountNamespace: randomString(),
+			ServiceAccountName:      randomString(),
+		}
+		impersonated := controllers.ImpersonateTenant(restConfig, clusterSummary)
+		Expect(impersonated.Impersonate.UserName).To(Equal(fmt.Sprintf("system:serviceaccount:%s:%s",
+			clusterSummary.Spec.ClusterProfileSpec.TenantRef.ServiceAccountNamespace,
+			clusterSummary.Spec.ClusterProfileSpec.TenantRef.ServiceAccountName)))
+		Expect(impersonated.Host).To(Equal(restConfig.Host))
+		// Original rest config is not modified
+		Expect(restConfig.Impersonate.UserName).To(BeEmpty())
+	})
+
+	It("impersonateInKubeconfig sets impersonated user for all users", func() {
+		config := clientcmdapi.NewConfig()
+		config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://" + randomString()}
+		config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: randomString()}
+		config.Contexts["cluster"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "admin"}
+		config.CurrentContext = "cluster"
+
+		kubeconfig, err := os.CreateTemp("", "kubeconfig")
+		Expect(err).To(BeNil())
+		defer os.Remove(kubeconfig.Name())
+		Expect(clientcmd.WriteToFile(*config, kubeconfig.Name())).To(Succeed())
+
+		userName := fmt.Sprintf("system:serviceaccount:%s:%s", randomString(), randomString())
+		Expect(controllers.ImpersonateInKubeconfig(kubeconfig.Name(), userName)).To(Succeed())
+
+		currentConfig, err := clientcmd.LoadFromFile(kubeconfig.Name())
+		Expect(err).To(BeNil())
+		Expect(currentConfig.AuthInfos["admin"].Impersonate).To(Equal(userName))
+		Expect(currentConfig.AuthInfos["admin"].Token).To(Equal(config.AuthInfos["admin"].Token))
+	})
+})
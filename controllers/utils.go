@@ -28,21 +28,18 @@ import (
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/discovery"
-	memory "k8s.io/client-go/discovery/cached"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/restmapper"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/addonclient"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
 )
@@ -54,8 +51,7 @@ import (
 //+kubebuilder:rbac:groups=*,resources=*,verbs=get;list;watch;impersonate
 
 const (
-	nameSeparator = "--"
-	clusterKind   = "Cluster"
+	clusterKind = "Cluster"
 )
 
 var (
@@ -108,17 +104,7 @@ func getPrefix(clusterType libsveltosv1beta1.ClusterType) string {
 // GetClusterSummaryName returns the ClusterSummary name given a ClusterProfile/Profile kind/name and
 // cluster type/Name.
 func GetClusterSummaryName(profileKind, profileName, clusterName string, isSveltosCluster bool) string {
-	clusterType := libsveltosv1beta1.ClusterTypeCapi
-	if isSveltosCluster {
-		clusterType = libsveltosv1beta1.ClusterTypeSveltos
-	}
-	prefix := getPrefix(clusterType)
-	if profileKind == configv1beta1.ClusterProfileKind {
-		// For backward compatibility (code before addition of Profiles) do not change this
-		return fmt.Sprintf("%s-%s-%s", profileName, prefix, clusterName)
-	}
-
-	return fmt.Sprintf("p--%s-%s-%s", profileName, prefix, clusterName)
+	return addonclient.GetClusterSummaryName(profileKind, profileName, clusterName, isSveltosCluster)
 }
 
 // getClusterSummary returns the ClusterSummary instance created by a specific
@@ -127,36 +113,7 @@ func getClusterSummary(ctx context.Context, c client.Client,
 	profileKind, profileName string, clusterNamespace, clusterName string,
 	clusterType libsveltosv1beta1.ClusterType) (*configv1beta1.ClusterSummary, error) {
 
-	profileLabel := ClusterProfileLabelName
-	if profileKind == configv1beta1.ProfileKind {
-		profileLabel = ProfileLabelName
-	}
-
-	listOptions := []client.ListOption{
-		client.InNamespace(clusterNamespace),
-		client.MatchingLabels{
-			profileLabel:                   profileName,
-			configv1beta1.ClusterNameLabel: clusterName,
-			configv1beta1.ClusterTypeLabel: string(clusterType),
-		},
-	}
-
-	clusterSummaryList := &configv1beta1.ClusterSummaryList{}
-	if err := c.List(ctx, clusterSummaryList, listOptions...); err != nil {
-		return nil, err
-	}
-
-	if len(clusterSummaryList.Items) == 0 {
-		return nil, apierrors.NewNotFound(
-			schema.GroupResource{Group: configv1beta1.GroupVersion.Group, Resource: configv1beta1.ClusterSummaryKind}, "")
-	}
-
-	if len(clusterSummaryList.Items) != 1 {
-		return nil, fmt.Errorf("more than one clustersummary found for cluster %s/%s created by %s %s",
-			clusterNamespace, clusterName, profileKind, profileName)
-	}
-
-	return &clusterSummaryList.Items[0], nil
+	return addonclient.GetClusterSummary(ctx, c, profileKind, profileName, clusterNamespace, clusterName, clusterType)
 }
 
 // getClusterConfiguration returns the ClusterConfiguration instance for a specific CAPI Cluster
@@ -184,18 +141,11 @@ func getEntryKey(resourceKind, resourceNamespace, resourceName string) string {
 }
 
 func getClusterReportName(profileKind, profileName, clusterName string, clusterType libsveltosv1beta1.ClusterType) string {
-	// TODO: shorten this value
-	prefix := "" // For backward compatibility (before addition of Profile) leave this empty for ClusterProfiles
-	if profileKind == configv1beta1.ProfileKind {
-		prefix = "p--"
-	}
-	return prefix + profileName + nameSeparator + strings.ToLower(string(clusterType)) +
-		nameSeparator + clusterName
+	return addonclient.GetClusterReportName(profileKind, profileName, clusterName, clusterType)
 }
 
 func getClusterConfigurationName(clusterName string, clusterType libsveltosv1beta1.ClusterType) string {
-	// TODO: shorten this value
-	return strings.ToLower(string(clusterType)) + nameSeparator + clusterName
+	return addonclient.GetClusterConfigurationName(clusterName, clusterType)
 }
 
 // getKeyFromObject returns the Key that can be used in the internal reconciler maps.
@@ -232,66 +182,7 @@ func addTypeInformationToObject(scheme *runtime.Scheme, obj client.Object) {
 
 // isCluterSummaryProvisioned returns true if ClusterSummary is currently fully deployed.
 func isCluterSummaryProvisioned(clusterSumary *configv1beta1.ClusterSummary) bool {
-	hasHelmCharts := false
-	hasRawYAMLs := false
-	hasKustomize := false
-
-	if clusterSumary.Spec.ClusterProfileSpec.HelmCharts != nil &&
-		len(clusterSumary.Spec.ClusterProfileSpec.HelmCharts) != 0 {
-
-		hasHelmCharts = true
-	}
-
-	if clusterSumary.Spec.ClusterProfileSpec.PolicyRefs != nil &&
-		len(clusterSumary.Spec.ClusterProfileSpec.PolicyRefs) != 0 {
-
-		hasRawYAMLs = true
-	}
-
-	if clusterSumary.Spec.ClusterProfileSpec.KustomizationRefs != nil &&
-		len(clusterSumary.Spec.ClusterProfileSpec.KustomizationRefs) != 0 {
-
-		hasKustomize = true
-	}
-
-	deployedHelmCharts := false
-	deployedRawYAMLs := false
-	deployedKustomize := false
-
-	for i := range clusterSumary.Status.FeatureSummaries {
-		fs := &clusterSumary.Status.FeatureSummaries[i]
-		if fs.Status != configv1beta1.FeatureStatusProvisioned {
-			return false
-		}
-		switch fs.FeatureID {
-		case configv1beta1.FeatureHelm:
-			deployedHelmCharts = true
-		case configv1beta1.FeatureResources:
-			deployedRawYAMLs = true
-		case configv1beta1.FeatureKustomize:
-			deployedKustomize = true
-		}
-	}
-
-	if hasHelmCharts {
-		if !deployedHelmCharts {
-			return false
-		}
-	}
-
-	if hasRawYAMLs {
-		if !deployedRawYAMLs {
-			return false
-		}
-	}
-
-	if hasKustomize {
-		if !deployedKustomize {
-			return false
-		}
-	}
-
-	return true
+	return addonclient.IsClusterSummaryProvisioned(clusterSumary)
 }
 
 func SetVersion(v string) {
@@ -309,13 +200,11 @@ func isNamespaced(r *unstructured.Unstructured, config *rest.Config) (bool, erro
 		Version: r.GroupVersionKind().Version,
 	}
 
-	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	mapper, _, err := getRemoteRESTMapperAndDynamicClient(config)
 	if err != nil {
 		return false, err
 	}
 
-	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc))
-
 	var mapping *meta.RESTMapping
 	mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 	if err != nil {
@@ -440,13 +329,7 @@ func unique[T comparable](input []T) []T {
 func getFeatureSummaryForFeatureID(clusterSummay *configv1beta1.ClusterSummary, fID configv1beta1.FeatureID,
 ) *configv1beta1.FeatureSummary {
 
-	for i := range clusterSummay.Status.FeatureSummaries {
-		if clusterSummay.Status.FeatureSummaries[i].FeatureID == fID {
-			return &clusterSummay.Status.FeatureSummaries[i]
-		}
-	}
-
-	return nil
+	return addonclient.GetFeatureSummaryForFeatureID(clusterSummay, fID)
 }
 
 // Return FeatureDeploymentInfo for featureID
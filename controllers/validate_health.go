@@ -23,14 +23,12 @@ import (
 
 	"github.com/go-logr/logr"
 	lua "github.com/yuin/gopher-lua"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/restmapper"
 
 	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
 	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
@@ -55,7 +53,7 @@ func validateHealthPolicies(ctx context.Context, remoteConfig *rest.Config, clus
 
 		if err := validateHealthPolicy(ctx, remoteConfig, check, logger); err != nil {
 			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to validate check: %s", err))
-			return err
+			return withMessageCode(configv1beta1.MessageCodeHealthCheckFailed, err)
 		}
 	}
 
@@ -90,6 +88,15 @@ func validateHealthPolicy(ctx context.Context, remoteConfig *rest.Config, check
 			l.V(logs.LogInfo).Info("resource is not healthy")
 			return fmt.Errorf("%s", msg)
 		}
+
+		healthy, msg, err = isHealthyAccordingToCEL(&list.Items[i], check.CELExpression, logger)
+		if err != nil {
+			return err
+		}
+		if !healthy {
+			l.V(logs.LogInfo).Info("resource is not healthy")
+			return fmt.Errorf("%s", msg)
+		}
 	}
 
 	return nil
@@ -105,14 +112,10 @@ func fetchResources(ctx context.Context, remoteConfig *rest.Config, check *confi
 		Kind:    check.Kind,
 	}
 
-	dc := discovery.NewDiscoveryClientForConfigOrDie(remoteConfig)
-	groupResources, err := restmapper.GetAPIGroupResources(dc)
+	mapper, d, err := getRemoteRESTMapperAndDynamicClient(remoteConfig)
 	if err != nil {
 		return nil, err
 	}
-	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
-
-	d := dynamic.NewForConfigOrDie(remoteConfig)
 
 	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 	if err != nil {
@@ -154,8 +157,19 @@ func fetchResources(ctx context.Context, remoteConfig *rest.Config, check *confi
 		options.FieldSelector += fmt.Sprintf("metadata.namespace=%s", check.Namespace)
 	}
 
+	if check.ResourceName != "" {
+		if options.FieldSelector != "" {
+			options.FieldSelector += ","
+		}
+		options.FieldSelector += fmt.Sprintf("metadata.name=%s", check.ResourceName)
+	}
+
 	list, err := d.Resource(resourceId).List(ctx, options)
 	if err != nil {
+		// RESTMapper is cached and might not know yet CRD was removed
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
@@ -227,3 +241,27 @@ func isHealthy(resource *unstructured.Unstructured, script string, logger logr.L
 
 	return true, "", nil
 }
+
+// isHealthyAccordingToCEL verifies whether resource is healthy according to CEL expression
+func isHealthyAccordingToCEL(resource *unstructured.Unstructured, expression string, logger logr.Logger,
+) (healthy bool, msg string, err error) {
+
+	if expression == "" {
+		return true, "", nil
+	}
+
+	healthy, err = evaluateCELExpression(expression, "obj", resource.UnstructuredContent())
+	if err != nil {
+		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to evaluate health for resource: %v", err))
+		return false, "", err
+	}
+
+	logger.V(logs.LogDebug).Info(fmt.Sprintf("is healthy: %t", healthy))
+
+	if !healthy {
+		return false, fmt.Sprintf("resource %s/%s is not healthy: %s evaluated to false",
+			resource.GetNamespace(), resource.GetName(), expression), nil
+	}
+
+	return true, "", nil
+}
@@ -127,6 +127,59 @@ var _ = Describe("Lua Health Policies", func() {
 	})
 })
 
+var _ = Describe("CEL Health Policies", func() {
+	var deployment *unstructured.Unstructured
+
+	BeforeEach(func() {
+		deployment = &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata": map[string]interface{}{
+					"namespace": "kyverno",
+					"name":      "kyverno",
+				},
+				"status": map[string]interface{}{
+					"availableReplicas": int64(1),
+				},
+			},
+		}
+	})
+
+	It("isHealthyAccordingToCEL evaluates CEL expression against resource", func() {
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		healthy, _, err := controllers.IsHealthyAccordingToCEL(deployment, "", logger)
+		Expect(err).To(BeNil())
+		Expect(healthy).To(BeTrue())
+
+		healthy, _, err = controllers.IsHealthyAccordingToCEL(deployment, "obj.status.availableReplicas >= 1", logger)
+		Expect(err).To(BeNil())
+		Expect(healthy).To(BeTrue())
+
+		healthy, msg, err := controllers.IsHealthyAccordingToCEL(deployment, "obj.status.availableReplicas >= 2", logger)
+		Expect(err).To(BeNil())
+		Expect(healthy).To(BeFalse())
+		Expect(msg).To(ContainSubstring("kyverno/kyverno"))
+
+		healthy, _, err = controllers.IsHealthyAccordingToCEL(deployment,
+			`has(obj.status.readyReplicas) && obj.status.readyReplicas >= 1`, logger)
+		Expect(err).To(BeNil())
+		Expect(healthy).To(BeFalse())
+	})
+
+	It("isHealthyAccordingToCEL returns an error for invalid expressions", func() {
+		logger := textlogger.NewLogger(textlogger.NewConfig())
+
+		_, _, err := controllers.IsHealthyAccordingToCEL(deployment, "obj.status.availableReplicas >=", logger)
+		Expect(err).ToNot(BeNil())
+
+		// Expression must return a boolean
+		_, _, err = controllers.IsHealthyAccordingToCEL(deployment, "obj.status.availableReplicas", logger)
+		Expect(err).ToNot(BeNil())
+	})
+})
+
 func verifyHealthLuaPolicies(dirName string) {
 	By(fmt.Sprintf("Verifying lua policies %s", dirName))
 
@@ -0,0 +1,154 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2/textlogger"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	"github.com/projectsveltos/addon-controller/pkg/workloadcluster"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+)
+
+var _ = Describe("Workload cluster", func() {
+	var clusterProfile *configv1beta1.ClusterProfile
+	var clusterSummary *configv1beta1.ClusterSummary
+	var workloadCluster *workloadcluster.WorkloadCluster
+	var registry *workloadcluster.Registry
+	var namespace string
+
+	BeforeEach(func() {
+		namespace = randomString()
+
+		var err error
+		workloadCluster, err = workloadcluster.Start(namespace, randomString(), scheme)
+		Expect(err).To(BeNil())
+
+		registry = &workloadcluster.Registry{}
+		registry.Add(workloadCluster)
+		controllers.SetRemoteRestConfigGetter(registry.GetRestConfig)
+
+		clusterProfile = &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: clusterProfileNamePrefix + randomString(),
+			},
+		}
+
+		clusterSummary = &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: controllers.GetClusterSummaryName(configv1beta1.ClusterProfileKind,
+					clusterProfile.Name, workloadCluster.Name, true),
+				Namespace: namespace,
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: namespace,
+				ClusterName:      workloadCluster.Name,
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+			},
+		}
+		addLabelsToClusterSummary(clusterSummary, clusterProfile.Name, workloadCluster.Name,
+			libsveltosv1beta1.ClusterTypeSveltos)
+
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+			},
+		}
+		Expect(testEnv.Client.Create(context.TODO(), ns)).To(Succeed())
+		Expect(waitForObject(context.TODO(), testEnv.Client, ns)).To(Succeed())
+
+		sveltosCluster := workloadCluster.SveltosCluster(nil)
+		status := sveltosCluster.Status
+		Expect(testEnv.Client.Create(context.TODO(), sveltosCluster)).To(Succeed())
+		Expect(waitForObject(context.TODO(), testEnv.Client, sveltosCluster)).To(Succeed())
+		sveltosCluster.Status = status
+		Expect(testEnv.Client.Status().Update(context.TODO(), sveltosCluster)).To(Succeed())
+
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name: controllers.GetClusterConfigurationName(workloadCluster.Name,
+					libsveltosv1beta1.ClusterTypeSveltos),
+			},
+		}
+		Expect(testEnv.Client.Create(context.TODO(), clusterConfiguration)).To(Succeed())
+		Expect(testEnv.Client.Create(context.TODO(), clusterProfile)).To(Succeed())
+		Expect(waitForObject(context.TODO(), testEnv.Client, clusterProfile)).To(Succeed())
+		Expect(testEnv.Client.Create(context.TODO(), clusterSummary)).To(Succeed())
+		Expect(waitForObject(context.TODO(), testEnv.Client, clusterSummary)).To(Succeed())
+
+		addOwnerReference(context.TODO(), testEnv.Client, clusterSummary, clusterProfile)
+		addOwnerReference(context.TODO(), testEnv.Client, clusterConfiguration, clusterProfile)
+	})
+
+	AfterEach(func() {
+		controllers.SetRemoteRestConfigGetter(nil)
+		registry.Remove(workloadCluster)
+		Expect(workloadCluster.Stop()).To(Succeed())
+
+		deleteResources(namespace, clusterProfile, clusterSummary)
+	})
+
+	It("deployResources deploys referenced resources to the workload cluster only", func() {
+		clusterRoleName := randomString()
+		configMap := createConfigMapWithPolicy(namespace, randomString(), fmt.Sprintf(viewClusterRole, clusterRoleName))
+		Expect(testEnv.Client.Create(context.TODO(), configMap)).To(Succeed())
+		Expect(waitForObject(context.TODO(), testEnv.Client, configMap)).To(Succeed())
+
+		currentClusterSummary := &configv1beta1.ClusterSummary{}
+		Expect(testEnv.Get(context.TODO(),
+			types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name},
+			currentClusterSummary)).To(Succeed())
+		currentClusterSummary.Spec.ClusterProfileSpec.PolicyRefs = []configv1beta1.PolicyRef{
+			{
+				Namespace: configMap.Namespace,
+				Name:      configMap.Name,
+				Kind:      string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+			},
+		}
+		Expect(testEnv.Client.Update(context.TODO(), currentClusterSummary)).To(Succeed())
+
+		// Eventual loop so testEnv Cache is synced
+		Eventually(func() error {
+			return controllers.GenericDeploy(ctx, testEnv.Client, namespace, workloadCluster.Name,
+				clusterSummary.Name, string(configv1beta1.FeatureResources), libsveltosv1beta1.ClusterTypeSveltos,
+				deployer.Options{}, textlogger.NewLogger(textlogger.NewConfig()))
+		}, timeout, pollingInterval).Should(BeNil())
+
+		currentClusterRole := &rbacv1.ClusterRole{}
+		Expect(workloadCluster.Client.Get(context.TODO(),
+			types.NamespacedName{Name: clusterRoleName}, currentClusterRole)).To(Succeed())
+
+		// ClusterRole is not deployed to the management cluster
+		err := testEnv.Client.Get(context.TODO(), types.NamespacedName{Name: clusterRoleName}, currentClusterRole)
+		Expect(err).ToNot(BeNil())
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,411 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addonclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/addonclient"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("AddonClient", func() {
+	It("GetLegacyClusterSummaryName returns names compatible with existing instances", func() {
+		Expect(addonclient.GetLegacyClusterSummaryName(configv1beta1.ClusterProfileKind, "cp", "cluster", false)).
+			To(Equal("cp-capi-cluster"))
+		Expect(addonclient.GetLegacyClusterSummaryName(configv1beta1.ProfileKind, "p", "cluster", true)).
+			To(Equal("p--p-sveltos-cluster"))
+		Expect(addonclient.GetClusterConfigurationName("cluster", libsveltosv1beta1.ClusterTypeSveltos)).
+			To(Equal("sveltos--cluster"))
+		Expect(addonclient.GetClusterReportName(configv1beta1.ProfileKind, "p", "cluster",
+			libsveltosv1beta1.ClusterTypeCapi)).To(Equal("p--p--capi--cluster"))
+	})
+
+	It("GetClusterSummaryName returns deterministic, short and unique names", func() {
+		// Names fitting in a label value are unchanged
+		name := addonclient.GetClusterSummaryName(configv1beta1.ClusterProfileKind, "cp", "cluster", false)
+		Expect(name).To(Equal("cp-capi-cluster"))
+		Expect(addonclient.GetClusterSummaryName(configv1beta1.ProfileKind, "p", "cluster", true)).
+			To(Equal("p--p-sveltos-cluster"))
+
+		hashedName := addonclient.GetHashedClusterSummaryName(configv1beta1.ClusterProfileKind, "cp", "cluster", false)
+		Expect(hashedName).To(HavePrefix("cp-capi-cluster-"))
+		Expect(hashedName).To(Equal(addonclient.GetHashedClusterSummaryName(configv1beta1.ClusterProfileKind,
+			"cp", "cluster", false)))
+
+		// Legacy names of these two collide, hashed names do not
+		Expect(addonclient.GetClusterSummaryName(configv1beta1.ClusterProfileKind, "a-capi", "b", false)).
+			To(Equal(addonclient.GetClusterSummaryName(configv1beta1.ClusterProfileKind, "a", "capi-b", false)))
+		Expect(addonclient.GetHashedClusterSummaryName(configv1beta1.ClusterProfileKind, "a-capi", "b", false)).
+			ToNot(Equal(addonclient.GetHashedClusterSummaryName(configv1beta1.ClusterProfileKind, "a", "capi-b", false)))
+
+		// Long names are truncated, still unique and valid label values
+		profileName := strings.Repeat("a", 200)
+		longName := addonclient.GetClusterSummaryName(configv1beta1.ProfileKind, profileName, "cluster1", true)
+		otherName := addonclient.GetClusterSummaryName(configv1beta1.ProfileKind, profileName, "cluster2", true)
+		Expect(longName).To(Equal(addonclient.GetHashedClusterSummaryName(configv1beta1.ProfileKind, profileName,
+			"cluster1", true)))
+		Expect(longName).ToNot(Equal(otherName))
+		Expect(validation.IsValidLabelValue(longName)).To(BeEmpty())
+		Expect(validation.IsDNS1123Subdomain(longName)).To(BeEmpty())
+
+		name = addonclient.GetClusterSummaryName(configv1beta1.ClusterProfileKind, strings.Repeat("b", 51)+"-x",
+			"cluster", false)
+		Expect(validation.IsValidLabelValue(name)).To(BeEmpty())
+	})
+
+	It("Names of identically named clusters of different types do not collide", func() {
+		Expect(addonclient.GetClusterSummaryName(configv1beta1.ClusterProfileKind, "cp", "cluster", false)).
+			ToNot(Equal(addonclient.GetClusterSummaryName(configv1beta1.ClusterProfileKind, "cp", "cluster", true)))
+		Expect(addonclient.GetClusterConfigurationName("cluster", libsveltosv1beta1.ClusterTypeCapi)).
+			ToNot(Equal(addonclient.GetClusterConfigurationName("cluster", libsveltosv1beta1.ClusterTypeSveltos)))
+		Expect(addonclient.GetClusterReportName(configv1beta1.ClusterProfileKind, "cp", "cluster",
+			libsveltosv1beta1.ClusterTypeCapi)).ToNot(Equal(addonclient.GetClusterReportName(configv1beta1.ClusterProfileKind,
+			"cp", "cluster", libsveltosv1beta1.ClusterTypeSveltos)))
+	})
+
+	It("GetClusterSummary and ListClusterSummariesForCluster use ClusterSummary labels", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		profileName := randomString()
+
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      addonclient.GetClusterSummaryName(configv1beta1.ClusterProfileKind, profileName, clusterName, true),
+				Labels: map[string]string{
+					addonclient.ClusterProfileLabelName: profileName,
+					configv1beta1.ClusterNameLabel:      clusterName,
+					configv1beta1.ClusterTypeLabel:      string(libsveltosv1beta1.ClusterTypeSveltos),
+				},
+			},
+		}
+
+		initObjects := []client.Object{clusterSummary}
+		c := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(initObjects...).Build()
+
+		cs, err := addonclient.GetClusterSummary(context.TODO(), c, configv1beta1.ClusterProfileKind, profileName,
+			clusterNamespace, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(cs.Name).To(Equal(clusterSummary.Name))
+
+		_, err = addonclient.GetClusterSummary(context.TODO(), c, configv1beta1.ProfileKind, profileName,
+			clusterNamespace, clusterName, libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		list, err := addonclient.ListClusterSummariesForCluster(context.TODO(), c, clusterNamespace, clusterName,
+			libsveltosv1beta1.ClusterTypeSveltos)
+		Expect(err).To(BeNil())
+		Expect(len(list.Items)).To(Equal(1))
+	})
+
+	It("IsClusterSummaryProvisioned and GetFailedFeatureSummaries read ClusterSummary status", func() {
+		clusterSummary := &configv1beta1.ClusterSummary{
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterProfileSpec: configv1beta1.Spec{
+					HelmCharts: []configv1beta1.HelmChart{{ReleaseName: randomString()}},
+					PolicyRefs: []configv1beta1.PolicyRef{{Name: randomString()}},
+				},
+			},
+			Status: configv1beta1.ClusterSummaryStatus{
+				FeatureSummaries: []configv1beta1.FeatureSummary{
+					{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusProvisioned},
+				},
+			},
+		}
+		Expect(addonclient.IsClusterSummaryProvisioned(clusterSummary)).To(BeFalse())
+		Expect(addonclient.GetFailedFeatureSummaries(clusterSummary)).To(BeEmpty())
+
+		clusterSummary.Status.FeatureSummaries = append(clusterSummary.Status.FeatureSummaries,
+			configv1beta1.FeatureSummary{FeatureID: configv1beta1.FeatureResources, Status: configv1beta1.FeatureStatusFailed})
+		Expect(addonclient.IsClusterSummaryProvisioned(clusterSummary)).To(BeFalse())
+		Expect(len(addonclient.GetFailedFeatureSummaries(clusterSummary))).To(Equal(1))
+
+		clusterSummary.Status.FeatureSummaries[1].Status = configv1beta1.FeatureStatusProvisioned
+		Expect(addonclient.IsClusterSummaryProvisioned(clusterSummary)).To(BeTrue())
+		Expect(addonclient.GetFeatureSummaryForFeatureID(clusterSummary, configv1beta1.FeatureKustomize)).To(BeNil())
+	})
+
+	It("DiffClusterConfigurations reports charts and resources changed between two snapshots", func() {
+		profileName := randomString()
+		earlier := metav1.NewTime(time.Now().Add(-time.Hour))
+		now := metav1.Now()
+
+		from := &configv1beta1.ClusterConfiguration{
+			Status: configv1beta1.ClusterConfigurationStatus{
+				ClusterProfileResources: []configv1beta1.ClusterProfileResource{
+					{
+						ClusterProfileName: profileName,
+						Features: []configv1beta1.Feature{
+							{
+								FeatureID: configv1beta1.FeatureHelm,
+								Charts: []configv1beta1.Chart{
+									{ReleaseName: "kyverno", Namespace: "kyverno", ChartVersion: "3.0.0", LastAppliedTime: &earlier},
+									{ReleaseName: "nginx", Namespace: "nginx", ChartVersion: "1.0.0", LastAppliedTime: &earlier},
+								},
+							},
+							{
+								FeatureID: configv1beta1.FeatureResources,
+								Resources: []configv1beta1.Resource{
+									{Kind: "ConfigMap", Namespace: "default", Name: "a", LastAppliedTime: &earlier},
+									{Kind: "ConfigMap", Namespace: "default", Name: "b", LastAppliedTime: &earlier},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		to := from.DeepCopy()
+		to.Status.ClusterProfileResources[0].Features[0].Charts = []configv1beta1.Chart{
+			{ReleaseName: "kyverno", Namespace: "kyverno", ChartVersion: "3.1.0", LastAppliedTime: &now},
+			{ReleaseName: "prometheus", Namespace: "monitoring", ChartVersion: "25.0.0", LastAppliedTime: &now},
+		}
+		to.Status.ClusterProfileResources[0].Features[1].Resources = []configv1beta1.Resource{
+			{Kind: "ConfigMap", Namespace: "default", Name: "a", LastAppliedTime: &now},
+			{Kind: "Secret", Namespace: "default", Name: "c", LastAppliedTime: &now},
+		}
+
+		Expect(addonclient.DiffClusterConfigurations(from, from).IsEmpty()).To(BeTrue())
+
+		diff := addonclient.DiffClusterConfigurations(from, to)
+		Expect(diff.IsEmpty()).To(BeFalse())
+		Expect(diff.ChartsAdded).To(HaveLen(1))
+		Expect(diff.ChartsAdded[0].ReleaseName).To(Equal("prometheus"))
+		Expect(diff.ChartsAdded[0].ToVersion).To(Equal("25.0.0"))
+		Expect(diff.ChartsUpgraded).To(HaveLen(1))
+		Expect(diff.ChartsUpgraded[0].FromVersion).To(Equal("3.0.0"))
+		Expect(diff.ChartsUpgraded[0].ToVersion).To(Equal("3.1.0"))
+		Expect(diff.ChartsRemoved).To(HaveLen(1))
+		Expect(diff.ChartsRemoved[0].ReleaseName).To(Equal("nginx"))
+		Expect(diff.ChartsRemoved[0].ProfileName).To(Equal(profileName))
+
+		Expect(diff.ResourcesAdded).To(HaveLen(1))
+		Expect(diff.ResourcesAdded[0].Name).To(Equal("c"))
+		Expect(diff.ResourcesUpdated).To(HaveLen(1))
+		Expect(diff.ResourcesUpdated[0].Name).To(Equal("a"))
+		Expect(diff.ResourcesRemoved).To(HaveLen(1))
+		Expect(diff.ResourcesRemoved[0].Name).To(Equal("b"))
+
+		diff = addonclient.DiffClusterConfigurations(nil, from)
+		Expect(diff.ChartsAdded).To(HaveLen(2))
+		Expect(diff.ResourcesAdded).To(HaveLen(2))
+	})
+
+	It("DiffClusterConfigurationSince compares recorded ClusterConfiguration snapshots", func() {
+		clusterNamespace := randomString()
+		clusterName := randomString()
+		clusterConfigurationName := addonclient.GetClusterConfigurationName(clusterName, libsveltosv1beta1.ClusterTypeCapi)
+
+		snapshot := func(versions ...string) string {
+			charts := make([]configv1beta1.Chart, len(versions))
+			for i := range versions {
+				charts[i] = configv1beta1.Chart{ReleaseName: fmt.Sprintf("release%d", i), Namespace: "default",
+					ChartVersion: versions[i]}
+			}
+			data, err := json.Marshal(configv1beta1.ClusterConfigurationStatus{
+				ClusterProfileResources: []configv1beta1.ClusterProfileResource{
+					{
+						ClusterProfileName: "cp",
+						Features:           []configv1beta1.Feature{{FeatureID: configv1beta1.FeatureHelm, Charts: charts}},
+					},
+				},
+			})
+			Expect(err).To(BeNil())
+			return string(data)
+		}
+
+		now := time.Now()
+		weekAgo := now.Add(-7 * 24 * time.Hour)
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      addonclient.GetClusterConfigurationSnapshotsName(clusterConfigurationName),
+			},
+			Data: map[string]string{
+				addonclient.GetClusterConfigurationSnapshotKey(weekAgo.Add(-time.Hour)): snapshot("1.0.0"),
+				addonclient.GetClusterConfigurationSnapshotKey(weekAgo.Add(time.Hour)):  snapshot("1.1.0"),
+				addonclient.GetClusterConfigurationSnapshotKey(now.Add(-time.Hour)):     snapshot("1.1.0", "2.0.0"),
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(configMap).Build()
+
+		snapshots, err := addonclient.ListClusterConfigurationSnapshots(context.TODO(), c, clusterNamespace,
+			clusterName, libsveltosv1beta1.ClusterTypeCapi)
+		Expect(err).To(BeNil())
+		Expect(snapshots).To(HaveLen(3))
+		Expect(snapshots[0].Time.Before(snapshots[1].Time)).To(BeTrue())
+		Expect(snapshots[1].Time.Before(snapshots[2].Time)).To(BeTrue())
+		Expect(snapshots[0].ClusterConfiguration.Name).To(Equal(clusterConfigurationName))
+
+		Expect(addonclient.GetClusterConfigurationSnapshotAt(snapshots, weekAgo.Add(-2*time.Hour))).To(BeNil())
+		Expect(addonclient.GetClusterConfigurationSnapshotAt(snapshots, weekAgo)).To(Equal(&snapshots[0]))
+		Expect(addonclient.GetClusterConfigurationSnapshotAt(snapshots, now)).To(Equal(&snapshots[2]))
+
+		// What changed this week: release0 upgraded, release1 added
+		diff, err := addonclient.DiffClusterConfigurationSince(context.TODO(), c, clusterNamespace, clusterName,
+			libsveltosv1beta1.ClusterTypeCapi, weekAgo)
+		Expect(err).To(BeNil())
+		Expect(diff.ChartsUpgraded).To(HaveLen(1))
+		Expect(diff.ChartsUpgraded[0].FromVersion).To(Equal("1.0.0"))
+		Expect(diff.ChartsUpgraded[0].ToVersion).To(Equal("1.1.0"))
+		Expect(diff.ChartsAdded).To(HaveLen(1))
+		Expect(diff.ChartsAdded[0].ReleaseName).To(Equal("release1"))
+
+		// No snapshot recorded for a cluster
+		diff, err = addonclient.DiffClusterConfigurationSince(context.TODO(), c, clusterNamespace, randomString(),
+			libsveltosv1beta1.ClusterTypeCapi, weekAgo)
+		Expect(err).To(BeNil())
+		Expect(diff.IsEmpty()).To(BeTrue())
+	})
+
+	It("DescribeProfile returns the profile tree: clusters, features, resources and helm releases", func() {
+		clusterNamespace := randomString()
+		profileName := randomString()
+		now := metav1.Now()
+
+		clusterProfile := &configv1beta1.ClusterProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: profileName},
+			Status: configv1beta1.Status{
+				MatchingClusterRefs: []corev1.ObjectReference{
+					{Namespace: clusterNamespace, Name: "b", Kind: libsveltosv1beta1.SveltosClusterKind},
+					{Namespace: clusterNamespace, Name: "a", Kind: libsveltosv1beta1.SveltosClusterKind},
+				},
+			},
+		}
+
+		// Cluster "a" is fully provisioned. No ClusterSummary exists yet for cluster "b".
+		clusterSummary := &configv1beta1.ClusterSummary{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      addonclient.GetClusterSummaryName(configv1beta1.ClusterProfileKind, profileName, "a", true),
+				Labels:    map[string]string{addonclient.ClusterProfileLabelName: profileName},
+			},
+			Spec: configv1beta1.ClusterSummarySpec{
+				ClusterNamespace: clusterNamespace,
+				ClusterName:      "a",
+				ClusterType:      libsveltosv1beta1.ClusterTypeSveltos,
+				ClusterProfileSpec: configv1beta1.Spec{
+					HelmCharts: []configv1beta1.HelmChart{
+						{ReleaseNamespace: "kyverno", ReleaseName: "kyverno", RepositoryURL: "https://kyverno.github.io/kyverno/"},
+					},
+					PolicyRefs: []configv1beta1.PolicyRef{{Name: randomString()}},
+				},
+			},
+			Status: configv1beta1.ClusterSummaryStatus{
+				FeatureSummaries: []configv1beta1.FeatureSummary{
+					{FeatureID: configv1beta1.FeatureResources, Status: configv1beta1.FeatureStatusProvisioned},
+					{FeatureID: configv1beta1.FeatureHelm, Status: configv1beta1.FeatureStatusProvisioned},
+				},
+			},
+		}
+
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: clusterNamespace,
+				Name:      addonclient.GetClusterConfigurationName("a", libsveltosv1beta1.ClusterTypeSveltos),
+			},
+			Status: configv1beta1.ClusterConfigurationStatus{
+				ClusterProfileResources: []configv1beta1.ClusterProfileResource{
+					{
+						ClusterProfileName: profileName,
+						Features: []configv1beta1.Feature{
+							{
+								FeatureID: configv1beta1.FeatureHelm,
+								Charts: []configv1beta1.Chart{
+									{ReleaseName: "kyverno", Namespace: "kyverno", ChartVersion: "3.1.0", LastAppliedTime: &now},
+								},
+							},
+							{
+								FeatureID: configv1beta1.FeatureResources,
+								Resources: []configv1beta1.Resource{
+									{Version: "v1", Kind: "ConfigMap", Namespace: "default", Name: "a", LastAppliedTime: &now},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		initObjects := []client.Object{clusterProfile, clusterSummary, clusterConfiguration}
+		c := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(initObjects...).
+			WithStatusSubresource(initObjects...).Build()
+
+		description, err := addonclient.DescribeProfile(context.TODO(), c, configv1beta1.ClusterProfileKind, "",
+			profileName)
+		Expect(err).To(BeNil())
+		Expect(description.Kind).To(Equal(configv1beta1.ClusterProfileKind))
+		Expect(description.Name).To(Equal(profileName))
+		Expect(description.State).To(Equal(addonclient.DescribeStateProgressing))
+		Expect(description.Clusters).To(HaveLen(2))
+
+		clusterA := &description.Clusters[0]
+		Expect(clusterA.Cluster.Name).To(Equal("a"))
+		Expect(clusterA.ClusterSummary).To(Equal(clusterSummary.Name))
+		Expect(clusterA.State).To(Equal(addonclient.DescribeStateReady))
+		Expect(clusterA.Features).To(HaveLen(2))
+		Expect(clusterA.Features[0].FeatureID).To(Equal(configv1beta1.FeatureHelm))
+		Expect(clusterA.Features[0].Releases).To(HaveLen(1))
+		Expect(clusterA.Features[0].Releases[0].ChartVersion).To(Equal("3.1.0"))
+		Expect(clusterA.Features[0].Releases[0].State).To(Equal(addonclient.DescribeStateReady))
+		Expect(clusterA.Features[1].FeatureID).To(Equal(configv1beta1.FeatureResources))
+		Expect(clusterA.Features[1].Resources).To(HaveLen(1))
+		Expect(clusterA.Features[1].Resources[0].Kind).To(Equal("ConfigMap"))
+
+		clusterB := &description.Clusters[1]
+		Expect(clusterB.Cluster.Name).To(Equal("b"))
+		Expect(clusterB.ClusterSummary).To(BeEmpty())
+		Expect(clusterB.State).To(Equal(addonclient.DescribeStateProgressing))
+
+		// A conflicting helm release fails the whole tree
+		clusterSummary.Status.HelmReleaseSummaries = []configv1beta1.HelmChartSummary{
+			{
+				ReleaseNamespace: "kyverno", ReleaseName: "kyverno",
+				Status: configv1beta1.HelmChartStatusConflict, ConflictMessage: "managed by another profile",
+			},
+		}
+		Expect(c.Status().Update(context.TODO(), clusterSummary)).To(Succeed())
+
+		description, err = addonclient.DescribeProfile(context.TODO(), c, configv1beta1.ClusterProfileKind, "",
+			profileName)
+		Expect(err).To(BeNil())
+		Expect(description.State).To(Equal(addonclient.DescribeStateFailed))
+		Expect(description.Clusters[0].State).To(Equal(addonclient.DescribeStateFailed))
+		Expect(description.Clusters[0].Features[0].Releases[0].Message).To(Equal("managed by another profile"))
+
+		_, err = addonclient.DescribeProfile(context.TODO(), c, configv1beta1.ProfileKind, clusterNamespace,
+			profileName)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,381 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addonclient
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// DescribeState is the state of a node in a ProfileDescription tree
+type DescribeState string
+
+const (
+	// DescribeStateReady indicates the node, and all its children, are deployed
+	DescribeStateReady = DescribeState("Ready")
+
+	// DescribeStateProgressing indicates the node, or one of its children, is being deployed
+	DescribeStateProgressing = DescribeState("Progressing")
+
+	// DescribeStateFailed indicates the node, or one of its children, failed to deploy
+	DescribeStateFailed = DescribeState("Failed")
+)
+
+// ProfileDescription is a hierarchical description of a ClusterProfile/Profile:
+// matching clusters -> features -> resources/helm releases.
+// It is designed to be rendered as a tree (like "clusterctl describe cluster").
+type ProfileDescription struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	// Revision is the ClusterProfile/Profile Spec revision
+	Revision int64 `json:"revision,omitempty"`
+
+	State DescribeState `json:"state"`
+
+	Clusters []ClusterDescription `json:"clusters,omitempty"`
+}
+
+// ClusterDescription describes the deployment of a ClusterProfile/Profile in a matching cluster
+type ClusterDescription struct {
+	Cluster corev1.ObjectReference `json:"cluster"`
+
+	// ClusterSummary is the name of the ClusterSummary. Empty if not created yet.
+	ClusterSummary string `json:"clusterSummary,omitempty"`
+
+	// ObservedRevision is the ClusterProfile/Profile revision last provisioned in the cluster
+	ObservedRevision int64 `json:"observedRevision,omitempty"`
+
+	State   DescribeState `json:"state"`
+	Message string        `json:"message,omitempty"`
+
+	Features []FeatureDescription `json:"features,omitempty"`
+}
+
+// FeatureDescription describes a feature (Resources, Helm, Kustomize) deployed in a cluster
+type FeatureDescription struct {
+	FeatureID configv1beta1.FeatureID `json:"featureID"`
+
+	State   DescribeState `json:"state"`
+	Message string        `json:"message,omitempty"`
+
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	Resources []ResourceDescription `json:"resources,omitempty"`
+	Releases  []ReleaseDescription  `json:"releases,omitempty"`
+}
+
+// ResourceDescription describes a resource deployed in a cluster
+type ResourceDescription struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	State DescribeState `json:"state"`
+
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+}
+
+// ReleaseDescription describes a helm release in a cluster
+type ReleaseDescription struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	ChartVersion string `json:"chartVersion,omitempty"`
+	RepoURL      string `json:"repoURL,omitempty"`
+
+	State   DescribeState `json:"state"`
+	Message string        `json:"message,omitempty"`
+
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+}
+
+// DescribeProfile returns the description of a ClusterProfile (profileNamespace is ignored) or
+// Profile. Status of ClusterProfile/Profile, ClusterSummaries and ClusterConfigurations is used.
+// When c is the controller cached client, no request reaches the API server.
+func DescribeProfile(ctx context.Context, c client.Client, profileKind, profileNamespace, profileName string,
+) (*ProfileDescription, error) {
+
+	var profile client.Object
+	var status *configv1beta1.Status
+	listOptions := []client.ListOption{}
+	if profileKind == configv1beta1.ProfileKind {
+		p := &configv1beta1.Profile{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: profileNamespace, Name: profileName}, p); err != nil {
+			return nil, err
+		}
+		profile, status = p, &p.Status
+		listOptions = append(listOptions, client.InNamespace(profileNamespace),
+			client.MatchingLabels{ProfileLabelName: profileName})
+	} else {
+		cp := &configv1beta1.ClusterProfile{}
+		if err := c.Get(ctx, types.NamespacedName{Name: profileName}, cp); err != nil {
+			return nil, err
+		}
+		profileKind = configv1beta1.ClusterProfileKind
+		profile, status = cp, &cp.Status
+		listOptions = append(listOptions, client.MatchingLabels{ClusterProfileLabelName: profileName})
+	}
+
+	clusterSummaries := &configv1beta1.ClusterSummaryList{}
+	if err := c.List(ctx, clusterSummaries, listOptions...); err != nil {
+		return nil, err
+	}
+
+	description := &ProfileDescription{
+		Kind:      profileKind,
+		Namespace: profile.GetNamespace(),
+		Name:      profile.GetName(),
+		Revision:  status.Revision,
+		Clusters:  make([]ClusterDescription, 0, len(status.MatchingClusterRefs)),
+	}
+
+	for i := range status.MatchingClusterRefs {
+		cluster := &status.MatchingClusterRefs[i]
+		clusterType := libsveltosv1beta1.ClusterTypeCapi
+		if cluster.Kind == libsveltosv1beta1.SveltosClusterKind {
+			clusterType = libsveltosv1beta1.ClusterTypeSveltos
+		}
+
+		clusterSummary := findClusterSummary(clusterSummaries.Items, cluster.Namespace, cluster.Name, clusterType)
+
+		clusterConfiguration, err := GetClusterConfiguration(ctx, c, cluster.Namespace, cluster.Name, clusterType)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		clusterDescription := describeCluster(cluster, clusterSummary,
+			getProfileFeatures(clusterConfiguration, profileKind, profileName))
+		description.Clusters = append(description.Clusters, *clusterDescription)
+	}
+
+	sort.Slice(description.Clusters, func(i, j int) bool {
+		ci := &description.Clusters[i].Cluster
+		cj := &description.Clusters[j].Cluster
+		if ci.Namespace != cj.Namespace {
+			return ci.Namespace < cj.Namespace
+		}
+		if ci.Name != cj.Name {
+			return ci.Name < cj.Name
+		}
+		return ci.Kind < cj.Kind
+	})
+
+	description.State = DescribeStateReady
+	for i := range description.Clusters {
+		description.State = worstState(description.State, description.Clusters[i].State)
+	}
+
+	return description, nil
+}
+
+func findClusterSummary(clusterSummaries []configv1beta1.ClusterSummary, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) *configv1beta1.ClusterSummary {
+
+	for i := range clusterSummaries {
+		cs := &clusterSummaries[i]
+		if cs.Spec.ClusterNamespace == clusterNamespace && cs.Spec.ClusterName == clusterName &&
+			cs.Spec.ClusterType == clusterType {
+
+			return cs
+		}
+	}
+	return nil
+}
+
+// getProfileFeatures returns the features deployed in a cluster by a ClusterProfile/Profile
+func getProfileFeatures(clusterConfiguration *configv1beta1.ClusterConfiguration, profileKind, profileName string,
+) []configv1beta1.Feature {
+
+	if clusterConfiguration == nil {
+		return nil
+	}
+
+	if profileKind == configv1beta1.ProfileKind {
+		for i := range clusterConfiguration.Status.ProfileResources {
+			if clusterConfiguration.Status.ProfileResources[i].ProfileName == profileName {
+				return clusterConfiguration.Status.ProfileResources[i].Features
+			}
+		}
+		return nil
+	}
+
+	for i := range clusterConfiguration.Status.ClusterProfileResources {
+		if clusterConfiguration.Status.ClusterProfileResources[i].ClusterProfileName == profileName {
+			return clusterConfiguration.Status.ClusterProfileResources[i].Features
+		}
+	}
+	return nil
+}
+
+func describeCluster(cluster *corev1.ObjectReference, clusterSummary *configv1beta1.ClusterSummary,
+	deployed []configv1beta1.Feature) *ClusterDescription {
+
+	description := &ClusterDescription{
+		Cluster: *cluster,
+		State:   DescribeStateProgressing,
+	}
+
+	if clusterSummary == nil {
+		description.Message = "ClusterSummary not created yet"
+		return description
+	}
+
+	description.ClusterSummary = clusterSummary.Name
+	description.ObservedRevision = clusterSummary.Status.ObservedRevision
+	for i := range clusterSummary.Status.FeatureSummaries {
+		fs := &clusterSummary.Status.FeatureSummaries[i]
+		description.Features = append(description.Features,
+			*describeFeature(fs, clusterSummary, getDeployedFeature(deployed, fs.FeatureID)))
+	}
+
+	sort.Slice(description.Features, func(i, j int) bool {
+		return description.Features[i].FeatureID < description.Features[j].FeatureID
+	})
+
+	if IsClusterSummaryProvisioned(clusterSummary) {
+		description.State = DescribeStateReady
+	}
+	for i := range description.Features {
+		description.State = worstState(description.State, description.Features[i].State)
+	}
+
+	return description
+}
+
+func getDeployedFeature(features []configv1beta1.Feature, featureID configv1beta1.FeatureID) *configv1beta1.Feature {
+	for i := range features {
+		if features[i].FeatureID == featureID {
+			return &features[i]
+		}
+	}
+	return nil
+}
+
+func describeFeature(fs *configv1beta1.FeatureSummary, clusterSummary *configv1beta1.ClusterSummary,
+	deployed *configv1beta1.Feature) *FeatureDescription {
+
+	description := &FeatureDescription{
+		FeatureID:       fs.FeatureID,
+		State:           getFeatureState(fs.Status),
+		LastAppliedTime: fs.LastAppliedTime,
+	}
+	if fs.FailureMessage != nil {
+		description.Message = *fs.FailureMessage
+	}
+
+	if deployed != nil {
+		for i := range deployed.Resources {
+			r := &deployed.Resources[i]
+			description.Resources = append(description.Resources, ResourceDescription{
+				Group:           r.Group,
+				Version:         r.Version,
+				Kind:            r.Kind,
+				Namespace:       r.Namespace,
+				Name:            r.Name,
+				State:           DescribeStateReady,
+				LastAppliedTime: r.LastAppliedTime,
+			})
+		}
+	}
+
+	if fs.FeatureID == configv1beta1.FeatureHelm {
+		description.Releases = describeReleases(clusterSummary, deployed)
+		for i := range description.Releases {
+			if description.Releases[i].State == DescribeStateFailed {
+				description.State = DescribeStateFailed
+			}
+		}
+	}
+
+	return description
+}
+
+// describeReleases returns a description for each helm release referenced by clusterSummary
+func describeReleases(clusterSummary *configv1beta1.ClusterSummary, deployed *configv1beta1.Feature,
+) []ReleaseDescription {
+
+	releases := make([]ReleaseDescription, 0)
+	for i := range clusterSummary.Spec.ClusterProfileSpec.HelmCharts {
+		chart := &clusterSummary.Spec.ClusterProfileSpec.HelmCharts[i]
+		release := ReleaseDescription{
+			Namespace: chart.ReleaseNamespace,
+			Name:      chart.ReleaseName,
+			RepoURL:   chart.RepositoryURL,
+			State:     DescribeStateProgressing,
+		}
+
+		if deployed != nil {
+			for j := range deployed.Charts {
+				c := &deployed.Charts[j]
+				if c.Namespace == chart.ReleaseNamespace && c.ReleaseName == chart.ReleaseName {
+					release.ChartVersion = c.ChartVersion
+					release.LastAppliedTime = c.LastAppliedTime
+					release.State = DescribeStateReady
+				}
+			}
+		}
+
+		for j := range clusterSummary.Status.HelmReleaseSummaries {
+			summary := &clusterSummary.Status.HelmReleaseSummaries[j]
+			if summary.ReleaseNamespace == chart.ReleaseNamespace && summary.ReleaseName == chart.ReleaseName &&
+				summary.Status == configv1beta1.HelmChartStatusConflict {
+
+				release.State = DescribeStateFailed
+				release.Message = summary.ConflictMessage
+			}
+		}
+
+		releases = append(releases, release)
+	}
+
+	return releases
+}
+
+func getFeatureState(status configv1beta1.FeatureStatus) DescribeState {
+	switch status {
+	case configv1beta1.FeatureStatusProvisioned, configv1beta1.FeatureStatusRemoved:
+		return DescribeStateReady
+	case configv1beta1.FeatureStatusFailed, configv1beta1.FeatureStatusFailedNonRetriable:
+		return DescribeStateFailed
+	default:
+		return DescribeStateProgressing
+	}
+}
+
+// worstState returns the worst between two states (Failed, then Progressing, then Ready)
+func worstState(a, b DescribeState) DescribeState {
+	if a == DescribeStateFailed || b == DescribeStateFailed {
+		return DescribeStateFailed
+	}
+	if a == DescribeStateProgressing || b == DescribeStateProgressing {
+		return DescribeStateProgressing
+	}
+	return DescribeStateReady
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addonclient
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// GetClusterSummary returns the ClusterSummary instance created by a specific
+// ClusterProfile/Profile for a specific Cluster
+func GetClusterSummary(ctx context.Context, c client.Client,
+	profileKind, profileName string, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (*configv1beta1.ClusterSummary, error) {
+
+	profileLabel := ClusterProfileLabelName
+	if profileKind == configv1beta1.ProfileKind {
+		profileLabel = ProfileLabelName
+	}
+
+	listOptions := []client.ListOption{
+		client.InNamespace(clusterNamespace),
+		client.MatchingLabels{
+			profileLabel:                   profileName,
+			configv1beta1.ClusterNameLabel: clusterName,
+			configv1beta1.ClusterTypeLabel: string(clusterType),
+		},
+	}
+
+	clusterSummaryList := &configv1beta1.ClusterSummaryList{}
+	if err := c.List(ctx, clusterSummaryList, listOptions...); err != nil {
+		return nil, err
+	}
+
+	if len(clusterSummaryList.Items) == 0 {
+		return nil, apierrors.NewNotFound(
+			schema.GroupResource{Group: configv1beta1.GroupVersion.Group, Resource: configv1beta1.ClusterSummaryKind}, "")
+	}
+
+	if len(clusterSummaryList.Items) != 1 {
+		return nil, fmt.Errorf("more than one clustersummary found for cluster %s/%s created by %s %s",
+			clusterNamespace, clusterName, profileKind, profileName)
+	}
+
+	return &clusterSummaryList.Items[0], nil
+}
+
+// ListClusterSummariesForCluster returns all ClusterSummary instances created for a specific Cluster,
+// regardless of the ClusterProfile/Profile which created them.
+func ListClusterSummariesForCluster(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType,
+) (*configv1beta1.ClusterSummaryList, error) {
+
+	listOptions := []client.ListOption{
+		client.InNamespace(clusterNamespace),
+		client.MatchingLabels{
+			configv1beta1.ClusterNameLabel: clusterName,
+			configv1beta1.ClusterTypeLabel: string(clusterType),
+		},
+	}
+
+	clusterSummaryList := &configv1beta1.ClusterSummaryList{}
+	if err := c.List(ctx, clusterSummaryList, listOptions...); err != nil {
+		return nil, err
+	}
+
+	return clusterSummaryList, nil
+}
+
+// GetClusterConfiguration returns the ClusterConfiguration instance for a specific Cluster
+func GetClusterConfiguration(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType,
+) (*configv1beta1.ClusterConfiguration, error) {
+
+	clusterConfiguration := &configv1beta1.ClusterConfiguration{}
+	if err := c.Get(ctx,
+		types.NamespacedName{
+			Namespace: clusterNamespace,
+			Name:      GetClusterConfigurationName(clusterName, clusterType),
+		},
+		clusterConfiguration); err != nil {
+		return nil, err
+	}
+
+	return clusterConfiguration, nil
+}
+
+// GetClusterReport returns the ClusterReport instance created by a specific
+// ClusterProfile/Profile for a specific Cluster
+func GetClusterReport(ctx context.Context, c client.Client,
+	profileKind, profileName string, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (*configv1beta1.ClusterReport, error) {
+
+	clusterReport := &configv1beta1.ClusterReport{}
+	if err := c.Get(ctx,
+		types.NamespacedName{
+			Namespace: clusterNamespace,
+			Name:      GetClusterReportName(profileKind, profileName, clusterName, clusterType),
+		},
+		clusterReport); err != nil {
+		return nil, err
+	}
+
+	return clusterReport, nil
+}
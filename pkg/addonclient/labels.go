@@ -0,0 +1,34 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package addonclient contains lightweight helpers to interact with the
+// addon-controller CRDs (ClusterProfile, Profile, ClusterSummary,
+// ClusterConfiguration and ClusterReport) without importing the controllers.
+package addonclient
+
+const (
+	// ClusterSummaryLabelName is added to each policy deployed by a ClusterSummary
+	// instance to a CAPI Cluster
+	ClusterSummaryLabelName = "projectsveltos.io/cluster-summary-name"
+
+	// ClusterProfileLabelName is added to all ClusterSummary instances created
+	// by a ClusterProfile instance
+	ClusterProfileLabelName = "projectsveltos.io/cluster-profile-name"
+
+	// ProfileLabelName is added to all ClusterSummary instances created
+	// by a Profile instance
+	ProfileLabelName = "projectsveltos.io/profile-name"
+)
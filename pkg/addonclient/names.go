@@ -0,0 +1,120 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addonclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	nameSeparator = "--"
+
+	// clusterSummaryNameHashLength is the number of hex characters of the hash used in ClusterSummary names
+	clusterSummaryNameHashLength = 10
+)
+
+func getPrefix(clusterType libsveltosv1beta1.ClusterType) string {
+	prefix := "capi"
+	if clusterType == libsveltosv1beta1.ClusterTypeSveltos {
+		prefix = "sveltos"
+	}
+	return prefix
+}
+
+// GetClusterSummaryName returns the ClusterSummary name given a ClusterProfile/Profile kind/name and
+// cluster type/Name.
+// Name is ClusterProfile/Profile name and cluster type/name concatenated (see GetLegacyClusterSummaryName)
+// when that is short enough to be used as label value (ClusterSummaryLabelName is added to all deployed
+// resources). Otherwise it is the hashed name (see GetHashedClusterSummaryName).
+// Concatenated names can collide (for instance, ClusterProfile "a-capi" and cluster "b" vs ClusterProfile "a"
+// and cluster "capi-b"). When creating a ClusterSummary whose name is already taken, the hashed name is used
+// instead. So GetClusterSummary, which looks ClusterSummaries up by labels, is the reliable way to fetch one.
+func GetClusterSummaryName(profileKind, profileName, clusterName string, isSveltosCluster bool) string {
+	name := GetLegacyClusterSummaryName(profileKind, profileName, clusterName, isSveltosCluster)
+	if len(name) <= validation.DNS1123LabelMaxLength {
+		return name
+	}
+
+	return GetHashedClusterSummaryName(profileKind, profileName, clusterName, isSveltosCluster)
+}
+
+// GetHashedClusterSummaryName returns a ClusterSummary name which is unique per ClusterProfile/Profile kind/name
+// and cluster type/name, and short enough to be used as label value: a readable prefix (the legacy name,
+// truncated if needed) followed by a hash of profile kind/name and cluster type/name.
+func GetHashedClusterSummaryName(profileKind, profileName, clusterName string, isSveltosCluster bool) string {
+	clusterType := libsveltosv1beta1.ClusterTypeCapi
+	if isSveltosCluster {
+		clusterType = libsveltosv1beta1.ClusterTypeSveltos
+	}
+
+	h := sha256.New()
+	h.Write([]byte(strings.Join([]string{profileKind, profileName, string(clusterType), clusterName}, "/")))
+	suffix := hex.EncodeToString(h.Sum(nil))[:clusterSummaryNameHashLength]
+
+	prefix := GetLegacyClusterSummaryName(profileKind, profileName, clusterName, isSveltosCluster)
+	if maxPrefixLength := validation.DNS1123LabelMaxLength - len(suffix) - 1; len(prefix) > maxPrefixLength {
+		prefix = prefix[:maxPrefixLength]
+	}
+	// Names must end with an alphanumeric character
+	prefix = strings.TrimRight(prefix, "-.")
+
+	return prefix + "-" + suffix
+}
+
+// GetLegacyClusterSummaryName returns ClusterProfile/Profile name and cluster type/name concatenated, the name
+// ClusterSummaries have always been created with. Such a name can collide or exceed length limits.
+func GetLegacyClusterSummaryName(profileKind, profileName, clusterName string, isSveltosCluster bool) string {
+	clusterType := libsveltosv1beta1.ClusterTypeCapi
+	if isSveltosCluster {
+		clusterType = libsveltosv1beta1.ClusterTypeSveltos
+	}
+	prefix := getPrefix(clusterType)
+	if profileKind == configv1beta1.ClusterProfileKind {
+		// For backward compatibility (code before addition of Profiles) do not change this
+		return fmt.Sprintf("%s-%s-%s", profileName, prefix, clusterName)
+	}
+
+	return fmt.Sprintf("p--%s-%s-%s", profileName, prefix, clusterName)
+}
+
+// GetClusterReportName returns the ClusterReport name given a ClusterProfile/Profile kind/name and
+// cluster type/Name.
+func GetClusterReportName(profileKind, profileName, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) string {
+
+	// TODO: shorten this value
+	prefix := "" // For backward compatibility (before addition of Profile) leave this empty for ClusterProfiles
+	if profileKind == configv1beta1.ProfileKind {
+		prefix = "p--"
+	}
+	return prefix + profileName + nameSeparator + strings.ToLower(string(clusterType)) +
+		nameSeparator + clusterName
+}
+
+// GetClusterConfigurationName returns the ClusterConfiguration name for a given cluster type/Name.
+func GetClusterConfigurationName(clusterName string, clusterType libsveltosv1beta1.ClusterType) string {
+	// TODO: shorten this value
+	return strings.ToLower(string(clusterType)) + nameSeparator + clusterName
+}
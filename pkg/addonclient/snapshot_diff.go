@@ -0,0 +1,250 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addonclient
+
+import (
+	"fmt"
+	"sort"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// ChartChange describes a helm chart whose deployment differs between two
+// ClusterConfiguration snapshots.
+type ChartChange struct {
+	// ProfileKind is the kind (ClusterProfile or Profile) which deployed the chart
+	ProfileKind string
+
+	// ProfileName is the name of the ClusterProfile/Profile which deployed the chart
+	ProfileName string
+
+	// ReleaseNamespace is the namespace of the helm release
+	ReleaseNamespace string
+
+	// ReleaseName is the name of the helm release
+	ReleaseName string
+
+	// RepoURL is the URL of the repository containing the chart
+	RepoURL string
+
+	// FromVersion is the chart version in the older snapshot. Empty for added charts.
+	FromVersion string
+
+	// ToVersion is the chart version in the newer snapshot. Empty for removed charts.
+	ToVersion string
+}
+
+// ResourceChange describes a policy whose deployment differs between two
+// ClusterConfiguration snapshots.
+type ResourceChange struct {
+	// ProfileKind is the kind (ClusterProfile or Profile) which deployed the resource
+	ProfileKind string
+
+	// ProfileName is the name of the ClusterProfile/Profile which deployed the resource
+	ProfileName string
+
+	// FeatureID is the feature (Resources, Kustomize, Helm) which deployed the resource
+	FeatureID configv1beta1.FeatureID
+
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// ConfigurationDiff is the structured diff between two ClusterConfiguration snapshots
+// taken for the same cluster.
+type ConfigurationDiff struct {
+	ChartsAdded    []ChartChange
+	ChartsUpgraded []ChartChange
+	ChartsRemoved  []ChartChange
+
+	ResourcesAdded []ResourceChange
+	// ResourcesUpdated contains resources present in both snapshots which were
+	// re-applied (LastAppliedTime changed) in between.
+	ResourcesUpdated []ResourceChange
+	ResourcesRemoved []ResourceChange
+}
+
+// IsEmpty returns true if nothing changed between the two snapshots
+func (d *ConfigurationDiff) IsEmpty() bool {
+	return len(d.ChartsAdded) == 0 && len(d.ChartsUpgraded) == 0 && len(d.ChartsRemoved) == 0 &&
+		len(d.ResourcesAdded) == 0 && len(d.ResourcesUpdated) == 0 && len(d.ResourcesRemoved) == 0
+}
+
+// DiffClusterConfigurations compares two snapshots of the ClusterConfiguration of
+// a cluster and returns which helm charts were added/upgraded/removed and which
+// policies were added/updated/removed going from "from" to "to".
+// Either snapshot can be nil, meaning nothing was deployed at that point in time.
+func DiffClusterConfigurations(from, to *configv1beta1.ClusterConfiguration) *ConfigurationDiff {
+	fromCharts, fromResources := flattenClusterConfiguration(from)
+	toCharts, toResources := flattenClusterConfiguration(to)
+
+	diff := &ConfigurationDiff{}
+
+	for k, toChart := range toCharts {
+		fromChart, ok := fromCharts[k]
+		if !ok {
+			change := toChart.change
+			change.ToVersion = toChart.version
+			diff.ChartsAdded = append(diff.ChartsAdded, change)
+			continue
+		}
+		if fromChart.version != toChart.version || fromChart.change.RepoURL != toChart.change.RepoURL {
+			change := toChart.change
+			change.FromVersion = fromChart.version
+			change.ToVersion = toChart.version
+			diff.ChartsUpgraded = append(diff.ChartsUpgraded, change)
+		}
+	}
+	for k, fromChart := range fromCharts {
+		if _, ok := toCharts[k]; !ok {
+			change := fromChart.change
+			change.FromVersion = fromChart.version
+			diff.ChartsRemoved = append(diff.ChartsRemoved, change)
+		}
+	}
+
+	for k, toResource := range toResources {
+		fromResource, ok := fromResources[k]
+		if !ok {
+			diff.ResourcesAdded = append(diff.ResourcesAdded, toResource.change)
+			continue
+		}
+		if fromResource.lastApplied != toResource.lastApplied {
+			diff.ResourcesUpdated = append(diff.ResourcesUpdated, toResource.change)
+		}
+	}
+	for k, fromResource := range fromResources {
+		if _, ok := toResources[k]; !ok {
+			diff.ResourcesRemoved = append(diff.ResourcesRemoved, fromResource.change)
+		}
+	}
+
+	sortChartChanges(diff.ChartsAdded)
+	sortChartChanges(diff.ChartsUpgraded)
+	sortChartChanges(diff.ChartsRemoved)
+	sortResourceChanges(diff.ResourcesAdded)
+	sortResourceChanges(diff.ResourcesUpdated)
+	sortResourceChanges(diff.ResourcesRemoved)
+
+	return diff
+}
+
+type chartSnapshot struct {
+	change  ChartChange
+	version string
+}
+
+type resourceSnapshot struct {
+	change      ResourceChange
+	lastApplied string
+}
+
+// flattenClusterConfiguration returns all charts and resources contained in a ClusterConfiguration
+// indexed by a key identifying the Profile which deployed them and their identity in the cluster.
+func flattenClusterConfiguration(clusterConfiguration *configv1beta1.ClusterConfiguration,
+) (charts map[string]chartSnapshot, resources map[string]resourceSnapshot) {
+
+	charts = make(map[string]chartSnapshot)
+	resources = make(map[string]resourceSnapshot)
+
+	if clusterConfiguration == nil {
+		return charts, resources
+	}
+
+	for i := range clusterConfiguration.Status.ClusterProfileResources {
+		cpr := &clusterConfiguration.Status.ClusterProfileResources[i]
+		flattenFeatures(configv1beta1.ClusterProfileKind, cpr.ClusterProfileName, cpr.Features, charts, resources)
+	}
+
+	for i := range clusterConfiguration.Status.ProfileResources {
+		pr := &clusterConfiguration.Status.ProfileResources[i]
+		flattenFeatures(configv1beta1.ProfileKind, pr.ProfileName, pr.Features, charts, resources)
+	}
+
+	return charts, resources
+}
+
+func flattenFeatures(profileKind, profileName string, features []configv1beta1.Feature,
+	charts map[string]chartSnapshot, resources map[string]resourceSnapshot) {
+
+	for i := range features {
+		f := &features[i]
+		for j := range f.Charts {
+			chart := &f.Charts[j]
+			key := fmt.Sprintf("%s:%s:%s/%s", profileKind, profileName, chart.Namespace, chart.ReleaseName)
+			charts[key] = chartSnapshot{
+				change: ChartChange{
+					ProfileKind:      profileKind,
+					ProfileName:      profileName,
+					ReleaseNamespace: chart.Namespace,
+					ReleaseName:      chart.ReleaseName,
+					RepoURL:          chart.RepoURL,
+				},
+				version: chart.ChartVersion,
+			}
+		}
+
+		for j := range f.Resources {
+			resource := &f.Resources[j]
+			key := fmt.Sprintf("%s:%s:%s:%s/%s:%s/%s", profileKind, profileName, f.FeatureID,
+				resource.Group, resource.Kind, resource.Namespace, resource.Name)
+			lastApplied := ""
+			if resource.LastAppliedTime != nil {
+				lastApplied = resource.LastAppliedTime.UTC().String()
+			}
+			resources[key] = resourceSnapshot{
+				change: ResourceChange{
+					ProfileKind: profileKind,
+					ProfileName: profileName,
+					FeatureID:   f.FeatureID,
+					Group:       resource.Group,
+					Kind:        resource.Kind,
+					Namespace:   resource.Namespace,
+					Name:        resource.Name,
+				},
+				lastApplied: lastApplied,
+			}
+		}
+	}
+}
+
+func sortChartChanges(changes []ChartChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].ProfileKind != changes[j].ProfileKind {
+			return changes[i].ProfileKind < changes[j].ProfileKind
+		}
+		if changes[i].ProfileName != changes[j].ProfileName {
+			return changes[i].ProfileName < changes[j].ProfileName
+		}
+		if changes[i].ReleaseNamespace != changes[j].ReleaseNamespace {
+			return changes[i].ReleaseNamespace < changes[j].ReleaseNamespace
+		}
+		return changes[i].ReleaseName < changes[j].ReleaseName
+	})
+}
+
+func sortResourceChanges(changes []ResourceChange) {
+	key := func(r *ResourceChange) string {
+		return fmt.Sprintf("%s:%s:%s:%s/%s:%s/%s", r.ProfileKind, r.ProfileName, r.FeatureID,
+			r.Group, r.Kind, r.Namespace, r.Name)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return key(&changes[i]) < key(&changes[j])
+	})
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addonclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// Every time the add-ons deployed in a cluster change, the controller records a snapshot of the
+// cluster ClusterConfiguration Status. Snapshots are stored in a ConfigMap, in the cluster namespace,
+// owned by the ClusterConfiguration (see GetClusterConfigurationSnapshotsName). Each key is the time
+// the snapshot was recorded (see GetClusterConfigurationSnapshotKey), each value the Status in JSON.
+
+const (
+	clusterConfigurationSnapshotsSuffix = "-snapshots"
+)
+
+// ClusterConfigurationSnapshot is the ClusterConfiguration of a cluster at a point in time
+type ClusterConfigurationSnapshot struct {
+	// Time is when the snapshot was recorded
+	Time time.Time
+
+	// ClusterConfiguration contains the ClusterConfiguration name, namespace and Status
+	// when the snapshot was recorded
+	ClusterConfiguration *configv1beta1.ClusterConfiguration
+}
+
+// GetClusterConfigurationSnapshotsName returns the name of the ConfigMap containing the snapshots
+// of a ClusterConfiguration
+func GetClusterConfigurationSnapshotsName(clusterConfigurationName string) string {
+	name := clusterConfigurationName + clusterConfigurationSnapshotsSuffix
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = fmt.Sprintf("%x%s", sha256.Sum256([]byte(clusterConfigurationName)), clusterConfigurationSnapshotsSuffix)
+	}
+	return name
+}
+
+// GetClusterConfigurationSnapshotKey returns the ConfigMap key of a snapshot recorded at t
+func GetClusterConfigurationSnapshotKey(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// ParseClusterConfigurationSnapshots returns the snapshots contained in configMap, oldest first
+func ParseClusterConfigurationSnapshots(configMap *corev1.ConfigMap, clusterConfigurationName string,
+) ([]ClusterConfigurationSnapshot, error) {
+
+	snapshots := make([]ClusterConfigurationSnapshot, 0, len(configMap.Data))
+	for key, value := range configMap.Data {
+		nsec, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot key %q: %w", key, err)
+		}
+
+		clusterConfiguration := &configv1beta1.ClusterConfiguration{}
+		clusterConfiguration.Namespace = configMap.Namespace
+		clusterConfiguration.Name = clusterConfigurationName
+		if err := json.Unmarshal([]byte(value), &clusterConfiguration.Status); err != nil {
+			return nil, fmt.Errorf("invalid snapshot %q: %w", key, err)
+		}
+
+		snapshots = append(snapshots, ClusterConfigurationSnapshot{
+			Time:                 time.Unix(0, nsec),
+			ClusterConfiguration: clusterConfiguration,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+	return snapshots, nil
+}
+
+// ListClusterConfigurationSnapshots returns the recorded snapshots of a cluster ClusterConfiguration,
+// oldest first. Only the most recent ones are retained by the controller.
+func ListClusterConfigurationSnapshots(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType,
+) ([]ClusterConfigurationSnapshot, error) {
+
+	clusterConfigurationName := GetClusterConfigurationName(clusterName, clusterType)
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{
+		Namespace: clusterNamespace,
+		Name:      GetClusterConfigurationSnapshotsName(clusterConfigurationName),
+	}, configMap)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return ParseClusterConfigurationSnapshots(configMap, clusterConfigurationName)
+}
+
+// GetClusterConfigurationSnapshotAt returns the snapshot in effect at t, that is the most recent one
+// recorded at or before t. Returns nil if none was.
+func GetClusterConfigurationSnapshotAt(snapshots []ClusterConfigurationSnapshot, t time.Time,
+) *ClusterConfigurationSnapshot {
+
+	var found *ClusterConfigurationSnapshot
+	for i := range snapshots {
+		if snapshots[i].Time.After(t) {
+			break
+		}
+		found = &snapshots[i]
+	}
+	return found
+}
+
+// DiffClusterConfigurationSince returns what changed in the add-ons deployed in a cluster since t:
+// the diff between the snapshot in effect at t and the most recent one.
+// If the oldest snapshot retained is more recent than t, it is used as starting point. Use
+// ListClusterConfigurationSnapshots and DiffClusterConfigurations to know which snapshots are compared.
+func DiffClusterConfigurationSince(ctx context.Context, c client.Client,
+	clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType, t time.Time,
+) (*ConfigurationDiff, error) {
+
+	snapshots, err := ListClusterConfigurationSnapshots(ctx, c, clusterNamespace, clusterName, clusterType)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return &ConfigurationDiff{}, nil
+	}
+
+	from := GetClusterConfigurationSnapshotAt(snapshots, t)
+	if from == nil {
+		from = &snapshots[0]
+	}
+	return DiffClusterConfigurations(from.ClusterConfiguration, snapshots[len(snapshots)-1].ClusterConfiguration), nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addonclient
+
+import (
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+)
+
+// GetFeatureSummaryForFeatureID returns the FeatureSummary for a given feature.
+// Returns nil if ClusterSummary has no status for such feature yet.
+func GetFeatureSummaryForFeatureID(clusterSummary *configv1beta1.ClusterSummary, fID configv1beta1.FeatureID,
+) *configv1beta1.FeatureSummary {
+
+	for i := range clusterSummary.Status.FeatureSummaries {
+		if clusterSummary.Status.FeatureSummaries[i].FeatureID == fID {
+			return &clusterSummary.Status.FeatureSummaries[i]
+		}
+	}
+
+	return nil
+}
+
+// GetFailedFeatureSummaries returns all FeatureSummaries currently reporting a failure
+func GetFailedFeatureSummaries(clusterSummary *configv1beta1.ClusterSummary) []configv1beta1.FeatureSummary {
+	failed := make([]configv1beta1.FeatureSummary, 0)
+	for i := range clusterSummary.Status.FeatureSummaries {
+		fs := &clusterSummary.Status.FeatureSummaries[i]
+		if fs.Status == configv1beta1.FeatureStatusFailed ||
+			fs.Status == configv1beta1.FeatureStatusFailedNonRetriable {
+
+			failed = append(failed, *fs)
+		}
+	}
+
+	return failed
+}
+
+// IsClusterSummaryProvisioned returns true if ClusterSummary is currently fully deployed.
+func IsClusterSummaryProvisioned(clusterSumary *configv1beta1.ClusterSummary) bool {
+	hasHelmCharts := len(clusterSumary.Spec.ClusterProfileSpec.HelmCharts) != 0
+	hasRawYAMLs := len(clusterSumary.Spec.ClusterProfileSpec.PolicyRefs) != 0 ||
+		clusterSumary.Spec.ClusterProfileSpec.SourceRef != nil
+	hasKustomize := len(clusterSumary.Spec.ClusterProfileSpec.KustomizationRefs) != 0
+	hasClusterMetadata := len(clusterSumary.Spec.ClusterProfileSpec.ClusterMetadataPropagations) != 0
+
+	deployedHelmCharts := false
+	deployedRawYAMLs := false
+	deployedKustomize := false
+	deployedClusterMetadata := false
+
+	for i := range clusterSumary.Status.FeatureSummaries {
+		fs := &clusterSumary.Status.FeatureSummaries[i]
+		if fs.Status != configv1beta1.FeatureStatusProvisioned {
+			return false
+		}
+		switch fs.FeatureID {
+		case configv1beta1.FeatureHelm:
+			deployedHelmCharts = true
+		case configv1beta1.FeatureResources:
+			deployedRawYAMLs = true
+		case configv1beta1.FeatureKustomize:
+			deployedKustomize = true
+		case configv1beta1.FeatureClusterMetadata:
+			deployedClusterMetadata = true
+		}
+	}
+
+	if hasHelmCharts && !deployedHelmCharts {
+		return false
+	}
+
+	if hasRawYAMLs && !deployedRawYAMLs {
+		return false
+	}
+
+	if hasKustomize && !deployedKustomize {
+		return false
+	}
+
+	if hasClusterMetadata && !deployedClusterMetadata {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,386 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterdeployer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// clusterdeployer implements the libsveltos DeployerInterface with a shared pool of workers
+// and one bounded queue per managed cluster.
+//
+// Same as libsveltos deployer, a request to deploy a feature in a cluster:
+// - is dropped if already waiting to be served (dirty);
+// - is only added to dirty if currently in progress. It will be queued again once the
+// in progress one is done. So same request is never processed more than once in parallel.
+//
+// Differently from libsveltos deployer, requests are queued in the queue of their cluster.
+// Workers serve clusters in round-robin and never process more than PerClusterWorkers
+// requests for the same cluster in parallel. So a slow or unreachable cluster (for instance
+// a hanging helm install) can occupy at most PerClusterWorkers workers while remaining
+// workers keep serving healthy clusters.
+
+// ErrQueueFull is returned by Deploy when the queue of the cluster is full
+var ErrQueueFull = errors.New("cluster deployment queue is full")
+
+const (
+	defaultPoolSize          = 20
+	defaultPerClusterWorkers = 1
+)
+
+// Options configures the ClusterDeployer
+type Options struct {
+	// PoolSize is the number of workers shared by all clusters
+	PoolSize int
+
+	// PerClusterWorkers is the maximum number of requests processed in parallel for
+	// a given cluster
+	PerClusterWorkers int
+
+	// PerClusterQueueSize is the maximum number of requests queued for a given cluster.
+	// Zero means no limit.
+	PerClusterQueueSize int
+}
+
+type request struct {
+	key              string
+	clusterNamespace string
+	clusterName      string
+	applicant        string
+	featureID        string
+	clusterType      libsveltosv1beta1.ClusterType
+	cleanup          bool
+	handler          deployer.RequestHandler
+	metric           deployer.MetricHandler
+	handlerOptions   deployer.Options
+}
+
+// clusterQueue contains requests for a given cluster
+type clusterQueue struct {
+	key string
+
+	// jobQueue contains the requests for this cluster waiting for a worker
+	jobQueue []*request
+
+	// active is the number of requests for this cluster currently being processed
+	active int
+
+	// ready is true when this cluster is in the ready list
+	ready bool
+}
+
+// ClusterDeployer implements the DeployerInterface
+type ClusterDeployer struct {
+	log logr.Logger
+	client.Client
+
+	options Options
+
+	mu   *sync.Mutex
+	cond *sync.Cond
+
+	// dirty contains all requests waiting to be served. That includes requests which arrived
+	// again while being processed; those are queued again, with latest parameters, once done.
+	dirty map[string]*request
+
+	// inProgress contains all requests currently being served
+	inProgress map[string]bool
+
+	// clusters contains the queue of each cluster with pending or in progress requests
+	clusters map[string]*clusterQueue
+
+	// ready contains, in the order they will be served, clusters with queued requests
+	// and fewer than PerClusterWorkers requests in progress
+	ready []*clusterQueue
+
+	// results contains results for processed requests
+	results map[string]error
+
+	// features contains currently registered feature IDs
+	features map[string]bool
+}
+
+var (
+	getClientLock    = &sync.Mutex{}
+	deployerInstance *ClusterDeployer
+)
+
+// GetClient returns the ClusterDeployer. First invocation creates it and starts its workers.
+func GetClient(ctx context.Context, l logr.Logger, c client.Client, options Options) *ClusterDeployer {
+	getClientLock.Lock()
+	defer getClientLock.Unlock()
+
+	if deployerInstance == nil {
+		deployerInstance = newClusterDeployer(ctx, l, c, options)
+	}
+
+	return deployerInstance
+}
+
+// GetDeployer returns the ClusterDeployer. Nil if GetClient was never invoked.
+func GetDeployer() *ClusterDeployer {
+	getClientLock.Lock()
+	defer getClientLock.Unlock()
+
+	return deployerInstance
+}
+
+func newClusterDeployer(ctx context.Context, l logr.Logger, c client.Client, options Options) *ClusterDeployer {
+	if options.PoolSize <= 0 {
+		options.PoolSize = defaultPoolSize
+	}
+	if options.PerClusterWorkers <= 0 {
+		options.PerClusterWorkers = defaultPerClusterWorkers
+	}
+
+	l.V(logs.LogInfo).Info(fmt.Sprintf("Creating instance now. Number of workers: %d. Per cluster workers: %d",
+		options.PoolSize, options.PerClusterWorkers))
+
+	mu := &sync.Mutex{}
+	d := &ClusterDeployer{
+		log:        l,
+		Client:     c,
+		options:    options,
+		mu:         mu,
+		cond:       sync.NewCond(mu),
+		dirty:      make(map[string]*request),
+		inProgress: make(map[string]bool),
+		clusters:   make(map[string]*clusterQueue),
+		results:    make(map[string]error),
+		features:   make(map[string]bool),
+	}
+
+	d.startWorkers(ctx)
+
+	return d
+}
+
+func (d *ClusterDeployer) RegisterFeatureID(featureID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.features[featureID]; ok {
+		return fmt.Errorf("featureID %s is already registered", featureID)
+	}
+
+	d.features[featureID] = true
+	return nil
+}
+
+func (d *ClusterDeployer) Deploy(
+	ctx context.Context,
+	clusterNamespace, clusterName, applicant, featureID string,
+	clusterType libsveltosv1beta1.ClusterType,
+	cleanup bool,
+	f deployer.RequestHandler,
+	m deployer.MetricHandler,
+	o deployer.Options,
+) error {
+
+	key := deployer.GetKey(clusterNamespace, clusterName, applicant, featureID, clusterType, cleanup)
+	logger := d.log.WithValues("key", key)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.features[featureID]; !ok {
+		return fmt.Errorf("featureID %s is not registered", featureID)
+	}
+
+	if _, ok := d.dirty[key]; ok {
+		logger.V(logs.LogVerbose).Info("request is already present in dirty")
+		return nil
+	}
+
+	req := &request{
+		key:              key,
+		clusterNamespace: clusterNamespace,
+		clusterName:      clusterName,
+		applicant:        applicant,
+		featureID:        featureID,
+		clusterType:      clusterType,
+		cleanup:          cleanup,
+		handler:          f,
+		metric:           m,
+		handlerOptions:   o,
+	}
+
+	queue := d.getClusterQueue(clusterNamespace, clusterName, clusterType)
+
+	// Push to queue if not already in progress. Otherwise it is queued once in progress one is done.
+	if !d.inProgress[key] {
+		if d.options.PerClusterQueueSize > 0 && len(queue.jobQueue) >= d.options.PerClusterQueueSize {
+			d.removeClusterQueueIfIdle(queue)
+			return ErrQueueFull
+		}
+		logger.V(logs.LogVerbose).Info("request added to jobQueue")
+		queue.jobQueue = append(queue.jobQueue, req)
+		d.markReady(queue)
+	}
+
+	// Since we got a new request, if a result was saved, clear it.
+	delete(d.results, key)
+	d.dirty[key] = req
+
+	return nil
+}
+
+func (d *ClusterDeployer) GetResult(
+	ctx context.Context,
+	clusterNamespace, clusterName, applicant, featureID string,
+	clusterType libsveltosv1beta1.ClusterType,
+	cleanup bool,
+) deployer.Result {
+
+	key := deployer.GetKey(clusterNamespace, clusterName, applicant, featureID, clusterType, cleanup)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err, ok := d.results[key]; ok {
+		// Result is returned only once
+		delete(d.results, key)
+		if err != nil {
+			return deployer.Result{ResultStatus: deployer.Failed, Err: err}
+		}
+		if cleanup {
+			return deployer.Result{ResultStatus: deployer.Removed}
+		}
+		return deployer.Result{ResultStatus: deployer.Deployed}
+	}
+
+	if _, ok := d.dirty[key]; ok || d.inProgress[key] {
+		return deployer.Result{ResultStatus: deployer.InProgress}
+	}
+
+	// Request has not been processed nor is currently queued
+	return deployer.Result{ResultStatus: deployer.Unavailable}
+}
+
+func (d *ClusterDeployer) IsInProgress(
+	clusterNamespace, clusterName, applicant, featureID string,
+	clusterType libsveltosv1beta1.ClusterType,
+	cleanup bool,
+) bool {
+
+	key := deployer.GetKey(clusterNamespace, clusterName, applicant, featureID, clusterType, cleanup)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.inProgress[key]
+}
+
+func (d *ClusterDeployer) CleanupEntries(
+	clusterNamespace, clusterName, applicant, featureID string,
+	clusterType libsveltosv1beta1.ClusterType,
+	cleanup bool) {
+
+	key := deployer.GetKey(clusterNamespace, clusterName, applicant, featureID, clusterType, cleanup)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.dirty, key)
+	delete(d.results, key)
+
+	queue, ok := d.clusters[getClusterKey(clusterNamespace, clusterName, clusterType)]
+	if !ok {
+		return
+	}
+	for i := range queue.jobQueue {
+		if queue.jobQueue[i].key == key {
+			queue.jobQueue = append(queue.jobQueue[:i], queue.jobQueue[i+1:]...)
+			break
+		}
+	}
+	if len(queue.jobQueue) == 0 {
+		d.unmarkReady(queue)
+	}
+	d.removeClusterQueueIfIdle(queue)
+}
+
+// Stats returns the number of requests waiting to be served and the number of workers busy
+func (d *ClusterDeployer) Stats() (queued, busy int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.dirty), len(d.inProgress)
+}
+
+func getClusterKey(clusterNamespace, clusterName string, clusterType libsveltosv1beta1.ClusterType) string {
+	return fmt.Sprintf("%s:%s/%s", clusterType, clusterNamespace, clusterName)
+}
+
+// getClusterQueue returns the queue for a cluster, creating it if it does not exist yet.
+// Must be called with lock held.
+func (d *ClusterDeployer) getClusterQueue(clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) *clusterQueue {
+
+	clusterKey := getClusterKey(clusterNamespace, clusterName, clusterType)
+	queue, ok := d.clusters[clusterKey]
+	if !ok {
+		queue = &clusterQueue{key: clusterKey}
+		d.clusters[clusterKey] = queue
+	}
+	return queue
+}
+
+// removeClusterQueueIfIdle forgets about a cluster with no queued nor in progress requests.
+// Must be called with lock held.
+func (d *ClusterDeployer) removeClusterQueueIfIdle(queue *clusterQueue) {
+	if len(queue.jobQueue) == 0 && queue.active == 0 && !queue.ready {
+		delete(d.clusters, queue.key)
+	}
+}
+
+// markReady adds cluster to the back of the ready list if it has queued requests
+// and can have more requests in progress. Must be called with lock held.
+func (d *ClusterDeployer) markReady(queue *clusterQueue) {
+	if queue.ready || len(queue.jobQueue) == 0 || queue.active >= d.options.PerClusterWorkers {
+		return
+	}
+
+	queue.ready = true
+	d.ready = append(d.ready, queue)
+	d.cond.Signal()
+}
+
+// unmarkReady removes cluster from the ready list. Must be called with lock held.
+func (d *ClusterDeployer) unmarkReady(queue *clusterQueue) {
+	if !queue.ready {
+		return
+	}
+
+	for i := range d.ready {
+		if d.ready[i] == queue {
+			d.ready = append(d.ready[:i], d.ready[i+1:]...)
+			break
+		}
+	}
+	queue.ready = false
+}
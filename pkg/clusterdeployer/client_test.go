@@ -0,0 +1,208 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterdeployer_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/klog/v2/textlogger"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/projectsveltos/addon-controller/pkg/clusterdeployer"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+)
+
+const (
+	featureResources = "Resources"
+	featureHelm      = "Helm"
+	featureKustomize = "Kustomize"
+
+	slowCluster    = "slow"
+	healthyCluster = "healthy"
+	namespace      = "default"
+	applicant      = "applicant"
+)
+
+var _ = Describe("ClusterDeployer", func() {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var block chan struct{}
+	var invocations atomic.Int32
+
+	// handler blocks while deploying in the slow cluster till block is closed
+	handler := func(ctx context.Context, c client.Client,
+		clusterNamespace, clusterName, applicant, featureID string,
+		clusterType libsveltosv1beta1.ClusterType, o deployer.Options, logger logr.Logger) error {
+
+		invocations.Add(1)
+		if clusterName == slowCluster {
+			<-block
+		}
+		return nil
+	}
+
+	getResult := func(d *clusterdeployer.ClusterDeployer, clusterName, featureID string) deployer.ResultStatus {
+		return d.GetResult(ctx, namespace, clusterName, applicant, featureID,
+			libsveltosv1beta1.ClusterTypeCapi, false).ResultStatus
+	}
+
+	newDeployer := func(options clusterdeployer.Options) *clusterdeployer.ClusterDeployer {
+		d := clusterdeployer.NewClusterDeployer(ctx, textlogger.NewLogger(textlogger.NewConfig()),
+			fake.NewClientBuilder().Build(), options)
+		for _, featureID := range []string{featureResources, featureHelm, featureKustomize} {
+			Expect(d.RegisterFeatureID(featureID)).To(Succeed())
+		}
+		return d
+	}
+
+	deploy := func(d *clusterdeployer.ClusterDeployer, clusterName, featureID string) error {
+		return d.Deploy(ctx, namespace, clusterName, applicant, featureID, libsveltosv1beta1.ClusterTypeCapi,
+			false, handler, nil, deployer.Options{})
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.TODO())
+		block = make(chan struct{})
+		invocations.Store(0)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("a slow cluster does not prevent deploying in other clusters", func() {
+		d := newDeployer(clusterdeployer.Options{PoolSize: 2, PerClusterWorkers: 1})
+
+		Expect(deploy(d, slowCluster, featureResources)).To(Succeed())
+		Expect(deploy(d, slowCluster, featureHelm)).To(Succeed())
+		Expect(deploy(d, healthyCluster, featureResources)).To(Succeed())
+		Expect(deploy(d, healthyCluster, featureHelm)).To(Succeed())
+
+		Eventually(func() bool {
+			return d.IsInProgress(namespace, slowCluster, applicant, featureResources,
+				libsveltosv1beta1.ClusterTypeCapi, false)
+		}, time.Minute, time.Millisecond*100).Should(BeTrue())
+
+		Eventually(func() deployer.ResultStatus {
+			return getResult(d, healthyCluster, featureResources)
+		}, time.Minute, time.Millisecond*100).Should(Equal(deployer.Deployed))
+		Eventually(func() deployer.ResultStatus {
+			return getResult(d, healthyCluster, featureHelm)
+		}, time.Minute, time.Millisecond*100).Should(Equal(deployer.Deployed))
+
+		// Only one worker at a time serves the slow cluster
+		Expect(d.IsInProgress(namespace, slowCluster, applicant, featureHelm,
+			libsveltosv1beta1.ClusterTypeCapi, false)).To(BeFalse())
+		Expect(getResult(d, slowCluster, featureHelm)).To(Equal(deployer.InProgress))
+		queued, busy := d.Stats()
+		Expect(queued).To(Equal(1))
+		Expect(busy).To(Equal(1))
+
+		close(block)
+		Eventually(func() deployer.ResultStatus {
+			return getResult(d, slowCluster, featureHelm)
+		}, time.Minute, time.Millisecond*100).Should(Equal(deployer.Deployed))
+		// Result is consumed once returned
+		Expect(getResult(d, slowCluster, featureHelm)).To(Equal(deployer.Unavailable))
+	})
+
+	It("Deploy fails when cluster queue is full", func() {
+		d := newDeployer(clusterdeployer.Options{PoolSize: 1, PerClusterWorkers: 1, PerClusterQueueSize: 1})
+
+		Expect(deploy(d, slowCluster, featureResources)).To(Succeed())
+		Eventually(func() bool {
+			return d.IsInProgress(namespace, slowCluster, applicant, featureResources,
+				libsveltosv1beta1.ClusterTypeCapi, false)
+		}, time.Minute, time.Millisecond*100).Should(BeTrue())
+
+		Expect(deploy(d, slowCluster, featureHelm)).To(Succeed())
+		Expect(deploy(d, slowCluster, featureKustomize)).To(MatchError(clusterdeployer.ErrQueueFull))
+
+		// Request already queued is dropped, not rejected
+		Expect(deploy(d, slowCluster, featureHelm)).To(Succeed())
+
+		// CleanupEntries frees the queue
+		d.CleanupEntries(namespace, slowCluster, applicant, featureHelm, libsveltosv1beta1.ClusterTypeCapi, false)
+		Expect(getResult(d, slowCluster, featureHelm)).To(Equal(deployer.Unavailable))
+		Expect(deploy(d, slowCluster, featureKustomize)).To(Succeed())
+
+		close(block)
+	})
+
+	It("request arriving while in progress is processed again", func() {
+		d := newDeployer(clusterdeployer.Options{PoolSize: 2, PerClusterWorkers: 2})
+
+		Expect(deploy(d, slowCluster, featureResources)).To(Succeed())
+		Eventually(func() bool {
+			return d.IsInProgress(namespace, slowCluster, applicant, featureResources,
+				libsveltosv1beta1.ClusterTypeCapi, false)
+		}, time.Minute, time.Millisecond*100).Should(BeTrue())
+
+		// Same request is never processed twice in parallel
+		Expect(deploy(d, slowCluster, featureResources)).To(Succeed())
+		Consistently(func() int32 {
+			return invocations.Load()
+		}, time.Second, time.Millisecond*100).Should(Equal(int32(1)))
+
+		close(block)
+		Eventually(func() deployer.ResultStatus {
+			return getResult(d, slowCluster, featureResources)
+		}, time.Minute, time.Millisecond*100).Should(Equal(deployer.Deployed))
+		Expect(invocations.Load()).To(Equal(int32(2)))
+	})
+
+	It("removing the last queued request of a ready cluster does not block other requests", func() {
+		d := newDeployer(clusterdeployer.Options{PoolSize: 1, PerClusterWorkers: 1})
+
+		Expect(deploy(d, slowCluster, featureResources)).To(Succeed())
+		Eventually(func() bool {
+			return d.IsInProgress(namespace, slowCluster, applicant, featureResources,
+				libsveltosv1beta1.ClusterTypeCapi, false)
+		}, time.Minute, time.Millisecond*100).Should(BeTrue())
+
+		// Only worker is busy, so healthy cluster is left ready with one queued request
+		Expect(deploy(d, healthyCluster, featureResources)).To(Succeed())
+		d.CleanupEntries(namespace, healthyCluster, applicant, featureResources, libsveltosv1beta1.ClusterTypeCapi, false)
+		Expect(getResult(d, healthyCluster, featureResources)).To(Equal(deployer.Unavailable))
+
+		// Worker looks for next request once done with the slow cluster
+		close(block)
+		Eventually(func() deployer.ResultStatus {
+			return getResult(d, slowCluster, featureResources)
+		}, time.Minute, time.Millisecond*100).Should(Equal(deployer.Deployed))
+
+		Expect(deploy(d, healthyCluster, featureHelm)).To(Succeed())
+		Eventually(func() deployer.ResultStatus {
+			return getResult(d, healthyCluster, featureHelm)
+		}, time.Minute, time.Millisecond*100).Should(Equal(deployer.Deployed))
+		Expect(invocations.Load()).To(Equal(int32(2)))
+	})
+
+	It("RegisterFeatureID fails for already registered features", func() {
+		d := newDeployer(clusterdeployer.Options{})
+		Expect(d.RegisterFeatureID(featureHelm)).ToNot(Succeed())
+		Expect(d.Deploy(ctx, namespace, healthyCluster, applicant, "unregistered",
+			libsveltosv1beta1.ClusterTypeCapi, false, handler, nil, deployer.Options{})).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,133 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterdeployer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+func (d *ClusterDeployer) startWorkers(ctx context.Context) {
+	for i := 0; i < d.options.PoolSize; i++ {
+		go d.processRequests(ctx, i)
+	}
+
+	// Wake up all workers on shutdown
+	go func() {
+		<-ctx.Done()
+		d.mu.Lock()
+		d.cond.Broadcast()
+		d.mu.Unlock()
+	}()
+}
+
+func (d *ClusterDeployer) processRequests(ctx context.Context, id int) {
+	logger := d.log.WithValues("worker", id)
+	logger.V(logs.LogInfo).Info("started worker")
+
+	for {
+		req := d.next(ctx)
+		if req == nil {
+			logger.V(logs.LogInfo).Info("context canceled")
+			return
+		}
+
+		l := logger.WithValues("key", req.key)
+		l.V(logs.LogDebug).Info(fmt.Sprintf("processing request. cleanup: %t", req.cleanup))
+		start := time.Now()
+		err := req.handler(ctx, d.Client, req.clusterNamespace, req.clusterName, req.applicant, req.featureID,
+			req.clusterType, req.handlerOptions, l)
+		d.storeResult(req, err)
+		if req.metric != nil {
+			req.metric(time.Since(start), req.clusterNamespace, req.clusterName, req.featureID, req.clusterType, l)
+		}
+	}
+}
+
+// next blocks till a request can be served and returns it. Clusters are served in round-robin.
+// Returns nil when context is canceled.
+func (d *ClusterDeployer) next(ctx context.Context) *request {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var queue *clusterQueue
+	for queue == nil {
+		for len(d.ready) == 0 {
+			if ctx.Err() != nil {
+				return nil
+			}
+			d.cond.Wait()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		queue = d.ready[0]
+		d.ready = d.ready[1:]
+		queue.ready = false
+
+		// Requests might have been removed since cluster was marked ready
+		if len(queue.jobQueue) == 0 {
+			d.removeClusterQueueIfIdle(queue)
+			queue = nil
+		}
+	}
+
+	req := queue.jobQueue[0]
+	queue.jobQueue = queue.jobQueue[1:]
+	queue.active++
+
+	d.inProgress[req.key] = true
+	delete(d.dirty, req.key)
+
+	// If this cluster can still be served, put it at the back of the ready list
+	d.markReady(queue)
+
+	return req
+}
+
+// storeResult stores result for further in time lookup and, if the same request arrived
+// while being processed, queues it again.
+func (d *ClusterDeployer) storeResult(req *request, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	l := d.log.WithValues("key", req.key)
+	if err != nil {
+		l.V(logs.LogDebug).Info(fmt.Sprintf("added to result with err %s", err.Error()))
+	} else {
+		l.V(logs.LogDebug).Info("added to result")
+	}
+
+	delete(d.inProgress, req.key)
+
+	queue := d.getClusterQueue(req.clusterNamespace, req.clusterName, req.clusterType)
+	queue.active--
+
+	if dirtyReq, ok := d.dirty[req.key]; ok {
+		l.V(logs.LogVerbose).Info("request arrived again while in progress. Add to jobQueue")
+		queue.jobQueue = append(queue.jobQueue, dirtyReq)
+	} else {
+		d.results[req.key] = err
+	}
+
+	d.markReady(queue)
+	d.removeClusterQueueIfIdle(queue)
+}
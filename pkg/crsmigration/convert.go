@@ -0,0 +1,173 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crsmigration helps ClusterAPI users migrating from ClusterResourceSets to Sveltos.
+//
+// A ClusterResourceSet deploys the content of ConfigMaps/Secrets in its namespace to all
+// clusters in the same namespace matching its selector. The equivalent Sveltos resource is
+// a Profile (namespaced as well) referencing the same ConfigMaps/Secrets in its PolicyRefs.
+//
+// Resources already applied by a ClusterResourceSet are adopted: when a Profile deploys an
+// object which already exists in the managed cluster and is not managed by any other
+// ClusterProfile/Profile, Sveltos takes ownership of it and updates it in place. Since content
+// is unchanged, objects are not deleted/recreated.
+package crsmigration
+
+import (
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// MigratedFromLabel is added to every Profile and Secret created by the migration.
+	// Value is the name of the ClusterResourceSet.
+	MigratedFromLabel = "projectsveltos.io/migrated-from-clusterresourceset"
+
+	// migratedSecretSuffix is appended to the name of Secrets copied from the ones referenced
+	// by a ClusterResourceSet. Secrets of ClusterResourceSet type can be referenced as they are,
+	// but copies, of type addons.projectsveltos.io/cluster-profile, survive ClusterResourceSet
+	// cleanup.
+	migratedSecretSuffix = "-sveltos"
+)
+
+// Result contains the outcome of converting a ClusterResourceSet
+type Result struct {
+	// Profile is the Profile equivalent to the ClusterResourceSet
+	Profile *configv1beta1.Profile
+
+	// Secrets maps the name of each Secret referenced by the ClusterResourceSet to the
+	// name of the copy, with type accepted by Sveltos, referenced by the Profile.
+	Secrets map[string]string
+
+	// AdoptedClusters lists the clusters where ClusterResourceSet already applied all
+	// resources. Resources in those clusters will be adopted by the Profile.
+	AdoptedClusters []string
+
+	// Warnings lists anything requiring user attention before removing the ClusterResourceSet
+	Warnings []string
+}
+
+// GetMigratedSecretName returns the name of the copy of a Secret referenced by a ClusterResourceSet
+func GetMigratedSecretName(secretName string) string {
+	return secretName + migratedSecretSuffix
+}
+
+// ConvertClusterResourceSet returns the Profile equivalent to a ClusterResourceSet.
+// bindings are the ClusterResourceSetBindings in the ClusterResourceSet namespace. Those are
+// used to find the clusters where resources were already applied.
+func ConvertClusterResourceSet(crs *addonsv1.ClusterResourceSet,
+	bindings []addonsv1.ClusterResourceSetBinding) *Result {
+
+	result := &Result{
+		Secrets: make(map[string]string),
+	}
+
+	syncMode := configv1beta1.SyncModeContinuous
+	if crs.Spec.Strategy == string(addonsv1.ClusterResourceSetStrategyApplyOnce) {
+		syncMode = configv1beta1.SyncModeOneTime
+	}
+
+	profile := &configv1beta1.Profile{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       configv1beta1.ProfileKind,
+			APIVersion: configv1beta1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: crs.Namespace,
+			Name:      crs.Name,
+			Labels: map[string]string{
+				MigratedFromLabel: crs.Name,
+			},
+		},
+		Spec: configv1beta1.Spec{
+			ClusterSelector: libsveltosv1beta1.Selector{LabelSelector: *crs.Spec.ClusterSelector.DeepCopy()},
+			SyncMode:        syncMode,
+		},
+	}
+
+	if len(crs.Spec.ClusterSelector.MatchLabels) == 0 && len(crs.Spec.ClusterSelector.MatchExpressions) == 0 {
+		result.Warnings = append(result.Warnings,
+			"ClusterResourceSet has an empty clusterSelector which matches no cluster. Profile matches no cluster either")
+	}
+
+	for i := range crs.Spec.Resources {
+		ref := &crs.Spec.Resources[i]
+		name := ref.Name
+		if ref.Kind == string(addonsv1.SecretClusterResourceSetResourceKind) {
+			name = GetMigratedSecretName(ref.Name)
+			result.Secrets[ref.Name] = name
+		}
+		profile.Spec.PolicyRefs = append(profile.Spec.PolicyRefs, configv1beta1.PolicyRef{
+			Name:           name,
+			Kind:           ref.Kind,
+			DeploymentType: configv1beta1.DeploymentTypeRemote,
+		})
+	}
+
+	result.Profile = profile
+	result.AdoptedClusters, result.Warnings = processBindings(crs, bindings, result.Warnings)
+
+	return result
+}
+
+// processBindings returns the clusters where all ClusterResourceSet resources were applied.
+// A warning is added for each cluster where some resource was not applied: those resources
+// will be deployed for the first time by the Profile.
+func processBindings(crs *addonsv1.ClusterResourceSet, bindings []addonsv1.ClusterResourceSetBinding,
+	warnings []string) (adopted, updatedWarnings []string) {
+
+	for i := range bindings {
+		binding := &bindings[i]
+		if binding.Namespace != crs.Namespace {
+			continue
+		}
+
+		// ClusterName is not set on bindings created by older ClusterAPI versions. Binding
+		// name matches the cluster name in such a case.
+		clusterName := binding.Spec.ClusterName
+		if clusterName == "" {
+			clusterName = binding.Name
+		}
+
+		for _, resourceSetBinding := range binding.Spec.Bindings {
+			if resourceSetBinding == nil || resourceSetBinding.ClusterResourceSetName != crs.Name {
+				continue
+			}
+
+			allApplied := true
+			for j := range crs.Spec.Resources {
+				if !resourceSetBinding.IsApplied(crs.Spec.Resources[j]) {
+					allApplied = false
+					warnings = append(warnings, fmt.Sprintf("%s %s was not applied to cluster %s. It will be deployed by the Profile",
+						crs.Spec.Resources[j].Kind, crs.Spec.Resources[j].Name, clusterName))
+				}
+			}
+
+			if allApplied {
+				adopted = append(adopted, clusterName)
+			}
+		}
+	}
+
+	sort.Strings(adopted)
+	return adopted, warnings
+}
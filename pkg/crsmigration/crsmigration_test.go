@@ -0,0 +1,142 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crsmigration_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/pkg/crsmigration"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+var _ = Describe("ClusterResourceSet migration", func() {
+	var crs *addonsv1.ClusterResourceSet
+	var configMap *corev1.ConfigMap
+	var secret *corev1.Secret
+
+	BeforeEach(func() {
+		namespace := randomString()
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Data:       map[string]string{"policy.yaml": "content"},
+		}
+
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Type:       addonsv1.ClusterResourceSetSecretType,
+			Data:       map[string][]byte{"policy.yaml": []byte("content")},
+		}
+
+		crs = &addonsv1.ClusterResourceSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: randomString()},
+			Spec: addonsv1.ClusterResourceSetSpec{
+				ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+				Resources: []addonsv1.ResourceRef{
+					{Kind: string(addonsv1.ConfigMapClusterResourceSetResourceKind), Name: configMap.Name},
+					{Kind: string(addonsv1.SecretClusterResourceSetResourceKind), Name: secret.Name},
+				},
+				Strategy: string(addonsv1.ClusterResourceSetStrategyApplyOnce),
+			},
+		}
+	})
+
+	getBinding := func(clusterName string, applied bool) addonsv1.ClusterResourceSetBinding {
+		resources := make([]addonsv1.ResourceBinding, len(crs.Spec.Resources))
+		for i := range crs.Spec.Resources {
+			resources[i] = addonsv1.ResourceBinding{ResourceRef: crs.Spec.Resources[i], Applied: applied}
+		}
+		return addonsv1.ClusterResourceSetBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: crs.Namespace, Name: clusterName},
+			Spec: addonsv1.ClusterResourceSetBindingSpec{
+				ClusterName: clusterName,
+				Bindings: []*addonsv1.ResourceSetBinding{
+					{ClusterResourceSetName: crs.Name, Resources: resources},
+				},
+			},
+		}
+	}
+
+	It("ConvertClusterResourceSet returns equivalent Profile", func() {
+		adoptedCluster := randomString()
+		notAppliedCluster := randomString()
+		bindings := []addonsv1.ClusterResourceSetBinding{
+			getBinding(adoptedCluster, true),
+			getBinding(notAppliedCluster, false),
+		}
+
+		result := crsmigration.ConvertClusterResourceSet(crs, bindings)
+		Expect(result.Profile.Namespace).To(Equal(crs.Namespace))
+		Expect(result.Profile.Name).To(Equal(crs.Name))
+		Expect(result.Profile.Labels).To(HaveKeyWithValue(crsmigration.MigratedFromLabel, crs.Name))
+		Expect(result.Profile.Spec.SyncMode).To(Equal(configv1beta1.SyncModeOneTime))
+		Expect(result.Profile.Spec.ClusterSelector.MatchLabels).To(Equal(crs.Spec.ClusterSelector.MatchLabels))
+
+		Expect(result.Profile.Spec.PolicyRefs).To(ConsistOf(
+			configv1beta1.PolicyRef{Kind: string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+				Name: configMap.Name, DeploymentType: configv1beta1.DeploymentTypeRemote},
+			configv1beta1.PolicyRef{Kind: string(libsveltosv1beta1.SecretReferencedResourceKind),
+				Name: crsmigration.GetMigratedSecretName(secret.Name), DeploymentType: configv1beta1.DeploymentTypeRemote},
+		))
+		Expect(result.Secrets).To(HaveKeyWithValue(secret.Name, crsmigration.GetMigratedSecretName(secret.Name)))
+
+		Expect(result.AdoptedClusters).To(Equal([]string{adoptedCluster}))
+		Expect(result.Warnings).To(HaveLen(len(crs.Spec.Resources)))
+	})
+
+	It("Migrate creates Profile and Secret copies", func() {
+		initObjects := []client.Object{crs, configMap, secret}
+		c := fake.NewClientBuilder().WithScheme(setupScheme()).WithObjects(initObjects...).Build()
+
+		results, err := crsmigration.Migrate(context.TODO(), c, crsmigration.MigrateOptions{DryRun: true})
+		Expect(err).To(BeNil())
+		Expect(results).To(HaveLen(1))
+
+		profile := &configv1beta1.Profile{}
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: crs.Namespace, Name: crs.Name}, profile)
+		Expect(err).ToNot(BeNil())
+
+		results, err = crsmigration.Migrate(context.TODO(), c, crsmigration.MigrateOptions{Namespace: crs.Namespace})
+		Expect(err).To(BeNil())
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Warnings).To(BeEmpty())
+
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: crs.Namespace, Name: crs.Name}, profile)).To(Succeed())
+
+		secretCopy := &corev1.Secret{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: crs.Namespace,
+			Name: crsmigration.GetMigratedSecretName(secret.Name)}, secretCopy)).To(Succeed())
+		Expect(secretCopy.Type).To(Equal(libsveltosv1beta1.ClusterProfileSecretType))
+		Expect(secretCopy.Data).To(Equal(secret.Data))
+
+		// Migrating again does not override existing instances
+		results, err = crsmigration.Migrate(context.TODO(), c, crsmigration.MigrateOptions{})
+		Expect(err).To(BeNil())
+		Expect(results[0].Warnings).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,150 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crsmigration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	addonsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// MigrateOptions configures Migrate
+type MigrateOptions struct {
+	// Namespace limits migration to ClusterResourceSets in this namespace. All namespaces if empty.
+	Namespace string
+
+	// DryRun, when true, only computes the Results. Nothing is created.
+	DryRun bool
+}
+
+// Migrate converts all ClusterResourceSets to Profiles. For each ClusterResourceSet it creates:
+// - a copy, with type accepted by Sveltos, of each referenced Secret;
+// - the equivalent Profile.
+// Existing Profiles/Secrets are never overwritten: a warning is reported instead.
+// ClusterResourceSets are not modified. Those can be deleted once Profiles are provisioned
+// (deleting a ClusterResourceSet does not remove resources from the managed clusters).
+func Migrate(ctx context.Context, c client.Client, options MigrateOptions) ([]*Result, error) {
+	listOptions := []client.ListOption{}
+	if options.Namespace != "" {
+		listOptions = append(listOptions, client.InNamespace(options.Namespace))
+	}
+
+	crsList := &addonsv1.ClusterResourceSetList{}
+	if err := c.List(ctx, crsList, listOptions...); err != nil {
+		return nil, err
+	}
+
+	bindingList := &addonsv1.ClusterResourceSetBindingList{}
+	if err := c.List(ctx, bindingList, listOptions...); err != nil {
+		return nil, err
+	}
+
+	results := make([]*Result, len(crsList.Items))
+	for i := range crsList.Items {
+		crs := &crsList.Items[i]
+		results[i] = ConvertClusterResourceSet(crs, bindingList.Items)
+
+		if options.DryRun {
+			continue
+		}
+
+		if err := createSecretCopies(ctx, c, crs, results[i]); err != nil {
+			return nil, err
+		}
+
+		if err := createProfile(ctx, c, results[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// createSecretCopies creates, for each Secret referenced by the ClusterResourceSet, a copy
+// with type addons.projectsveltos.io/cluster-profile
+func createSecretCopies(ctx context.Context, c client.Client, crs *addonsv1.ClusterResourceSet,
+	result *Result) error {
+
+	names := make([]string, 0, len(result.Secrets))
+	for name := range result.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		copyName := result.Secrets[name]
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: crs.Namespace, Name: name}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("Secret %s/%s referenced by ClusterResourceSet not found", crs.Namespace, name))
+				continue
+			}
+			return err
+		}
+
+		secretCopy := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: crs.Namespace,
+				Name:      copyName,
+				Labels: map[string]string{
+					MigratedFromLabel: crs.Name,
+				},
+				Annotations: secret.Annotations,
+			},
+			Type: libsveltosv1beta1.ClusterProfileSecretType,
+			Data: secret.Data,
+		}
+
+		err = c.Create(ctx, secretCopy)
+		if err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("Secret %s/%s already exists. Not modified", crs.Namespace, copyName))
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createProfile(ctx context.Context, c client.Client, result *Result) error {
+	err := c.Create(ctx, result.Profile)
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("Profile %s/%s already exists. Not modified",
+					result.Profile.Namespace, result.Profile.Name))
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
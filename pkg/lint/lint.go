@@ -0,0 +1,247 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lint validates ClusterProfiles/Profiles offline, without access to a management
+// cluster, so that CI pipelines can catch mistakes before applying them.
+//
+// Checks are the ones addon-controller would otherwise only report at reconciliation time:
+// unknown fields, invalid selectors, CEL expressions and lua scripts which do not compile,
+// helm values which are not valid templates, self dependencies and duplicated entries.
+// Anything depending on the management cluster content (referenced ConfigMaps/Secrets,
+// matching clusters, etc.) is not validated.
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/google/cel-go/cel"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/funcmap"
+)
+
+// Issue is a problem found in a ClusterProfile/Profile
+type Issue struct {
+	// Object identifies the ClusterProfile/Profile, as Kind/name or Kind/namespace/name
+	Object string
+
+	// Field is the path of the offending field, for instance spec.clusterSelector
+	Field string
+
+	// Message describes the problem
+	Message string
+}
+
+func (i *Issue) String() string {
+	if i.Field == "" {
+		return fmt.Sprintf("%s: %s", i.Object, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Object, i.Field, i.Message)
+}
+
+// LintFile validates all ClusterProfiles/Profiles contained in file. File can contain multiple
+// YAML documents.
+func LintFile(path string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(data)
+}
+
+// Lint validates all ClusterProfiles/Profiles contained in data. Data can contain multiple YAML
+// documents. An error is returned only if data is not valid YAML.
+func Lint(data []byte) ([]Issue, error) {
+	var issues []Issue
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for i := 0; ; i++ {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+
+		issues = append(issues, lintDocument(i, document)...)
+	}
+
+	return issues, nil
+}
+
+func lintDocument(index int, document []byte) []Issue {
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(document, typeMeta); err != nil {
+		return []Issue{{Object: fmt.Sprintf("document %d", index), Message: err.Error()}}
+	}
+
+	object := fmt.Sprintf("document %d", index)
+	if typeMeta.APIVersion != configv1beta1.GroupVersion.String() {
+		return []Issue{{Object: object,
+			Message: fmt.Sprintf("unsupported apiVersion %q (only %s is supported)",
+				typeMeta.APIVersion, configv1beta1.GroupVersion.String())}}
+	}
+
+	switch typeMeta.Kind {
+	case configv1beta1.ClusterProfileKind:
+		clusterProfile := &configv1beta1.ClusterProfile{}
+		if err := yaml.UnmarshalStrict(document, clusterProfile); err != nil {
+			return []Issue{{Object: object, Message: err.Error()}}
+		}
+		object = fmt.Sprintf("%s/%s", configv1beta1.ClusterProfileKind, clusterProfile.Name)
+		return lintSpec(object, clusterProfile.Name, &clusterProfile.Spec)
+	case configv1beta1.ProfileKind:
+		profile := &configv1beta1.Profile{}
+		if err := yaml.UnmarshalStrict(document, profile); err != nil {
+			return []Issue{{Object: object, Message: err.Error()}}
+		}
+		object = fmt.Sprintf("%s/%s/%s", configv1beta1.ProfileKind, profile.Namespace, profile.Name)
+		return lintSpec(object, profile.Name, &profile.Spec)
+	default:
+		return []Issue{{Object: object,
+			Message: fmt.Sprintf("unsupported kind %q (only %s and %s are supported)",
+				typeMeta.Kind, configv1beta1.ClusterProfileKind, configv1beta1.ProfileKind)}}
+	}
+}
+
+func lintSpec(object, name string, spec *configv1beta1.Spec) []Issue {
+	var issues []Issue
+	addIssue := func(field string, err error) {
+		if err != nil {
+			issues = append(issues, Issue{Object: object, Field: field, Message: err.Error()})
+		}
+	}
+
+	addIssue("spec.clusterSelector", validateSelector(&spec.ClusterSelector))
+	if spec.ClusterCELSelector != "" {
+		addIssue("spec.clusterCELSelector", validateCELExpression(spec.ClusterCELSelector, "cluster"))
+	}
+
+	for i := range spec.RolloutRings {
+		addIssue(fmt.Sprintf("spec.rolloutRings[%d].clusterSelector", i),
+			validateSelector(&spec.RolloutRings[i].ClusterSelector))
+	}
+
+	for i := range spec.ValidateHealths {
+		check := &spec.ValidateHealths[i]
+		if check.CELExpression != "" {
+			addIssue(fmt.Sprintf("spec.validateHealths[%d].celExpression", i),
+				validateCELExpression(check.CELExpression, "obj"))
+		}
+		if check.Script != "" {
+			addIssue(fmt.Sprintf("spec.validateHealths[%d].script", i),
+				validateLuaScript(check.Script, check.Name))
+		}
+	}
+
+	for i := range spec.HelmCharts {
+		addIssue(fmt.Sprintf("spec.helmCharts[%d].values", i),
+			validateTemplate(spec.HelmCharts[i].Values, spec.HelmCharts[i].ChartName))
+	}
+
+	for i := range spec.DependsOn {
+		if spec.DependsOn[i] == name {
+			addIssue(fmt.Sprintf("spec.dependsOn[%d]", i), errors.New("profile cannot depend on itself"))
+		}
+	}
+
+	addIssue("spec.deploymentOrder", validateDeploymentOrder(spec.DeploymentOrder))
+
+	return issues
+}
+
+func validateSelector(selector *libsveltosv1beta1.Selector) error {
+	_, err := metav1.LabelSelectorAsSelector(&selector.LabelSelector)
+	return err
+}
+
+// validateCELExpression verifies expression compiles with variable name available and
+// returns a boolean
+func validateCELExpression(expression, name string) error {
+	env, err := cel.NewEnv(cel.Variable(name, cel.DynType))
+	if err != nil {
+		return err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return fmt.Errorf("invalid CEL expression: %w", issues.Err())
+	}
+
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return fmt.Errorf("CEL expression must return a boolean, not %s", ast.OutputType())
+	}
+
+	return nil
+}
+
+func validateLuaScript(script, name string) error {
+	chunk, err := parse.Parse(strings.NewReader(script), name)
+	if err != nil {
+		return fmt.Errorf("invalid lua script: %w", err)
+	}
+	if _, err := lua.Compile(chunk, name); err != nil {
+		return fmt.Errorf("invalid lua script: %w", err)
+	}
+	return nil
+}
+
+// validateTemplate verifies values can be parsed as a template, with same functions
+// available when templates are instantiated
+func validateTemplate(values, name string) error {
+	if values == "" {
+		return nil
+	}
+
+	funcMap := funcmap.SveltosFuncMap()
+	funcMap["getResource"] = func(id string) map[string]interface{} {
+		return nil
+	}
+
+	if _, err := template.New(name).Option("missingkey=error").Funcs(funcMap).Parse(values); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	return nil
+}
+
+func validateDeploymentOrder(order []configv1beta1.FeatureID) error {
+	seen := make(map[configv1beta1.FeatureID]bool, len(order))
+	for i := range order {
+		if seen[order[i]] {
+			return fmt.Errorf("feature %s is listed more than once", order[i])
+		}
+		seen[order[i]] = true
+	}
+	return nil
+}
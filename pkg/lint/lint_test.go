@@ -0,0 +1,159 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectsveltos/addon-controller/pkg/lint"
+)
+
+const validProfiles = `apiVersion: config.projectsveltos.io/v1beta1
+kind: ClusterProfile
+metadata:
+  name: kyverno
+spec:
+  clusterSelector:
+    matchLabels:
+      env: fv
+  clusterCELSelector: cluster.spec.paused == false
+  helmCharts:
+  - repositoryURL: https://kyverno.github.io/kyverno/
+    repositoryName: kyverno
+    chartName: kyverno/kyverno
+    chartVersion: v3.2.5
+    releaseName: kyverno-latest
+    releaseNamespace: kyverno
+    helmChartAction: Install
+    values: |
+      admissionController:
+        replicas: {{ .Cluster.spec.topology.controlPlane.replicas }}
+  validateHealths:
+  - name: deployment-health
+    featureID: Helm
+    group: apps
+    version: v1
+    kind: Deployment
+    script: |
+      function evaluate()
+        hs = {}
+        hs.healthy = true
+        return hs
+      end
+---
+apiVersion: config.projectsveltos.io/v1beta1
+kind: Profile
+metadata:
+  name: nginx
+  namespace: eng
+spec:
+  dependsOn:
+  - kyverno
+`
+
+var _ = Describe("Lint", func() {
+	It("Lint reports no issue for valid ClusterProfiles/Profiles", func() {
+		issues, err := lint.Lint([]byte(validProfiles))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issues).To(BeEmpty())
+	})
+
+	It("Lint reports invalid fields", func() {
+		data := `apiVersion: config.projectsveltos.io/v1beta1
+kind: ClusterProfile
+metadata:
+  name: invalid
+spec:
+  clusterSelector:
+    matchExpressions:
+    - key: env
+      operator: Equals
+  clusterCELSelector: cluster.spec.paused ==
+  dependsOn:
+  - invalid
+  deploymentOrder:
+  - Helm
+  - Helm
+  helmCharts:
+  - chartName: kyverno/kyverno
+    values: "{{ .Cluster.metadata.name "
+  validateHealths:
+  - name: check
+    featureID: Helm
+    group: apps
+    version: v1
+    kind: Deployment
+    celExpression: obj.status.replicas
+    script: "function evaluate("
+`
+		issues, err := lint.Lint([]byte(data))
+		Expect(err).ToNot(HaveOccurred())
+
+		fields := make([]string, len(issues))
+		for i := range issues {
+			Expect(issues[i].Object).To(Equal("ClusterProfile/invalid"))
+			fields[i] = issues[i].Field
+		}
+		Expect(fields).To(ConsistOf(
+			"spec.clusterSelector",
+			"spec.clusterCELSelector",
+			"spec.validateHealths[0].script",
+			"spec.helmCharts[0].values",
+			"spec.dependsOn[0]",
+			"spec.deploymentOrder",
+		))
+	})
+
+	It("Lint reports unknown fields and unsupported kinds", func() {
+		data := `apiVersion: config.projectsveltos.io/v1beta1
+kind: Profile
+metadata:
+  name: typo
+  namespace: eng
+spec:
+  clusterSelectr:
+    matchLabels:
+      env: fv
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+`
+		issues, err := lint.Lint([]byte(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issues).To(HaveLen(2))
+		Expect(issues[0].Message).To(ContainSubstring("clusterSelectr"))
+		Expect(issues[1].Message).To(ContainSubstring("unsupported apiVersion"))
+	})
+
+	It("LintFile validates the content of a file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "profiles.yaml")
+		Expect(os.WriteFile(path, []byte(validProfiles), 0600)).To(Succeed())
+
+		issues, err := lint.LintFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issues).To(BeEmpty())
+
+		_, err = lint.LintFile(filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+		Expect(err).To(HaveOccurred())
+	})
+})
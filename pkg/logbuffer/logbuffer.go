@@ -0,0 +1,139 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logbuffer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Buffer keeps in memory the latest log lines
+type Buffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// New returns a Buffer keeping the latest size log lines
+func New(size int) *Buffer {
+	return &Buffer{lines: make([]string, size)}
+}
+
+// Add adds a line, dropping the oldest one if buffer is full
+func (b *Buffer) Add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.lines) == 0 {
+		return
+	}
+
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Lines returns the lines currently in the buffer, oldest first
+func (b *Buffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]string{}, b.lines[:b.next]...)
+	}
+	return append(append([]string{}, b.lines[b.next:]...), b.lines[:b.next]...)
+}
+
+// WrapSink returns a LogSink forwarding everything to sink and also keeping
+// each log line in the Buffer
+func (b *Buffer) WrapSink(sink logr.LogSink) logr.LogSink {
+	return &bufferSink{sink: sink, buffer: b}
+}
+
+type bufferSink struct {
+	sink   logr.LogSink
+	buffer *Buffer
+	name   string
+	values []interface{}
+}
+
+func (s *bufferSink) Init(info logr.RuntimeInfo) {
+	// Account for this sink in the call depth
+	info.CallDepth++
+	s.sink.Init(info)
+}
+
+func (s *bufferSink) Enabled(level int) bool {
+	return s.sink.Enabled(level)
+}
+
+func (s *bufferSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(level, msg, keysAndValues...)
+	s.buffer.Add(s.format("I", msg, nil, keysAndValues))
+}
+
+func (s *bufferSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(err, msg, keysAndValues...)
+	s.buffer.Add(s.format("E", msg, err, keysAndValues))
+}
+
+func (s *bufferSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	values := make([]interface{}, 0, len(s.values)+len(keysAndValues))
+	values = append(values, s.values...)
+	values = append(values, keysAndValues...)
+	return &bufferSink{sink: s.sink.WithValues(keysAndValues...), buffer: s.buffer, name: s.name, values: values}
+}
+
+func (s *bufferSink) WithName(name string) logr.LogSink {
+	fullName := name
+	if s.name != "" {
+		fullName = s.name + "/" + name
+	}
+	return &bufferSink{sink: s.sink.WithName(name), buffer: s.buffer, name: fullName, values: s.values}
+}
+
+func (s *bufferSink) format(severity, msg string, err error, keysAndValues []interface{}) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s", severity, time.Now().UTC().Format(time.RFC3339Nano)))
+	if s.name != "" {
+		sb.WriteString(fmt.Sprintf(" %s", s.name))
+	}
+	sb.WriteString(fmt.Sprintf(" %q", msg))
+	if err != nil {
+		sb.WriteString(fmt.Sprintf(" err=%q", err.Error()))
+	}
+	writeKeysAndValues(&sb, s.values)
+	writeKeysAndValues(&sb, keysAndValues)
+	return sb.String()
+}
+
+func writeKeysAndValues(sb *strings.Builder, keysAndValues []interface{}) {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		var value interface{} = "(MISSING)"
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		sb.WriteString(fmt.Sprintf(" %v=%q", keysAndValues[i], fmt.Sprint(value)))
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logbuffer_test
+
+import (
+	"errors"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectsveltos/addon-controller/pkg/logbuffer"
+)
+
+var _ = Describe("Buffer", func() {
+	It("keeps only latest lines", func() {
+		buffer := logbuffer.New(3)
+		Expect(buffer.Lines()).To(BeEmpty())
+
+		buffer.Add("a")
+		buffer.Add("b")
+		Expect(buffer.Lines()).To(Equal([]string{"a", "b"}))
+
+		buffer.Add("c")
+		buffer.Add("d")
+		Expect(buffer.Lines()).To(Equal([]string{"b", "c", "d"}))
+
+		// A zero size buffer keeps nothing
+		buffer = logbuffer.New(0)
+		buffer.Add("a")
+		Expect(buffer.Lines()).To(BeEmpty())
+	})
+
+	It("WrapSink keeps log lines with name and values", func() {
+		buffer := logbuffer.New(10)
+		logger := logr.New(buffer.WrapSink(funcr.New(func(_, _ string) {}, funcr.Options{}).GetSink()))
+
+		logger.WithName("controller").WithValues("profile", "foo").Info("deploying", "cluster", "bar")
+		logger.Error(errors.New("timeout"), "failed")
+
+		lines := buffer.Lines()
+		Expect(len(lines)).To(Equal(2))
+		Expect(lines[0]).To(HavePrefix("I "))
+		Expect(lines[0]).To(ContainSubstring(`controller "deploying" profile="foo" cluster="bar"`))
+		Expect(lines[1]).To(HavePrefix("E "))
+		Expect(lines[1]).To(ContainSubstring(`"failed" err="timeout"`))
+	})
+})
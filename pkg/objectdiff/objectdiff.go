@@ -0,0 +1,255 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package objectdiff compares Kubernetes objects field by field.
+//
+// Objects are canonicalized before being compared, so that representations the apiserver
+// considers equivalent are not reported as changes:
+// - fields populated by the apiserver (resourceVersion, uid, managedFields, status, etc.) are dropped;
+// - fields set to well known defaults (for instance protocol TCP) are dropped;
+// - null values, empty maps and empty lists are dropped;
+// - numbers are compared by value, whatever type they were decoded with;
+// - quantities are compared by value (1 vs 1000m cpu, 1Gi vs 1024Mi);
+// - ports are compared by value (80 vs "80").
+//
+// Differences are returned sorted by path, so output is stable.
+package objectdiff
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+var (
+	// serverPopulatedMetadata are metadata fields set by the apiserver
+	serverPopulatedMetadata = []string{"uid", "resourceVersion", "generation", "creationTimestamp",
+		"managedFields", "selfLink", "deletionTimestamp", "deletionGracePeriodSeconds"}
+
+	// quantityParents are the fields whose entries are quantities (for instance resources.limits.cpu)
+	quantityParents = map[string]bool{"limits": true, "requests": true, "hard": true, "used": true,
+		"capacity": true, "allocatable": true, "overhead": true}
+
+	// quantityFields are fields whose value is a quantity
+	quantityFields = map[string]bool{"sizeLimit": true, "storage": true}
+
+	// portFields are IntOrString fields which, when numeric, are ports
+	portFields = map[string]bool{"port": true, "targetPort": true, "containerPort": true,
+		"hostPort": true, "nodePort": true}
+
+	// wellKnownDefaults are fields the apiserver defaults. When set to the default value they
+	// are dropped, so an object explicitly setting them matches one which does not.
+	wellKnownDefaults = map[string]interface{}{
+		"protocol":                      "TCP",
+		"terminationMessagePath":        "/dev/termination-log",
+		"terminationMessagePolicy":      "File",
+		"dnsPolicy":                     "ClusterFirst",
+		"schedulerName":                 "default-scheduler",
+		"terminationGracePeriodSeconds": int64(30),
+		"revisionHistoryLimit":          int64(10),
+		"progressDeadlineSeconds":       int64(600),
+		"sessionAffinity":               "None",
+		"podManagementPolicy":           "OrderedReady",
+	}
+)
+
+// Difference is a field whose value differs between two objects
+type Difference struct {
+	// Path of the field, for instance spec.template.spec.containers[0].image
+	Path string
+
+	// From is the canonical value in the first object. Nil if field is not set.
+	From interface{}
+
+	// To is the canonical value in the second object. Nil if field is not set.
+	To interface{}
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %v => %v", d.Path, d.From, d.To)
+}
+
+// Options customizes the comparison
+type Options struct {
+	// Subset, when set, only compares fields set in the first object. Fields set only
+	// in the second object (for instance fields defaulted by controllers) are ignored.
+	Subset bool
+}
+
+// Canonicalize returns a canonical copy of obj. obj is not modified.
+func Canonicalize(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	canonical := obj.DeepCopy()
+
+	unstructured.RemoveNestedField(canonical.Object, "status")
+	for i := range serverPopulatedMetadata {
+		unstructured.RemoveNestedField(canonical.Object, "metadata", serverPopulatedMetadata[i])
+	}
+	unstructured.RemoveNestedField(canonical.Object, "metadata", "annotations", lastAppliedConfigAnnotation)
+
+	value := canonicalizeValue("", "", canonical.Object)
+	if m, ok := value.(map[string]interface{}); ok {
+		canonical.Object = m
+	} else {
+		canonical.Object = map[string]interface{}{}
+	}
+	return canonical
+}
+
+// Compare canonicalizes from and to and returns their differences, sorted by path.
+// Returns no difference if objects are equivalent.
+func Compare(from, to *unstructured.Unstructured, options Options) []Difference {
+	differences := make([]Difference, 0)
+	compareValues(Canonicalize(from).Object, Canonicalize(to).Object, "", options, &differences)
+
+	sort.SliceStable(differences, func(i, j int) bool {
+		return differences[i].Path < differences[j].Path
+	})
+	return differences
+}
+
+// Equal returns true if from and to are equivalent once canonicalized
+func Equal(from, to *unstructured.Unstructured) bool {
+	return len(Compare(from, to, Options{})) == 0
+}
+
+func canonicalizeValue(parent, key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k := range v {
+			c := canonicalizeValue(key, k, v[k])
+			if c == nil {
+				continue
+			}
+			if d, ok := wellKnownDefaults[k]; ok && reflect.DeepEqual(c, d) {
+				continue
+			}
+			result[k] = c
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		return result
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		result := make([]interface{}, len(v))
+		for i := range v {
+			// List elements are canonicalized as if they were the list itself
+			result[i] = canonicalizeValue(parent, key, v[i])
+		}
+		return result
+	case nil:
+		return nil
+	default:
+		return canonicalizeScalar(parent, key, v)
+	}
+}
+
+func canonicalizeScalar(parent, key string, value interface{}) interface{} {
+	if quantityParents[parent] || quantityFields[key] {
+		if q, err := resource.ParseQuantity(fmt.Sprint(value)); err == nil {
+			return q.String()
+		}
+	}
+
+	if portFields[key] {
+		if s, ok := value.(string); ok {
+			if port, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return port
+			}
+		}
+	}
+
+	return canonicalizeNumber(value)
+}
+
+// canonicalizeNumber converts numbers to int64 when integral, to float64 otherwise
+func canonicalizeNumber(value interface{}) interface{} {
+	switch n := value.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float32:
+		return canonicalizeNumber(float64(n))
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < math.MaxInt64 {
+			return int64(n)
+		}
+		return n
+	default:
+		return value
+	}
+}
+
+func compareValues(from, to interface{}, path string, options Options, differences *[]Difference) {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			*differences = append(*differences, Difference{Path: path, From: from, To: to})
+			return
+		}
+		for k := range f {
+			compareValues(f[k], t[k], joinPath(path, k), options, differences)
+		}
+		if options.Subset {
+			return
+		}
+		for k := range t {
+			if _, ok := f[k]; !ok {
+				*differences = append(*differences, Difference{Path: joinPath(path, k), To: t[k]})
+			}
+		}
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok || len(t) != len(f) {
+			*differences = append(*differences, Difference{Path: path, From: from, To: to})
+			return
+		}
+		for i := range f {
+			compareValues(f[i], t[i], fmt.Sprintf("%s[%d]", path, i), options, differences)
+		}
+	case nil:
+		if to != nil && !options.Subset {
+			*differences = append(*differences, Difference{Path: path, To: to})
+		}
+	default:
+		if !reflect.DeepEqual(from, to) {
+			*differences = append(*differences, Difference{Path: path, From: from, To: to})
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectdiff_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/projectsveltos/addon-controller/pkg/objectdiff"
+)
+
+const (
+	desiredDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: default
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.25
+        ports:
+        - containerPort: "80"
+          protocol: TCP
+        resources:
+          limits:
+            cpu: "1"
+            memory: 1Gi
+      volumes: []`
+
+	liveDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: default
+  uid: 3f1c2b8e-1d2a-4c5b-9e8f-7a6b5c4d3e2f
+  resourceVersion: "100"
+  generation: 3
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: "{}"
+spec:
+  replicas: 2.0
+  progressDeadlineSeconds: 600
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.25
+        ports:
+        - containerPort: 80
+        resources:
+          limits:
+            cpu: 1000m
+            memory: 1024Mi
+status:
+  readyReplicas: 2`
+)
+
+func getUnstructured(data string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	Expect(yaml.Unmarshal([]byte(data), &u.Object)).To(Succeed())
+	return u
+}
+
+var _ = Describe("ObjectDiff", func() {
+	It("Canonicalize drops server populated fields and defaults without modifying object", func() {
+		live := getUnstructured(liveDeployment)
+
+		canonical := objectdiff.Canonicalize(live)
+		Expect(canonical.GetResourceVersion()).To(BeEmpty())
+		Expect(string(canonical.GetUID())).To(BeEmpty())
+		Expect(canonical.GetAnnotations()).To(BeEmpty())
+		Expect(canonical.Object).ToNot(HaveKey("status"))
+
+		_, found, err := unstructured.NestedFieldNoCopy(canonical.Object, "spec", "progressDeadlineSeconds")
+		Expect(err).To(BeNil())
+		Expect(found).To(BeFalse())
+
+		replicas, found, err := unstructured.NestedFieldNoCopy(canonical.Object, "spec", "replicas")
+		Expect(err).To(BeNil())
+		Expect(found).To(BeTrue())
+		Expect(replicas).To(Equal(int64(2)))
+
+		Expect(live.GetResourceVersion()).To(Equal("100"))
+		Expect(live.Object).To(HaveKey("status"))
+	})
+
+	It("Compare does not report equivalent representations", func() {
+		desired := getUnstructured(desiredDeployment)
+		live := getUnstructured(liveDeployment)
+
+		Expect(objectdiff.Compare(desired, live, objectdiff.Options{})).To(BeEmpty())
+		Expect(objectdiff.Equal(desired, live)).To(BeTrue())
+	})
+
+	It("Compare returns differences sorted by path", func() {
+		desired := getUnstructured(desiredDeployment)
+		live := getUnstructured(liveDeployment)
+		Expect(unstructured.SetNestedField(live.Object, int64(3), "spec", "replicas")).To(Succeed())
+		Expect(unstructured.SetNestedField(live.Object, "IfNotPresent", "spec", "template", "spec",
+			"imagePullPolicy")).To(Succeed())
+		live.SetLabels(map[string]string{"app": "nginx"})
+
+		differences := objectdiff.Compare(desired, live, objectdiff.Options{})
+		Expect(differences).To(HaveLen(3))
+		Expect(differences[0]).To(Equal(objectdiff.Difference{Path: "metadata.labels",
+			To: map[string]interface{}{"app": "nginx"}}))
+		Expect(differences[1]).To(Equal(objectdiff.Difference{Path: "spec.replicas",
+			From: int64(2), To: int64(3)}))
+		Expect(differences[2].Path).To(Equal("spec.template.spec.imagePullPolicy"))
+		Expect(differences[2].From).To(BeNil())
+
+		// Only fields set in desired are compared
+		differences = objectdiff.Compare(desired, live, objectdiff.Options{Subset: true})
+		Expect(differences).To(HaveLen(1))
+		Expect(differences[0].String()).To(Equal("spec.replicas: 2 => 3"))
+	})
+
+	It("Compare reports different quantities and lists", func() {
+		desired := getUnstructured(desiredDeployment)
+		live := getUnstructured(liveDeployment)
+
+		containers, _, err := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+		Expect(err).To(BeNil())
+		Expect(unstructured.SetNestedField(containers[0].(map[string]interface{}), "500m",
+			"resources", "limits", "cpu")).To(Succeed())
+		containers = append(containers, map[string]interface{}{"name": "sidecar", "image": "busybox"})
+		Expect(unstructured.SetNestedSlice(live.Object, containers, "spec", "template", "spec",
+			"containers")).To(Succeed())
+
+		differences := objectdiff.Compare(desired, live, objectdiff.Options{Subset: true})
+		Expect(differences).To(HaveLen(1))
+		Expect(differences[0].Path).To(Equal("spec.template.spec.containers"))
+
+		Expect(unstructured.SetNestedSlice(live.Object, containers[:1], "spec", "template", "spec",
+			"containers")).To(Succeed())
+		differences = objectdiff.Compare(desired, live, objectdiff.Options{Subset: true})
+		Expect(differences).To(HaveLen(1))
+		Expect(differences[0]).To(Equal(objectdiff.Difference{
+			Path: "spec.template.spec.containers[0].resources.limits.cpu", From: "1", To: "500m"}))
+	})
+})
@@ -0,0 +1,87 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaletest
+
+import (
+	"context"
+	"sync"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	"github.com/projectsveltos/libsveltos/lib/deployer"
+)
+
+// fakeDeployer implements deployer.DeployerInterface. Unlike libsveltos fake deployer, it is safe
+// for concurrent use and every request completes successfully as soon as it is submitted.
+// Registered handlers are never invoked.
+type fakeDeployer struct {
+	mux sync.Mutex
+
+	// requests submitted so far
+	deployed map[string]bool
+}
+
+func newFakeDeployer() *fakeDeployer {
+	return &fakeDeployer{
+		deployed: make(map[string]bool),
+	}
+}
+
+func (d *fakeDeployer) RegisterFeatureID(_ string) error {
+	return nil
+}
+
+func (d *fakeDeployer) Deploy(_ context.Context, clusterNamespace, clusterName, applicant, featureID string,
+	clusterType libsveltosv1beta1.ClusterType, cleanup bool, _ deployer.RequestHandler,
+	_ deployer.MetricHandler, _ deployer.Options) error {
+
+	key := deployer.GetKey(clusterNamespace, clusterName, applicant, featureID, clusterType, cleanup)
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.deployed[key] = true
+	return nil
+}
+
+func (d *fakeDeployer) IsInProgress(_, _, _, _ string, _ libsveltosv1beta1.ClusterType, _ bool) bool {
+	return false
+}
+
+func (d *fakeDeployer) GetResult(_ context.Context, clusterNamespace, clusterName, applicant, featureID string,
+	clusterType libsveltosv1beta1.ClusterType, cleanup bool) deployer.Result {
+
+	key := deployer.GetKey(clusterNamespace, clusterName, applicant, featureID, clusterType, cleanup)
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if _, ok := d.deployed[key]; !ok {
+		return deployer.Result{ResultStatus: deployer.Unavailable}
+	}
+	return deployer.Result{ResultStatus: deployer.Deployed}
+}
+
+func (d *fakeDeployer) CleanupEntries(clusterNamespace, clusterName, applicant, featureID string,
+	clusterType libsveltosv1beta1.ClusterType, cleanup bool) {
+
+	key := deployer.GetKey(clusterNamespace, clusterName, applicant, featureID, clusterType, cleanup)
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	delete(d.deployed, key)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package scaletest provides a load-testing mode for addon-controller: a synthetic fleet of
+// SveltosClusters and ClusterProfiles is reconciled against a fake management cluster, with
+// managed clusters replaced by fake clients, and the time spent in each phase is recorded.
+//
+// It is meant to be run in CI to catch regressions (for instance O(N²) behaviors in how
+// ClusterProfileReconciler maintains its maps or cleans ClusterConfigurations) which only show
+// up with a large number of clusters and profiles.
+package scaletest
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// groupLabel is set on every SveltosCluster and used by ClusterProfiles to select clusters
+	groupLabel = "scaletest.projectsveltos.io/group"
+
+	// configMapName is the name of the ConfigMap, created in each cluster namespace, which all
+	// ClusterProfiles reference
+	configMapName = "scaletest"
+)
+
+// Config describes the synthetic fleet
+type Config struct {
+	// Clusters is the number of SveltosClusters
+	Clusters int
+
+	// Profiles is the number of ClusterProfiles
+	Profiles int
+
+	// Namespaces is the number of namespaces clusters are spread across
+	Namespaces int
+
+	// Groups is the number of cluster groups. Each cluster belongs to one group and each
+	// ClusterProfile selects one group. So each ClusterProfile matches Clusters/Groups clusters.
+	Groups int
+
+	// Workers is the number of concurrent reconciliations, mimicking MaxConcurrentReconciles
+	Workers int
+}
+
+// DefaultConfig returns the configuration used by CI in load-testing mode:
+// 1,000 clusters and 50 ClusterProfiles.
+func DefaultConfig() Config {
+	return Config{
+		Clusters:   1000,
+		Profiles:   50,
+		Namespaces: 10,
+		Groups:     10,
+		Workers:    10,
+	}
+}
+
+func (c *Config) validate() error {
+	if c.Clusters <= 0 || c.Profiles <= 0 || c.Namespaces <= 0 || c.Groups <= 0 || c.Workers <= 0 {
+		return fmt.Errorf("clusters, profiles, namespaces, groups and workers must be positive: %+v", *c)
+	}
+	return nil
+}
+
+func getNamespaceName(i int) string {
+	return fmt.Sprintf("scaletest-%d", i)
+}
+
+func getGroupName(i int) string {
+	return fmt.Sprintf("group-%d", i)
+}
+
+// getClusterGroup returns the group cluster i belongs to. When shift is set, clusters
+// are moved to the next group.
+func getClusterGroup(cfg *Config, i int, shift bool) string {
+	group := i % cfg.Groups
+	if shift {
+		group = (group + 1) % cfg.Groups
+	}
+	return getGroupName(group)
+}
+
+// getFleet returns all the objects to create in the management cluster:
+// namespaces, a ConfigMap per namespace, SveltosClusters and ClusterProfiles.
+func getFleet(cfg *Config) []client.Object {
+	objects := make([]client.Object, 0, 2*cfg.Namespaces+cfg.Clusters+cfg.Profiles)
+
+	for i := 0; i < cfg.Namespaces; i++ {
+		objects = append(objects,
+			&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: getNamespaceName(i)},
+			},
+			getConfigMap(getNamespaceName(i)),
+		)
+	}
+
+	for i := 0; i < cfg.Clusters; i++ {
+		objects = append(objects, getSveltosCluster(cfg, i))
+	}
+
+	for i := 0; i < cfg.Profiles; i++ {
+		objects = append(objects, getClusterProfile(cfg, i))
+	}
+
+	return objects
+}
+
+func getConfigMap(namespace string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      configMapName,
+		},
+		Data: map[string]string{
+			"namespace.yaml": `apiVersion: v1
+kind: Namespace
+metadata:
+  name: scaletest`,
+		},
+	}
+}
+
+func getSveltosCluster(cfg *Config, i int) *libsveltosv1beta1.SveltosCluster {
+	return &libsveltosv1beta1.SveltosCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: getNamespaceName(i % cfg.Namespaces),
+			Name:      fmt.Sprintf("cluster-%d", i),
+			Labels: map[string]string{
+				groupLabel: getClusterGroup(cfg, i, false),
+			},
+		},
+		Status: libsveltosv1beta1.SveltosClusterStatus{
+			Ready: true,
+		},
+	}
+}
+
+func getClusterProfile(cfg *Config, i int) *configv1beta1.ClusterProfile {
+	return &configv1beta1.ClusterProfile{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       configv1beta1.ClusterProfileKind,
+			APIVersion: configv1beta1.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("profile-%d", i),
+		},
+		Spec: configv1beta1.Spec{
+			ClusterSelector: libsveltosv1beta1.Selector{
+				LabelSelector: metav1.LabelSelector{
+					MatchLabels: map[string]string{groupLabel: getGroupName(i % cfg.Groups)},
+				},
+			},
+			PolicyRefs: []configv1beta1.PolicyRef{
+				{
+					// Empty namespace: the ConfigMap in the cluster namespace is used
+					Name: configMapName,
+					Kind: string(libsveltosv1beta1.ConfigMapReferencedResourceKind),
+				},
+			},
+		},
+	}
+}
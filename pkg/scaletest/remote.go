@@ -0,0 +1,84 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaletest
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	// sveltosNamespace is the namespace Sveltos deploys its agents to in managed clusters
+	sveltosNamespace = "projectsveltos"
+)
+
+// RemoteClusters holds a fake client per managed cluster. Clients are created, lazily, the first
+// time a managed cluster is accessed.
+// RemoteClusters.GetClient can be passed to controllers.SetRemoteClientGetter.
+type RemoteClusters struct {
+	scheme *runtime.Scheme
+
+	mux     sync.Mutex
+	clients map[string]client.Client
+}
+
+// NewRemoteClusters returns a RemoteClusters whose fake clients use scheme
+func NewRemoteClusters(scheme *runtime.Scheme) *RemoteClusters {
+	return &RemoteClusters{
+		scheme:  scheme,
+		clients: make(map[string]client.Client),
+	}
+}
+
+// GetClient returns the fake client for managed cluster clusterNamespace/clusterName
+func (r *RemoteClusters) GetClient(_ context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (client.Client, error) {
+
+	key := string(clusterType) + ":" + clusterNamespace + "/" + clusterName
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	c, ok := r.clients[key]
+	if !ok {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: sveltosNamespace,
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(r.scheme).WithObjects(ns).Build()
+		r.clients[key] = c
+	}
+
+	return c, nil
+}
+
+// Len returns the number of managed clusters accessed so far
+func (r *RemoteClusters) Len() int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return len(r.clients)
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaletest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1beta1 "github.com/projectsveltos/addon-controller/api/v1beta1"
+	"github.com/projectsveltos/addon-controller/controllers"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	libsveltosset "github.com/projectsveltos/libsveltos/lib/set"
+)
+
+// Phases of a scale test run
+const (
+	// PhaseClusterProfileMatch reconciles all ClusterProfiles: matching clusters are found,
+	// ClusterProfileReconciler maps are updated, ClusterSummaries and ClusterConfigurations are created.
+	PhaseClusterProfileMatch = "ClusterProfile match"
+
+	// PhaseClusterSummaryDeploy reconciles all ClusterSummaries, submitting deployment requests
+	// to the (fake) deployer.
+	PhaseClusterSummaryDeploy = "ClusterSummary deploy"
+
+	// PhaseClusterSummaryProvisioned reconciles all ClusterSummaries again, collecting deployment
+	// results from the (fake) deployer.
+	PhaseClusterSummaryProvisioned = "ClusterSummary provisioned"
+
+	// PhaseClusterProfileRematch moves every cluster to a different group and reconciles all ClusterProfiles
+	// again: all matches change, so stale ClusterSummaries and ClusterConfigurations are cleaned.
+	PhaseClusterProfileRematch = "ClusterProfile rematch"
+)
+
+// Result is the outcome of a scale test run
+type Result struct {
+	// Timings contains the time spent in each phase
+	Timings *Timings
+
+	// ClusterSummaries is the number of ClusterSummaries present after PhaseClusterProfileMatch
+	ClusterSummaries int
+
+	// RemoteClusters is the number of managed clusters accessed
+	RemoteClusters int
+}
+
+// GetScheme returns the scheme used by the fake management cluster
+func GetScheme() (*runtime.Scheme, error) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	if err := configv1beta1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	if err := libsveltosv1beta1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	if err := clusterv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	if err := apiextensionsv1.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run creates the synthetic fleet described by cfg in a fake management cluster and reconciles it.
+// Managed clusters are fake clients.
+// Run overrides, for its whole duration, how controllers access managed clusters, so it must not
+// run concurrently with other tests using the controllers package.
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	scheme, err := GetScheme()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = ctrl.LoggerInto(ctx, logr.Discard())
+
+	objects := getFleet(&cfg)
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&configv1beta1.ClusterProfile{}, &configv1beta1.ClusterSummary{},
+			&configv1beta1.ClusterConfiguration{}, &configv1beta1.ClusterReport{},
+			&libsveltosv1beta1.SveltosCluster{}).
+		WithObjects(objects...).Build()
+
+	remoteClusters := NewRemoteClusters(scheme)
+	controllers.SetRemoteClientGetter(remoteClusters.GetClient)
+	defer controllers.SetRemoteClientGetter(nil)
+
+	timings := newTimings()
+
+	clusterProfileReconciler := getClusterProfileReconciler(c, scheme)
+	clusterSummaryReconciler := getClusterSummaryReconciler(c, scheme)
+	// Watchers are a singleton: when invoked multiple times, the first client is kept. This is fine
+	// as no watcher is needed (ClusterProfiles do not use TemplateResourceRefs nor drift detection).
+	controllers.InitializeWatchers(logr.Discard(), nil, c)
+
+	if err := reconcileClusterProfiles(ctx, &cfg, clusterProfileReconciler, timings,
+		PhaseClusterProfileMatch); err != nil {
+		return nil, err
+	}
+
+	clusterSummaries := &configv1beta1.ClusterSummaryList{}
+	if err := c.List(ctx, clusterSummaries); err != nil {
+		return nil, err
+	}
+
+	for _, phase := range []string{PhaseClusterSummaryDeploy, PhaseClusterSummaryProvisioned} {
+		if err := reconcileClusterSummaries(ctx, &cfg, clusterSummaries, clusterSummaryReconciler,
+			timings, phase); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := moveClusters(ctx, &cfg, c); err != nil {
+		return nil, err
+	}
+
+	if err := reconcileClusterProfiles(ctx, &cfg, clusterProfileReconciler, timings,
+		PhaseClusterProfileRematch); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Timings:          timings,
+		ClusterSummaries: len(clusterSummaries.Items),
+		RemoteClusters:   remoteClusters.Len(),
+	}, nil
+}
+
+func getClusterProfileReconciler(c client.Client, scheme *runtime.Scheme) *controllers.ClusterProfileReconciler {
+	return &controllers.ClusterProfileReconciler{
+		Client:          c,
+		Scheme:          scheme,
+		ClusterMap:      make(map[corev1.ObjectReference]*libsveltosset.Set),
+		ClusterSetMap:   make(map[corev1.ObjectReference]*libsveltosset.Set),
+		ClusterProfiles: make(map[corev1.ObjectReference]libsveltosv1beta1.Selector),
+		ClusterLabels:   make(map[corev1.ObjectReference]map[string]string),
+		Mux:             sync.Mutex{},
+	}
+}
+
+func getClusterSummaryReconciler(c client.Client, scheme *runtime.Scheme,
+) *controllers.ClusterSummaryReconciler {
+
+	d := newFakeDeployer()
+	controllers.RegisterFeatures(d, logr.Discard())
+
+	return &controllers.ClusterSummaryReconciler{
+		Client:           c,
+		Scheme:           scheme,
+		Logger:           logr.Discard(),
+		Deployer:         d,
+		ClusterMap:       make(map[corev1.ObjectReference]*libsveltosset.Set),
+		ReferenceMap:     make(map[corev1.ObjectReference]*libsveltosset.Set),
+		DeploymentSlots:  make(map[corev1.ObjectReference]*libsveltosset.Set),
+		DeploymentQueue:  make(map[corev1.ObjectReference][]corev1.ObjectReference),
+		EnforcementSlots: make(map[corev1.ObjectReference]*libsveltosset.Set),
+		PolicyMux:        sync.Mutex{},
+	}
+}
+
+// runConcurrently invokes reconcile for each of the n requests using cfg.Workers goroutines.
+// Time taken by each reconciliation and by the phase overall is recorded.
+func runConcurrently(cfg *Config, n int, timings *Timings, phase string,
+	reconcile func(i int) error) error {
+
+	start := time.Now()
+
+	work := make(chan int)
+	errs := make(chan error, n)
+
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				reconcileStart := time.Now()
+				if err := reconcile(i); err != nil {
+					errs <- err
+				}
+				timings.record(phase, time.Since(reconcileStart))
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	close(errs)
+
+	timings.addPhase(phase, time.Since(start))
+
+	// Report first error only
+	for err := range errs {
+		return fmt.Errorf("%s: %w", phase, err)
+	}
+	return nil
+}
+
+func reconcileClusterProfiles(ctx context.Context, cfg *Config, r *controllers.ClusterProfileReconciler,
+	timings *Timings, phase string) error {
+
+	return runConcurrently(cfg, cfg.Profiles, timings, phase, func(i int) error {
+		_, err := r.Reconcile(ctx, ctrl.Request{
+			NamespacedName: client.ObjectKeyFromObject(getClusterProfile(cfg, i)),
+		})
+		return err
+	})
+}
+
+func reconcileClusterSummaries(ctx context.Context, cfg *Config, clusterSummaries *configv1beta1.ClusterSummaryList,
+	r *controllers.ClusterSummaryReconciler, timings *Timings, phase string) error {
+
+	return runConcurrently(cfg, len(clusterSummaries.Items), timings, phase, func(i int) error {
+		_, err := r.Reconcile(ctx, ctrl.Request{
+			NamespacedName: client.ObjectKeyFromObject(&clusterSummaries.Items[i]),
+		})
+		return err
+	})
+}
+
+// moveClusters moves every cluster to the next group, so that no cluster matches any
+// of the ClusterProfiles it was matching before.
+func moveClusters(ctx context.Context, cfg *Config, c client.Client) error {
+	for i := 0; i < cfg.Clusters; i++ {
+		cluster := &libsveltosv1beta1.SveltosCluster{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(getSveltosCluster(cfg, i)), cluster); err != nil {
+			return err
+		}
+		cluster.Labels[groupLabel] = getClusterGroup(cfg, i, true)
+		if err := c.Update(ctx, cluster); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaletest_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectsveltos/addon-controller/pkg/scaletest"
+)
+
+const (
+	// scaleTestEnv enables the load-testing mode, reconciling scaletest.DefaultConfig fleet
+	scaleTestEnv = "SCALE_TEST"
+)
+
+func report(result *scaletest.Result) {
+	var sb strings.Builder
+	result.Timings.Report(&sb)
+	AddReportEntry("timings", sb.String())
+	GinkgoWriter.Print(sb.String())
+}
+
+var _ = Describe("Scale test", func() {
+	It("reconciles a small fleet", func() {
+		cfg := scaletest.Config{
+			Clusters:   20,
+			Profiles:   4,
+			Namespaces: 2,
+			Groups:     2,
+			Workers:    4,
+		}
+
+		result, err := scaletest.Run(context.TODO(), cfg)
+		Expect(err).To(BeNil())
+		report(result)
+
+		// Each ClusterProfile matches Clusters/Groups clusters
+		Expect(result.ClusterSummaries).To(Equal(cfg.Profiles * cfg.Clusters / cfg.Groups))
+
+		for _, phase := range []string{scaletest.PhaseClusterProfileMatch, scaletest.PhaseClusterProfileRematch} {
+			Expect(result.Timings.Get(phase).Count).To(Equal(cfg.Profiles))
+		}
+		Expect(result.Timings.Get(scaletest.PhaseClusterSummaryDeploy).Count).To(Equal(result.ClusterSummaries))
+	})
+
+	It("ClusterProfile reconciliation time grows linearly with the number of clusters", func() {
+		if os.Getenv(scaleTestEnv) == "" {
+			Skip("set " + scaleTestEnv + " to run in load-testing mode")
+		}
+
+		// Number of clusters per namespace is kept constant: lookups scoped to the cluster namespace
+		// are expected to be linear in the number of objects in that namespace. What must not grow
+		// more than linearly is the cost of cluster-wide bookkeeping (ClusterProfileReconciler maps,
+		// ClusterConfiguration cleanup).
+		cfg := scaletest.DefaultConfig()
+		half := cfg
+		half.Clusters /= 2
+		half.Namespaces /= 2
+
+		halfResult, err := scaletest.Run(context.TODO(), half)
+		Expect(err).To(BeNil())
+		report(halfResult)
+
+		result, err := scaletest.Run(context.TODO(), cfg)
+		Expect(err).To(BeNil())
+		report(result)
+
+		// Doubling the clusters doubles the clusters each ClusterProfile matches. With linear behavior,
+		// reconciling a ClusterProfile takes about twice as long; with quadratic behavior four times.
+		const maxRatio = 3
+		for _, phase := range []string{scaletest.PhaseClusterProfileMatch, scaletest.PhaseClusterProfileRematch} {
+			halfMean := halfResult.Timings.Get(phase).Mean
+			mean := result.Timings.Get(phase).Mean
+			Expect(mean).To(BeNumerically("<", maxRatio*halfMean+time.Millisecond),
+				"%s: mean reconciliation %s with %d clusters, %s with %d clusters",
+				phase, halfMean, half.Clusters, mean, cfg.Clusters)
+		}
+	})
+})
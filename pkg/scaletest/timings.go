@@ -0,0 +1,145 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaletest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PhaseTiming summarizes the time spent in one phase of the scale test
+type PhaseTiming struct {
+	// Phase is the name of the phase
+	Phase string
+
+	// Elapsed is the wall-clock time of the phase
+	Elapsed time.Duration
+
+	// Count is the number of reconciliations run in the phase
+	Count int
+
+	// Mean and Max are computed over the reconciliations run in the phase
+	Mean time.Duration
+	Max  time.Duration
+}
+
+// Timings records how long each reconciliation takes, per phase. It is safe for concurrent use.
+type Timings struct {
+	mux     sync.Mutex
+	order   []string
+	elapsed map[string]time.Duration
+	samples map[string][]time.Duration
+}
+
+func newTimings() *Timings {
+	return &Timings{
+		elapsed: make(map[string]time.Duration),
+		samples: make(map[string][]time.Duration),
+	}
+}
+
+func (t *Timings) addPhase(phase string, elapsed time.Duration) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if _, ok := t.elapsed[phase]; !ok {
+		t.order = append(t.order, phase)
+	}
+	t.elapsed[phase] += elapsed
+}
+
+func (t *Timings) record(phase string, d time.Duration) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.samples[phase] = append(t.samples[phase], d)
+}
+
+// Get returns the timing of phase. Zero value is returned if phase never ran.
+func (t *Timings) Get(phase string) PhaseTiming {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	return t.get(phase)
+}
+
+func (t *Timings) get(phase string) PhaseTiming {
+	pt := PhaseTiming{
+		Phase:   phase,
+		Elapsed: t.elapsed[phase],
+		Count:   len(t.samples[phase]),
+	}
+
+	if pt.Count == 0 {
+		return pt
+	}
+
+	var total time.Duration
+	for _, d := range t.samples[phase] {
+		total += d
+		if d > pt.Max {
+			pt.Max = d
+		}
+	}
+	pt.Mean = total / time.Duration(pt.Count)
+
+	return pt
+}
+
+// Phases returns the timing of all phases, in the order they ran
+func (t *Timings) Phases() []PhaseTiming {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	result := make([]PhaseTiming, len(t.order))
+	for i := range t.order {
+		result[i] = t.get(t.order[i])
+	}
+	return result
+}
+
+// Percentile returns the p-th percentile (0 < p <= 100) of the reconciliations run in phase
+func (t *Timings) Percentile(phase string, p float64) time.Duration {
+	t.mux.Lock()
+	samples := append([]time.Duration(nil), t.samples[phase]...)
+	t.mux.Unlock()
+
+	if len(samples) == 0 {
+		return 0
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	index := int(float64(len(samples))*p/100+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(samples) {
+		index = len(samples) - 1
+	}
+	return samples[index]
+}
+
+// Report writes a human readable summary of all phases to w
+func (t *Timings) Report(w io.Writer) {
+	for _, pt := range t.Phases() {
+		fmt.Fprintf(w, "%-32s elapsed=%-14s reconciliations=%-6d mean=%-14s p99=%-14s max=%s\n",
+			pt.Phase, pt.Elapsed, pt.Count, pt.Mean, t.Percentile(pt.Phase, 99), pt.Max)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -133,7 +134,7 @@ func (s *ProfileScope) IsOneTimeSync() bool {
 // IsDryRunSync returns true if Profile sync mod is set to dryRun
 func (s *ProfileScope) IsDryRunSync() bool {
 	spec := s.GetSpec()
-	return spec.SyncMode == configv1beta1.SyncModeDryRun
+	return configv1beta1.IsDryRunSyncMode(spec.SyncMode)
 }
 
 func (s *ProfileScope) GetSpec() *configv1beta1.Spec {
@@ -164,6 +165,66 @@ func (s *ProfileScope) GetStatus() *configv1beta1.Status {
 	return nil
 }
 
+// SetRolloutAborted sets the RolloutAborted condition. An empty reason means rollout is not aborted.
+func (s *ProfileScope) SetRolloutAborted(reason, message string) {
+	condition := metav1.Condition{
+		Type:               configv1beta1.RolloutAbortedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             configv1beta1.NotAbortedReason,
+		ObservedGeneration: s.Profile.GetGeneration(),
+	}
+	if reason != "" {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reason
+		condition.Message = message
+	}
+
+	meta.SetStatusCondition(&s.GetStatus().Conditions, condition)
+}
+
+// IsRolloutAborted returns true if the rollout of current Spec was aborted, that is if
+// RolloutAborted condition is set to true for current generation.
+func (s *ProfileScope) IsRolloutAborted() bool {
+	condition := meta.FindStatusCondition(s.GetStatus().Conditions, configv1beta1.RolloutAbortedCondition)
+	return condition != nil && condition.Status == metav1.ConditionTrue &&
+		condition.ObservedGeneration == s.Profile.GetGeneration()
+}
+
+// SetControllerVersionSupported sets the ControllerVersionSupported condition. message is only
+// used when the controller version is not supported.
+func (s *ProfileScope) SetControllerVersionSupported(supported bool, message string) {
+	condition := metav1.Condition{
+		Type:               configv1beta1.ControllerVersionSupportedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             configv1beta1.ControllerVersionSatisfiedReason,
+		ObservedGeneration: s.Profile.GetGeneration(),
+	}
+	if !supported {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = configv1beta1.ControllerVersionTooOldReason
+		condition.Message = message
+	}
+
+	meta.SetStatusCondition(&s.GetStatus().Conditions, condition)
+}
+
+// SetReady sets the Ready condition and records the generation it was computed for
+func (s *ProfileScope) SetReady(ready bool, reason, message string) {
+	condition := metav1.Condition{
+		Type:               configv1beta1.ReadyCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: s.Profile.GetGeneration(),
+	}
+	if !ready {
+		condition.Status = metav1.ConditionFalse
+	}
+
+	meta.SetStatusCondition(&s.GetStatus().Conditions, condition)
+	s.GetStatus().ObservedGeneration = s.Profile.GetGeneration()
+}
+
 func (s *ProfileScope) GetClusterProfile() *configv1beta1.ClusterProfile {
 	return s.Profile.(*configv1beta1.ClusterProfile)
 }
@@ -225,6 +225,31 @@ var _ = Describe("ProfileScope/ClusterProfileScope", func() {
 		Expect(reflect.DeepEqual(profile.Status.MatchingClusterRefs, matchingClusters)).To(BeTrue())
 	})
 
+	It("SetRolloutAborted sets the RolloutAborted condition for current generation", func() {
+		params := scope.ProfileScopeParams{
+			Client:  c,
+			Profile: clusterProfile,
+			Logger:  textlogger.NewLogger(textlogger.NewConfig()),
+		}
+
+		scope, err := scope.NewProfileScope(params)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scope.IsRolloutAborted()).To(BeFalse())
+
+		scope.SetRolloutAborted(configv1beta1.FailureThresholdExceededReason, randomString())
+		Expect(scope.IsRolloutAborted()).To(BeTrue())
+		Expect(len(clusterProfile.Status.Conditions)).To(Equal(1))
+
+		// Spec changed: rollout of new Spec is not aborted
+		clusterProfile.Generation++
+		Expect(scope.IsRolloutAborted()).To(BeFalse())
+
+		scope.SetRolloutAborted("", "")
+		Expect(scope.IsRolloutAborted()).To(BeFalse())
+		Expect(len(clusterProfile.Status.Conditions)).To(Equal(1))
+		Expect(clusterProfile.Status.Conditions[0].Reason).To(Equal(configv1beta1.NotAbortedReason))
+	})
+
 	It("Close updates ClusterProfile", func() {
 		objects := []client.Object{clusterProfile, profile}
 		for i := range objects {
@@ -18,9 +18,11 @@ package scope
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -73,6 +75,8 @@ type ClusterSummaryScope struct {
 
 // PatchObject persists the cluster configuration and status.
 func (s *ClusterSummaryScope) PatchObject(ctx context.Context) error {
+	s.updateFeatureConditions()
+
 	return s.patchHelper.Patch(
 		ctx,
 		s.ClusterSummary,
@@ -104,8 +108,17 @@ func (s *ClusterSummaryScope) initializeFeatureStatusSummary() {
 func (s *ClusterSummaryScope) SetFeatureStatus(featureID configv1beta1.FeatureID,
 	status configv1beta1.FeatureStatus, hash []byte) {
 
+	now := metav1.Now()
+
 	for i := range s.ClusterSummary.Status.FeatureSummaries {
 		if s.ClusterSummary.Status.FeatureSummaries[i].FeatureID == featureID {
+			// LastTransitionTime only changes on real transitions so that re-asserting the
+			// same status does not cause a status update
+			if s.ClusterSummary.Status.FeatureSummaries[i].Status != status ||
+				s.ClusterSummary.Status.FeatureSummaries[i].LastTransitionTime == nil {
+
+				s.ClusterSummary.Status.FeatureSummaries[i].LastTransitionTime = &now
+			}
 			s.ClusterSummary.Status.FeatureSummaries[i].Status = status
 			s.ClusterSummary.Status.FeatureSummaries[i].Hash = hash
 			return
@@ -117,18 +130,172 @@ func (s *ClusterSummaryScope) SetFeatureStatus(featureID configv1beta1.FeatureID
 	s.ClusterSummary.Status.FeatureSummaries = append(
 		s.ClusterSummary.Status.FeatureSummaries,
 		configv1beta1.FeatureSummary{
-			FeatureID: featureID,
-			Status:    status,
-			Hash:      hash,
+			FeatureID:          featureID,
+			Status:             status,
+			Hash:               hash,
+			LastTransitionTime: &now,
 		},
 	)
 }
 
+// updateFeatureConditions sets, for each feature, the condition reporting whether the feature
+// is provisioned. Conditions are derived from FeatureSummaries so that tools like kubectl wait
+// can consume ClusterSummary readiness.
+func (s *ClusterSummaryScope) updateFeatureConditions() {
+	featureSummaries := make(map[configv1beta1.FeatureID]*configv1beta1.FeatureSummary)
+	for i := range s.ClusterSummary.Status.FeatureSummaries {
+		fs := &s.ClusterSummary.Status.FeatureSummaries[i]
+		featureSummaries[fs.FeatureID] = fs
+	}
+
+	for featureID, conditionType := range configv1beta1.FeatureConditionTypes {
+		fs, ok := featureSummaries[featureID]
+		if !ok || fs.Status == "" {
+			meta.RemoveStatusCondition(&s.ClusterSummary.Status.Conditions, conditionType)
+			continue
+		}
+		meta.SetStatusCondition(&s.ClusterSummary.Status.Conditions,
+			s.getFeatureCondition(conditionType, fs))
+	}
+
+	s.updateDriftDetectionCondition()
+}
+
+func (s *ClusterSummaryScope) getFeatureCondition(conditionType string,
+	fs *configv1beta1.FeatureSummary) metav1.Condition {
+
+	condition := metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: s.ClusterSummary.Generation,
+	}
+
+	switch fs.Status {
+	case configv1beta1.FeatureStatusProvisioned:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = configv1beta1.ProvisionedReason
+	case configv1beta1.FeatureStatusProvisioning:
+		condition.Reason = configv1beta1.ProvisioningReason
+	case configv1beta1.FeatureStatusFailed:
+		condition.Reason = configv1beta1.ProvisioningFailedReason
+	case configv1beta1.FeatureStatusFailedNonRetriable:
+		condition.Reason = configv1beta1.ProvisioningFailedNonRetriableReason
+	case configv1beta1.FeatureStatusRemoving:
+		condition.Reason = configv1beta1.RemovingReason
+	case configv1beta1.FeatureStatusRemoved:
+		condition.Reason = configv1beta1.RemovedReason
+	}
+
+	if fs.FailureMessage != nil {
+		condition.Message = *fs.FailureMessage
+		if fs.FailureReason != nil && *fs.FailureReason != "" {
+			condition.Message = configv1beta1.FormatMessage(configv1beta1.MessageCode(*fs.FailureReason),
+				*fs.FailureMessage)
+		}
+	}
+
+	return condition
+}
+
+// updateDriftDetectionCondition sets the DriftDetectionDeployed condition. drift-detection-manager
+// is deployed as part of each feature deployment, failing the feature on error. So it is considered
+// deployed once all features are provisioned.
+func (s *ClusterSummaryScope) updateDriftDetectionCondition() {
+	featureSummaries := s.ClusterSummary.Status.FeatureSummaries
+	if !s.IsContinuousWithDriftDetection() || len(featureSummaries) == 0 {
+		meta.RemoveStatusCondition(&s.ClusterSummary.Status.Conditions,
+			configv1beta1.DriftDetectionDeployedCondition)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               configv1beta1.DriftDetectionDeployedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             configv1beta1.ProvisionedReason,
+		ObservedGeneration: s.ClusterSummary.Generation,
+	}
+
+	for i := range featureSummaries {
+		if featureSummaries[i].Status != configv1beta1.FeatureStatusProvisioned {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = configv1beta1.ProvisioningReason
+			condition.Message = fmt.Sprintf("feature %s is not provisioned yet", featureSummaries[i].FeatureID)
+			break
+		}
+	}
+
+	meta.SetStatusCondition(&s.ClusterSummary.Status.Conditions, condition)
+}
+
 // SetDependenciesMessage sets the dependencies status.
 func (s *ClusterSummaryScope) SetDependenciesMessage(message *string) {
 	s.ClusterSummary.Status.Dependencies = message
 }
 
+// pausedMessageCodes maps Paused condition reasons to their MessageCode
+var pausedMessageCodes = map[string]configv1beta1.MessageCode{
+	configv1beta1.PausedByClusterReason:           configv1beta1.MessageCodeClusterPaused,
+	configv1beta1.PausedByProfileReason:           configv1beta1.MessageCodeProfilePaused,
+	configv1beta1.PausedByAnnotationReason:        configv1beta1.MessageCodePausedByAnnotation,
+	configv1beta1.PausedByMaintenanceWindowReason: configv1beta1.MessageCodeOutsideMaintenanceWindow,
+}
+
+// SetPaused sets the Paused condition. An empty reason means ClusterSummary is not paused.
+func (s *ClusterSummaryScope) SetPaused(reason string) {
+	condition := metav1.Condition{
+		Type:               configv1beta1.ClusterSummaryPausedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             configv1beta1.NotPausedReason,
+		ObservedGeneration: s.ClusterSummary.Generation,
+	}
+	if reason != "" {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reason
+		condition.Message = "deployments to the cluster are paused"
+		if code, ok := pausedMessageCodes[reason]; ok {
+			condition.Message = configv1beta1.FormatMessage(code, condition.Message)
+		}
+	}
+
+	meta.SetStatusCondition(&s.ClusterSummary.Status.Conditions, condition)
+}
+
+// connectionMessageCodes maps ConnectionDown condition reasons to their MessageCode
+var connectionMessageCodes = map[string]configv1beta1.MessageCode{
+	configv1beta1.KubeconfigInvalidReason:    configv1beta1.MessageCodeKubeconfigInvalid,
+	configv1beta1.APIServerUnreachableReason: configv1beta1.MessageCodeAPIServerUnreachable,
+}
+
+// SetConnectionStatus sets Status.Connection and the ConnectionDown condition.
+// A reason equal to ConnectedReason means the managed cluster was reached.
+func (s *ClusterSummaryScope) SetConnectionStatus(connection *configv1beta1.ClusterConnectionStatus,
+	reason, message string) {
+
+	s.ClusterSummary.Status.Connection = connection
+
+	condition := metav1.Condition{
+		Type:               configv1beta1.ConnectionDownCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             configv1beta1.ConnectedReason,
+		ObservedGeneration: s.ClusterSummary.Generation,
+	}
+	if reason != configv1beta1.ConnectedReason {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reason
+		condition.Message = message
+		if code, ok := connectionMessageCodes[reason]; ok {
+			condition.Message = configv1beta1.FormatMessage(code, condition.Message)
+		}
+	}
+
+	meta.SetStatusCondition(&s.ClusterSummary.Status.Conditions, condition)
+}
+
+// IsPaused returns true if ClusterSummary Paused condition is set to true.
+func (s *ClusterSummaryScope) IsPaused() bool {
+	return meta.IsStatusConditionTrue(s.ClusterSummary.Status.Conditions, configv1beta1.ClusterSummaryPausedCondition)
+}
+
 // SetFailureMessage sets the infrastructure status failure message.
 func (s *ClusterSummaryScope) SetFailureMessage(featureID configv1beta1.FeatureID, failureMessage *string) {
 	for i := range s.ClusterSummary.Status.FeatureSummaries {
@@ -192,6 +359,31 @@ func (s *ClusterSummaryScope) SetLastAppliedTime(featureID configv1beta1.Feature
 	)
 }
 
+// SetRetryStatus sets the number of consecutive deployment failures and the earliest time
+// deployment will be retried for a feature.
+func (s *ClusterSummaryScope) SetRetryStatus(featureID configv1beta1.FeatureID,
+	consecutiveFailures int32, nextRetryTime *metav1.Time) {
+
+	for i := range s.ClusterSummary.Status.FeatureSummaries {
+		if s.ClusterSummary.Status.FeatureSummaries[i].FeatureID == featureID {
+			s.ClusterSummary.Status.FeatureSummaries[i].ConsecutiveFailures = consecutiveFailures
+			s.ClusterSummary.Status.FeatureSummaries[i].NextRetryTime = nextRetryTime
+			return
+		}
+	}
+
+	s.initializeFeatureStatusSummary()
+
+	s.ClusterSummary.Status.FeatureSummaries = append(
+		s.ClusterSummary.Status.FeatureSummaries,
+		configv1beta1.FeatureSummary{
+			FeatureID:           featureID,
+			ConsecutiveFailures: consecutiveFailures,
+			NextRetryTime:       nextRetryTime,
+		},
+	)
+}
+
 // IsContinuousWithDriftDetection returns true if ClusterProfile is set to SyncModeContinuousWithDriftDetection
 func (s *ClusterSummaryScope) IsContinuousWithDriftDetection() bool {
 	return s.ClusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeContinuousWithDriftDetection
@@ -210,5 +402,5 @@ func (s *ClusterSummaryScope) IsOneTimeSync() bool {
 
 // IsDryRunSync returns true if ClusterProfile sync mod is set to dryRun
 func (s *ClusterSummaryScope) IsDryRunSync() bool {
-	return s.ClusterSummary.Spec.ClusterProfileSpec.SyncMode == configv1beta1.SyncModeDryRun
+	return configv1beta1.IsDryRunSyncMode(s.ClusterSummary.Spec.ClusterProfileSpec.SyncMode)
 }
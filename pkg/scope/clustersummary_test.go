@@ -22,6 +22,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2/textlogger"
@@ -122,6 +123,36 @@ var _ = Describe("ClusterSummaryScope", func() {
 		Expect(clusterSummary.Status.FeatureSummaries[0].Status).To(Equal(configv1beta1.FeatureStatusProvisioned))
 	})
 
+	It("SetFeatureStatus updates LastTransitionTime only when status changes", func() {
+		params := &scope.ClusterSummaryScopeParams{
+			Client:         c,
+			Profile:        clusterProfile,
+			ClusterSummary: clusterSummary,
+			Logger:         textlogger.NewLogger(textlogger.NewConfig()),
+		}
+
+		lastTransitionTime := metav1.NewTime(time.Now().Add(-time.Hour))
+		clusterSummary.Status.FeatureSummaries = []configv1beta1.FeatureSummary{
+			{
+				FeatureID: configv1beta1.FeatureResources, Status: configv1beta1.FeatureStatusProvisioned,
+				Hash: []byte(randomString()), LastTransitionTime: &lastTransitionTime,
+			},
+		}
+
+		scope, err := scope.NewClusterSummaryScope(params)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scope).ToNot(BeNil())
+
+		hash := []byte(randomString())
+		scope.SetFeatureStatus(configv1beta1.FeatureResources, configv1beta1.FeatureStatusProvisioned, hash)
+		Expect(clusterSummary.Status.FeatureSummaries[0].Hash).To(Equal(hash))
+		Expect(*clusterSummary.Status.FeatureSummaries[0].LastTransitionTime).To(Equal(lastTransitionTime))
+
+		scope.SetFeatureStatus(configv1beta1.FeatureResources, configv1beta1.FeatureStatusProvisioning, hash)
+		Expect(clusterSummary.Status.FeatureSummaries[0].LastTransitionTime).ToNot(BeNil())
+		Expect(clusterSummary.Status.FeatureSummaries[0].LastTransitionTime.After(lastTransitionTime.Time)).To(BeTrue())
+	})
+
 	It("SetFailureMessage updates ClusterSummary Status FeatureSummary when not nil", func() {
 		params := &scope.ClusterSummaryScopeParams{
 			Client:         c,
@@ -250,6 +281,59 @@ var _ = Describe("ClusterSummaryScope", func() {
 		Expect(clusterSummary.Status.FeatureSummaries[0].Status).To(Equal(configv1beta1.FeatureStatusProvisioning))
 	})
 
+	It("SetPaused sets the Paused condition", func() {
+		params := &scope.ClusterSummaryScopeParams{
+			Client:         c,
+			ClusterSummary: clusterSummary,
+			Profile:        clusterProfile,
+			Logger:         textlogger.NewLogger(textlogger.NewConfig()),
+		}
+
+		scope, err := scope.NewClusterSummaryScope(params)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scope.IsPaused()).To(BeFalse())
+
+		scope.SetPaused(configv1beta1.PausedByAnnotationReason)
+		Expect(scope.IsPaused()).To(BeTrue())
+		Expect(len(clusterSummary.Status.Conditions)).To(Equal(1))
+		Expect(clusterSummary.Status.Conditions[0].Reason).To(Equal(configv1beta1.PausedByAnnotationReason))
+
+		scope.SetPaused("")
+		Expect(scope.IsPaused()).To(BeFalse())
+		Expect(len(clusterSummary.Status.Conditions)).To(Equal(1))
+		Expect(clusterSummary.Status.Conditions[0].Reason).To(Equal(configv1beta1.NotPausedReason))
+	})
+
+	It("SetConnectionStatus sets Status.Connection and the ConnectionDown condition", func() {
+		params := &scope.ClusterSummaryScopeParams{
+			Client:         c,
+			ClusterSummary: clusterSummary,
+			Profile:        clusterProfile,
+			Logger:         textlogger.NewLogger(textlogger.NewConfig()),
+		}
+
+		scope, err := scope.NewClusterSummaryScope(params)
+		Expect(err).ToNot(HaveOccurred())
+
+		scope.SetConnectionStatus(&configv1beta1.ClusterConnectionStatus{KubeconfigValid: true},
+			configv1beta1.APIServerUnreachableReason, "connection refused")
+		Expect(clusterSummary.Status.Connection).ToNot(BeNil())
+		Expect(clusterSummary.Status.Connection.APIServerReachable).To(BeFalse())
+		Expect(len(clusterSummary.Status.Conditions)).To(Equal(1))
+		Expect(clusterSummary.Status.Conditions[0].Type).To(Equal(configv1beta1.ConnectionDownCondition))
+		Expect(clusterSummary.Status.Conditions[0].Status).To(Equal(metav1.ConditionTrue))
+		Expect(clusterSummary.Status.Conditions[0].Reason).To(Equal(configv1beta1.APIServerUnreachableReason))
+		Expect(clusterSummary.Status.Conditions[0].Message).To(
+			Equal(configv1beta1.FormatMessage(configv1beta1.MessageCodeAPIServerUnreachable, "connection refused")))
+
+		scope.SetConnectionStatus(&configv1beta1.ClusterConnectionStatus{KubeconfigValid: true, APIServerReachable: true},
+			configv1beta1.ConnectedReason, "")
+		Expect(clusterSummary.Status.Connection.APIServerReachable).To(BeTrue())
+		Expect(len(clusterSummary.Status.Conditions)).To(Equal(1))
+		Expect(clusterSummary.Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
+		Expect(clusterSummary.Status.Conditions[0].Reason).To(Equal(configv1beta1.ConnectedReason))
+	})
+
 	It("SetFailureReason updates ClusterSummary Status FeatureSummary when not nil", func() {
 		params := &scope.ClusterSummaryScopeParams{
 			Client:         c,
@@ -333,6 +417,71 @@ var _ = Describe("ClusterSummaryScope", func() {
 		Expect(len(currentClusterSummary.Status.FeatureSummaries)).To(Equal(1))
 	})
 
+	It("Close sets per-feature conditions", func() {
+		clusterSummary.Spec.ClusterProfileSpec.SyncMode = configv1beta1.SyncModeContinuousWithDriftDetection
+		params := &scope.ClusterSummaryScopeParams{
+			Client:         c,
+			Profile:        clusterProfile,
+			ClusterSummary: clusterSummary,
+			Logger:         textlogger.NewLogger(textlogger.NewConfig()),
+		}
+
+		scope, err := scope.NewClusterSummaryScope(params)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scope).ToNot(BeNil())
+
+		failureMessage := failedToDeploy
+		failureReason := string(configv1beta1.MessageCodeChartPullFailed)
+		clusterSummary.Status.FeatureSummaries = []configv1beta1.FeatureSummary{
+			{
+				FeatureID: configv1beta1.FeatureHelm,
+				Status:    configv1beta1.FeatureStatusProvisioned,
+			},
+			{
+				FeatureID:      configv1beta1.FeatureResources,
+				Status:         configv1beta1.FeatureStatusFailed,
+				FailureMessage: &failureMessage,
+			},
+			{
+				FeatureID:      configv1beta1.FeatureClusterMetadata,
+				Status:         configv1beta1.FeatureStatusFailed,
+				FailureReason:  &failureReason,
+				FailureMessage: &failureMessage,
+			},
+		}
+
+		Expect(scope.Close(context.TODO())).To(Succeed())
+
+		currentClusterSummary := &configv1beta1.ClusterSummary{}
+		Expect(c.Get(context.TODO(),
+			types.NamespacedName{Namespace: clusterSummary.Namespace, Name: clusterSummary.Name},
+			currentClusterSummary)).To(Succeed())
+
+		conditions := currentClusterSummary.Status.Conditions
+		helmCondition := meta.FindStatusCondition(conditions, configv1beta1.HelmProvisionedCondition)
+		Expect(helmCondition).ToNot(BeNil())
+		Expect(helmCondition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(helmCondition.Reason).To(Equal(configv1beta1.ProvisionedReason))
+
+		resourcesCondition := meta.FindStatusCondition(conditions, configv1beta1.ResourcesProvisionedCondition)
+		Expect(resourcesCondition).ToNot(BeNil())
+		Expect(resourcesCondition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(resourcesCondition.Reason).To(Equal(configv1beta1.ProvisioningFailedReason))
+		Expect(resourcesCondition.Message).To(Equal(failedToDeploy))
+
+		metadataCondition := meta.FindStatusCondition(conditions, configv1beta1.ClusterMetadataProvisionedCondition)
+		Expect(metadataCondition).ToNot(BeNil())
+		Expect(metadataCondition.Message).To(Equal("[SVE1001] " + failedToDeploy))
+		Expect(configv1beta1.GetMessageCode(metadataCondition.Message)).To(Equal(configv1beta1.MessageCodeChartPullFailed))
+		Expect(configv1beta1.GetMessageCode(resourcesCondition.Message)).To(BeEmpty())
+
+		Expect(meta.FindStatusCondition(conditions, configv1beta1.KustomizeProvisionedCondition)).To(BeNil())
+
+		driftCondition := meta.FindStatusCondition(conditions, configv1beta1.DriftDetectionDeployedCondition)
+		Expect(driftCondition).ToNot(BeNil())
+		Expect(driftCondition.Status).To(Equal(metav1.ConditionFalse))
+	})
+
 	It("SetLastAppliedTime updates featureSummary with time (entry not existing yet)", func() {
 		params := &scope.ClusterSummaryScopeParams{
 			Client:         c,
@@ -0,0 +1,186 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// verifier runs verifications (for instance health checks of features just deployed in a
+// managed cluster) with its own pool of workers and its own rate limit. So slow verifications
+// never hold the workers deploying features.
+//
+// Same as clusterdeployer, a verification:
+// - is dropped if already queued or in progress;
+// - result is returned only once.
+// Results never collected are forgotten after resultTTL.
+
+const (
+	defaultPoolSize = 10
+	defaultQPS      = 20
+	defaultBurst    = 40
+
+	resultTTL = 10 * time.Minute
+)
+
+// VerifyFunc is the verification to run
+type VerifyFunc func(ctx context.Context) error
+
+// ResultStatus is the status of a verification
+type ResultStatus int
+
+const (
+	// Unavailable means verification was never requested or its result was already returned
+	Unavailable ResultStatus = iota
+
+	// InProgress means verification is queued or being run
+	InProgress
+
+	// Done means verification was run. Result.Err contains the outcome
+	Done
+)
+
+// Result is the result of a verification
+type Result struct {
+	Status ResultStatus
+	Err    error
+}
+
+// Options configures the Verifier
+type Options struct {
+	// PoolSize is the number of workers running verifications
+	PoolSize int
+
+	// QPS is the maximum number of verifications started per second
+	QPS float64
+
+	// Burst is the maximum number of verifications started at once
+	Burst int
+}
+
+type result struct {
+	err  error
+	time time.Time
+}
+
+// Verifier runs verifications asynchronously
+type Verifier struct {
+	log     logr.Logger
+	options Options
+	limiter *rate.Limiter
+
+	mu   *sync.Mutex
+	cond *sync.Cond
+
+	// queue contains, in order, the keys of verifications waiting for a worker
+	queue []string
+
+	// requests contains verifications waiting for a worker
+	requests map[string]VerifyFunc
+
+	// inProgress contains verifications currently being run
+	inProgress map[string]bool
+
+	// results contains results of verifications run and not collected yet
+	results map[string]*result
+}
+
+// New creates a Verifier and starts its workers. Workers stop when ctx is canceled.
+func New(ctx context.Context, l logr.Logger, options Options) *Verifier {
+	if options.PoolSize <= 0 {
+		options.PoolSize = defaultPoolSize
+	}
+	if options.QPS <= 0 {
+		options.QPS = defaultQPS
+	}
+	if options.Burst <= 0 {
+		options.Burst = defaultBurst
+	}
+
+	l.V(logs.LogInfo).Info(fmt.Sprintf("Creating verifier. Number of workers: %d. QPS: %v. Burst: %d",
+		options.PoolSize, options.QPS, options.Burst))
+
+	mu := &sync.Mutex{}
+	v := &Verifier{
+		log:        l,
+		options:    options,
+		limiter:    rate.NewLimiter(rate.Limit(options.QPS), options.Burst),
+		mu:         mu,
+		cond:       sync.NewCond(mu),
+		requests:   make(map[string]VerifyFunc),
+		inProgress: make(map[string]bool),
+		results:    make(map[string]*result),
+	}
+
+	v.startWorkers(ctx)
+
+	return v
+}
+
+// Verify queues verification f for key. No action if a verification for the same key
+// is already queued or in progress.
+func (v *Verifier) Verify(key string, f VerifyFunc) {
+	logger := v.log.WithValues("key", key)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, ok := v.requests[key]; ok || v.inProgress[key] {
+		logger.V(logs.LogVerbose).Info("verification is already queued or in progress")
+		return
+	}
+
+	logger.V(logs.LogVerbose).Info("verification queued")
+	delete(v.results, key)
+	v.requests[key] = f
+	v.queue = append(v.queue, key)
+	v.cond.Signal()
+}
+
+// GetResult returns the result of the verification for key. Once a verification is Done,
+// its result is returned only once.
+func (v *Verifier) GetResult(key string) Result {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r, ok := v.results[key]; ok {
+		delete(v.results, key)
+		return Result{Status: Done, Err: r.err}
+	}
+
+	if _, ok := v.requests[key]; ok || v.inProgress[key] {
+		return Result{Status: InProgress}
+	}
+
+	return Result{Status: Unavailable}
+}
+
+// Stats returns the number of verifications queued and the number of workers busy
+func (v *Verifier) Stats() (queued, busy int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return len(v.queue), len(v.inProgress)
+}
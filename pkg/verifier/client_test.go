@@ -0,0 +1,107 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verifier_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/klog/v2/textlogger"
+
+	"github.com/projectsveltos/addon-controller/pkg/verifier"
+)
+
+var _ = Describe("Verifier", func() {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var block chan struct{}
+	var invocations atomic.Int32
+
+	slow := func(ctx context.Context) error {
+		invocations.Add(1)
+		<-block
+		return nil
+	}
+
+	failing := func(ctx context.Context) error {
+		invocations.Add(1)
+		return errors.New("not healthy")
+	}
+
+	getStatus := func(v *verifier.Verifier, key string) verifier.ResultStatus {
+		return v.GetResult(key).Status
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.TODO())
+		block = make(chan struct{})
+		invocations.Store(0)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("slow verifications do not prevent running other verifications", func() {
+		v := verifier.New(ctx, textlogger.NewLogger(textlogger.NewConfig()), verifier.Options{PoolSize: 2})
+
+		v.Verify("slow", slow)
+		Eventually(func() int32 {
+			return invocations.Load()
+		}, time.Minute, time.Millisecond*100).Should(Equal(int32(1)))
+
+		// Same verification is never run twice in parallel
+		v.Verify("slow", slow)
+		Expect(getStatus(v, "slow")).To(Equal(verifier.InProgress))
+
+		v.Verify("failing", failing)
+		Eventually(func() verifier.ResultStatus {
+			return getStatus(v, "failing")
+		}, time.Minute, time.Millisecond*100).Should(Equal(verifier.Done))
+		Expect(invocations.Load()).To(Equal(int32(2)))
+
+		queued, busy := v.Stats()
+		Expect(queued).To(Equal(0))
+		Expect(busy).To(Equal(1))
+
+		close(block)
+		Eventually(func() verifier.ResultStatus {
+			return getStatus(v, "slow")
+		}, time.Minute, time.Millisecond*100).Should(Equal(verifier.Done))
+		// Result is consumed once returned
+		Expect(getStatus(v, "slow")).To(Equal(verifier.Unavailable))
+		Expect(invocations.Load()).To(Equal(int32(2)))
+	})
+
+	It("GetResult returns verification error", func() {
+		v := verifier.New(ctx, textlogger.NewLogger(textlogger.NewConfig()), verifier.Options{PoolSize: 1})
+
+		Expect(getStatus(v, "failing")).To(Equal(verifier.Unavailable))
+		v.Verify("failing", failing)
+
+		var result verifier.Result
+		Eventually(func() verifier.ResultStatus {
+			result = v.GetResult("failing")
+			return result.Status
+		}, time.Minute, time.Millisecond*100).Should(Equal(verifier.Done))
+		Expect(result.Err).To(MatchError("not healthy"))
+	})
+})
@@ -0,0 +1,112 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+func (v *Verifier) startWorkers(ctx context.Context) {
+	for i := 0; i < v.options.PoolSize; i++ {
+		go v.processRequests(ctx, i)
+	}
+
+	// Wake up all workers on shutdown
+	go func() {
+		<-ctx.Done()
+		v.mu.Lock()
+		v.cond.Broadcast()
+		v.mu.Unlock()
+	}()
+}
+
+func (v *Verifier) processRequests(ctx context.Context, id int) {
+	logger := v.log.WithValues("worker", id)
+	logger.V(logs.LogInfo).Info("started worker")
+
+	for {
+		// Wait for the rate limiter before picking a verification, so queued verifications
+		// can still be replaced or reported as in progress
+		if err := v.limiter.Wait(ctx); err != nil {
+			logger.V(logs.LogInfo).Info("context canceled")
+			return
+		}
+
+		key, f := v.next(ctx)
+		if f == nil {
+			logger.V(logs.LogInfo).Info("context canceled")
+			return
+		}
+
+		l := logger.WithValues("key", key)
+		l.V(logs.LogDebug).Info("running verification")
+		err := f(ctx)
+		v.storeResult(key, err)
+	}
+}
+
+// next blocks till a verification can be run and returns it. Returns nil when context is canceled.
+func (v *Verifier) next(ctx context.Context) (string, VerifyFunc) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for len(v.queue) == 0 {
+		if ctx.Err() != nil {
+			return "", nil
+		}
+		v.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return "", nil
+	}
+
+	key := v.queue[0]
+	v.queue = v.queue[1:]
+
+	f := v.requests[key]
+	delete(v.requests, key)
+	v.inProgress[key] = true
+
+	return key, f
+}
+
+// storeResult stores result for further in time lookup and forgets results never collected
+func (v *Verifier) storeResult(key string, err error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	l := v.log.WithValues("key", key)
+	if err != nil {
+		l.V(logs.LogDebug).Info(fmt.Sprintf("added to result with err %s", err.Error()))
+	} else {
+		l.V(logs.LogDebug).Info("added to result")
+	}
+
+	delete(v.inProgress, key)
+
+	now := time.Now()
+	for k := range v.results {
+		if now.Sub(v.results[k].time) > resultTTL {
+			delete(v.results, k)
+		}
+	}
+	v.results[key] = &result{err: err, time: now}
+}
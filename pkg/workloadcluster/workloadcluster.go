@@ -0,0 +1,204 @@
+/*
+Copyright 2024. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workloadcluster provides envtest-based API servers acting as managed (workload)
+// clusters, so logic deploying add-ons can be covered by integration tests without real
+// ClusterAPI clusters.
+//
+// A typical test:
+//   - starts a WorkloadCluster;
+//   - creates, in the management cluster, the SveltosCluster returned by WorkloadCluster.SveltosCluster;
+//   - adds the WorkloadCluster to a Registry passed to controllers.SetRemoteRestConfigGetter;
+//   - verifies, using WorkloadCluster.Client, resources deployed in the workload cluster;
+//   - stops the WorkloadCluster.
+package workloadcluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/yaml"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+	libsveltoscrd "github.com/projectsveltos/libsveltos/lib/crd"
+)
+
+const (
+	// sveltosNamespace is the namespace Sveltos deploys its agents to in managed clusters
+	sveltosNamespace = "projectsveltos"
+)
+
+// WorkloadCluster is an envtest API server acting as a managed cluster
+type WorkloadCluster struct {
+	// Namespace and Name of the SveltosCluster representing this cluster in the management cluster
+	Namespace string
+	Name      string
+
+	// Config is the rest config to access the API server
+	Config *rest.Config
+
+	// Client is a client to access the API server
+	Client client.Client
+
+	env *envtest.Environment
+}
+
+// Start starts an API server acting as the managed cluster namespace/name.
+// As in any cluster managed by Sveltos, the projectsveltos namespace and the Sveltos CRDs used
+// in managed clusters (ResourceSummary and Reloader) are present. CRDs in crdDirectoryPaths are
+// installed as well.
+func Start(namespace, name string, scheme *runtime.Scheme, crdDirectoryPaths ...string,
+) (*WorkloadCluster, error) {
+
+	crds, err := getSveltosCRDs()
+	if err != nil {
+		return nil, err
+	}
+
+	env := &envtest.Environment{
+		Scheme:                scheme,
+		CRDs:                  crds,
+		CRDDirectoryPaths:     crdDirectoryPaths,
+		ErrorIfCRDPathMissing: true,
+	}
+
+	config, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start workload cluster %s/%s: %w", namespace, name, err)
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		_ = env.Stop()
+		return nil, err
+	}
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: sveltosNamespace,
+		},
+	}
+	if err := c.Create(context.TODO(), ns); err != nil {
+		_ = env.Stop()
+		return nil, err
+	}
+
+	return &WorkloadCluster{
+		Namespace: namespace,
+		Name:      name,
+		Config:    config,
+		Client:    c,
+		env:       env,
+	}, nil
+}
+
+// getSveltosCRDs returns the Sveltos CRDs present in any managed cluster
+func getSveltosCRDs() ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	crdYAMLs := [][]byte{
+		libsveltoscrd.GetResourceSummaryCRDYAML(),
+		libsveltoscrd.GetReloaderCRDYAML(),
+	}
+
+	crds := make([]*apiextensionsv1.CustomResourceDefinition, len(crdYAMLs))
+	for i := range crdYAMLs {
+		crds[i] = &apiextensionsv1.CustomResourceDefinition{}
+		if err := yaml.Unmarshal(crdYAMLs[i], crds[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return crds, nil
+}
+
+// Stop stops the API server
+func (w *WorkloadCluster) Stop() error {
+	return w.env.Stop()
+}
+
+// SveltosCluster returns the SveltosCluster representing this cluster in the management cluster.
+// It is marked as ready, so add-ons are deployed to it once it matches a ClusterProfile/Profile.
+// Labels are set on the SveltosCluster.
+func (w *WorkloadCluster) SveltosCluster(labels map[string]string) *libsveltosv1beta1.SveltosCluster {
+	return &libsveltosv1beta1.SveltosCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: w.Namespace,
+			Name:      w.Name,
+			Labels:    labels,
+		},
+		Status: libsveltosv1beta1.SveltosClusterStatus{
+			Ready: true,
+		},
+	}
+}
+
+// Registry keeps track of the WorkloadClusters, so the rest config to access a managed cluster
+// can be resolved to the one of its WorkloadCluster.
+type Registry struct {
+	mux      sync.RWMutex
+	clusters map[string]*WorkloadCluster
+}
+
+func getKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// Add registers the WorkloadCluster
+func (r *Registry) Add(w *WorkloadCluster) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.clusters == nil {
+		r.clusters = make(map[string]*WorkloadCluster)
+	}
+	r.clusters[getKey(w.Namespace, w.Name)] = w
+}
+
+// Remove unregisters the WorkloadCluster
+func (r *Registry) Remove(w *WorkloadCluster) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	delete(r.clusters, getKey(w.Namespace, w.Name))
+}
+
+// GetRestConfig returns the rest config of the WorkloadCluster registered for the SveltosCluster
+// clusterNamespace/clusterName. Nil is returned for any other cluster, so default access is used.
+// It can be passed to controllers.SetRemoteRestConfigGetter.
+func (r *Registry) GetRestConfig(_ context.Context, clusterNamespace, clusterName string,
+	clusterType libsveltosv1beta1.ClusterType) (*rest.Config, error) {
+
+	if clusterType != libsveltosv1beta1.ClusterTypeSveltos {
+		return nil, nil
+	}
+
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	w, ok := r.clusters[getKey(clusterNamespace, clusterName)]
+	if !ok {
+		return nil, nil
+	}
+
+	return w.Config, nil
+}